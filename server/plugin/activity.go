@@ -0,0 +1,190 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultActivityDays = 7
+	// maxActivityDays is the oldest activity GitHub returns events for.
+	maxActivityDays = 90
+	// maxActivityPages caps the number of pages of events fetched. GitHub doesn't return more than 300 events.
+	maxActivityPages = 3
+	activityPageSize = 100
+)
+
+// repositoryActivity counts the actions of a user on a repository.
+type repositoryActivity struct {
+	Repository       string `json:"repository"`
+	URL              string `json:"url"`
+	CommitsPushed    int    `json:"commits_pushed"`
+	PRsOpened        int    `json:"prs_opened"`
+	PRsMerged        int    `json:"prs_merged"`
+	IssuesOpened     int    `json:"issues_opened"`
+	IssuesClosed     int    `json:"issues_closed"`
+	ReviewsSubmitted int    `json:"reviews_submitted"`
+	Comments         int    `json:"comments"`
+}
+
+func (a *repositoryActivity) isEmpty() bool {
+	return a.CommitsPushed+a.PRsOpened+a.PRsMerged+a.IssuesOpened+a.IssuesClosed+a.ReviewsSubmitted+a.Comments == 0
+}
+
+// userActivity is the activity of a user over the last days, by repository.
+type userActivity struct {
+	Username     string                `json:"username"`
+	Days         int                   `json:"days"`
+	Repositories []*repositoryActivity `json:"repositories"`
+}
+
+// parseActivityDays parses a number of days given as "7d" or "7". It defaults to defaultActivityDays.
+func parseActivityDays(value string) (int, error) {
+	if value == "" {
+		return defaultActivityDays, nil
+	}
+
+	days, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(value), "d"))
+	if err != nil || days <= 0 || days > maxActivityDays {
+		return 0, errors.Errorf("Invalid number of days `%s`. Use a number of days between 1 and %d, e.g. `7d`.", value, maxActivityDays)
+	}
+
+	return days, nil
+}
+
+// getUserActivity aggregates the events performed by a user in the given number of days before now. When org is set,
+// only the events on repositories of that organization are counted.
+func (p *Plugin) getUserActivity(ctx context.Context, githubClient *github.Client, username, org string, days int, now time.Time) (*userActivity, error) {
+	since := now.AddDate(0, 0, -days)
+	byRepo := map[string]*repositoryActivity{}
+
+	opts := &github.ListOptions{PerPage: activityPageSize}
+	for page := 0; page < maxActivityPages; page++ {
+		events, resp, err := githubClient.Activity.ListEventsPerformedByUser(ctx, username, false, opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list events")
+		}
+
+		reachedSince := false
+		for _, event := range events {
+			if event.GetCreatedAt().Before(since) {
+				reachedSince = true
+				break
+			}
+
+			repository := event.GetRepo().GetName()
+			if org != "" && !strings.EqualFold(strings.Split(repository, "/")[0], org) {
+				continue
+			}
+
+			activity, ok := byRepo[repository]
+			if !ok {
+				activity = &repositoryActivity{
+					Repository: repository,
+					URL:        p.getBaseURL() + repository,
+				}
+				byRepo[repository] = activity
+			}
+
+			if err := countEvent(activity, event); err != nil {
+				p.API.LogWarn("Failed to parse event payload", "type", event.GetType(), "error", err.Error())
+			}
+		}
+
+		if reachedSince || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	activity := &userActivity{
+		Username:     username,
+		Days:         days,
+		Repositories: []*repositoryActivity{},
+	}
+	for _, repoActivity := range byRepo {
+		if !repoActivity.isEmpty() {
+			activity.Repositories = append(activity.Repositories, repoActivity)
+		}
+	}
+	sort.Slice(activity.Repositories, func(i, j int) bool {
+		return activity.Repositories[i].Repository < activity.Repositories[j].Repository
+	})
+
+	return activity, nil
+}
+
+func countEvent(activity *repositoryActivity, event *github.Event) error {
+	payload, err := event.ParsePayload()
+	if err != nil {
+		return err
+	}
+
+	switch payload := payload.(type) {
+	case *github.PushEvent:
+		size := payload.GetSize()
+		if size == 0 {
+			size = len(payload.Commits)
+		}
+		activity.CommitsPushed += size
+	case *github.PullRequestEvent:
+		switch {
+		case payload.GetAction() == "opened":
+			activity.PRsOpened++
+		case payload.GetAction() == "closed" && payload.GetPullRequest().GetMerged():
+			activity.PRsMerged++
+		}
+	case *github.IssuesEvent:
+		switch payload.GetAction() {
+		case "opened":
+			activity.IssuesOpened++
+		case "closed":
+			activity.IssuesClosed++
+		}
+	case *github.PullRequestReviewEvent:
+		activity.ReviewsSubmitted++
+	case *github.IssueCommentEvent, *github.PullRequestReviewCommentEvent, *github.CommitCommentEvent:
+		activity.Comments++
+	}
+
+	return nil
+}
+
+func formatUserActivity(activity *userActivity) string {
+	if len(activity.Repositories) == 0 {
+		return fmt.Sprintf("No GitHub activity in the last %d %s.", activity.Days, pluralize(activity.Days, "day", "days"))
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "#### Your GitHub activity in the last %d %s\n", activity.Days, pluralize(activity.Days, "day", "days"))
+	for _, repo := range activity.Repositories {
+		counts := []string{}
+		for _, count := range []struct {
+			value            int
+			singular, plural string
+		}{
+			{repo.CommitsPushed, "commit pushed", "commits pushed"},
+			{repo.PRsOpened, "pull request opened", "pull requests opened"},
+			{repo.PRsMerged, "pull request merged", "pull requests merged"},
+			{repo.IssuesOpened, "issue opened", "issues opened"},
+			{repo.IssuesClosed, "issue closed", "issues closed"},
+			{repo.ReviewsSubmitted, "review submitted", "reviews submitted"},
+			{repo.Comments, "comment", "comments"},
+		} {
+			if count.value > 0 {
+				counts = append(counts, fmt.Sprintf("%d %s", count.value, pluralize(count.value, count.singular, count.plural)))
+			}
+		}
+
+		fmt.Fprintf(&message, "* [%s](%s): %s\n", repo.Repository, repo.URL, strings.Join(counts, ", "))
+	}
+
+	return message.String()
+}
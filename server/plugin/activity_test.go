@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseActivityDays(t *testing.T) {
+	for value, expected := range map[string]int{
+		"":    defaultActivityDays,
+		"7d":  7,
+		"30":  30,
+		"90D": 90,
+		"0d":  0,
+		"91d": 0,
+		"-1d": 0,
+		"abc": 0,
+		"7w":  0,
+	} {
+		t.Run(value, func(t *testing.T) {
+			days, err := parseActivityDays(value)
+			if expected == 0 {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, expected, days)
+		})
+	}
+}
+
+func TestGetUserActivity(t *testing.T) {
+	now := time.Date(2020, 9, 14, 12, 0, 0, 0, time.UTC)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/alice/events", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"type": "PushEvent", "created_at": "2020-09-14T10:00:00Z", "repo": {"name": "org/repo"}, "payload": {"size": 3}},
+			{"type": "PullRequestEvent", "created_at": "2020-09-13T10:00:00Z", "repo": {"name": "org/repo"}, "payload": {"action": "opened", "pull_request": {}}},
+			{"type": "PullRequestEvent", "created_at": "2020-09-13T09:00:00Z", "repo": {"name": "org/repo"}, "payload": {"action": "closed", "pull_request": {"merged": true}}},
+			{"type": "PullRequestEvent", "created_at": "2020-09-13T08:00:00Z", "repo": {"name": "org/repo"}, "payload": {"action": "closed", "pull_request": {"merged": false}}},
+			{"type": "IssuesEvent", "created_at": "2020-09-12T10:00:00Z", "repo": {"name": "org/other"}, "payload": {"action": "opened", "issue": {}}},
+			{"type": "IssuesEvent", "created_at": "2020-09-12T09:00:00Z", "repo": {"name": "org/other"}, "payload": {"action": "closed", "issue": {}}},
+			{"type": "PullRequestReviewEvent", "created_at": "2020-09-11T10:00:00Z", "repo": {"name": "org/repo"}, "payload": {"action": "created"}},
+			{"type": "IssueCommentEvent", "created_at": "2020-09-11T09:00:00Z", "repo": {"name": "org/other"}, "payload": {"action": "created"}},
+			{"type": "WatchEvent", "created_at": "2020-09-10T10:00:00Z", "repo": {"name": "org/starred"}, "payload": {"action": "started"}},
+			{"type": "PushEvent", "created_at": "2020-09-10T09:00:00Z", "repo": {"name": "personal/repo"}, "payload": {"size": 1}},
+			{"type": "PushEvent", "created_at": "2020-09-01T10:00:00Z", "repo": {"name": "org/repo"}, "payload": {"size": 5}}
+		]`)
+	})
+
+	client := newTestGitHubClient(t, mux)
+	p := NewPlugin()
+
+	t.Run("all repositories", func(t *testing.T) {
+		activity, err := p.getUserActivity(context.Background(), client, "alice", "", 7, now)
+		require.NoError(t, err)
+
+		assert.Equal(t, 7, activity.Days)
+		assert.Equal(t, []*repositoryActivity{
+			{Repository: "org/other", URL: "https://github.com/org/other", IssuesOpened: 1, IssuesClosed: 1, Comments: 1},
+			{Repository: "org/repo", URL: "https://github.com/org/repo", CommitsPushed: 3, PRsOpened: 1, PRsMerged: 1, ReviewsSubmitted: 1},
+			{Repository: "personal/repo", URL: "https://github.com/personal/repo", CommitsPushed: 1},
+		}, activity.Repositories)
+	})
+
+	t.Run("locked to an organization", func(t *testing.T) {
+		activity, err := p.getUserActivity(context.Background(), client, "alice", "org", 7, now)
+		require.NoError(t, err)
+
+		require.Len(t, activity.Repositories, 2)
+		assert.Equal(t, "org/other", activity.Repositories[0].Repository)
+		assert.Equal(t, "org/repo", activity.Repositories[1].Repository)
+	})
+
+	t.Run("no activity", func(t *testing.T) {
+		activity, err := p.getUserActivity(context.Background(), client, "alice", "", 1, now.AddDate(0, 1, 0))
+		require.NoError(t, err)
+
+		assert.Empty(t, activity.Repositories)
+		assert.Equal(t, "No GitHub activity in the last 1 day.", formatUserActivity(activity))
+	})
+}
+
+func TestFormatUserActivity(t *testing.T) {
+	activity := &userActivity{
+		Days: 7,
+		Repositories: []*repositoryActivity{
+			{Repository: "org/other", URL: "https://github.com/org/other", IssuesOpened: 1, Comments: 2},
+			{Repository: "org/repo", URL: "https://github.com/org/repo", CommitsPushed: 3, PRsMerged: 1},
+		},
+	}
+
+	assert.Equal(t, "#### Your GitHub activity in the last 7 days\n"+
+		"* [org/other](https://github.com/org/other): 1 issue opened, 2 comments\n"+
+		"* [org/repo](https://github.com/org/repo): 3 commits pushed, 1 pull request merged\n", formatUserActivity(activity))
+
+	activity = &userActivity{
+		Days: 1,
+		Repositories: []*repositoryActivity{
+			{Repository: "org/repo", URL: "https://github.com/org/repo", CommitsPushed: 1, PRsOpened: 1, IssuesClosed: 1, ReviewsSubmitted: 1, Comments: 1},
+		},
+	}
+
+	assert.Equal(t, "#### Your GitHub activity in the last 1 day\n"+
+		"* [org/repo](https://github.com/org/repo): 1 commit pushed, 1 pull request opened, 1 issue closed, 1 review submitted, 1 comment\n", formatUserActivity(activity))
+
+	assert.Equal(t, "No GitHub activity in the last 1 day.", formatUserActivity(&userActivity{Days: 1}))
+}
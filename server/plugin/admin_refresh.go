@@ -0,0 +1,223 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	refreshAllStateKey = "refresh_all_state"
+	refreshAllJobKey   = "refresh_all"
+
+	// refreshAllJobInterval is the delay between two pages of users being refreshed.
+	refreshAllJobInterval = 10 * time.Second
+	refreshAllPageSize    = 100
+
+	defaultRefreshAllConcurrency = 5
+	defaultRefreshAllDelay       = 200 * time.Millisecond
+)
+
+var errRefreshAllRunning = errors.New("a refresh of all sidebars is already running")
+
+// refreshAllState is the progress of a refresh of the sidebars of all connected users. It's stored
+// after every page of users so that the refresh resumes where it stopped after a restart.
+type refreshAllState struct {
+	RequestedBy string    `json:"requested_by"`
+	StartedAt   time.Time `json:"started_at"`
+	// Page is the next page of KV store keys to go through.
+	Page      int `json:"page"`
+	Total     int `json:"total"`
+	Refreshed int `json:"refreshed"`
+	Failures  int `json:"failures"`
+}
+
+func (s *refreshAllState) progress() string {
+	failures := "failures"
+	if s.Failures == 1 {
+		failures = "failure"
+	}
+
+	return fmt.Sprintf("refreshed %d/%d users, %d %s", s.Refreshed, s.Total, s.Failures, failures)
+}
+
+// connectedUserIDs returns the IDs of the connected users among KV store keys.
+func connectedUserIDs(keys []string) []string {
+	userIDs := []string{}
+	for _, key := range keys {
+		if strings.HasSuffix(key, githubTokenKey) {
+			userIDs = append(userIDs, strings.TrimSuffix(key, githubTokenKey))
+		}
+	}
+
+	return userIDs
+}
+
+// listConnectedUserIDs returns the IDs of all the connected users.
+func (p *Plugin) listConnectedUserIDs() ([]string, error) {
+	userIDs := []string{}
+	for page := 0; ; page++ {
+		keys, appErr := p.API.KVList(page, refreshAllPageSize)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "could not list keys")
+		}
+
+		userIDs = append(userIDs, connectedUserIDs(keys)...)
+
+		if len(keys) < refreshAllPageSize {
+			return userIDs, nil
+		}
+	}
+}
+
+func (p *Plugin) countConnectedUsers() (int, error) {
+	count := 0
+	for page := 0; ; page++ {
+		keys, appErr := p.API.KVList(page, refreshAllPageSize)
+		if appErr != nil {
+			return 0, errors.Wrap(appErr, "could not list keys")
+		}
+
+		count += len(connectedUserIDs(keys))
+
+		if len(keys) < refreshAllPageSize {
+			return count, nil
+		}
+	}
+}
+
+func (p *Plugin) getRefreshAllState() (*refreshAllState, error) {
+	value, appErr := p.API.KVGet(refreshAllStateKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get refresh state from KV store")
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	var state *refreshAllState
+	if err := json.Unmarshal(value, &state); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal refresh state")
+	}
+
+	return state, nil
+}
+
+// startRefreshAll schedules a refresh of the sidebars of all connected users. Pages of users are
+// then refreshed by the refresh job.
+func (p *Plugin) startRefreshAll(userID string) (*refreshAllState, error) {
+	total, err := p.countConnectedUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &refreshAllState{
+		RequestedBy: userID,
+		StartedAt:   time.Now(),
+		Total:       total,
+	}
+
+	value, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal refresh state")
+	}
+
+	stored, appErr := p.API.KVCompareAndSet(refreshAllStateKey, nil, value)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not store refresh state in KV store")
+	}
+	if !stored {
+		return nil, errRefreshAllRunning
+	}
+
+	return state, nil
+}
+
+// refreshSidebars sends a refresh event to users, a few at a time, and returns the number of users
+// whose account couldn't be read.
+func (p *Plugin) refreshSidebars(userIDs []string) int {
+	config := p.getConfiguration()
+	delay := config.getRefreshAllDelay()
+
+	var lock sync.Mutex
+	failures := 0
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, config.getRefreshAllConcurrency())
+	for _, userID := range userIDs {
+		userID := userID
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				time.Sleep(delay)
+				<-workers
+				wg.Done()
+			}()
+
+			if _, apiErr := p.getGitHubUserInfo(userID); apiErr != nil {
+				lock.Lock()
+				failures++
+				lock.Unlock()
+				return
+			}
+
+			p.sendRefreshEvent(userID)
+		}()
+	}
+	wg.Wait()
+
+	return failures
+}
+
+// refreshAllSidebars refreshes the next page of users of a pending refresh of all sidebars. The
+// admin who started it gets a summary once all pages are done.
+func (p *Plugin) refreshAllSidebars() {
+	state, err := p.getRefreshAllState()
+	if err != nil {
+		p.API.LogWarn("Failed to get refresh state", "error", err.Error())
+		return
+	}
+	if state == nil {
+		return
+	}
+
+	keys, appErr := p.API.KVList(state.Page, refreshAllPageSize)
+	if appErr != nil {
+		p.API.LogWarn("Failed to list keys", "error", appErr.Error())
+		return
+	}
+
+	userIDs := connectedUserIDs(keys)
+	failures := p.refreshSidebars(userIDs)
+
+	state.Page++
+	state.Refreshed += len(userIDs) - failures
+	state.Failures += failures
+
+	if len(keys) < refreshAllPageSize {
+		if appErr := p.API.KVDelete(refreshAllStateKey); appErr != nil {
+			p.API.LogWarn("Failed to delete refresh state", "error", appErr.Error())
+		}
+
+		p.CreateBotDMPost(state.RequestedBy, fmt.Sprintf("Finished refreshing the GitHub sidebars: %s.", state.progress()), "")
+		return
+	}
+
+	value, err := json.Marshal(state)
+	if err != nil {
+		p.API.LogWarn("Failed to marshal refresh state", "error", err.Error())
+		return
+	}
+	if appErr := p.API.KVSet(refreshAllStateKey, value); appErr != nil {
+		p.API.LogWarn("Failed to store refresh state", "error", appErr.Error())
+		return
+	}
+
+	p.API.LogDebug("Refreshing all sidebars", "progress", state.progress())
+}
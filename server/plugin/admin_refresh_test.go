@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestConnectedUserIDs(t *testing.T) {
+	assert.Equal(t, []string{"user1", "user2"}, connectedUserIDs([]string{
+		"user1" + githubTokenKey,
+		"subscriptions",
+		"user2" + githubTokenKey,
+		"user1-muted-users",
+	}))
+	assert.Empty(t, connectedUserIDs(nil))
+}
+
+func TestStartRefreshAll(t *testing.T) {
+	keys := []string{"user1" + githubTokenKey, "user2" + githubTokenKey, "subscriptions"}
+
+	t.Run("started", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("KVList", 0, refreshAllPageSize).Return(keys, nil).Once()
+		api.On("KVCompareAndSet", refreshAllStateKey, []byte(nil), mock.MatchedBy(func(value []byte) bool {
+			var state refreshAllState
+			require.NoError(t, json.Unmarshal(value, &state))
+			return state.RequestedBy == "adminID" && state.Total == 2 && state.Page == 0
+		})).Return(true, nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		state, err := p.startRefreshAll("adminID")
+		require.NoError(t, err)
+		assert.Equal(t, 2, state.Total)
+	})
+
+	t.Run("already running", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("KVList", 0, refreshAllPageSize).Return(keys, nil).Once()
+		api.On("KVCompareAndSet", refreshAllStateKey, []byte(nil), mock.Anything).Return(false, nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		_, err := p.startRefreshAll("adminID")
+		assert.Equal(t, errRefreshAllRunning, err)
+	})
+}
+
+func TestRefreshAllSidebars(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	userInfo, err := json.Marshal(&GitHubUserInfo{UserID: "user1", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	setupPlugin := func(t *testing.T, state *refreshAllState, keys []string) (*Plugin, *plugintest.API) {
+		value, err := json.Marshal(state)
+		require.NoError(t, err)
+
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, RefreshAllConcurrency: 2, RefreshAllDelay: 1})
+		api := &plugintest.API{}
+		api.On("KVGet", refreshAllStateKey).Return(value, nil).Once()
+		api.On("KVList", state.Page, refreshAllPageSize).Return(keys, nil).Once()
+		api.On("KVGet", "user1"+githubTokenKey).Return(userInfo, nil)
+		api.On("KVGet", "user2"+githubTokenKey).Return(nil, nil)
+		api.On("PublishWebSocketEvent", wsEventRefresh, map[string]interface{}(nil), &model.WebsocketBroadcast{UserId: "user1"}).Once()
+		p.SetAPI(api)
+		return p, api
+	}
+
+	t.Run("page in progress", func(t *testing.T) {
+		keys := make([]string, refreshAllPageSize)
+		keys[0] = "user1" + githubTokenKey
+		keys[1] = "user2" + githubTokenKey
+		p, api := setupPlugin(t, &refreshAllState{RequestedBy: "adminID", Page: 2, Total: 300, Refreshed: 198}, keys)
+		api.On("KVSet", refreshAllStateKey, mock.MatchedBy(func(value []byte) bool {
+			var state refreshAllState
+			require.NoError(t, json.Unmarshal(value, &state))
+			return state.Page == 3 && state.Refreshed == 199 && state.Failures == 1
+		})).Return(nil).Once()
+		api.On("LogDebug", "Refreshing all sidebars", "progress", "refreshed 199/300 users, 1 failure").Once()
+		defer api.AssertExpectations(t)
+
+		p.refreshAllSidebars()
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		p, api := setupPlugin(t, &refreshAllState{RequestedBy: "adminID", Page: 2, Total: 2}, []string{"user1" + githubTokenKey, "user2" + githubTokenKey})
+		api.On("KVDelete", refreshAllStateKey).Return(nil).Once()
+		api.On("GetDirectChannel", "adminID", "botID").Return(&model.Channel{Id: "dmChannelID"}, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "dmChannelID" && post.Message == "Finished refreshing the GitHub sidebars: refreshed 1/2 users, 1 failure."
+		})).Return(&model.Post{}, nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.refreshAllSidebars()
+	})
+
+	t.Run("no pending refresh", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("KVGet", refreshAllStateKey).Return(nil, nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.refreshAllSidebars()
+	})
+}
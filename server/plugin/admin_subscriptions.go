@@ -0,0 +1,215 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// allChannels is the --channel value removing the subscriptions of all channels to a repository.
+const allChannels = "all"
+
+// adminSubscription is a subscription of a channel to a repository, as listed to system admins.
+type adminSubscription struct {
+	Repository      string `json:"repository"`
+	ChannelID       string `json:"channel_id"`
+	ChannelName     string `json:"channel_name"`
+	TeamID          string `json:"team_id"`
+	CreatorID       string `json:"creator_id"`
+	CreatorUsername string `json:"creator_username"`
+	Features        string `json:"features"`
+	Flags           string `json:"flags"`
+}
+
+// subscriptionsKeyFor returns the key under which the subscriptions to a repository or an
+// organization are stored, the same way Unsubscribe does.
+func (p *Plugin) subscriptionsKeyFor(repository string) (string, error) {
+	owner, repo := parseOwnerAndRepo(repository, p.getBaseURL())
+	if owner == "" {
+		return "", errors.New("invalid repository")
+	}
+	return fullNameFromOwnerAndRepo(owner, repo), nil
+}
+
+// findRepositorySubscriptions returns the subscriptions of all channels to a repository or an organization.
+func (p *Plugin) findRepositorySubscriptions(repository string) ([]*Subscription, error) {
+	key, err := p.subscriptionsKeyFor(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get subscriptions")
+	}
+
+	found := []*Subscription{}
+	for _, sub := range subs.Repositories[key] {
+		// this is needed to be backwards compatible
+		if sub.Repository == "" {
+			sub.Repository = key
+		}
+		found = append(found, sub)
+	}
+
+	return found, nil
+}
+
+// describeSubscriptions resolves the channels and creators of subscriptions. Channels or users that
+// can't be found are left without a name.
+func (p *Plugin) describeSubscriptions(subs []*Subscription) []*adminSubscription {
+	described := make([]*adminSubscription, 0, len(subs))
+	for _, sub := range subs {
+		d := &adminSubscription{
+			Repository: strings.Trim(sub.Repository, "/"),
+			ChannelID:  sub.ChannelID,
+			CreatorID:  sub.CreatorID,
+			Features:   sub.Features,
+			Flags:      sub.Flags.String(),
+		}
+
+		if channel, appErr := p.API.GetChannel(sub.ChannelID); appErr == nil {
+			d.ChannelName = channel.Name
+			d.TeamID = channel.TeamId
+		}
+		if sub.CreatorID != "" {
+			if user, appErr := p.API.GetUser(sub.CreatorID); appErr == nil {
+				d.CreatorUsername = user.Username
+			}
+		}
+
+		described = append(described, d)
+	}
+
+	return described
+}
+
+// removeRepositorySubscriptions removes the subscriptions of one or all channels to a repository, and
+// lets the affected channels know. It returns the removed subscriptions.
+func (p *Plugin) removeRepositorySubscriptions(repository, channelID string) ([]*Subscription, error) {
+	subs, err := p.findRepositorySubscriptions(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := []*Subscription{}
+	for _, sub := range subs {
+		if channelID != allChannels && sub.ChannelID != channelID {
+			continue
+		}
+
+		if err := p.Unsubscribe(sub.ChannelID, repository); err != nil {
+			return removed, errors.Wrapf(err, "could not unsubscribe channel %s", sub.ChannelID)
+		}
+		removed = append(removed, sub)
+
+		notice := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: sub.ChannelID,
+			Message:   fmt.Sprintf("A system admin removed the subscription of this channel to `%s`.", strings.Trim(sub.Repository, "/")),
+		}
+		if _, appErr := p.API.CreatePost(notice); appErr != nil {
+			p.API.LogWarn("Failed to post subscription removal notice", "channelID", sub.ChannelID, "error", appErr.Error())
+		}
+	}
+
+	return removed, nil
+}
+
+// formatAdminSubscriptions lists subscriptions in a Markdown table.
+func formatAdminSubscriptions(repository string, subs []*adminSubscription) string {
+	if len(subs) == 0 {
+		return fmt.Sprintf("No channel is subscribed to %s.", repository)
+	}
+
+	txt := fmt.Sprintf("### Channels subscribed to %s\n", repository)
+	txt += "| Channel | Channel ID | Creator | Features |\n|---|---|---|---|\n"
+	for _, sub := range subs {
+		channel := "_unknown_"
+		if sub.ChannelName != "" {
+			channel = "~" + sub.ChannelName
+		}
+		creator := "_unknown_"
+		if sub.CreatorUsername != "" {
+			creator = "@" + sub.CreatorUsername
+		}
+		features := sub.Features
+		if sub.Flags != "" {
+			features += " " + sub.Flags
+		}
+		txt += fmt.Sprintf("| %s | %s | %s | %s |\n", channel, sub.ChannelID, creator, features)
+	}
+
+	return txt
+}
+
+func (p *Plugin) handleAdminSubscriptions(parameters []string) string {
+	usage := "Invalid admin subscriptions command. Use `/github admin subscriptions find owner[/repo]` or `/github admin subscriptions remove owner[/repo] --channel <channel ID|all>`."
+	if len(parameters) < 2 {
+		return usage
+	}
+
+	repository := parameters[1]
+
+	switch parameters[0] {
+	case "find":
+		if len(parameters) != 2 {
+			return usage
+		}
+
+		subs, err := p.findRepositorySubscriptions(repository)
+		if err != nil {
+			p.API.LogWarn("Failed to find subscriptions", "repo", repository, "error", err.Error())
+			return "Encountered an error finding the subscriptions. Please try again."
+		}
+
+		return formatAdminSubscriptions(repository, p.describeSubscriptions(subs))
+	case "remove":
+		if len(parameters) != 4 || parameters[2] != "--channel" {
+			return usage
+		}
+		channelID := parameters[3]
+
+		removed, err := p.removeRepositorySubscriptions(repository, channelID)
+		if err != nil {
+			p.API.LogWarn("Failed to remove subscriptions", "repo", repository, "channel", channelID, "error", err.Error())
+			return fmt.Sprintf("Encountered an error removing the subscriptions. %d subscriptions were removed before the error.", len(removed))
+		}
+
+		if len(removed) == 0 {
+			if channelID == allChannels {
+				return fmt.Sprintf("No channel is subscribed to %s.", repository)
+			}
+			return fmt.Sprintf("Channel %s is not subscribed to %s.", channelID, repository)
+		}
+
+		return fmt.Sprintf("Successfully removed %d subscriptions to %s.", len(removed), repository)
+	default:
+		return usage
+	}
+}
+
+func (p *Plugin) getAdminSubscriptions(w http.ResponseWriter, r *http.Request, userID string) {
+	if !p.isSystemAdmin(userID) {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Only system administrators can list the subscriptions of all channels.", StatusCode: http.StatusForbidden})
+		return
+	}
+
+	repository := r.URL.Query().Get("repo")
+	if repository == "" {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a repository.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	subs, err := p.findRepositorySubscriptions(repository)
+	if err != nil {
+		p.API.LogWarn("Failed to find subscriptions", "repo", repository, "error", err.Error())
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to find the subscriptions.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, p.describeSubscriptions(subs))
+}
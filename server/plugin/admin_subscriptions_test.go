@@ -0,0 +1,157 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAdminSubscriptionsTest(t *testing.T) (*Plugin, *plugintest.API, *[]byte, *[]string) {
+	subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "channel1", CreatorID: "creator1", Features: "pulls,issues", Repository: "owner/repo"},
+			{ChannelID: "channel2", CreatorID: "creator2", Features: "pushes", Flags: SubscriptionFlags{ExcludeOrgMembers: true}, Repository: "owner/repo"},
+		},
+		"owner/other": {
+			{ChannelID: "channel1", CreatorID: "creator1", Features: "pulls", Repository: "owner/other"},
+		},
+	}})
+	require.NoError(t, err)
+
+	notified := &[]string{}
+
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	p.setConfiguration(&Configuration{})
+	api := &plugintest.API{}
+	api.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+	api.On("KVGet", SubscriptionsKey).Return(func(string) []byte {
+		return subscriptions
+	}, nil)
+	api.On("KVSet", SubscriptionsKey, mock.Anything).Return(func(_ string, value []byte) *model.AppError {
+		subscriptions = value
+		return nil
+	})
+	api.On("KVSet", SubscriptionsRevisionKey, mock.Anything).Return(nil)
+	api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", Name: "town-square", TeamId: "teamID"}, nil)
+	api.On("GetChannel", "channel2").Return(nil, &model.AppError{Message: "not found"})
+	api.On("GetUser", "creator1").Return(&model.User{Id: "creator1", Username: "alice"}, nil)
+	api.On("GetUser", "creator2").Return(&model.User{Id: "creator2", Username: "bob"}, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		*notified = append(*notified, post.ChannelId+": "+post.Message)
+		return post
+	}, nil)
+	p.SetAPI(api)
+
+	return p, api, &subscriptions, notified
+}
+
+func TestHandleAdminSubscriptionsFind(t *testing.T) {
+	p, _, _, _ := setupAdminSubscriptionsTest(t)
+
+	assert.Equal(t, "### Channels subscribed to owner/repo\n"+
+		"| Channel | Channel ID | Creator | Features |\n|---|---|---|---|\n"+
+		"| ~town-square | channel1 | @alice | pulls,issues |\n"+
+		"| _unknown_ | channel2 | @bob | pushes --exclude-org-member |\n",
+		p.handleAdminSubscriptions([]string{"find", "owner/repo"}))
+
+	assert.Equal(t, "No channel is subscribed to owner/none.", p.handleAdminSubscriptions([]string{"find", "owner/none"}))
+}
+
+func TestHandleAdminSubscriptionsRemove(t *testing.T) {
+	remaining := func(t *testing.T, value []byte, repository string) []string {
+		var subs Subscriptions
+		require.NoError(t, json.Unmarshal(value, &subs))
+
+		channelIDs := []string{}
+		for _, sub := range subs.Repositories[repository] {
+			channelIDs = append(channelIDs, sub.ChannelID)
+		}
+		return channelIDs
+	}
+
+	t.Run("one channel", func(t *testing.T) {
+		p, _, subscriptions, notified := setupAdminSubscriptionsTest(t)
+
+		message := p.handleAdminSubscriptions([]string{"remove", "owner/repo", "--channel", "channel1"})
+		assert.Equal(t, "Successfully removed 1 subscriptions to owner/repo.", message)
+		assert.Equal(t, []string{"channel2"}, remaining(t, *subscriptions, "owner/repo"))
+		assert.Equal(t, []string{"channel1"}, remaining(t, *subscriptions, "owner/other"))
+		assert.Equal(t, []string{"channel1: A system admin removed the subscription of this channel to `owner/repo`."}, *notified)
+	})
+
+	t.Run("all channels", func(t *testing.T) {
+		p, _, subscriptions, notified := setupAdminSubscriptionsTest(t)
+
+		message := p.handleAdminSubscriptions([]string{"remove", "owner/repo", "--channel", "all"})
+		assert.Equal(t, "Successfully removed 2 subscriptions to owner/repo.", message)
+		assert.Empty(t, remaining(t, *subscriptions, "owner/repo"))
+		assert.Equal(t, []string{"channel1"}, remaining(t, *subscriptions, "owner/other"))
+		assert.Equal(t, []string{
+			"channel1: A system admin removed the subscription of this channel to `owner/repo`.",
+			"channel2: A system admin removed the subscription of this channel to `owner/repo`.",
+		}, *notified)
+	})
+
+	t.Run("channel not subscribed", func(t *testing.T) {
+		p, _, _, notified := setupAdminSubscriptionsTest(t)
+
+		message := p.handleAdminSubscriptions([]string{"remove", "owner/repo", "--channel", "channel3"})
+		assert.Equal(t, "Channel channel3 is not subscribed to owner/repo.", message)
+		assert.Empty(t, *notified)
+	})
+
+	t.Run("missing channel", func(t *testing.T) {
+		p, _, _, _ := setupAdminSubscriptionsTest(t)
+
+		message := p.handleAdminSubscriptions([]string{"remove", "owner/repo"})
+		assert.Contains(t, message, "Invalid admin subscriptions command.")
+	})
+}
+
+func TestHandleAdminRequiresSystemAdmin(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("HasPermissionTo", "userID", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	message := p.handleAdmin(nil, &model.CommandArgs{UserId: "userID"}, []string{"subscriptions", "remove", "owner/repo", "--channel", "all"}, nil)
+	assert.Equal(t, "Only system administrators can use admin commands.", message)
+}
+
+func TestGetAdminSubscriptions(t *testing.T) {
+	t.Run("subscriptions listed", func(t *testing.T) {
+		p, api, _, _ := setupAdminSubscriptionsTest(t)
+		api.On("HasPermissionTo", "userID", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+
+		w := httptest.NewRecorder()
+		p.getAdminSubscriptions(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/subscriptions?repo=owner/repo", nil), "userID")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var subs []*adminSubscription
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &subs))
+		assert.Equal(t, []*adminSubscription{
+			{Repository: "owner/repo", ChannelID: "channel1", ChannelName: "town-square", TeamID: "teamID", CreatorID: "creator1", CreatorUsername: "alice", Features: "pulls,issues"},
+			{Repository: "owner/repo", ChannelID: "channel2", CreatorID: "creator2", CreatorUsername: "bob", Features: "pushes", Flags: "--exclude-org-member"},
+		}, subs)
+	})
+
+	t.Run("not an admin", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("HasPermissionTo", "userID", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+		p.SetAPI(api)
+
+		w := httptest.NewRecorder()
+		p.getAdminSubscriptions(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/subscriptions?repo=owner/repo", nil), "userID")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
@@ -0,0 +1,154 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const announcementChannelsFlag = "--channels"
+
+// announcementResult is the outcome of posting the announcement in one of the channels.
+type announcementResult struct {
+	ChannelName string
+	Err         error
+}
+
+// getAnnouncementMessage returns the message announcing the plugin to the users of a channel.
+func (p *Plugin) getAnnouncementMessage() string {
+	connect := "enter `/github connect`"
+	if siteURL := p.API.GetConfig().ServiceSettings.SiteURL; siteURL != nil && *siteURL != "" {
+		connect = fmt.Sprintf("[connect your GitHub account](%s/plugins/%s/oauth/connect) or enter `/github connect`", strings.TrimSuffix(*siteURL, "/"), Manifest.Id)
+	}
+
+	return "#### GitHub is now available in Mattermost\n" +
+		"Get notified about the pull requests awaiting your review and your mentions, follow the activity of your repositories in channels, and create issues without leaving Mattermost.\n\n" +
+		fmt.Sprintf("To get started, %s. Use `/github help` to learn about the other commands.", connect)
+}
+
+// parseAnnouncementChannels returns the distinct channel names of `--channels town-square,engineering`.
+func parseAnnouncementChannels(parameters []string) ([]string, error) {
+	if len(parameters) < 2 || parameters[0] != announcementChannelsFlag {
+		return nil, errors.New("Please specify the channels to post the announcement in, e.g. `/github setup announcement --channels town-square,engineering`.")
+	}
+
+	names := []string{}
+	for _, name := range strings.Split(strings.Join(parameters[1:], ""), ",") {
+		name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "~"))
+		if name == "" || containsValue(names, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, errors.New("Please specify at least one channel.")
+	}
+
+	return names, nil
+}
+
+// getAnnouncementTeamIDs returns the teams of a user, starting with the current one.
+func (p *Plugin) getAnnouncementTeamIDs(userID, currentTeamID string) ([]string, error) {
+	teams, appErr := p.API.GetTeamsForUser(userID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get the teams of the user")
+	}
+
+	teamIDs := []string{}
+	if currentTeamID != "" {
+		teamIDs = append(teamIDs, currentTeamID)
+	}
+	for _, team := range teams {
+		if !containsValue(teamIDs, team.Id) {
+			teamIDs = append(teamIDs, team.Id)
+		}
+	}
+
+	return teamIDs, nil
+}
+
+// getAnnouncementChannel resolves the name of a channel in the teams of the admin, and makes sure
+// the bot is allowed to post there.
+func (p *Plugin) getAnnouncementChannel(teamIDs []string, name string) (*model.Channel, error) {
+	for _, teamID := range teamIDs {
+		channel, appErr := p.API.GetChannelByName(teamID, name, false)
+		if appErr != nil {
+			continue
+		}
+
+		switch channel.Type {
+		case model.CHANNEL_OPEN:
+		case model.CHANNEL_PRIVATE:
+			if _, appErr := p.API.GetChannelMember(channel.Id, p.BotUserID); appErr != nil {
+				return nil, errors.New("the GitHub bot is not a member of this private channel. Please add it to the channel first")
+			}
+		default:
+			return nil, errors.New("announcements can only be posted in public or private channels")
+		}
+
+		return channel, nil
+	}
+
+	return nil, errors.New("there is no such channel in your teams")
+}
+
+// postAnnouncement posts the announcement in each of the channels. A channel failing doesn't stop
+// the announcement from being posted in the next ones.
+func (p *Plugin) postAnnouncement(teamIDs, channelNames []string, message string) []*announcementResult {
+	results := make([]*announcementResult, 0, len(channelNames))
+	for _, name := range channelNames {
+		result := &announcementResult{ChannelName: name}
+		results = append(results, result)
+
+		channel, err := p.getAnnouncementChannel(teamIDs, name)
+		if err != nil {
+			result.Err = err
+			continue
+		}
+
+		post := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: channel.Id,
+			Message:   message,
+		}
+		if _, appErr := p.API.CreatePost(post); appErr != nil {
+			p.API.LogWarn("Failed to post announcement", "channelID", channel.Id, "error", appErr.Error())
+			result.Err = errors.New("the post could not be created")
+		}
+	}
+
+	return results
+}
+
+func formatAnnouncementResults(results []*announcementResult) string {
+	var message strings.Builder
+	message.WriteString("#### Announcement\n")
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(&message, "* :x: ~%s: %s.\n", result.ChannelName, result.Err.Error())
+			continue
+		}
+		fmt.Fprintf(&message, "* :white_check_mark: ~%s: posted.\n", result.ChannelName)
+	}
+
+	return strings.TrimSuffix(message.String(), "\n")
+}
+
+// handleSetupAnnouncement posts the announcement of the plugin in the given channels, and reports
+// how it went for each of them.
+func (p *Plugin) handleSetupAnnouncement(args *model.CommandArgs, parameters []string) string {
+	channelNames, err := parseAnnouncementChannels(parameters)
+	if err != nil {
+		return err.Error()
+	}
+
+	teamIDs, err := p.getAnnouncementTeamIDs(args.UserId, args.TeamId)
+	if err != nil {
+		p.API.LogWarn("Failed to get teams for the announcement", "userID", args.UserId, "error", err.Error())
+		return "Encountered an error getting your teams."
+	}
+
+	return formatAnnouncementResults(p.postAnnouncement(teamIDs, channelNames, p.getAnnouncementMessage()))
+}
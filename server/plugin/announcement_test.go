@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAnnouncementChannels(t *testing.T) {
+	names, err := parseAnnouncementChannels([]string{"--channels", "town-square,~Engineering,", "town-square"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"town-square", "engineering"}, names)
+
+	names, err = parseAnnouncementChannels([]string{"--channels", "town-square,", "engineering"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"town-square", "engineering"}, names)
+
+	_, err = parseAnnouncementChannels([]string{"town-square"})
+	assert.Error(t, err)
+	_, err = parseAnnouncementChannels([]string{"--channels", ","})
+	assert.EqualError(t, err, "Please specify at least one channel.")
+}
+
+func TestHandleSetupAnnouncement(t *testing.T) {
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	api := &plugintest.API{}
+	api.On("HasPermissionTo", "adminID", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+	siteURL := "https://mattermost.example.com/"
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	api.On("GetTeamsForUser", "adminID").Return([]*model.Team{{Id: "otherTeamID"}, {Id: "teamID"}}, nil)
+
+	notFound := &model.AppError{Message: "not found"}
+	api.On("GetChannelByName", "teamID", "town-square", false).Return(&model.Channel{Id: "townSquareID", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+	api.On("GetChannelByName", "teamID", "engineering", false).Return(nil, notFound)
+	api.On("GetChannelByName", "otherTeamID", "engineering", false).Return(&model.Channel{Id: "engineeringID", Name: "engineering", Type: model.CHANNEL_OPEN}, nil)
+	api.On("GetChannelByName", "teamID", "secret", false).Return(&model.Channel{Id: "secretID", Name: "secret", Type: model.CHANNEL_PRIVATE}, nil)
+	api.On("GetChannelMember", "secretID", "botID").Return(nil, notFound)
+	api.On("GetChannelByName", "teamID", "broken", false).Return(&model.Channel{Id: "brokenID", Name: "broken", Type: model.CHANNEL_OPEN}, nil)
+	api.On("GetChannelByName", mock.AnythingOfType("string"), "missing", false).Return(nil, notFound)
+
+	posted := map[string]string{}
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "brokenID"
+	})).Return(nil, &model.AppError{Message: "failed"})
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		assert.Equal(t, "botID", post.UserId)
+		posted[post.ChannelId] = post.Message
+		return post
+	}, nil)
+	api.On("LogWarn", "Failed to post announcement", "channelID", "brokenID", "error", mock.AnythingOfType("string"))
+	p.SetAPI(api)
+
+	args := &model.CommandArgs{UserId: "adminID", TeamId: "teamID"}
+	message := p.handleSetup(nil, args, []string{"announcement", "--channels", "town-square,engineering,secret,missing,broken"}, nil)
+	assert.Equal(t, "#### Announcement\n"+
+		"* :white_check_mark: ~town-square: posted.\n"+
+		"* :white_check_mark: ~engineering: posted.\n"+
+		"* :x: ~secret: the GitHub bot is not a member of this private channel. Please add it to the channel first.\n"+
+		"* :x: ~missing: there is no such channel in your teams.\n"+
+		"* :x: ~broken: the post could not be created.", message)
+
+	// The announcement is rendered once for all the channels
+	require.Len(t, posted, 2)
+	assert.Equal(t, posted["townSquareID"], posted["engineeringID"])
+	assert.Contains(t, posted["townSquareID"], "[connect your GitHub account](https://mattermost.example.com/plugins/github/oauth/connect)")
+
+	assert.Equal(t, "Please specify the channels to post the announcement in, e.g. `/github setup announcement --channels town-square,engineering`.",
+		p.handleSetup(nil, args, []string{"announcement"}, nil))
+}
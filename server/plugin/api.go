@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -17,10 +18,11 @@ import (
 	"github.com/mattermost/mattermost-server/v5/plugin"
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-github/server/plugin/graphql"
 )
 
 const (
-	apiErrorIDNotConnected = "not_connected"
 	// TokenTTL is the OAuth token expiry duration in seconds
 	TokenTTL = 10 * 60
 )
@@ -31,7 +33,11 @@ type OAuthState struct {
 	PrivateAllowed bool   `json:"private_allowed"`
 }
 
+// APIErrorResponse is the body of the error responses of the API.
 type APIErrorResponse struct {
+	// ID identifies the kind of error for the webapp. It is one of the stable apiErrorID values,
+	// i.e. "not_connected", "github_not_found", "github_forbidden", "rate_limited" or "internal",
+	// or empty for errors of the request itself, e.g. a missing parameter.
 	ID         string `json:"id"`
 	Message    string `json:"message"`
 	StatusCode int    `json:"status_code"`
@@ -48,6 +54,8 @@ type PRDetails struct {
 	Mergeable          bool                        `json:"mergeable"`
 	RequestedReviewers []*string                   `json:"requestedReviewers"`
 	Reviews            []*github.PullRequestReview `json:"reviews"`
+	ReviewDecision     string                      `json:"reviewDecision,omitempty"`
+	Labels             []*github.Label             `json:"labels"`
 }
 
 // HTTPHandlerFuncWithUser is http.HandleFunc but userID is already exported
@@ -113,25 +121,49 @@ func (p *Plugin) initializeAPI() {
 	oauthRouter.HandleFunc("/complete", p.extractUserMiddleWare(p.completeConnectUserToGitHub, ResponseTypePlain)).Methods(http.MethodGet)
 
 	apiRouter.HandleFunc("/connected", p.getConnected).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/connect/token", p.extractUserMiddleWare(p.connectWithPersonalAccessToken, ResponseTypeJSON)).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/settings", p.getSettings).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/todo", p.extractUserMiddleWare(p.postToDo, ResponseTypeJSON)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/reviews", p.extractUserMiddleWare(p.getReviews, ResponseTypePlain)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/yourprs", p.extractUserMiddleWare(p.getYourPrs, ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/client_state", p.extractUserMiddleWare(p.getClientState, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/todo", p.extractUserMiddleWare(p.checkCommandEnabled("todo", p.postToDo), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/todo/action", p.extractUserMiddleWare(p.checkCommandEnabled("todo", p.handleToDoAction), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/todo/share", p.extractUserMiddleWare(p.checkCommandEnabled("todo", p.handleToDoShareAction), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/reviews", p.extractUserMiddleWare(p.trackLastSeen(p.getReviews), ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/yourprs", p.extractUserMiddleWare(p.trackLastSeen(p.getYourPrs), ResponseTypePlain)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/prsdetails", p.extractUserMiddleWare(p.getPrsDetails, ResponseTypePlain)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/prs/status", p.extractUserMiddleWare(p.getPullRequestsStatus, ResponseTypeJSON)).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/searchissues", p.extractUserMiddleWare(p.searchIssues, ResponseTypePlain)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/yourassignments", p.extractUserMiddleWare(p.getYourAssignments, ResponseTypePlain)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/createissue", p.extractUserMiddleWare(p.createIssue, ResponseTypePlain)).Methods(http.MethodPost)
-	apiRouter.HandleFunc("/createissuecomment", p.extractUserMiddleWare(p.createIssueComment, ResponseTypePlain)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/yourassignments", p.extractUserMiddleWare(p.trackLastSeen(p.getYourAssignments), ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/saved_searches", p.extractUserMiddleWare(p.checkCommandEnabled("search", p.getSavedSearchesList), ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/createissue", p.extractUserMiddleWare(p.checkCommandEnabled("issue", p.createIssue), ResponseTypePlain)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/creatediscussion", p.extractUserMiddleWare(p.checkCommandEnabled("issue", p.createDiscussion), ResponseTypePlain)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/issue_types", p.extractUserMiddleWare(p.getIssueTypes, ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/discussion_categories", p.extractUserMiddleWare(p.getDiscussionCategories, ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/createissuecomment", p.extractUserMiddleWare(p.checkCommandEnabled("issue", p.createIssueComment), ResponseTypePlain)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/attachedcomment", p.extractUserMiddleWare(p.checkCommandEnabled("issue", p.handleAttachedCommentAction), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/setup/subscribe", p.extractUserMiddleWare(p.handleSetupSubscribeAction, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/channel_suggestion", p.extractUserMiddleWare(p.handleChannelSuggestionAction, ResponseTypeJSON)).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/mentions", p.extractUserMiddleWare(p.getMentions, ResponseTypePlain)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/unreads", p.extractUserMiddleWare(p.getUnreads, ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/unreads", p.extractUserMiddleWare(p.trackLastSeen(p.getUnreads), ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/lhs-content", p.extractUserMiddleWare(p.trackLastSeen(p.getLHSContent), ResponseTypePlain)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/labels", p.extractUserMiddleWare(p.getLabels, ResponseTypePlain)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/milestones", p.extractUserMiddleWare(p.getMilestones, ResponseTypePlain)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/assignees", p.extractUserMiddleWare(p.getAssignees, ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/assignee_suggestions", p.extractUserMiddleWare(p.getAssigneeSuggestionsList, ResponseTypeJSON)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/repositories", p.extractUserMiddleWare(p.getRepositories, ResponseTypePlain)).Methods(http.MethodGet)
-	apiRouter.HandleFunc("/settings", p.extractUserMiddleWare(p.updateSettings, ResponseTypePlain)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/settings", p.extractUserMiddleWare(p.checkCommandEnabled("settings", p.updateSettings), ResponseTypePlain)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/me/activity", p.extractUserMiddleWare(p.checkCommandEnabled("me", p.getMyActivity), ResponseTypeJSON)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/user", p.extractUserMiddleWare(p.getGitHubUser, ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/resolve_users", p.extractUserMiddleWare(p.checkCommandEnabled("whois", p.resolveUsers), ResponseTypeJSON)).Methods(http.MethodPost)
 	apiRouter.HandleFunc("/issue", p.extractUserMiddleWare(p.getIssueByNumber, ResponseTypePlain)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/pr", p.extractUserMiddleWare(p.getPrByNumber, ResponseTypePlain)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/createpr", p.extractUserMiddleWare(p.checkCommandEnabled("pr", p.createPR), ResponseTypePlain)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/pr/reviewers", p.extractUserMiddleWare(p.checkCommandEnabled("reviewers", p.updatePrReviewers), ResponseTypeJSON)).Methods(http.MethodPost, http.MethodDelete)
+	apiRouter.HandleFunc("/admin/refresh", p.extractUserMiddleWare(p.checkCommandEnabled("admin", p.refreshAllUsers), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/username_mappings/sync", p.extractUserMiddleWare(p.checkCommandEnabled("admin", p.syncUsernames), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/subscriptions", p.extractUserMiddleWare(p.getAdminSubscriptions, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/status", p.extractUserMiddleWare(p.getAdminStatus, ResponseTypeJSON)).Methods(http.MethodGet)
+	apiRouter.HandleFunc("/admin/replay", p.extractUserMiddleWare(p.checkEventReplayAllowed(p.replayEvent), ResponseTypeJSON)).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/admin/recent_deliveries", p.extractUserMiddleWare(p.checkEventReplayAllowed(p.getRecentDeliveriesList), ResponseTypeJSON)).Methods(http.MethodGet)
 
 	apiRouter.HandleFunc("/config", checkPluginRequest(p.getConfig)).Methods(http.MethodGet)
 	apiRouter.HandleFunc("/token", checkPluginRequest(p.getToken)).Methods(http.MethodGet)
@@ -171,6 +203,18 @@ func (p *Plugin) extractUserMiddleWare(handler HTTPHandlerFuncWithUser, response
 	}
 }
 
+// checkCommandEnabled rejects requests to endpoints doing the same as a slash command disabled by the administrator.
+func (p *Plugin) checkCommandEnabled(command string, handler HTTPHandlerFuncWithUser) HTTPHandlerFuncWithUser {
+	return func(w http.ResponseWriter, r *http.Request, userID string) {
+		if p.getConfiguration().isCommandDisabled(command) {
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: disabledCommandMessage, StatusCode: http.StatusForbidden})
+			return
+		}
+
+		handler(w, r, userID)
+	}
+}
+
 func checkPluginRequest(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// All other plugins are allowed
@@ -219,6 +263,13 @@ func (p *Plugin) connectUserToGitHub(w http.ResponseWriter, r *http.Request, use
 		return
 	}
 
+	// A new attempt supersedes the previous one, e.g. after clicking connect again
+	if err = p.supersedeConnectAttempt(userID, state.Token); err != nil {
+		p.API.LogWarn("Failed to supersede previous connect attempt", "userID", userID, "error", err.Error())
+		http.Error(w, "error setting stored state", http.StatusInternalServerError)
+		return
+	}
+
 	appErr := p.API.KVSetWithExpiry(state.Token, stateBytes, TokenTTL)
 	if appErr != nil {
 		http.Error(w, "error setting stored state", http.StatusBadRequest)
@@ -246,6 +297,11 @@ func (p *Plugin) completeConnectUserToGitHub(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if storedState == nil {
+		http.Error(w, "This connection attempt expired or was replaced by a newer one. Please connect to GitHub again from Mattermost.", http.StatusBadRequest)
+		return
+	}
+
 	appErr = p.API.KVDelete(stateToken)
 	if appErr != nil {
 		p.API.LogWarn("Failed to delete state token", "error", appErr.Error())
@@ -269,6 +325,8 @@ func (p *Plugin) completeConnectUserToGitHub(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	p.finishConnectAttempt(state.UserID, stateToken)
+
 	ctx := context.Background()
 	conf := p.getOAuthConfig(state.PrivateAllowed)
 
@@ -287,70 +345,12 @@ func (p *Plugin) completeConnectUserToGitHub(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	userInfo := &GitHubUserInfo{
-		UserID:         state.UserID,
-		Token:          tok,
-		GitHubUsername: gitUser.GetLogin(),
-		LastToDoPostAt: model.GetMillis(),
-		Settings: &UserSettings{
-			SidebarButtons: settingButtonsTeam,
-			DailyReminder:  true,
-			Notifications:  true,
-		},
-		AllowedPrivateRepos: state.PrivateAllowed,
-	}
-
-	if err = p.storeGitHubUserInfo(userInfo); err != nil {
+	if _, err = p.connectGitHubAccount(ctx, state.UserID, tok, githubClient, gitUser, state.PrivateAllowed, connectionModeOAuth); err != nil {
 		p.API.LogWarn("Failed to store GitHub user info", "error", err.Error())
 		http.Error(w, "Unable to connect user to GitHub", http.StatusInternalServerError)
 		return
 	}
 
-	if err = p.storeGitHubToUserIDMapping(gitUser.GetLogin(), state.UserID); err != nil {
-		p.API.LogWarn("Failed to store GitHub user info mapping", "error", err.Error())
-	}
-
-	commandHelp, err := renderTemplate("helpText", p.getConfiguration())
-	if err != nil {
-		p.API.LogWarn("Failed to render help template", "error", err.Error())
-	}
-
-	// Post intro post
-	message := fmt.Sprintf("#### Welcome to the Mattermost GitHub Plugin!\n"+
-		"You've connected your Mattermost account to [%s](%s) on GitHub. Read about the features of this plugin below:\n\n"+
-		"##### Daily Reminders\n"+
-		"The first time you log in each day, you will get a post right here letting you know what messages you need to read and what pull requests are awaiting your review.\n"+
-		"Turn off reminders with `/github settings reminders off`.\n\n"+
-		"##### Notifications\n"+
-		"When someone mentions you, requests your review, comments on or modifies one of your pull requests/issues, or assigns you, you'll get a post here about it.\n"+
-		"Turn off notifications with `/github settings notifications off`.\n\n"+
-		"##### Sidebar Buttons\n"+
-		"Check out the buttons in the left-hand sidebar of Mattermost.\n"+
-		"* The first button tells you how many pull requests you have submitted.\n"+
-		"* The second shows the number of PR that are awaiting your review.\n"+
-		"* The third shows the number of PR and issues your are assiged to.\n"+
-		"* The fourth tracks the number of unread messages you have.\n"+
-		"* The fifth will refresh the numbers.\n\n"+
-		"Click on them!\n\n"+
-		"##### Slash Commands\n"+
-		commandHelp, gitUser.GetLogin(), gitUser.GetHTMLURL())
-
-	p.CreateBotDMPost(state.UserID, message, "custom_git_welcome")
-
-	config := p.getConfiguration()
-
-	p.API.PublishWebSocketEvent(
-		wsEventConnect,
-		map[string]interface{}{
-			"connected":           true,
-			"github_username":     userInfo.GitHubUsername,
-			"github_client_id":    config.GitHubOAuthClientID,
-			"enterprise_base_url": config.EnterpriseBaseURL,
-			"organization":        config.GitHubOrg,
-		},
-		&model.WebsocketBroadcast{UserId: state.UserID},
-	)
-
 	html := `
 			<!DOCTYPE html>
 			<html>
@@ -374,6 +374,20 @@ func (p *Plugin) completeConnectUserToGitHub(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// getOAuthRestrictionMessage explains a user how to get access to an organization with OAuth App access restrictions.
+func (p *Plugin) getOAuthRestrictionMessage(org string) string {
+	baseURL := strings.TrimSuffix(p.getBaseURL(), "/")
+	requestURL := fmt.Sprintf("%s/settings/connections/applications/%s", baseURL, p.getConfiguration().GitHubOAuthClientID)
+	approvalURL := fmt.Sprintf("%s/organizations/%s/settings/oauth_application_policy", baseURL, org)
+
+	return fmt.Sprintf("The **%s** organization has enabled [OAuth App access restrictions](%s), so the plugin can't access its repositories with your account. "+
+		"Until the plugin is approved, your sidebar and to do list won't show anything from this organization.\n"+
+		"* [Request approval](%s) of the plugin from the organization owners.\n"+
+		"* Organization owners can approve it in the [organization settings](%s).\n"+
+		"Once approved, reconnect your account with `/github disconnect` followed by `/github connect`.",
+		org, oauthAppRestrictionsDocsURL, requestURL, approvalURL)
+}
+
 func (p *Plugin) getGitHubUser(w http.ResponseWriter, r *http.Request, _ string) {
 	type GitHubUserRequest struct {
 		UserID string `json:"user_id"`
@@ -414,6 +428,82 @@ func (p *Plugin) getGitHubUser(w http.ResponseWriter, r *http.Request, _ string)
 	p.writeJSON(w, resp)
 }
 
+// connectGitHubAccount stores the token of a user who authenticated on GitHub, welcomes them and
+// tells their other sessions they are now connected.
+func (p *Plugin) connectGitHubAccount(ctx context.Context, userID string, tok *oauth2.Token, githubClient *github.Client, gitUser *github.User, privateAllowed bool, mode string) (*GitHubUserInfo, error) {
+	orgs := []string{}
+	if p.isOrganizationLocked() {
+		orgs = append(orgs, strings.TrimSpace(p.getConfiguration().GitHubOrg))
+	}
+	restrictedOrgs := findOAuthRestrictedOrgs(ctx, githubClient, orgs)
+
+	userInfo := &GitHubUserInfo{
+		UserID:         userID,
+		Token:          tok,
+		GitHubUsername: gitUser.GetLogin(),
+		LastToDoPostAt: model.GetMillis(),
+		Settings: &UserSettings{
+			SidebarButtons: settingButtonsTeam,
+			DailyReminder:  true,
+			Notifications:  true,
+		},
+		AllowedPrivateRepos: privateAllowed,
+		RestrictedOrgs:      restrictedOrgs,
+		ConnectionMode:      mode,
+	}
+	settingsRestored := p.restoreUserSettings(userInfo)
+
+	if err := p.storeGitHubUserInfo(userInfo); err != nil {
+		return nil, err
+	}
+
+	// Data prefetched for a previous connection may belong to another GitHub account
+	p.clearSidebarCache(userID)
+
+	if settingsRestored {
+		p.deleteUserSettingsBackup(userID)
+		p.addWeeklySummaryUser(userInfo)
+	}
+
+	// Connecting supersedes linking a username
+	if _, err := p.removeLinkedUsername(userID); err != nil {
+		p.API.LogWarn("Failed to remove linked username", "error", err.Error())
+	}
+
+	if err := p.storeGitHubToUserIDMapping(gitUser.GetLogin(), userID); err != nil {
+		p.API.LogWarn("Failed to store GitHub user info mapping", "error", err.Error())
+	}
+
+	restoredNotice := ""
+	if settingsRestored {
+		restoredNotice = "Your reminder, notification and sidebar settings from your previous connection have been restored.\n\n"
+	}
+
+	// Post intro post
+	message := p.getWelcomeMessage(gitUser.GetLogin(), gitUser.GetHTMLURL(), restoredNotice)
+	p.CreateBotDMPost(userID, message, "custom_git_welcome")
+
+	config := p.getConfiguration()
+
+	for _, org := range restrictedOrgs {
+		p.CreateBotDMPost(userID, p.getOAuthRestrictionMessage(org), "")
+	}
+
+	payload := config.ClientConfiguration()
+	payload["connected"] = true
+	payload["github_username"] = userInfo.GitHubUsername
+	payload["connection_mode"] = mode
+	payload["sidebar_button_list"] = userInfo.Settings.sidebarButtons()
+
+	p.API.PublishWebSocketEvent(
+		wsEventConnect,
+		payload,
+		&model.WebsocketBroadcast{UserId: userID},
+	)
+
+	return userInfo, nil
+}
+
 func (p *Plugin) getConnected(w http.ResponseWriter, r *http.Request) {
 	config := p.getConfiguration()
 
@@ -424,11 +514,17 @@ func (p *Plugin) getConnected(w http.ResponseWriter, r *http.Request) {
 		EnterpriseBaseURL string        `json:"enterprise_base_url,omitempty"`
 		Organization      string        `json:"organization"`
 		Settings          *UserSettings `json:"settings"`
+		RestrictedOrgs    []string      `json:"restricted_orgs,omitempty"`
+		ConnectionMode    string        `json:"connection_mode,omitempty"`
+		// SidebarButtonList is the parsed settings.sidebar_buttons, in the order they are shown
+		SidebarButtonList []string `json:"sidebar_button_list,omitempty"`
+		// RateLimits are only fetched on request, as the response is needed on every page load
+		RateLimits *RateLimitsStatus `json:"rate_limits,omitempty"`
 	}
 
 	resp := &ConnectedResponse{
 		Connected:         false,
-		EnterpriseBaseURL: config.EnterpriseBaseURL,
+		EnterpriseBaseURL: config.enterpriseWebURL(),
 		Organization:      config.GitHubOrg,
 	}
 
@@ -448,29 +544,52 @@ func (p *Plugin) getConnected(w http.ResponseWriter, r *http.Request) {
 	resp.GitHubUsername = info.GitHubUsername
 	resp.GitHubClientID = config.GitHubOAuthClientID
 	resp.Settings = info.Settings
+	resp.SidebarButtonList = info.Settings.sidebarButtons()
+	resp.RestrictedOrgs = info.RestrictedOrgs
+	resp.ConnectionMode = info.ConnectionMode
+	if resp.ConnectionMode == "" {
+		resp.ConnectionMode = connectionModeOAuth
+	}
+
+	if r.URL.Query().Get("include_rate") == "true" {
+		limits, err := getRateLimits(r.Context(), p.githubConnect(*info.Token))
+		if err != nil {
+			p.API.LogDebug("Failed to get rate limits", "userID", userID, "error", err.Error())
+		} else {
+			resp.RateLimits = limits
+		}
+	}
+
+	// The timezone is kept for the notifications, which aren't sent from the browser
+	storeInfo := false
+	if offset, err := strconv.Atoi(r.Header.Get("X-Timezone-Offset")); err == nil && offset != info.TimezoneOffset {
+		info.TimezoneOffset = offset
+		storeInfo = true
+	}
 
 	if info.Settings.DailyReminder && r.URL.Query().Get("reminder") == "true" {
 		lastPostAt := info.LastToDoPostAt
-
-		var timezone *time.Location
-		offset, _ := strconv.Atoi(r.Header.Get("X-Timezone-Offset"))
-		timezone = time.FixedZone("local", -60*offset)
+		timezone := timezoneFromOffset(info.TimezoneOffset)
 
 		// Post to do message if it's the next day and been more than an hour since the last post
 		now := model.GetMillis()
 		nt := time.Unix(now/1000, 0).In(timezone)
 		lt := time.Unix(lastPostAt/1000, 0).In(timezone)
 		if nt.Sub(lt).Hours() >= 1 && (nt.Day() != lt.Day() || nt.Month() != lt.Month() || nt.Year() != lt.Year()) {
-			if p.HasUnreads(info) {
+			if p.HasUnreads(r.Context(), info) {
 				p.PostToDo(info)
 				info.LastToDoPostAt = now
-				if err := p.storeGitHubUserInfo(info); err != nil {
-					p.API.LogWarn("Failed to store github info for new user", "userID", userID, "error", err.Error())
-				}
+				storeInfo = true
 			}
 		}
 	}
 
+	if storeInfo {
+		if err := p.storeGitHubUserInfo(info); err != nil {
+			p.API.LogWarn("Failed to store github info for new user", "userID", userID, "error", err.Error())
+		}
+	}
+
 	privateRepoStoreKey := info.UserID + githubPrivateRepoKey
 	if config.EnablePrivateRepo && !info.AllowedPrivateRepos {
 		val, err := p.API.KVGet(privateRepoStoreKey)
@@ -509,6 +628,7 @@ func (p *Plugin) getMentions(w http.ResponseWriter, r *http.Request, userID stri
 	result, _, err := githubClient.Search.Issues(context.Background(), query, &github.SearchOptions{})
 	if err != nil {
 		p.API.LogWarn("Failed to search for issues", "query", query, "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, "Failed to search for mentions."))
 		return
 	}
 
@@ -522,21 +642,64 @@ func (p *Plugin) getUnreads(w http.ResponseWriter, r *http.Request, userID strin
 		return
 	}
 
+	var since time.Time
+	if sinceParam := r.URL.Query().Get("since"); sinceParam != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, sinceParam)
+		if err != nil {
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a valid since time, e.g. 2020-09-14T09:00:00Z.", StatusCode: http.StatusBadRequest})
+			return
+		}
+	}
+
+	if since.IsZero() && p.writeCachedSidebarSection(w, r, userID, sidebarUnreads) {
+		return
+	}
+
 	githubClient := p.githubConnect(*info.Token)
 
-	notifications, _, err := githubClient.Activity.ListNotifications(context.Background(), &github.NotificationListOptions{})
+	filteredNotifications, err := p.getUnreadsData(r.Context(), githubClient, since)
 	if err != nil {
 		p.API.LogWarn("Failed to list notifications", "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, "Failed to fetch unread notifications."))
 		return
 	}
 
-	type filteredNotification struct {
-		github.Notification
+	p.writeJSON(w, filteredNotifications)
+}
+
+// FilteredNotification is an unread notification of a user, with a link to its subject.
+type FilteredNotification struct {
+	github.Notification
+
+	HTMLUrl            string `json:"html_url"`
+	RepositoryFullName string `json:"repository_full_name"`
+}
+
+// getUnreadsData returns the unread notifications of a user updated after since, if set. Notifications are
+// paged through up to the configured number of pages, and sorted by reason then by most recently updated.
+func (p *Plugin) getUnreadsData(ctx context.Context, githubClient *github.Client, since time.Time) ([]*FilteredNotification, error) {
+	opts := &github.NotificationListOptions{
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: 50},
+	}
+
+	var notifications []*github.Notification
+	for page := 0; page < p.getConfiguration().getUnreadsPageLimit(); page++ {
+		pageNotifications, resp, err := githubClient.Activity.ListNotifications(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
 
-		HTMLUrl string `json:"html_url"`
+		notifications = append(notifications, pageNotifications...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
-	filteredNotifications := []*filteredNotification{}
+	baseURL := p.getBaseURL()
+	filteredNotifications := []*FilteredNotification{}
 	for _, n := range notifications {
 		if n.GetReason() == notificationReasonSubscribed {
 			continue
@@ -554,57 +717,72 @@ func (p *Plugin) getUnreads(w http.ResponseWriter, r *http.Request, userID strin
 			subjectURL = n.GetSubject().GetLatestCommentURL()
 		}
 
-		filteredNotifications = append(filteredNotifications, &filteredNotification{
-			Notification: *n,
-			HTMLUrl:      fixGithubNotificationSubjectURL(subjectURL, issueNum),
+		filteredNotifications = append(filteredNotifications, &FilteredNotification{
+			Notification:       *n,
+			HTMLUrl:            fixGithubNotificationSubjectURL(baseURL, subjectURL, issueNum),
+			RepositoryFullName: n.GetRepository().GetFullName(),
 		})
 	}
 
-	p.writeJSON(w, filteredNotifications)
+	sort.SliceStable(filteredNotifications, func(i, j int) bool {
+		a, b := filteredNotifications[i], filteredNotifications[j]
+		if a.GetReason() != b.GetReason() {
+			return a.GetReason() < b.GetReason()
+		}
+		return a.GetUpdatedAt().After(b.GetUpdatedAt())
+	})
+
+	return filteredNotifications, nil
 }
 
 func (p *Plugin) getReviews(w http.ResponseWriter, r *http.Request, userID string) {
-	config := p.getConfiguration()
-
 	info, apiErr := p.getGitHubUserInfo(userID)
 	if apiErr != nil {
 		p.writeAPIError(w, apiErr)
 		return
 	}
 
+	// Prefetched data isn't filtered by label
+	label := r.URL.Query().Get("label")
+	if label == "" && p.writeCachedSidebarSection(w, r, userID, sidebarReviews) {
+		return
+	}
+
 	githubClient := p.githubConnect(*info.Token)
-	username := info.GitHubUsername
 
-	query := getReviewSearchQuery(username, config.GitHubOrg)
-	result, _, err := githubClient.Search.Issues(context.Background(), query, &github.SearchOptions{})
+	issues, _, err := p.fetchSidebarSection(r.Context(), githubClient, info.GitHubUsername, sidebarReviews, label)
 	if err != nil {
-		p.API.LogWarn("Failed to search for review", "query", query, "error", err.Error())
+		p.API.LogWarn("Failed to search for review", "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, "Failed to fetch review requests."))
 		return
 	}
 
-	p.writeJSON(w, result.Issues)
+	p.writeJSON(w, issues)
 }
 
 func (p *Plugin) getYourPrs(w http.ResponseWriter, r *http.Request, userID string) {
-	config := p.getConfiguration()
-
 	info, apiErr := p.getGitHubUserInfo(userID)
 	if apiErr != nil {
 		p.writeAPIError(w, apiErr)
 		return
 	}
 
+	// Prefetched data isn't filtered by label
+	label := r.URL.Query().Get("label")
+	if label == "" && p.writeCachedSidebarSection(w, r, userID, sidebarYourPrs) {
+		return
+	}
+
 	githubClient := p.githubConnect(*info.Token)
-	username := info.GitHubUsername
 
-	query := getYourPrsSearchQuery(username, config.GitHubOrg)
-	result, _, err := githubClient.Search.Issues(context.Background(), query, &github.SearchOptions{})
+	issues, _, err := p.fetchSidebarSection(r.Context(), githubClient, info.GitHubUsername, sidebarYourPrs, label)
 	if err != nil {
-		p.API.LogWarn("Failed to search for PRs", "query", query, "error", err.Error())
+		p.API.LogWarn("Failed to search for PRs", "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, "Failed to fetch pull requests."))
 		return
 	}
 
-	p.writeJSON(w, result.Issues)
+	p.writeJSON(w, issues)
 }
 
 func (p *Plugin) getPrsDetails(w http.ResponseWriter, r *http.Request, userID string) {
@@ -623,10 +801,39 @@ func (p *Plugin) getPrsDetails(w http.ResponseWriter, r *http.Request, userID st
 		return
 	}
 
+	prDetails := p.getPRsDetailsData(context.Background(), githubClient, prList)
+
+	p.writeJSON(w, prDetails)
+}
+
+// getPRsDetailsData fetches the details of pull requests with a single GraphQL query. The details of
+// the pull requests the query couldn't resolve are fetched with the REST API.
+func (p *Plugin) getPRsDetailsData(ctx context.Context, githubClient *github.Client, prList []*PRDetails) []*PRDetails {
+	refs := make([]graphql.PullRequestRef, len(prList))
+	for i, pr := range prList {
+		repoOwner, repoName := getRepoOwnerAndNameFromURL(pr.URL)
+		refs[i] = graphql.PullRequestRef{Owner: repoOwner, Repo: repoName, Number: pr.Number}
+	}
+
 	prDetails := make([]*PRDetails, len(prList))
-	ctx := context.Background()
+
+	details, err := graphql.NewClient(githubClient).GetPullRequestsDetails(ctx, refs)
+	if err != nil {
+		p.API.LogDebug("Failed to fetch PR details with GraphQL, falling back to the REST API", "error", err.Error())
+	} else {
+		for i, detail := range details {
+			if detail != nil {
+				prDetails[i] = newPRDetailsFromGraphQL(prList[i].URL, prList[i].Number, detail)
+			}
+		}
+	}
+
 	var wg sync.WaitGroup
 	for i, pr := range prList {
+		if prDetails[i] != nil {
+			continue
+		}
+
 		i := i
 		pr := pr
 		wg.Add(1)
@@ -639,7 +846,50 @@ func (p *Plugin) getPrsDetails(w http.ResponseWriter, r *http.Request, userID st
 
 	wg.Wait()
 
-	p.writeJSON(w, prDetails)
+	return prDetails
+}
+
+// newPRDetailsFromGraphQL converts the details fetched with GraphQL to the shape returned by the REST API.
+func newPRDetailsFromGraphQL(prURL string, prNumber int, details *graphql.PullRequestDetails) *PRDetails {
+	status := strings.ToLower(details.CheckState)
+	if status == "expected" {
+		status = "pending"
+	}
+
+	requestedReviewers := []*string{}
+	for _, reviewer := range details.RequestedReviewers {
+		requestedReviewers = append(requestedReviewers, github.String(reviewer))
+	}
+
+	reviews := []*github.PullRequestReview{}
+	for _, review := range details.LatestReviews {
+		submittedAt := review.SubmittedAt
+		reviews = append(reviews, &github.PullRequestReview{
+			ID:          github.Int64(review.ID),
+			User:        &github.User{Login: github.String(review.Author)},
+			Body:        github.String(review.Body),
+			State:       github.String(review.State),
+			SubmittedAt: &submittedAt,
+			HTMLURL:     github.String(review.URL),
+			CommitID:    github.String(review.CommitID),
+		})
+	}
+
+	labels := []*github.Label{}
+	for _, label := range details.Labels {
+		labels = append(labels, &github.Label{Name: github.String(label.Name), Color: github.String(label.Color)})
+	}
+
+	return &PRDetails{
+		URL:                prURL,
+		Number:             prNumber,
+		Status:             status,
+		Mergeable:          details.Mergeable == "MERGEABLE",
+		RequestedReviewers: requestedReviewers,
+		Reviews:            reviews,
+		ReviewDecision:     details.ReviewDecision,
+		Labels:             labels,
+	}
 }
 
 func (p *Plugin) fetchPRDetails(ctx context.Context, client *github.Client, prURL string, prNumber int) *PRDetails {
@@ -648,6 +898,7 @@ func (p *Plugin) fetchPRDetails(ctx context.Context, client *github.Client, prUR
 	// Initialize to a non-nil slice to simplify JSON handling semantics
 	requestedReviewers := []*string{}
 	var reviewsList []*github.PullRequestReview = []*github.PullRequestReview{}
+	labels := []*github.Label{}
 
 	repoOwner, repoName := getRepoOwnerAndNameFromURL(prURL)
 
@@ -676,6 +927,7 @@ func (p *Plugin) fetchPRDetails(ctx context.Context, client *github.Client, prUR
 		}
 
 		mergeable = prInfo.GetMergeable()
+		labels = capLabels(prInfo.Labels)
 
 		for _, v := range prInfo.RequestedReviewers {
 			requestedReviewers = append(requestedReviewers, v.Login)
@@ -696,6 +948,7 @@ func (p *Plugin) fetchPRDetails(ctx context.Context, client *github.Client, prUR
 		Mergeable:          mergeable,
 		RequestedReviewers: requestedReviewers,
 		Reviews:            reviewsList,
+		Labels:             labels,
 	}
 }
 
@@ -709,6 +962,19 @@ func fetchReviews(ctx context.Context, client *github.Client, repoOwner string,
 	return reviewsList, nil
 }
 
+// capLabels keeps the first labels of a pull request or issue, so many labels don't bloat the
+// payloads of the sidebar.
+func capLabels(labels []*github.Label) []*github.Label {
+	if len(labels) > graphql.MaxLabels {
+		return labels[:graphql.MaxLabels]
+	}
+	if labels == nil {
+		return []*github.Label{}
+	}
+
+	return labels
+}
+
 func getRepoOwnerAndNameFromURL(url string) (string, string) {
 	splitted := strings.Split(url, "/")
 	return splitted[len(splitted)-2], splitted[len(splitted)-1]
@@ -726,10 +992,12 @@ func (p *Plugin) searchIssues(w http.ResponseWriter, r *http.Request, userID str
 	githubClient := p.githubConnect(*info.Token)
 
 	searchTerm := r.FormValue("term")
-	query := getIssuesSearchQuery(config.GitHubOrg, searchTerm)
+	includePullRequests := r.FormValue("include_prs") == "true"
+	query := getIssuesSearchQuery(config.GitHubOrg, searchTerm, includePullRequests)
 	result, _, err := githubClient.Search.Issues(context.Background(), query, &github.SearchOptions{})
 	if err != nil {
 		p.API.LogWarn("Failed to search for issues", "query", query, "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, "Failed to search for issues."))
 		return
 	}
 
@@ -737,28 +1005,24 @@ func (p *Plugin) searchIssues(w http.ResponseWriter, r *http.Request, userID str
 }
 
 func (p *Plugin) getPermaLink(postID string) string {
-	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	permalink, err := p.buildPermaLink(postID)
+	if err != nil {
+		// A relative link still works from within Mattermost
+		return fmt.Sprintf("/_redirect/pl/%v", postID)
+	}
 
-	return fmt.Sprintf("%v/_redirect/pl/%v", siteURL, postID)
+	return permalink
 }
 
-func getFailReason(code int, repo string, username string) string {
-	cause := ""
-	switch code {
-	case http.StatusInternalServerError:
-		cause = "Internal server error"
-	case http.StatusBadRequest:
-		cause = "Bad request"
-	case http.StatusNotFound:
-		cause = fmt.Sprintf("Sorry, either you don't have access to the repo %s with the user %s or it is no longer available", repo, username)
-	case http.StatusUnauthorized:
-		cause = fmt.Sprintf("Sorry, your user %s is unauthorized to do this action", username)
-	case http.StatusForbidden:
-		cause = fmt.Sprintf("Sorry, you don't have enough permissions to comment in the repo %s with the user %s", repo, username)
-	default:
-		cause = fmt.Sprintf("Unknown status code %d", code)
-	}
-	return cause
+// buildPermaLink returns the permalink of a post. It fails when the Site URL of the server isn't
+// set, as links posted on GitHub must be absolute.
+func (p *Plugin) buildPermaLink(postID string) (string, error) {
+	config := p.API.GetConfig()
+	if config == nil || config.ServiceSettings.SiteURL == nil || *config.ServiceSettings.SiteURL == "" {
+		return "", errors.New("the Site URL of the server is not set")
+	}
+
+	return fmt.Sprintf("%v/_redirect/pl/%v", strings.TrimSuffix(*config.ServiceSettings.SiteURL, "/"), postID), nil
 }
 
 func (p *Plugin) createIssueComment(w http.ResponseWriter, r *http.Request, userID string) {
@@ -768,6 +1032,13 @@ func (p *Plugin) createIssueComment(w http.ResponseWriter, r *http.Request, user
 		Repo    string `json:"repo"`
 		Number  int    `json:"number"`
 		Comment string `json:"comment"`
+		// ExpectedState is the state the issue or pull request must be in, if set.
+		ExpectedState string `json:"expected_state"`
+	}
+
+	type CreateIssueCommentResponse struct {
+		*github.IssueComment
+		IsPullRequest bool `json:"is_pull_request"`
 	}
 
 	req := &CreateIssueCommentRequest{}
@@ -802,13 +1073,43 @@ func (p *Plugin) createIssueComment(w http.ResponseWriter, r *http.Request, user
 		return
 	}
 
+	if req.ExpectedState != "" && req.ExpectedState != "open" && req.ExpectedState != "closed" {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a valid expected state: open or closed.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
 	info, apiErr := p.getGitHubUserInfo(userID)
 	if apiErr != nil {
 		p.writeAPIError(w, apiErr)
 		return
 	}
 
-	githubClient := p.githubConnect(*info.Token)
+	githubClient, err := p.getGithubClientFor(info, githubWrite)
+	if err != nil {
+		p.writeAPIError(w, newAPIError(err, "Failed to create the comment."))
+		return
+	}
+
+	// Pull requests accept issue comments too, so both are fetched as issues. The comment is posted
+	// to the conversation of a pull request, not as a review comment.
+	issue, _, err := githubClient.Issues.Get(context.Background(), req.Owner, req.Repo, req.Number)
+	if err != nil {
+		apiErr := newAPIError(err, "Failed to get the issue or pull request.")
+		if apiErr.ID == apiErrorIDGitHubNotFound {
+			apiErr.Message = fmt.Sprintf("There is no issue or pull request #%d in %s.", req.Number, fullNameFromOwnerAndRepo(req.Owner, req.Repo))
+		} else {
+			p.API.LogWarn("Failed to get issue", "repo", fullNameFromOwnerAndRepo(req.Owner, req.Repo), "number", req.Number, "error", err.Error())
+		}
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	target := &attachedComment{Owner: req.Owner, Repo: req.Repo, Number: req.Number, IsPullRequest: issue.IsPullRequest()}
+
+	if req.ExpectedState != "" && issue.GetState() != req.ExpectedState {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: fmt.Sprintf("The %s #%d is %s.", target.targetName(), req.Number, issue.GetState()), StatusCode: http.StatusConflict})
+		return
+	}
 
 	post, appErr := p.API.GetPost(req.PostID)
 	if appErr != nil {
@@ -827,21 +1128,21 @@ func (p *Plugin) createIssueComment(w http.ResponseWriter, r *http.Request, user
 	}
 
 	currentUsername := info.GitHubUsername
-	permalink := p.getPermaLink(req.PostID)
-	permalinkMessage := fmt.Sprintf("*@%s attached a* [message](%s) *from %s*\n\n", currentUsername, permalink, commentUsername)
+	// The comment is created without the link to the message rather than losing it
+	permalink, err := p.buildPermaLink(req.PostID)
+	if err != nil {
+		p.API.LogWarn("Failed to build the permalink of an attached message", "postID", req.PostID, "error", err.Error())
+	}
 
-	req.Comment = permalinkMessage + req.Comment
+	req.Comment = formatAttachedCommentBody(currentUsername, permalink, commentUsername, req.Comment)
 	comment := &github.IssueComment{
 		Body: &req.Comment,
 	}
 
-	result, rawResponse, err := githubClient.Issues.CreateComment(context.Background(), req.Owner, req.Repo, req.Number, comment)
+	result, _, err := githubClient.Issues.CreateComment(context.Background(), req.Owner, req.Repo, req.Number, comment)
 	if err != nil {
-		statusCode := 500
-		if rawResponse != nil {
-			statusCode = rawResponse.StatusCode
-		}
-		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to create an issue comment: " + getFailReason(statusCode, req.Repo, currentUsername), StatusCode: statusCode})
+		p.API.LogWarn("Failed to create issue comment", "repo", fullNameFromOwnerAndRepo(req.Owner, req.Repo), "number", req.Number, "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, fmt.Sprintf("Failed to comment on %s #%d.", target.targetName(), req.Number)))
 		return
 	}
 
@@ -851,7 +1152,10 @@ func (p *Plugin) createIssueComment(w http.ResponseWriter, r *http.Request, user
 		rootID = post.RootId
 	}
 
-	permalinkReplyMessage := fmt.Sprintf("[Message](%v) attached to GitHub issue [#%v](%v)", permalink, req.Number, result.GetHTMLURL())
+	permalinkReplyMessage := fmt.Sprintf("[Message](%v) attached to GitHub %s [#%v](%v)", permalink, target.targetName(), req.Number, result.GetHTMLURL())
+	if permalink == "" {
+		permalinkReplyMessage = fmt.Sprintf("Message attached to GitHub %s [#%v](%v). The comment doesn't link back to the message, as its link couldn't be generated.", target.targetName(), req.Number, result.GetHTMLURL())
+	}
 	reply := &model.Post{
 		Message:   permalinkReplyMessage,
 		ChannelId: post.ChannelId,
@@ -860,34 +1164,40 @@ func (p *Plugin) createIssueComment(w http.ResponseWriter, r *http.Request, user
 		UserId:    userID,
 	}
 
-	_, appErr = p.API.CreatePost(reply)
+	target.AttachedPostID = req.PostID
+	target.UserID = userID
+	target.CommentID = result.GetID()
+	appErr = p.createAttachedCommentReply(reply, target)
 	if appErr != nil {
 		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to create notification post " + req.PostID, StatusCode: http.StatusInternalServerError})
 		return
 	}
+	p.trackSelfEvent(selfEventIssueComment, result.GetHTMLURL(), currentUsername, post.ChannelId)
 
-	p.writeJSON(w, result)
+	p.writeJSON(w, &CreateIssueCommentResponse{IssueComment: result, IsPullRequest: target.IsPullRequest})
 }
 
 func (p *Plugin) getYourAssignments(w http.ResponseWriter, r *http.Request, userID string) {
-	config := p.getConfiguration()
-
 	info, apiErr := p.getGitHubUserInfo(userID)
 	if apiErr != nil {
 		p.writeAPIError(w, apiErr)
 		return
 	}
+
+	if p.writeCachedSidebarSection(w, r, userID, sidebarAssignments) {
+		return
+	}
+
 	githubClient := p.githubConnect(*info.Token)
 
-	username := info.GitHubUsername
-	query := getYourAssigneeSearchQuery(username, config.GitHubOrg)
-	result, _, err := githubClient.Search.Issues(context.Background(), query, &github.SearchOptions{})
+	issues, _, err := p.fetchSidebarSection(r.Context(), githubClient, info.GitHubUsername, sidebarAssignments, "")
 	if err != nil {
-		p.API.LogWarn("Failed to search for assignments", "query", query, "error", err.Error())
+		p.API.LogWarn("Failed to search for assignments", "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, "Failed to fetch assignments."))
 		return
 	}
 
-	p.writeJSON(w, result.Issues)
+	p.writeJSON(w, issues)
 }
 
 func (p *Plugin) postToDo(w http.ResponseWriter, r *http.Request, userID string) {
@@ -897,18 +1207,12 @@ func (p *Plugin) postToDo(w http.ResponseWriter, r *http.Request, userID string)
 		return
 	}
 
-	githubClient := p.githubConnect(*info.Token)
-	username := info.GitHubUsername
-
-	text, err := p.GetToDo(context.Background(), username, githubClient)
-	if err != nil {
+	if err := p.postToDoDigest(r.Context(), info); err != nil {
 		p.API.LogWarn("Failed to get Todos", "error", err.Error())
-		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Encountered an error getting the to do items.", StatusCode: http.StatusUnauthorized})
+		p.writeAPIError(w, newAPIError(err, "Encountered an error getting the to do items."))
 		return
 	}
 
-	p.CreateBotDMPost(userID, text, "custom_git_todo")
-
 	resp := struct {
 		Status string
 	}{"OK"}
@@ -936,6 +1240,12 @@ func (p *Plugin) updateSettings(w http.ResponseWriter, r *http.Request, userID s
 		return
 	}
 
+	buttons, parseErr := parseSidebarButtons(settings.SidebarButtons)
+	if parseErr != nil {
+		http.Error(w, parseErr.Error(), http.StatusBadRequest)
+		return
+	}
+
 	info.Settings = settings
 
 	if err := p.storeGitHubUserInfo(info); err != nil {
@@ -944,7 +1254,13 @@ func (p *Plugin) updateSettings(w http.ResponseWriter, r *http.Request, userID s
 		return
 	}
 
-	p.writeJSON(w, info.Settings)
+	p.sendSidebarButtonsEvent(userID, buttons)
+	p.addWeeklySummaryUser(info)
+
+	p.writeJSON(w, struct {
+		*UserSettings
+		SidebarButtonList []string `json:"sidebar_button_list"`
+	}{info.Settings, buttons})
 }
 
 func (p *Plugin) getIssueByNumber(w http.ResponseWriter, r *http.Request, userID string) {
@@ -968,21 +1284,32 @@ func (p *Plugin) getIssueByNumber(w http.ResponseWriter, r *http.Request, userID
 	if err != nil {
 		// If the issue is not found, it's probably behind a private repo.
 		// Return an empty repose in this case.
-		var gerr *github.ErrorResponse
-		if errors.As(err, &gerr) && gerr.Response.StatusCode == http.StatusNotFound {
+		apiErr := newAPIError(err, "Could not get issue.")
+		if apiErr.ID == apiErrorIDGitHubNotFound {
 			p.API.LogDebug("Issue not found", "owner", owner, "repo", repo, "number", numberInt)
 			p.writeJSON(w, nil)
 			return
 		}
 
 		p.API.LogDebug("Could not get issue", "owner", owner, "repo", repo, "number", numberInt, "error", err.Error())
-		p.writeAPIError(w, &APIErrorResponse{Message: "Could not get issue", StatusCode: http.StatusInternalServerError})
+		p.writeAPIError(w, apiErr)
 		return
 	}
 	if result.Body != nil {
-		*result.Body = mdCommentRegex.ReplaceAllString(result.GetBody(), "")
+		*result.Body = sanitizeMarkdown(result.GetBody(), result.GetHTMLURL())
 	}
-	p.writeJSON(w, result)
+
+	issue := &issueWithReferences{Issue: result}
+
+	// The linked pull requests are a nice-to-have, e.g. the token may lack access to the timeline.
+	linked, err := listLinkedPullRequests(context.Background(), githubClient, owner, repo, numberInt)
+	if err != nil {
+		p.API.LogDebug("Could not get linked pull requests", "owner", owner, "repo", repo, "number", numberInt, "error", err.Error())
+	} else {
+		issue.LinkedPullRequests = linked
+	}
+
+	p.writeJSON(w, issue)
 }
 
 func (p *Plugin) getPrByNumber(w http.ResponseWriter, r *http.Request, userID string) {
@@ -1007,21 +1334,35 @@ func (p *Plugin) getPrByNumber(w http.ResponseWriter, r *http.Request, userID st
 	if err != nil {
 		// If the pull request is not found, it's probably behind a private repo.
 		// Return an empty repose in this case.
-		var gerr *github.ErrorResponse
-		if errors.As(err, &gerr) && gerr.Response.StatusCode == http.StatusNotFound {
+		apiErr := newAPIError(err, "Could not get pull request.")
+		if apiErr.ID == apiErrorIDGitHubNotFound {
 			p.API.LogDebug("Pull request not found", "owner", owner, "repo", repo, "number", numberInt)
 			p.writeJSON(w, nil)
 			return
 		}
 
 		p.API.LogDebug("Could not get pull request", "owner", owner, "repo", repo, "number", numberInt, "error", err.Error())
-		p.writeAPIError(w, &APIErrorResponse{Message: "Could not get pull request", StatusCode: http.StatusInternalServerError})
+		p.writeAPIError(w, apiErr)
 		return
 	}
 	if result.Body != nil {
-		*result.Body = mdCommentRegex.ReplaceAllString(result.GetBody(), "")
+		*result.Body = sanitizeMarkdown(result.GetBody(), result.GetHTMLURL())
 	}
-	p.writeJSON(w, result)
+
+	pr := &pullRequestWithReferences{
+		PullRequest:      result,
+		ReferencedIssues: parseClosingReferences(result.GetBody(), owner, repo),
+	}
+
+	// Reactions are only returned with the issue of a pull request, and are a nice-to-have.
+	issue, _, err := githubClient.Issues.Get(context.Background(), owner, repo, numberInt)
+	if err != nil {
+		p.API.LogDebug("Could not get pull request reactions", "owner", owner, "repo", repo, "number", numberInt, "error", err.Error())
+	} else {
+		pr.Reactions = issue.Reactions
+	}
+
+	p.writeJSON(w, pr)
 }
 
 func (p *Plugin) getLabels(w http.ResponseWriter, r *http.Request, userID string) {
@@ -1045,7 +1386,7 @@ func (p *Plugin) getLabels(w http.ResponseWriter, r *http.Request, userID string
 		labels, resp, err := githubClient.Issues.ListLabels(context.Background(), owner, repo, &opt)
 		if err != nil {
 			p.API.LogWarn("Failed to list labels", "error", err.Error())
-			p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch labels", StatusCode: http.StatusInternalServerError})
+			p.writeAPIError(w, newAPIError(err, "Failed to fetch labels."))
 			return
 		}
 		allLabels = append(allLabels, labels...)
@@ -1071,22 +1412,11 @@ func (p *Plugin) getAssignees(w http.ResponseWriter, r *http.Request, userID str
 		return
 	}
 
-	githubClient := p.githubConnect(*info.Token)
-	var allAssignees []*github.User
-	opt := github.ListOptions{PerPage: 50}
-
-	for {
-		assignees, resp, err := githubClient.Issues.ListAssignees(context.Background(), owner, repo, &opt)
-		if err != nil {
-			p.API.LogWarn("Failed to list assignees", "error", err.Error())
-			p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch assignees", StatusCode: http.StatusInternalServerError})
-			return
-		}
-		allAssignees = append(allAssignees, assignees...)
-		if resp.NextPage == 0 {
-			break
-		}
-		opt.Page = resp.NextPage
+	allAssignees, err := listAssignees(context.Background(), p.githubConnect(*info.Token), owner, repo)
+	if err != nil {
+		p.API.LogWarn("Failed to list assignees", "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, "Failed to fetch assignees."))
+		return
 	}
 
 	p.writeJSON(w, allAssignees)
@@ -1113,7 +1443,7 @@ func (p *Plugin) getMilestones(w http.ResponseWriter, r *http.Request, userID st
 		milestones, resp, err := githubClient.Issues.ListMilestones(context.Background(), owner, repo, &github.MilestoneListOptions{ListOptions: opt})
 		if err != nil {
 			p.API.LogWarn("Failed to list milestones", "error", err.Error())
-			p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch milestones", StatusCode: http.StatusInternalServerError})
+			p.writeAPIError(w, newAPIError(err, "Failed to fetch milestones."))
 			return
 		}
 		allMilestones = append(allMilestones, milestones...)
@@ -1135,8 +1465,34 @@ func (p *Plugin) getRepositories(w http.ResponseWriter, r *http.Request, userID
 
 	githubClient := p.githubConnect(*info.Token)
 
-	ctx := context.Background()
-	org := p.getConfiguration().GitHubOrg
+	allRepos, listErr := p.getRepositoriesData(context.Background(), githubClient)
+	if listErr != nil {
+		p.API.LogWarn("Failed to list repositories", "error", listErr.Error())
+		p.writeAPIError(w, newAPIError(listErr, "Failed to fetch repositories."))
+		return
+	}
+
+	// Only send down fields to client that are needed
+	type RepositoryResponse struct {
+		Name        string          `json:"name,omitempty"`
+		FullName    string          `json:"full_name,omitempty"`
+		Permissions map[string]bool `json:"permissions,omitempty"`
+	}
+
+	resp := make([]RepositoryResponse, len(allRepos))
+	for i, r := range allRepos {
+		resp[i].Name = r.GetName()
+		resp[i].FullName = r.GetFullName()
+		resp[i].Permissions = r.GetPermissions()
+	}
+
+	p.writeJSON(w, resp)
+}
+
+// getRepositoriesData lists the repositories of the locked organization if any, otherwise those of the user.
+func (p *Plugin) getRepositoriesData(ctx context.Context, githubClient *github.Client) ([]*github.Repository, error) {
+	config := p.getConfiguration()
+	org := config.GitHubOrg
 
 	var allRepos []*github.Repository
 	opt := github.ListOptions{PerPage: 50}
@@ -1145,9 +1501,7 @@ func (p *Plugin) getRepositories(w http.ResponseWriter, r *http.Request, userID
 		for {
 			repos, resp, err := githubClient.Repositories.List(ctx, "", &github.RepositoryListOptions{ListOptions: opt})
 			if err != nil {
-				p.API.LogWarn("Failed to list repositories", "error", err.Error())
-				p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch repositories", StatusCode: http.StatusInternalServerError})
-				return
+				return nil, errors.Wrap(err, "failed to list repositories")
 			}
 			allRepos = append(allRepos, repos...)
 			if resp.NextPage == 0 {
@@ -1159,9 +1513,7 @@ func (p *Plugin) getRepositories(w http.ResponseWriter, r *http.Request, userID
 		for {
 			repos, resp, err := githubClient.Repositories.ListByOrg(ctx, org, &github.RepositoryListByOrgOptions{Sort: "full_name", ListOptions: opt})
 			if err != nil {
-				p.API.LogWarn("Failed to list repositories by org", "error", err.Error())
-				p.writeAPIError(w, &APIErrorResponse{Message: "Failed to fetch repositories", StatusCode: http.StatusInternalServerError})
-				return
+				return nil, errors.Wrap(err, "failed to list repositories by org")
 			}
 			allRepos = append(allRepos, repos...)
 			if resp.NextPage == 0 {
@@ -1171,21 +1523,19 @@ func (p *Plugin) getRepositories(w http.ResponseWriter, r *http.Request, userID
 		}
 	}
 
-	// Only send down fields to client that are needed
-	type RepositoryResponse struct {
-		Name        string          `json:"name,omitempty"`
-		FullName    string          `json:"full_name,omitempty"`
-		Permissions map[string]bool `json:"permissions,omitempty"`
+	if !config.isIssueCreationRestrictedToOrg() {
+		return allRepos, nil
 	}
 
-	resp := make([]RepositoryResponse, len(allRepos))
-	for i, r := range allRepos {
-		resp[i].Name = r.GetName()
-		resp[i].FullName = r.GetFullName()
-		resp[i].Permissions = r.GetPermissions()
+	// Never offer repositories outside of the organization, whatever GitHub listed
+	orgRepos := []*github.Repository{}
+	for _, repo := range allRepos {
+		if isRepoInOrg(repo.GetFullName(), org) {
+			orgRepos = append(orgRepos, repo)
+		}
 	}
 
-	p.writeJSON(w, resp)
+	return orgRepos, nil
 }
 
 func (p *Plugin) createIssue(w http.ResponseWriter, r *http.Request, userID string) {
@@ -1198,6 +1548,12 @@ func (p *Plugin) createIssue(w http.ResponseWriter, r *http.Request, userID stri
 		Labels    []string `json:"labels"`
 		Assignees []string `json:"assignees"`
 		Milestone int      `json:"milestone"`
+		// IssueType is the name of an issue type of the repository, e.g. Bug.
+		IssueType string `json:"issue_type"`
+		// Project is the number of a project of the repository owner to add the issue to.
+		Project int `json:"project"`
+		// ProjectFields are the values of fields of the project, keyed by field name.
+		ProjectFields map[string]string `json:"project_fields"`
 	}
 
 	// get data for the issue from the request body and fill IssueRequest object
@@ -1218,11 +1574,21 @@ func (p *Plugin) createIssue(w http.ResponseWriter, r *http.Request, userID stri
 		return
 	}
 
+	if config := p.getConfiguration(); config.isIssueCreationRestrictedToOrg() && !isRepoInOrg(issue.Repo, config.GitHubOrg) {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: fmt.Sprintf("Issues can only be created in repositories of the %s organization.", strings.TrimSpace(config.GitHubOrg)), StatusCode: http.StatusForbidden})
+		return
+	}
+
 	if issue.PostID == "" && issue.ChannelID == "" {
 		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide either a postID or a channelID", StatusCode: http.StatusBadRequest})
 		return
 	}
 
+	if len(issue.ProjectFields) > 0 && issue.Project <= 0 {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide the project to set the fields of.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
 	// Make sure user has a connected github account
 	info, apiErr := p.getGitHubUserInfo(userID)
 	if apiErr != nil {
@@ -1251,9 +1617,13 @@ func (p *Plugin) createIssue(w http.ResponseWriter, r *http.Request, userID stri
 			return
 		}
 
-		permalink = p.getPermaLink(issue.PostID)
-
-		mmMessage = fmt.Sprintf("_Issue created from a [Mattermost message](%v) *by %s*._", permalink, username)
+		// The issue is created without the footer rather than losing it
+		permalink, err = p.buildPermaLink(issue.PostID)
+		if err != nil {
+			p.API.LogWarn("Failed to build the permalink of the message of a new issue", "postID", issue.PostID, "error", err.Error())
+		} else {
+			mmMessage = fmt.Sprintf("_Issue created from a [Mattermost message](%v) *by %s*._", permalink, username)
+		}
 	}
 
 	ghIssue := &github.IssueRequest{
@@ -1274,33 +1644,25 @@ func (p *Plugin) createIssue(w http.ResponseWriter, r *http.Request, userID stri
 	}
 	*ghIssue.Body = ghIssue.GetBody() + mmMessage
 
-	currentUser, appErr := p.API.GetUser(userID)
-	if appErr != nil {
-		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to load current user", StatusCode: http.StatusInternalServerError})
-		return
-	}
-
 	splittedRepo := strings.Split(issue.Repo, "/")
 	owner := splittedRepo[0]
 	repoName := splittedRepo[1]
 
-	githubClient := p.githubConnect(*info.Token)
-	result, resp, err := githubClient.Issues.Create(context.Background(), owner, repoName, ghIssue)
+	githubClient, err := p.getGithubClientFor(info, githubWrite)
+	if err != nil {
+		p.writeAPIError(w, newAPIError(err, "Failed to create the issue."))
+		return
+	}
+
+	result, _, err := githubClient.Issues.Create(context.Background(), owner, repoName, ghIssue)
 	if err != nil {
 		p.API.LogWarn("Failed to create issue", "error", err.Error())
-		if resp != nil && resp.Response.StatusCode == http.StatusGone {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusGone {
 			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Issues are disabled on this repository.", StatusCode: http.StatusMethodNotAllowed})
 			return
 		}
-		p.writeAPIError(w,
-			&APIErrorResponse{
-				ID: "",
-				Message: "failed to create issue: " + getFailReason(resp.StatusCode,
-					issue.Repo,
-					currentUser.Username,
-				),
-				StatusCode: resp.StatusCode,
-			})
+		p.writeAPIError(w, newAPIError(err, fmt.Sprintf("Failed to create the issue in %s.", issue.Repo)))
 		return
 	}
 
@@ -1312,7 +1674,16 @@ func (p *Plugin) createIssue(w http.ResponseWriter, r *http.Request, userID stri
 			rootID = post.RootId
 		}
 		channelID = post.ChannelId
-		message += fmt.Sprintf(" from a [message](%s)", permalink)
+		if permalink != "" {
+			message += fmt.Sprintf(" from a [message](%s)", permalink)
+		} else {
+			message += ". The issue doesn't link back to the message, as its link couldn't be generated."
+		}
+	}
+
+	// Issue types and project fields can only be set with the GraphQL API
+	for _, failure := range p.setIssueFields(r.Context(), graphql.NewClient(githubClient), owner, repoName, result, issue.IssueType, issue.Project, issue.ProjectFields) {
+		message += "\n" + failure
 	}
 
 	reply := &model.Post{
@@ -1323,6 +1694,7 @@ func (p *Plugin) createIssue(w http.ResponseWriter, r *http.Request, userID stri
 		UserId:    userID,
 	}
 
+	var appErr *model.AppError
 	if post != nil {
 		_, appErr = p.API.CreatePost(reply)
 	} else {
@@ -1333,12 +1705,148 @@ func (p *Plugin) createIssue(w http.ResponseWriter, r *http.Request, userID stri
 		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to create notification post, postID: " + issue.PostID + ", channelID: " + channelID, StatusCode: http.StatusInternalServerError})
 		return
 	}
+	if post != nil {
+		p.trackSelfEvent(selfEventIssueOpened, result.GetHTMLURL(), info.GitHubUsername, channelID)
+	}
 
 	p.writeJSON(w, result)
 }
 
+// getClientState returns the client configuration along with the repositories the given channel is subscribed to.
+func (p *Plugin) getClientState(w http.ResponseWriter, r *http.Request, userID string) {
+	state := p.getConfiguration().ClientConfiguration()
+
+	channelID := r.URL.Query().Get("channelId")
+	if channelID == "" {
+		p.writeJSON(w, state)
+		return
+	}
+
+	if !p.API.HasPermissionToChannel(userID, channelID, model.PERMISSION_READ_CHANNEL) {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Not authorized to read the channel.", StatusCode: http.StatusForbidden})
+		return
+	}
+
+	subs, err := p.GetSubscriptionsByChannel(channelID)
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions for channel", "channelID", channelID, "error", err.Error())
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to get subscriptions.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	repositories := []string{}
+	for _, sub := range subs {
+		repositories = append(repositories, sub.Repository)
+	}
+	state["subscriptions"] = repositories
+
+	p.writeJSON(w, state)
+}
+
+func (p *Plugin) getMyActivity(w http.ResponseWriter, r *http.Request, userID string) {
+	days, err := parseActivityDays(r.URL.Query().Get("days"))
+	if err != nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	activity, err := p.getUserActivity(r.Context(), p.githubConnect(*info.Token), info.GitHubUsername, p.getConfiguration().GitHubOrg, days, time.Now())
+	if err != nil {
+		p.API.LogWarn("Failed to get user activity", "userID", userID, "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, "Failed to get GitHub activity."))
+		return
+	}
+
+	p.writeJSON(w, activity)
+}
+
+// updatePrReviewers requests reviews from the given reviewers on POST, and removes them on DELETE.
+func (p *Plugin) updatePrReviewers(w http.ResponseWriter, r *http.Request, userID string) {
+	type UpdateReviewersRequest struct {
+		Owner     string   `json:"owner"`
+		Repo      string   `json:"repo"`
+		Number    int      `json:"number"`
+		Reviewers []string `json:"reviewers"`
+	}
+
+	req := &UpdateReviewersRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.API.LogWarn("Error decoding UpdateReviewersRequest JSON body", "error", err.Error())
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a JSON object.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if req.Owner == "" || req.Repo == "" {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a valid repo.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if req.Number <= 0 {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a valid pull request number.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	reviewers := p.resolveGitHubUsernames(req.Reviewers)
+	if len(reviewers) == 0 {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide at least one reviewer.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	githubClient, err := p.getGithubClientFor(info, githubWrite)
+	if err != nil {
+		p.writeAPIError(w, newAPIError(err, "Failed to update reviewers."))
+		return
+	}
+
+	update, err := updateReviewers(r.Context(), githubClient, req.Owner, req.Repo, req.Number, reviewers, r.Method == http.MethodDelete)
+	if err != nil {
+		p.API.LogWarn("Failed to update reviewers", "repo", fullNameFromOwnerAndRepo(req.Owner, req.Repo), "number", req.Number, "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, "Failed to update reviewers."))
+		return
+	}
+
+	p.writeJSON(w, update)
+}
+
+// checkSecretsRequest tells if a plugin request asks for secrets, and if the plugin is trusted
+// with them. It writes an error if the plugin isn't.
+func (p *Plugin) checkSecretsRequest(w http.ResponseWriter, r *http.Request) (includeSecrets bool, ok bool) {
+	if r.URL.Query().Get("include_secrets") != "true" {
+		return false, true
+	}
+
+	pluginID := r.Header.Get("Mattermost-Plugin-ID")
+	if !p.getConfiguration().isPluginTrusted(pluginID) {
+		p.API.LogWarn("Denied secrets to a plugin that isn't trusted", "pluginID", pluginID, "url", r.URL.Path)
+		http.Error(w, "Not authorized to get secrets", http.StatusForbidden)
+		return false, false
+	}
+
+	return true, true
+}
+
 func (p *Plugin) getConfig(w http.ResponseWriter, r *http.Request) {
+	includeSecrets, ok := p.checkSecretsRequest(w, r)
+	if !ok {
+		return
+	}
+
 	config := p.getConfiguration()
+	if !includeSecrets {
+		config = config.Sanitized()
+	}
 
 	p.writeJSON(w, config)
 }
@@ -1350,13 +1858,28 @@ func (p *Plugin) getToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	includeSecrets, ok := p.checkSecretsRequest(w, r)
+	if !ok {
+		return
+	}
+
 	info, apiErr := p.getGitHubUserInfo(userID)
 	if apiErr != nil {
 		http.Error(w, apiErr.Error(), apiErr.StatusCode)
 		return
 	}
 
-	p.writeJSON(w, info.Token)
+	token := info.Token
+	if !includeSecrets {
+		token = &oauth2.Token{
+			AccessToken:  maskSecret(token.AccessToken),
+			TokenType:    token.TokenType,
+			RefreshToken: maskSecret(token.RefreshToken),
+			Expiry:       token.Expiry,
+		}
+	}
+
+	p.writeJSON(w, token)
 }
 
 // parseRepo parses the owner & repository name from the repo query parameter
@@ -1380,3 +1903,51 @@ func (p *Plugin) getSettings(w http.ResponseWriter, _ *http.Request) {
 
 	p.writeJSON(w, resp)
 }
+
+func (p *Plugin) refreshAllUsers(w http.ResponseWriter, r *http.Request, userID string) {
+	if !p.isSystemAdmin(userID) {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Only system administrators can refresh the sidebars of all users.", StatusCode: http.StatusForbidden})
+		return
+	}
+
+	state, err := p.startRefreshAll(userID)
+	if err == errRefreshAllRunning {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "A refresh of all sidebars is already running.", StatusCode: http.StatusConflict})
+		return
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to start refreshing all sidebars", "error", err.Error())
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to start refreshing all sidebars.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, state)
+}
+
+func (p *Plugin) syncUsernames(w http.ResponseWriter, r *http.Request, userID string) {
+	if !p.isSystemAdmin(userID) {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Only system administrators can sync usernames.", StatusCode: http.StatusForbidden})
+		return
+	}
+
+	org := strings.TrimSpace(p.getConfiguration().GitHubOrg)
+	if org == "" {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Usernames can only be synced when the plugin is locked to an organization.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	result, err := p.syncUsernameMappings(r.Context(), p.githubConnect(*info.Token), org)
+	if err != nil {
+		p.API.LogWarn("Failed to sync usernames", "org", org, "error", err.Error())
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to sync usernames.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, result)
+}
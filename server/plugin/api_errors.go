@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"net/http"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+// The IDs of the errors returned by the API. The webapp tells errors apart by them, so they must
+// not change.
+const (
+	// apiErrorIDNotConnected is returned when the user hasn't connected a GitHub account, or GitHub
+	// rejects the token of the account, e.g. because it was revoked.
+	apiErrorIDNotConnected = "not_connected"
+	// apiErrorIDGitHubNotFound is returned when GitHub can't find a resource, or the user can't see it.
+	apiErrorIDGitHubNotFound = "github_not_found"
+	// apiErrorIDGitHubForbidden is returned when the user isn't allowed to do something on GitHub.
+	apiErrorIDGitHubForbidden = "github_forbidden"
+	// apiErrorIDRateLimited is returned when the GitHub rate limit of the user is exhausted.
+	apiErrorIDRateLimited = "rate_limited"
+	// apiErrorIDReadOnlyMode is returned when the user turned on read-only mode and asked for a change on GitHub.
+	apiErrorIDReadOnlyMode = "read_only_mode"
+	// apiErrorIDInternal is returned for any other failure, e.g. GitHub being unreachable.
+	apiErrorIDInternal = "internal"
+)
+
+// The classes of errors the API maps to responses. Errors wrapping them, and errors of the GitHub
+// client, are mapped by newAPIError.
+var (
+	ErrNotConnected    = errors.New("not connected to GitHub")
+	ErrGitHubNotFound  = errors.New("not found on GitHub")
+	ErrGitHubForbidden = errors.New("forbidden by GitHub")
+	ErrRateLimited     = errors.New("GitHub rate limit exceeded")
+	ErrReadOnlyMode    = errors.New("read-only mode is on")
+	ErrInternal        = errors.New("internal error")
+)
+
+// classifyAPIError returns the class of an error, inspecting the responses of GitHub it wraps.
+func classifyAPIError(err error) error {
+	for _, class := range []error{ErrNotConnected, ErrGitHubNotFound, ErrGitHubForbidden, ErrRateLimited, ErrReadOnlyMode, ErrInternal} {
+		if errors.Is(err, class) {
+			return class
+		}
+	}
+
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr) {
+		return ErrRateLimited
+	}
+
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil {
+		switch errResp.Response.StatusCode {
+		case http.StatusUnauthorized:
+			return ErrNotConnected
+		case http.StatusForbidden:
+			return ErrGitHubForbidden
+		case http.StatusNotFound, http.StatusGone:
+			return ErrGitHubNotFound
+		}
+	}
+
+	return ErrInternal
+}
+
+// newAPIError maps an error to a response of the API. message tells what failed, e.g. "Failed to
+// fetch labels.", and is followed by the cause for errors the user can act on. Responses wrapped
+// by err are returned as they are.
+func newAPIError(err error, message string) *APIErrorResponse {
+	var apiErr *APIErrorResponse
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	switch classifyAPIError(err) {
+	case ErrNotConnected:
+		return &APIErrorResponse{ID: apiErrorIDNotConnected, Message: "Must connect user account to GitHub first.", StatusCode: http.StatusUnauthorized}
+	case ErrGitHubNotFound:
+		return &APIErrorResponse{ID: apiErrorIDGitHubNotFound, Message: message + " GitHub couldn't find it, or your GitHub account can't access it.", StatusCode: http.StatusNotFound}
+	case ErrGitHubForbidden:
+		return &APIErrorResponse{ID: apiErrorIDGitHubForbidden, Message: message + " Your GitHub account doesn't have the permission to do this.", StatusCode: http.StatusForbidden}
+	case ErrRateLimited:
+		return &APIErrorResponse{ID: apiErrorIDRateLimited, Message: message + " The GitHub rate limit was exceeded, please try again later.", StatusCode: http.StatusTooManyRequests}
+	case ErrReadOnlyMode:
+		return &APIErrorResponse{ID: apiErrorIDReadOnlyMode, Message: readOnlyModeMessage, StatusCode: http.StatusForbidden}
+	default:
+		return &APIErrorResponse{ID: apiErrorIDInternal, Message: message, StatusCode: http.StatusInternalServerError}
+	}
+}
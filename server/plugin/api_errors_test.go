@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func githubErrorResponse(statusCode int) *github.ErrorResponse {
+	return &github.ErrorResponse{Response: &http.Response{StatusCode: statusCode, Request: httptest.NewRequest(http.MethodGet, "/", nil)}}
+}
+
+func TestNewAPIError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err        error
+		id         string
+		statusCode int
+		message    string
+	}{
+		"not connected": {
+			err:        ErrNotConnected,
+			id:         "not_connected",
+			statusCode: http.StatusUnauthorized,
+			message:    "Must connect user account to GitHub first.",
+		},
+		"revoked token": {
+			err:        githubErrorResponse(http.StatusUnauthorized),
+			id:         "not_connected",
+			statusCode: http.StatusUnauthorized,
+			message:    "Must connect user account to GitHub first.",
+		},
+		"not found": {
+			err:        githubErrorResponse(http.StatusNotFound),
+			id:         "github_not_found",
+			statusCode: http.StatusNotFound,
+			message:    "Failed to fetch labels. GitHub couldn't find it, or your GitHub account can't access it.",
+		},
+		"gone": {
+			err:        githubErrorResponse(http.StatusGone),
+			id:         "github_not_found",
+			statusCode: http.StatusNotFound,
+		},
+		"wrapped not found": {
+			err:        errors.Wrap(githubErrorResponse(http.StatusNotFound), "could not list labels"),
+			id:         "github_not_found",
+			statusCode: http.StatusNotFound,
+		},
+		"forbidden": {
+			err:        githubErrorResponse(http.StatusForbidden),
+			id:         "github_forbidden",
+			statusCode: http.StatusForbidden,
+			message:    "Failed to fetch labels. Your GitHub account doesn't have the permission to do this.",
+		},
+		"wrapped forbidden class": {
+			err:        errors.Wrap(ErrGitHubForbidden, "team reviewers"),
+			id:         "github_forbidden",
+			statusCode: http.StatusForbidden,
+		},
+		"rate limited": {
+			err:        &github.RateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}},
+			id:         "rate_limited",
+			statusCode: http.StatusTooManyRequests,
+			message:    "Failed to fetch labels. The GitHub rate limit was exceeded, please try again later.",
+		},
+		"abuse rate limited": {
+			err:        errors.Wrap(&github.AbuseRateLimitError{Response: &http.Response{StatusCode: http.StatusForbidden}}, "search"),
+			id:         "rate_limited",
+			statusCode: http.StatusTooManyRequests,
+		},
+		"read-only mode": {
+			err:        ErrReadOnlyMode,
+			id:         "read_only_mode",
+			statusCode: http.StatusForbidden,
+			message:    readOnlyModeMessage,
+		},
+		"validation failed": {
+			err:        githubErrorResponse(http.StatusUnprocessableEntity),
+			id:         "internal",
+			statusCode: http.StatusInternalServerError,
+			message:    "Failed to fetch labels.",
+		},
+		"github down": {
+			err:        githubErrorResponse(http.StatusBadGateway),
+			id:         "internal",
+			statusCode: http.StatusInternalServerError,
+		},
+		"network failure": {
+			err:        errors.New("dial tcp: connection refused"),
+			id:         "internal",
+			statusCode: http.StatusInternalServerError,
+			message:    "Failed to fetch labels.",
+		},
+		"api error": {
+			err:        errors.Wrap(&APIErrorResponse{ID: "", Message: "Invalid repository.", StatusCode: http.StatusBadRequest}, "labels"),
+			id:         "",
+			statusCode: http.StatusBadRequest,
+			message:    "Invalid repository.",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			apiErr := newAPIError(tc.err, "Failed to fetch labels.")
+			assert.Equal(t, tc.id, apiErr.ID)
+			assert.Equal(t, tc.statusCode, apiErr.StatusCode)
+			if tc.message != "" {
+				assert.Equal(t, tc.message, apiErr.Message)
+			}
+		})
+	}
+}
+
+func TestAPIErrorResponses(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	status := http.StatusNotFound
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	setup := func() *Plugin {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		api.On("KVGet", "otherID"+githubTokenKey).Return(nil, nil)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+		return p
+	}
+
+	t.Run("not connected", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setup().getLabels(w, httptest.NewRequest(http.MethodGet, "/api/v1/labels?repo=owner/repo", nil), "otherID")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), `"id":"not_connected"`)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		status = http.StatusNotFound
+		w := httptest.NewRecorder()
+		setup().getLabels(w, httptest.NewRequest(http.MethodGet, "/api/v1/labels?repo=owner/repo", nil), "userID")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), `"id":"github_not_found"`)
+	})
+
+	t.Run("to do failure isn't unauthorized", func(t *testing.T) {
+		status = http.StatusBadGateway
+		w := httptest.NewRecorder()
+		setup().postToDo(w, httptest.NewRequest(http.MethodPost, "/api/v1/todo", nil), "userID")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), `"id":"internal"`)
+	})
+
+	t.Run("search failure is reported", func(t *testing.T) {
+		status = http.StatusForbidden
+		w := httptest.NewRecorder()
+		setup().searchIssues(w, httptest.NewRequest(http.MethodGet, "/api/v1/searchissues?term=bug", nil), "userID")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), `"id":"github_forbidden"`)
+	})
+}
@@ -1,16 +1,25 @@
 package plugin
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
 	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
 
 	"github.com/mattermost/mattermost-plugin-github/server/testutils"
 )
@@ -173,6 +182,55 @@ func TestGetToken(t *testing.T) {
 		})
 	}
 }
+func TestGetTokenSecrets(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	accessToken, err := encrypt([]byte(encryptionKey), "accessToken")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: accessToken, TokenType: "bearer"}})
+	require.NoError(t, err)
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, TrustedPluginIDs: "trustedPluginId"})
+	api := &plugintest.API{}
+	api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+	api.On("LogWarn", "Denied secrets to a plugin that isn't trusted", "pluginID", "somePluginId", "url", "/api/v1/token")
+	p.SetAPI(api)
+
+	getToken := func(pluginID, url string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.Header.Set("Mattermost-Plugin-ID", pluginID)
+		w := httptest.NewRecorder()
+		p.getToken(w, r)
+		return w
+	}
+
+	t.Run("masked", func(t *testing.T) {
+		w := getToken("somePluginId", "/api/v1/token?userID=userID")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		token := &oauth2.Token{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), token))
+		assert.Equal(t, maskedSecret, token.AccessToken)
+		assert.Equal(t, "bearer", token.TokenType)
+		assert.Empty(t, token.RefreshToken)
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		w := getToken("trustedPluginId", "/api/v1/token?userID=userID&include_secrets=true")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		token := &oauth2.Token{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), token))
+		assert.Equal(t, "accessToken", token.AccessToken)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		w := getToken("somePluginId", "/api/v1/token?userID=userID&include_secrets=true")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.NotContains(t, w.Body.String(), "accessToken")
+	})
+}
+
 func TestGetConfig(t *testing.T) {
 	httpTestJSON := testutils.HTTPTest{
 		T:       t,
@@ -189,6 +247,7 @@ func TestGetConfig(t *testing.T) {
 		GitHubOAuthClientID:     "mockID",
 		GitHubOAuthClientSecret: "mockSecret",
 		EncryptionKey:           "mockKey",
+		TrustedPluginIDs:        "otherPluginId, trustedPluginId",
 	}
 
 	for name, test := range map[string]struct {
@@ -218,11 +277,43 @@ func TestGetConfig(t *testing.T) {
 				Body:   nil,
 			},
 			context: &plugin.Context{SourcePluginId: "somePluginId"},
+			expectedResponse: testutils.ExpectedResponse{
+				StatusCode:   http.StatusOK,
+				ResponseType: testutils.ContentTypeJSON,
+				Body: &Configuration{
+					GitHubOrg:               "mockOrg",
+					GitHubOAuthClientID:     "mockID",
+					GitHubOAuthClientSecret: maskedSecret,
+					EncryptionKey:           maskedSecret,
+					TrustedPluginIDs:        "otherPluginId, trustedPluginId",
+				},
+			},
+		}, "secrets for a trusted plugin": {
+			httpTest: httpTestJSON,
+			request: testutils.Request{
+				Method: http.MethodGet,
+				URL:    "/api/v1/config?include_secrets=true",
+				Body:   nil,
+			},
+			context: &plugin.Context{SourcePluginId: "trustedPluginId"},
 			expectedResponse: testutils.ExpectedResponse{
 				StatusCode:   http.StatusOK,
 				ResponseType: testutils.ContentTypeJSON,
 				Body:         config,
 			},
+		}, "secrets denied": {
+			httpTest: httpTestString,
+			request: testutils.Request{
+				Method: http.MethodGet,
+				URL:    "/api/v1/config?include_secrets=true",
+				Body:   nil,
+			},
+			context: &plugin.Context{SourcePluginId: "somePluginId"},
+			expectedResponse: testutils.ExpectedResponse{
+				StatusCode:   http.StatusForbidden,
+				ResponseType: testutils.ContentTypePlain,
+				Body:         "Not authorized to get secrets\n",
+			},
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
@@ -230,7 +321,9 @@ func TestGetConfig(t *testing.T) {
 			p.setConfiguration(config)
 			p.initializeAPI()
 
-			p.SetAPI(&plugintest.API{})
+			api := &plugintest.API{}
+			api.On("LogWarn", "Denied secrets to a plugin that isn't trusted", "pluginID", "somePluginId", "url", "/api/v1/config").Maybe()
+			p.SetAPI(api)
 
 			req := test.httpTest.CreateHTTPRequest(test.request)
 			rr := httptest.NewRecorder()
@@ -241,3 +334,463 @@ func TestGetConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestGetClientState(t *testing.T) {
+	config := &Configuration{
+		GitHubOrg:               "mockOrg",
+		GitHubOAuthClientID:     "mockID",
+		GitHubOAuthClientSecret: "mockSecret",
+		WebhookSecret:           "mockWebhookSecret",
+		EncryptionKey:           "mockKey",
+	}
+
+	subs, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo":  {{ChannelID: "channelID", Repository: "owner/repo"}},
+		"owner/other": {{ChannelID: "otherChannelID", Repository: "owner/other"}},
+	}})
+	require.NoError(t, err)
+
+	for name, test := range map[string]struct {
+		url                   string
+		canReadChannel        bool
+		expectedStatusCode    int
+		expectedSubscriptions interface{}
+	}{
+		"without channel": {
+			url:                "/api/v1/client_state",
+			expectedStatusCode: http.StatusOK,
+		},
+		"with channel": {
+			url:                   "/api/v1/client_state?channelId=channelID",
+			canReadChannel:        true,
+			expectedStatusCode:    http.StatusOK,
+			expectedSubscriptions: []interface{}{"owner/repo"},
+		},
+		"without permission to the channel": {
+			url:                "/api/v1/client_state?channelId=channelID",
+			expectedStatusCode: http.StatusForbidden,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := NewPlugin()
+			p.setConfiguration(config)
+			p.initializeAPI()
+
+			api := &plugintest.API{}
+			api.On("HasPermissionToChannel", "userID", "channelID", model.PERMISSION_READ_CHANNEL).Return(test.canReadChannel)
+			api.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+			api.On("KVGet", SubscriptionsKey).Return(subs, nil)
+			p.SetAPI(api)
+
+			req := httptest.NewRequest(http.MethodGet, test.url, nil)
+			req.Header.Set("Mattermost-User-ID", "userID")
+			w := httptest.NewRecorder()
+			p.ServeHTTP(&plugin.Context{}, w, req)
+
+			require.Equal(t, test.expectedStatusCode, w.Code)
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var state map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &state))
+			assert.Equal(t, "mockOrg", state["organization"])
+			assert.Equal(t, false, state["restrict_issue_creation_to_org"])
+			assert.Equal(t, "mockID", state["github_client_id"])
+			assert.Equal(t, test.expectedSubscriptions, state["subscriptions"])
+			assert.NotContains(t, w.Body.String(), "mockSecret")
+			assert.NotContains(t, w.Body.String(), "mockKey")
+		})
+	}
+}
+
+func TestGetUnreadsData(t *testing.T) {
+	var requestedPages []string
+	var since string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		requestedPages = append(requestedPages, page)
+		since = r.URL.Query().Get("since")
+
+		switch page {
+		case "1":
+			w.Header().Set("Link", `<https://api.github.com/notifications?page=2>; rel="next"`)
+			fmt.Fprint(w, `[
+				{"id": "1", "reason": "mention", "updated_at": "2020-09-14T09:00:00Z", "repository": {"full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"url": "https://api.github.com/repos/owner/repo/issues/1"}},
+				{"id": "2", "reason": "subscribed", "updated_at": "2020-09-14T09:00:00Z", "repository": {"full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"url": "https://api.github.com/repos/owner/repo/issues/2"}}
+			]`)
+		case "2":
+			w.Header().Set("Link", `<https://api.github.com/notifications?page=3>; rel="next"`)
+			fmt.Fprint(w, `[
+				{"id": "3", "reason": "assign", "updated_at": "2020-09-12T09:00:00Z", "repository": {"full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"url": "https://api.github.com/repos/owner/repo/issues/3"}},
+				{"id": "4", "reason": "mention", "updated_at": "2020-09-15T09:00:00Z", "repository": {"full_name": "owner/other", "owner": {"login": "owner"}}, "subject": {"url": "https://api.github.com/repos/owner/other/pulls/4"}}
+			]`)
+		case "3":
+			fmt.Fprint(w, `[
+				{"id": "5", "reason": "assign", "updated_at": "2020-09-13T09:00:00Z", "repository": {"full_name": "owner/repo", "owner": {"login": "owner"}}, "subject": {"url": "https://api.github.com/repos/owner/repo/issues/5"}}
+			]`)
+		}
+	})
+
+	client := newTestGitHubClient(t, mux)
+
+	notificationIDs := func(notifications []*FilteredNotification) []string {
+		ids := []string{}
+		for _, n := range notifications {
+			ids = append(ids, n.GetID())
+		}
+		return ids
+	}
+
+	t.Run("multiple pages", func(t *testing.T) {
+		requestedPages = nil
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{})
+
+		notifications, err := p.getUnreadsData(context.Background(), client, time.Time{})
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"1", "2", "3"}, requestedPages)
+		assert.Equal(t, []string{"5", "3", "4", "1"}, notificationIDs(notifications))
+		assert.Equal(t, "owner/other", notifications[2].RepositoryFullName)
+		assert.Equal(t, "https://github.com/owner/other/pull/4", notifications[2].HTMLUrl)
+		assert.Empty(t, since)
+	})
+
+	t.Run("page limit", func(t *testing.T) {
+		requestedPages = nil
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{UnreadsPageLimit: 2})
+
+		notifications, err := p.getUnreadsData(context.Background(), client, time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"1", "2"}, requestedPages)
+		assert.Equal(t, []string{"3", "4", "1"}, notificationIDs(notifications))
+		assert.Equal(t, "2020-09-01T00:00:00Z", since)
+	})
+}
+
+func TestCheckCommandEnabled(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{DisabledCommands: "issue"})
+	p.SetAPI(&plugintest.API{})
+
+	called := false
+	handler := func(w http.ResponseWriter, r *http.Request, userID string) {
+		called = true
+	}
+
+	w := httptest.NewRecorder()
+	p.checkCommandEnabled("issue", handler)(w, httptest.NewRequest(http.MethodPost, "/api/v1/createissue", nil), "userID")
+	assert.False(t, called)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = httptest.NewRecorder()
+	p.checkCommandEnabled("todo", handler)(w, httptest.NewRequest(http.MethodPost, "/api/v1/todo", nil), "userID")
+	assert.True(t, called)
+}
+
+func TestDisabledCommandRoutes(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{
+		GitHubOAuthClientID:     "mockID",
+		GitHubOAuthClientSecret: "mockSecret",
+		EncryptionKey:           "mockKey",
+		DisabledCommands:        "todo",
+	})
+	p.initializeAPI()
+	p.SetAPI(&plugintest.API{})
+
+	for _, url := range []string{"/api/v1/todo", "/api/v1/todo/action", "/api/v1/todo/share"} {
+		req := httptest.NewRequest(http.MethodPost, url, nil)
+		req.Header.Set("Mattermost-User-ID", "userID")
+		w := httptest.NewRecorder()
+		p.ServeHTTP(&plugin.Context{}, w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code, url)
+	}
+}
+
+func TestGetPRsDetailsData(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": {
+			"pr0": {"pullRequest": {
+				"mergeable": "CONFLICTING",
+				"reviewDecision": "CHANGES_REQUESTED",
+				"reviewRequests": {"nodes": [{"requestedReviewer": {"login": "alice"}}]},
+				"latestReviews": {"nodes": [{"databaseId": 10, "author": {"login": "bob"}, "state": "CHANGES_REQUESTED"}]},
+				"commits": {"nodes": [{"commit": {"statusCheckRollup": {"state": "EXPECTED"}}}]},
+				"labels": {"nodes": [{"name": "blocked", "color": "b60205"}]}
+			}},
+			"pr1": {"pullRequest": null}
+		}, "errors": [{"type": "NOT_FOUND", "path": ["pr1", "pullRequest"], "message": "Could not resolve to a PullRequest"}]}`)
+	})
+	mux.HandleFunc("/repos/owner/repo/pulls/2", func(w http.ResponseWriter, r *http.Request) {
+		labels := []string{}
+		for i := 0; i < 12; i++ {
+			labels = append(labels, fmt.Sprintf(`{"name": "label%d", "color": "ededed"}`, i))
+		}
+		fmt.Fprintf(w, `{"number": 2, "mergeable": true, "head": {"sha": "abc"}, "labels": [%s]}`, strings.Join(labels, ", "))
+	})
+	mux.HandleFunc("/repos/owner/repo/pulls/2/reviews", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/repos/owner/repo/commits/abc/status", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"state": "success"}`)
+	})
+	githubClient := newTestGitHubClient(t, mux)
+
+	p := NewPlugin()
+	prList := []*PRDetails{
+		{URL: "https://github.com/owner/repo", Number: 1},
+		{URL: "https://github.com/owner/repo", Number: 2},
+	}
+
+	prDetails := p.getPRsDetailsData(context.Background(), githubClient, prList)
+	require.Len(t, prDetails, 2)
+
+	assert.Equal(t, "pending", prDetails[0].Status)
+	assert.False(t, prDetails[0].Mergeable)
+	assert.Equal(t, "CHANGES_REQUESTED", prDetails[0].ReviewDecision)
+	require.Len(t, prDetails[0].RequestedReviewers, 1)
+	assert.Equal(t, "alice", *prDetails[0].RequestedReviewers[0])
+	require.Len(t, prDetails[0].Reviews, 1)
+	assert.Equal(t, "bob", prDetails[0].Reviews[0].GetUser().GetLogin())
+	assert.Equal(t, "CHANGES_REQUESTED", prDetails[0].Reviews[0].GetState())
+	require.Len(t, prDetails[0].Labels, 1)
+	assert.Equal(t, "blocked", prDetails[0].Labels[0].GetName())
+	assert.Equal(t, "b60205", prDetails[0].Labels[0].GetColor())
+
+	assert.Equal(t, "success", prDetails[1].Status)
+	assert.True(t, prDetails[1].Mergeable)
+	assert.Empty(t, prDetails[1].RequestedReviewers)
+	assert.Empty(t, prDetails[1].Reviews)
+	require.Len(t, prDetails[1].Labels, 10)
+	assert.Equal(t, "label9", prDetails[1].Labels[9].GetName())
+}
+
+func TestGetRepositoriesData(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/Mattermost/repos", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"full_name": "mattermost/server"}, {"full_name": "someone/transferred"}]`)
+	})
+	githubClient := newTestGitHubClient(t, mux)
+
+	t.Run("not restricted", func(t *testing.T) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{GitHubOrg: "Mattermost"})
+
+		repos, err := p.getRepositoriesData(context.Background(), githubClient)
+		require.NoError(t, err)
+		assert.Len(t, repos, 2)
+	})
+
+	t.Run("restricted to the organization", func(t *testing.T) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{GitHubOrg: "Mattermost", RestrictIssueCreationToOrg: true})
+
+		repos, err := p.getRepositoriesData(context.Background(), githubClient)
+		require.NoError(t, err)
+		require.Len(t, repos, 1)
+		assert.Equal(t, "mattermost/server", repos[0].GetFullName())
+	})
+}
+
+func TestCreateIssueRestrictedToOrg(t *testing.T) {
+	createIssue := func(config *Configuration, repo string) *httptest.ResponseRecorder {
+		p := NewPlugin()
+		p.setConfiguration(config)
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(nil, nil)
+		p.SetAPI(api)
+
+		w := httptest.NewRecorder()
+		body := strings.NewReader(fmt.Sprintf(`{"title": "Title", "repo": %q, "channel_id": "channelID"}`, repo))
+		p.createIssue(w, httptest.NewRequest(http.MethodPost, "/api/v1/createissue", body), "userID")
+		return w
+	}
+
+	t.Run("repository outside of the organization", func(t *testing.T) {
+		w := createIssue(&Configuration{GitHubOrg: "mattermost", RestrictIssueCreationToOrg: true}, "someone/repo")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "Issues can only be created in repositories of the mattermost organization.")
+	})
+
+	// Not being connected is the next check
+	t.Run("repository of the organization", func(t *testing.T) {
+		w := createIssue(&Configuration{GitHubOrg: "mattermost", RestrictIssueCreationToOrg: true}, "MatterMost/repo")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Must connect user account to GitHub first.")
+	})
+
+	t.Run("not restricted", func(t *testing.T) {
+		w := createIssue(&Configuration{GitHubOrg: "mattermost"}, "someone/repo")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestCreateIssuePermalink(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice"})
+	require.NoError(t, err)
+
+	issueBody := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v3/repos/owner/repo/issues" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var issue github.IssueRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&issue))
+		issueBody = issue.GetBody()
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number": 7, "html_url": "https://github.com/owner/repo/issues/7"}`)
+	}))
+	defer ts.Close()
+
+	createIssue := func(siteURL string) (*httptest.ResponseRecorder, *string) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+		reply := new(string)
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), []byte("1"), int64(selfEventTTL)).Return(nil)
+		api.On("GetPost", "postID").Return(&model.Post{Id: "postID", UserId: "userID", ChannelId: "channelID"}, nil)
+		api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			*reply = post.Message
+			return post
+		}, nil)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		w := httptest.NewRecorder()
+		body := strings.NewReader(`{"title": "Title", "body": "Steps to reproduce", "repo": "owner/repo", "post_id": "postID"}`)
+		p.createIssue(w, httptest.NewRequest(http.MethodPost, "/api/v1/createissue", body), "userID")
+		return w, reply
+	}
+
+	t.Run("with permalink", func(t *testing.T) {
+		w, reply := createIssue("https://mattermost.example.com")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"number":7`)
+		assert.Equal(t, "Steps to reproduce\n\n_Issue created from a [Mattermost message](https://mattermost.example.com/_redirect/pl/postID) *by @alice*._", issueBody)
+		assert.Equal(t, "Created GitHub issue [#7](https://github.com/owner/repo/issues/7) from a [message](https://mattermost.example.com/_redirect/pl/postID)", *reply)
+	})
+
+	t.Run("permalink failure", func(t *testing.T) {
+		w, reply := createIssue("")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"number":7`)
+		assert.Equal(t, "Steps to reproduce", issueBody)
+		assert.Equal(t, "Created GitHub issue [#7](https://github.com/owner/repo/issues/7). The issue doesn't link back to the message, as its link couldn't be generated.", *reply)
+	})
+}
+
+func TestCreateIssueComment(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice"})
+	require.NoError(t, err)
+
+	commentBody := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/owner/repo/issues/12":
+			fmt.Fprint(w, `{"number": 12, "state": "open"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/owner/repo/issues/34":
+			fmt.Fprint(w, `{"number": 34, "state": "closed", "pull_request": {"url": "https://api.github.com/repos/owner/repo/pulls/34"}}`)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/comments"):
+			var comment github.IssueComment
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&comment))
+			commentBody = comment.GetBody()
+			fmt.Fprint(w, `{"id": 1, "html_url": "https://github.com/owner/repo/issues/1#issuecomment-1"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	siteURL := "https://mattermost.example.com"
+
+	createIssueComment := func(body string) (*httptest.ResponseRecorder, *string) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+		reply := new(string)
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		api.On("KVGet", "postID"+attachedCommentsKey).Return(nil, nil)
+		api.On("KVSet", "postID"+attachedCommentsKey, mock.Anything).Return(nil)
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), []byte("1"), int64(selfEventTTL)).Return(nil)
+		api.On("GetPost", "postID").Return(&model.Post{Id: "postID", UserId: "userID", ChannelId: "channelID"}, nil)
+		api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			*reply = post.Message
+			return post
+		}, nil)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		w := httptest.NewRecorder()
+		p.createIssueComment(w, httptest.NewRequest(http.MethodPost, "/api/v1/createissuecomment", strings.NewReader(body)), "userID")
+		return w, reply
+	}
+
+	t.Run("issue", func(t *testing.T) {
+		w, reply := createIssueComment(`{"post_id": "postID", "owner": "owner", "repo": "repo", "number": 12, "comment": "comment", "expected_state": "open"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"is_pull_request":false`)
+		assert.Contains(t, w.Body.String(), `"html_url":"https://github.com/owner/repo/issues/1#issuecomment-1"`)
+		assert.Equal(t, "[Message](https://mattermost.example.com/_redirect/pl/postID) attached to GitHub issue [#12](https://github.com/owner/repo/issues/1#issuecomment-1)", *reply)
+		assert.Equal(t, "*@alice attached a* [message](https://mattermost.example.com/_redirect/pl/postID) *from @alice*\n\ncomment", commentBody)
+	})
+
+	t.Run("permalink failure", func(t *testing.T) {
+		siteURL = ""
+		defer func() { siteURL = "https://mattermost.example.com" }()
+
+		w, reply := createIssueComment(`{"post_id": "postID", "owner": "owner", "repo": "repo", "number": 12, "comment": "comment"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"html_url":"https://github.com/owner/repo/issues/1#issuecomment-1"`)
+		assert.Equal(t, "*@alice attached a message from @alice*\n\ncomment", commentBody)
+		assert.Equal(t, "Message attached to GitHub issue [#12](https://github.com/owner/repo/issues/1#issuecomment-1). The comment doesn't link back to the message, as its link couldn't be generated.", *reply)
+	})
+
+	t.Run("pull request", func(t *testing.T) {
+		w, reply := createIssueComment(`{"post_id": "postID", "owner": "owner", "repo": "repo", "number": 34, "comment": "comment"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"is_pull_request":true`)
+		assert.Contains(t, *reply, "attached to GitHub pull request [#34]")
+	})
+
+	t.Run("unexpected state", func(t *testing.T) {
+		w, reply := createIssueComment(`{"post_id": "postID", "owner": "owner", "repo": "repo", "number": 34, "comment": "comment", "expected_state": "open"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "The pull request #34 is closed.")
+		assert.Empty(t, *reply)
+	})
+
+	t.Run("missing number", func(t *testing.T) {
+		w, reply := createIssueComment(`{"post_id": "postID", "owner": "owner", "repo": "repo", "number": 56, "comment": "comment"}`)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "There is no issue or pull request #56 in owner/repo.")
+		assert.Empty(t, *reply)
+	})
+
+	t.Run("invalid expected state", func(t *testing.T) {
+		w, _ := createIssueComment(`{"post_id": "postID", "owner": "owner", "repo": "repo", "number": 12, "comment": "comment", "expected_state": "merged"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
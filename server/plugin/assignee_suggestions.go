@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-github/server/plugin/graphql"
+)
+
+const (
+	assigneeSuggestionsKey = "_assigneesuggestions"
+
+	// assigneeSuggestionsCacheTTL is how long the suggestions for a repository are cached, in seconds.
+	assigneeSuggestionsCacheTTL = 10 * 60
+	// maxAssigneeSuggestions is the number of least loaded assignees suggested.
+	maxAssigneeSuggestions = 3
+)
+
+// assigneeSuggestion is a user who can be assigned to a new issue, along with how many open issues
+// of the repository they are already assigned to.
+type assigneeSuggestion struct {
+	Login      string `json:"login"`
+	OpenIssues int    `json:"open_issues"`
+}
+
+// listAssignees lists the users who can be assigned to the issues of a repository.
+func listAssignees(ctx context.Context, githubClient *github.Client, owner, repo string) ([]*github.User, error) {
+	var allAssignees []*github.User
+	opt := github.ListOptions{PerPage: 50}
+
+	for {
+		assignees, resp, err := githubClient.Issues.ListAssignees(ctx, owner, repo, &opt)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list assignees")
+		}
+		allAssignees = append(allAssignees, assignees...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+
+	return allAssignees, nil
+}
+
+// suggestAssignees returns the assignees of a repository with the fewest open assigned issues. The
+// open issues of all assignees are counted in batched searches, leaving out the assignees whose
+// search failed.
+func suggestAssignees(ctx context.Context, githubClient *github.Client, owner, repo string) ([]*assigneeSuggestion, error) {
+	assignees, err := listAssignees(ctx, githubClient, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]string, len(assignees))
+	for i, assignee := range assignees {
+		queries[i] = fmt.Sprintf("assignee:%s is:open repo:%s", assignee.GetLogin(), fullNameFromOwnerAndRepo(owner, repo))
+	}
+
+	counts, err := graphql.NewClient(githubClient).CountIssues(ctx, queries)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search assigned issues")
+	}
+
+	suggestions := make([]*assigneeSuggestion, 0, len(counts))
+	for i, assignee := range assignees {
+		if count, ok := counts[i]; ok {
+			suggestions = append(suggestions, &assigneeSuggestion{Login: assignee.GetLogin(), OpenIssues: count})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].OpenIssues != suggestions[j].OpenIssues {
+			return suggestions[i].OpenIssues < suggestions[j].OpenIssues
+		}
+		return suggestions[i].Login < suggestions[j].Login
+	})
+	if len(suggestions) > maxAssigneeSuggestions {
+		suggestions = suggestions[:maxAssigneeSuggestions]
+	}
+
+	return suggestions, nil
+}
+
+// getAssigneeSuggestions returns the suggested assignees of a repository, from the cache if possible.
+// The cache is per user, so that only users who could list the assignees themselves are served them.
+func (p *Plugin) getAssigneeSuggestions(ctx context.Context, githubClient *github.Client, userID, owner, repo string) ([]*assigneeSuggestion, error) {
+	key := hashKey(assigneeSuggestionsKey, userID, owner, repo)
+
+	value, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get assignee suggestions from KV store")
+	}
+	if value != nil {
+		suggestions := []*assigneeSuggestion{}
+		if err := json.Unmarshal(value, &suggestions); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal assignee suggestions")
+		}
+		return suggestions, nil
+	}
+
+	suggestions, err := suggestAssignees(ctx, githubClient, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = json.Marshal(suggestions)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal assignee suggestions")
+	}
+	if appErr := p.API.KVSetWithExpiry(key, value, assigneeSuggestionsCacheTTL); appErr != nil {
+		p.API.LogWarn("Failed to cache assignee suggestions", "repo", fullNameFromOwnerAndRepo(owner, repo), "error", appErr.Error())
+	}
+
+	return suggestions, nil
+}
+
+func (p *Plugin) getAssigneeSuggestionsList(w http.ResponseWriter, r *http.Request, userID string) {
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	owner, repo, err := parseRepo(r.URL.Query().Get("repo"))
+	if err != nil {
+		p.writeAPIError(w, &APIErrorResponse{Message: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	suggestions, err := p.getAssigneeSuggestions(r.Context(), p.githubConnect(*info.Token), userID, owner, repo)
+	if err != nil {
+		p.API.LogWarn("Failed to suggest assignees", "repo", fullNameFromOwnerAndRepo(owner, repo), "error", err.Error())
+		p.writeAPIError(w, &APIErrorResponse{Message: "Failed to suggest assignees", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, suggestions)
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newAssigneeSuggestionsMux serves the given assignees, along with failing searches for the
+// assignees in failing.
+func newAssigneeSuggestionsMux(t *testing.T, counts map[string]int, failing []string, searches *int32) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo/assignees", func(w http.ResponseWriter, r *http.Request) {
+		logins := []string{}
+		for login := range counts {
+			logins = append(logins, fmt.Sprintf(`{"login": %q}`, login))
+		}
+		for _, login := range failing {
+			logins = append(logins, fmt.Sprintf(`{"login": %q}`, login))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(logins, ","))
+	})
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(searches, 1)
+
+		var req struct {
+			Variables map[string]string `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		fields := []string{}
+		errs := []string{}
+		for name, query := range req.Variables {
+			alias := "count" + strings.TrimPrefix(name, "query")
+			assert.True(t, strings.HasSuffix(query, " is:open repo:owner/repo"), query)
+			login := strings.TrimPrefix(strings.Split(query, " ")[0], "assignee:")
+			count, ok := counts[login]
+			if !ok {
+				fields = append(fields, fmt.Sprintf(`%q: null`, alias))
+				errs = append(errs, fmt.Sprintf(`{"path": [%q], "message": "The listed users cannot be searched"}`, alias))
+				continue
+			}
+			fields = append(fields, fmt.Sprintf(`%q: {"issueCount": %d}`, alias, count))
+		}
+
+		fmt.Fprintf(w, `{"data": {%s}, "errors": [%s]}`, strings.Join(fields, ", "), strings.Join(errs, ", "))
+	})
+
+	return mux
+}
+
+func TestSuggestAssignees(t *testing.T) {
+	counts := map[string]int{"alice": 2, "bob": 7, "carol": 0, "dave": 2, "erin": 4}
+	var searches int32
+	githubClient := newTestGitHubClient(t, newAssigneeSuggestionsMux(t, counts, []string{"ghost"}, &searches))
+
+	suggestions, err := suggestAssignees(context.Background(), githubClient, "owner", "repo")
+	require.NoError(t, err)
+	assert.Equal(t, []*assigneeSuggestion{
+		{Login: "carol", OpenIssues: 0},
+		{Login: "alice", OpenIssues: 2},
+		{Login: "dave", OpenIssues: 2},
+	}, suggestions)
+	assert.EqualValues(t, 1, searches, "the searches are batched")
+}
+
+func TestGetAssigneeSuggestions(t *testing.T) {
+	key := hashKey(assigneeSuggestionsKey, "userID", "owner", "repo")
+
+	t.Run("suggestions are cached", func(t *testing.T) {
+		var searches int32
+		githubClient := newTestGitHubClient(t, newAssigneeSuggestionsMux(t, map[string]int{"alice": 1}, nil, &searches))
+
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("KVGet", key).Return(nil, nil).Once()
+		api.On("KVSetWithExpiry", key, mock.MatchedBy(func(value []byte) bool {
+			return string(value) == `[{"login":"alice","open_issues":1}]`
+		}), int64(assigneeSuggestionsCacheTTL)).Return(nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		suggestions, err := p.getAssigneeSuggestions(context.Background(), githubClient, "userID", "owner", "repo")
+		require.NoError(t, err)
+		assert.Equal(t, []*assigneeSuggestion{{Login: "alice", OpenIssues: 1}}, suggestions)
+		assert.EqualValues(t, 1, searches)
+	})
+
+	t.Run("cached suggestions are used", func(t *testing.T) {
+		var searches int32
+		githubClient := newTestGitHubClient(t, newAssigneeSuggestionsMux(t, map[string]int{"alice": 1}, nil, &searches))
+
+		cached, err := json.Marshal([]*assigneeSuggestion{{Login: "bob", OpenIssues: 3}})
+		require.NoError(t, err)
+
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("KVGet", key).Return(cached, nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		suggestions, err := p.getAssigneeSuggestions(context.Background(), githubClient, "userID", "owner", "repo")
+		require.NoError(t, err)
+		assert.Equal(t, []*assigneeSuggestion{{Login: "bob", OpenIssues: 3}}, suggestions)
+		assert.EqualValues(t, 0, searches)
+	})
+}
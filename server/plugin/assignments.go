@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-github/server/plugin/graphql"
+)
+
+// assignmentItem is an issue or pull request of the assignments section of the sidebar. Its type
+// tells the webapp which icon to show, and its source why it's assigned to the user.
+type assignmentItem struct {
+	*github.Issue
+	Type   string `json:"type"`
+	Source string `json:"source"`
+}
+
+func newAssignmentItem(issue *github.Issue) *assignmentItem {
+	item := &assignmentItem{Issue: issue, Type: graphql.AssignmentTypeIssue, Source: graphql.AssignmentSourceAssignee}
+	if issue.IsPullRequest() {
+		item.Type = graphql.AssignmentTypePullRequest
+	}
+
+	return item
+}
+
+// newAssignmentItemFromGraphQL converts an assignment fetched with GraphQL to the shape returned
+// by the REST search. baseURL is the base URL of the REST API, which the repository URL is under.
+func newAssignmentItemFromGraphQL(baseURL string, assignment *graphql.Assignment) *assignmentItem {
+	labels := make([]*github.Label, 0, len(assignment.Labels))
+	for _, label := range assignment.Labels {
+		labels = append(labels, &github.Label{Name: github.String(label.Name), Color: github.String(label.Color)})
+	}
+
+	createdAt, updatedAt := assignment.CreatedAt, assignment.UpdatedAt
+	issue := &github.Issue{
+		Number:        github.Int(assignment.Number),
+		Title:         github.String(assignment.Title),
+		HTMLURL:       github.String(assignment.URL),
+		State:         github.String(strings.ToLower(assignment.State)),
+		RepositoryURL: github.String(strings.TrimSuffix(baseURL, "/") + "/repos/" + assignment.Repository),
+		User:          &github.User{Login: github.String(assignment.Author)},
+		CreatedAt:     &createdAt,
+		UpdatedAt:     &updatedAt,
+		Labels:        labels,
+	}
+	if assignment.Type == graphql.AssignmentTypePullRequest {
+		issue.PullRequestLinks = &github.PullRequestLinks{HTMLURL: github.String(assignment.URL)}
+	}
+
+	return &assignmentItem{Issue: issue, Type: assignment.Type, Source: assignment.Source}
+}
+
+// fetchAssignments gets the assignments section of the sidebar of a user, filtered by label if one
+// is given. A single GraphQL query finds the issues and pull requests assigned to the user along with
+// the pull requests whose review was requested from a team of the user. The REST search of the
+// assigned ones is only run when the GraphQL query fails, e.g. on GitHub Enterprise versions lacking
+// the queried fields.
+func (p *Plugin) fetchAssignments(ctx context.Context, githubClient *github.Client, username, label string) ([]*assignmentItem, *github.Response, error) {
+	org := p.getConfiguration().GitHubOrg
+
+	labelQualifier := ""
+	if label != "" {
+		labelQualifier = " " + labelSearchQualifier(label)
+	}
+	assignedQuery := getYourAssigneeSearchQuery(username, org) + labelQualifier
+
+	assignments, err := graphql.NewClient(githubClient).GetAssignments(ctx, assignedQuery, getTeamReviewSearchQuery(username, org)+labelQualifier)
+	if err == nil {
+		items := make([]*assignmentItem, 0, len(assignments))
+		for _, assignment := range assignments {
+			items = append(items, newAssignmentItemFromGraphQL(githubClient.BaseURL.String(), assignment))
+		}
+		return items, nil, nil
+	}
+	p.API.LogDebug("Failed to fetch assignments with GraphQL, falling back to the REST API", "error", err.Error())
+
+	result, resp, err := githubClient.Search.Issues(ctx, assignedQuery, &github.SearchOptions{})
+	if err != nil {
+		return nil, resp, errors.Wrapf(err, "failed to search for %s", assignedQuery)
+	}
+
+	items := make([]*assignmentItem, 0, len(result.Issues))
+	for _, issue := range result.Issues {
+		issue.Labels = capLabels(issue.Labels)
+		items = append(items, newAssignmentItem(issue))
+	}
+
+	return items, resp, nil
+}
+
+// withoutReviews drops from the assignments section of the sidebar the pull requests assigned through
+// a team review request that the reviews section already lists. Both sections are JSON encoded.
+func withoutReviews(assignments, reviews json.RawMessage) (json.RawMessage, error) {
+	var reviewItems []struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(reviews, &reviewItems); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal reviews")
+	}
+
+	listed := map[string]bool{}
+	for _, review := range reviewItems {
+		listed[review.HTMLURL] = true
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(assignments, &items); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal assignments")
+	}
+
+	kept := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		var assignment struct {
+			HTMLURL string `json:"html_url"`
+			Source  string `json:"source"`
+		}
+		if err := json.Unmarshal(item, &assignment); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal assignment")
+		}
+
+		if assignment.Source == graphql.AssignmentSourceTeamReview && listed[assignment.HTMLURL] {
+			continue
+		}
+		kept = append(kept, item)
+	}
+
+	return json.Marshal(kept)
+}
@@ -0,0 +1,121 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestGetTeamReviewSearchQuery(t *testing.T) {
+	assert.Equal(t, "is:pr is:open review-requested:alice -user-review-requested:alice archived:false org:acme", getTeamReviewSearchQuery("alice", "acme"))
+	assert.Equal(t, "is:pr is:open review-requested:alice -user-review-requested:alice archived:false ", getTeamReviewSearchQuery("alice", ""))
+}
+
+func TestFetchAssignments(t *testing.T) {
+	setup := func(t *testing.T, graphqlResponse string) ([]map[string]interface{}, int) {
+		restSearches := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/api/v3/search/issues":
+				restSearches++
+				assert.Equal(t, `is:open assignee:alice archived:false  label:"bug"`, r.URL.Query().Get("q"))
+				fmt.Fprint(w, `{"total_count": 2, "items": [
+					{"number": 1, "html_url": "https://github.com/owner/repo/issues/1"},
+					{"number": 2, "html_url": "https://github.com/owner/repo/pull/2", "pull_request": {"html_url": "https://github.com/owner/repo/pull/2"}}
+				]}`)
+			case "/api/graphql":
+				fmt.Fprint(w, graphqlResponse)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(ts.Close)
+
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		api.On("LogDebug", "Failed to fetch assignments with GraphQL, falling back to the REST API", "error", mock.AnythingOfType("string")).Maybe()
+		p.SetAPI(api)
+
+		client, err := GetGitHubClient(oauth2.Token{AccessToken: "token"}, p.getConfiguration())
+		require.NoError(t, err)
+		items, _, err := p.fetchAssignments(context.Background(), client, "alice", "bug")
+		require.NoError(t, err)
+
+		// The webapp gets the fields of the items
+		data, err := json.Marshal(items)
+		require.NoError(t, err)
+		var decoded []map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		return decoded, restSearches
+	}
+
+	t.Run("GraphQL results", func(t *testing.T) {
+		items, restSearches := setup(t, `{"data": {
+			"assigned": {"nodes": [
+				{"__typename": "Issue", "number": 1, "url": "https://github.com/owner/repo/issues/1"},
+				{"__typename": "PullRequest", "number": 2, "url": "https://github.com/owner/repo/pull/2"}
+			]},
+			"teamReviews": {"nodes": [
+				{"__typename": "PullRequest", "number": 3, "title": "Feature", "url": "https://github.com/owner/other/pull/3", "state": "OPEN",
+				 "createdAt": "2021-03-05T12:00:00Z", "author": {"login": "carol"}, "repository": {"nameWithOwner": "owner/other"},
+				 "labels": {"nodes": [{"name": "bug", "color": "d73a4a"}]}}
+			]}
+		}}`)
+
+		assert.Zero(t, restSearches)
+		require.Len(t, items, 3)
+		assert.Equal(t, "issue", items[0]["type"])
+		assert.Equal(t, "assignee", items[0]["source"])
+		assert.Equal(t, "pull_request", items[1]["type"])
+
+		teamReview := items[2]
+		assert.Equal(t, "pull_request", teamReview["type"])
+		assert.Equal(t, "team_review", teamReview["source"])
+		assert.Equal(t, float64(3), teamReview["number"])
+		assert.Equal(t, "Feature", teamReview["title"])
+		assert.Equal(t, "open", teamReview["state"])
+		assert.Equal(t, map[string]interface{}{"html_url": "https://github.com/owner/other/pull/3"}, teamReview["pull_request"])
+		assert.Regexp(t, "/api/v3/repos/owner/other$", teamReview["repository_url"])
+		assert.Equal(t, "carol", teamReview["user"].(map[string]interface{})["login"])
+		assert.Equal(t, "2021-03-05T12:00:00Z", teamReview["created_at"])
+		assert.Len(t, teamReview["labels"], 1)
+	})
+
+	t.Run("REST results when GraphQL fails", func(t *testing.T) {
+		items, restSearches := setup(t, `{"errors": [{"message": "Field 'search' doesn't exist on type 'Query'", "type": "undefinedField"}]}`)
+
+		assert.Equal(t, 1, restSearches)
+		require.Len(t, items, 2)
+		assert.Equal(t, float64(1), items[0]["number"])
+		assert.Equal(t, float64(2), items[1]["number"])
+	})
+}
+
+func TestWithoutReviews(t *testing.T) {
+	assignments := json.RawMessage(`[
+		{"html_url": "https://github.com/owner/repo/issues/1", "source": "assignee"},
+		{"html_url": "https://github.com/owner/repo/pull/2", "source": "team_review"},
+		{"html_url": "https://github.com/owner/repo/pull/3", "source": "team_review"},
+		{"html_url": "https://github.com/owner/repo/pull/4", "source": "assignee"}
+	]`)
+	reviews := json.RawMessage(`[{"html_url": "https://github.com/owner/repo/pull/2"}, {"html_url": "https://github.com/owner/repo/pull/4"}]`)
+
+	deduplicated, err := withoutReviews(assignments, reviews)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"html_url": "https://github.com/owner/repo/issues/1", "source": "assignee"},
+		{"html_url": "https://github.com/owner/repo/pull/3", "source": "team_review"},
+		{"html_url": "https://github.com/owner/repo/pull/4", "source": "assignee"}
+	]`, string(deduplicated))
+}
@@ -0,0 +1,433 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	attachedCommentsKey = "_attachedcomments"
+
+	propAttachedPostID     = "gh_attached_post_id"
+	propCommentID          = "gh_comment_id"
+	propCommentRepo        = "gh_comment_repo"
+	propCommentIssueNumber = "gh_comment_issue_number"
+	propCommentOnPR        = "gh_comment_on_pull_request"
+
+	attachedCommentActionUpdate       = "update"
+	attachedCommentActionPromptDelete = "prompt_delete"
+	attachedCommentActionDelete       = "delete"
+	attachedCommentActionDismiss      = "dismiss"
+	attachedCommentContextAction      = "action"
+	attachedCommentContextReplyPostID = "reply_post_id"
+	attachedCommentDeletedNote        = "_The GitHub comment was deleted._"
+)
+
+// attachedComment is a GitHub comment on an issue or pull request created from a Mattermost post.
+// It's tracked through the props of the reply confirming the comment was created. Comments on pull
+// requests are conversation comments, made through the issues API; review comments on the lines of
+// a diff can't be attached, as a message doesn't tell which file and line it's about.
+type attachedComment struct {
+	ReplyPostID    string
+	AttachedPostID string
+	// UserID is the Mattermost user who created the comment. Only they can edit or delete it.
+	UserID    string
+	Owner     string
+	Repo      string
+	Number    int
+	CommentID int64
+	// IsPullRequest tells if the comment is on a pull request rather than an issue.
+	IsPullRequest bool
+}
+
+// attachedCommentFromPost reads the comment tracked by a confirmation reply.
+func attachedCommentFromPost(reply *model.Post) (*attachedComment, bool) {
+	attachedPostID, _ := reply.GetProp(propAttachedPostID).(string)
+	commentIDValue, _ := reply.GetProp(propCommentID).(string)
+	fullName, _ := reply.GetProp(propCommentRepo).(string)
+	numberValue, _ := reply.GetProp(propCommentIssueNumber).(string)
+	if attachedPostID == "" || commentIDValue == "" {
+		return nil, false
+	}
+
+	commentID, err := strconv.ParseInt(commentIDValue, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	number, err := strconv.Atoi(numberValue)
+	if err != nil {
+		return nil, false
+	}
+	owner, repo, err := parseRepo(fullName)
+	if err != nil {
+		return nil, false
+	}
+
+	return &attachedComment{
+		ReplyPostID:    reply.Id,
+		AttachedPostID: attachedPostID,
+		UserID:         reply.UserId,
+		Owner:          owner,
+		Repo:           repo,
+		Number:         number,
+		CommentID:      commentID,
+		IsPullRequest:  reply.GetProp(propCommentOnPR) == "true",
+	}, true
+}
+
+func (c *attachedComment) setProps(reply *model.Post) {
+	reply.AddProp(propAttachedPostID, c.AttachedPostID)
+	reply.AddProp(propCommentID, strconv.FormatInt(c.CommentID, 10))
+	reply.AddProp(propCommentRepo, fullNameFromOwnerAndRepo(c.Owner, c.Repo))
+	reply.AddProp(propCommentIssueNumber, strconv.Itoa(c.Number))
+	if c.IsPullRequest {
+		reply.AddProp(propCommentOnPR, "true")
+	}
+}
+
+// targetName names the kind of GitHub object the comment is on.
+func (c *attachedComment) targetName() string {
+	if c.IsPullRequest {
+		return "pull request"
+	}
+	return "issue"
+}
+
+func (c *attachedComment) issueReference() string {
+	return fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(c.Owner, c.Repo), c.Number)
+}
+
+// formatAttachedCommentBody builds the body of a GitHub comment created from a Mattermost post.
+func formatAttachedCommentBody(githubUsername, permalink, author, message string) string {
+	if permalink == "" {
+		return fmt.Sprintf("*@%s attached a message from %s*\n\n", githubUsername, author) + message
+	}
+
+	return fmt.Sprintf("*@%s attached a* [message](%s) *from %s*\n\n", githubUsername, permalink, author) + message
+}
+
+func (p *Plugin) getAttachedCommentAction(action, replyPostID string) *model.PostActionIntegration {
+	return &model.PostActionIntegration{
+		URL: fmt.Sprintf("/plugins/%s/api/v1/attachedcomment", Manifest.Id),
+		Context: map[string]interface{}{
+			attachedCommentContextAction:      action,
+			attachedCommentContextReplyPostID: replyPostID,
+		},
+	}
+}
+
+// attachDeleteAction adds a button to a confirmation reply to delete the GitHub comment.
+func (p *Plugin) attachDeleteAction(reply *model.Post) {
+	model.ParseSlackAttachment(reply, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{{
+			Name:        "Delete GitHub comment",
+			Integration: p.getAttachedCommentAction(attachedCommentActionPromptDelete, reply.Id),
+		}},
+	}})
+}
+
+// getAttachedCommentReplies returns the IDs of the confirmation replies of the comments created from a post.
+func (p *Plugin) getAttachedCommentReplies(postID string) ([]string, error) {
+	value, appErr := p.API.KVGet(postID + attachedCommentsKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get attached comments from KV store")
+	}
+
+	replyIDs := []string{}
+	if value == nil {
+		return replyIDs, nil
+	}
+
+	if err := json.Unmarshal(value, &replyIDs); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal attached comments")
+	}
+
+	return replyIDs, nil
+}
+
+func (p *Plugin) storeAttachedCommentReply(postID, replyPostID string) error {
+	replyIDs, err := p.getAttachedCommentReplies(postID)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(append(replyIDs, replyPostID))
+	if err != nil {
+		return errors.Wrap(err, "could not marshal attached comments")
+	}
+
+	if appErr := p.API.KVSet(postID+attachedCommentsKey, value); appErr != nil {
+		return errors.Wrap(appErr, "could not store attached comments in KV store")
+	}
+
+	return nil
+}
+
+// removeAttachedCommentReplies stops tracking the given confirmation replies of the comments created from a post.
+func (p *Plugin) removeAttachedCommentReplies(postID string, removedIDs []string) error {
+	replyIDs, err := p.getAttachedCommentReplies(postID)
+	if err != nil {
+		return err
+	}
+
+	removed := map[string]bool{}
+	for _, replyID := range removedIDs {
+		removed[replyID] = true
+	}
+
+	kept := []string{}
+	for _, replyID := range replyIDs {
+		if !removed[replyID] {
+			kept = append(kept, replyID)
+		}
+	}
+
+	if len(kept) == 0 {
+		if appErr := p.API.KVDelete(postID + attachedCommentsKey); appErr != nil {
+			return errors.Wrap(appErr, "could not delete attached comments from KV store")
+		}
+		return nil
+	}
+
+	value, err := json.Marshal(kept)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal attached comments")
+	}
+
+	if appErr := p.API.KVSet(postID+attachedCommentsKey, value); appErr != nil {
+		return errors.Wrap(appErr, "could not store attached comments in KV store")
+	}
+
+	return nil
+}
+
+// createAttachedCommentReply posts the reply confirming a comment was created from a post and keeps
+// track of the comment so that later changes to the post can be propagated to it.
+func (p *Plugin) createAttachedCommentReply(reply *model.Post, comment *attachedComment) *model.AppError {
+	reply.Id = model.NewId()
+	comment.ReplyPostID = reply.Id
+	comment.setProps(reply)
+	p.attachDeleteAction(reply)
+
+	if _, appErr := p.API.CreatePost(reply); appErr != nil {
+		return appErr
+	}
+
+	if err := p.storeAttachedCommentReply(comment.AttachedPostID, reply.Id); err != nil {
+		p.API.LogWarn("Failed to store attached comment", "postID", comment.AttachedPostID, "error", err.Error())
+	}
+
+	return nil
+}
+
+// promptAttachedCommentsUpdate offers the creators of the GitHub comments attached from an edited post
+// to update them with the new message.
+func (p *Plugin) promptAttachedCommentsUpdate(newPost, oldPost *model.Post) {
+	if newPost.Message == oldPost.Message {
+		return
+	}
+
+	replyIDs, err := p.getAttachedCommentReplies(newPost.Id)
+	if err != nil {
+		p.API.LogWarn("Failed to get attached comments", "postID", newPost.Id, "error", err.Error())
+		return
+	}
+
+	deletedIDs := []string{}
+	for _, replyID := range replyIDs {
+		reply, appErr := p.API.GetPost(replyID)
+		if appErr != nil || reply.DeleteAt != 0 {
+			// The reply was deleted, and the comment with it
+			deletedIDs = append(deletedIDs, replyID)
+			continue
+		}
+
+		comment, ok := attachedCommentFromPost(reply)
+		if !ok {
+			continue
+		}
+
+		prompt := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: newPost.ChannelId,
+			RootId:    reply.RootId,
+			Message:   fmt.Sprintf("A [message](%s) you attached to GitHub %s %s was edited. Do you want to update the GitHub comment?", p.getPermaLink(newPost.Id), comment.targetName(), comment.issueReference()),
+		}
+		model.ParseSlackAttachment(prompt, []*model.SlackAttachment{{
+			Actions: []*model.PostAction{{
+				Name:        "Update comment",
+				Integration: p.getAttachedCommentAction(attachedCommentActionUpdate, reply.Id),
+			}, {
+				Name:        "Dismiss",
+				Integration: p.getAttachedCommentAction(attachedCommentActionDismiss, reply.Id),
+			}},
+		}})
+		p.API.SendEphemeralPost(comment.UserID, prompt)
+	}
+
+	if len(deletedIDs) == 0 {
+		return
+	}
+
+	// Mattermost doesn't tell plugins about deleted posts, so the replies are only found missing here
+	if err := p.removeAttachedCommentReplies(newPost.Id, deletedIDs); err != nil {
+		p.API.LogWarn("Failed to remove deleted attached comments", "postID", newPost.Id, "error", err.Error())
+		return
+	}
+	p.API.LogDebug("Removed deleted attached comments", "postID", newPost.Id, "count", len(deletedIDs))
+}
+
+// editAttachedComment updates the body of a GitHub comment. It returns false if the comment no longer exists.
+func editAttachedComment(ctx context.Context, githubClient *github.Client, comment *attachedComment, body string) (bool, error) {
+	_, resp, err := githubClient.Issues.EditComment(ctx, comment.Owner, comment.Repo, comment.CommentID, &github.IssueComment{Body: &body})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to edit comment")
+	}
+
+	return true, nil
+}
+
+// deleteAttachedComment deletes a GitHub comment. Deleting a comment that no longer exists isn't an error.
+func deleteAttachedComment(ctx context.Context, githubClient *github.Client, comment *attachedComment) error {
+	resp, err := githubClient.Issues.DeleteComment(ctx, comment.Owner, comment.Repo, comment.CommentID)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to delete comment")
+	}
+
+	return nil
+}
+
+// markAttachedCommentDeleted stops tracking the comment of a confirmation reply once it's deleted.
+func (p *Plugin) markAttachedCommentDeleted(reply *model.Post) {
+	reply.DelProp(propAttachedPostID)
+	reply.DelProp(propCommentID)
+	reply.DelProp(propCommentRepo)
+	reply.DelProp(propCommentIssueNumber)
+	reply.DelProp("attachments")
+	reply.Message += "\n\n" + attachedCommentDeletedNote
+
+	if _, appErr := p.API.UpdatePost(reply); appErr != nil {
+		p.API.LogWarn("Failed to update attached comment reply", "postID", reply.Id, "error", appErr.Error())
+	}
+}
+
+func (p *Plugin) handleAttachedCommentAction(w http.ResponseWriter, r *http.Request, userID string) {
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a post action request.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	action, _ := request.Context[attachedCommentContextAction].(string)
+	replyPostID, _ := request.Context[attachedCommentContextReplyPostID].(string)
+
+	response := &model.PostActionIntegrationResponse{}
+	response.EphemeralText = p.runAttachedCommentAction(userID, request, action, replyPostID)
+	p.writeJSON(w, response)
+}
+
+// runAttachedCommentAction runs an action on the GitHub comment of a confirmation reply and returns the
+// message to show to the user.
+func (p *Plugin) runAttachedCommentAction(userID string, request *model.PostActionIntegrationRequest, action, replyPostID string) string {
+	if action == attachedCommentActionDismiss {
+		p.API.DeleteEphemeralPost(userID, request.PostId)
+		return ""
+	}
+
+	reply, appErr := p.API.GetPost(replyPostID)
+	if appErr != nil {
+		return "The GitHub comment is no longer attached to a message."
+	}
+
+	comment, ok := attachedCommentFromPost(reply)
+	if !ok {
+		return "The GitHub comment is no longer attached to a message."
+	}
+	if comment.UserID != userID {
+		return "Only the user who attached the message can change the GitHub comment."
+	}
+
+	if action == attachedCommentActionPromptDelete {
+		prompt := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: reply.ChannelId,
+			RootId:    reply.RootId,
+			Message:   fmt.Sprintf("Do you want to delete the comment attached to GitHub %s %s?", comment.targetName(), comment.issueReference()),
+		}
+		model.ParseSlackAttachment(prompt, []*model.SlackAttachment{{
+			Actions: []*model.PostAction{{
+				Name:        "Delete comment",
+				Style:       "danger",
+				Integration: p.getAttachedCommentAction(attachedCommentActionDelete, reply.Id),
+			}, {
+				Name:        "Cancel",
+				Integration: p.getAttachedCommentAction(attachedCommentActionDismiss, reply.Id),
+			}},
+		}})
+		p.API.SendEphemeralPost(userID, prompt)
+		return ""
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		return apiErr.Message
+	}
+	githubClient, err := p.getGithubClientFor(info, githubWrite)
+	if err != nil {
+		return readOnlyModeMessage
+	}
+
+	p.API.DeleteEphemeralPost(userID, request.PostId)
+
+	p.followAttachedCommentTransfers(comment, reply)
+
+	switch action {
+	case attachedCommentActionUpdate:
+		post, appErr := p.API.GetPost(comment.AttachedPostID)
+		if appErr != nil {
+			return "The attached message no longer exists."
+		}
+
+		author, err := p.getUsername(post.UserId)
+		if err != nil {
+			p.API.LogWarn("Failed to get username", "userID", post.UserId, "error", err.Error())
+			return "Failed to update the GitHub comment."
+		}
+
+		body := formatAttachedCommentBody(info.GitHubUsername, p.getPermaLink(post.Id), author, post.Message)
+		exists, err := editAttachedComment(context.Background(), githubClient, comment, body)
+		if err != nil {
+			p.API.LogWarn("Failed to edit attached comment", "issue", comment.issueReference(), "error", err.Error())
+			return "Failed to update the GitHub comment."
+		}
+		if !exists {
+			p.markAttachedCommentDeleted(reply)
+			return fmt.Sprintf("The comment on GitHub %s %s was already deleted.", comment.targetName(), comment.issueReference())
+		}
+
+		return fmt.Sprintf("Updated the comment on GitHub %s %s.", comment.targetName(), comment.issueReference())
+	case attachedCommentActionDelete:
+		if err := deleteAttachedComment(context.Background(), githubClient, comment); err != nil {
+			p.API.LogWarn("Failed to delete attached comment", "issue", comment.issueReference(), "error", err.Error())
+			return "Failed to delete the GitHub comment."
+		}
+
+		p.markAttachedCommentDeleted(reply)
+		return fmt.Sprintf("Deleted the comment on GitHub %s %s.", comment.targetName(), comment.issueReference())
+	default:
+		return fmt.Sprintf("Unknown action %q.", action)
+	}
+}
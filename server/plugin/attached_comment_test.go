@@ -0,0 +1,242 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachedCommentProps(t *testing.T) {
+	comment := &attachedComment{
+		ReplyPostID:    "replyID",
+		AttachedPostID: "postID",
+		UserID:         "userID",
+		Owner:          "owner",
+		Repo:           "repo",
+		Number:         12,
+		CommentID:      9007199254740993,
+	}
+
+	reply := &model.Post{Id: "replyID", UserId: "userID"}
+	comment.setProps(reply)
+
+	// Props are stored as JSON
+	reply = model.PostFromJson(strings.NewReader(reply.ToJson()))
+
+	read, ok := attachedCommentFromPost(reply)
+	require.True(t, ok)
+	assert.Equal(t, comment, read)
+	assert.Equal(t, "owner/repo#12", read.issueReference())
+
+	comment.IsPullRequest = true
+	reply = &model.Post{Id: "replyID", UserId: "userID"}
+	comment.setProps(reply)
+	read, ok = attachedCommentFromPost(model.PostFromJson(strings.NewReader(reply.ToJson())))
+	require.True(t, ok)
+	assert.Equal(t, comment, read)
+	assert.Equal(t, "pull request", read.targetName())
+
+	_, ok = attachedCommentFromPost(&model.Post{Id: "otherID"})
+	assert.False(t, ok)
+}
+
+func TestPromptAttachedCommentsUpdate(t *testing.T) {
+	reply := &model.Post{Id: "replyID", UserId: "attacherID", ChannelId: "channelID", RootId: "postID"}
+	(&attachedComment{AttachedPostID: "postID", Owner: "owner", Repo: "repo", Number: 12, CommentID: 34}).setProps(reply)
+
+	replyIDs, err := json.Marshal([]string{"replyID", "deletedReplyID"})
+	require.NoError(t, err)
+
+	t.Run("creator of the comment is prompted", func(t *testing.T) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		api.On("KVGet", "postID"+attachedCommentsKey).Return(replyIDs, nil)
+		api.On("GetPost", "replyID").Return(reply, nil)
+		api.On("GetPost", "deletedReplyID").Return(nil, &model.AppError{Message: "not found"})
+		siteURL := "https://mattermost.example.com"
+		api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		api.On("SendEphemeralPost", "attacherID", mock.MatchedBy(func(post *model.Post) bool {
+			attachments := post.Attachments()
+			return post.RootId == "postID" && len(attachments) == 1 && len(attachments[0].Actions) == 2 &&
+				attachments[0].Actions[0].Integration.Context[attachedCommentContextAction] == attachedCommentActionUpdate &&
+				attachments[0].Actions[0].Integration.Context[attachedCommentContextReplyPostID] == "replyID"
+		})).Return(&model.Post{}).Once()
+		api.On("KVSet", "postID"+attachedCommentsKey, []byte(`["replyID"]`)).Return(nil).Once()
+		api.On("LogDebug", "Removed deleted attached comments", "postID", "postID", "count", 1).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.promptAttachedCommentsUpdate(
+			&model.Post{Id: "postID", ChannelId: "channelID", Message: "fixed typo"},
+			&model.Post{Id: "postID", ChannelId: "channelID", Message: "fixed typp"},
+		)
+	})
+
+	t.Run("all replies deleted", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		store := map[string][]byte{"postID" + attachedCommentsKey: replyIDs}
+		api.On("KVGet", "postID"+attachedCommentsKey).Return(func(key string) []byte { return store[key] }, nil)
+		api.On("KVDelete", "postID"+attachedCommentsKey).Return(func(key string) *model.AppError {
+			delete(store, key)
+			return nil
+		}).Once()
+		api.On("GetPost", "replyID").Return(&model.Post{Id: "replyID", DeleteAt: 1}, nil).Once()
+		api.On("GetPost", "deletedReplyID").Return(nil, &model.AppError{Message: "not found"}).Once()
+		api.On("LogDebug", "Removed deleted attached comments", "postID", "postID", "count", 2).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.promptAttachedCommentsUpdate(
+			&model.Post{Id: "postID", ChannelId: "channelID", Message: "fixed typo"},
+			&model.Post{Id: "postID", ChannelId: "channelID", Message: "fixed typp"},
+		)
+
+		// The deleted replies aren't looked up again on the next edit
+		p.promptAttachedCommentsUpdate(
+			&model.Post{Id: "postID", ChannelId: "channelID", Message: "fixed another typo"},
+			&model.Post{Id: "postID", ChannelId: "channelID", Message: "fixed typo"},
+		)
+	})
+
+	t.Run("message unchanged", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.promptAttachedCommentsUpdate(
+			&model.Post{Id: "postID", Message: "message", IsPinned: true},
+			&model.Post{Id: "postID", Message: "message"},
+		)
+	})
+}
+
+func TestEditAttachedComment(t *testing.T) {
+	comment := &attachedComment{Owner: "owner", Repo: "repo", Number: 12, CommentID: 34}
+
+	t.Run("comment edited", func(t *testing.T) {
+		var body map[string]interface{}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/repos/owner/repo/issues/comments/34", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPatch, r.Method)
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			_, _ = w.Write([]byte(`{"id": 34}`))
+		})
+
+		exists, err := editAttachedComment(context.Background(), newTestGitHubClient(t, mux), comment, "new body")
+		require.NoError(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, "new body", body["body"])
+	})
+
+	t.Run("comment already deleted", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/repos/owner/repo/issues/comments/34", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		})
+
+		exists, err := editAttachedComment(context.Background(), newTestGitHubClient(t, mux), comment, "new body")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("edit failed", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/repos/owner/repo/issues/comments/34", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"message": "Forbidden"}`, http.StatusForbidden)
+		})
+
+		_, err := editAttachedComment(context.Background(), newTestGitHubClient(t, mux), comment, "new body")
+		assert.Error(t, err)
+	})
+}
+
+func TestDeleteAttachedComment(t *testing.T) {
+	comment := &attachedComment{Owner: "owner", Repo: "repo", Number: 12, CommentID: 34}
+
+	t.Run("comment deleted", func(t *testing.T) {
+		deleted := false
+		mux := http.NewServeMux()
+		mux.HandleFunc("/repos/owner/repo/issues/comments/34", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodDelete, r.Method)
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		require.NoError(t, deleteAttachedComment(context.Background(), newTestGitHubClient(t, mux), comment))
+		assert.True(t, deleted)
+	})
+
+	t.Run("comment already deleted", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/repos/owner/repo/issues/comments/34", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		})
+
+		assert.NoError(t, deleteAttachedComment(context.Background(), newTestGitHubClient(t, mux), comment))
+	})
+}
+
+func TestRunAttachedCommentAction(t *testing.T) {
+	reply := &model.Post{Id: "replyID", UserId: "attacherID", ChannelId: "channelID", RootId: "postID"}
+	(&attachedComment{AttachedPostID: "postID", Owner: "owner", Repo: "repo", Number: 12, CommentID: 34}).setProps(reply)
+	request := &model.PostActionIntegrationRequest{PostId: "promptID"}
+
+	t.Run("dismiss", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("DeleteEphemeralPost", "attacherID", "promptID").Return().Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		assert.Empty(t, p.runAttachedCommentAction("attacherID", request, attachedCommentActionDismiss, "replyID"))
+	})
+
+	t.Run("other users can't change the comment", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("GetPost", "replyID").Return(reply, nil)
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		message := p.runAttachedCommentAction("otherID", request, attachedCommentActionDelete, "replyID")
+		assert.Equal(t, "Only the user who attached the message can change the GitHub comment.", message)
+	})
+
+	t.Run("deletion is confirmed first", func(t *testing.T) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		api.On("GetPost", "replyID").Return(reply, nil)
+		api.On("SendEphemeralPost", "attacherID", mock.MatchedBy(func(post *model.Post) bool {
+			attachments := post.Attachments()
+			return len(attachments) == 1 && len(attachments[0].Actions) == 2 &&
+				attachments[0].Actions[0].Integration.Context[attachedCommentContextAction] == attachedCommentActionDelete
+		})).Return(&model.Post{}).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		assert.Empty(t, p.runAttachedCommentAction("attacherID", request, attachedCommentActionPromptDelete, "replyID"))
+	})
+
+	t.Run("comment no longer attached", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("GetPost", "replyID").Return(&model.Post{Id: "replyID", UserId: "attacherID"}, nil)
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		message := p.runAttachedCommentAction("attacherID", request, attachedCommentActionDelete, "replyID")
+		assert.Equal(t, "The GitHub comment is no longer attached to a message.", message)
+	})
+}
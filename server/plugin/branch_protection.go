@@ -0,0 +1,288 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// branchProtectionRuleEventType is the type of the webhook events sent when a branch protection rule
+// is created, edited or deleted.
+const branchProtectionRuleEventType = "branch_protection_rule"
+
+// enforcementLevelOff is the enforcement level of a disabled setting of a branch protection rule.
+const enforcementLevelOff = "off"
+
+// BranchProtectionRule is a branch protection rule of a repository.
+type BranchProtectionRule struct {
+	ID *int64 `json:"id,omitempty"`
+	// Name is the branch name pattern the rule applies to.
+	Name                                 *string  `json:"name,omitempty"`
+	PullRequestReviewsEnforcementLevel   *string  `json:"pull_request_reviews_enforcement_level,omitempty"`
+	RequiredApprovingReviewCount         *int     `json:"required_approving_review_count,omitempty"`
+	RequiredStatusChecks                 []string `json:"required_status_checks,omitempty"`
+	RequiredStatusChecksEnforcementLevel *string  `json:"required_status_checks_enforcement_level,omitempty"`
+	AllowForcePushesEnforcementLevel     *string  `json:"allow_force_pushes_enforcement_level,omitempty"`
+}
+
+func (r *BranchProtectionRule) GetName() string {
+	if r == nil || r.Name == nil {
+		return ""
+	}
+	return *r.Name
+}
+
+func (r *BranchProtectionRule) GetPullRequestReviewsEnforcementLevel() string {
+	if r == nil || r.PullRequestReviewsEnforcementLevel == nil {
+		return enforcementLevelOff
+	}
+	return *r.PullRequestReviewsEnforcementLevel
+}
+
+func (r *BranchProtectionRule) GetRequiredApprovingReviewCount() int {
+	if r == nil || r.RequiredApprovingReviewCount == nil {
+		return 0
+	}
+	return *r.RequiredApprovingReviewCount
+}
+
+func (r *BranchProtectionRule) GetRequiredStatusChecksEnforcementLevel() string {
+	if r == nil || r.RequiredStatusChecksEnforcementLevel == nil {
+		return enforcementLevelOff
+	}
+	return *r.RequiredStatusChecksEnforcementLevel
+}
+
+func (r *BranchProtectionRule) GetAllowForcePushesEnforcementLevel() string {
+	if r == nil || r.AllowForcePushesEnforcementLevel == nil {
+		return enforcementLevelOff
+	}
+	return *r.AllowForcePushesEnforcementLevel
+}
+
+// describeReviews describes the required reviews of the rule.
+func (r *BranchProtectionRule) describeReviews() string {
+	if r.GetPullRequestReviewsEnforcementLevel() == enforcementLevelOff {
+		return "not required"
+	}
+
+	count := r.GetRequiredApprovingReviewCount()
+	if count == 1 {
+		return "1 approving review"
+	}
+	return fmt.Sprintf("%d approving reviews", count)
+}
+
+// describeStatusChecks describes the required status checks of the rule.
+func (r *BranchProtectionRule) describeStatusChecks() string {
+	if r == nil || len(r.RequiredStatusChecks) == 0 || r.GetRequiredStatusChecksEnforcementLevel() == enforcementLevelOff {
+		return "not required"
+	}
+	return "`" + strings.Join(r.RequiredStatusChecks, "`, `") + "`"
+}
+
+// describeForcePushes describes who the rule allows to force push.
+func (r *BranchProtectionRule) describeForcePushes() string {
+	switch r.GetAllowForcePushesEnforcementLevel() {
+	case enforcementLevelOff:
+		return "not allowed"
+	case "non_admins":
+		return "allowed for non-admins"
+	default:
+		return "allowed for everyone"
+	}
+}
+
+// branchProtectionSetting is a setting of a branch protection rule described in the posts.
+type branchProtectionSetting struct {
+	name     string
+	describe func(*BranchProtectionRule) string
+}
+
+var branchProtectionSettings = []branchProtectionSetting{
+	{"Required reviews", (*BranchProtectionRule).describeReviews},
+	{"Required status checks", (*BranchProtectionRule).describeStatusChecks},
+	{"Force pushes", (*BranchProtectionRule).describeForcePushes},
+}
+
+// BranchProtectionRuleChange is the previous value of a field of an edited rule.
+type BranchProtectionRuleChange struct {
+	From json.RawMessage `json:"from,omitempty"`
+}
+
+// BranchProtectionRuleEvent is triggered when a branch protection rule is created, edited or deleted.
+// go-github doesn't support it yet.
+type BranchProtectionRuleEvent struct {
+	Action *string               `json:"action,omitempty"`
+	Rule   *BranchProtectionRule `json:"rule,omitempty"`
+	// Changes are the previous values of the fields changed by an edit, by field name.
+	Changes map[string]*BranchProtectionRuleChange `json:"changes,omitempty"`
+	Repo    *github.Repository                     `json:"repository,omitempty"`
+	Sender  *github.User                           `json:"sender,omitempty"`
+}
+
+func (e *BranchProtectionRuleEvent) GetAction() string {
+	if e == nil || e.Action == nil {
+		return ""
+	}
+	return *e.Action
+}
+
+func (e *BranchProtectionRuleEvent) GetRule() *BranchProtectionRule {
+	if e == nil {
+		return nil
+	}
+	return e.Rule
+}
+
+func (e *BranchProtectionRuleEvent) GetRepo() *github.Repository {
+	if e == nil {
+		return nil
+	}
+	return e.Repo
+}
+
+func (e *BranchProtectionRuleEvent) GetSender() *github.User {
+	if e == nil {
+		return nil
+	}
+	return e.Sender
+}
+
+// previousRule returns the rule as it was before an edit, by applying the previous values of the
+// changed fields to the edited rule.
+func (e *BranchProtectionRuleEvent) previousRule() (*BranchProtectionRule, error) {
+	value, err := json.Marshal(e.GetRule())
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err = json.Unmarshal(value, &fields); err != nil {
+		return nil, err
+	}
+	for name, change := range e.Changes {
+		if change != nil && len(change.From) > 0 {
+			fields[name] = change.From
+		}
+	}
+
+	if value, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+
+	var previous *BranchProtectionRule
+	if err = json.Unmarshal(value, &previous); err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// GetSettingDescriptions describes the settings of the rule. For edited rules, only the settings
+// that changed are described, along with their previous value.
+func (e *BranchProtectionRuleEvent) GetSettingDescriptions() []string {
+	rule := e.GetRule()
+
+	switch e.GetAction() {
+	case "created":
+		descriptions := make([]string, 0, len(branchProtectionSettings))
+		for _, setting := range branchProtectionSettings {
+			descriptions = append(descriptions, fmt.Sprintf("%s: %s", setting.name, setting.describe(rule)))
+		}
+		return descriptions
+	case "edited":
+		previous, err := e.previousRule()
+		if err != nil {
+			return nil
+		}
+
+		descriptions := []string{}
+		for _, setting := range branchProtectionSettings {
+			before, after := setting.describe(previous), setting.describe(rule)
+			if before != after {
+				descriptions = append(descriptions, fmt.Sprintf("%s: %s → %s", setting.name, before, after))
+			}
+		}
+		return descriptions
+	default:
+		return nil
+	}
+}
+
+func parseBranchProtectionRuleEvent(body []byte) (*BranchProtectionRuleEvent, error) {
+	var event *BranchProtectionRuleEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (p *Plugin) postBranchProtectionRuleEvent(event *BranchProtectionRuleEvent, delivery *webhookDelivery) {
+	action := event.GetAction()
+	if action != "created" && action != "edited" && action != "deleted" {
+		return
+	}
+
+	// Edits of settings not described in the posts aren't worth a post
+	if action == "edited" && len(event.GetSettingDescriptions()) == 0 {
+		return
+	}
+
+	repo := event.GetRepo()
+
+	subs := delivery.matchSubscriptions(p.GetSubscribedChannelsForRepository(repo))
+	if len(subs) == 0 {
+		return
+	}
+
+	message, err := renderTemplate("branchProtectionRule", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	post := &model.Post{
+		UserId:  p.BotUserID,
+		Type:    "custom_git_branch_protection",
+		Props:   eventPostProps(repo.GetFullName(), objectTypeRef, event.GetRule().GetName(), branchProtectionRuleEventType+"."+action),
+		Message: message,
+	}
+
+	for _, sub := range subs {
+		if !sub.BranchProtection() {
+			continue
+		}
+
+		if !p.isSubscriptionCreatorOrgMember(sub) {
+			continue
+		}
+
+		if p.excludeSender(event.GetSender(), sub) {
+			continue
+		}
+
+		post.ChannelId = sub.ChannelID
+		p.postSubscriptionEvent(post, sub, featureBranchProtection, delivery)
+	}
+}
+
+// isSubscriptionCreatorOrgMember checks if the creator of a subscription is a member of the
+// organization the plugin is locked to. It's always true when the plugin isn't locked to one.
+func (p *Plugin) isSubscriptionCreatorOrgMember(sub *Subscription) bool {
+	if !p.isOrganizationLocked() {
+		return true
+	}
+
+	info, apiErr := p.getGitHubUserInfo(sub.CreatorID)
+	if apiErr != nil {
+		p.API.LogWarn("Failed to get subscription creator", "channelID", sub.ChannelID, "error", apiErr.Message)
+		return false
+	}
+
+	creator := &github.User{Login: &info.GitHubUsername}
+
+	return p.isUserOrganizationMember(info, creator, strings.TrimSpace(p.getConfiguration().GitHubOrg))
+}
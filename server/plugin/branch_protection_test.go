@@ -0,0 +1,201 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// branchProtectionRuleFixture is a branch_protection_rule payload as sent by GitHub, trimmed to the fields in use.
+const branchProtectionRuleFixture = `{
+  "action": "%s",
+  "rule": {
+    "id": 21,
+    "name": "main",
+    "pull_request_reviews_enforcement_level": "non_admins",
+    "required_approving_review_count": 2,
+    "required_status_checks": ["ci", "lint"],
+    "required_status_checks_enforcement_level": "everyone",
+    "allow_force_pushes_enforcement_level": "off"
+  },
+  "changes": %s,
+  "repository": {
+    "full_name": "mattermost/mattermost-plugin-github",
+    "html_url": "https://github.com/mattermost/mattermost-plugin-github",
+    "private": false
+  },
+  "sender": {"login": "panda", "html_url": "https://github.com/panda"}
+}`
+
+func loadBranchProtectionRuleFixture(t *testing.T, action, changes string) *BranchProtectionRuleEvent {
+	event, err := parseBranchProtectionRuleEvent([]byte(fmt.Sprintf(branchProtectionRuleFixture, action, changes)))
+	require.NoError(t, err)
+	return event
+}
+
+func TestBranchProtectionRuleSettingDescriptions(t *testing.T) {
+	t.Run("created", func(t *testing.T) {
+		event := loadBranchProtectionRuleFixture(t, "created", "null")
+		assert.Equal(t, []string{
+			"Required reviews: 2 approving reviews",
+			"Required status checks: `ci`, `lint`",
+			"Force pushes: not allowed",
+		}, event.GetSettingDescriptions())
+	})
+
+	t.Run("edited", func(t *testing.T) {
+		event := loadBranchProtectionRuleFixture(t, "edited", `{
+			"required_approving_review_count": {"from": 1},
+			"required_status_checks": {"from": ["ci"]},
+			"allow_force_pushes_enforcement_level": {"from": "everyone"}
+		}`)
+		assert.Equal(t, []string{
+			"Required reviews: 1 approving review → 2 approving reviews",
+			"Required status checks: `ci` → `ci`, `lint`",
+			"Force pushes: allowed for everyone → not allowed",
+		}, event.GetSettingDescriptions())
+	})
+
+	t.Run("reviews disabled", func(t *testing.T) {
+		event := loadBranchProtectionRuleFixture(t, "edited", `{"pull_request_reviews_enforcement_level": {"from": "off"}}`)
+		assert.Equal(t, []string{"Required reviews: not required → 2 approving reviews"}, event.GetSettingDescriptions())
+	})
+
+	t.Run("edit of other settings", func(t *testing.T) {
+		event := loadBranchProtectionRuleFixture(t, "edited", `{"dismiss_stale_reviews_on_push": {"from": true}}`)
+		assert.Empty(t, event.GetSettingDescriptions())
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		event := loadBranchProtectionRuleFixture(t, "deleted", "null")
+		assert.Empty(t, event.GetSettingDescriptions())
+	})
+}
+
+func TestBranchProtectionRuleTemplate(t *testing.T) {
+	event := loadBranchProtectionRuleFixture(t, "edited", `{"required_approving_review_count": {"from": 1}}`)
+
+	expected := `
+[\[mattermost/mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) [panda](https://github.com/panda) edited the branch protection rule for ` + "`main`" + `
+* Required reviews: 1 approving review → 2 approving reviews
+`
+	actual, err := renderTemplate("branchProtectionRule", event)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+
+	event = loadBranchProtectionRuleFixture(t, "deleted", "null")
+	expected = `
+[\[mattermost/mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) [panda](https://github.com/panda) deleted the branch protection rule for ` + "`main`" + `
+`
+	actual, err = renderTemplate("branchProtectionRule", event)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestPostBranchProtectionRuleEvent(t *testing.T) {
+	subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"mattermost/mattermost-plugin-github": {
+			{ChannelID: "memberChannelID", CreatorID: "memberID", Features: featureBranchProtection, Repository: "mattermost/mattermost-plugin-github"},
+			{ChannelID: "outsiderChannelID", CreatorID: "outsiderID", Features: featureBranchProtection, Repository: "mattermost/mattermost-plugin-github"},
+			{ChannelID: "pushesChannelID", CreatorID: "memberID", Features: featurePushes, Repository: "mattermost/mattermost-plugin-github"},
+		},
+	}})
+	require.NoError(t, err)
+
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+
+	userInfo := func(userID, login string) []byte {
+		value, err := json.Marshal(&GitHubUserInfo{
+			UserID:         userID,
+			Token:          &oauth2.Token{AccessToken: token},
+			GitHubUsername: login,
+		})
+		require.NoError(t, err)
+		return value
+	}
+
+	t.Run("posted to subscriptions with the feature", func(t *testing.T) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{})
+		api := &plugintest.API{}
+		api.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+		api.On("KVGet", SubscriptionsKey).Return(subscriptions, nil)
+		api.On("KVGet", RoutesKey).Return(nil, nil)
+		api.On("KVGet", "memberID-muted-users").Return(nil, nil)
+		api.On("KVGet", "outsiderID-muted-users").Return(nil, nil)
+		for _, channelID := range []string{"memberChannelID", "outsiderChannelID"} {
+			channelID := channelID
+			api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+				return post.ChannelId == channelID &&
+					post.Type == "custom_git_branch_protection" &&
+					post.GetProp(postPropEventType) == "branch_protection_rule.created" &&
+					post.GetProp(postPropObjectID) == "main"
+			})).Return(&model.Post{}, nil).Once()
+		}
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.postBranchProtectionRuleEvent(loadBranchProtectionRuleFixture(t, "created", "null"), testWebhookDelivery(p))
+	})
+
+	t.Run("only subscriptions of org members when locked to an org", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/v3/orgs/org/members/member" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer ts.Close()
+
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{
+			GitHubOrg:           "org",
+			EncryptionKey:       encryptionKey,
+			EnterpriseBaseURL:   ts.URL,
+			EnterpriseUploadURL: ts.URL,
+		})
+		api := &plugintest.API{}
+		api.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+		api.On("KVGet", SubscriptionsKey).Return(subscriptions, nil)
+		api.On("KVGet", RoutesKey).Return(nil, nil)
+		api.On("KVGet", "memberID"+githubTokenKey).Return(userInfo("memberID", "member"), nil)
+		api.On("KVGet", "outsiderID"+githubTokenKey).Return(userInfo("outsiderID", "outsider"), nil)
+		api.On("KVGet", orgMembersGenerationKeyFor("org")).Return(nil, nil)
+		api.On("KVGet", orgMembershipKeyFor("org", "", "memberID", "member")).Return(nil, nil)
+		api.On("KVGet", orgMembershipKeyFor("org", "", "outsiderID", "outsider")).Return(nil, nil)
+		api.On("KVGet", "memberID-muted-users").Return(nil, nil)
+		api.On("KVSetWithExpiry", orgMembershipKeyFor("org", "", "memberID", "member"), []byte("true"), int64(orgMembershipCacheTTL/time.Second)).Return(nil)
+		api.On("KVSetWithExpiry", orgMembershipKeyFor("org", "", "outsiderID", "outsider"), []byte("false"), int64(orgMembershipCacheTTL/time.Second)).Return(nil)
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "memberChannelID"
+		})).Return(&model.Post{}, nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.postBranchProtectionRuleEvent(loadBranchProtectionRuleFixture(t, "deleted", "null"), testWebhookDelivery(p))
+	})
+
+	t.Run("edit of other settings not posted", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.postBranchProtectionRuleEvent(loadBranchProtectionRuleFixture(t, "edited", `{"dismiss_stale_reviews_on_push": {"from": true}}`), testWebhookDelivery(p))
+	})
+}
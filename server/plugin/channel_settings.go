@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	channelSettingsKey = "_channelsettings"
+
+	renderStyleDefault   = "default"
+	renderStyleSkipBody  = "skip-body"
+	renderStyleCollapsed = "collapsed"
+)
+
+var validRenderStyles = []string{renderStyleDefault, renderStyleSkipBody, renderStyleCollapsed}
+
+func checkRenderStyle(style string) error {
+	if !containsValue(validRenderStyles, style) {
+		return errors.Errorf("Invalid render style %q. Accepted values are: %s.", style, strings.Join(validRenderStyles, ", "))
+	}
+
+	return nil
+}
+
+// ChannelSettings holds the defaults applying to all subscriptions of a channel.
+type ChannelSettings struct {
+	RenderStyle string `json:",omitempty"`
+}
+
+func (p *Plugin) getChannelSettings(channelID string) (*ChannelSettings, error) {
+	value, appErr := p.API.KVGet(channelID + channelSettingsKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get channel settings from KV store")
+	}
+
+	settings := &ChannelSettings{}
+	if value == nil {
+		return settings, nil
+	}
+
+	if err := json.Unmarshal(value, settings); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal channel settings")
+	}
+
+	return settings, nil
+}
+
+func (p *Plugin) storeChannelSettings(channelID string, settings *ChannelSettings) error {
+	if *settings == (ChannelSettings{}) {
+		if appErr := p.API.KVDelete(channelID + channelSettingsKey); appErr != nil {
+			return errors.Wrap(appErr, "could not delete channel settings from KV store")
+		}
+		return nil
+	}
+
+	value, err := json.Marshal(settings)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal channel settings")
+	}
+
+	if appErr := p.API.KVSet(channelID+channelSettingsKey, value); appErr != nil {
+		return errors.Wrap(appErr, "could not store channel settings in KV store")
+	}
+
+	return nil
+}
+
+// getRenderStyle returns the render style of a subscription: its own flag if set, otherwise the
+// setting of its channel, otherwise the default style.
+func (p *Plugin) getRenderStyle(sub *Subscription) string {
+	if sub.Flags.RenderStyle != "" {
+		return sub.Flags.RenderStyle
+	}
+
+	settings, err := p.getChannelSettings(sub.ChannelID)
+	if err != nil {
+		p.API.LogWarn("Failed to get channel settings", "channelID", sub.ChannelID, "error", err.Error())
+		return renderStyleDefault
+	}
+
+	if settings.RenderStyle != "" {
+		return settings.RenderStyle
+	}
+
+	return renderStyleDefault
+}
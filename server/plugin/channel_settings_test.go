@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRenderStyle(t *testing.T) {
+	collapsedChannel, err := json.Marshal(&ChannelSettings{RenderStyle: renderStyleCollapsed})
+	require.NoError(t, err)
+
+	for name, test := range map[string]struct {
+		subscriptionStyle string
+		channelSettings   []byte
+		expected          string
+	}{
+		"subscription flag beats channel setting": {subscriptionStyle: renderStyleSkipBody, channelSettings: collapsedChannel, expected: renderStyleSkipBody},
+		"channel setting beats default":           {channelSettings: collapsedChannel, expected: renderStyleCollapsed},
+		"subscription flag without channel":       {subscriptionStyle: renderStyleSkipBody, expected: renderStyleSkipBody},
+		"default":                                 {expected: renderStyleDefault},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := NewPlugin()
+			api := &plugintest.API{}
+			api.On("KVGet", "channelID"+channelSettingsKey).Return(test.channelSettings, nil)
+			p.SetAPI(api)
+
+			sub := &Subscription{ChannelID: "channelID", Flags: SubscriptionFlags{RenderStyle: test.subscriptionStyle}}
+			assert.Equal(t, test.expected, p.getRenderStyle(sub))
+		})
+	}
+}
+
+func TestSubscriptionFlagsRenderStyle(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(renderStyleFlag, renderStyleCollapsed))
+	assert.Equal(t, "--render-style collapsed", flags.String())
+
+	assert.Error(t, flags.SetFlag(renderStyleFlag, "compact"))
+	assert.Equal(t, renderStyleCollapsed, flags.RenderStyle)
+}
+
+func TestStoreChannelSettings(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("KVSet", "channelID"+channelSettingsKey, []byte(`{"RenderStyle":"collapsed"}`)).Return(nil).Once()
+	api.On("KVDelete", "channelID"+channelSettingsKey).Return(nil).Once()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	require.NoError(t, p.storeChannelSettings("channelID", &ChannelSettings{RenderStyle: renderStyleCollapsed}))
+	require.NoError(t, p.storeChannelSettings("channelID", &ChannelSettings{}))
+}
@@ -0,0 +1,338 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	channelSuggestionsJobKey          = "channel_suggestions"
+	channelSuggestionsRepositoriesKey = "channel_suggestions_repositories"
+	channelSuggestionDismissedKey     = "_channelsuggestiondismissed"
+
+	// channelSuggestionsJobInterval is how often the job checks if the cached repositories of the
+	// organization are older than channelSuggestionsRefreshInterval.
+	channelSuggestionsJobInterval     = time.Hour
+	channelSuggestionsRefreshInterval = 24 * time.Hour
+
+	channelSuggestionActionSubscribe = "subscribe"
+	channelSuggestionActionDismiss   = "dismiss"
+
+	channelSuggestionContextAction     = "action"
+	channelSuggestionContextRepository = "repository"
+)
+
+// suggestionRepositories are the cached public repositories of the organization new channels are
+// matched against.
+type suggestionRepositories struct {
+	Org          string    `json:"org"`
+	Repositories []string  `json:"repositories"`
+	RefreshedAt  time.Time `json:"refreshed_at"`
+}
+
+// normalizeChannelSuggestionName reduces a channel or repository name to its lowercase letters and
+// digits, so that payments-service, payments_service and PaymentsService are the same slug.
+func normalizeChannelSuggestionName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return -1
+		}
+	}, name)
+}
+
+// findSuggestedRepository returns the repository a channel is named after, or an empty string.
+// A repository with the exact name of the channel wins, otherwise the slug of the name or display
+// name of the channel must match the slug of a single repository.
+func findSuggestedRepository(channel *model.Channel, repositories []string) string {
+	names := []string{channel.Name, channel.DisplayName}
+
+	for _, name := range names {
+		for _, repo := range repositories {
+			if name != "" && strings.EqualFold(name, repo) {
+				return repo
+			}
+		}
+	}
+
+	matches := []string{}
+	for _, name := range names {
+		slug := normalizeChannelSuggestionName(name)
+		if slug == "" {
+			continue
+		}
+		for _, repo := range repositories {
+			if slug == normalizeChannelSuggestionName(repo) && !SliceContainsString(matches, repo) {
+				matches = append(matches, repo)
+			}
+		}
+	}
+
+	// Suggesting one of several matching repositories would be a guess
+	if len(matches) != 1 {
+		return ""
+	}
+
+	return matches[0]
+}
+
+func channelSuggestionDismissedKeyFor(channelID string) string {
+	return hashKey(channelSuggestionDismissedKey, channelID)
+}
+
+func (p *Plugin) isChannelSuggestionDismissed(channelID string) (bool, error) {
+	value, appErr := p.API.KVGet(channelSuggestionDismissedKeyFor(channelID))
+	if appErr != nil {
+		return false, errors.Wrap(appErr, "could not get channel suggestion marker from KV store")
+	}
+
+	return value != nil, nil
+}
+
+func (p *Plugin) dismissChannelSuggestion(channelID string) error {
+	if appErr := p.API.KVSet(channelSuggestionDismissedKeyFor(channelID), []byte(time.Now().UTC().Format(time.RFC3339))); appErr != nil {
+		return errors.Wrap(appErr, "could not store channel suggestion marker in KV store")
+	}
+
+	return nil
+}
+
+func (p *Plugin) getSuggestionRepositories() (*suggestionRepositories, error) {
+	value, appErr := p.API.KVGet(channelSuggestionsRepositoriesKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get repositories from KV store")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	repositories := &suggestionRepositories{}
+	if err := json.Unmarshal(value, repositories); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal repositories")
+	}
+
+	return repositories, nil
+}
+
+func (p *Plugin) storeSuggestionRepositories(repositories *suggestionRepositories) error {
+	value, err := json.Marshal(repositories)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal repositories")
+	}
+
+	if appErr := p.API.KVSet(channelSuggestionsRepositoriesKey, value); appErr != nil {
+		return errors.Wrap(appErr, "could not store repositories in KV store")
+	}
+
+	return nil
+}
+
+// listSuggestionRepositories lists the names of the public repositories of an organization. The
+// private ones are never suggested, as the suggestion is posted in a public channel.
+func listSuggestionRepositories(ctx context.Context, githubClient *github.Client, org string) ([]string, error) {
+	names := []string{}
+	opt := github.ListOptions{PerPage: 100}
+	for {
+		repos, resp, err := githubClient.Repositories.ListByOrg(ctx, org, &github.RepositoryListByOrgOptions{Type: "public", ListOptions: opt})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list repositories by org")
+		}
+		for _, repo := range repos {
+			if !repo.GetPrivate() && !repo.GetArchived() {
+				names = append(names, repo.GetName())
+			}
+		}
+		if resp.NextPage == 0 {
+			return names, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// refreshSuggestionRepositories refreshes the cached repositories of the organization once a day,
+// with the account of the first connected system admin.
+func (p *Plugin) refreshSuggestionRepositories() {
+	config := p.getConfiguration()
+	org := strings.TrimSpace(config.GitHubOrg)
+	if !config.SuggestSubscriptionsOnChannelCreate || org == "" {
+		return
+	}
+
+	cached, err := p.getSuggestionRepositories()
+	if err != nil {
+		p.API.LogWarn("Failed to get the repositories suggested to new channels", "error", err.Error())
+		return
+	}
+	if cached != nil && cached.Org == org && time.Since(cached.RefreshedAt) < channelSuggestionsRefreshInterval {
+		return
+	}
+
+	adminIDs, err := p.getSystemAdminIDs()
+	if err != nil {
+		p.API.LogWarn("Failed to get system admins", "error", err.Error())
+		return
+	}
+
+	for _, adminID := range adminIDs {
+		info, apiErr := p.getGitHubUserInfo(adminID)
+		if apiErr != nil {
+			continue
+		}
+
+		repositories, err := listSuggestionRepositories(context.Background(), p.getGithubClient(info), org)
+		if err != nil {
+			p.API.LogWarn("Failed to list the repositories suggested to new channels", "org", org, "userID", adminID, "error", err.Error())
+			continue
+		}
+
+		if err := p.storeSuggestionRepositories(&suggestionRepositories{Org: org, Repositories: repositories, RefreshedAt: time.Now()}); err != nil {
+			p.API.LogWarn("Failed to store the repositories suggested to new channels", "error", err.Error())
+		}
+		return
+	}
+
+	p.API.LogDebug("No connected system admin to list the repositories suggested to new channels", "org", org)
+}
+
+func (p *Plugin) getChannelSuggestionAction(name, action, repository string) *model.PostAction {
+	return &model.PostAction{
+		Name: name,
+		Integration: &model.PostActionIntegration{
+			URL: fmt.Sprintf("/plugins/%s/api/v1/channel_suggestion", Manifest.Id),
+			Context: map[string]interface{}{
+				channelSuggestionContextAction:     action,
+				channelSuggestionContextRepository: repository,
+			},
+		},
+	}
+}
+
+// ChannelHasBeenCreated suggests subscribing a new public channel named after a repository of the
+// organization to it. The channel is only subscribed if one of its members confirms.
+func (p *Plugin) ChannelHasBeenCreated(c *plugin.Context, channel *model.Channel) {
+	config := p.getConfiguration()
+	if !config.SuggestSubscriptionsOnChannelCreate || channel.Type != model.CHANNEL_OPEN {
+		return
+	}
+
+	dismissed, err := p.isChannelSuggestionDismissed(channel.Id)
+	if err != nil {
+		p.API.LogWarn("Failed to check the channel suggestion marker", "channelID", channel.Id, "error", err.Error())
+		return
+	}
+	if dismissed {
+		return
+	}
+
+	cached, err := p.getSuggestionRepositories()
+	if err != nil {
+		p.API.LogWarn("Failed to get the repositories suggested to new channels", "error", err.Error())
+		return
+	}
+	if cached == nil || cached.Org != strings.TrimSpace(config.GitHubOrg) {
+		return
+	}
+
+	repo := findSuggestedRepository(channel, cached.Repositories)
+	if repo == "" {
+		return
+	}
+
+	fullName := cached.Org + "/" + repo
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: channel.Id,
+		Message:   fmt.Sprintf("This channel looks like it's about the GitHub repository [%s](%s). Subscribe it to get the notifications of the repository here, or run `/github subscriptions add %s`.", fullName, p.getBaseURL()+fullName, fullName),
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Actions: []*model.PostAction{
+			p.getChannelSuggestionAction("Subscribe", channelSuggestionActionSubscribe, fullName),
+			p.getChannelSuggestionAction("Don't suggest again", channelSuggestionActionDismiss, fullName),
+		},
+	}})
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to suggest a subscription to a new channel", "channelID", channel.Id, "error", appErr.Error())
+	}
+}
+
+func (p *Plugin) handleChannelSuggestionAction(w http.ResponseWriter, r *http.Request, userID string) {
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a post action request.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	p.writeJSON(w, p.runChannelSuggestionAction(userID, request))
+}
+
+// runChannelSuggestionAction subscribes the channel to the suggested repository as the user, or
+// stops suggesting subscriptions in the channel. The suggestion is replaced with the outcome.
+func (p *Plugin) runChannelSuggestionAction(userID string, request *model.PostActionIntegrationRequest) *model.PostActionIntegrationResponse {
+	response := &model.PostActionIntegrationResponse{}
+
+	action, _ := request.Context[channelSuggestionContextAction].(string)
+	repository, _ := request.Context[channelSuggestionContextRepository].(string)
+
+	switch action {
+	case channelSuggestionActionDismiss:
+		if err := p.dismissChannelSuggestion(request.ChannelId); err != nil {
+			p.API.LogWarn("Failed to dismiss channel suggestion", "channelID", request.ChannelId, "error", err.Error())
+			response.EphemeralText = "Encountered an error dismissing the suggestion."
+			return response
+		}
+		response.Update = &model.Post{Message: fmt.Sprintf("Subscribing this channel to %s won't be suggested again.", repository)}
+		return response
+	case channelSuggestionActionSubscribe:
+	default:
+		response.EphemeralText = fmt.Sprintf("Unknown action %q.", action)
+		return response
+	}
+
+	dismissed, err := p.isChannelSuggestionDismissed(request.ChannelId)
+	if err != nil {
+		p.API.LogWarn("Failed to check the channel suggestion marker", "channelID", request.ChannelId, "error", err.Error())
+		response.EphemeralText = "Encountered an error subscribing this channel."
+		return response
+	}
+	if dismissed {
+		response.Update = &model.Post{Message: fmt.Sprintf("Subscribing this channel to %s won't be suggested again.", repository)}
+		return response
+	}
+
+	if p.getConfiguration().isCommandDisabled("subscriptions") {
+		response.EphemeralText = disabledCommandMessage
+		return response
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		response.EphemeralText = "You must connect your account to GitHub first. Either click on the GitHub logo in the bottom left of the screen or enter `/github connect`."
+		return response
+	}
+
+	owner, repo := parseOwnerAndRepo(repository, p.getBaseURL())
+	if err := p.Subscribe(context.Background(), p.getGithubClient(info), userID, owner, repo, request.ChannelId, defaultSubscriptionFeatures, SubscriptionFlags{}); err != nil {
+		response.EphemeralText = err.Error()
+		return response
+	}
+
+	subscriber := "someone"
+	if user, appErr := p.API.GetUser(userID); appErr == nil {
+		subscriber = "@" + user.Username
+	}
+	response.Update = &model.Post{Message: fmt.Sprintf("%s subscribed this channel to [%s](%s).", subscriber, repository, p.getBaseURL()+repository)}
+	return response
+}
@@ -0,0 +1,296 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestFindSuggestedRepository(t *testing.T) {
+	repositories := []string{"payments-service", "payments_service_v2", "PaymentsApi", "web-app", "webapp", "docs"}
+
+	for _, tc := range []struct {
+		name        string
+		displayName string
+		expected    string
+	}{
+		{name: "payments-service", expected: "payments-service"},
+		{name: "paymentsapi", expected: "PaymentsApi"},
+		{name: "payments-api", expected: "PaymentsApi"},
+		{name: "abc123", displayName: "Payments Service V2", expected: "payments_service_v2"},
+		// The exact name wins over another repository with the same slug
+		{name: "web-app", expected: "web-app"},
+		// Close but not exact names aren't suggested
+		{name: "payments-services"},
+		{name: "payment-service"},
+		{name: "payments-service-v3"},
+		{name: "docs-team", displayName: "Docs Team"},
+		{name: "app"},
+		// Ambiguous slugs aren't suggested
+		{name: "web_app"},
+		{name: "abc123", displayName: "Web App"},
+		{name: "abc123", displayName: "---"},
+	} {
+		t.Run(tc.name+" "+tc.displayName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, findSuggestedRepository(&model.Channel{Name: tc.name, DisplayName: tc.displayName}, repositories))
+		})
+	}
+
+	assert.Empty(t, findSuggestedRepository(&model.Channel{Name: "docs"}, nil))
+}
+
+func TestRefreshSuggestionRepositories(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "adminID", GitHubUsername: "admin", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v3/orgs/acme/repos" && r.URL.Query().Get("page") == "":
+			requests++
+			assert.Equal(t, "public", r.URL.Query().Get("type"))
+			w.Header().Set("Link", fmt.Sprintf(`<%s/api/v3/orgs/acme/repos?type=public&page=2>; rel="next"`, "http://"+r.Host))
+			fmt.Fprint(w, `[{"name": "payments-service"}, {"name": "secret", "private": true}]`)
+		case r.URL.Path == "/api/v3/orgs/acme/repos":
+			requests++
+			fmt.Fprint(w, `[{"name": "legacy", "archived": true}, {"name": "docs"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func(config *Configuration) (*Plugin, map[string][]byte) {
+		p := NewPlugin()
+		config.EncryptionKey = encryptionKey
+		config.EnterpriseBaseURL = ts.URL + "/"
+		config.EnterpriseUploadURL = ts.URL + "/"
+		p.setConfiguration(config)
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		store["adminID"+githubTokenKey] = info
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("GetUsers", &model.UserGetOptions{Role: model.SYSTEM_ADMIN_ROLE_ID, Active: true, Page: 0, PerPage: adminsPerPage}).Return([]*model.User{{Id: "otherAdminID"}, {Id: "adminID"}}, nil)
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+		return p, store
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		requests = 0
+		p, store := setup(&Configuration{GitHubOrg: "acme"})
+
+		p.refreshSuggestionRepositories()
+		assert.Zero(t, requests)
+		assert.NotContains(t, store, channelSuggestionsRepositoriesKey)
+	})
+
+	t.Run("refreshed with a connected admin", func(t *testing.T) {
+		requests = 0
+		p, _ := setup(&Configuration{GitHubOrg: "acme", SuggestSubscriptionsOnChannelCreate: true})
+
+		p.refreshSuggestionRepositories()
+		assert.Equal(t, 2, requests)
+
+		cached, err := p.getSuggestionRepositories()
+		require.NoError(t, err)
+		require.NotNil(t, cached)
+		assert.Equal(t, "acme", cached.Org)
+		assert.Equal(t, []string{"payments-service", "docs"}, cached.Repositories)
+
+		// Fresh repositories aren't listed again
+		p.refreshSuggestionRepositories()
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("stale or of another organization", func(t *testing.T) {
+		for _, cached := range []*suggestionRepositories{
+			{Org: "acme", Repositories: []string{"old"}, RefreshedAt: time.Now().Add(-25 * time.Hour)},
+			{Org: "other", Repositories: []string{"old"}, RefreshedAt: time.Now()},
+		} {
+			requests = 0
+			p, _ := setup(&Configuration{GitHubOrg: "acme", SuggestSubscriptionsOnChannelCreate: true})
+			require.NoError(t, p.storeSuggestionRepositories(cached))
+
+			p.refreshSuggestionRepositories()
+			assert.Equal(t, 2, requests)
+
+			refreshed, err := p.getSuggestionRepositories()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"payments-service", "docs"}, refreshed.Repositories)
+		}
+	})
+}
+
+func TestChannelHasBeenCreated(t *testing.T) {
+	setup := func(config *Configuration) (*Plugin, *plugintest.API, map[string][]byte) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(config)
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		p.SetAPI(api)
+		cached, err := json.Marshal(&suggestionRepositories{Org: "acme", Repositories: []string{"payments-service", "docs"}, RefreshedAt: time.Now()})
+		require.NoError(t, err)
+		store[channelSuggestionsRepositoriesKey] = cached
+		return p, api, store
+	}
+	enabled := &Configuration{GitHubOrg: "acme", SuggestSubscriptionsOnChannelCreate: true}
+
+	t.Run("suggested", func(t *testing.T) {
+		p, api, store := setup(enabled)
+		var post *model.Post
+		api.On("CreatePost", mock.Anything).Return(func(p *model.Post) *model.Post {
+			post = p
+			return p
+		}, nil)
+
+		p.ChannelHasBeenCreated(nil, &model.Channel{Id: "channelID", Name: "payments_service", DisplayName: "Payments Service", Type: model.CHANNEL_OPEN})
+		require.NotNil(t, post)
+		assert.Equal(t, "botID", post.UserId)
+		assert.Equal(t, "channelID", post.ChannelId)
+		assert.Contains(t, post.Message, "[acme/payments-service](https://github.com/acme/payments-service)")
+		assert.Contains(t, post.Message, "`/github subscriptions add acme/payments-service`")
+
+		attachments := post.Attachments()
+		require.Len(t, attachments, 1)
+		require.Len(t, attachments[0].Actions, 2)
+		assert.Equal(t, "Subscribe", attachments[0].Actions[0].Name)
+		assert.Equal(t, channelSuggestionActionSubscribe, attachments[0].Actions[0].Integration.Context[channelSuggestionContextAction])
+		assert.Equal(t, "acme/payments-service", attachments[0].Actions[0].Integration.Context[channelSuggestionContextRepository])
+		assert.Equal(t, channelSuggestionActionDismiss, attachments[0].Actions[1].Integration.Context[channelSuggestionContextAction])
+
+		// Nothing is subscribed until a member confirms
+		assert.NotContains(t, store, SubscriptionsKey)
+	})
+
+	for name, tc := range map[string]struct {
+		config  *Configuration
+		channel *model.Channel
+	}{
+		"disabled":           {config: &Configuration{GitHubOrg: "acme"}, channel: &model.Channel{Id: "channelID", Name: "payments-service", Type: model.CHANNEL_OPEN}},
+		"private channel":    {config: enabled, channel: &model.Channel{Id: "channelID", Name: "payments-service", Type: model.CHANNEL_PRIVATE}},
+		"no matching repo":   {config: enabled, channel: &model.Channel{Id: "channelID", Name: "payments-services", Type: model.CHANNEL_OPEN}},
+		"dismissed":          {config: enabled, channel: &model.Channel{Id: "dismissedID", Name: "payments-service", Type: model.CHANNEL_OPEN}},
+		"other organization": {config: &Configuration{GitHubOrg: "other", SuggestSubscriptionsOnChannelCreate: true}, channel: &model.Channel{Id: "channelID", Name: "payments-service", Type: model.CHANNEL_OPEN}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p, api, store := setup(tc.config)
+			store[channelSuggestionDismissedKeyFor("dismissedID")] = []byte("2024-03-02T10:00:00Z")
+
+			p.ChannelHasBeenCreated(nil, tc.channel)
+			api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		})
+	}
+}
+
+func TestRunChannelSuggestionAction(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/repos/acme/payments-service":
+			fmt.Fprint(w, `{"full_name": "acme/payments-service"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func() (*Plugin, map[string][]byte) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/", GitHubOrg: "acme", SuggestSubscriptionsOnChannelCreate: true})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		store["userID"+githubTokenKey] = info
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("int64")).Return(func(key string, value []byte, ttl int64) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("GetUser", "userID").Return(&model.User{Id: "userID", Username: "alice"}, nil)
+		p.SetAPI(api)
+		return p, store
+	}
+	request := func(action string) *model.PostActionIntegrationRequest {
+		return &model.PostActionIntegrationRequest{
+			ChannelId: "channelID",
+			Context: map[string]interface{}{
+				channelSuggestionContextAction:     action,
+				channelSuggestionContextRepository: "acme/payments-service",
+			},
+		}
+	}
+
+	t.Run("subscribe", func(t *testing.T) {
+		p, _ := setup()
+
+		response := p.runChannelSuggestionAction("userID", request(channelSuggestionActionSubscribe))
+		assert.Empty(t, response.EphemeralText)
+		require.NotNil(t, response.Update)
+		assert.Equal(t, "@alice subscribed this channel to [acme/payments-service]("+ts.URL+"/acme/payments-service).", response.Update.Message)
+
+		subs, err := p.GetSubscriptionsByChannel("channelID")
+		require.NoError(t, err)
+		require.Len(t, subs, 1)
+		assert.Equal(t, "acme/payments-service", subs[0].Repository)
+		assert.Equal(t, "userID", subs[0].CreatorID)
+		assert.Equal(t, defaultSubscriptionFeatures, subs[0].Features)
+	})
+
+	t.Run("subscribe without a connected account", func(t *testing.T) {
+		p, store := setup()
+
+		response := p.runChannelSuggestionAction("otherUserID", request(channelSuggestionActionSubscribe))
+		assert.Contains(t, response.EphemeralText, "You must connect your account to GitHub first.")
+		assert.Nil(t, response.Update)
+		assert.NotContains(t, store, SubscriptionsKey)
+	})
+
+	t.Run("dismiss", func(t *testing.T) {
+		p, store := setup()
+
+		response := p.runChannelSuggestionAction("userID", request(channelSuggestionActionDismiss))
+		require.NotNil(t, response.Update)
+		assert.Equal(t, "Subscribing this channel to acme/payments-service won't be suggested again.", response.Update.Message)
+		assert.Contains(t, store, channelSuggestionDismissedKeyFor("channelID"))
+
+		// A stale suggestion can't subscribe the channel anymore
+		response = p.runChannelSuggestionAction("userID", request(channelSuggestionActionSubscribe))
+		require.NotNil(t, response.Update)
+		assert.Equal(t, "Subscribing this channel to acme/payments-service won't be suggested again.", response.Update.Message)
+		assert.NotContains(t, store, SubscriptionsKey)
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		p, _ := setup()
+
+		response := p.runChannelSuggestionAction("userID", request("merge"))
+		assert.Equal(t, `Unknown action "merge".`, response.EphemeralText)
+	})
+}
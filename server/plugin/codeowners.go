@@ -0,0 +1,237 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	suggestReviewersFlag = "suggest-reviewers"
+
+	codeOwnersKey = "_codeowners"
+	// codeOwnersCacheTTL is how long the CODEOWNERS file of a repository is cached, in seconds.
+	codeOwnersCacheTTL = 60 * 60
+)
+
+// codeOwnersPaths are where GitHub looks for the CODEOWNERS file of a repository, in order.
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeOwnersRule is a line of a CODEOWNERS file.
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	// matchesDirectories tells if the files under the directories matched by the pattern match too.
+	matchesDirectories bool
+	// owners are the users, teams and emails owning the matched files. Rules without owners
+	// remove the owners of the files matched by earlier rules.
+	owners []string
+}
+
+func (r *codeOwnersRule) matches(file string) bool {
+	if r.pattern.MatchString(file) {
+		return true
+	}
+	if !r.matchesDirectories {
+		return false
+	}
+
+	for dir := path.Dir(file); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if r.pattern.MatchString(dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compileCodeOwnersPattern compiles a pattern of a CODEOWNERS file, which follows the rules of
+// gitignore files: a pattern without a slash, other than a trailing one, matches at any depth, and
+// the files under a matched directory are matched too. As on GitHub, a pattern ending with `/*`
+// only matches the files directly in the directory.
+func compileCodeOwnersPattern(pattern string) (*codeOwnersRule, error) {
+	glob := pattern
+	if !strings.Contains(strings.TrimSuffix(glob, "/"), "/") {
+		glob = "**/" + glob
+	}
+
+	re, err := compilePathPattern(glob)
+	if err != nil {
+		return nil, err
+	}
+
+	return &codeOwnersRule{pattern: re, matchesDirectories: !strings.HasSuffix(pattern, "/*") && pattern != "*"}, nil
+}
+
+// stripCodeOwnersComment removes the comment of a line of a CODEOWNERS file. A `#` escaped with a
+// backslash doesn't start a comment.
+func stripCodeOwnersComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] != '\\') {
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// parseCodeOwners parses a CODEOWNERS file. Comments, blank lines and invalid patterns are skipped.
+func parseCodeOwners(content string) []*codeOwnersRule {
+	rules := []*codeOwnersRule{}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(stripCodeOwnersComment(line))
+		if len(fields) == 0 {
+			continue
+		}
+
+		rule, err := compileCodeOwnersPattern(strings.ReplaceAll(fields[0], `\#`, "#"))
+		if err != nil {
+			continue
+		}
+		rule.owners = fields[1:]
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// codeOwnersOf returns the owners of a file, given by the last rule matching it.
+func codeOwnersOf(rules []*codeOwnersRule, file string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].matches(file) {
+			return rules[i].owners
+		}
+	}
+
+	return nil
+}
+
+// suggestReviewers returns the owners of the changed files, in the order of the files, leaving out
+// the author of the pull request.
+func suggestReviewers(rules []*codeOwnersRule, files []string, author string) []string {
+	reviewers := []string{}
+	for _, file := range files {
+		for _, owner := range codeOwnersOf(rules, file) {
+			if strings.EqualFold(owner, "@"+author) || SliceContainsString(reviewers, owner) {
+				continue
+			}
+			reviewers = append(reviewers, owner)
+		}
+	}
+
+	return reviewers
+}
+
+// formatSuggestedReviewers renders the suggested reviewers of a pull request. Users linked to a
+// Mattermost account are mentioned, while the other users are named without an at-mention, as the
+// same username may belong to someone else on Mattermost.
+func formatSuggestedReviewers(reviewers []string) string {
+	names := make([]string, 0, len(reviewers))
+	for _, reviewer := range reviewers {
+		login := strings.TrimPrefix(reviewer, "@")
+		if !strings.HasPrefix(reviewer, "@") || strings.Contains(login, "/") {
+			// Emails and teams
+			names = append(names, reviewer)
+			continue
+		}
+
+		if username := lookupMattermostUsername(login); username != "" {
+			names = append(names, "@"+username)
+		} else {
+			names = append(names, login)
+		}
+	}
+
+	return "Suggested reviewers: " + strings.Join(names, ", ")
+}
+
+// codeOwnersFile is the cached CODEOWNERS file of a repository. Content is empty when the
+// repository has none.
+type codeOwnersFile struct {
+	Content string
+}
+
+// getCodeOwners returns the CODEOWNERS file of a repository, fetched with the client of the
+// subscription creator and cached for codeOwnersCacheTTL.
+func (p *Plugin) getCodeOwners(ctx context.Context, sub *Subscription, repo *github.Repository) (string, error) {
+	key := hashKey(codeOwnersKey, repo.GetFullName())
+
+	value, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return "", errors.Wrap(appErr, "could not get CODEOWNERS file from KV store")
+	}
+	if value != nil {
+		file := &codeOwnersFile{}
+		if err := json.Unmarshal(value, file); err != nil {
+			return "", errors.Wrap(err, "could not unmarshal CODEOWNERS file")
+		}
+		return file.Content, nil
+	}
+
+	info, apiErr := p.getGitHubUserInfo(sub.CreatorID)
+	if apiErr != nil {
+		return "", errors.Wrap(apiErr, "failed to get subscription creator info")
+	}
+	githubClient := p.githubConnect(*info.Token)
+
+	file := &codeOwnersFile{}
+	for _, filePath := range codeOwnersPaths {
+		content, _, resp, err := githubClient.Repositories.GetContents(ctx, repo.GetOwner().GetLogin(), repo.GetName(), filePath, nil)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get %s", filePath)
+		}
+
+		file.Content, err = content.GetContent()
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to decode %s", filePath)
+		}
+		break
+	}
+
+	value, err := json.Marshal(file)
+	if err != nil {
+		return "", errors.Wrap(err, "could not marshal CODEOWNERS file")
+	}
+	if appErr := p.API.KVSetWithExpiry(key, value, codeOwnersCacheTTL); appErr != nil {
+		p.API.LogWarn("Failed to cache CODEOWNERS file", "repo", repo.GetFullName(), "error", appErr.Error())
+	}
+
+	return file.Content, nil
+}
+
+// getSuggestedReviewers returns the line suggesting the owners of the files changed by a new pull
+// request as its reviewers. An empty string is returned when there's no suggestion, including when
+// the CODEOWNERS file or the changed files can't be fetched, as the suggestion is only a bonus.
+func (p *Plugin) getSuggestedReviewers(sub *Subscription, event *github.PullRequestEvent) string {
+	content, err := p.getCodeOwners(context.Background(), sub, event.GetRepo())
+	if err != nil {
+		p.API.LogDebug("Failed to get CODEOWNERS file to suggest reviewers", "repo", event.GetRepo().GetFullName(), "error", err.Error())
+		return ""
+	}
+
+	rules := parseCodeOwners(content)
+	if len(rules) == 0 {
+		return ""
+	}
+
+	files, err := p.getPullRequestFiles(sub, event)
+	if err != nil {
+		p.API.LogDebug("Failed to get pull request files to suggest reviewers", "repo", event.GetRepo().GetFullName(), "error", err.Error())
+		return ""
+	}
+
+	reviewers := suggestReviewers(rules, files, event.GetPullRequest().GetUser().GetLogin())
+	if len(reviewers) == 0 {
+		return ""
+	}
+
+	return formatSuggestedReviewers(reviewers)
+}
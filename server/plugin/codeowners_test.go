@@ -0,0 +1,199 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+const testCodeOwners = `# Lines starting with '#' are comments.
+
+# These owners are the default owners for everything in the repo,
+# unless a later match takes precedence.
+*       @global-owner1 @global-owner2
+
+# Order is important; the last matching pattern takes the most precedence.
+*.js    @js-owner #This is an inline comment.
+
+# Files of a directory anywhere in the repository.
+apps/ @octocat
+
+# Files directly in the docs directory, but not in its subdirectories.
+docs/*  docs@example.com
+
+# Files in the build directory at the root of the repository, and in its subdirectories.
+/build/ @doctocat
+
+# Files in any logs directory.
+**/logs @octo-org/logging
+
+# The payments service, except for its generated files which have no owners.
+/services/payments/ @octo-org/payments @alice
+/services/payments/generated/
+
+\#notes.md @alice
+`
+
+func TestParseCodeOwners(t *testing.T) {
+	rules := parseCodeOwners(testCodeOwners)
+	require.Len(t, rules, 9)
+
+	for file, owners := range map[string][]string{
+		"README.md":                          {"@global-owner1", "@global-owner2"},
+		"src/index.js":                       {"@js-owner"},
+		"apps/web/main.go":                   {"@octocat"},
+		"services/apps/main.go":              {"@octocat"},
+		"docs/getting-started.md":            {"docs@example.com"},
+		"docs/build-app/troubleshooting.md":  {"@global-owner1", "@global-owner2"},
+		"build/logs/today.log":               {"@octo-org/logging"},
+		"build/logs/archive/yesterday.log":   {"@octo-org/logging"},
+		"build/scripts/release.sh":           {"@doctocat"},
+		"other/build/main.go":                {"@global-owner1", "@global-owner2"},
+		"scripts/logs/run.log":               {"@octo-org/logging"},
+		"deeply/nested/logs/run.log":         {"@octo-org/logging"},
+		"services/payments/main.go":          {"@octo-org/payments", "@alice"},
+		"services/payments/api/handler.go":   {"@octo-org/payments", "@alice"},
+		"services/payments/generated/api.go": {},
+		"other/services/payments/main.go":    {"@global-owner1", "@global-owner2"},
+		"#notes.md":                          {"@alice"},
+	} {
+		assert.Equal(t, owners, codeOwnersOf(rules, file), file)
+	}
+
+	assert.Nil(t, codeOwnersOf(parseCodeOwners("/docs/ @octocat"), "README.md"))
+	assert.Empty(t, parseCodeOwners("# Only comments\n\n   \n"))
+}
+
+func TestSuggestReviewers(t *testing.T) {
+	rules := parseCodeOwners(testCodeOwners)
+
+	reviewers := suggestReviewers(rules, []string{"services/payments/main.go", "src/index.js", "services/payments/api/handler.go", "services/payments/generated/api.go"}, "alice")
+	assert.Equal(t, []string{"@octo-org/payments", "@js-owner"}, reviewers)
+
+	assert.Empty(t, suggestReviewers(rules, []string{"services/payments/generated/api.go"}, "bob"))
+}
+
+func TestFormatSuggestedReviewers(t *testing.T) {
+	defer func(callback func(string) string) { gitHubToUsernameMappingCallback = callback }(gitHubToUsernameMappingCallback)
+	gitHubToUsernameMappingCallback = func(login string) string {
+		if login == "bob" {
+			return "bob.mm"
+		}
+		return ""
+	}
+
+	assert.Equal(t, "Suggested reviewers: @org/team-a, alice, @bob.mm, docs@example.com", formatSuggestedReviewers([]string{"@org/team-a", "@alice", "@bob", "docs@example.com"}))
+}
+
+func TestSuggestedReviewersOnNewPullRequest(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "creatorID", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	event := &github.PullRequestEvent{
+		Action: github.String("opened"),
+		PullRequest: &github.PullRequest{
+			Number:  github.Int(1),
+			Title:   github.String("Refund payments"),
+			HTMLURL: github.String("https://github.com/owner/repo/pull/1"),
+			User:    &github.User{Login: github.String("carol")},
+			Head:    &github.PullRequestBranch{SHA: github.String("abc")},
+		},
+		Repo:   &github.Repository{FullName: github.String("owner/repo"), Name: github.String("repo"), Owner: &github.User{Login: github.String("owner")}},
+		Sender: &github.User{Login: github.String("carol")},
+	}
+
+	setup := func(t *testing.T, contentsStatus int) (*Plugin, map[string]int) {
+		requests := map[string]int{}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests[r.URL.Path]++
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/api/v3/repos/owner/repo/contents/CODEOWNERS":
+				w.WriteHeader(contentsStatus)
+				fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q}`, base64.StdEncoding.EncodeToString([]byte(testCodeOwners)))
+			case "/api/v3/repos/owner/repo/pulls/1/files":
+				fmt.Fprint(w, `[{"filename": "services/payments/refund.go"}, {"filename": "web/app.js"}]`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"message": "Not Found"}`)
+			}
+		}))
+		t.Cleanup(ts.Close)
+
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		store["creatorID"+githubTokenKey] = info
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("int64")).Return(func(key string, value []byte, ttl int64) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		return p, requests
+	}
+
+	t.Run("suggested reviewers", func(t *testing.T) {
+		p, requests := setup(t, http.StatusOK)
+		renderer := &pullRequestRenderer{p}
+		sub := &Subscription{CreatorID: "creatorID", Features: "pulls", Flags: SubscriptionFlags{SuggestReviewers: true}}
+
+		post, err := renderer.Render(event, sub)
+		require.NoError(t, err)
+		require.NotNil(t, post)
+		assert.True(t, strings.HasSuffix(post.Message, "\n\nSuggested reviewers: @octo-org/payments, alice, js-owner"), post.Message)
+
+		// The CODEOWNERS file is cached, including where it was missing
+		_, err = renderer.Render(event, sub)
+		require.NoError(t, err)
+		assert.Equal(t, 1, requests["/api/v3/repos/owner/repo/contents/.github/CODEOWNERS"])
+		assert.Equal(t, 1, requests["/api/v3/repos/owner/repo/contents/CODEOWNERS"])
+		assert.Zero(t, requests["/api/v3/repos/owner/repo/contents/docs/CODEOWNERS"])
+	})
+
+	t.Run("without the flag", func(t *testing.T) {
+		p, requests := setup(t, http.StatusOK)
+
+		post, err := (&pullRequestRenderer{p}).Render(event, &Subscription{CreatorID: "creatorID", Features: "pulls"})
+		require.NoError(t, err)
+		assert.NotContains(t, post.Message, "Suggested reviewers")
+		assert.Empty(t, requests)
+	})
+
+	t.Run("API failure", func(t *testing.T) {
+		p, _ := setup(t, http.StatusInternalServerError)
+
+		post, err := (&pullRequestRenderer{p}).Render(event, &Subscription{CreatorID: "creatorID", Features: "pulls", Flags: SubscriptionFlags{SuggestReviewers: true}})
+		require.NoError(t, err)
+		require.NotNil(t, post)
+		assert.NotContains(t, post.Message, "Suggested reviewers")
+	})
+}
+
+func TestSubscriptionFlagsSuggestReviewers(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(suggestReviewersFlag, "true"))
+	assert.True(t, flags.SuggestReviewers)
+	assert.Equal(t, "--suggest-reviewers true", flags.String())
+
+	assert.Error(t, flags.SetFlag(suggestReviewersFlag, "yes please"))
+	require.NoError(t, flags.SetFlag(suggestReviewersFlag, "false"))
+	assert.Empty(t, flags.String())
+}
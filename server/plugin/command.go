@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/google/go-github/v31/github"
@@ -14,30 +15,54 @@ import (
 )
 
 const (
-	featureIssueCreation = "issue_creations"
-	featureIssues        = "issues"
-	featurePulls         = "pulls"
-	featurePushes        = "pushes"
-	featureCreates       = "creates"
-	featureDeletes       = "deletes"
-	featureIssueComments = "issue_comments"
-	featurePullReviews   = "pull_reviews"
+	featureIssueCreation    = "issue_creations"
+	featureIssues           = "issues"
+	featurePulls            = "pulls"
+	featurePushes           = "pushes"
+	featureCreates          = "creates"
+	featureDeletes          = "deletes"
+	featureIssueComments    = "issue_comments"
+	featurePullReviews      = "pull_reviews"
+	featureBranchProtection = "branch_protection"
+	featureForks            = "forks"
+	featureStars            = "stars"
+	featureWiki             = "wiki"
+	featureMilestoneChanges = "milestone_changes"
+	featureWorkflowFailure  = "workflow_failure"
+
+	// defaultSubscriptionFeatures are the features of the subscriptions added without a list of features.
+	defaultSubscriptionFeatures = "pulls,issues,creates,deletes"
 )
 
 var validFeatures = map[string]bool{
-	featureIssueCreation: true,
-	featureIssues:        true,
-	featurePulls:         true,
-	featurePushes:        true,
-	featureCreates:       true,
-	featureDeletes:       true,
-	featureIssueComments: true,
-	featurePullReviews:   true,
+	featureIssueCreation:    true,
+	featureIssues:           true,
+	featurePulls:            true,
+	featurePushes:           true,
+	featureCreates:          true,
+	featureDeletes:          true,
+	featureIssueComments:    true,
+	featurePullReviews:      true,
+	featureBranchProtection: true,
+	featureForks:            true,
+	featureStars:            true,
+	featureWiki:             true,
+	featureMilestoneChanges: true,
+	featureWorkflowFailure:  true,
+}
+
+// criticalFeatures are the features every event of which matters. They are never sampled nor
+// held back by the rate cap of a subscription.
+var criticalFeatures = map[string]bool{
+	featureWorkflowFailure:  true,
+	featureBranchProtection: true,
 }
 
 const (
 	list      = "list"
 	deleteAll = "delete-all"
+
+	disabledCommandMessage = "This command has been disabled by your administrator."
 )
 
 // validateFeatures returns false when 1 or more given features
@@ -54,13 +79,16 @@ func validateFeatures(features []string) (bool, []string) {
 			hasLabel = true
 			continue
 		}
+		if strings.HasPrefix(f, featureMilestoneChanges+":") {
+			continue
+		}
 		invalidFeatures = append(invalidFeatures, f)
 		valid = false
 	}
 	if valid && hasLabel {
-		// must have "pulls" or "issues" in features when using a label
+		// must have "pulls", "issues" or milestone changes in features when using a label
 		for _, f := range features {
-			if f == featurePulls || f == featureIssues {
+			if f == featurePulls || f == featureIssues || strings.HasPrefix(f, featureMilestoneChanges) {
 				return valid, invalidFeatures
 			}
 		}
@@ -69,6 +97,24 @@ func validateFeatures(features []string) (bool, []string) {
 	return valid, invalidFeatures
 }
 
+// checkFeatureList returns an error describing why the comma-delimited list of features is invalid, if it is.
+func checkFeatureList(features string) error {
+	fs := strings.Split(features, ",")
+	if SliceContainsString(fs, featureIssues) && SliceContainsString(fs, featureIssueCreation) {
+		return errors.New("Feature list cannot contain both issue and issue_creations")
+	}
+
+	ok, ifs := validateFeatures(fs)
+	if !ok {
+		if len(ifs) == 0 {
+			return errors.New("Feature list must have \"pulls\" or \"issues\" when using a label.")
+		}
+		return errors.Errorf("Invalid feature(s) provided: %s", strings.Join(ifs, ","))
+	}
+
+	return nil
+}
+
 func (p *Plugin) getCommand(config *Configuration) (*model.Command, error) {
 	iconData, err := command.GetIconData(p.API, "assets/icon-bg.svg")
 	if err != nil {
@@ -78,7 +124,7 @@ func (p *Plugin) getCommand(config *Configuration) (*model.Command, error) {
 	return &model.Command{
 		Trigger:              "github",
 		AutoComplete:         true,
-		AutoCompleteDesc:     "Available commands: connect, disconnect, todo, me, settings, subscribe, unsubscribe, mute, help, issue",
+		AutoCompleteDesc:     "Available commands: connect, disconnect, todo, me, whois, settings, subscribe, unsubscribe, mute, help, issue, assign, unassign, reviewers, channel-settings, export-events, webhook, setup, admin, keywords, link-username, unlink-username, workflow, pr, search, watch",
 		AutoCompleteHint:     "[command]",
 		AutocompleteData:     getAutocompleteData(config),
 		AutocompleteIconData: iconData,
@@ -100,24 +146,27 @@ func (p *Plugin) getGithubClient(userInfo *GitHubUserInfo) *github.Client {
 }
 
 func (p *Plugin) getMutedUsernames(userInfo *GitHubUserInfo) []string {
-	mutedUsernameBytes, err := p.API.KVGet(userInfo.UserID + "-muted-users")
+	muted, err := p.getMutedUsers(userInfo.UserID)
 	if err != nil {
+		p.API.LogWarn("Failed to get muted users", "userID", userInfo.UserID, "error", err.Error())
 		return nil
 	}
-	mutedUsernames := string(mutedUsernameBytes)
-	var mutedUsers []string
-	if len(mutedUsernames) == 0 {
-		return mutedUsers
-	}
-	mutedUsers = strings.Split(mutedUsernames, ",")
-	return mutedUsers
+	return muted.usernames()
 }
 
 func (p *Plugin) handleMuteList(args *model.CommandArgs, userInfo *GitHubUserInfo) string {
-	mutedUsernames := p.getMutedUsernames(userInfo)
+	muted, err := p.getMutedUsers(userInfo.UserID)
+	if err != nil {
+		p.API.LogWarn("Failed to get muted users", "userID", userInfo.UserID, "error", err.Error())
+		return "Error occurred getting the list of muted users"
+	}
 	var mutedUsers string
-	for _, user := range mutedUsernames {
-		mutedUsers += fmt.Sprintf("- %v\n", user)
+	for _, user := range muted.Users {
+		if user.Scope == muteScopeChannels {
+			mutedUsers += fmt.Sprintf("- %v (notifications and subscribed channels)\n", user.Username)
+		} else {
+			mutedUsers += fmt.Sprintf("- %v\n", user.Username)
+		}
 	}
 	if len(mutedUsers) == 0 {
 		return "You have no muted users"
@@ -125,50 +174,53 @@ func (p *Plugin) handleMuteList(args *model.CommandArgs, userInfo *GitHubUserInf
 	return "Your muted users:\n" + mutedUsers
 }
 
-func contains(s []string, e string) bool {
-	for _, a := range s {
-		if a == e {
-			return true
-		}
-	}
-	return false
-}
-
-func (p *Plugin) handleMuteAdd(args *model.CommandArgs, username string, userInfo *GitHubUserInfo) string {
-	mutedUsernames := p.getMutedUsernames(userInfo)
-	if contains(mutedUsernames, username) {
-		return username + " is already muted"
-	}
-
+func (p *Plugin) handleMuteAdd(args *model.CommandArgs, username, scope string, userInfo *GitHubUserInfo) string {
 	if strings.Contains(username, ",") {
 		return "Invalid username provided"
 	}
 
-	var mutedUsers string
-	if len(mutedUsernames) > 0 {
-		// , is a character not allowed in github usernames so we can split on them
-		mutedUsers = strings.Join(mutedUsernames, ",") + "," + username
+	muted, err := p.getMutedUsers(userInfo.UserID)
+	if err != nil {
+		p.API.LogWarn("Failed to get muted users", "userID", userInfo.UserID, "error", err.Error())
+		return "Error occurred getting the list of muted users"
+	}
+
+	if user := muted.get(username); user != nil {
+		if user.Scope == scope {
+			return username + " is already muted"
+		}
+		user.Scope = scope
 	} else {
-		mutedUsers = username
+		muted.Users = append(muted.Users, &mutedUser{Username: username, CreatedAt: time.Now(), Scope: scope})
 	}
-	if err := p.API.KVSet(userInfo.UserID+"-muted-users", []byte(mutedUsers)); err != nil {
+
+	if err := p.storeMutedUsers(userInfo.UserID, muted); err != nil {
+		p.API.LogWarn("Failed to store muted users", "userID", userInfo.UserID, "error", err.Error())
 		return "Error occurred saving list of muted users"
 	}
+	if scope == muteScopeChannels {
+		return fmt.Sprintf("`%v`", username) + " is now muted. You will no longer receive notifications for comments in your PRs and issues, and the channels you subscribed won't get the events of this user."
+	}
 	return fmt.Sprintf("`%v`", username) + " is now muted. You will no longer receive notifications for comments in your PRs and issues."
 }
 
 func (p *Plugin) handleUnmute(args *model.CommandArgs, username string, userInfo *GitHubUserInfo) string {
-	mutedUsernames := p.getMutedUsernames(userInfo)
-	userToMute := []string{username}
-	newMutedList := arrayDifference(mutedUsernames, userToMute)
-	if err := p.API.KVSet(userInfo.UserID+"-muted-users", []byte(strings.Join(newMutedList, ","))); err != nil {
+	muted, err := p.getMutedUsers(userInfo.UserID)
+	if err != nil {
+		p.API.LogWarn("Failed to get muted users", "userID", userInfo.UserID, "error", err.Error())
+		return "Error occurred unmuting users"
+	}
+	muted.remove(username)
+	if err := p.storeMutedUsers(userInfo.UserID, muted); err != nil {
+		p.API.LogWarn("Failed to store muted users", "userID", userInfo.UserID, "error", err.Error())
 		return "Error occurred unmuting users"
 	}
 	return fmt.Sprintf("`%v`", username) + " is no longer muted"
 }
 
 func (p *Plugin) handleUnmuteAll(args *model.CommandArgs, userInfo *GitHubUserInfo) string {
-	if err := p.API.KVSet(userInfo.UserID+"-muted-users", []byte("")); err != nil {
+	if err := p.storeMutedUsers(userInfo.UserID, &mutedUsers{Users: []*mutedUser{}}); err != nil {
+		p.API.LogWarn("Failed to store muted users", "userID", userInfo.UserID, "error", err.Error())
 		return "Error occurred unmuting users"
 	}
 	return "Unmuted all users"
@@ -185,10 +237,16 @@ func (p *Plugin) handleMuteCommand(_ *plugin.Context, args *model.CommandArgs, p
 	case command == list:
 		return p.handleMuteList(args, userInfo)
 	case command == "add":
-		if len(parameters) != 2 {
+		switch {
+		case len(parameters) == 2:
+			return p.handleMuteAdd(args, parameters[1], "", userInfo)
+		case len(parameters) == 4 && parameters[2] == "--scope" && parameters[3] == muteScopeChannels:
+			return p.handleMuteAdd(args, parameters[1], muteScopeChannels, userInfo)
+		case len(parameters) == 4 && parameters[2] == "--scope":
+			return fmt.Sprintf("Invalid scope %q. Use `--scope channels` to mute the user in the channels you subscribed too.", parameters[3])
+		default:
 			return "Invalid number of parameters supplied to " + command
 		}
-		return p.handleMuteAdd(args, parameters[1], userInfo)
 	case command == "delete":
 		if len(parameters) != 2 {
 			return "Invalid number of parameters supplied to " + command
@@ -229,7 +287,7 @@ func (p *Plugin) handleSubscribe(c *plugin.Context, args *model.CommandArgs, par
 
 func (p *Plugin) handleSubscriptions(c *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
 	if len(parameters) == 0 {
-		return "Invalid subscribe command. Available commands are 'list', 'add' and 'delete'."
+		return "Invalid subscribe command. Available commands are 'list', 'add', 'delete', 'pause', 'resume' and 'claim'."
 	}
 
 	command := parameters[0]
@@ -242,11 +300,151 @@ func (p *Plugin) handleSubscriptions(c *plugin.Context, args *model.CommandArgs,
 		return p.handleSubscribesAdd(c, args, parameters, userInfo)
 	case command == "delete":
 		return p.handleUnsubscribe(c, args, parameters, userInfo)
+	case command == "claim":
+		return p.handleSubscriptionsClaim(args, parameters, userInfo)
+	case command == "pause":
+		return p.handleSubscriptionsPause(args, parameters)
+	case command == "resume":
+		return p.handleSubscriptionsResume(args, parameters)
+	case command == "route":
+		return p.handleSubscriptionsRoute(c, args, parameters, userInfo)
+	default:
+		return fmt.Sprintf("Unknown subcommand %v", command)
+	}
+}
+
+func (p *Plugin) handleSubscriptionsRoute(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
+	if !p.isSystemAdmin(args.UserId) {
+		return "Only system administrators can manage subscription routes."
+	}
+
+	if len(parameters) == 0 {
+		return "Invalid route command. Available commands are 'list', 'add' and 'delete'."
+	}
+
+	command := parameters[0]
+	parameters = parameters[1:]
+
+	switch {
+	case command == "list":
+		return p.handleRouteList()
+	case command == "add":
+		if len(parameters) != 3 {
+			return "Please specify a repository, a list of features and a target channel."
+		}
+		return p.handleRouteAdd(args, parameters[0], parameters[1], parameters[2])
+	case command == "delete":
+		if len(parameters) != 2 {
+			return "Please specify a repository and a target channel."
+		}
+		return p.handleRouteDelete(args, parameters[0], parameters[1])
 	default:
 		return fmt.Sprintf("Unknown subcommand %v", command)
 	}
 }
 
+func (p *Plugin) handleRouteList() string {
+	routes, err := p.ListRoutes()
+	if err != nil {
+		return err.Error()
+	}
+
+	if len(routes) == 0 {
+		return "Currently there are no subscription routes"
+	}
+
+	txt := "### Subscription routes\n"
+	for _, route := range routes {
+		channelName := route.ChannelID
+		if channel, appErr := p.API.GetChannel(route.ChannelID); appErr == nil {
+			channelName = channel.Name
+		}
+		txt += fmt.Sprintf("* `%s` - %s to ~%s\n", strings.Trim(route.Repository, "/"), route.Features, channelName)
+	}
+
+	return txt
+}
+
+func (p *Plugin) handleRouteAdd(args *model.CommandArgs, repository, features, channelName string) string {
+	if err := checkFeatureList(features); err != nil {
+		return err.Error()
+	}
+
+	owner, repo := parseOwnerAndRepo(repository, p.getBaseURL())
+	if owner == "" {
+		return "Invalid repository."
+	}
+
+	if err := p.checkOrg(owner); err != nil {
+		return err.Error()
+	}
+
+	channel, err := p.getRouteTargetChannel(args.TeamId, channelName)
+	if err != nil {
+		return err.Error()
+	}
+
+	route := &Route{
+		ChannelID:  channel.Id,
+		CreatorID:  args.UserId,
+		Features:   features,
+		Repository: fullNameFromOwnerAndRepo(owner, repo),
+	}
+
+	if err := p.AddRoute(route); err != nil {
+		p.API.LogWarn("Failed to add route", "repo", route.Repository, "error", err.Error())
+		return "Encountered an error trying to add the route. Please try again."
+	}
+
+	return fmt.Sprintf("Successfully routed %s events of %s to ~%s.", features, strings.Trim(route.Repository, "/"), channel.Name)
+}
+
+func (p *Plugin) handleRouteDelete(args *model.CommandArgs, repository, channelName string) string {
+	owner, repo := parseOwnerAndRepo(repository, p.getBaseURL())
+	if owner == "" {
+		return "Invalid repository."
+	}
+
+	channel, appErr := p.API.GetChannelByName(args.TeamId, strings.TrimPrefix(channelName, "~"), false)
+	if appErr != nil {
+		return fmt.Sprintf("Unknown channel %s.", channelName)
+	}
+
+	removed, err := p.DeleteRoute(fullNameFromOwnerAndRepo(owner, repo), channel.Id)
+	if err != nil {
+		p.API.LogWarn("Failed to delete route", "repo", repository, "error", err.Error())
+		return "Encountered an error trying to delete the route. Please try again."
+	}
+
+	if !removed {
+		return fmt.Sprintf("There is no route of %s to ~%s.", repository, channel.Name)
+	}
+
+	return fmt.Sprintf("Successfully deleted the route of %s to ~%s.", repository, channel.Name)
+}
+
+// getRouteTargetChannel resolves the channel a route posts to and makes sure the bot is able to post there.
+func (p *Plugin) getRouteTargetChannel(teamID, channelName string) (*model.Channel, error) {
+	channelName = strings.TrimPrefix(channelName, "~")
+
+	channel, appErr := p.API.GetChannelByName(teamID, channelName, false)
+	if appErr != nil {
+		return nil, errors.Errorf("Unknown channel ~%s.", channelName)
+	}
+
+	switch channel.Type {
+	case model.CHANNEL_OPEN:
+	case model.CHANNEL_PRIVATE:
+		if _, appErr := p.API.GetChannelMember(channel.Id, p.BotUserID); appErr != nil {
+			return nil, errors.Errorf("The GitHub bot is not a member of ~%s. Please add it to the channel first.", channelName)
+		}
+	default:
+		return nil, errors.New("Events can only be routed to public or private channels.")
+	}
+
+	return channel, nil
+}
+
 func (p *Plugin) handleSubscriptionsList(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
 	txt := ""
 	subs, err := p.GetSubscriptionsByChannel(args.ChannelId)
@@ -265,63 +463,111 @@ func (p *Plugin) handleSubscriptionsList(_ *plugin.Context, args *model.CommandA
 		if subFlags != "" {
 			txt += fmt.Sprintf(" %s", subFlags)
 		}
+		txt += fmt.Sprintf(" - render style: `%s`", p.getRenderStyle(sub))
+		if creator := p.describeSubscriptionCreator(sub); creator != "" {
+			txt += " - " + creator
+		}
+		if sub.paused() {
+			txt += " - " + describeSubscriptionPause(sub, time.Now())
+		}
 		txt += "\n"
 	}
 
 	return txt
 }
 
-func (p *Plugin) handleSubscribesAdd(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
-	features := "pulls,issues,creates,deletes"
+func (p *Plugin) handleSubscribesAdd(c *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) > 0 && parameters[0] == "--"+fromHeaderFlag {
+		return p.handleSubscribesAddFromHeader(c, args, parameters[1:], userInfo)
+	}
+
+	features := defaultSubscriptionFeatures
 	flags := SubscriptionFlags{}
+	channelID := args.ChannelId
+	targetDM := false
 
 	if len(parameters) > 1 {
 		var optionList []string
 
-		for _, element := range parameters[1:] {
-			if isFlag(element) {
-				flags.AddFlag(parseFlag(element))
-			} else {
+		for i := 1; i < len(parameters); i++ {
+			element := parameters[i]
+			if !isFlag(element) {
 				optionList = append(optionList, element)
+				continue
 			}
+
+			flag := parseFlag(element)
+			if flag == subscriptionTargetFlag {
+				if i+1 >= len(parameters) || parameters[i+1] != subscriptionTargetDM {
+					return fmt.Sprintf("The flag %s only supports `%s`, to subscribe your direct messages with the bot.", element, subscriptionTargetDM)
+				}
+				targetDM = true
+				i++
+				continue
+			}
+
+			valueCount := flagValueCount(flag)
+			if valueCount == 0 {
+				flags.AddFlag(flag)
+				continue
+			}
+
+			if i+valueCount >= len(parameters) {
+				return fmt.Sprintf("The flag %s requires %d value(s).", element, valueCount)
+			}
+
+			if err := flags.SetFlag(flag, strings.Join(parameters[i+1:i+1+valueCount], " ")); err != nil {
+				return err.Error()
+			}
+			i += valueCount
 		}
 
 		if len(optionList) > 1 {
 			return "Just one list of features is allowed"
 		} else if len(optionList) == 1 {
 			features = optionList[0]
-			fs := strings.Split(features, ",")
-			if SliceContainsString(fs, featureIssues) && SliceContainsString(fs, featureIssueCreation) {
-				return "Feature list cannot contain both issue and issue_creations"
-			}
-			ok, ifs := validateFeatures(fs)
-			if !ok {
-				msg := fmt.Sprintf("Invalid feature(s) provided: %s", strings.Join(ifs, ","))
-				if len(ifs) == 0 {
-					msg = "Feature list must have \"pulls\" or \"issues\" when using a label."
-				}
-				return msg
+			if err := checkFeatureList(features); err != nil {
+				return err.Error()
 			}
 		}
 	}
 
+	target := ""
+	if targetDM {
+		if flags.SyncHeader {
+			return fmt.Sprintf("Unable to set --%s flag. Direct messages have no channel header to sync.", syncHeaderFlag)
+		}
+
+		var err error
+		channelID, err = p.getBotDMChannelID(args.UserId)
+		if err != nil {
+			p.API.LogWarn("Failed to get the bot DM channel for a subscription", "userID", args.UserId, "error", err.Error())
+			return "Encountered an error getting your direct messages with the bot. Please try again."
+		}
+		target = " in your direct messages with the bot"
+	}
+
 	ctx := context.Background()
 	githubClient := p.getGithubClient(userInfo)
 
 	owner, repo := parseOwnerAndRepo(parameters[0], p.getBaseURL())
 	if repo == "" {
-		if err := p.SubscribeOrg(ctx, githubClient, args.UserId, owner, args.ChannelId, features, flags); err != nil {
+		if err := p.SubscribeOrg(ctx, githubClient, args.UserId, owner, channelID, features, flags); err != nil {
 			return err.Error()
 		}
 
-		return fmt.Sprintf("Successfully subscribed to organization %s.", owner)
+		return fmt.Sprintf("Successfully subscribed to organization %s%s.", owner, target)
 	}
 
-	if err := p.Subscribe(ctx, githubClient, args.UserId, owner, repo, args.ChannelId, features, flags); err != nil {
+	if err := p.Subscribe(ctx, githubClient, args.UserId, owner, repo, channelID, features, flags); err != nil {
 		return err.Error()
 	}
 
-	msg := fmt.Sprintf("Successfully subscribed to %s.", repo)
+	msg := fmt.Sprintf("Successfully subscribed to %s%s.", repo, target)
+	if targetDM {
+		// Only the creator reads the direct messages with the bot
+		return msg
+	}
 
 	ghRepo, _, err := githubClient.Repositories.Get(ctx, owner, repo)
 	if err != nil {
@@ -333,6 +579,49 @@ func (p *Plugin) handleSubscribesAdd(_ *plugin.Context, args *model.CommandArgs,
 	return msg
 }
 
+func (p *Plugin) handleChannelSettings(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
+	if len(parameters) == 0 {
+		return "Please specify a subcommand: `get`, `render-style` or `unset`."
+	}
+
+	settings, err := p.getChannelSettings(args.ChannelId)
+	if err != nil {
+		p.API.LogWarn("Failed to get channel settings", "channelID", args.ChannelId, "error", err.Error())
+		return "Encountered an error getting the channel settings."
+	}
+
+	switch command := parameters[0]; command {
+	case "get":
+		renderStyle := settings.RenderStyle
+		if renderStyle == "" {
+			renderStyle = renderStyleDefault + " (not set)"
+		}
+		return fmt.Sprintf("#### Channel settings\n* Render style: `%s`", renderStyle)
+	case renderStyleFlag:
+		if len(parameters) != 2 {
+			return fmt.Sprintf("Please specify a render style: %s.", strings.Join(validRenderStyles, ", "))
+		}
+		if err := checkRenderStyle(parameters[1]); err != nil {
+			return err.Error()
+		}
+		settings.RenderStyle = parameters[1]
+	case "unset":
+		if len(parameters) != 2 || parameters[1] != renderStyleFlag {
+			return "Please specify the setting to unset: `render-style`."
+		}
+		settings.RenderStyle = ""
+	default:
+		return fmt.Sprintf("Unknown subcommand %v", command)
+	}
+
+	if err := p.storeChannelSettings(args.ChannelId, settings); err != nil {
+		p.API.LogWarn("Failed to store channel settings", "channelID", args.ChannelId, "error", err.Error())
+		return "Encountered an error storing the channel settings."
+	}
+
+	return "Channel settings updated."
+}
+
 func (p *Plugin) handleUnsubscribe(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
 	if len(parameters) == 0 {
 		return "Please specify a repository."
@@ -353,7 +642,15 @@ func (p *Plugin) handleDisconnect(_ *plugin.Context, args *model.CommandArgs, _
 	return "Disconnected your GitHub account."
 }
 
-func (p *Plugin) handleTodo(_ *plugin.Context, _ *model.CommandArgs, _ []string, userInfo *GitHubUserInfo) string {
+func (p *Plugin) handleTodo(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) > 0 {
+		if len(parameters) > 1 || parameters[0] != toDoShareFlag {
+			return "Unknown subcommand. Use `/github todo` or `/github todo --share`."
+		}
+
+		return p.handleShareToDo(context.Background(), args, userInfo)
+	}
+
 	githubClient := p.getGithubClient(userInfo)
 
 	text, err := p.GetToDo(context.Background(), userInfo.GitHubUsername, githubClient)
@@ -364,17 +661,173 @@ func (p *Plugin) handleTodo(_ *plugin.Context, _ *model.CommandArgs, _ []string,
 	return text
 }
 
-func (p *Plugin) handleMe(_ *plugin.Context, _ *model.CommandArgs, _ []string, userInfo *GitHubUserInfo) string {
+func (p *Plugin) handleMe(_ *plugin.Context, _ *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
 	githubClient := p.getGithubClient(userInfo)
+
+	if len(parameters) > 0 {
+		if parameters[0] != "activity" || len(parameters) > 2 {
+			return "Unknown subcommand. Use `/github me` or `/github me activity [days]`."
+		}
+
+		return p.handleMeActivity(githubClient, parameters[1:], userInfo)
+	}
+
 	gitUser, _, err := githubClient.Users.Get(context.Background(), "")
 	if err != nil {
 		return "Encountered an error getting your GitHub profile."
 	}
 
 	text := fmt.Sprintf("You are connected to GitHub as:\n# [![image](%s =40x40)](%s) [%s](%s)", gitUser.GetAvatarURL(), gitUser.GetHTMLURL(), gitUser.GetLogin(), gitUser.GetHTMLURL())
+
+	// An exhausted quota explains an empty sidebar, but the profile is shown without it
+	limits, err := getRateLimits(context.Background(), githubClient)
+	if err != nil {
+		p.API.LogDebug("Failed to get rate limits", "userID", userInfo.UserID, "error", err.Error())
+	} else if quota := formatRateLimits(limits, time.Now()); quota != "" {
+		text += "\n\n" + quota
+	}
+
 	return text
 }
 
+func (p *Plugin) handleMeActivity(githubClient *github.Client, parameters []string, userInfo *GitHubUserInfo) string {
+	daysParameter := ""
+	if len(parameters) > 0 {
+		daysParameter = parameters[0]
+	}
+
+	days, err := parseActivityDays(daysParameter)
+	if err != nil {
+		return err.Error()
+	}
+
+	activity, err := p.getUserActivity(context.Background(), githubClient, userInfo.GitHubUsername, p.getConfiguration().GitHubOrg, days, time.Now())
+	if err != nil {
+		p.API.LogWarn("Failed to get user activity", "userID", userInfo.UserID, "error", err.Error())
+		return "Encountered an error getting your GitHub activity."
+	}
+
+	return formatUserActivity(activity)
+}
+
+func (p *Plugin) handleExportEvents(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
+	if !p.isSystemAdmin(args.UserId) {
+		return "Only system administrators can export the GitHub notifications of a channel."
+	}
+
+	if len(parameters) > 1 {
+		return "Invalid export-events command. Use `/github export-events [days]`, e.g. `30d`."
+	}
+
+	days, err := parseActivityDays(strings.Join(parameters, ""))
+	if err != nil {
+		return err.Error()
+	}
+
+	if err := p.exportChannelEvents(args.ChannelId, days, time.Now()); err != nil {
+		p.API.LogWarn("Failed to export channel events", "channelID", args.ChannelId, "error", err.Error())
+		return "Encountered an error exporting the GitHub notifications of this channel."
+	}
+
+	return ""
+}
+
+func (p *Plugin) handleWebhookCommand(_ *plugin.Context, _ *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) != 2 || parameters[0] != "info" {
+		return "Invalid webhook command. Use `/github webhook info owner/repo`."
+	}
+
+	owner, repo := parseOwnerAndRepo(parameters[1], p.getBaseURL())
+	if owner == "" || repo == "" {
+		return "Please specify a repository as `owner/repo`."
+	}
+	target := fullNameFromOwnerAndRepo(owner, repo)
+
+	// The webhook info is only shown to users who can see the repository
+	githubClient := p.getGithubClient(userInfo)
+	if _, _, err := githubClient.Repositories.Get(context.Background(), owner, repo); err != nil {
+		p.API.LogDebug("Failed to get repository", "repo", target, "error", err.Error())
+		return fmt.Sprintf("Unable to get the webhook info of %s. Make sure the repository exists and you have access to it.", target)
+	}
+
+	info, err := p.getWebhookInfo(target)
+	if err == nil && info == nil {
+		// The webhook may be configured on the organization
+		info, err = p.getWebhookInfo(owner)
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to get webhook info", "repo", target, "error", err.Error())
+		return "Encountered an error getting the webhook info."
+	}
+
+	lastDelivery, err := p.getLastWebhookDelivery(target)
+	if err != nil {
+		p.API.LogWarn("Failed to get last webhook delivery", "repo", target, "error", err.Error())
+		return "Encountered an error getting the webhook info."
+	}
+
+	return formatWebhookInfo(target, info, lastDelivery)
+}
+
+func (p *Plugin) handleAdmin(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if !p.isSystemAdmin(args.UserId) {
+		return "Only system administrators can use admin commands."
+	}
+
+	if len(parameters) == 0 {
+		return "Invalid admin command. Available commands are 'refresh-all', 'sync-usernames', 'refresh-org-members', 'subscriptions' and 'webhook-queue'."
+	}
+
+	if parameters[0] == "subscriptions" {
+		return p.handleAdminSubscriptions(parameters[1:])
+	}
+
+	if len(parameters) != 1 {
+		return "Invalid admin command. Available commands are 'refresh-all', 'sync-usernames', 'refresh-org-members', 'subscriptions' and 'webhook-queue'."
+	}
+
+	switch parameters[0] {
+	case "refresh-all":
+		return p.handleAdminRefreshAll(args)
+	case "sync-usernames":
+		return p.handleAdminSyncUsernames(userInfo)
+	case "refresh-org-members":
+		return p.handleAdminRefreshOrgMembers()
+	case "webhook-queue":
+		return p.handleAdminWebhookQueue()
+	default:
+		return "Invalid admin command. Available commands are 'refresh-all', 'sync-usernames', 'refresh-org-members', 'subscriptions' and 'webhook-queue'."
+	}
+}
+
+func (p *Plugin) handleAdminSyncUsernames(userInfo *GitHubUserInfo) string {
+	org := strings.TrimSpace(p.getConfiguration().GitHubOrg)
+	if org == "" {
+		return "Usernames can only be synced when the plugin is locked to an organization. Set the GitHub Organization in the plugin settings."
+	}
+
+	result, err := p.syncUsernameMappings(context.Background(), p.getGithubClient(userInfo), org)
+	if err != nil {
+		p.API.LogWarn("Failed to sync usernames", "org", org, "error", err.Error())
+		return "Encountered an error syncing the usernames of the organization members."
+	}
+
+	return formatUsernameSyncResult(org, result)
+}
+
+func (p *Plugin) handleAdminRefreshAll(args *model.CommandArgs) string {
+	state, err := p.startRefreshAll(args.UserId)
+	if err == errRefreshAllRunning {
+		return "A refresh of all sidebars is already running."
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to start refreshing all sidebars", "error", err.Error())
+		return "Encountered an error starting to refresh all sidebars."
+	}
+
+	return fmt.Sprintf("Refreshing the GitHub sidebars of %d connected users. A summary will be sent by direct message once done.", state.Total)
+}
+
 func (p *Plugin) handleHelp(_ *plugin.Context, _ *model.CommandArgs, _ []string, _ *GitHubUserInfo) string {
 	message, err := renderTemplate("helpText", p.getConfiguration())
 	if err != nil {
@@ -391,7 +844,38 @@ func (p *Plugin) handleSettings(_ *plugin.Context, _ *model.CommandArgs, paramet
 	}
 
 	setting := parameters[0]
-	if setting != settingNotifications && setting != settingReminders {
+	if setting == settingSidebar {
+		message, err := handleSidebarSetting(userInfo.Settings, parameters[1:])
+		if err != nil {
+			return err.Error()
+		}
+
+		if err := p.storeGitHubUserInfo(userInfo); err != nil {
+			p.API.LogWarn("Failed to store github user info", "error", err.Error())
+			return "Failed to store settings"
+		}
+
+		p.sendSidebarButtonsEvent(userInfo.UserID, userInfo.Settings.sidebarButtons())
+		return message
+	}
+
+	if setting == settingWeeklySummary {
+		message, err := handleWeeklySummarySetting(userInfo.Settings, parameters[1:])
+		if err != nil {
+			return err.Error()
+		}
+
+		if err := p.storeGitHubUserInfo(userInfo); err != nil {
+			p.API.LogWarn("Failed to store github user info", "error", err.Error())
+			return "Failed to store settings"
+		}
+
+		p.addWeeklySummaryUser(userInfo)
+		return message
+	}
+
+	if setting != settingNotifications && setting != settingReminders && setting != settingDiscoverable && setting != settingReadOnly &&
+		setting != settingConsolidatedDMs && setting != settingUrgentDMs {
 		return "Unknown setting."
 	}
 
@@ -425,6 +909,14 @@ func (p *Plugin) handleSettings(_ *plugin.Context, _ *model.CommandArgs, paramet
 		userInfo.Settings.Notifications = value
 	} else if setting == settingReminders {
 		userInfo.Settings.DailyReminder = value
+	} else if setting == settingDiscoverable {
+		userInfo.Settings.Discoverable = &value
+	} else if setting == settingReadOnly {
+		userInfo.Settings.ReadOnlyMode = value
+	} else if setting == settingConsolidatedDMs {
+		userInfo.Settings.ConsolidatedDMs = value
+	} else if setting == settingUrgentDMs {
+		userInfo.Settings.UrgentDMsAsRootPosts = value
 	}
 
 	err := p.storeGitHubUserInfo(userInfo)
@@ -438,7 +930,7 @@ func (p *Plugin) handleSettings(_ *plugin.Context, _ *model.CommandArgs, paramet
 
 func (p *Plugin) handleIssue(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
 	if len(parameters) == 0 {
-		return "Invalid issue command. Available command is 'create'."
+		return "Invalid issue command. Available commands are 'create' and 'export'."
 	}
 
 	command := parameters[0]
@@ -448,11 +940,44 @@ func (p *Plugin) handleIssue(_ *plugin.Context, args *model.CommandArgs, paramet
 	case command == "create":
 		p.openIssueCreateModal(args.UserId, args.ChannelId, strings.Join(parameters, " "))
 		return ""
+	case command == "export":
+		return p.handleIssueExport(args, parameters, userInfo)
 	default:
 		return fmt.Sprintf("Unknown subcommand %v", command)
 	}
 }
 
+func (p *Plugin) handleReviewers(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) != 3 || (parameters[0] != "add" && parameters[0] != "remove") {
+		return "Please use `/github reviewers add owner/repo#123 username[,username]` or `/github reviewers remove owner/repo#123 username[,username]`."
+	}
+
+	remove := parameters[0] == "remove"
+
+	owner, repo, number, err := parsePullRequestReference(parameters[1], p.getChannelDefaultRepo(args.ChannelId))
+	if err != nil {
+		return err.Error()
+	}
+
+	reviewers := p.resolveGitHubUsernames(strings.Split(parameters[2], ","))
+	if len(reviewers) == 0 {
+		return "Please provide at least one reviewer."
+	}
+
+	githubClient, err := p.getGithubClientFor(userInfo, githubWrite)
+	if err != nil {
+		return readOnlyModeMessage
+	}
+
+	update, err := updateReviewers(context.Background(), githubClient, owner, repo, number, reviewers, remove)
+	if err != nil {
+		p.API.LogWarn("Failed to update reviewers", "repo", fullNameFromOwnerAndRepo(owner, repo), "number", number, "error", err.Error())
+		return fmt.Sprintf("Encountered an error updating the reviewers of %s#%d.", fullNameFromOwnerAndRepo(owner, repo), number)
+	}
+
+	return formatReviewersUpdate(update, owner, repo, number, remove)
+}
+
 type CommandHandleFunc func(c *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string
 
 func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
@@ -469,6 +994,13 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 			return &model.CommandResponse{}, nil
 		}
 
+		if isConnectTokenCommand(action, parameters) {
+			if message := p.handleConnectToken(args, *siteURL); message != "" {
+				p.postCommandResponse(args, message)
+			}
+			return &model.CommandResponse{}, nil
+		}
+
 		privateAllowed := false
 		if len(parameters) > 0 {
 			if len(parameters) != 1 || parameters[0] != "private" {
@@ -493,11 +1025,31 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		return &model.CommandResponse{}, nil
 	}
 
+	if p.getConfiguration().isCommandDisabled(action) {
+		p.postCommandResponse(args, disabledCommandMessage)
+		return &model.CommandResponse{}, nil
+	}
+
+	// Linking a username is meant for users who don't connect their account
+	if action == linkUsernameCommand || action == unlinkUsernameCommand {
+		p.postCommandResponse(args, p.CommandHandlers[action](c, args, parameters, nil))
+		return &model.CommandResponse{}, nil
+	}
+
+	// Invites are only sent to users who aren't connected
+	if isInvitesSetting(action, parameters) {
+		p.postCommandResponse(args, p.handleInvitesSetting(args.UserId, parameters[1:]))
+		return &model.CommandResponse{}, nil
+	}
+
 	info, apiErr := p.getGitHubUserInfo(args.UserId)
 	if apiErr != nil {
 		text := "Unknown error."
 		if apiErr.ID == apiErrorIDNotConnected {
 			text = "You must connect your account to GitHub first. Either click on the GitHub logo in the bottom left of the screen or enter `/github connect`."
+			if linked, _ := p.getLinkedUsername(args.UserId); linked != nil {
+				text = fmt.Sprintf("Your GitHub username @%s is only linked to get notifications. To use this command, connect your account to GitHub with `/github connect`.", linked.GitHubUsername)
+			}
 		}
 		p.postCommandResponse(args, text)
 		return &model.CommandResponse{}, nil
@@ -521,6 +1073,10 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 	connect := model.NewAutocompleteData("connect", "", "Connect your Mattermost account to your GitHub account")
 	private := model.NewAutocompleteData("private", "(optional)", "If used, read access to your private repositories will be requested")
 	connect.AddCommand(private)
+	if config.AllowPersonalAccessTokens {
+		token := model.NewAutocompleteData(connectTokenParameter, "", "Connect with a GitHub personal access token instead of authorizing the plugin")
+		connect.AddCommand(token)
+	}
 	github.AddCommand(connect)
 
 	disconnect := model.NewAutocompleteData("disconnect", "", "Disconnect your Mattermost account from your GitHub account")
@@ -530,16 +1086,19 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 	github.AddCommand(help)
 
 	todo := model.NewAutocompleteData("todo", "", "Get a list of unread messages and pull requests awaiting your review")
+	if config.AllowSharedTodos {
+		todo.AddTextArgument("Share the list in the current channel after previewing it", "[--share]", "")
+	}
 	github.AddCommand(todo)
 
-	subscriptions := model.NewAutocompleteData("subscriptions", "[command]", "Available commands: list, add, delete")
+	subscriptions := model.NewAutocompleteData("subscriptions", "[command]", "Available commands: list, add, delete, pause, resume, claim")
 
 	subscribeList := model.NewAutocompleteData("list", "", "List the current channel subscriptions")
 	subscriptions.AddCommand(subscribeList)
 
 	subscriptionsAdd := model.NewAutocompleteData("add", "[owner/repo] [features] [flags]", "Subscribe the current channel to receive notifications about opened pull requests and issues for an organization or repository. [features] and [flags] are optional arguments")
 	subscriptionsAdd.AddTextArgument("Owner/repo to subscribe to", "[owner/repo]", "")
-	subscriptionsAdd.AddTextArgument("Comma-delimited list of one or more of: issues, pulls, pushes, creates, deletes, issue_creations, issue_comments, pull_reviews, label:\"<labelname>\". Defaults to pulls,issues,creates,deletes", "[features] (optional)", `/[^,-\s]+(,[^,-\s]+)*/`)
+	subscriptionsAdd.AddTextArgument("Comma-delimited list of one or more of: issues, pulls, pushes, creates, deletes, issue_creations, issue_comments, pull_reviews, branch_protection, forks, stars, wiki, milestone_changes, milestone_changes:\"<milestone>\", label:\"<labelname>\". Defaults to pulls,issues,creates,deletes", "[features] (optional)", `/[^,-\s]+(,[^,-\s]+)*/`)
 	if config.GitHubOrg != "" {
 		flags := []model.AutocompleteListItem{{
 			HelpText: "Events triggered by organization members will not be delivered (the organization config should be set, otherwise this flag has not effect)",
@@ -548,21 +1107,227 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 		}}
 		subscriptionsAdd.AddStaticListArgument("Currently supports --exclude-org-member", false, flags)
 	}
+	subscriptionsAdd.AddNamedTextArgument(renderStyleFlag, "How to render new pull requests and issues: default, skip-body or collapsed. Overrides the channel setting", "[style]", "", false)
+	subscriptionsAdd.AddNamedTextArgument(issueFormFieldsFlag, "Show only the given fields of the form of new issues created from an issue form", "\"[field],[field]\"", "", false)
+	subscriptionsAdd.AddNamedTextArgument(pathsFlag, "Only post pushes and pull requests touching files matching the given glob patterns", "\"[pattern],[pattern]\"", "", false)
+	subscriptionsAdd.AddNamedTextArgument(reviewSLAFlag, "Post the review requests pending for longer than the given duration, e.g. 24h", "[duration]", "", false)
+	subscriptionsAdd.AddNamedTextArgument(coalesceCommentsFlag, "Merge the comments on an issue within the given duration into the post of the first one, e.g. 2m", "[duration]", "", false)
+	subscriptionsAdd.AddNamedTextArgument(liveUpdateFlag, "Update the posts of new issues when their title or labels change", "true", "", false)
+	subscriptionsAdd.AddNamedTextArgument(relatedPRsFlag, "Reply to the posts of new issues with the open pull requests which may already fix them", "true", "", false)
+	subscriptionsAdd.AddNamedTextArgument(suggestReviewersFlag, "Suggest the owners of the changed files as reviewers of new pull requests, based on CODEOWNERS", "true", "", false)
+	subscriptionsAdd.AddNamedTextArgument(logExcerptFlag, "Add the end of the log of the failed job to the posts of workflow failures", "true", "", false)
+	subscriptionsAdd.AddNamedTextArgument(sampleFlag, "Post at most a number out of every few events of each feature, e.g. 1/10", "[rate]", "", false)
+	subscriptionsAdd.AddNamedTextArgument(maxPerHourFlag, "Post at most the given number of notifications per hour, then summarize the suppressed ones, e.g. 30", "[count]", "", false)
+	subscriptionsAdd.AddNamedTextArgument(starMilestonesFlag, "With the stars feature, only post when the number of stars reaches one of the given counts", "[count],[count]", "", false)
+	subscriptionsAdd.AddNamedTextArgument(templateFlag, "Render notifications with a notification template configured by a system admin", "[name]", "", false)
+	subscriptionsAdd.AddNamedTextArgument(mentionUsersFlag, "Mention the Mattermost users of the authors of pushed commits", "true", "", false)
+	subscriptionsAdd.AddNamedTextArgument(syncHeaderFlag, "Keep the channel header in sync with the description and default branch of the repository", "true", "", false)
+	subscriptionsAdd.AddNamedTextArgument(fromHeaderFlag, "Subscribe to the repository linked in the channel header instead of [owner/repo]. Pick one by its position if several are linked", "[position] (optional)", "", false)
+	subscriptionsAdd.AddNamedTextArgument(subscriptionTargetFlag, "Subscribe your direct messages with the bot instead of the current channel", "dm", "", false)
+	subscriptionsAdd.AddNamedTextArgument(weeklyDigestFlag, "Post a weekly digest of the activity on the given day and time (UTC)", "[day] [hh:mm]", "", false)
 	subscriptions.AddCommand(subscriptionsAdd)
 
 	subscriptionsDelete := model.NewAutocompleteData("delete", "[owner/repo]", "Unsubscribe the current channel from an organization or repository")
 	subscriptionsDelete.AddTextArgument("Owner/repo to unsubscribe from", "[owner/repo]", "")
 	subscriptions.AddCommand(subscriptionsDelete)
 
+	subscriptionsPause := model.NewAutocompleteData("pause", "[owner/repo] [duration]", "Pause the notifications of a subscription of the current channel, until resumed or for a duration")
+	subscriptionsPause.AddTextArgument("Owner/repo of the subscription", "[owner/repo]", "")
+	subscriptionsPause.AddTextArgument("(Optional) Duration of the pause, e.g. 90m, 12h or 7d", "[duration]", "")
+	subscriptions.AddCommand(subscriptionsPause)
+
+	subscriptionsResume := model.NewAutocompleteData("resume", "[owner/repo]", "Resume the notifications of a paused subscription of the current channel")
+	subscriptionsResume.AddTextArgument("Owner/repo of the subscription", "[owner/repo]", "")
+	subscriptions.AddCommand(subscriptionsResume)
+
+	subscriptionsClaim := model.NewAutocompleteData("claim", "[owner/repo]", "Become the creator of the subscription of the current channel, whose GitHub access is used for private repositories")
+	subscriptionsClaim.AddTextArgument("Owner/repo of the subscription", "[owner/repo]", "")
+	subscriptions.AddCommand(subscriptionsClaim)
+
+	subscriptionsRoute := model.NewAutocompleteData("route", "[command]", "Available commands: list, add, delete")
+
+	routeList := model.NewAutocompleteData("list", "", "List the subscription routes")
+	subscriptionsRoute.AddCommand(routeList)
+
+	routeAdd := model.NewAutocompleteData("add", "[owner/repo] [features] [channel]", "Route events of an organization or repository to another channel")
+	routeAdd.AddTextArgument("Owner/repo to route events from", "[owner/repo]", "")
+	routeAdd.AddTextArgument("Comma-delimited list of features to route", "[features]", `/[^,-\s]+(,[^,-\s]+)*/`)
+	routeAdd.AddTextArgument("Channel to route events to", "[~channel]", "")
+	subscriptionsRoute.AddCommand(routeAdd)
+
+	routeDelete := model.NewAutocompleteData("delete", "[owner/repo] [channel]", "Delete the route of an organization or repository to a channel")
+	routeDelete.AddTextArgument("Owner/repo to stop routing events from", "[owner/repo]", "")
+	routeDelete.AddTextArgument("Channel events are routed to", "[~channel]", "")
+	subscriptionsRoute.AddCommand(routeDelete)
+
+	subscriptionsRoute.RoleID = model.SYSTEM_ADMIN_ROLE_ID
+	subscriptions.AddCommand(subscriptionsRoute)
+
 	github.AddCommand(subscriptions)
 
-	me := model.NewAutocompleteData("me", "", "Display the connected GitHub account")
+	channelSettings := model.NewAutocompleteData("channel-settings", "[command]", "Available commands: get, render-style, unset")
+
+	channelSettingsGet := model.NewAutocompleteData("get", "", "Display the settings of the current channel")
+	channelSettings.AddCommand(channelSettingsGet)
+
+	renderStyles := []model.AutocompleteListItem{{
+		HelpText: "Render the full pull request or issue",
+		Item:     renderStyleDefault,
+	}, {
+		HelpText: "Leave out the description of the pull request or issue",
+		Item:     renderStyleSkipBody,
+	}, {
+		HelpText: "Render the pull request or issue on a single line",
+		Item:     renderStyleCollapsed,
+	}}
+	channelSettingsRenderStyle := model.NewAutocompleteData(renderStyleFlag, "[style]", "Set the render style of the subscriptions of the current channel")
+	channelSettingsRenderStyle.AddStaticListArgument("Render style", true, renderStyles)
+	channelSettings.AddCommand(channelSettingsRenderStyle)
+
+	channelSettingsUnset := model.NewAutocompleteData("unset", "[setting]", "Unset a setting of the current channel")
+	channelSettingsUnset.AddStaticListArgument("Setting to unset", true, []model.AutocompleteListItem{{
+		HelpText: "Go back to the default render style",
+		Item:     renderStyleFlag,
+	}})
+	channelSettings.AddCommand(channelSettingsUnset)
+
+	github.AddCommand(channelSettings)
+
+	exportEvents := model.NewAutocompleteData("export-events", "[days]", "Export the GitHub notifications of the current channel to CSV")
+	exportEvents.AddTextArgument("Number of days to export, e.g. 30d. Defaults to 7 days", "[days] (optional)", "")
+	exportEvents.RoleID = model.SYSTEM_ADMIN_ROLE_ID
+	github.AddCommand(exportEvents)
+
+	webhook := model.NewAutocompleteData("webhook", "[command]", "Available commands: info")
+	webhookInfo := model.NewAutocompleteData("info", "[owner/repo]", "Display the webhook configuration and last delivery of a repository")
+	webhookInfo.AddTextArgument("Owner/repo of the webhook", "[owner/repo]", "")
+	webhook.AddCommand(webhookInfo)
+	github.AddCommand(webhook)
+
+	setup := model.NewAutocompleteData("setup", "[command]", "Available commands: test, welcome, announcement")
+	setupTest := model.NewAutocompleteData("test", "[owner/repo]", "Check the configuration, your connection, the webhook of a repository and that the bot can post here")
+	setupTest.AddTextArgument("Owner/repo whose webhook to test", "[owner/repo]", "")
+	setup.AddCommand(setupTest)
+	setupWelcome := model.NewAutocompleteData("welcome", "", "Preview the message sent to users connecting their account")
+	setup.AddCommand(setupWelcome)
+	setupAnnouncement := model.NewAutocompleteData("announcement", "--channels [channels]", "Announce the plugin in the given channels, with a link to connect an account")
+	setupAnnouncement.AddNamedTextArgument("channels", "Comma-separated names of the channels to post the announcement in", "town-square,engineering", "", true)
+	setup.AddCommand(setupAnnouncement)
+	setup.RoleID = model.SYSTEM_ADMIN_ROLE_ID
+	github.AddCommand(setup)
+
+	admin := model.NewAutocompleteData("admin", "[command]", "Available commands: refresh-all, sync-usernames, refresh-org-members, subscriptions, webhook-queue")
+	adminRefreshAll := model.NewAutocompleteData("refresh-all", "", "Refresh the GitHub sidebar of all connected users")
+	admin.AddCommand(adminRefreshAll)
+	adminSyncUsernames := model.NewAutocompleteData("sync-usernames", "", "Map the members of the organization to the Mattermost users with their public email")
+	admin.AddCommand(adminSyncUsernames)
+	adminRefreshOrgMembers := model.NewAutocompleteData("refresh-org-members", "", "Check the members of the organization again for the org member filters")
+	admin.AddCommand(adminRefreshOrgMembers)
+	adminSubscriptions := model.NewAutocompleteData("subscriptions", "[command]", "Available commands: find, remove")
+	adminSubscriptionsFind := model.NewAutocompleteData("find", "[owner/repo]", "List the channels subscribed to a repository")
+	adminSubscriptionsFind.AddTextArgument("Owner/repo the channels are subscribed to", "[owner/repo]", "")
+	adminSubscriptions.AddCommand(adminSubscriptionsFind)
+	adminSubscriptionsRemove := model.NewAutocompleteData("remove", "[owner/repo] --channel [channel ID|all]", "Remove the subscriptions of one or all channels to a repository")
+	adminSubscriptionsRemove.AddTextArgument("Owner/repo the channels are subscribed to", "[owner/repo]", "")
+	adminSubscriptionsRemove.AddTextArgument("ID of the channel, or all", "--channel [channel ID|all]", "")
+	adminSubscriptions.AddCommand(adminSubscriptionsRemove)
+	admin.AddCommand(adminSubscriptions)
+	adminWebhookQueue := model.NewAutocompleteData("webhook-queue", "", "Show the activity of the queue of webhook events")
+	admin.AddCommand(adminWebhookQueue)
+	admin.RoleID = model.SYSTEM_ADMIN_ROLE_ID
+	github.AddCommand(admin)
+
+	keywords := model.NewAutocompleteData("keywords", "[command]", "Available commands: list, add, remove")
+	keywordsList := model.NewAutocompleteData("list", "[--channel ~channel]", "List the keywords posted to a channel")
+	keywordsList.AddTextArgument("Channel of the keywords, defaults to the current channel", "[--channel here|~channel] (optional)", "")
+	keywords.AddCommand(keywordsList)
+	keywordsAdd := model.NewAutocompleteData("add", "[keyword] [--channel ~channel]", "Post new issues, pull requests and comments mentioning a keyword or a team to a channel")
+	keywordsAdd.AddTextArgument("Keyword, or team mention like @org/team", "[keyword]", "")
+	keywordsAdd.AddTextArgument("Channel to post to, defaults to the current channel", "[--channel here|~channel] (optional)", "")
+	keywords.AddCommand(keywordsAdd)
+	keywordsRemove := model.NewAutocompleteData("remove", "[keyword] [--channel ~channel]", "Stop posting mentions of a keyword to a channel")
+	keywordsRemove.AddTextArgument("Keyword, or team mention like @org/team", "[keyword]", "")
+	keywordsRemove.AddTextArgument("Channel to stop posting to, defaults to the current channel", "[--channel here|~channel] (optional)", "")
+	keywords.AddCommand(keywordsRemove)
+	keywords.RoleID = model.SYSTEM_ADMIN_ROLE_ID
+	github.AddCommand(keywords)
+
+	linkUsername := model.NewAutocompleteData(linkUsernameCommand, "[GitHub username|verify]", "Get notified about your GitHub mentions without connecting your account")
+	linkUsername.AddTextArgument("Your GitHub username, or verify once the verification code is added to your GitHub profile", "[GitHub username|verify]", "")
+	github.AddCommand(linkUsername)
+	unlinkUsername := model.NewAutocompleteData(unlinkUsernameCommand, "", "Stop getting notified about the GitHub username you linked")
+	github.AddCommand(unlinkUsername)
+
+	workflow := model.NewAutocompleteData("workflow", "[command]", "Available commands: list, run")
+	workflowList := model.NewAutocompleteData("list", "[owner/repo]", "List the workflows of a repository that can be run with workflow_dispatch")
+	workflowList.AddTextArgument("Owner/repo of the workflows", "[owner/repo]", "")
+	workflow.AddCommand(workflowList)
+	workflowRun := model.NewAutocompleteData("run", "[owner/repo] [workflow file] [ref] [key=value ...]", "Run a workflow with workflow_dispatch")
+	workflowRun.AddTextArgument("Owner/repo of the workflow", "[owner/repo]", "")
+	workflowRun.AddTextArgument("File name of the workflow, e.g. deploy.yml", "[workflow file]", "")
+	workflowRun.AddTextArgument("Branch or tag to run the workflow on", "[ref]", "")
+	workflowRun.AddTextArgument("Inputs of the workflow", "[key=value ...] (optional)", "")
+	workflow.AddCommand(workflowRun)
+	github.AddCommand(workflow)
+
+	pr := model.NewAutocompleteData("pr", "[command]", "Available commands: create")
+	prCreate := model.NewAutocompleteData("create", "[owner/repo] [head] [base]", "Create a pull request of a branch")
+	prCreate.AddTextArgument("Owner/repo of the pull request", "[owner/repo]", "")
+	prCreate.AddTextArgument("Branch to merge", "[head]", "")
+	prCreate.AddTextArgument("Branch to merge into. Defaults to the default branch of the repository", "[base] (optional)", "")
+	prCreate.AddNamedTextArgument(pullRequestTitleFlag, "Title of the pull request. Defaults to the name of the branch", "\"[title]\"", "", false)
+	prCreate.AddNamedTextArgument(pullRequestBodyFlag, "Description of the pull request", "\"[body]\"", "", false)
+	prCreate.AddStaticListArgument("Currently supports --draft", false, []model.AutocompleteListItem{{
+		HelpText: "Create a draft pull request",
+		Hint:     "(optional)",
+		Item:     "--" + pullRequestDraftFlag,
+	}})
+	pr.AddCommand(prCreate)
+	github.AddCommand(pr)
+
+	search := model.NewAutocompleteData("search", "[command]", "Available commands: save, list, delete")
+	searchSave := model.NewAutocompleteData("save", "[name] \"[query]\"", "Save a GitHub search as a section of your sidebar")
+	searchSave.AddTextArgument("Name of the search, e.g. urgent-reviews", "[name]", "")
+	searchSave.AddTextArgument("GitHub search query, e.g. \"is:pr review-requested:@me label:urgent\"", "\"[query]\"", "")
+	search.AddCommand(searchSave)
+	searchList := model.NewAutocompleteData("list", "", "List your saved searches")
+	search.AddCommand(searchList)
+	searchDelete := model.NewAutocompleteData("delete", "[name]", "Delete a saved search")
+	searchDelete.AddTextArgument("Name of the search", "[name]", "")
+	search.AddCommand(searchDelete)
+	github.AddCommand(search)
+
+	watch := model.NewAutocompleteData("watch", "[owner/repo] [all|participating|ignore]", "Show or change how you watch a repository on GitHub, or use `list` to list the repositories you watch")
+	watch.AddTextArgument("Repository, or `list`. The repository can be omitted if the channel is subscribed to a single one", "[owner/repo|list]", "")
+	watch.AddStaticListArgument("Notifications to get from GitHub", false, []model.AutocompleteListItem{{
+		HelpText: "Get notified of all the activity of the repository",
+		Item:     watchStateAll,
+	}, {
+		HelpText: "Get notified only when you participate or are mentioned",
+		Item:     watchStateParticipating,
+	}, {
+		HelpText: "Never get notified of the activity of the repository on GitHub",
+		Item:     watchStateIgnore,
+	}})
+	github.AddCommand(watch)
+
+	me := model.NewAutocompleteData("me", "[command]", "Display the connected GitHub account")
+	meActivity := model.NewAutocompleteData("activity", "[days]", "Summarize your recent GitHub activity")
+	meActivity.AddTextArgument("Number of days to summarize, e.g. 7d. Defaults to 7 days", "[days] (optional)", "")
+	me.AddCommand(meActivity)
 	github.AddCommand(me)
 
+	whois := model.NewAutocompleteData("whois", "[@mattermost-user|github-login]", "Tell if a user is connected to GitHub, or which user a GitHub account is connected to")
+	whois.AddTextArgument("Mattermost @mention or GitHub username", "[@mattermost-user|github-login]", "")
+	github.AddCommand(whois)
+
 	mute := model.NewAutocompleteData("mute", "[command]", "Available commands: list, add, delete, delete-all")
 
-	muteAdd := model.NewAutocompleteData("add", "[github username]", "Mute notifications from the provided GitHub user")
+	muteAdd := model.NewAutocompleteData("add", "[github username] [--scope channels]", "Mute notifications from the provided GitHub user")
 	muteAdd.AddTextArgument("GitHub user to mute", "[username]", "")
+	muteAdd.AddNamedStaticListArgument("scope", "Also mute the user in the channels you subscribed", false, []model.AutocompleteListItem{
+		{HelpText: "Direct messages and the channels you subscribed", Item: muteScopeChannels},
+	})
 	mute.AddCommand(muteAdd)
 
 	muteDelete := model.NewAutocompleteData("delete", "[github username]", "Unmute notifications from the provided GitHub user")
@@ -584,6 +1349,27 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 	}, {
 		HelpText: "Turn reminders on/off",
 		Item:     "reminders",
+	}, {
+		HelpText: "Turn the weekly summary on/off, optionally followed by a day and time in UTC",
+		Item:     "weekly-summary",
+	}, {
+		HelpText: "Allow or prevent showing your Mattermost account next to your GitHub login",
+		Item:     "discoverable",
+	}, {
+		HelpText: "Prevent or allow changes on GitHub with your account, e.g. creating issues",
+		Item:     "read-only",
+	}, {
+		HelpText: "Post your notifications of the day in a single thread, or each in a message of its own",
+		Item:     "consolidated-dms",
+	}, {
+		HelpText: "Keep posting mentions and review requests in messages of their own when consolidating your notifications",
+		Item:     "urgent-dms",
+	}, {
+		HelpText: "Allow or stop invites to connect your account when you're mentioned on GitHub, for users who aren't connected",
+		Item:     "invites",
+	}, {
+		HelpText: "Choose the sidebar buttons and their order among prs, reviews, assignments, unreads and refresh, e.g. prs,reviews,refresh",
+		Item:     "sidebar",
 	}}
 	settings.AddStaticListArgument("Setting to update", true, setting)
 	value := []model.AutocompleteListItem{{
@@ -596,14 +1382,59 @@ func getAutocompleteData(config *Configuration) *model.AutocompleteData {
 	settings.AddStaticListArgument("", true, value)
 	github.AddCommand(settings)
 
-	issue := model.NewAutocompleteData("issue", "[command]", "Available commands: create")
+	issue := model.NewAutocompleteData("issue", "[command]", "Available commands: create, export")
 
 	issueCreate := model.NewAutocompleteData("create", "[title]", "Open a dialog to create a new issue in Github, using the title if provided")
 	issueCreate.AddTextArgument("Title for the Github issue", "[title]", "")
 	issue.AddCommand(issueCreate)
 
+	issueExport := model.NewAutocompleteData("export", "[owner/repo] [--label bug] [--format csv|md]", "Post the open issues of a repository in the channel, as a CSV file or a markdown table")
+	issueExport.AddTextArgument("Repository to export the issues of. It can be omitted if the channel is subscribed to a single one", "[owner/repo]", "")
+	issueExport.AddNamedTextArgument("label", "Only export the issues with these comma-delimited labels", "[labels]", "", false)
+	issueExport.AddNamedStaticListArgument("format", "Format of the export, csv by default", false, []model.AutocompleteListItem{{
+		HelpText: "CSV file",
+		Item:     issueExportFormatCSV,
+	}, {
+		HelpText: "Markdown table",
+		Item:     issueExportFormatMarkdown,
+	}})
+	issue.AddCommand(issueExport)
+
 	github.AddCommand(issue)
 
+	reviewers := model.NewAutocompleteData("reviewers", "[command]", "Available commands: add, remove")
+
+	reviewersAdd := model.NewAutocompleteData("add", "[owner/repo#number] [usernames]", "Request a review of a pull request")
+	reviewersAdd.AddTextArgument("Pull request to request a review of. The repository can be omitted if the channel is subscribed to a single one", "[owner/repo#number]", "")
+	reviewersAdd.AddTextArgument("Comma-delimited list of GitHub usernames or Mattermost @mentions", "[usernames]", "")
+	reviewers.AddCommand(reviewersAdd)
+
+	reviewersRemove := model.NewAutocompleteData("remove", "[owner/repo#number] [usernames]", "Remove requested reviewers from a pull request")
+	reviewersRemove.AddTextArgument("Pull request to remove reviewers from. The repository can be omitted if the channel is subscribed to a single one", "[owner/repo#number]", "")
+	reviewersRemove.AddTextArgument("Comma-delimited list of GitHub usernames or Mattermost @mentions", "[usernames]", "")
+	reviewers.AddCommand(reviewersRemove)
+
+	github.AddCommand(reviewers)
+
+	assign := model.NewAutocompleteData("assign", "[owner/repo#number] [@github-login|me]", "Assign a user to an issue or pull request, yourself by default")
+	assign.AddTextArgument("Issue or pull request to assign. The repository can be omitted if the channel is subscribed to a single one", "[owner/repo#number]", "")
+	assign.AddTextArgument("GitHub username or Mattermost @mention of the user to assign", "[@github-login|me]", "")
+	github.AddCommand(assign)
+
+	unassign := model.NewAutocompleteData("unassign", "[owner/repo#number] [@github-login|me]", "Unassign a user from an issue or pull request, yourself by default")
+	unassign.AddTextArgument("Issue or pull request to unassign. The repository can be omitted if the channel is subscribed to a single one", "[owner/repo#number]", "")
+	unassign.AddTextArgument("GitHub username or Mattermost @mention of the user to unassign", "[@github-login|me]", "")
+	github.AddCommand(unassign)
+
+	// Disabled commands are hidden so that users don't see options they can't use.
+	enabledCommands := []*model.AutocompleteData{}
+	for _, subCommand := range github.SubCommands {
+		if !config.isCommandDisabled(subCommand.Trigger) {
+			enabledCommands = append(enabledCommands, subCommand)
+		}
+	}
+	github.SubCommands = enabledCommands
+
 	return github
 }
 
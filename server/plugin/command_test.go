@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestValidateFeatures(t *testing.T) {
@@ -72,6 +75,16 @@ func TestValidateFeatures(t *testing.T) {
 			args: []string{"pulls", "push", "create", `label:"ruby"`},
 			want: output{false, []string{"push", "create"}},
 		},
+		{
+			name: "milestone filter valid",
+			args: []string{"pushes", `milestone_changes:"v2.0"`},
+			want: output{true, []string{}},
+		},
+		{
+			name: "all features valid with label and milestone changes in features",
+			args: []string{"milestone_changes", `label:"ruby"`},
+			want: output{true, []string{}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -197,3 +210,36 @@ func TestParseCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAutocompleteDataDisabledCommands(t *testing.T) {
+	triggers := func(data *model.AutocompleteData) []string {
+		result := []string{}
+		for _, subCommand := range data.SubCommands {
+			result = append(result, subCommand.Trigger)
+		}
+		return result
+	}
+
+	all := triggers(getAutocompleteData(&Configuration{}))
+	assert.Contains(t, all, "issue")
+	assert.Contains(t, all, "reviewers")
+
+	filtered := triggers(getAutocompleteData(&Configuration{DisabledCommands: " issue, Reviewers ,"}))
+	assert.NotContains(t, filtered, "issue")
+	assert.NotContains(t, filtered, "reviewers")
+	assert.Len(t, filtered, len(all)-2)
+}
+
+func TestExecuteCommandDisabled(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{DisabledCommands: "issue"})
+	api := &plugintest.API{}
+	api.On("SendEphemeralPost", "userID", mock.MatchedBy(func(post *model.Post) bool {
+		return post.Message == disabledCommandMessage
+	})).Return(nil).Once()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	_, appErr := p.ExecuteCommand(nil, &model.CommandArgs{UserId: "userID", Command: "/github issue create title"})
+	assert.Nil(t, appErr)
+}
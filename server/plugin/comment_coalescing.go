@@ -0,0 +1,193 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	coalesceCommentsFlag = "coalesce-comments"
+	commentCoalescingKey = "_commentcoalescing"
+
+	minCommentCoalescingWindow = 30 * time.Second
+	maxCommentCoalescingWindow = time.Hour
+	// commentCoalescingExcerptLength is the length of the excerpt of the latest merged comment.
+	commentCoalescingExcerptLength  = 80
+	commentCoalescingUpdateAttempts = 5
+)
+
+// commentCoalescingWindow is the window during which the comments on an issue are merged into the
+// post of the first one, for a subscription. It is stored with the time left as its expiry, so the
+// next comment after the window closes starts a new post.
+type commentCoalescingWindow struct {
+	PostID string
+	// Message is the message of the post of the first comment.
+	Message   string
+	StartedAt time.Time
+	// Merged is the number of comments merged into the post.
+	Merged int
+	// Authors are the authors of the merged comments, in order of their first comment.
+	Authors []string
+	Latest  string
+}
+
+// parseCoalesceWindow parses the value of the --coalesce-comments flag, e.g. 2m.
+func parseCoalesceWindow(value string) (time.Duration, error) {
+	window, err := time.ParseDuration(value)
+	if err != nil || window < minCommentCoalescingWindow || window > maxCommentCoalescingWindow {
+		return 0, errors.Errorf("Invalid value %q for --%s. Use a duration between 30s and 1h, e.g. `2m`.", value, coalesceCommentsFlag)
+	}
+
+	return window, nil
+}
+
+func commentCoalescingKeyFor(sub *Subscription, issueURL string) string {
+	return hashKey(commentCoalescingKey, sub.ChannelID, sub.Repository, issueURL)
+}
+
+// commentExcerpt returns the beginning of the first line of a comment.
+func commentExcerpt(body string) string {
+	body = strings.TrimSpace(body)
+	if i := strings.IndexAny(body, "\r\n"); i >= 0 {
+		body = body[:i]
+	}
+
+	return truncateText(body, commentCoalescingExcerptLength)
+}
+
+// merge counts a comment as merged into the post of the window.
+func (w *commentCoalescingWindow) merge(author, body string) {
+	w.Merged++
+	if !containsValue(w.Authors, author) {
+		w.Authors = append(w.Authors, author)
+	}
+	w.Latest = commentExcerpt(body)
+}
+
+// message returns the message of the post with the merged comments summarized.
+func (w *commentCoalescingWindow) message() string {
+	return fmt.Sprintf("%s\n\n💬 +%d more %s from %s (latest: '%s')", w.Message, w.Merged, pluralize(w.Merged, "comment", "comments"), strings.Join(w.Authors, ", "), w.Latest)
+}
+
+// expiry returns the number of seconds left in the window, at least one.
+func (w *commentCoalescingWindow) expiry(window time.Duration, now time.Time) int64 {
+	left := w.StartedAt.Add(window).Sub(now).Seconds()
+	return int64(math.Max(1, math.Ceil(left)))
+}
+
+// mergeIntoCoalescingWindow merges a comment into the open coalescing window of an issue for a
+// subscription, if there is one. It returns the updated window, or nil if there is none.
+func (p *Plugin) mergeIntoCoalescingWindow(key string, window time.Duration, author, body string, now time.Time) (*commentCoalescingWindow, error) {
+	for attempt := 0; attempt < commentCoalescingUpdateAttempts; attempt++ {
+		oldValue, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "could not get comment coalescing window from KV store")
+		}
+		if oldValue == nil {
+			return nil, nil
+		}
+
+		coalescing := &commentCoalescingWindow{}
+		if err := json.Unmarshal(oldValue, coalescing); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal comment coalescing window")
+		}
+		if !now.Before(coalescing.StartedAt.Add(window)) {
+			return nil, nil
+		}
+
+		coalescing.merge(author, body)
+
+		newValue, err := json.Marshal(coalescing)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not marshal comment coalescing window")
+		}
+
+		stored, appErr := p.API.KVSetWithOptions(key, newValue, model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        oldValue,
+			ExpireInSeconds: coalescing.expiry(window, now),
+		})
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "could not store comment coalescing window in KV store")
+		}
+		if stored {
+			return coalescing, nil
+		}
+	}
+
+	return nil, errors.New("too many concurrent updates of the comment coalescing window")
+}
+
+// updateCoalescedPost edits the post of a coalescing window to summarize the merged comments.
+func (p *Plugin) updateCoalescedPost(coalescing *commentCoalescingWindow) error {
+	post, appErr := p.API.GetPost(coalescing.PostID)
+	if appErr != nil {
+		return errors.Wrap(appErr, "could not get post")
+	}
+	if post.DeleteAt != 0 {
+		return errors.New("post was deleted")
+	}
+
+	post.Message = coalescing.message()
+	if _, appErr := p.API.UpdatePost(post); appErr != nil {
+		return errors.Wrap(appErr, "could not update post")
+	}
+
+	return nil
+}
+
+// postCoalescedComment posts a comment for a subscription merging comments. The first comment on
+// an issue is posted normally and opens a window, the next ones during the window edit its post.
+// Direct notifications of the comment aren't affected.
+func (p *Plugin) postCoalescedComment(post *model.Post, sub *Subscription, event *github.IssueCommentEvent, delivery *webhookDelivery) {
+	window, err := parseCoalesceWindow(sub.Flags.CoalesceComments)
+	if err != nil || delivery.Replayed {
+		p.postSubscriptionEvent(post, sub, featureIssueComments, delivery)
+		return
+	}
+
+	now := time.Now()
+	key := commentCoalescingKeyFor(sub, event.GetIssue().GetHTMLURL())
+
+	coalescing, err := p.mergeIntoCoalescingWindow(key, window, event.GetSender().GetLogin(), event.GetComment().GetBody(), now)
+	if err != nil {
+		// Posting too much beats missing comments
+		p.API.LogWarn("Failed to coalesce comment", "channelID", sub.ChannelID, "repo", sub.Repository, "error", err.Error())
+	}
+
+	if coalescing != nil {
+		err := p.updateCoalescedPost(coalescing)
+		if err == nil {
+			delivery.recordFiltered(filterReasonCoalesced)
+			return
+		}
+		// The post may have been deleted, start a new window
+		p.API.LogWarn("Failed to update coalesced comment post", "postID", coalescing.PostID, "error", err.Error())
+	}
+
+	created := p.postSubscriptionEvent(post, sub, featureIssueComments, delivery)
+	if created == nil {
+		return
+	}
+
+	value, err := json.Marshal(&commentCoalescingWindow{
+		PostID:    created.Id,
+		Message:   created.Message,
+		StartedAt: now,
+		Authors:   []string{},
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to marshal comment coalescing window", "error", err.Error())
+		return
+	}
+	if appErr := p.API.KVSetWithExpiry(key, value, int64(window.Seconds())); appErr != nil {
+		p.API.LogWarn("Failed to store comment coalescing window", "channelID", sub.ChannelID, "repo", sub.Repository, "error", appErr.Error())
+	}
+}
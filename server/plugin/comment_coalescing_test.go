@@ -0,0 +1,184 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCoalesceWindow(t *testing.T) {
+	window, err := parseCoalesceWindow("2m")
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, window)
+
+	for _, value := range []string{"", "2", "10s", "2h"} {
+		_, err := parseCoalesceWindow(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestCommentCoalescingWindowMessage(t *testing.T) {
+	w := &commentCoalescingWindow{Message: "alice commented", Authors: []string{}}
+	w.merge("bob", "I disagree\nfor many reasons")
+	assert.Equal(t, "alice commented\n\n💬 +1 more comment from bob (latest: 'I disagree')", w.message())
+
+	w.merge("carol", "Me too")
+	w.merge("bob", "Still disagree")
+	assert.Equal(t, "alice commented\n\n💬 +3 more comments from bob, carol (latest: 'Still disagree')", w.message())
+}
+
+func TestPostCoalescedComments(t *testing.T) {
+	subs, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "coalescedID", Repository: "owner/repo", Features: "issue_comments", Flags: SubscriptionFlags{CoalesceComments: "2m"}},
+			{ChannelID: "channelID", Repository: "owner/repo", Features: "issue_comments"},
+		},
+	}})
+	require.NoError(t, err)
+
+	setup := func() (*Plugin, *plugintest.API, map[string][]byte, map[string]*model.Post) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		store[SubscriptionsKey] = subs
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(120)).Return(func(key string, value []byte, _ int64) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(func(key string, value []byte, opts model.PluginKVSetOptions) bool {
+			if !bytes.Equal(store[key], opts.OldValue) {
+				return false
+			}
+			store[key] = value
+			return true
+		}, nil)
+
+		// Posts by ID, the webhook handlers reusing their post for every channel
+		posts := map[string]*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			created := post.Clone()
+			created.Id = model.NewId()
+			posts[created.Id] = created
+			return created
+		}, nil)
+		api.On("GetPost", mock.AnythingOfType("string")).Return(func(postID string) *model.Post {
+			return posts[postID].Clone()
+		}, nil)
+		api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			posts[post.Id] = post
+			return post
+		}, nil)
+		p.SetAPI(api)
+
+		return p, api, store, posts
+	}
+
+	comment := func(login, body string) *github.IssueCommentEvent {
+		return &github.IssueCommentEvent{
+			Action: github.String("created"),
+			Repo:   &github.Repository{FullName: github.String("owner/repo"), Name: github.String("repo"), Owner: &github.User{Login: github.String("owner")}},
+			Issue:  &github.Issue{Number: github.Int(12), Title: github.String("Heated issue"), HTMLURL: github.String("https://github.com/owner/repo/issues/12")},
+			Comment: &github.IssueComment{
+				HTMLURL: github.String("https://github.com/owner/repo/issues/12#issuecomment-1"),
+				Body:    github.String(body),
+			},
+			Sender: &github.User{Login: github.String(login)},
+		}
+	}
+
+	postsInChannel := func(posts map[string]*model.Post, channelID string) []*model.Post {
+		inChannel := []*model.Post{}
+		for _, post := range posts {
+			if post.ChannelId == channelID {
+				inChannel = append(inChannel, post)
+			}
+		}
+		return inChannel
+	}
+
+	t.Run("comments within the window are merged", func(t *testing.T) {
+		p, _, _, posts := setup()
+
+		p.postIssueCommentEvent(comment("alice", "First"), testWebhookDelivery(p))
+		p.postIssueCommentEvent(comment("bob", "Second\nwith details"), testWebhookDelivery(p))
+		p.postIssueCommentEvent(comment("carol", "Third"), testWebhookDelivery(p))
+		p.postIssueCommentEvent(comment("bob", "Fourth"), testWebhookDelivery(p))
+
+		assert.Len(t, postsInChannel(posts, "channelID"), 4)
+
+		coalesced := postsInChannel(posts, "coalescedID")
+		require.Len(t, coalesced, 1)
+		assert.Contains(t, coalesced[0].Message, "First")
+		assert.NotContains(t, coalesced[0].Message, "Second")
+		assert.Contains(t, coalesced[0].Message, "\n\n💬 +3 more comments from bob, carol (latest: 'Fourth')")
+	})
+
+	t.Run("a comment after the window starts a new post", func(t *testing.T) {
+		p, _, store, posts := setup()
+
+		p.postIssueCommentEvent(comment("alice", "First"), testWebhookDelivery(p))
+
+		// The window opened three minutes ago
+		key := commentCoalescingKeyFor(&Subscription{ChannelID: "coalescedID", Repository: "owner/repo"}, "https://github.com/owner/repo/issues/12")
+		coalescing := &commentCoalescingWindow{}
+		require.NoError(t, json.Unmarshal(store[key], coalescing))
+		coalescing.StartedAt = coalescing.StartedAt.Add(-3 * time.Minute)
+		store[key], err = json.Marshal(coalescing)
+		require.NoError(t, err)
+
+		p.postIssueCommentEvent(comment("bob", "Second"), testWebhookDelivery(p))
+		assert.Len(t, postsInChannel(posts, "coalescedID"), 2)
+	})
+
+	t.Run("a deleted post starts a new window", func(t *testing.T) {
+		p, api, _, posts := setup()
+		api.On("LogWarn", "Failed to update coalesced comment post", "postID", mock.AnythingOfType("string"), "error", "post was deleted").Once()
+
+		p.postIssueCommentEvent(comment("alice", "First"), testWebhookDelivery(p))
+		for _, post := range postsInChannel(posts, "coalescedID") {
+			post.DeleteAt = model.GetMillis()
+		}
+		p.postIssueCommentEvent(comment("bob", "Second"), testWebhookDelivery(p))
+
+		assert.Len(t, postsInChannel(posts, "coalescedID"), 2)
+		api.AssertCalled(t, "LogWarn", "Failed to update coalesced comment post", "postID", mock.AnythingOfType("string"), "error", "post was deleted")
+	})
+
+	t.Run("capped comments open no window", func(t *testing.T) {
+		p, _, store, posts := setup()
+		capped, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+			"owner/repo": {{ChannelID: "coalescedID", Repository: "owner/repo", Features: "issue_comments", Flags: SubscriptionFlags{CoalesceComments: "2m", MaxPerHour: 1}}},
+		}})
+		require.NoError(t, err)
+		store[SubscriptionsKey] = capped
+
+		// The first comment uses up the cap, and the comments merged into its post don't count
+		p.postIssueCommentEvent(comment("alice", "First"), testWebhookDelivery(p))
+		p.postIssueCommentEvent(comment("bob", "Second"), testWebhookDelivery(p))
+		require.Len(t, postsInChannel(posts, "coalescedID"), 1)
+
+		// Once the window is over, the next comment is capped
+		store[commentCoalescingKeyFor(&Subscription{ChannelID: "coalescedID", Repository: "owner/repo"}, "https://github.com/owner/repo/issues/12")] = nil
+		delivery := testWebhookDelivery(p)
+		p.postIssueCommentEvent(comment("carol", "Third"), delivery)
+
+		messages := []string{}
+		for _, post := range postsInChannel(posts, "coalescedID") {
+			messages = append(messages, post.Message)
+		}
+		require.Len(t, messages, 2)
+		assert.Contains(t, strings.Join(messages, "\n"), "Rate cap of 1 notifications per hour reached for owner/repo")
+		assert.NotContains(t, strings.Join(messages, "\n"), "Third")
+		assert.Equal(t, map[string]int{filterReasonRateCapped: 1}, delivery.summary().Filtered)
+	})
+}
@@ -1,11 +1,19 @@
 package plugin
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/pkg/errors"
 )
 
+// maskedSecret replaces the secrets returned to plugins that aren't trusted with them.
+const maskedSecret = "********"
+
 // configuration captures the plugin's external configuration as exposed in the Mattermost server
 // configuration, as well as values computed from the configuration. Any public fields will be
 // deserialized from the Mattermost server configuration in OnConfigurationChange.
@@ -28,6 +36,49 @@ type Configuration struct {
 	EnterpriseBaseURL       string
 	EnterpriseUploadURL     string
 	EnableCodePreview       string
+	// RestrictIssueCreationToOrg limits the repositories issues can be created in to those of GitHubOrg.
+	RestrictIssueCreationToOrg bool
+	// WebhookPayloadSizeLimit is the maximum size of a webhook payload, in megabytes.
+	WebhookPayloadSizeLimit int
+	// UnreadsPageLimit is the maximum number of pages of notifications fetched for the unreads.
+	UnreadsPageLimit int
+	// DisabledCommands is a comma-separated list of slash commands that can't be used.
+	DisabledCommands string
+	// TrustedPluginIDs is a comma-separated list of the plugins allowed to get secrets through the plugin-to-plugin API.
+	TrustedPluginIDs string
+	// RefreshAllConcurrency is the number of sidebars refreshed at the same time when refreshing all of them.
+	RefreshAllConcurrency int
+	// RefreshAllDelay is the delay in milliseconds between two sidebar refreshes of a worker.
+	RefreshAllDelay int
+	// IssueExportMaxIssues is the maximum number of open issues of an export of a repository.
+	IssueExportMaxIssues int
+	// IssueLiveUpdateMaxAgeDays is the age in days after which issue posts are no longer updated by
+	// subscriptions with the --live-update flag.
+	IssueLiveUpdateMaxAgeDays int
+	// EnableEventReplay allows system admins to replay webhook events and keeps the last received ones.
+	EnableEventReplay bool
+	// NotificationTemplates is a JSON object of named notification templates subscriptions can select.
+	NotificationTemplates string
+	// CustomWelcomeMessage is a template replacing the built-in message sent to users connecting their account.
+	CustomWelcomeMessage string
+	// SuppressHelpInWelcome stops appending the slash command help to the custom welcome message.
+	SuppressHelpInWelcome bool
+	// InviteUnconnectedUsers invites the users whose username matches a GitHub login mentioned on
+	// GitHub to connect their account.
+	InviteUnconnectedUsers bool
+	// AllowPersonalAccessTokens allows users to connect their account with a personal access token
+	// instead of the OAuth application.
+	AllowPersonalAccessTokens bool
+	// AllowSharedTodos allows users to share their to do list in a channel with /github todo --share.
+	AllowSharedTodos bool
+	// SuggestSubscriptionsOnChannelCreate suggests subscribing the new public channels named after a
+	// repository of GitHubOrg to it.
+	SuggestSubscriptionsOnChannelCreate bool
+
+	// notificationTemplates are the parsed NotificationTemplates, by name and event type.
+	notificationTemplates map[string]map[string]*template.Template
+	// welcomeMessage is the parsed CustomWelcomeMessage, or nil if it isn't set.
+	welcomeMessage *template.Template
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -37,6 +88,153 @@ func (c *Configuration) Clone() *Configuration {
 	return &clone
 }
 
+// ClientConfiguration returns the configuration fields the webapp needs. It's broadcast to all
+// users, so it must never include secrets.
+func (c *Configuration) ClientConfiguration() map[string]interface{} {
+	return map[string]interface{}{
+		"github_client_id":               c.GitHubOAuthClientID,
+		"enterprise_base_url":            c.enterpriseWebURL(),
+		"organization":                   c.GitHubOrg,
+		"left_sidebar_enabled":           c.EnableLeftSidebar,
+		"restrict_issue_creation_to_org": c.isIssueCreationRestrictedToOrg(),
+	}
+}
+
+// isIssueCreationRestrictedToOrg checks if issues can only be created in the repositories of the
+// locked organization. There is nothing to restrict to if no organization is configured.
+func (c *Configuration) isIssueCreationRestrictedToOrg() bool {
+	return c.RestrictIssueCreationToOrg && strings.TrimSpace(c.GitHubOrg) != ""
+}
+
+// organizationWarning returns a warning if the GitHub Organization setting lists several
+// organizations. The plugin can only be locked to a single one, and a list would be used as the
+// name of an organization that doesn't exist, matching no repository at all.
+func (c *Configuration) organizationWarning() string {
+	org := strings.TrimSpace(c.GitHubOrg)
+	if !strings.ContainsAny(org, ", ;") {
+		return ""
+	}
+
+	return fmt.Sprintf("The GitHub Organization %q lists several organizations, but the plugin can only be locked to a single one. Set one organization, or none to allow all of them.", org)
+}
+
+// getWebhookPayloadSizeLimit returns the maximum size of a webhook payload in bytes.
+func (c *Configuration) getWebhookPayloadSizeLimit() int64 {
+	if c.WebhookPayloadSizeLimit <= 0 {
+		return defaultWebhookPayloadSizeLimit
+	}
+
+	return int64(c.WebhookPayloadSizeLimit) * 1024 * 1024
+}
+
+// getUnreadsPageLimit returns the maximum number of pages of notifications fetched for the unreads.
+func (c *Configuration) getUnreadsPageLimit() int {
+	if c.UnreadsPageLimit <= 0 {
+		return defaultUnreadsPageLimit
+	}
+
+	return c.UnreadsPageLimit
+}
+
+// getRefreshAllConcurrency returns the number of sidebars refreshed at the same time when refreshing all of them.
+func (c *Configuration) getRefreshAllConcurrency() int {
+	if c.RefreshAllConcurrency <= 0 {
+		return defaultRefreshAllConcurrency
+	}
+
+	return c.RefreshAllConcurrency
+}
+
+// getRefreshAllDelay returns the delay between two sidebar refreshes of a worker when refreshing all of them.
+func (c *Configuration) getRefreshAllDelay() time.Duration {
+	if c.RefreshAllDelay <= 0 {
+		return defaultRefreshAllDelay
+	}
+
+	return time.Duration(c.RefreshAllDelay) * time.Millisecond
+}
+
+// getIssueExportMaxIssues returns the maximum number of open issues of an export of a repository.
+func (c *Configuration) getIssueExportMaxIssues() int {
+	if c.IssueExportMaxIssues <= 0 {
+		return defaultIssueExportMaxIssues
+	}
+
+	return c.IssueExportMaxIssues
+}
+
+// getIssueLiveUpdateMaxAge returns the age after which issue posts are no longer updated.
+func (c *Configuration) getIssueLiveUpdateMaxAge() time.Duration {
+	days := c.IssueLiveUpdateMaxAgeDays
+	if days <= 0 {
+		days = defaultIssueLiveUpdateMaxAgeDays
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// getDisabledCommands returns the slash commands disabled by the administrator.
+func (c *Configuration) getDisabledCommands() []string {
+	commands := []string{}
+	for _, command := range strings.Split(c.DisabledCommands, ",") {
+		command = strings.ToLower(strings.TrimSpace(command))
+		if command != "" {
+			commands = append(commands, command)
+		}
+	}
+
+	return commands
+}
+
+// isCommandDisabled checks if a slash command, and the endpoints doing the same, are disabled.
+func (c *Configuration) isCommandDisabled(command string) bool {
+	return containsValue(c.getDisabledCommands(), command)
+}
+
+// unknownDisabledCommands returns the disabled commands that aren't slash commands of the plugin,
+// e.g. misspelled ones. They are ignored rather than rejecting the whole configuration.
+func (c *Configuration) unknownDisabledCommands(handlers map[string]CommandHandleFunc) []string {
+	unknown := []string{}
+	for _, command := range c.getDisabledCommands() {
+		if _, ok := handlers[command]; !ok {
+			unknown = append(unknown, command)
+		}
+	}
+
+	return unknown
+}
+
+// isPluginTrusted checks if a plugin is allowed to get secrets through the plugin-to-plugin API.
+func (c *Configuration) isPluginTrusted(pluginID string) bool {
+	for _, trustedID := range strings.Split(c.TrustedPluginIDs, ",") {
+		if trustedID = strings.TrimSpace(trustedID); trustedID != "" && trustedID == pluginID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sanitized returns a copy of the configuration with the secrets masked, for plugins that aren't
+// trusted with them.
+func (c *Configuration) Sanitized() *Configuration {
+	sanitized := c.Clone()
+	sanitized.GitHubOAuthClientSecret = maskSecret(c.GitHubOAuthClientSecret)
+	sanitized.WebhookSecret = maskSecret(c.WebhookSecret)
+	sanitized.EncryptionKey = maskSecret(c.EncryptionKey)
+
+	return sanitized
+}
+
+// maskSecret hides a secret while still telling if it's set.
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+
+	return maskedSecret
+}
+
 // IsValid checks if all needed fields are set.
 func (c *Configuration) IsValid() error {
 	if c.GitHubOAuthClientID == "" {
@@ -104,8 +302,45 @@ func (p *Plugin) OnConfigurationChange() error {
 		return errors.Wrap(err, "failed to load plugin configuration")
 	}
 
+	for _, command := range configuration.unknownDisabledCommands(p.CommandHandlers) {
+		p.API.LogWarn("Ignoring unknown command in disabled commands", "command", command)
+	}
+
+	notificationTemplates, err := parseNotificationTemplates(configuration.NotificationTemplates)
+	if err != nil {
+		return errors.Wrap(err, "invalid notification templates")
+	}
+	configuration.notificationTemplates = notificationTemplates
+
+	welcomeMessage, err := parseWelcomeMessage(configuration.CustomWelcomeMessage)
+	if err != nil {
+		return errors.Wrap(err, "invalid custom welcome message")
+	}
+	configuration.welcomeMessage = welcomeMessage
+
+	for _, warning := range configuration.enterpriseURLWarnings() {
+		p.API.LogWarn("Inconsistent GitHub Enterprise URLs", "warning", warning)
+	}
+	if warning := configuration.organizationWarning(); warning != "" {
+		p.API.LogWarn("Invalid GitHub Organization", "warning", warning)
+	}
+
+	// Webapps get the configuration when connecting, so there is nothing to update on activation
+	p.configurationLock.RLock()
+	activating := p.configuration == nil
+	p.configurationLock.RUnlock()
+	oldClientConfiguration := p.getConfiguration().ClientConfiguration()
+
 	p.setConfiguration(configuration)
 
+	if clientConfiguration := configuration.ClientConfiguration(); !activating && !reflect.DeepEqual(oldClientConfiguration, clientConfiguration) {
+		p.API.PublishWebSocketEvent(
+			wsEventConfigUpdate,
+			clientConfiguration,
+			&model.WebsocketBroadcast{},
+		)
+	}
+
 	command, err := p.getCommand(configuration)
 	if err != nil {
 		return errors.Wrap(err, "failed to get command")
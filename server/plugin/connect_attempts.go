@@ -0,0 +1,37 @@
+package plugin
+
+import (
+	"github.com/pkg/errors"
+)
+
+// connectAttemptKey stores the OAuth state token of the latest connect attempt of a user.
+const connectAttemptKey = "_connectattempt"
+
+// supersedeConnectAttempt makes the connect attempt with the given state token the pending one of
+// a user. The state of the previous attempt is deleted right away, so that an old browser tab
+// can't complete it.
+func (p *Plugin) supersedeConnectAttempt(userID, stateToken string) error {
+	previous, appErr := p.API.KVGet(userID + connectAttemptKey)
+	if appErr != nil {
+		return errors.Wrap(appErr, "could not get pending connect attempt")
+	}
+
+	if previous != nil && string(previous) != stateToken {
+		if appErr := p.API.KVDelete(string(previous)); appErr != nil {
+			return errors.Wrap(appErr, "could not delete the state of the superseded connect attempt")
+		}
+	}
+
+	if appErr := p.API.KVSetWithExpiry(userID+connectAttemptKey, []byte(stateToken), TokenTTL); appErr != nil {
+		return errors.Wrap(appErr, "could not store pending connect attempt")
+	}
+
+	return nil
+}
+
+// finishConnectAttempt forgets the pending connect attempt of a user, unless a newer one started.
+func (p *Plugin) finishConnectAttempt(userID, stateToken string) {
+	if _, appErr := p.API.KVCompareAndDelete(userID+connectAttemptKey, []byte(stateToken)); appErr != nil {
+		p.API.LogWarn("Failed to delete pending connect attempt", "userID", userID, "error", appErr.Error())
+	}
+}
@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepeatedConnectAttempts(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{GitHubOAuthClientID: "clientID"})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(TokenTTL)).Return(func(key string, value []byte, _ int64) *model.AppError {
+		store[key] = value
+		return nil
+	})
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(func(key string) *model.AppError {
+		delete(store, key)
+		return nil
+	})
+	p.SetAPI(api)
+
+	connect := func() string {
+		w := httptest.NewRecorder()
+		p.connectUserToGitHub(w, httptest.NewRequest(http.MethodGet, "/oauth/connect", nil), "userID")
+		require.Equal(t, http.StatusFound, w.Code)
+
+		location, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		return location.Query().Get("state")
+	}
+
+	// Clicking connect several times in a row
+	first := connect()
+	second := connect()
+	last := connect()
+	require.NotEqual(t, first, last)
+
+	assert.Nil(t, store[first])
+	assert.Nil(t, store[second])
+	assert.NotNil(t, store[last])
+	assert.Equal(t, []byte(last), store["userID"+connectAttemptKey])
+
+	t.Run("a superseded attempt can't be completed", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		p.completeConnectUserToGitHub(w, httptest.NewRequest(http.MethodGet, "/oauth/complete?code=code&state="+first, nil), "userID")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "This connection attempt expired or was replaced by a newer one.")
+	})
+
+	t.Run("finishing an attempt keeps a newer one", func(t *testing.T) {
+		api.On("KVCompareAndDelete", "userID"+connectAttemptKey, []byte(second)).Return(false, nil).Once()
+		p.finishConnectAttempt("userID", second)
+		assert.Equal(t, []byte(last), store["userID"+connectAttemptKey])
+	})
+}
@@ -0,0 +1,281 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	weeklyDigestJobKey = "weekly_digest"
+	weeklyDigestKey    = "_weeklydigest"
+
+	// weeklyDigestJobInterval is how often the scheduler looks for digests due to be posted.
+	weeklyDigestJobInterval = 15 * time.Minute
+	// weeklyDigestGracePeriod is how late a digest may still be posted after its scheduled time.
+	weeklyDigestGracePeriod = time.Hour
+
+	weeklyDigestTopPRs = 5
+	// weeklyDigestMaxContributors caps the number of authors checked for being new contributors.
+	weeklyDigestMaxContributors = 20
+)
+
+// weeklySchedule is a day of the week and a time of day, in UTC.
+type weeklySchedule struct {
+	Weekday time.Weekday
+	Hour    int
+	Minute  int
+}
+
+// parseWeeklySchedule parses a schedule of the form "monday 09:00".
+func parseWeeklySchedule(value string) (*weeklySchedule, error) {
+	invalidErr := errors.Errorf("Invalid weekly digest schedule %q. Use a day and a time, e.g. `monday 09:00`.", value)
+
+	parts := strings.Fields(value)
+	if len(parts) != 2 {
+		return nil, invalidErr
+	}
+
+	schedule := &weeklySchedule{Weekday: -1}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(parts[0], day.String()) {
+			schedule.Weekday = day
+		}
+	}
+	if schedule.Weekday == -1 {
+		return nil, invalidErr
+	}
+
+	hourAndMinute := strings.Split(parts[1], ":")
+	if len(hourAndMinute) != 2 {
+		return nil, invalidErr
+	}
+
+	hour, err := strconv.Atoi(hourAndMinute[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return nil, invalidErr
+	}
+
+	minute, err := strconv.Atoi(hourAndMinute[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return nil, invalidErr
+	}
+
+	schedule.Hour = hour
+	schedule.Minute = minute
+
+	return schedule, nil
+}
+
+// lastOccurrence returns the latest time the schedule was due at or before now.
+func (s *weeklySchedule) lastOccurrence(now time.Time) time.Time {
+	now = now.UTC()
+	occurrence := time.Date(now.Year(), now.Month(), now.Day(), s.Hour, s.Minute, 0, 0, time.UTC)
+	occurrence = occurrence.AddDate(0, 0, -int((now.Weekday()-s.Weekday+7)%7))
+	if occurrence.After(now) {
+		occurrence = occurrence.AddDate(0, 0, -7)
+	}
+
+	return occurrence
+}
+
+// digestCount is a count of items in a digest section. It's unavailable if it couldn't be computed.
+type digestCount struct {
+	Count     int
+	Available bool
+}
+
+type weeklyDigest struct {
+	Repository      string
+	From            time.Time
+	To              time.Time
+	MergedPRs       digestCount
+	TopMergedPRs    []*github.Issue
+	OpenedIssues    digestCount
+	ClosedIssues    digestCount
+	NewContributors digestCount
+	Releases        digestCount
+}
+
+// postWeeklyDigests posts the digests that are due. It's run periodically by the scheduler.
+func (p *Plugin) postWeeklyDigests() {
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions for weekly digests", "error", err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, repoSubs := range subs.Repositories {
+		for _, sub := range repoSubs {
+			if sub.Flags.WeeklyDigest == "" {
+				continue
+			}
+
+			schedule, err := parseWeeklySchedule(sub.Flags.WeeklyDigest)
+			if err != nil {
+				p.API.LogWarn("Invalid weekly digest schedule", "repo", sub.Repository, "channelID", sub.ChannelID, "error", err.Error())
+				continue
+			}
+
+			due := schedule.lastOccurrence(now)
+			if now.Sub(due) > weeklyDigestGracePeriod || p.getLastWeeklyDigest(sub) >= due.Unix() {
+				continue
+			}
+
+			if err := p.postWeeklyDigest(sub, due); err != nil {
+				p.API.LogWarn("Failed to post weekly digest", "repo", sub.Repository, "channelID", sub.ChannelID, "error", err.Error())
+			}
+		}
+	}
+}
+
+func (p *Plugin) postWeeklyDigest(sub *Subscription, to time.Time) error {
+	info, apiErr := p.getGitHubUserInfo(sub.CreatorID)
+	if apiErr != nil {
+		return errors.Wrap(apiErr, "failed to get subscription creator info")
+	}
+
+	githubClient := p.githubConnect(*info.Token)
+	digest := p.getWeeklyDigest(context.Background(), githubClient, sub.Repository, to.AddDate(0, 0, -7), to)
+
+	message, err := renderTemplate("weeklyDigest", digest)
+	if err != nil {
+		return errors.Wrap(err, "failed to render template")
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: sub.ChannelID,
+		Message:   message,
+		Type:      "custom_git_digest",
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		return errors.Wrap(appErr, "failed to create post")
+	}
+
+	if appErr := p.API.KVSet(weeklyDigestStoreKey(sub), []byte(strconv.FormatInt(to.Unix(), 10))); appErr != nil {
+		return errors.Wrap(appErr, "failed to store weekly digest time")
+	}
+
+	return nil
+}
+
+// getLastWeeklyDigest returns the unix time of the period end of the last digest posted for a subscription.
+func (p *Plugin) getLastWeeklyDigest(sub *Subscription) int64 {
+	value, appErr := p.API.KVGet(weeklyDigestStoreKey(sub))
+	if appErr != nil || value == nil {
+		return 0
+	}
+
+	last, _ := strconv.ParseInt(string(value), 10, 64)
+	return last
+}
+
+func weeklyDigestStoreKey(sub *Subscription) string {
+	return hashKey(weeklyDigestKey, sub.ChannelID, sub.Repository)
+}
+
+// getWeeklyDigest computes the digest of a repository or organization for the given period.
+// Sections that fail to be computed are marked as unavailable.
+func (p *Plugin) getWeeklyDigest(ctx context.Context, githubClient *github.Client, repository string, from, to time.Time) *weeklyDigest {
+	owner, repo := parseOwnerAndRepo(repository, p.getBaseURL())
+	scope := "repo:" + fullNameFromOwnerAndRepo(owner, repo)
+	if repo == "" {
+		scope = "org:" + owner
+	}
+	period := fmt.Sprintf("%s..%s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+
+	digest := &weeklyDigest{
+		Repository: strings.Trim(repository, "/"),
+		From:       from,
+		To:         to,
+	}
+
+	merged, _, err := githubClient.Search.Issues(ctx, fmt.Sprintf("%s is:pr is:merged merged:%s", scope, period), &github.SearchOptions{
+		Sort:        "comments",
+		Order:       "desc",
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to search merged pull requests for weekly digest", "repo", repository, "error", err.Error())
+	} else {
+		digest.MergedPRs = digestCount{Count: merged.GetTotal(), Available: true}
+		digest.TopMergedPRs = merged.Issues
+		if len(digest.TopMergedPRs) > weeklyDigestTopPRs {
+			digest.TopMergedPRs = digest.TopMergedPRs[:weeklyDigestTopPRs]
+		}
+		digest.NewContributors = p.countNewContributors(ctx, githubClient, scope, from, merged.Issues)
+	}
+
+	digest.OpenedIssues = p.countSearchResults(ctx, githubClient, fmt.Sprintf("%s is:issue created:%s", scope, period))
+	digest.ClosedIssues = p.countSearchResults(ctx, githubClient, fmt.Sprintf("%s is:issue is:closed closed:%s", scope, period))
+
+	if repo != "" {
+		digest.Releases = p.countReleases(ctx, githubClient, owner, repo, from, to)
+	}
+
+	return digest
+}
+
+func (p *Plugin) countSearchResults(ctx context.Context, githubClient *github.Client, query string) digestCount {
+	result, _, err := githubClient.Search.Issues(ctx, query, &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 1}})
+	if err != nil {
+		p.API.LogWarn("Failed to search for weekly digest", "query", query, "error", err.Error())
+		return digestCount{}
+	}
+
+	return digestCount{Count: result.GetTotal(), Available: true}
+}
+
+// countNewContributors counts the authors of the merged pull requests that had no pull request merged before.
+func (p *Plugin) countNewContributors(ctx context.Context, githubClient *github.Client, scope string, from time.Time, mergedPRs []*github.Issue) digestCount {
+	authors := []string{}
+	for _, pr := range mergedPRs {
+		login := pr.GetUser().GetLogin()
+		if login != "" && !containsValue(authors, login) {
+			authors = append(authors, login)
+		}
+	}
+	if len(authors) > weeklyDigestMaxContributors {
+		authors = authors[:weeklyDigestMaxContributors]
+	}
+
+	count := 0
+	for _, author := range authors {
+		previous := p.countSearchResults(ctx, githubClient, fmt.Sprintf("%s is:pr is:merged author:%s merged:<%s", scope, author, from.Format(time.RFC3339)))
+		if !previous.Available {
+			return digestCount{}
+		}
+
+		if previous.Count == 0 {
+			count++
+		}
+	}
+
+	return digestCount{Count: count, Available: true}
+}
+
+func (p *Plugin) countReleases(ctx context.Context, githubClient *github.Client, owner, repo string, from, to time.Time) digestCount {
+	releases, _, err := githubClient.Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		p.API.LogWarn("Failed to list releases for weekly digest", "repo", fullNameFromOwnerAndRepo(owner, repo), "error", err.Error())
+		return digestCount{}
+	}
+
+	count := 0
+	for _, release := range releases {
+		publishedAt := release.GetPublishedAt().Time
+		if !publishedAt.Before(from) && publishedAt.Before(to) {
+			count++
+		}
+	}
+
+	return digestCount{Count: count, Available: true}
+}
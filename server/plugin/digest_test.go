@@ -0,0 +1,153 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWeeklySchedule(t *testing.T) {
+	for value, expected := range map[string]*weeklySchedule{
+		"monday 09:00":  {Weekday: time.Monday, Hour: 9, Minute: 0},
+		"Sunday 23:59":  {Weekday: time.Sunday, Hour: 23, Minute: 59},
+		"FRIDAY 7:05":   {Weekday: time.Friday, Hour: 7, Minute: 5},
+		"monday":        nil,
+		"09:00":         nil,
+		"someday 09:00": nil,
+		"monday 24:00":  nil,
+		"monday 09:60":  nil,
+		"monday 0900":   nil,
+	} {
+		t.Run(value, func(t *testing.T) {
+			schedule, err := parseWeeklySchedule(value)
+			if expected == nil {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, expected, schedule)
+		})
+	}
+}
+
+func TestWeeklyScheduleLastOccurrence(t *testing.T) {
+	schedule := &weeklySchedule{Weekday: time.Monday, Hour: 9}
+
+	// 2020-09-14 is a Monday
+	for name, test := range map[string]struct {
+		now      time.Time
+		expected time.Time
+	}{
+		"right on time":        {now: time.Date(2020, 9, 14, 9, 0, 0, 0, time.UTC), expected: time.Date(2020, 9, 14, 9, 0, 0, 0, time.UTC)},
+		"later the same day":   {now: time.Date(2020, 9, 14, 10, 30, 0, 0, time.UTC), expected: time.Date(2020, 9, 14, 9, 0, 0, 0, time.UTC)},
+		"earlier the same day": {now: time.Date(2020, 9, 14, 8, 0, 0, 0, time.UTC), expected: time.Date(2020, 9, 7, 9, 0, 0, 0, time.UTC)},
+		"later in the week":    {now: time.Date(2020, 9, 17, 8, 0, 0, 0, time.UTC), expected: time.Date(2020, 9, 14, 9, 0, 0, 0, time.UTC)},
+		"on sunday":            {now: time.Date(2020, 9, 20, 12, 0, 0, 0, time.UTC), expected: time.Date(2020, 9, 14, 9, 0, 0, 0, time.UTC)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, schedule.lastOccurrence(test.now))
+		})
+	}
+}
+
+func TestSubscriptionFlagsWeeklyDigest(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(weeklyDigestFlag, "monday 09:00"))
+	assert.Equal(t, "--weekly-digest monday 09:00", flags.String())
+
+	flags.AddFlag(excludeOrgMemberFlag)
+	assert.Equal(t, "--exclude-org-member,--weekly-digest monday 09:00", flags.String())
+
+	assert.Error(t, flags.SetFlag(weeklyDigestFlag, "someday"))
+	assert.Equal(t, "monday 09:00", flags.WeeklyDigest)
+}
+
+func TestGetWeeklyDigest(t *testing.T) {
+	from := time.Date(2020, 9, 7, 9, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		switch query {
+		case "repo:owner/repo is:pr is:merged merged:2020-09-07T09:00:00Z..2020-09-14T09:00:00Z":
+			fmt.Fprint(w, `{"total_count": 2, "items": [
+				{"number": 1, "title": "First", "comments": 3, "user": {"login": "alice"}},
+				{"number": 2, "title": "Second", "comments": 1, "user": {"login": "bob"}}
+			]}`)
+		case "repo:owner/repo is:pr is:merged author:alice merged:<2020-09-07T09:00:00Z":
+			fmt.Fprint(w, `{"total_count": 4, "items": []}`)
+		case "repo:owner/repo is:pr is:merged author:bob merged:<2020-09-07T09:00:00Z":
+			fmt.Fprint(w, `{"total_count": 0, "items": []}`)
+		case "repo:owner/repo is:issue created:2020-09-07T09:00:00Z..2020-09-14T09:00:00Z":
+			fmt.Fprint(w, `{"total_count": 5, "items": []}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("/repos/owner/repo/releases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"published_at": "2020-09-08T10:00:00Z"},
+			{"published_at": "2020-09-01T10:00:00Z"}
+		]`)
+	})
+
+	client := newTestGitHubClient(t, mux)
+
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	p.SetAPI(api)
+
+	digest := p.getWeeklyDigest(context.Background(), client, "owner/repo", from, to)
+
+	assert.Equal(t, "owner/repo", digest.Repository)
+	assert.Equal(t, digestCount{Count: 2, Available: true}, digest.MergedPRs)
+	assert.Len(t, digest.TopMergedPRs, 2)
+	assert.Equal(t, digestCount{Count: 1, Available: true}, digest.NewContributors)
+	assert.Equal(t, digestCount{Count: 5, Available: true}, digest.OpenedIssues)
+	assert.Equal(t, digestCount{Available: false}, digest.ClosedIssues)
+	assert.Equal(t, digestCount{Count: 1, Available: true}, digest.Releases)
+}
+
+func TestWeeklyDigestTemplate(t *testing.T) {
+	digest := &weeklyDigest{
+		Repository: "owner/repo",
+		From:       time.Date(2020, 9, 7, 9, 0, 0, 0, time.UTC),
+		To:         time.Date(2020, 9, 14, 9, 0, 0, 0, time.UTC),
+		MergedPRs:  digestCount{Count: 1, Available: true},
+		TopMergedPRs: []*github.Issue{{
+			Number:   github.Int(1),
+			Title:    github.String("First"),
+			HTMLURL:  github.String("https://github.com/owner/repo/pull/1"),
+			Comments: github.Int(3),
+			User:     &github.User{Login: github.String("alice"), HTMLURL: github.String("https://github.com/alice")},
+		}},
+		OpenedIssues:    digestCount{Count: 5, Available: true},
+		NewContributors: digestCount{Count: 0, Available: true},
+	}
+
+	expected := `
+#### Weekly digest for owner/repo
+Sep 7 - Sep 14, 2020
+##### Merged pull requests: 1
+* [#1 First](https://github.com/owner/repo/pull/1) by [alice](https://github.com/alice) - 3 comments
+##### New issues: 5
+##### Closed issues: unavailable
+##### New contributors: 0
+##### Releases: unavailable
+`
+
+	actual, err := renderTemplate("weeklyDigest", digest)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
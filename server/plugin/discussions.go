@@ -0,0 +1,199 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+
+	"github.com/mattermost/mattermost-plugin-github/server/plugin/graphql"
+)
+
+// getDiscussionRepository looks up a repository to create discussions in, failing if it can't be
+// found or has discussions disabled.
+func (p *Plugin) getDiscussionRepository(ctx context.Context, client *graphql.Client, owner, repo string) (*graphql.DiscussionRepository, *APIErrorResponse) {
+	discussionRepo, err := client.GetDiscussionRepository(ctx, owner, repo)
+	if err == graphql.ErrRepositoryNotFound {
+		return nil, &APIErrorResponse{ID: apiErrorIDGitHubNotFound, Message: fmt.Sprintf("Couldn't find the repository %s/%s. Please check that you have access to it.", owner, repo), StatusCode: http.StatusNotFound}
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to get discussion categories", "repo", owner+"/"+repo, "error", err.Error())
+		return nil, newAPIError(err, "Failed to fetch discussion categories.")
+	}
+	if !discussionRepo.DiscussionsEnabled {
+		return nil, &APIErrorResponse{ID: "", Message: "Discussions are disabled on this repository.", StatusCode: http.StatusMethodNotAllowed}
+	}
+
+	return discussionRepo, nil
+}
+
+func (p *Plugin) getDiscussionCategories(w http.ResponseWriter, r *http.Request, userID string) {
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	owner, repo, err := parseRepo(r.URL.Query().Get("repo"))
+	if err != nil {
+		p.writeAPIError(w, &APIErrorResponse{Message: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	client := graphql.NewClient(p.githubConnect(*info.Token))
+	discussionRepo, apiErr := p.getDiscussionRepository(r.Context(), client, owner, repo)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	p.writeJSON(w, discussionRepo.Categories)
+}
+
+func (p *Plugin) createDiscussion(w http.ResponseWriter, r *http.Request, userID string) {
+	type DiscussionRequest struct {
+		Title      string `json:"title"`
+		Body       string `json:"body"`
+		Repo       string `json:"repo"`
+		CategoryID string `json:"category_id"`
+		PostID     string `json:"post_id"`
+		ChannelID  string `json:"channel_id"`
+	}
+
+	discussion := &DiscussionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&discussion); err != nil {
+		p.API.LogWarn("Error decoding JSON body", "error", err.Error())
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a JSON object.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if discussion.Title == "" {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a valid discussion title.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	owner, repo, err := parseRepo(discussion.Repo)
+	if err != nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a valid repo name.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if discussion.CategoryID == "" {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a discussion category.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if config := p.getConfiguration(); config.isIssueCreationRestrictedToOrg() && !isRepoInOrg(discussion.Repo, config.GitHubOrg) {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: fmt.Sprintf("Discussions can only be created in repositories of the %s organization.", strings.TrimSpace(config.GitHubOrg)), StatusCode: http.StatusForbidden})
+		return
+	}
+
+	if discussion.PostID == "" && discussion.ChannelID == "" {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide either a postID or a channelID", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	// Make sure user has a connected github account
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	mmMessage := ""
+	var post *model.Post
+	permalink := ""
+	if discussion.PostID != "" {
+		var appErr *model.AppError
+		post, appErr = p.API.GetPost(discussion.PostID)
+		if appErr != nil {
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to load post " + discussion.PostID, StatusCode: http.StatusInternalServerError})
+			return
+		}
+		if post == nil {
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to load post " + discussion.PostID + ": not found", StatusCode: http.StatusNotFound})
+			return
+		}
+
+		username, err := p.getUsername(post.UserId)
+		if err != nil {
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to get username", StatusCode: http.StatusInternalServerError})
+			return
+		}
+
+		permalink = p.getPermaLink(discussion.PostID)
+
+		mmMessage = fmt.Sprintf("_Discussion created from a [Mattermost message](%v) *by %s*._", permalink, username)
+	}
+
+	body := discussion.Body
+	if body != "" && mmMessage != "" {
+		mmMessage = "\n\n" + mmMessage
+	}
+	body += mmMessage
+
+	githubClient, err := p.getGithubClientFor(info, githubWrite)
+	if err != nil {
+		p.writeAPIError(w, newAPIError(err, "Failed to create the discussion."))
+		return
+	}
+
+	// Discussions can only be created with the GraphQL API
+	client := graphql.NewClient(githubClient)
+	discussionRepo, apiErr := p.getDiscussionRepository(r.Context(), client, owner, repo)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+	if discussionRepo.Category(discussion.CategoryID) == nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "The discussion category doesn't exist in this repository.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	result, err := client.CreateDiscussion(r.Context(), discussionRepo.ID, discussion.CategoryID, discussion.Title, body)
+	if err != nil {
+		p.API.LogWarn("Failed to create discussion", "error", err.Error())
+		if errs, ok := err.(graphql.Errors); ok && len(errs) > 0 && errs[0].Type == "FORBIDDEN" {
+			p.writeAPIError(w, &APIErrorResponse{ID: apiErrorIDGitHubForbidden, Message: fmt.Sprintf("You don't have permission to create discussions in %s.", discussion.Repo), StatusCode: http.StatusForbidden})
+			return
+		}
+		p.writeAPIError(w, newAPIError(err, "Failed to create the discussion."))
+		return
+	}
+
+	rootID := discussion.PostID
+	channelID := discussion.ChannelID
+	message := fmt.Sprintf("Created GitHub discussion [#%v](%v)", result.Number, result.URL)
+	if post != nil {
+		if post.RootId != "" {
+			rootID = post.RootId
+		}
+		channelID = post.ChannelId
+		message += fmt.Sprintf(" from a [message](%s)", permalink)
+	}
+
+	reply := &model.Post{
+		Message:   message,
+		ChannelId: channelID,
+		RootId:    rootID,
+		ParentId:  rootID,
+		UserId:    userID,
+	}
+
+	var appErr *model.AppError
+	if post != nil {
+		_, appErr = p.API.CreatePost(reply)
+	} else {
+		p.API.SendEphemeralPost(userID, reply)
+	}
+	if appErr != nil {
+		p.API.LogWarn("failed to create notification post", "error", appErr.Error())
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "failed to create notification post, postID: " + discussion.PostID + ", channelID: " + channelID, StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, result)
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestDiscussions(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	var created map[string]interface{}
+	discussionsEnabled := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/graphql", r.URL.Path)
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		switch {
+		case strings.Contains(req.Query, "createDiscussion"):
+			created = req.Variables
+			fmt.Fprint(w, `{"data": {"createDiscussion": {"discussion": {"number": 3, "url": "https://github.com/owner/repo/discussions/3"}}}}`)
+		case req.Variables["name"] == "repo":
+			fmt.Fprintf(w, `{"data": {"repository": {"id": "R_1", "hasDiscussionsEnabled": %t, "discussionCategories": {"nodes": [{"id": "DC_1", "name": "Q&A", "isAnswerable": true}]}}}}`, discussionsEnabled)
+		default:
+			fmt.Fprint(w, `{"data": {"repository": null}, "errors": [{"type": "NOT_FOUND", "message": "Could not resolve to a Repository"}]}`)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func() (*Plugin, *plugintest.API, *[]*model.Post) {
+		created = nil
+		discussionsEnabled = true
+
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		posts := &[]*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			*posts = append(*posts, post)
+			return post
+		}, nil)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		return p, api, posts
+	}
+
+	t.Run("categories", func(t *testing.T) {
+		p, _, _ := setup()
+
+		w := httptest.NewRecorder()
+		p.getDiscussionCategories(w, httptest.NewRequest(http.MethodGet, "/api/v1/discussion_categories?repo=owner/repo", nil), "userID")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[{"id": "DC_1", "name": "Q&A", "isAnswerable": true}]`, w.Body.String())
+
+		w = httptest.NewRecorder()
+		p.getDiscussionCategories(w, httptest.NewRequest(http.MethodGet, "/api/v1/discussion_categories?repo=owner/missing", nil), "userID")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("discussions disabled", func(t *testing.T) {
+		p, _, _ := setup()
+		discussionsEnabled = false
+
+		w := httptest.NewRecorder()
+		p.getDiscussionCategories(w, httptest.NewRequest(http.MethodGet, "/api/v1/discussion_categories?repo=owner/repo", nil), "userID")
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Contains(t, w.Body.String(), "Discussions are disabled on this repository.")
+	})
+
+	createDiscussion := func(p *Plugin, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		p.createDiscussion(w, httptest.NewRequest(http.MethodPost, "/api/v1/creatediscussion", bytes.NewBufferString(body)), "userID")
+		return w
+	}
+
+	t.Run("from a post", func(t *testing.T) {
+		p, api, posts := setup()
+		api.On("GetPost", "postID").Return(&model.Post{Id: "postID", ChannelId: "channelID", UserId: "authorID"}, nil)
+		api.On("KVGet", "authorID"+githubTokenKey).Return(nil, nil)
+		api.On("GetUser", "authorID").Return(&model.User{Username: "bob"}, nil)
+		api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: model.NewString("http://mattermost.example.com")}})
+
+		w := createDiscussion(p, `{"title": "How to?", "body": "Question", "repo": "owner/repo", "category_id": "DC_1", "post_id": "postID"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"number": 3, "url": "https://github.com/owner/repo/discussions/3"}`, w.Body.String())
+
+		assert.Equal(t, "R_1", created["repositoryId"])
+		assert.Equal(t, "DC_1", created["categoryId"])
+		assert.Equal(t, "How to?", created["title"])
+		assert.Contains(t, created["body"], "Question\n\n_Discussion created from a [Mattermost message](")
+		assert.Contains(t, created["body"], "*by `@bob`*._")
+
+		require.Len(t, *posts, 1)
+		assert.Equal(t, "channelID", (*posts)[0].ChannelId)
+		assert.Equal(t, "postID", (*posts)[0].RootId)
+		assert.Contains(t, (*posts)[0].Message, "Created GitHub discussion [#3](https://github.com/owner/repo/discussions/3) from a [message](")
+	})
+
+	t.Run("unknown category", func(t *testing.T) {
+		p, _, _ := setup()
+
+		w := createDiscussion(p, `{"title": "How to?", "repo": "owner/repo", "category_id": "DC_2", "channel_id": "channelID"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Nil(t, created)
+	})
+
+	t.Run("discussions disabled", func(t *testing.T) {
+		p, _, _ := setup()
+		discussionsEnabled = false
+
+		w := createDiscussion(p, `{"title": "How to?", "repo": "owner/repo", "category_id": "DC_1", "channel_id": "channelID"}`)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Nil(t, created)
+	})
+
+	t.Run("missing fields", func(t *testing.T) {
+		p, _, _ := setup()
+
+		assert.Equal(t, http.StatusBadRequest, createDiscussion(p, `{"repo": "owner/repo", "category_id": "DC_1", "channel_id": "channelID"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, createDiscussion(p, `{"title": "How to?", "repo": "owner/repo", "channel_id": "channelID"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, createDiscussion(p, `{"title": "How to?", "repo": "owner/repo", "category_id": "DC_1"}`).Code)
+	})
+}
@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	dmThreadKey = "_dmthread"
+	// dmThreadDateFormat is the format of the day of a daily activity thread, in the timezone of its user.
+	dmThreadDateFormat = "2006-01-02"
+)
+
+// dmThread is the root post of the daily thread the notifications of a user are posted in when they
+// consolidate their direct messages.
+type dmThread struct {
+	RootID string `json:"root_id"`
+	Date   string `json:"date"`
+}
+
+// timezoneFromOffset returns the timezone of a user from the offset sent by their browser, in minutes
+// behind UTC like getTimezoneOffset in JavaScript.
+func timezoneFromOffset(offset int) *time.Location {
+	return time.FixedZone("local", -60*offset)
+}
+
+// getNotificationSettings returns the settings and timezone of a user, without decrypting their token.
+// It returns nil if the user never connected their account.
+func (p *Plugin) getNotificationSettings(userID string) (*UserSettings, *time.Location) {
+	value, appErr := p.API.KVGet(userID + githubTokenKey)
+	if appErr != nil || value == nil {
+		return nil, nil
+	}
+
+	info := &GitHubUserInfo{}
+	if err := json.Unmarshal(value, info); err != nil || info.Settings == nil {
+		return nil, nil
+	}
+
+	return info.Settings, timezoneFromOffset(info.TimezoneOffset)
+}
+
+// CreateNotificationDMPost sends a personal notification to a user. Users consolidating their direct
+// messages get it as a reply in the thread of the day, which is started by the first notification of
+// the day. Urgent notifications, like mentions and review requests, are still posted on their own if
+// the user asked for it.
+func (p *Plugin) CreateNotificationDMPost(userID, message, postType string, urgent bool) {
+	settings, timezone := p.getNotificationSettings(userID)
+	if settings == nil || !settings.ConsolidatedDMs || (urgent && settings.UrgentDMsAsRootPosts) {
+		p.CreateBotDMPost(userID, message, postType)
+		return
+	}
+
+	channel, appErr := p.API.GetDirectChannel(userID, p.BotUserID)
+	if appErr != nil {
+		p.API.LogWarn("Couldn't get bot's DM channel", "userID", userID, "error", appErr.Error())
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: channel.Id,
+		Message:   message,
+		Type:      postType,
+	}
+
+	if err := p.postInDMThread(userID, post, time.Now().In(timezone)); err != nil {
+		p.API.LogWarn("Failed to post in the daily thread, posting on its own", "userID", userID, "error", err.Error())
+		post.RootId = ""
+		if _, appErr := p.API.CreatePost(post); appErr != nil {
+			p.API.LogWarn("Failed to create DM post", "userID", userID, "post", post, "error", appErr.Error())
+		}
+	}
+}
+
+// postInDMThread posts a notification as a reply in the thread of the day of a user. The first
+// notification of the day, or the first one after the root of the thread was deleted, starts a new
+// thread instead.
+func (p *Plugin) postInDMThread(userID string, post *model.Post, now time.Time) error {
+	key := userID + dmThreadKey
+	oldValue, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return errors.Wrap(appErr, "could not get the daily thread from KV store")
+	}
+
+	thread := &dmThread{}
+	if oldValue != nil {
+		if err := json.Unmarshal(oldValue, thread); err != nil {
+			return errors.Wrap(err, "could not unmarshal the daily thread")
+		}
+	}
+
+	date := now.Format(dmThreadDateFormat)
+	if thread.RootID != "" && thread.Date == date {
+		root, appErr := p.API.GetPost(thread.RootID)
+		if appErr == nil && root.DeleteAt == 0 {
+			post.RootId = root.Id
+			if _, appErr := p.API.CreatePost(post); appErr != nil {
+				return errors.Wrap(appErr, "could not reply in the daily thread")
+			}
+			return nil
+		}
+	}
+
+	root := post.Clone()
+	root.Message = "#### GitHub activity for " + now.Format("January 2") + "\n" + post.Message
+	created, appErr := p.API.CreatePost(root)
+	if appErr != nil {
+		return errors.Wrap(appErr, "could not start the daily thread")
+	}
+
+	newValue, err := json.Marshal(&dmThread{RootID: created.Id, Date: date})
+	if err != nil {
+		return errors.Wrap(err, "could not marshal the daily thread")
+	}
+
+	// If another notification started a thread meanwhile, this one is left as a post of its own
+	if _, appErr := p.API.KVCompareAndSet(key, oldValue, newValue); appErr != nil {
+		p.API.LogWarn("Failed to store the daily thread", "userID", userID, "error", appErr.Error())
+	}
+
+	return nil
+}
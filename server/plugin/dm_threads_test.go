@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimezoneFromOffset(t *testing.T) {
+	now := time.Date(2021, 3, 5, 23, 30, 0, 0, time.UTC)
+
+	// Browsers west of UTC have positive offsets
+	assert.Equal(t, "2021-03-05 18:30", now.In(timezoneFromOffset(300)).Format("2006-01-02 15:04"))
+	assert.Equal(t, "2021-03-06 01:30", now.In(timezoneFromOffset(-120)).Format("2006-01-02 15:04"))
+}
+
+func TestCreateNotificationDMPost(t *testing.T) {
+	setup := func(settings *UserSettings, timezoneOffset int) (*Plugin, *plugintest.API, map[string][]byte, *[]*model.Post) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		if settings != nil {
+			info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Settings: settings, TimezoneOffset: timezoneOffset})
+			require.NoError(t, err)
+			store["userID"+githubTokenKey] = info
+		}
+
+		posts := &[]*model.Post{}
+		api.On("GetDirectChannel", "userID", "botID").Return(&model.Channel{Id: "dmID"}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			created := post.Clone()
+			created.Id = model.NewId()
+			*posts = append(*posts, created)
+			return created
+		}, nil)
+		p.SetAPI(api)
+
+		return p, api, store, posts
+	}
+
+	getThread := func(t *testing.T, store map[string][]byte) *dmThread {
+		thread := &dmThread{}
+		require.NoError(t, json.Unmarshal(store["userID"+dmThreadKey], thread))
+		return thread
+	}
+
+	t.Run("not consolidated", func(t *testing.T) {
+		for name, settings := range map[string]*UserSettings{
+			"not connected":   nil,
+			"setting off":     {},
+			"urgent breakout": {ConsolidatedDMs: true, UrgentDMsAsRootPosts: true},
+		} {
+			t.Run(name, func(t *testing.T) {
+				p, _, store, posts := setup(settings, 0)
+
+				p.CreateNotificationDMPost("userID", "alice mentioned you", "custom_git_mention", true)
+				require.Len(t, *posts, 1)
+				assert.Equal(t, "alice mentioned you", (*posts)[0].Message)
+				assert.Equal(t, "dmID", (*posts)[0].ChannelId)
+				assert.Empty(t, (*posts)[0].RootId)
+				assert.Nil(t, store["userID"+dmThreadKey])
+			})
+		}
+	})
+
+	t.Run("thread of the day", func(t *testing.T) {
+		p, api, store, posts := setup(&UserSettings{ConsolidatedDMs: true, UrgentDMsAsRootPosts: true}, 0)
+		api.On("GetPost", mock.AnythingOfType("string")).Return(func(postID string) *model.Post {
+			return &model.Post{Id: postID}
+		}, nil)
+
+		p.CreateNotificationDMPost("userID", "bob commented on your pull request", "custom_git_author", false)
+		require.Len(t, *posts, 1)
+		root := (*posts)[0]
+		assert.Equal(t, "#### GitHub activity for "+time.Now().UTC().Format("January 2")+"\nbob commented on your pull request", root.Message)
+		assert.Equal(t, "custom_git_author", root.Type)
+		assert.Empty(t, root.RootId)
+		assert.Equal(t, &dmThread{RootID: root.Id, Date: time.Now().UTC().Format(dmThreadDateFormat)}, getThread(t, store))
+
+		p.CreateNotificationDMPost("userID", "bob approved your pull request", "custom_git_review", false)
+		require.Len(t, *posts, 2)
+		assert.Equal(t, "bob approved your pull request", (*posts)[1].Message)
+		assert.Equal(t, root.Id, (*posts)[1].RootId)
+
+		// Urgent notifications break out of the thread when asked for
+		p.CreateNotificationDMPost("userID", "bob requested your review", "custom_git_review_request", true)
+		require.Len(t, *posts, 3)
+		assert.Empty(t, (*posts)[2].RootId)
+		assert.Equal(t, root.Id, getThread(t, store).RootID)
+	})
+
+	t.Run("day rollover", func(t *testing.T) {
+		p, api, store, posts := setup(&UserSettings{ConsolidatedDMs: true}, 300)
+		api.On("GetPost", "yesterdayID").Return(&model.Post{Id: "yesterdayID"}, nil)
+		store["userID"+dmThreadKey] = []byte(`{"root_id": "yesterdayID", "date": "2021-03-04"}`)
+
+		// Past midnight in UTC, but still March 5 for the user
+		timezone := timezoneFromOffset(300)
+		require.NoError(t, p.postInDMThread("userID", &model.Post{Message: "first"}, time.Date(2021, 3, 6, 1, 0, 0, 0, time.UTC).In(timezone)))
+		require.Len(t, *posts, 1)
+		assert.Equal(t, "#### GitHub activity for March 5\nfirst", (*posts)[0].Message)
+		assert.Empty(t, (*posts)[0].RootId)
+		assert.Equal(t, &dmThread{RootID: (*posts)[0].Id, Date: "2021-03-05"}, getThread(t, store))
+
+		api.On("GetPost", (*posts)[0].Id).Return(&model.Post{Id: (*posts)[0].Id}, nil)
+		require.NoError(t, p.postInDMThread("userID", &model.Post{Message: "second"}, time.Date(2021, 3, 6, 4, 59, 0, 0, time.UTC).In(timezone)))
+		require.Len(t, *posts, 2)
+		assert.Equal(t, (*posts)[0].Id, (*posts)[1].RootId)
+
+		require.NoError(t, p.postInDMThread("userID", &model.Post{Message: "next day"}, time.Date(2021, 3, 6, 5, 0, 0, 0, time.UTC).In(timezone)))
+		require.Len(t, *posts, 3)
+		assert.Equal(t, "#### GitHub activity for March 6\nnext day", (*posts)[2].Message)
+		assert.Empty(t, (*posts)[2].RootId)
+		assert.Equal(t, "2021-03-06", getThread(t, store).Date)
+	})
+
+	t.Run("root post deleted", func(t *testing.T) {
+		for name, getPost := range map[string]func(api *plugintest.API){
+			"not found": func(api *plugintest.API) {
+				api.On("GetPost", "rootID").Return(nil, model.NewAppError("GetPost", "app.post.get.app_error", nil, "", 404))
+			},
+			"soft deleted": func(api *plugintest.API) {
+				api.On("GetPost", "rootID").Return(&model.Post{Id: "rootID", DeleteAt: 1}, nil)
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				p, api, store, posts := setup(&UserSettings{ConsolidatedDMs: true}, 0)
+				getPost(api)
+				now := time.Date(2021, 3, 5, 12, 0, 0, 0, time.UTC)
+				store["userID"+dmThreadKey] = []byte(`{"root_id": "rootID", "date": "2021-03-05"}`)
+
+				require.NoError(t, p.postInDMThread("userID", &model.Post{Message: "comment"}, now))
+				require.Len(t, *posts, 1)
+				assert.Equal(t, "#### GitHub activity for March 5\ncomment", (*posts)[0].Message)
+				assert.Empty(t, (*posts)[0].RootId)
+				assert.Equal(t, &dmThread{RootID: (*posts)[0].Id, Date: "2021-03-05"}, getThread(t, store))
+			})
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	gitHubWebBaseURL = "https://github.com/"
+	gitHubAPIBaseURL = "https://api.github.com/"
+
+	// enterpriseAPIPath is where GitHub Enterprise serves the REST API, under its base URL.
+	enterpriseAPIPath = "api/v3/"
+	// enterpriseUploadPath is where GitHub Enterprise serves the uploads API, under its upload URL.
+	enterpriseUploadPath = "api/uploads/"
+)
+
+// getWebBaseURL returns the base URL of the web interface of GitHub, ending with a slash. The base
+// URL of GitHub Enterprise may have a path prefix, e.g. https://git.example.com/github/, and may be
+// configured with or without a trailing slash, or even with the /api/v3 path of the REST API.
+func getWebBaseURL(enterpriseBaseURL string) string {
+	baseURL := strings.TrimSpace(enterpriseBaseURL)
+	if baseURL == "" {
+		return gitHubWebBaseURL
+	}
+
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	return strings.TrimSuffix(baseURL, enterpriseAPIPath)
+}
+
+// getAPIBaseURL returns the base URL of the REST API of GitHub, ending with a slash.
+func getAPIBaseURL(enterpriseBaseURL string) string {
+	webBaseURL := getWebBaseURL(enterpriseBaseURL)
+	if webBaseURL == gitHubWebBaseURL {
+		return gitHubAPIBaseURL
+	}
+
+	return webBaseURL + enterpriseAPIPath
+}
+
+// trimAPIBaseURL returns the path of a URL of the REST API relative to its base URL, e.g.
+// repos/owner/repo/issues/1. URLs of the API of another GitHub instance are recognized by the
+// /api/v3 path or the api host of GitHub Enterprise. False is returned for other URLs.
+func trimAPIBaseURL(apiURL, webBaseURL string) (string, bool) {
+	apiBaseURL := getAPIBaseURL(webBaseURL)
+	if strings.HasPrefix(apiURL, apiBaseURL) {
+		return strings.TrimPrefix(apiURL, apiBaseURL), true
+	}
+
+	if index := strings.Index(apiURL, "/"+enterpriseAPIPath); index >= 0 {
+		return apiURL[index+len(enterpriseAPIPath)+1:], true
+	}
+
+	u, err := url.Parse(apiURL)
+	if err != nil || !strings.HasPrefix(u.Host, "api.") {
+		return "", false
+	}
+
+	return strings.TrimPrefix(u.Path, "/"), true
+}
+
+// enterpriseWebURL returns the URL of the web interface of GitHub Enterprise without a trailing
+// slash, as the webapp links to it, or an empty string when GitHub.com is used.
+func (c *Configuration) enterpriseWebURL() string {
+	if strings.TrimSpace(c.EnterpriseBaseURL) == "" {
+		return ""
+	}
+
+	return strings.TrimSuffix(getWebBaseURL(c.EnterpriseBaseURL), "/")
+}
+
+// enterpriseURLWarnings returns what looks wrong with the GitHub Enterprise URLs of the
+// configuration. GitHub.com is used unless both are set.
+func (c *Configuration) enterpriseURLWarnings() []string {
+	baseURL, uploadURL := strings.TrimSpace(c.EnterpriseBaseURL), strings.TrimSpace(c.EnterpriseUploadURL)
+	if baseURL == "" && uploadURL == "" {
+		return nil
+	}
+	if baseURL == "" || uploadURL == "" {
+		return []string{"Both the GitHub Enterprise Base URL and Upload URL must be set to use GitHub Enterprise. GitHub.com is used instead."}
+	}
+
+	warnings := []string{}
+	base, baseErr := url.Parse(baseURL)
+	if baseErr != nil || base.Scheme == "" || base.Host == "" {
+		warnings = append(warnings, "The GitHub Enterprise Base URL must be an absolute URL, like https://github.example.com/.")
+	}
+	upload, uploadErr := url.Parse(uploadURL)
+	if uploadErr != nil || upload.Scheme == "" || upload.Host == "" {
+		warnings = append(warnings, "The GitHub Enterprise Upload URL must be an absolute URL, like https://github.example.com/.")
+	}
+	if len(warnings) > 0 {
+		return warnings
+	}
+
+	basePath, uploadPath := strings.TrimSuffix(base.Path, "/")+"/", strings.TrimSuffix(upload.Path, "/")+"/"
+	if strings.HasSuffix(basePath, "/"+enterpriseAPIPath) {
+		warnings = append(warnings, "The GitHub Enterprise Base URL should be the URL of the web interface, without /api/v3.")
+	}
+	uploadPath = strings.TrimSuffix(strings.TrimSuffix(uploadPath, enterpriseUploadPath), enterpriseAPIPath)
+	if base.Host == upload.Host && strings.TrimSuffix(basePath, enterpriseAPIPath) != uploadPath {
+		warnings = append(warnings, "The GitHub Enterprise Base URL and Upload URL are on the same host but have different paths. Both should usually include the path GitHub Enterprise is served under.")
+	}
+
+	return warnings
+}
@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestGetWebAndAPIBaseURL(t *testing.T) {
+	for _, tc := range []struct {
+		EnterpriseBaseURL string
+		WebBaseURL        string
+		APIBaseURL        string
+	}{
+		{EnterpriseBaseURL: "", WebBaseURL: "https://github.com/", APIBaseURL: "https://api.github.com/"},
+		{EnterpriseBaseURL: "https://github.example.com/", WebBaseURL: "https://github.example.com/", APIBaseURL: "https://github.example.com/api/v3/"},
+		{EnterpriseBaseURL: "https://github.example.com", WebBaseURL: "https://github.example.com/", APIBaseURL: "https://github.example.com/api/v3/"},
+		{EnterpriseBaseURL: "https://git.corp.example.com/github/", WebBaseURL: "https://git.corp.example.com/github/", APIBaseURL: "https://git.corp.example.com/github/api/v3/"},
+		{EnterpriseBaseURL: " https://git.corp.example.com/github", WebBaseURL: "https://git.corp.example.com/github/", APIBaseURL: "https://git.corp.example.com/github/api/v3/"},
+		{EnterpriseBaseURL: "https://git.corp.example.com/github/api/v3", WebBaseURL: "https://git.corp.example.com/github/", APIBaseURL: "https://git.corp.example.com/github/api/v3/"},
+	} {
+		assert.Equal(t, tc.WebBaseURL, getWebBaseURL(tc.EnterpriseBaseURL), tc.EnterpriseBaseURL)
+		assert.Equal(t, tc.APIBaseURL, getAPIBaseURL(tc.EnterpriseBaseURL), tc.EnterpriseBaseURL)
+	}
+}
+
+func TestConvertAPIURLsToWebURLs(t *testing.T) {
+	hosts := []struct {
+		Name              string
+		EnterpriseBaseURL string
+		APIBaseURL        string
+		WebBaseURL        string
+	}{
+		{Name: "github.com", EnterpriseBaseURL: "", APIBaseURL: "https://api.github.com/", WebBaseURL: "https://github.com/"},
+		{Name: "enterprise", EnterpriseBaseURL: "https://github.example.com", APIBaseURL: "https://github.example.com/api/v3/", WebBaseURL: "https://github.example.com/"},
+		{Name: "enterprise with path prefix", EnterpriseBaseURL: "https://git.corp.example.com/github/", APIBaseURL: "https://git.corp.example.com/github/api/v3/", WebBaseURL: "https://git.corp.example.com/github/"},
+		// The hosts of the API and of the web interface don't differ by an api. prefix here
+		{Name: "enterprise on an api host", EnterpriseBaseURL: "https://api.corp.example.com/github/", APIBaseURL: "https://api.corp.example.com/github/api/v3/", WebBaseURL: "https://api.corp.example.com/github/"},
+	}
+
+	paths := []struct {
+		Name     string
+		APIPath  string
+		IssueNum string
+		WebPath  string
+	}{
+		{Name: "issue", APIPath: "repos/owner/repo/issues/123", WebPath: "owner/repo/issues/123"},
+		{Name: "pull request", APIPath: "repos/owner/repo/pulls/123", WebPath: "owner/repo/pull/123"},
+		{Name: "commit", APIPath: "repos/owner/repo/commits/cc6c385d", WebPath: "owner/repo/commit/cc6c385d"},
+		{Name: "issue comment", APIPath: "repos/owner/repo/issues/comments/655139214", IssueNum: "4", WebPath: "owner/repo/issues/4#issuecomment-655139214"},
+		{Name: "review comment", APIPath: "repos/owner/repo/pulls/comments/655139214", IssueNum: "5", WebPath: "owner/repo/pull/5#discussion_r655139214"},
+		{Name: "vulnerability alert", APIPath: "repos/owner/repo/dependabot/alerts/3", WebPath: "owner/repo/security/dependabot/3"},
+		{Name: "repository", APIPath: "repos/owner/repos", WebPath: "owner/repos"},
+	}
+
+	for _, host := range hosts {
+		for _, path := range paths {
+			t.Run(host.Name+"/"+path.Name, func(t *testing.T) {
+				baseURL := getWebBaseURL(host.EnterpriseBaseURL)
+				assert.Equal(t, host.WebBaseURL+path.WebPath, fixGithubNotificationSubjectURL(baseURL, host.APIBaseURL+path.APIPath, path.IssueNum))
+			})
+		}
+	}
+
+	// URLs of the web interface are left as is
+	assert.Equal(t, "https://git.corp.example.com/github/owner/repo/issues/1", fixGithubNotificationSubjectURL("https://git.corp.example.com/github/", "https://git.corp.example.com/github/owner/repo/issues/1", ""))
+	assert.Equal(t, "", fixGithubNotificationSubjectURL("https://github.com/", "", ""))
+}
+
+func TestToDoNotificationURLWithPathPrefix(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EnterpriseBaseURL: "https://git.corp.example.com/github", EnterpriseUploadURL: "https://git.corp.example.com/github"})
+
+	// The client requests the API under the path prefix
+	client, err := GetGitHubClient(oauth2.Token{AccessToken: "token"}, p.getConfiguration())
+	require.NoError(t, err)
+	assert.Equal(t, "https://git.corp.example.com/github/api/v3/", client.BaseURL.String())
+
+	baseURL := p.getBaseURL()
+	assert.Equal(t, "https://git.corp.example.com/github/", baseURL)
+	assert.Equal(t, "* [owner/repo](https://git.corp.example.com/github/owner/repo) [Fix login](https://git.corp.example.com/github/owner/repo/pull/1)\n",
+		getToDoDisplayText(baseURL, "Fix login", fixGithubNotificationSubjectURL(baseURL, "https://git.corp.example.com/github/api/v3/repos/owner/repo/pulls/1", "1"), ""))
+
+	assert.Equal(t, "https://git.corp.example.com/github", p.getConfiguration().enterpriseWebURL())
+	assert.Empty(t, (&Configuration{}).enterpriseWebURL())
+}
+
+func TestEnterpriseURLWarnings(t *testing.T) {
+	for _, tc := range []struct {
+		Name      string
+		BaseURL   string
+		UploadURL string
+		Warnings  []string
+	}{
+		{Name: "github.com"},
+		{Name: "consistent", BaseURL: "https://github.example.com/", UploadURL: "https://github.example.com/", Warnings: []string{}},
+		{Name: "consistent with path prefix", BaseURL: "https://git.corp.example.com/github", UploadURL: "https://git.corp.example.com/github/", Warnings: []string{}},
+		{Name: "upload API path", BaseURL: "https://github.example.com/", UploadURL: "https://github.example.com/api/uploads/", Warnings: []string{}},
+		{Name: "uploads host", BaseURL: "https://github.example.com/", UploadURL: "https://uploads.github.example.com/", Warnings: []string{}},
+		{
+			Name:     "missing upload URL",
+			BaseURL:  "https://github.example.com/",
+			Warnings: []string{"Both the GitHub Enterprise Base URL and Upload URL must be set to use GitHub Enterprise. GitHub.com is used instead."},
+		},
+		{
+			Name:      "relative URL",
+			BaseURL:   "github.example.com",
+			UploadURL: "https://github.example.com/",
+			Warnings:  []string{"The GitHub Enterprise Base URL must be an absolute URL, like https://github.example.com/."},
+		},
+		{
+			Name:      "API path",
+			BaseURL:   "https://git.corp.example.com/github/api/v3/",
+			UploadURL: "https://git.corp.example.com/github/",
+			Warnings:  []string{"The GitHub Enterprise Base URL should be the URL of the web interface, without /api/v3."},
+		},
+		{
+			Name:      "missing path prefix",
+			BaseURL:   "https://git.corp.example.com/github/",
+			UploadURL: "https://git.corp.example.com/",
+			Warnings:  []string{"The GitHub Enterprise Base URL and Upload URL are on the same host but have different paths. Both should usually include the path GitHub Enterprise is served under."},
+		},
+	} {
+		t.Run(tc.Name, func(t *testing.T) {
+			config := &Configuration{EnterpriseBaseURL: tc.BaseURL, EnterpriseUploadURL: tc.UploadURL}
+			assert.Equal(t, tc.Warnings, config.enterpriseURLWarnings())
+		})
+	}
+}
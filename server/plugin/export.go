@@ -0,0 +1,243 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// Props attached to the posts of subscriptions, describing the GitHub event they notify about.
+const (
+	postPropRepository = "gh_repo"
+	postPropObjectType = "gh_object_type"
+	postPropObjectID   = "gh_object_id"
+	postPropEventType  = "gh_event"
+	// postPropReplayed marks the posts of events replayed by a system admin.
+	postPropReplayed = "gh_replayed"
+)
+
+const (
+	objectTypePullRequest = "pull_request"
+	objectTypeIssue       = "issue"
+	objectTypeRef         = "ref"
+	objectTypeRepository  = "repository"
+
+	// exportEventsMaxRows caps the number of rows of an export.
+	exportEventsMaxRows  = 5000
+	exportEventsPageSize = 200
+)
+
+// eventTypesByPostType maps the types of subscription posts to the event they notify about, for
+// posts created before props were attached.
+var eventTypesByPostType = map[string]string{
+	"custom_git_pr":                  "pull_request",
+	"custom_git_issue":               "issues",
+	"custom_git_push":                "push",
+	"custom_git_create":              "create",
+	"custom_git_delete":              "delete",
+	"custom_git_comment":             "issue_comment",
+	"custom_git_pull_review":         "pull_request_review",
+	"custom_git_pull_review_comment": "pull_request_review_comment",
+	"custom_git_review_thread":       "pull_request_review_thread",
+	"custom_git_fork":                "fork",
+	"custom_git_star":                "star",
+}
+
+var (
+	// objectReferenceRegex matches references such as owner/repo#123.
+	objectReferenceRegex = regexp.MustCompile(`([\w.-]+/[\w.-]+)#(\d+)`)
+	// objectURLRegex matches links to pull requests and issues.
+	objectURLRegex = regexp.MustCompile(`https?://[^\s)]+/([\w.-]+/[\w.-]+)/(pull|issues)/(\d+)`)
+	// repositoryLinkRegex matches links to repositories, optionally followed by a ref, e.g. [\[owner/repo:main\]].
+	repositoryLinkRegex = regexp.MustCompile(`\[\\?\[([\w.-]+/[\w.-]+)(?::[^\]\\]*)?\\?\]\]`)
+)
+
+// eventPostProps returns the props describing the event a subscription post notifies about.
+func eventPostProps(repository, objectType, objectID, eventType string) model.StringInterface {
+	return model.StringInterface{
+		postPropRepository: repository,
+		postPropObjectType: objectType,
+		postPropObjectID:   objectID,
+		postPropEventType:  eventType,
+	}
+}
+
+// issueObjectType returns the object type of an issue, which may be a pull request.
+func issueObjectType(issue *github.Issue) string {
+	if issue.IsPullRequest() {
+		return objectTypePullRequest
+	}
+
+	return objectTypeIssue
+}
+
+// exportedEvent is a row of an export of the GitHub notifications of a channel.
+type exportedEvent struct {
+	CreatedAt  time.Time
+	Repository string
+	ObjectType string
+	ObjectID   string
+	EventType  string
+	PostID     string
+}
+
+// extractExportedEvent reads the event a post notifies about from its props. The event of posts
+// without props is parsed from their message on a best-effort basis.
+func extractExportedEvent(post *model.Post) *exportedEvent {
+	event := &exportedEvent{
+		CreatedAt: time.Unix(0, post.CreateAt*int64(time.Millisecond)).UTC(),
+		PostID:    post.Id,
+	}
+
+	if repository, ok := post.GetProp(postPropRepository).(string); ok {
+		event.Repository = repository
+		event.ObjectType, _ = post.GetProp(postPropObjectType).(string)
+		event.ObjectID, _ = post.GetProp(postPropObjectID).(string)
+		event.EventType, _ = post.GetProp(postPropEventType).(string)
+		return event
+	}
+
+	event.EventType = eventTypesByPostType[post.Type]
+
+	if match := objectURLRegex.FindStringSubmatch(post.Message); match != nil {
+		event.Repository = match[1]
+		event.ObjectType = objectTypeIssue
+		if match[2] == "pull" {
+			event.ObjectType = objectTypePullRequest
+		}
+		event.ObjectID = match[3]
+		return event
+	}
+
+	if match := objectReferenceRegex.FindStringSubmatch(post.Message); match != nil {
+		event.Repository = match[1]
+		event.ObjectID = match[2]
+		return event
+	}
+
+	if match := repositoryLinkRegex.FindStringSubmatch(post.Message); match != nil {
+		event.Repository = match[1]
+	}
+
+	return event
+}
+
+// getExportedEvents returns the events of the posts created by the bot in a channel since the given
+// time, oldest first. At most maxRows events are returned, the latest ones, the second return value
+// being true if some were left out.
+func (p *Plugin) getExportedEvents(channelID string, since time.Time, maxRows int) ([]*exportedEvent, bool, error) {
+	sinceMillis := since.UnixNano() / int64(time.Millisecond)
+
+	// Posts are listed newest first
+	posts := []*model.Post{}
+	truncated := false
+	for page := 0; ; page++ {
+		postList, appErr := p.API.GetPostsForChannel(channelID, page, exportEventsPageSize)
+		if appErr != nil {
+			return nil, false, errors.Wrap(appErr, "could not get posts")
+		}
+
+		reachedSince := false
+		for _, postID := range postList.Order {
+			post := postList.Posts[postID]
+			if post.CreateAt < sinceMillis {
+				reachedSince = true
+				break
+			}
+
+			if post.UserId != p.BotUserID || post.DeleteAt != 0 || eventTypesByPostType[post.Type] == "" {
+				continue
+			}
+
+			if len(posts) == maxRows {
+				truncated = true
+				break
+			}
+			posts = append(posts, post)
+		}
+
+		if reachedSince || truncated || len(postList.Order) < exportEventsPageSize {
+			break
+		}
+	}
+
+	events := make([]*exportedEvent, 0, len(posts))
+	for i := len(posts) - 1; i >= 0; i-- {
+		events = append(events, extractExportedEvent(posts[i]))
+	}
+
+	return events, truncated, nil
+}
+
+// generateEventsCSV writes events as CSV, with a header row.
+func generateEventsCSV(events []*exportedEvent) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write([]string{"created_at", "repository", "object_type", "object_id", "event_type", "post_id"}); err != nil {
+		return nil, err
+	}
+
+	for _, event := range events {
+		if err := writer.Write([]string{
+			event.CreatedAt.Format(time.RFC3339),
+			event.Repository,
+			event.ObjectType,
+			event.ObjectID,
+			event.EventType,
+			event.PostID,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// exportChannelEvents uploads a CSV of the GitHub notifications posted in a channel over the last
+// days, and posts it in the channel.
+func (p *Plugin) exportChannelEvents(channelID string, days int, now time.Time) error {
+	events, truncated, err := p.getExportedEvents(channelID, now.AddDate(0, 0, -days), exportEventsMaxRows)
+	if err != nil {
+		return err
+	}
+
+	data, err := generateEventsCSV(events)
+	if err != nil {
+		return errors.Wrap(err, "could not generate CSV")
+	}
+
+	filename := fmt.Sprintf("github-events-%s.csv", now.Format("2006-01-02"))
+	fileInfo, appErr := p.API.UploadFile(data, channelID, filename)
+	if appErr != nil {
+		return errors.Wrap(appErr, "could not upload export")
+	}
+
+	message := fmt.Sprintf("Export of the %d GitHub notifications posted in this channel in the last %d days.", len(events), days)
+	if truncated {
+		message += fmt.Sprintf(" The export was truncated to the latest %d notifications.", exportEventsMaxRows)
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: channelID,
+		Message:   message,
+		FileIds:   []string{fileInfo.Id},
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		return errors.Wrap(appErr, "could not create post")
+	}
+
+	return nil
+}
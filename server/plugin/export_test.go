@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractExportedEvent(t *testing.T) {
+	createAt := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		post     *model.Post
+		expected *exportedEvent
+	}{
+		"props": {
+			post: &model.Post{
+				Type:  "custom_git_pr",
+				Props: eventPostProps("owner/repo", objectTypePullRequest, "42", "pull_request.opened"),
+			},
+			expected: &exportedEvent{Repository: "owner/repo", ObjectType: objectTypePullRequest, ObjectID: "42", EventType: "pull_request.opened"},
+		},
+		"pull request link": {
+			post: &model.Post{
+				Type:    "custom_git_comment",
+				Message: "[\\[owner/repo\\]](https://github.com/owner/repo) New comment by [user](https://github.com/user) on [#42 Fix](https://github.com/owner/repo/pull/42#issuecomment-1):",
+			},
+			expected: &exportedEvent{Repository: "owner/repo", ObjectType: objectTypePullRequest, ObjectID: "42", EventType: "issue_comment"},
+		},
+		"issue link": {
+			post: &model.Post{
+				Type:    "custom_git_issue",
+				Message: "#### Bug\n##### [owner/repo#7](https://github.example.com/owner/repo/issues/7)",
+			},
+			expected: &exportedEvent{Repository: "owner/repo", ObjectType: objectTypeIssue, ObjectID: "7", EventType: "issues"},
+		},
+		"reference": {
+			post: &model.Post{
+				Type:    "custom_git_pr",
+				Message: "owner/repo#3 was merged",
+			},
+			expected: &exportedEvent{Repository: "owner/repo", ObjectID: "3", EventType: "pull_request"},
+		},
+		"repository link": {
+			post: &model.Post{
+				Type:    "custom_git_push",
+				Message: "[user](https://github.com/user) pushed [1 new commit](https://github.com/owner/repo/compare/a...b) to [\\[owner/repo:main\\]](https://github.com/owner/repo/tree/main):",
+			},
+			expected: &exportedEvent{Repository: "owner/repo", EventType: "push"},
+		},
+		"created branch": {
+			post: &model.Post{
+				Type:    "custom_git_create",
+				Message: "[\\[owner/repo\\]](https://github.com/owner/repo) New branch [feature](https://github.com/owner/repo/tree/feature) was created by [user](https://github.com/user).",
+			},
+			expected: &exportedEvent{Repository: "owner/repo", EventType: "create"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tc.post.Id = "postID"
+			tc.post.CreateAt = createAt.UnixNano() / int64(time.Millisecond)
+			tc.expected.PostID = "postID"
+			tc.expected.CreatedAt = createAt
+
+			assert.Equal(t, tc.expected, extractExportedEvent(tc.post))
+		})
+	}
+}
+
+func TestGenerateEventsCSV(t *testing.T) {
+	data, err := generateEventsCSV([]*exportedEvent{
+		{
+			CreatedAt:  time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC),
+			Repository: "owner/repo",
+			ObjectType: objectTypePullRequest,
+			ObjectID:   "42",
+			EventType:  "pull_request.opened",
+			PostID:     "postID",
+		},
+		{
+			CreatedAt: time.Date(2020, 9, 2, 10, 0, 0, 0, time.UTC),
+			EventType: "push, maybe",
+			PostID:    "postID2",
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "created_at,repository,object_type,object_id,event_type,post_id\n"+
+		"2020-09-01T10:00:00Z,owner/repo,pull_request,42,pull_request.opened,postID\n"+
+		"2020-09-02T10:00:00Z,,,,\"push, maybe\",postID2\n", string(data))
+}
+
+func TestGetExportedEvents(t *testing.T) {
+	now := time.Now()
+	millis := func(tm time.Time) int64 {
+		return tm.UnixNano() / int64(time.Millisecond)
+	}
+
+	postList := &model.PostList{
+		Order: []string{"new", "user", "mid", "old", "expired"},
+		Posts: map[string]*model.Post{
+			"new":     {Id: "new", UserId: "botID", Type: "custom_git_pr", CreateAt: millis(now.Add(-time.Hour))},
+			"user":    {Id: "user", UserId: "userID", CreateAt: millis(now.Add(-2 * time.Hour))},
+			"mid":     {Id: "mid", UserId: "botID", Type: "custom_git_issue", CreateAt: millis(now.Add(-3 * time.Hour))},
+			"old":     {Id: "old", UserId: "botID", Type: "custom_git_push", CreateAt: millis(now.Add(-4 * time.Hour))},
+			"expired": {Id: "expired", UserId: "botID", Type: "custom_git_push", CreateAt: millis(now.Add(-48 * time.Hour))},
+		},
+	}
+
+	setupPlugin := func(t *testing.T) (*Plugin, *plugintest.API) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		api.On("GetPostsForChannel", "channelID", 0, exportEventsPageSize).Return(postList, nil).Once()
+		p.SetAPI(api)
+		return p, api
+	}
+
+	t.Run("posts of the bot in the window", func(t *testing.T) {
+		p, api := setupPlugin(t)
+		defer api.AssertExpectations(t)
+
+		events, truncated, err := p.getExportedEvents("channelID", now.Add(-24*time.Hour), 10)
+		require.NoError(t, err)
+		assert.False(t, truncated)
+		require.Len(t, events, 3)
+		assert.Equal(t, "old", events[0].PostID)
+		assert.Equal(t, "mid", events[1].PostID)
+		assert.Equal(t, "new", events[2].PostID)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		p, api := setupPlugin(t)
+		defer api.AssertExpectations(t)
+
+		events, truncated, err := p.getExportedEvents("channelID", now.Add(-24*time.Hour), 2)
+		require.NoError(t, err)
+		assert.True(t, truncated)
+		require.Len(t, events, 2)
+		assert.Equal(t, "mid", events[0].PostID)
+		assert.Equal(t, "new", events[1].PostID)
+	})
+}
+
+func TestExportChannelEvents(t *testing.T) {
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	api := &plugintest.API{}
+	api.On("GetPostsForChannel", "channelID", 0, exportEventsPageSize).Return(&model.PostList{}, nil).Once()
+	api.On("UploadFile", []byte("created_at,repository,object_type,object_id,event_type,post_id\n"), "channelID", "github-events-2020-09-30.csv").
+		Return(&model.FileInfo{Id: "fileID"}, nil).Once()
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "channelID" &&
+			post.UserId == "botID" &&
+			len(post.FileIds) == 1 && post.FileIds[0] == "fileID" &&
+			post.Message == "Export of the 0 GitHub notifications posted in this channel in the last 30 days."
+	})).Return(&model.Post{}, nil).Once()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	require.NoError(t, p.exportChannelEvents("channelID", 30, time.Date(2020, 9, 30, 12, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,141 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+)
+
+// gistAnchorRegex splits the anchor of a gist permalink into the file anchor and the lines,
+// e.g. file-main-go-L3-L5.
+var gistAnchorRegex = regexp.MustCompile(`^(file-[\w-]+?)-(L\d+(?:-L\d+)?)$`)
+
+// gistFileAnchorRegex matches the characters GitHub replaces in the anchors of gist files.
+var gistFileAnchorRegex = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// gistReplacement holds necessary info to replace gist permalinks
+// in messages with a code preview block.
+type gistReplacement struct {
+	index    int    // index of the permalink in the string
+	word     string // the permalink
+	user     string
+	id       string
+	revision string
+	file     string // the anchor of the file, e.g. file-main-go
+	line     string
+}
+
+// gistFileAnchor returns the anchor GitHub gives to a file of a gist, e.g. file-main-go for main.go.
+func gistFileAnchor(filename string) string {
+	return "file-" + gistFileAnchorRegex.ReplaceAllString(strings.ToLower(filename), "-")
+}
+
+// getGistReplacements returns the gist permalink replacements that needs to be performed
+// on a message. The returned slice is sorted by the index in ascending order.
+func (p *Plugin) getGistReplacements(msg string) []gistReplacement {
+	matches := p.githubGistRegex.FindAllStringSubmatchIndex(msg, -1)
+	var replacements []gistReplacement
+	for i, m := range matches {
+		// have a limit on the number of replacements to do
+		if i > maxPermalinkReplacements {
+			break
+		}
+		index := m[0]
+		// ignore if the word is inside a link
+		if isInsideLink(msg, index) {
+			continue
+		}
+
+		anchor := gistAnchorRegex.FindStringSubmatch(msg[m[8]:m[9]])
+		if anchor == nil {
+			continue
+		}
+
+		r := gistReplacement{
+			index: index,
+			word:  msg[m[0]:m[1]],
+			user:  msg[m[2]:m[3]],
+			id:    msg[m[4]:m[5]],
+			file:  anchor[1],
+			line:  anchor[2],
+		}
+		if m[6] != -1 {
+			r.revision = msg[m[6]:m[7]]
+		}
+		replacements = append(replacements, r)
+	}
+	return replacements
+}
+
+// makeGistReplacements perform the given gist replacements on the msg and returns the new msg.
+// Secret gists are only previewed if code previews are enabled for private repositories. Gists
+// that can't be fetched with the client of the poster are left untouched.
+func (p *Plugin) makeGistReplacements(msg string, replacements []gistReplacement, ghClient *github.Client) string {
+	config := p.getConfiguration()
+
+	// iterating the slice in reverse to preserve the replacement indices.
+	for i := len(replacements) - 1; i >= 0; i-- {
+		r := replacements[i]
+
+		ctx, cancel := context.WithTimeout(context.Background(), permalinkReqTimeout)
+		defer cancel()
+
+		var gist *github.Gist
+		var err error
+		if r.revision != "" {
+			gist, _, err = ghClient.Gists.GetRevision(ctx, r.id, r.revision)
+		} else {
+			gist, _, err = ghClient.Gists.Get(ctx, r.id)
+		}
+		if err != nil {
+			p.API.LogDebug("Error while fetching gist", "error", err.Error(), "id", r.id)
+			continue
+		}
+
+		if !gist.GetPublic() && config.EnableCodePreview != "privateAndPublic" {
+			continue
+		}
+
+		var file *github.GistFile
+		for _, f := range gist.Files {
+			if gistFileAnchor(f.GetFilename()) == r.file {
+				f := f
+				file = &f
+				break
+			}
+		}
+		if file == nil {
+			p.API.LogWarn("Gist permalink points to an unknown file", "id", r.id, "file", r.file)
+			continue
+		}
+
+		// get the required lines.
+		start, end := getLineNumbers(r.line)
+		// bad anchor tag, ignore.
+		if start == -1 || end == -1 {
+			continue
+		}
+		isTruncated := false
+		if end-start > maxPreviewLines {
+			end = start + maxPreviewLines
+			isTruncated = true
+		}
+		lines, err := filterLines(file.GetContent(), start, end)
+		if err != nil {
+			p.API.LogError("Error while filtering lines", "error", err.Error(), "id", r.id, "file", file.GetFilename())
+		}
+		if lines == "" {
+			p.API.LogError("Line numbers out of range. Skipping.", "id", r.id, "file", file.GetFilename(), "start", start, "end", end)
+			continue
+		}
+		title := fmt.Sprintf("%s/%s (gist)", r.user, file.GetFilename())
+		final := getCodeBlockMarkdown(title, file.GetFilename(), r.word, lines, isTruncated)
+
+		// replace word in msg starting from r.index only once.
+		msg = msg[:r.index] + strings.Replace(msg[r.index:], r.word, final, 1)
+	}
+	return msg
+}
@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGistFileAnchor(t *testing.T) {
+	assert.Equal(t, "file-main-go", gistFileAnchor("main.go"))
+	assert.Equal(t, "file-hello_world-rb", gistFileAnchor("hello_world.rb"))
+	assert.Equal(t, "file-readme-md", gistFileAnchor("README.md"))
+}
+
+func TestGetGistReplacements(t *testing.T) {
+	p := NewPlugin()
+
+	for name, tc := range map[string]struct {
+		input        string
+		replacements []gistReplacement
+	}{
+		"line range": {
+			input: "see https://gist.github.com/octocat/aa5a315d61ae9438b18d#file-main-go-L3-L5 please",
+			replacements: []gistReplacement{{
+				index: 4,
+				word:  "https://gist.github.com/octocat/aa5a315d61ae9438b18d#file-main-go-L3-L5",
+				user:  "octocat",
+				id:    "aa5a315d61ae9438b18d",
+				file:  "file-main-go",
+				line:  "L3-L5",
+			}},
+		},
+		"single line of a revision": {
+			input: "https://gist.github.com/octocat/aa5a315d61ae9438b18d/57a7f021a713b1c5a6a199b54cc514735d2d462f#file-hello_world-rb-L2",
+			replacements: []gistReplacement{{
+				word:     "https://gist.github.com/octocat/aa5a315d61ae9438b18d/57a7f021a713b1c5a6a199b54cc514735d2d462f#file-hello_world-rb-L2",
+				user:     "octocat",
+				id:       "aa5a315d61ae9438b18d",
+				revision: "57a7f021a713b1c5a6a199b54cc514735d2d462f",
+				file:     "file-hello_world-rb",
+				line:     "L2",
+			}},
+		},
+		"no lines": {
+			input: "https://gist.github.com/octocat/aa5a315d61ae9438b18d#file-main-go",
+		},
+		"inside a link": {
+			input: "[gist](https://gist.github.com/octocat/aa5a315d61ae9438b18d#file-main-go-L3)",
+		},
+		"repository permalink": {
+			input: "https://github.com/octocat/aa5a315d61ae9438b18d#file-main-go-L3",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.replacements, p.getGistReplacements(tc.input))
+		})
+	}
+}
+
+func TestMakeGistReplacements(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gists/aa11":
+			fmt.Fprint(w, `{"public": true, "files": {"main.go": {"filename": "main.go", "content": "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n"}}}`)
+		case "/gists/bb22":
+			fmt.Fprint(w, `{"public": false, "files": {"notes.txt": {"filename": "notes.txt", "content": "one\ntwo\n"}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := github.NewClient(nil)
+	baseURL, _ := url.Parse(ts.URL + "/")
+	client.BaseURL = baseURL
+
+	setup := func(codePreview string) *Plugin {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EnableCodePreview: codePreview})
+		api := &plugintest.API{}
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		p.SetAPI(api)
+		return p
+	}
+
+	for name, tc := range map[string]struct {
+		codePreview string
+		input       string
+		output      string
+	}{
+		"public gist": {
+			codePreview: "public",
+			input:       "see https://gist.github.com/octocat/aa11#file-main-go-L5-L7 please",
+			output:      "see \n[octocat/main.go (gist)](https://gist.github.com/octocat/aa11#file-main-go-L5-L7)\n```go\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n```\n please",
+		},
+		"secret gist with previews of public repositories": {
+			codePreview: "public",
+			input:       "https://gist.github.com/octocat/bb22#file-notes-txt-L1",
+			output:      "https://gist.github.com/octocat/bb22#file-notes-txt-L1",
+		},
+		"secret gist with previews of private repositories": {
+			codePreview: "privateAndPublic",
+			input:       "https://gist.github.com/octocat/bb22#file-notes-txt-L1",
+			output:      "\n[octocat/notes.txt (gist)](https://gist.github.com/octocat/bb22#file-notes-txt-L1)\n```txt\none\ntwo\n```\n",
+		},
+		"inaccessible gist": {
+			codePreview: "privateAndPublic",
+			input:       "https://gist.github.com/octocat/cc33#file-notes-txt-L1",
+			output:      "https://gist.github.com/octocat/cc33#file-notes-txt-L1",
+		},
+		"unknown file": {
+			codePreview: "public",
+			input:       "https://gist.github.com/octocat/aa11#file-other-go-L1",
+			output:      "https://gist.github.com/octocat/aa11#file-other-go-L1",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := setup(tc.codePreview)
+			msg := p.makeGistReplacements(tc.input, p.getGistReplacements(tc.input), client)
+			assert.Equal(t, tc.output, msg)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package graphql
+
+import (
+	"context"
+	"time"
+)
+
+// maxAssignments caps the number of results of each search of GetAssignments.
+const maxAssignments = 100
+
+// Sources of the assignments of a user.
+const (
+	// AssignmentSourceAssignee is an issue or pull request the user is assigned to.
+	AssignmentSourceAssignee = "assignee"
+	// AssignmentSourceTeamReview is a pull request whose review was requested from a team of the user.
+	AssignmentSourceTeamReview = "team_review"
+)
+
+// Types of assignments.
+const (
+	AssignmentTypeIssue       = "issue"
+	AssignmentTypePullRequest = "pull_request"
+)
+
+const assignmentFields = `
+__typename
+... on Issue {
+  number title url state createdAt updatedAt
+  author { login }
+  repository { nameWithOwner }
+  labels(first: 10) { nodes { name color } }
+}
+... on PullRequest {
+  number title url state createdAt updatedAt
+  author { login }
+  repository { nameWithOwner }
+  labels(first: 10) { nodes { name color } }
+}`
+
+const assignmentsQuery = `
+query($assigned: String!, $teamReviews: String!, $first: Int!) {
+  assigned: search(type: ISSUE, query: $assigned, first: $first) { nodes { ` + assignmentFields + ` } }
+  teamReviews: search(type: ISSUE, query: $teamReviews, first: $first) { nodes { ` + assignmentFields + ` } }
+}`
+
+// Assignment is an issue or pull request assigned to a user.
+type Assignment struct {
+	// Type is AssignmentTypeIssue or AssignmentTypePullRequest.
+	Type   string
+	Source string
+	Number int
+	Title  string
+	URL    string
+	State  string
+	// Repository is the full name of the repository, e.g. owner/repo.
+	Repository string
+	Author     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	// Labels are the first MaxLabels labels of the issue or pull request.
+	Labels []Label
+}
+
+type assignmentNode struct {
+	TypeName  string    `json:"__typename"`
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Author    struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Repository struct {
+		NameWithOwner string `json:"nameWithOwner"`
+	} `json:"repository"`
+	Labels struct {
+		Nodes []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"nodes"`
+	} `json:"labels"`
+}
+
+type assignmentSearch struct {
+	Nodes []*assignmentNode `json:"nodes"`
+}
+
+func (n *assignmentNode) assignment(source string) *Assignment {
+	assignment := &Assignment{
+		Type:       AssignmentTypeIssue,
+		Source:     source,
+		Number:     n.Number,
+		Title:      n.Title,
+		URL:        n.URL,
+		State:      n.State,
+		Repository: n.Repository.NameWithOwner,
+		Author:     n.Author.Login,
+		CreatedAt:  n.CreatedAt,
+		UpdatedAt:  n.UpdatedAt,
+		Labels:     []Label{},
+	}
+	if n.TypeName == "PullRequest" {
+		assignment.Type = AssignmentTypePullRequest
+	}
+
+	for _, label := range n.Labels.Nodes {
+		assignment.Labels = append(assignment.Labels, Label{Name: label.Name, Color: label.Color})
+	}
+
+	return assignment
+}
+
+// GetAssignments runs the search of the issues and pull requests assigned to a user, and the search
+// of the pull requests whose review was requested from one of their teams, in a single query. The
+// assignments are returned in that order, without duplicates.
+//
+// An error is returned if the query fails, for instance when the GitHub Enterprise server doesn't
+// support the queried fields.
+func (c *Client) GetAssignments(ctx context.Context, assignedQuery, teamReviewsQuery string) ([]*Assignment, error) {
+	data := struct {
+		Assigned    *assignmentSearch `json:"assigned"`
+		TeamReviews *assignmentSearch `json:"teamReviews"`
+	}{}
+	variables := map[string]interface{}{
+		"assigned":    assignedQuery,
+		"teamReviews": teamReviewsQuery,
+		"first":       maxAssignments,
+	}
+	if err := c.query(ctx, assignmentsQuery, variables, &data); err != nil {
+		return nil, err
+	}
+
+	assignments := []*Assignment{}
+	seen := map[string]bool{}
+	for _, search := range []struct {
+		Result *assignmentSearch
+		Source string
+	}{
+		{Result: data.Assigned, Source: AssignmentSourceAssignee},
+		{Result: data.TeamReviews, Source: AssignmentSourceTeamReview},
+	} {
+		if search.Result == nil {
+			continue
+		}
+		for _, node := range search.Result.Nodes {
+			// Search results can be other types, e.g. a node the user can no longer see
+			if node == nil || node.URL == "" || seen[node.URL] {
+				continue
+			}
+			seen[node.URL] = true
+			assignments = append(assignments, node.assignment(search.Source))
+		}
+	}
+
+	return assignments, nil
+}
@@ -0,0 +1,79 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAssignments(t *testing.T) {
+	t.Run("merged searches", func(t *testing.T) {
+		client := newTestClient(t, "/api/v3/", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/graphql", r.URL.Path)
+
+			var req request
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, map[string]interface{}{
+				"assigned":    "is:open assignee:alice",
+				"teamReviews": "is:pr is:open review-requested:alice -user-review-requested:alice",
+				"first":       float64(maxAssignments),
+			}, req.Variables)
+
+			fmt.Fprint(w, `{"data": {
+				"assigned": {"nodes": [
+					{"__typename": "Issue", "number": 1, "title": "Bug", "url": "https://github.com/owner/repo/issues/1", "state": "OPEN",
+					 "createdAt": "2021-03-05T12:00:00Z", "author": {"login": "bob"}, "repository": {"nameWithOwner": "owner/repo"},
+					 "labels": {"nodes": [{"name": "bug", "color": "d73a4a"}]}},
+					{"__typename": "PullRequest", "number": 2, "title": "Fix", "url": "https://github.com/owner/repo/pull/2", "state": "OPEN",
+					 "author": {"login": "alice"}, "repository": {"nameWithOwner": "owner/repo"}, "labels": {"nodes": []}}
+				]},
+				"teamReviews": {"nodes": [
+					{"__typename": "PullRequest", "number": 2, "title": "Fix", "url": "https://github.com/owner/repo/pull/2", "state": "OPEN"},
+					{"__typename": "PullRequest", "number": 3, "title": "Feature", "url": "https://github.com/owner/other/pull/3", "state": "OPEN",
+					 "author": {"login": "carol"}, "repository": {"nameWithOwner": "owner/other"}},
+					{}
+				]}
+			}}`)
+		})
+
+		assignments, err := client.GetAssignments(context.Background(), "is:open assignee:alice", "is:pr is:open review-requested:alice -user-review-requested:alice")
+		require.NoError(t, err)
+		require.Len(t, assignments, 3)
+
+		assert.Equal(t, &Assignment{
+			Type:       AssignmentTypeIssue,
+			Source:     AssignmentSourceAssignee,
+			Number:     1,
+			Title:      "Bug",
+			URL:        "https://github.com/owner/repo/issues/1",
+			State:      "OPEN",
+			Repository: "owner/repo",
+			Author:     "bob",
+			CreatedAt:  time.Date(2021, 3, 5, 12, 0, 0, 0, time.UTC),
+			Labels:     []Label{{Name: "bug", Color: "d73a4a"}},
+		}, assignments[0])
+
+		// The pull request both assigned and requested from a team is only listed once
+		assert.Equal(t, AssignmentTypePullRequest, assignments[1].Type)
+		assert.Equal(t, AssignmentSourceAssignee, assignments[1].Source)
+		assert.Equal(t, 3, assignments[2].Number)
+		assert.Equal(t, AssignmentTypePullRequest, assignments[2].Type)
+		assert.Equal(t, AssignmentSourceTeamReview, assignments[2].Source)
+		assert.Equal(t, "owner/other", assignments[2].Repository)
+	})
+
+	t.Run("unsupported fields", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"errors": [{"message": "Field 'search' doesn't exist on type 'Query'", "type": "undefinedField"}]}`)
+		})
+
+		_, err := client.GetAssignments(context.Background(), "assigned", "teamReviews")
+		assert.EqualError(t, err, "Field 'search' doesn't exist on type 'Query'")
+	})
+}
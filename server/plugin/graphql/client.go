@@ -0,0 +1,90 @@
+// Package graphql queries the GitHub GraphQL API using the transport of an authenticated REST client.
+package graphql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+// Client runs GraphQL queries against GitHub or GitHub Enterprise.
+type Client struct {
+	client *github.Client
+}
+
+// NewClient returns a client sharing the authentication and base URL of the given REST client.
+func NewClient(client *github.Client) *Client {
+	return &Client{client: client}
+}
+
+type request struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Error is an error returned by the GraphQL API alongside the data it could resolve.
+type Error struct {
+	Message string        `json:"message"`
+	Type    string        `json:"type"`
+	Path    []interface{} `json:"path"`
+}
+
+// Errors are the errors of a GraphQL response.
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Message)
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// hasType tells if one of the errors has the given type, e.g. NOT_FOUND.
+func (e Errors) hasType(errorType string) bool {
+	for _, err := range e {
+		if err.Type == errorType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type response struct {
+	Data   interface{} `json:"data"`
+	Errors Errors      `json:"errors"`
+}
+
+// endpoint returns the URL of the GraphQL API relative to the base URL of the REST API. GitHub
+// Enterprise serves it at /api/graphql next to /api/v3.
+func (c *Client) endpoint() string {
+	if strings.HasSuffix(c.client.BaseURL.Path, "/api/v3/") {
+		return "../graphql"
+	}
+
+	return "graphql"
+}
+
+// query runs a query and decodes its data into data. When the response holds errors for parts of
+// the query, the resolved data is decoded and the errors are returned as Errors.
+func (c *Client) query(ctx context.Context, query string, variables map[string]interface{}, data interface{}) error {
+	req, err := c.client.NewRequest("POST", c.endpoint(), &request{Query: query, Variables: variables})
+	if err != nil {
+		return errors.Wrap(err, "could not create GraphQL request")
+	}
+
+	resp := &response{Data: data}
+	if _, err := c.client.Do(ctx, req, resp); err != nil {
+		return errors.Wrap(err, "GraphQL request failed")
+	}
+
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+
+	return nil
+}
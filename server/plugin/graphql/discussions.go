@@ -0,0 +1,119 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRepositoryNotFound is returned when a repository doesn't exist or isn't visible to the user.
+var ErrRepositoryNotFound = errors.New("repository not found")
+
+const discussionRepositoryQuery = `
+query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    id
+    hasDiscussionsEnabled
+    discussionCategories(first: 100) {
+      nodes { id name isAnswerable }
+    }
+  }
+}`
+
+const createDiscussionMutation = `
+mutation($repositoryId: ID!, $categoryId: ID!, $title: String!, $body: String!) {
+  createDiscussion(input: {repositoryId: $repositoryId, categoryId: $categoryId, title: $title, body: $body}) {
+    discussion { number url }
+  }
+}`
+
+// DiscussionCategory is a category discussions of a repository are created in.
+type DiscussionCategory struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// IsAnswerable tells if discussions of the category can be marked as answered, e.g. Q&A.
+	IsAnswerable bool `json:"isAnswerable"`
+}
+
+// DiscussionRepository is what's needed to create a discussion in a repository.
+type DiscussionRepository struct {
+	ID                 string
+	DiscussionsEnabled bool
+	Categories         []DiscussionCategory
+}
+
+// Category returns the category with the given ID, or nil if the repository has none.
+func (r *DiscussionRepository) Category(id string) *DiscussionCategory {
+	for i := range r.Categories {
+		if r.Categories[i].ID == id {
+			return &r.Categories[i]
+		}
+	}
+
+	return nil
+}
+
+// Discussion is a created discussion.
+type Discussion struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+}
+
+// GetDiscussionRepository fetches the ID of a repository, whether it has discussions enabled and
+// its discussion categories. ErrRepositoryNotFound is returned if the user can't see it.
+func (c *Client) GetDiscussionRepository(ctx context.Context, owner, repo string) (*DiscussionRepository, error) {
+	var data struct {
+		Repository *struct {
+			ID                    string `json:"id"`
+			HasDiscussionsEnabled bool   `json:"hasDiscussionsEnabled"`
+			DiscussionCategories  struct {
+				Nodes []DiscussionCategory `json:"nodes"`
+			} `json:"discussionCategories"`
+		} `json:"repository"`
+	}
+
+	if err := c.query(ctx, discussionRepositoryQuery, map[string]interface{}{"owner": owner, "name": repo}, &data); err != nil {
+		if errs, ok := err.(Errors); ok && errs.hasType("NOT_FOUND") {
+			return nil, ErrRepositoryNotFound
+		}
+		return nil, err
+	}
+	if data.Repository == nil {
+		return nil, ErrRepositoryNotFound
+	}
+
+	categories := data.Repository.DiscussionCategories.Nodes
+	if categories == nil {
+		categories = []DiscussionCategory{}
+	}
+
+	return &DiscussionRepository{
+		ID:                 data.Repository.ID,
+		DiscussionsEnabled: data.Repository.HasDiscussionsEnabled,
+		Categories:         categories,
+	}, nil
+}
+
+// CreateDiscussion creates a discussion in a category of a repository, both given by their IDs.
+func (c *Client) CreateDiscussion(ctx context.Context, repositoryID, categoryID, title, body string) (*Discussion, error) {
+	var data struct {
+		CreateDiscussion *struct {
+			Discussion *Discussion `json:"discussion"`
+		} `json:"createDiscussion"`
+	}
+
+	variables := map[string]interface{}{
+		"repositoryId": repositoryID,
+		"categoryId":   categoryID,
+		"title":        title,
+		"body":         body,
+	}
+	if err := c.query(ctx, createDiscussionMutation, variables, &data); err != nil {
+		return nil, err
+	}
+	if data.CreateDiscussion == nil || data.CreateDiscussion.Discussion == nil {
+		return nil, errors.New("no discussion was created")
+	}
+
+	return data.CreateDiscussion.Discussion, nil
+}
@@ -0,0 +1,80 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetDiscussionRepository(t *testing.T) {
+	t.Run("categories", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			var req request
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, map[string]interface{}{"owner": "owner", "name": "repo"}, req.Variables)
+
+			fmt.Fprint(w, `{"data": {"repository": {
+				"id": "R_1",
+				"hasDiscussionsEnabled": true,
+				"discussionCategories": {"nodes": [{"id": "DC_1", "name": "General"}, {"id": "DC_2", "name": "Q&A", "isAnswerable": true}]}
+			}}}`)
+		})
+
+		repo, err := client.GetDiscussionRepository(context.Background(), "owner", "repo")
+		require.NoError(t, err)
+		assert.Equal(t, "R_1", repo.ID)
+		assert.True(t, repo.DiscussionsEnabled)
+		assert.Equal(t, []DiscussionCategory{{ID: "DC_1", Name: "General"}, {ID: "DC_2", Name: "Q&A", IsAnswerable: true}}, repo.Categories)
+		assert.Equal(t, "General", repo.Category("DC_1").Name)
+		assert.Nil(t, repo.Category("DC_3"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data": {"repository": null}, "errors": [{"type": "NOT_FOUND", "path": ["repository"], "message": "Could not resolve to a Repository"}]}`)
+		})
+
+		_, err := client.GetDiscussionRepository(context.Background(), "owner", "repo")
+		assert.Equal(t, ErrRepositoryNotFound, err)
+	})
+
+	t.Run("unsupported schema", func(t *testing.T) {
+		client := newTestClient(t, "/api/v3/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"errors": [{"message": "Field 'hasDiscussionsEnabled' doesn't exist on type 'Repository'"}]}`)
+		})
+
+		_, err := client.GetDiscussionRepository(context.Background(), "owner", "repo")
+		assert.EqualError(t, err, "Field 'hasDiscussionsEnabled' doesn't exist on type 'Repository'")
+	})
+}
+
+func TestCreateDiscussion(t *testing.T) {
+	t.Run("created", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			var req request
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Contains(t, req.Query, "createDiscussion(input:")
+			assert.Equal(t, map[string]interface{}{"repositoryId": "R_1", "categoryId": "DC_1", "title": "Title", "body": "Body"}, req.Variables)
+
+			fmt.Fprint(w, `{"data": {"createDiscussion": {"discussion": {"number": 3, "url": "https://github.com/owner/repo/discussions/3"}}}}`)
+		})
+
+		discussion, err := client.CreateDiscussion(context.Background(), "R_1", "DC_1", "Title", "Body")
+		require.NoError(t, err)
+		assert.Equal(t, &Discussion{Number: 3, URL: "https://github.com/owner/repo/discussions/3"}, discussion)
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data": {"createDiscussion": null}, "errors": [{"type": "FORBIDDEN", "message": "Resource not accessible by integration"}]}`)
+		})
+
+		_, err := client.CreateDiscussion(context.Background(), "R_1", "DC_1", "Title", "Body")
+		assert.EqualError(t, err, "Resource not accessible by integration")
+	})
+}
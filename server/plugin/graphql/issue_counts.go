@@ -0,0 +1,71 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// buildIssueCountsQuery builds a query counting the results of the given issue searches, each
+// under the alias countN.
+func buildIssueCountsQuery(queries []string) (string, map[string]interface{}) {
+	var declarations, fields strings.Builder
+	variables := map[string]interface{}{}
+
+	for i, query := range queries {
+		fmt.Fprintf(&declarations, "$query%d: String!, ", i)
+		fmt.Fprintf(&fields, "count%d: search(type: ISSUE, query: $query%d, first: 1) { issueCount }\n", i, i)
+
+		variables[fmt.Sprintf("query%d", i)] = query
+	}
+
+	query := fmt.Sprintf("query(%s) {\n%s}", strings.TrimSuffix(declarations.String(), ", "), fields.String())
+
+	return query, variables
+}
+
+// CountIssues counts the issues and pull requests matching each of the given search queries in as
+// few queries as possible. The counts are keyed by the index of their search; searches that failed
+// are missing.
+//
+// An error is returned if a query fails as a whole, for instance when the GraphQL API is unavailable.
+func (c *Client) CountIssues(ctx context.Context, queries []string) (map[int]int, error) {
+	counts := map[int]int{}
+
+	for start := 0; start < len(queries); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(queries) {
+			end = len(queries)
+		}
+
+		query, variables := buildIssueCountsQuery(queries[start:end])
+		data := map[string]json.RawMessage{}
+		if err := c.query(ctx, query, variables, &data); err != nil {
+			// The searches that didn't fail still come with their counts
+			if _, ok := err.(Errors); !ok || len(data) == 0 {
+				return nil, err
+			}
+		}
+
+		for alias, raw := range data {
+			index, err := strconv.Atoi(strings.TrimPrefix(alias, "count"))
+			if err != nil || index < 0 || index >= end-start {
+				continue
+			}
+
+			var search *struct {
+				IssueCount int `json:"issueCount"`
+			}
+			if err := json.Unmarshal(raw, &search); err != nil {
+				return nil, err
+			}
+			if search != nil {
+				counts[start+index] = search.IssueCount
+			}
+		}
+	}
+
+	return counts, nil
+}
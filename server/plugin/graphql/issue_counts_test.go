@@ -0,0 +1,68 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildIssueCountsQuery(t *testing.T) {
+	query, variables := buildIssueCountsQuery([]string{"assignee:alice is:open", "assignee:bob is:open"})
+
+	assert.Contains(t, query, "query($query0: String!, $query1: String!)")
+	assert.Contains(t, query, "count0: search(type: ISSUE, query: $query0, first: 1) { issueCount }")
+	assert.Contains(t, query, "count1: search(type: ISSUE, query: $query1, first: 1) { issueCount }")
+	assert.Equal(t, map[string]interface{}{"query0": "assignee:alice is:open", "query1": "assignee:bob is:open"}, variables)
+}
+
+func TestCountIssues(t *testing.T) {
+	t.Run("batched searches", func(t *testing.T) {
+		requests := 0
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			var req request
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			fields := []string{}
+			for name := range req.Variables {
+				fields = append(fields, fmt.Sprintf(`%q: {"issueCount": %d}`, "count"+strings.TrimPrefix(name, "query"), len(fields)))
+			}
+			fmt.Fprintf(w, `{"data": {%s}}`, strings.Join(fields, ", "))
+		})
+
+		queries := make([]string, maxBatchSize+2)
+		for i := range queries {
+			queries[i] = fmt.Sprintf("assignee:user%d is:open", i)
+		}
+
+		counts, err := client.CountIssues(context.Background(), queries)
+		require.NoError(t, err)
+		assert.Len(t, counts, len(queries))
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("failed searches are dropped", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data": {"count0": {"issueCount": 3}, "count1": null}, "errors": [{"path": ["count1"], "message": "The listed users cannot be searched"}]}`)
+		})
+
+		counts, err := client.CountIssues(context.Background(), []string{"assignee:alice", "assignee:ghost"})
+		require.NoError(t, err)
+		assert.Equal(t, map[int]int{0: 3}, counts)
+	})
+
+	t.Run("failed query", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"errors": [{"message": "API rate limit exceeded", "type": "RATE_LIMITED"}]}`)
+		})
+
+		_, err := client.CountIssues(context.Background(), []string{"assignee:alice"})
+		assert.EqualError(t, err, "API rate limit exceeded")
+	})
+}
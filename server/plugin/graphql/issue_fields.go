@@ -0,0 +1,284 @@
+package graphql
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnsupported is returned when the GitHub server doesn't have the fields or mutations of a
+// query, e.g. issue types on older GitHub Enterprise versions.
+var ErrUnsupported = errors.New("not supported by this GitHub server")
+
+// ErrProjectNotFound is returned when a project doesn't exist or isn't visible to the user.
+var ErrProjectNotFound = errors.New("project not found")
+
+// Data types of project fields whose values can be set.
+const (
+	ProjectFieldText         = "TEXT"
+	ProjectFieldNumber       = "NUMBER"
+	ProjectFieldDate         = "DATE"
+	ProjectFieldSingleSelect = "SINGLE_SELECT"
+)
+
+const issueTypesQuery = `
+query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) {
+    issueTypes(first: 100) {
+      nodes { id name description isEnabled }
+    }
+  }
+}`
+
+const updateIssueTypeMutation = `
+mutation($issueId: ID!, $issueTypeId: ID!) {
+  updateIssueIssueType(input: {issueId: $issueId, issueTypeId: $issueTypeId}) {
+    issue { id }
+  }
+}`
+
+const projectQuery = `
+query($owner: String!, $number: Int!) {
+  repositoryOwner(login: $owner) {
+    ... on ProjectV2Owner {
+      projectV2(number: $number) {
+        id
+        title
+        fields(first: 100) {
+          nodes {
+            ... on ProjectV2FieldCommon { id name dataType }
+            ... on ProjectV2SingleSelectField { options { id name } }
+          }
+        }
+      }
+    }
+  }
+}`
+
+const addProjectItemMutation = `
+mutation($projectId: ID!, $contentId: ID!) {
+  addProjectV2ItemById(input: {projectId: $projectId, contentId: $contentId}) {
+    item { id }
+  }
+}`
+
+const updateProjectItemFieldMutation = `
+mutation($projectId: ID!, $itemId: ID!, $fieldId: ID!, $value: ProjectV2FieldValue!) {
+  updateProjectV2ItemFieldValue(input: {projectId: $projectId, itemId: $itemId, fieldId: $fieldId, value: $value}) {
+    projectV2Item { id }
+  }
+}`
+
+// IssueType is a type issues of a repository can be given, e.g. Bug or Feature.
+type IssueType struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ProjectFieldOption is an option of a single select project field, e.g. a priority.
+type ProjectFieldOption struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ProjectField is a custom field of a project.
+type ProjectField struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// DataType is one of the ProjectField constants, or another type whose values can't be set.
+	DataType string               `json:"dataType"`
+	Options  []ProjectFieldOption `json:"options"`
+}
+
+// Project is a project issues can be added to.
+type Project struct {
+	ID     string
+	Title  string
+	Fields []ProjectField
+}
+
+// Field returns the field with the given name, ignoring case, or nil if the project has none.
+func (p *Project) Field(name string) *ProjectField {
+	for i := range p.Fields {
+		if strings.EqualFold(p.Fields[i].Name, name) {
+			return &p.Fields[i]
+		}
+	}
+
+	return nil
+}
+
+// Value returns the value to set the field to for the given text: the option with that name for
+// single select fields, or the text parsed as the data type of the field.
+func (f *ProjectField) Value(value string) (map[string]interface{}, error) {
+	switch f.DataType {
+	case ProjectFieldText:
+		return map[string]interface{}{"text": value}, nil
+	case ProjectFieldNumber:
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, errors.Errorf("%q is not a number", value)
+		}
+		return map[string]interface{}{"number": number}, nil
+	case ProjectFieldDate:
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(value))
+		if err != nil {
+			return nil, errors.Errorf("%q is not a date such as 2006-01-02", value)
+		}
+		return map[string]interface{}{"date": date.Format("2006-01-02")}, nil
+	case ProjectFieldSingleSelect:
+		for _, option := range f.Options {
+			if strings.EqualFold(option.Name, strings.TrimSpace(value)) {
+				return map[string]interface{}{"singleSelectOptionId": option.ID}, nil
+			}
+		}
+		return nil, errors.Errorf("%q is not an option of the field", value)
+	default:
+		return nil, errors.Errorf("fields of type %s can't be set", f.DataType)
+	}
+}
+
+// unsupported returns ErrUnsupported if the errors of a query are about fields the server doesn't
+// have, or else the error itself.
+func unsupported(err error) error {
+	if errs, ok := err.(Errors); ok && errs.hasType("undefinedField") {
+		return ErrUnsupported
+	}
+
+	return err
+}
+
+// GetIssueTypes fetches the enabled issue types of a repository. ErrUnsupported is returned if the
+// server has no issue types and ErrRepositoryNotFound if the user can't see the repository.
+func (c *Client) GetIssueTypes(ctx context.Context, owner, repo string) ([]IssueType, error) {
+	var data struct {
+		Repository *struct {
+			IssueTypes struct {
+				Nodes []struct {
+					IssueType
+					IsEnabled bool `json:"isEnabled"`
+				} `json:"nodes"`
+			} `json:"issueTypes"`
+		} `json:"repository"`
+	}
+
+	if err := c.query(ctx, issueTypesQuery, map[string]interface{}{"owner": owner, "name": repo}, &data); err != nil {
+		if errs, ok := err.(Errors); ok && errs.hasType("NOT_FOUND") {
+			return nil, ErrRepositoryNotFound
+		}
+		return nil, unsupported(err)
+	}
+	if data.Repository == nil {
+		return nil, ErrRepositoryNotFound
+	}
+
+	types := []IssueType{}
+	for _, node := range data.Repository.IssueTypes.Nodes {
+		if node.IsEnabled {
+			types = append(types, node.IssueType)
+		}
+	}
+
+	return types, nil
+}
+
+// UpdateIssueType sets the type of an issue, both given by their IDs.
+func (c *Client) UpdateIssueType(ctx context.Context, issueID, issueTypeID string) error {
+	var data struct {
+		UpdateIssueIssueType *struct{} `json:"updateIssueIssueType"`
+	}
+
+	if err := c.query(ctx, updateIssueTypeMutation, map[string]interface{}{"issueId": issueID, "issueTypeId": issueTypeID}, &data); err != nil {
+		return unsupported(err)
+	}
+	if data.UpdateIssueIssueType == nil {
+		return errors.New("the issue type wasn't updated")
+	}
+
+	return nil
+}
+
+// GetProject fetches a project of a user or organization by its number, along with its fields.
+// ErrUnsupported is returned if the server has no projects and ErrProjectNotFound if the user
+// can't see the project.
+func (c *Client) GetProject(ctx context.Context, owner string, number int) (*Project, error) {
+	var data struct {
+		RepositoryOwner *struct {
+			ProjectV2 *struct {
+				ID     string `json:"id"`
+				Title  string `json:"title"`
+				Fields struct {
+					Nodes []ProjectField `json:"nodes"`
+				} `json:"fields"`
+			} `json:"projectV2"`
+		} `json:"repositoryOwner"`
+	}
+
+	if err := c.query(ctx, projectQuery, map[string]interface{}{"owner": owner, "number": number}, &data); err != nil {
+		if errs, ok := err.(Errors); ok && errs.hasType("NOT_FOUND") {
+			return nil, ErrProjectNotFound
+		}
+		return nil, unsupported(err)
+	}
+	if data.RepositoryOwner == nil || data.RepositoryOwner.ProjectV2 == nil {
+		return nil, ErrProjectNotFound
+	}
+
+	project := data.RepositoryOwner.ProjectV2
+	fields := []ProjectField{}
+	for _, field := range project.Fields.Nodes {
+		// Fields not implementing ProjectV2FieldCommon, if any, are empty
+		if field.ID != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return &Project{ID: project.ID, Title: project.Title, Fields: fields}, nil
+}
+
+// AddProjectItem adds an issue or pull request to a project, both given by their IDs, and returns
+// the ID of the project item.
+func (c *Client) AddProjectItem(ctx context.Context, projectID, contentID string) (string, error) {
+	var data struct {
+		AddProjectV2ItemByID *struct {
+			Item *struct {
+				ID string `json:"id"`
+			} `json:"item"`
+		} `json:"addProjectV2ItemById"`
+	}
+
+	if err := c.query(ctx, addProjectItemMutation, map[string]interface{}{"projectId": projectID, "contentId": contentID}, &data); err != nil {
+		return "", unsupported(err)
+	}
+	if data.AddProjectV2ItemByID == nil || data.AddProjectV2ItemByID.Item == nil {
+		return "", errors.New("no project item was added")
+	}
+
+	return data.AddProjectV2ItemByID.Item.ID, nil
+}
+
+// UpdateProjectItemField sets a field of a project item to a value returned by ProjectField.Value.
+func (c *Client) UpdateProjectItemField(ctx context.Context, projectID, itemID, fieldID string, value map[string]interface{}) error {
+	var data struct {
+		UpdateProjectV2ItemFieldValue *struct{} `json:"updateProjectV2ItemFieldValue"`
+	}
+
+	variables := map[string]interface{}{
+		"projectId": projectID,
+		"itemId":    itemID,
+		"fieldId":   fieldID,
+		"value":     value,
+	}
+	if err := c.query(ctx, updateProjectItemFieldMutation, variables, &data); err != nil {
+		return unsupported(err)
+	}
+	if data.UpdateProjectV2ItemFieldValue == nil {
+		return errors.New("the project field wasn't updated")
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetIssueTypes(t *testing.T) {
+	t.Run("enabled types", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			var req request
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, map[string]interface{}{"owner": "owner", "name": "repo"}, req.Variables)
+
+			fmt.Fprint(w, `{"data": {"repository": {"issueTypes": {"nodes": [
+				{"id": "IT_1", "name": "Bug", "description": "An unexpected problem", "isEnabled": true},
+				{"id": "IT_2", "name": "Epic", "isEnabled": false},
+				{"id": "IT_3", "name": "Feature", "isEnabled": true}
+			]}}}}`)
+		})
+
+		types, err := client.GetIssueTypes(context.Background(), "owner", "repo")
+		require.NoError(t, err)
+		assert.Equal(t, []IssueType{{ID: "IT_1", Name: "Bug", Description: "An unexpected problem"}, {ID: "IT_3", Name: "Feature"}}, types)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data": {"repository": null}, "errors": [{"type": "NOT_FOUND", "path": ["repository"], "message": "Could not resolve to a Repository"}]}`)
+		})
+
+		_, err := client.GetIssueTypes(context.Background(), "owner", "repo")
+		assert.Equal(t, ErrRepositoryNotFound, err)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		client := newTestClient(t, "/api/v3/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"errors": [{"message": "Field 'issueTypes' doesn't exist on type 'Repository'", "type": "undefinedField"}]}`)
+		})
+
+		_, err := client.GetIssueTypes(context.Background(), "owner", "repo")
+		assert.Equal(t, ErrUnsupported, err)
+	})
+}
+
+func TestUpdateIssueType(t *testing.T) {
+	client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Contains(t, req.Query, "updateIssueIssueType(input:")
+		assert.Equal(t, map[string]interface{}{"issueId": "I_1", "issueTypeId": "IT_1"}, req.Variables)
+
+		fmt.Fprint(w, `{"data": {"updateIssueIssueType": {"issue": {"id": "I_1"}}}}`)
+	})
+
+	require.NoError(t, client.UpdateIssueType(context.Background(), "I_1", "IT_1"))
+}
+
+func TestGetProject(t *testing.T) {
+	t.Run("fields", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			var req request
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, map[string]interface{}{"owner": "owner", "number": float64(3)}, req.Variables)
+
+			fmt.Fprint(w, `{"data": {"repositoryOwner": {"projectV2": {"id": "PVT_1", "title": "Roadmap", "fields": {"nodes": [
+				{"id": "PVTF_1", "name": "Title", "dataType": "TITLE"},
+				{"id": "PVTSSF_1", "name": "Priority", "dataType": "SINGLE_SELECT", "options": [{"id": "o1", "name": "P0"}, {"id": "o2", "name": "P1"}]},
+				{}
+			]}}}}}`)
+		})
+
+		project, err := client.GetProject(context.Background(), "owner", 3)
+		require.NoError(t, err)
+		assert.Equal(t, "PVT_1", project.ID)
+		assert.Equal(t, "Roadmap", project.Title)
+		require.Len(t, project.Fields, 2)
+		assert.Equal(t, "PVTSSF_1", project.Field("priority").ID)
+		assert.Nil(t, project.Field("Status"))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data": {"repositoryOwner": {"projectV2": null}}, "errors": [{"type": "NOT_FOUND", "path": ["repositoryOwner", "projectV2"], "message": "Could not resolve to a ProjectV2 with the number 3."}]}`)
+		})
+
+		_, err := client.GetProject(context.Background(), "owner", 3)
+		assert.Equal(t, ErrProjectNotFound, err)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		client := newTestClient(t, "/api/v3/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"errors": [{"message": "Field 'projectV2' doesn't exist on type 'ProjectV2Owner'", "type": "undefinedField"}]}`)
+		})
+
+		_, err := client.GetProject(context.Background(), "owner", 3)
+		assert.Equal(t, ErrUnsupported, err)
+	})
+}
+
+func TestAddProjectItem(t *testing.T) {
+	client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]interface{}{"projectId": "PVT_1", "contentId": "I_1"}, req.Variables)
+
+		fmt.Fprint(w, `{"data": {"addProjectV2ItemById": {"item": {"id": "PVTI_1"}}}}`)
+	})
+
+	itemID, err := client.AddProjectItem(context.Background(), "PVT_1", "I_1")
+	require.NoError(t, err)
+	assert.Equal(t, "PVTI_1", itemID)
+}
+
+func TestUpdateProjectItemField(t *testing.T) {
+	t.Run("updated", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			var req request
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, map[string]interface{}{
+				"projectId": "PVT_1",
+				"itemId":    "PVTI_1",
+				"fieldId":   "PVTSSF_1",
+				"value":     map[string]interface{}{"singleSelectOptionId": "o1"},
+			}, req.Variables)
+
+			fmt.Fprint(w, `{"data": {"updateProjectV2ItemFieldValue": {"projectV2Item": {"id": "PVTI_1"}}}}`)
+		})
+
+		require.NoError(t, client.UpdateProjectItemField(context.Background(), "PVT_1", "PVTI_1", "PVTSSF_1", map[string]interface{}{"singleSelectOptionId": "o1"}))
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"data": {"updateProjectV2ItemFieldValue": null}, "errors": [{"type": "FORBIDDEN", "message": "Resource not accessible by integration"}]}`)
+		})
+
+		err := client.UpdateProjectItemField(context.Background(), "PVT_1", "PVTI_1", "PVTSSF_1", map[string]interface{}{"text": "x"})
+		assert.EqualError(t, err, "Resource not accessible by integration")
+	})
+}
+
+func TestProjectFieldValue(t *testing.T) {
+	priority := &ProjectField{DataType: ProjectFieldSingleSelect, Options: []ProjectFieldOption{{ID: "o1", Name: "P0"}}}
+	value, err := priority.Value(" p0")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"singleSelectOptionId": "o1"}, value)
+	_, err = priority.Value("P3")
+	assert.EqualError(t, err, `"P3" is not an option of the field`)
+
+	value, err = (&ProjectField{DataType: ProjectFieldNumber}).Value("3.5")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"number": 3.5}, value)
+	_, err = (&ProjectField{DataType: ProjectFieldNumber}).Value("high")
+	assert.Error(t, err)
+
+	value, err = (&ProjectField{DataType: ProjectFieldDate}).Value("2024-05-01")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"date": "2024-05-01"}, value)
+
+	value, err = (&ProjectField{DataType: ProjectFieldText}).Value("Needs triage")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"text": "Needs triage"}, value)
+
+	_, err = (&ProjectField{DataType: "ITERATION"}).Value("Sprint 3")
+	assert.EqualError(t, err, "fields of type ITERATION can't be set")
+}
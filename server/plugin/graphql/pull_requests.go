@@ -0,0 +1,308 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBatchSize caps the number of pull requests fetched by a single query.
+const maxBatchSize = 25
+
+// MaxLabels caps the number of labels fetched for a pull request, as queried in pullRequestFields.
+const MaxLabels = 10
+
+const pullRequestFields = `
+state
+mergeable
+reviewDecision
+reviewRequests(first: 100) {
+  nodes {
+    requestedReviewer {
+      ... on User { login }
+    }
+  }
+}
+latestReviews(first: 100) {
+  nodes {
+    databaseId
+    author { login }
+    body
+    state
+    submittedAt
+    url
+    commit { oid }
+  }
+}
+commits(last: 1) {
+  nodes {
+    commit {
+      statusCheckRollup { state }
+    }
+  }
+}
+labels(first: 10) {
+  nodes { name color }
+}`
+
+// PullRequestRef identifies a pull request.
+type PullRequestRef struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+// Review is the latest review of a user on a pull request.
+type Review struct {
+	ID          int64
+	Author      string
+	Body        string
+	State       string
+	SubmittedAt time.Time
+	URL         string
+	CommitID    string
+}
+
+// Label is a label of a pull request.
+type Label struct {
+	Name string
+	// Color is the hexadecimal color of the label, without a leading #.
+	Color string
+}
+
+// PullRequestDetails are the details of a pull request shown next to its links.
+type PullRequestDetails struct {
+	// State is OPEN, CLOSED or MERGED.
+	State string
+	// Mergeable is MERGEABLE, CONFLICTING or UNKNOWN.
+	Mergeable string
+	// ReviewDecision is APPROVED, CHANGES_REQUESTED, REVIEW_REQUIRED or empty.
+	ReviewDecision string
+	// RequestedReviewers are the logins of the users whose review is requested.
+	RequestedReviewers []string
+	LatestReviews      []Review
+	// CheckState is the combined state of the statuses and checks of the head commit, e.g. SUCCESS,
+	// or empty if it has none.
+	CheckState string
+	// Labels are the first MaxLabels labels of the pull request.
+	Labels []Label
+}
+
+type pullRequestNode struct {
+	State          string `json:"state"`
+	Mergeable      string `json:"mergeable"`
+	ReviewDecision string `json:"reviewDecision"`
+	ReviewRequests struct {
+		Nodes []struct {
+			RequestedReviewer struct {
+				Login string `json:"login"`
+			} `json:"requestedReviewer"`
+		} `json:"nodes"`
+	} `json:"reviewRequests"`
+	LatestReviews struct {
+		Nodes []struct {
+			DatabaseID int64 `json:"databaseId"`
+			Author     struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			Body        string    `json:"body"`
+			State       string    `json:"state"`
+			SubmittedAt time.Time `json:"submittedAt"`
+			URL         string    `json:"url"`
+			Commit      struct {
+				OID string `json:"oid"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"latestReviews"`
+	Commits struct {
+		Nodes []struct {
+			Commit struct {
+				StatusCheckRollup struct {
+					State string `json:"state"`
+				} `json:"statusCheckRollup"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
+	Labels struct {
+		Nodes []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"nodes"`
+	} `json:"labels"`
+}
+
+type repositoryNode struct {
+	PullRequest *pullRequestNode `json:"pullRequest"`
+}
+
+func (n *pullRequestNode) details() *PullRequestDetails {
+	details := &PullRequestDetails{
+		State:              n.State,
+		Mergeable:          n.Mergeable,
+		ReviewDecision:     n.ReviewDecision,
+		RequestedReviewers: []string{},
+		LatestReviews:      []Review{},
+		Labels:             []Label{},
+	}
+
+	for _, request := range n.ReviewRequests.Nodes {
+		// Team review requests have no login
+		if request.RequestedReviewer.Login != "" {
+			details.RequestedReviewers = append(details.RequestedReviewers, request.RequestedReviewer.Login)
+		}
+	}
+
+	for _, review := range n.LatestReviews.Nodes {
+		details.LatestReviews = append(details.LatestReviews, Review{
+			ID:          review.DatabaseID,
+			Author:      review.Author.Login,
+			Body:        review.Body,
+			State:       review.State,
+			SubmittedAt: review.SubmittedAt,
+			URL:         review.URL,
+			CommitID:    review.Commit.OID,
+		})
+	}
+
+	for _, label := range n.Labels.Nodes {
+		details.Labels = append(details.Labels, Label{Name: label.Name, Color: label.Color})
+	}
+
+	if len(n.Commits.Nodes) > 0 {
+		details.CheckState = n.Commits.Nodes[0].Commit.StatusCheckRollup.State
+	}
+
+	return details
+}
+
+// buildPullRequestsQuery builds a query fetching the given pull requests, each under the alias prN,
+// and the rate limit cost of the query.
+func buildPullRequestsQuery(refs []PullRequestRef) (string, map[string]interface{}) {
+	var declarations, fields strings.Builder
+	variables := map[string]interface{}{}
+
+	for i, ref := range refs {
+		fmt.Fprintf(&declarations, "$owner%d: String!, $name%d: String!, $number%d: Int!, ", i, i, i)
+		fmt.Fprintf(&fields, "pr%d: repository(owner: $owner%d, name: $name%d) { pullRequest(number: $number%d) { %s } }\n", i, i, i, i, pullRequestFields)
+
+		variables[fmt.Sprintf("owner%d", i)] = ref.Owner
+		variables[fmt.Sprintf("name%d", i)] = ref.Repo
+		variables[fmt.Sprintf("number%d", i)] = ref.Number
+	}
+
+	fields.WriteString("rateLimit { cost }\n")
+
+	query := fmt.Sprintf("query(%s) {\n%s}", strings.TrimSuffix(declarations.String(), ", "), fields.String())
+
+	return query, variables
+}
+
+// PullRequestsResult is the outcome of fetching a batch of pull requests.
+type PullRequestsResult struct {
+	// Details are in the order of the fetched pull requests, with nil for the pull requests that
+	// couldn't be fetched.
+	Details []*PullRequestDetails
+	// Errors are the errors of the pull requests that couldn't be fetched, by their index. Pull
+	// requests missing from Details without an error weren't returned by GitHub.
+	Errors map[int]Error
+	// Cost is the total rate limit cost of the queries, in points of the GraphQL rate limit.
+	Cost int
+}
+
+// GetPullRequestsDetails fetches the details of pull requests in as few queries as possible. The
+// returned details are in the order of refs, with nil for the pull requests that couldn't be
+// fetched, e.g. because they don't exist or aren't visible to the user.
+//
+// An error is returned if a query fails as a whole, for instance when the GitHub Enterprise server
+// doesn't support the queried fields.
+func (c *Client) GetPullRequestsDetails(ctx context.Context, refs []PullRequestRef) ([]*PullRequestDetails, error) {
+	result, err := c.GetPullRequests(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Details, nil
+}
+
+// GetPullRequests fetches pull requests like GetPullRequestsDetails, also returning why the pull
+// requests that couldn't be fetched failed and what the queries cost.
+func (c *Client) GetPullRequests(ctx context.Context, refs []PullRequestRef) (*PullRequestsResult, error) {
+	result := &PullRequestsResult{
+		Details: make([]*PullRequestDetails, len(refs)),
+		Errors:  map[int]Error{},
+	}
+
+	for start := 0; start < len(refs); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(refs) {
+			end = len(refs)
+		}
+
+		query, variables := buildPullRequestsQuery(refs[start:end])
+		data := map[string]json.RawMessage{}
+		if err := c.query(ctx, query, variables, &data); err != nil {
+			// Partial errors, e.g. a missing pull request, still come with the data of the others
+			queryErrors, ok := err.(Errors)
+			if !ok || len(data) == 0 {
+				return nil, err
+			}
+
+			for _, queryErr := range queryErrors {
+				if index, ok := pullRequestIndex(queryErr.Path); ok && index < end-start {
+					result.Errors[start+index] = queryErr
+				}
+			}
+		}
+
+		if raw, ok := data["rateLimit"]; ok {
+			var rateLimit *struct {
+				Cost int `json:"cost"`
+			}
+			if err := json.Unmarshal(raw, &rateLimit); err == nil && rateLimit != nil {
+				result.Cost += rateLimit.Cost
+			}
+		}
+
+		for i := range refs[start:end] {
+			raw, ok := data[fmt.Sprintf("pr%d", i)]
+			if !ok {
+				continue
+			}
+
+			var repo *repositoryNode
+			if err := json.Unmarshal(raw, &repo); err != nil {
+				return nil, err
+			}
+			if repo == nil || repo.PullRequest == nil {
+				continue
+			}
+
+			result.Details[start+i] = repo.PullRequest.details()
+		}
+	}
+
+	return result, nil
+}
+
+// pullRequestIndex returns the index of the pull request an error is about, given the path of the
+// error, e.g. ["pr1", "pullRequest"].
+func pullRequestIndex(path []interface{}) (int, bool) {
+	if len(path) == 0 {
+		return 0, false
+	}
+
+	alias, ok := path[0].(string)
+	if !ok || !strings.HasPrefix(alias, "pr") {
+		return 0, false
+	}
+
+	index, err := strconv.Atoi(strings.TrimPrefix(alias, "pr"))
+	if err != nil || index < 0 {
+		return 0, false
+	}
+
+	return index, true
+}
@@ -0,0 +1,163 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, basePath string, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + basePath)
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	return NewClient(client)
+}
+
+func TestBuildPullRequestsQuery(t *testing.T) {
+	query, variables := buildPullRequestsQuery([]PullRequestRef{
+		{Owner: "owner", Repo: "repo", Number: 1},
+		{Owner: "other", Repo: "project", Number: 2},
+	})
+
+	assert.Contains(t, query, "query($owner0: String!, $name0: String!, $number0: Int!, $owner1: String!, $name1: String!, $number1: Int!)")
+	assert.Contains(t, query, "pr0: repository(owner: $owner0, name: $name0) { pullRequest(number: $number0)")
+	assert.Contains(t, query, "pr1: repository(owner: $owner1, name: $name1) { pullRequest(number: $number1)")
+	assert.Equal(t, map[string]interface{}{
+		"owner0": "owner", "name0": "repo", "number0": 1,
+		"owner1": "other", "name1": "project", "number1": 2,
+	}, variables)
+}
+
+func TestGetPullRequestsDetails(t *testing.T) {
+	refs := []PullRequestRef{
+		{Owner: "owner", Repo: "repo", Number: 1},
+		{Owner: "owner", Repo: "repo", Number: 2},
+	}
+
+	t.Run("batched query", func(t *testing.T) {
+		requests := 0
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			assert.Equal(t, "/graphql", r.URL.Path)
+			assert.Equal(t, http.MethodPost, r.Method)
+
+			var req request
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Len(t, req.Variables, 6)
+
+			fmt.Fprint(w, `{"data": {
+				"pr0": {"pullRequest": {
+					"mergeable": "MERGEABLE",
+					"reviewDecision": "APPROVED",
+					"reviewRequests": {"nodes": [{"requestedReviewer": {"login": "alice"}}, {"requestedReviewer": {}}]},
+					"latestReviews": {"nodes": [{"databaseId": 10, "author": {"login": "bob"}, "state": "APPROVED", "submittedAt": "2020-09-01T10:00:00Z", "commit": {"oid": "abc"}}]},
+					"commits": {"nodes": [{"commit": {"statusCheckRollup": {"state": "SUCCESS"}}}]},
+					"labels": {"nodes": [{"name": "blocked", "color": "b60205"}, {"name": "needs-qa", "color": "fbca04"}]}
+				}},
+				"pr1": null
+			}, "errors": [{"type": "NOT_FOUND", "path": ["pr1"], "message": "Could not resolve to a Repository"}]}`)
+		})
+
+		details, err := client.GetPullRequestsDetails(context.Background(), refs)
+		require.NoError(t, err)
+		assert.Equal(t, 1, requests)
+		require.Len(t, details, 2)
+		assert.Nil(t, details[1])
+
+		require.NotNil(t, details[0])
+		assert.Equal(t, "MERGEABLE", details[0].Mergeable)
+		assert.Equal(t, "APPROVED", details[0].ReviewDecision)
+		assert.Equal(t, []string{"alice"}, details[0].RequestedReviewers)
+		assert.Equal(t, "SUCCESS", details[0].CheckState)
+		assert.Equal(t, []Label{{Name: "blocked", Color: "b60205"}, {Name: "needs-qa", Color: "fbca04"}}, details[0].Labels)
+		require.Len(t, details[0].LatestReviews, 1)
+		assert.Equal(t, int64(10), details[0].LatestReviews[0].ID)
+		assert.Equal(t, "bob", details[0].LatestReviews[0].Author)
+		assert.Equal(t, "abc", details[0].LatestReviews[0].CommitID)
+	})
+
+	t.Run("unsupported schema", func(t *testing.T) {
+		client := newTestClient(t, "/api/v3/", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/graphql", r.URL.Path)
+			fmt.Fprint(w, `{"errors": [{"message": "Field 'latestReviews' doesn't exist on type 'PullRequest'"}]}`)
+		})
+
+		details, err := client.GetPullRequestsDetails(context.Background(), refs)
+		assert.Error(t, err)
+		assert.Nil(t, details)
+	})
+
+	t.Run("request failure", func(t *testing.T) {
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+
+		_, err := client.GetPullRequestsDetails(context.Background(), refs)
+		assert.Error(t, err)
+	})
+
+	t.Run("large batches are split", func(t *testing.T) {
+		requests := 0
+		client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			fmt.Fprint(w, `{"data": {}}`)
+		})
+
+		details, err := client.GetPullRequestsDetails(context.Background(), make([]PullRequestRef, maxBatchSize+1))
+		require.NoError(t, err)
+		assert.Len(t, details, maxBatchSize+1)
+		assert.Equal(t, 2, requests)
+	})
+}
+
+func TestGetPullRequests(t *testing.T) {
+	refs := []PullRequestRef{
+		{Owner: "owner", Repo: "repo", Number: 1},
+		{Owner: "owner", Repo: "missing", Number: 2},
+		{Owner: "secret", Repo: "repo", Number: 3},
+	}
+
+	client := newTestClient(t, "/", func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Contains(t, req.Query, "rateLimit { cost }")
+
+		fmt.Fprint(w, `{"data": {
+			"pr0": {"pullRequest": {"state": "MERGED", "mergeable": "UNKNOWN", "commits": {"nodes": [{"commit": {"statusCheckRollup": {"state": "FAILURE"}}}]}}},
+			"pr1": null,
+			"pr2": null,
+			"rateLimit": {"cost": 3}
+		}, "errors": [
+			{"type": "NOT_FOUND", "path": ["pr1"], "message": "Could not resolve to a Repository with the name 'owner/missing'."},
+			{"type": "FORBIDDEN", "path": ["pr2", "pullRequest"], "message": "Resource protected by organization SAML enforcement."}
+		]}`)
+	})
+
+	result, err := client.GetPullRequests(context.Background(), refs)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Cost)
+
+	require.Len(t, result.Details, 3)
+	require.NotNil(t, result.Details[0])
+	assert.Equal(t, "MERGED", result.Details[0].State)
+	assert.Equal(t, "FAILURE", result.Details[0].CheckState)
+	assert.Nil(t, result.Details[1])
+	assert.Nil(t, result.Details[2])
+
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, "NOT_FOUND", result.Errors[1].Type)
+	assert.Equal(t, "FORBIDDEN", result.Errors[2].Type)
+}
@@ -0,0 +1,224 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	repositoryEventType = "repository"
+
+	// headerSyncSeparator separates the synced part of a channel header from the rest of it.
+	headerSyncSeparator = " || "
+)
+
+// RepositoryEditChange holds the previous value of a field of an edited repository.
+type RepositoryEditChange struct {
+	From *string `json:"from,omitempty"`
+}
+
+// RepositoryEditChanges holds the previous values of the synced fields of an edited repository.
+type RepositoryEditChanges struct {
+	Description   *RepositoryEditChange `json:"description,omitempty"`
+	DefaultBranch *RepositoryEditChange `json:"default_branch,omitempty"`
+}
+
+// RepositoryEditEvent is a repository event with the edited action. go-github doesn't parse the
+// changes of an edit yet.
+type RepositoryEditEvent struct {
+	Action  *string                `json:"action,omitempty"`
+	Repo    *github.Repository     `json:"repository,omitempty"`
+	Changes *RepositoryEditChanges `json:"changes,omitempty"`
+	Sender  *github.User           `json:"sender,omitempty"`
+}
+
+func (e *RepositoryEditEvent) GetAction() string {
+	if e == nil || e.Action == nil {
+		return ""
+	}
+	return *e.Action
+}
+
+func (e *RepositoryEditEvent) GetRepo() *github.Repository {
+	if e == nil {
+		return nil
+	}
+	return e.Repo
+}
+
+// syncedFieldsChanged tells if the edit changed the description or the default branch.
+func (e *RepositoryEditEvent) syncedFieldsChanged() bool {
+	return e.Changes != nil && (e.Changes.Description != nil || e.Changes.DefaultBranch != nil)
+}
+
+// previousRepo returns the repository with the values of its synced fields before the edit.
+func (e *RepositoryEditEvent) previousRepo() *github.Repository {
+	previous := *e.GetRepo()
+	if e.Changes == nil {
+		return &previous
+	}
+	if e.Changes.Description != nil {
+		previous.Description = e.Changes.Description.From
+	}
+	if e.Changes.DefaultBranch != nil {
+		previous.DefaultBranch = e.Changes.DefaultBranch.From
+	}
+	return &previous
+}
+
+// parseRepositoryEditEvent parses a repository event if it's an edit. It returns nil for other actions.
+func parseRepositoryEditEvent(body []byte) (*RepositoryEditEvent, error) {
+	var event *RepositoryEditEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	if event.GetAction() != "edited" {
+		return nil, nil
+	}
+	return event, nil
+}
+
+// syncedHeaderPrefix is how a header synced with the given repository starts.
+func syncedHeaderPrefix(repository string) string {
+	return repository + " — "
+}
+
+func formatSyncedHeader(repo *github.Repository) string {
+	description := repo.GetDescription()
+	if description == "" {
+		description = "No description"
+	}
+
+	return fmt.Sprintf("%s%s | default branch: %s", syncedHeaderPrefix(repo.GetFullName()), description, repo.GetDefaultBranch())
+}
+
+// splitSyncedHeader returns the part of a header that wasn't synced with the given repository.
+func splitSyncedHeader(header, repository string) string {
+	if !strings.HasPrefix(header, syncedHeaderPrefix(repository)) {
+		return header
+	}
+
+	index := strings.Index(header, headerSyncSeparator)
+	if index == -1 {
+		return ""
+	}
+
+	return header[index+len(headerSyncSeparator):]
+}
+
+// buildSyncedHeader replaces the synced part of a header, preserving the rest of it.
+func buildSyncedHeader(header string, repo *github.Repository) string {
+	return joinSyncedHeader(formatSyncedHeader(repo), splitSyncedHeader(header, repo.GetFullName()))
+}
+
+// buildEditedSyncedHeader replaces the synced part of a header once the repository was edited. The
+// synced part is found by rendering it with the values before the edit, so that it's replaced whole
+// even if the previous description contained the separator.
+func buildEditedSyncedHeader(header string, previous, repo *github.Repository) string {
+	old := formatSyncedHeader(previous)
+	switch {
+	case header == old:
+		return joinSyncedHeader(formatSyncedHeader(repo), "")
+	case strings.HasPrefix(header, old+headerSyncSeparator):
+		return joinSyncedHeader(formatSyncedHeader(repo), strings.TrimPrefix(header, old+headerSyncSeparator))
+	default:
+		return buildSyncedHeader(header, repo)
+	}
+}
+
+// joinSyncedHeader joins the synced part of a header and the rest of it, truncated to the maximum
+// length of a header.
+func joinSyncedHeader(synced, rest string) string {
+	newHeader := synced
+	if rest != "" {
+		newHeader += headerSyncSeparator + rest
+	}
+
+	if runes := []rune(newHeader); len(runes) > model.CHANNEL_HEADER_MAX_RUNES {
+		newHeader = string(runes[:model.CHANNEL_HEADER_MAX_RUNES])
+	}
+
+	return newHeader
+}
+
+// canEditChannelHeader checks if the bot is allowed to edit the header of a channel.
+func (p *Plugin) canEditChannelHeader(channel *model.Channel) bool {
+	permission := model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES
+	if channel.Type == model.CHANNEL_PRIVATE {
+		permission = model.PERMISSION_MANAGE_PRIVATE_CHANNEL_PROPERTIES
+	}
+
+	return p.API.HasPermissionToChannel(p.BotUserID, channel.Id, permission)
+}
+
+// updateChannelHeader sets the header of a channel if it changed and the bot is allowed to.
+func (p *Plugin) updateChannelHeader(channelID string, buildHeader func(header string) string) error {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return errors.Wrap(appErr, "could not get channel")
+	}
+
+	header := buildHeader(channel.Header)
+	if header == channel.Header {
+		return nil
+	}
+
+	if !p.canEditChannelHeader(channel) {
+		return errors.New("not allowed to edit the channel header")
+	}
+
+	channel.Header = header
+	if _, appErr := p.API.UpdateChannel(channel); appErr != nil {
+		return errors.Wrap(appErr, "could not update channel")
+	}
+
+	return nil
+}
+
+// syncChannelHeader updates the header of a channel with the description and default branch of a repository.
+func (p *Plugin) syncChannelHeader(channelID string, repo *github.Repository) error {
+	return p.updateChannelHeader(channelID, func(header string) string {
+		return buildSyncedHeader(header, repo)
+	})
+}
+
+// removeSyncedChannelHeader removes the part of a channel header synced with a repository.
+func (p *Plugin) removeSyncedChannelHeader(channelID, repository string) error {
+	return p.updateChannelHeader(channelID, func(header string) string {
+		return splitSyncedHeader(header, repository)
+	})
+}
+
+// handleRepositoryEditEvent syncs the channel headers of the subscriptions asking for it when the
+// description or default branch of a repository is edited.
+func (p *Plugin) handleRepositoryEditEvent(event *RepositoryEditEvent) {
+	if !event.syncedFieldsChanged() {
+		return
+	}
+
+	repo := event.GetRepo()
+	previous := event.previousRepo()
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions", "error", err.Error())
+		return
+	}
+
+	for _, sub := range subs.Repositories[repo.GetFullName()] {
+		if !sub.Flags.SyncHeader {
+			continue
+		}
+
+		err := p.updateChannelHeader(sub.ChannelID, func(header string) string {
+			return buildEditedSyncedHeader(header, previous, repo)
+		})
+		if err != nil {
+			p.API.LogWarn("Failed to sync channel header", "channelID", sub.ChannelID, "repo", repo.GetFullName(), "error", err.Error())
+		}
+	}
+}
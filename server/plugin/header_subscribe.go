@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+// fromHeaderFlag subscribes to the repository linked in the channel header instead of a given one.
+const fromHeaderFlag = "from-header"
+
+// reservedGitHubOwners are the first parts of GitHub paths that aren't owners of repositories.
+var reservedGitHubOwners = map[string]bool{
+	"orgs":          true,
+	"settings":      true,
+	"marketplace":   true,
+	"sponsors":      true,
+	"topics":        true,
+	"notifications": true,
+	"apps":          true,
+}
+
+// headerRepositoryRegex matches the links to repositories on the given GitHub base URL.
+func headerRepositoryRegex(baseURL string) *regexp.Regexp {
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	return regexp.MustCompile(`(?i)` + regexp.QuoteMeta(baseURL) + `([\w.-]+)/([\w.-]+)`)
+}
+
+// findHeaderRepositories returns the repositories linked in a channel header, in order and without
+// duplicates, as owner/repo.
+func findHeaderRepositories(header, baseURL string) []string {
+	repositories := []string{}
+	seen := map[string]bool{}
+	for _, match := range headerRepositoryRegex(baseURL).FindAllStringSubmatch(header, -1) {
+		owner := match[1]
+		repo := strings.TrimSuffix(strings.TrimRight(match[2], "."), ".git")
+		if repo == "" || reservedGitHubOwners[strings.ToLower(owner)] {
+			continue
+		}
+
+		fullName := fullNameFromOwnerAndRepo(owner, repo)
+		if seen[strings.ToLower(fullName)] {
+			continue
+		}
+		seen[strings.ToLower(fullName)] = true
+		repositories = append(repositories, fullName)
+	}
+
+	return repositories
+}
+
+// getChannelHeader returns the header of a channel.
+func (p *Plugin) getChannelHeader(channelID string) (string, error) {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return "", errors.Wrap(appErr, "could not get channel")
+	}
+
+	return channel.Header, nil
+}
+
+// formatHeaderRepositoryChoices lists the repositories linked in a channel header with the commands
+// subscribing to each of them.
+func formatHeaderRepositoryChoices(repositories, parameters []string) string {
+	rest := ""
+	if len(parameters) > 0 {
+		rest = " " + strings.Join(parameters, " ")
+	}
+
+	message := "The channel header links to several GitHub repositories. Please pick one:\n"
+	for i, repository := range repositories {
+		message += fmt.Sprintf("* `/github subscriptions add --%s %d%s` for %s\n", fromHeaderFlag, i+1, rest, repository)
+	}
+
+	return message
+}
+
+// handleSubscribesAddFromHeader subscribes the channel to the repository linked in its header. When
+// several repositories are linked, the user picks one by its position in the header.
+func (p *Plugin) handleSubscribesAddFromHeader(c *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	header, err := p.getChannelHeader(args.ChannelId)
+	if err != nil {
+		p.API.LogWarn("Failed to get channel header", "channelID", args.ChannelId, "error", err.Error())
+		return "Encountered an error getting the channel header."
+	}
+
+	repositories := findHeaderRepositories(header, p.getBaseURL())
+	if len(repositories) == 0 {
+		return fmt.Sprintf("No GitHub repository is linked in the channel header. Add a link like `%sowner/repo` to it, or use `/github subscriptions add owner/repo`.", strings.TrimSuffix(p.getBaseURL(), "/")+"/")
+	}
+
+	choice := 0
+	if len(parameters) > 0 {
+		if index, err := strconv.Atoi(parameters[0]); err == nil {
+			if index < 1 || index > len(repositories) {
+				return fmt.Sprintf("Invalid choice %d. The channel header links to %d GitHub %s.", index, len(repositories), pluralize(len(repositories), "repository", "repositories"))
+			}
+			choice = index
+			parameters = parameters[1:]
+		}
+	}
+
+	if choice == 0 {
+		if len(repositories) > 1 {
+			return formatHeaderRepositoryChoices(repositories, parameters)
+		}
+		choice = 1
+	}
+
+	repository := repositories[choice-1]
+	message := p.handleSubscribesAdd(c, args, append([]string{repository}, parameters...), userInfo)
+
+	return fmt.Sprintf("Found %s in the channel header.\n%s", repository, message)
+}
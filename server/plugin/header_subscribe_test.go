@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindHeaderRepositories(t *testing.T) {
+	for name, tc := range map[string]struct {
+		header   string
+		baseURL  string
+		expected []string
+	}{
+		"no link": {
+			header:   "Team channel, see owner/repo",
+			baseURL:  "https://github.com/",
+			expected: []string{},
+		},
+		"plain link": {
+			header:   "Code: https://github.com/owner/repo",
+			baseURL:  "https://github.com/",
+			expected: []string{"owner/repo"},
+		},
+		"markdown link and end of sentence": {
+			header:   "[Repository](https://github.com/owner/repo.js) | See https://github.com/owner/other.",
+			baseURL:  "https://github.com/",
+			expected: []string{"owner/repo.js", "owner/other"},
+		},
+		"links to pages of a repository": {
+			header:   "https://github.com/owner/repo/issues and https://github.com/Owner/Repo/pulls",
+			baseURL:  "https://github.com/",
+			expected: []string{"owner/repo"},
+		},
+		"clone url": {
+			header:   "git clone https://github.com/owner/repo.git",
+			baseURL:  "https://github.com/",
+			expected: []string{"owner/repo"},
+		},
+		"owner and reserved paths": {
+			header:   "https://github.com/owner https://github.com/orgs/owner/projects",
+			baseURL:  "https://github.com/",
+			expected: []string{},
+		},
+		"enterprise": {
+			header:   "https://github.com/owner/public https://git.example.com/team/service",
+			baseURL:  "https://git.example.com",
+			expected: []string{"team/service"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, findHeaderRepositories(tc.header, tc.baseURL))
+		})
+	}
+}
+
+func TestHandleSubscribesAddFromHeader(t *testing.T) {
+	subscribeFromHeader := func(header string, parameters ...string) string {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{})
+		api := &plugintest.API{}
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Header: header}, nil)
+		p.SetAPI(api)
+
+		return p.handleSubscribesAdd(nil, &model.CommandArgs{UserId: "userID", ChannelId: "channelID"}, append([]string{"--from-header"}, parameters...), &GitHubUserInfo{UserID: "userID"})
+	}
+
+	t.Run("no link", func(t *testing.T) {
+		message := subscribeFromHeader("Team channel")
+		assert.Equal(t, "No GitHub repository is linked in the channel header. Add a link like `https://github.com/owner/repo` to it, or use `/github subscriptions add owner/repo`.", message)
+	})
+
+	t.Run("several links", func(t *testing.T) {
+		message := subscribeFromHeader("https://github.com/owner/web | https://github.com/owner/api", "pulls", "--render-style", "collapsed")
+		assert.Equal(t, "The channel header links to several GitHub repositories. Please pick one:\n"+
+			"* `/github subscriptions add --from-header 1 pulls --render-style collapsed` for owner/web\n"+
+			"* `/github subscriptions add --from-header 2 pulls --render-style collapsed` for owner/api\n", message)
+	})
+
+	t.Run("invalid choice", func(t *testing.T) {
+		message := subscribeFromHeader("https://github.com/owner/web | https://github.com/owner/api", "3")
+		assert.Equal(t, "Invalid choice 3. The channel header links to 2 GitHub repositories.", message)
+	})
+
+	t.Run("invalid features of the chosen repository", func(t *testing.T) {
+		message := subscribeFromHeader("https://github.com/owner/web | https://github.com/owner/api", "2", "unknown")
+		assert.Contains(t, message, "Found owner/api in the channel header.\n")
+	})
+}
@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSyncedHeader(t *testing.T) {
+	repo := &github.Repository{
+		FullName:      github.String("owner/repo"),
+		Description:   github.String("A repository"),
+		DefaultBranch: github.String("main"),
+	}
+	synced := "owner/repo — A repository | default branch: main"
+
+	for name, tc := range map[string]struct {
+		header   string
+		expected string
+	}{
+		"empty header": {
+			header:   "",
+			expected: synced,
+		},
+		"existing header is preserved": {
+			header:   "Welcome!",
+			expected: synced + " || Welcome!",
+		},
+		"synced part is replaced": {
+			header:   "owner/repo — Old description | default branch: master || Welcome!",
+			expected: synced + " || Welcome!",
+		},
+		"synced part without rest is replaced": {
+			header:   "owner/repo — Old description | default branch: master",
+			expected: synced,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, buildSyncedHeader(tc.header, repo))
+		})
+	}
+
+	t.Run("header is truncated", func(t *testing.T) {
+		header := buildSyncedHeader(strings.Repeat("a", model.CHANNEL_HEADER_MAX_RUNES), repo)
+		assert.Len(t, []rune(header), model.CHANNEL_HEADER_MAX_RUNES)
+		assert.True(t, strings.HasPrefix(header, synced))
+	})
+}
+
+func TestBuildEditedSyncedHeader(t *testing.T) {
+	previous := &github.Repository{
+		FullName:      github.String("owner/repo"),
+		Description:   github.String("Old || description"),
+		DefaultBranch: github.String("master"),
+	}
+	repo := &github.Repository{
+		FullName:      github.String("owner/repo"),
+		Description:   github.String("A repository"),
+		DefaultBranch: github.String("main"),
+	}
+	synced := "owner/repo — A repository | default branch: main"
+
+	assert.Equal(t, synced, buildEditedSyncedHeader("owner/repo — Old || description | default branch: master", previous, repo))
+	assert.Equal(t, synced+" || Welcome!", buildEditedSyncedHeader("owner/repo — Old || description | default branch: master || Welcome!", previous, repo))
+	// Headers synced before other edits are rebuilt as usual
+	assert.Equal(t, synced+" || Welcome!", buildEditedSyncedHeader("owner/repo — Older | default branch: master || Welcome!", previous, repo))
+	assert.Equal(t, synced+" || Welcome!", buildEditedSyncedHeader("Welcome!", previous, repo))
+}
+
+func TestSplitSyncedHeader(t *testing.T) {
+	assert.Equal(t, "Welcome!", splitSyncedHeader("owner/repo — desc | default branch: main || Welcome!", "owner/repo"))
+	assert.Equal(t, "", splitSyncedHeader("owner/repo — desc | default branch: main", "owner/repo"))
+	assert.Equal(t, "other/repo — desc || Welcome!", splitSyncedHeader("other/repo — desc || Welcome!", "owner/repo"))
+}
+
+func TestSyncChannelHeader(t *testing.T) {
+	repo := &github.Repository{
+		FullName:      github.String("owner/repo"),
+		Description:   github.String("A repository"),
+		DefaultBranch: github.String("main"),
+	}
+
+	t.Run("header updated", func(t *testing.T) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Type: model.CHANNEL_OPEN, Header: "Welcome!"}, nil)
+		api.On("HasPermissionToChannel", "botID", "channelID", model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES).Return(true)
+		api.On("UpdateChannel", mock.MatchedBy(func(channel *model.Channel) bool {
+			return channel.Header == "owner/repo — A repository | default branch: main || Welcome!"
+		})).Return(&model.Channel{}, nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		assert.NoError(t, p.syncChannelHeader("channelID", repo))
+	})
+
+	t.Run("header unchanged", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Header: "owner/repo — A repository | default branch: main"}, nil)
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		assert.NoError(t, p.syncChannelHeader("channelID", repo))
+	})
+
+	t.Run("bot not allowed", func(t *testing.T) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Type: model.CHANNEL_PRIVATE}, nil)
+		api.On("HasPermissionToChannel", "botID", "channelID", model.PERMISSION_MANAGE_PRIVATE_CHANNEL_PROPERTIES).Return(false)
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		assert.Error(t, p.syncChannelHeader("channelID", repo))
+	})
+}
+
+func TestHandleRepositoryEditEvent(t *testing.T) {
+	subs, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "syncedID", Repository: "owner/repo", Flags: SubscriptionFlags{SyncHeader: true}},
+			{ChannelID: "otherID", Repository: "owner/repo"},
+		},
+	}})
+	require.NoError(t, err)
+
+	setup := func() (*Plugin, *plugintest.API) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		store[SubscriptionsKey] = subs
+		p.SetAPI(api)
+		return p, api
+	}
+
+	edit := func(changes string) *RepositoryEditEvent {
+		event, err := parseRepositoryEditEvent([]byte(`{"action": "edited", "repository": {"full_name": "owner/repo", "description": "A repository", "default_branch": "main"}, "changes": ` + changes + `}`))
+		require.NoError(t, err)
+		return event
+	}
+
+	t.Run("description changed", func(t *testing.T) {
+		p, api := setup()
+		api.On("GetChannel", "syncedID").Return(&model.Channel{Id: "syncedID", Type: model.CHANNEL_OPEN, Header: "owner/repo — Old || description | default branch: main || Welcome!"}, nil)
+		api.On("HasPermissionToChannel", "botID", "syncedID", model.PERMISSION_MANAGE_PUBLIC_CHANNEL_PROPERTIES).Return(true)
+		api.On("UpdateChannel", mock.MatchedBy(func(channel *model.Channel) bool {
+			return channel.Header == "owner/repo — A repository | default branch: main || Welcome!"
+		})).Return(&model.Channel{}, nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.handleRepositoryEditEvent(edit(`{"description": {"from": "Old || description"}}`))
+	})
+
+	t.Run("other fields changed", func(t *testing.T) {
+		p, api := setup()
+
+		p.handleRepositoryEditEvent(edit(`{"homepage": {"from": "https://example.com"}}`))
+		api.AssertNotCalled(t, "GetChannel", mock.Anything)
+	})
+}
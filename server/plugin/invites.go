@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	settingInvites = "invites"
+
+	invitesKey = "_invites"
+	// inviteInterval is the minimum delay between two invites to connect sent to the same user.
+	inviteInterval = 30 * 24 * time.Hour
+)
+
+// inviteState records the invites to connect their GitHub account sent to a user who isn't connected.
+type inviteState struct {
+	OptedOut      bool
+	LastInvitedAt time.Time
+}
+
+func (p *Plugin) getInviteState(userID string) ([]byte, *inviteState, error) {
+	value, appErr := p.API.KVGet(userID + invitesKey)
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "could not get invite state from KV store")
+	}
+
+	state := &inviteState{}
+	if value == nil {
+		return nil, state, nil
+	}
+
+	if err := json.Unmarshal(value, state); err != nil {
+		return nil, nil, errors.Wrap(err, "could not unmarshal invite state")
+	}
+
+	return value, state, nil
+}
+
+// storeInviteState stores the invite state of a user, unless it changed since oldValue was read.
+func (p *Plugin) storeInviteState(userID string, oldValue []byte, state *inviteState) (bool, error) {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return false, errors.Wrap(err, "could not marshal invite state")
+	}
+
+	stored, appErr := p.API.KVCompareAndSet(userID+invitesKey, oldValue, value)
+	if appErr != nil {
+		return false, errors.Wrap(appErr, "could not store invite state in KV store")
+	}
+
+	return stored, nil
+}
+
+// inviteUnconnectedUser invites the Mattermost user whose username is a GitHub login without a
+// mapping to connect their account, when InviteUnconnectedUsers is enabled. trigger describes the
+// GitHub event mentioning the login, e.g. "@octocat mentioned you in ...". Users are invited at most
+// once every inviteInterval, and never after opting out.
+func (p *Plugin) inviteUnconnectedUser(login, trigger string, now time.Time) {
+	if !p.getConfiguration().InviteUnconnectedUsers || login == "" {
+		return
+	}
+
+	// Only exact matches are invited, Mattermost usernames being lowercase
+	user, appErr := p.API.GetUserByUsername(strings.ToLower(login))
+	if appErr != nil || user == nil || user.IsBot || user.DeleteAt != 0 {
+		return
+	}
+
+	if p.isConnected(user.Id) {
+		return
+	}
+	if linked, _ := p.getLinkedUsername(user.Id); linked != nil {
+		return
+	}
+
+	oldValue, state, err := p.getInviteState(user.Id)
+	if err != nil {
+		p.API.LogWarn("Failed to get invite state", "userID", user.Id, "error", err.Error())
+		return
+	}
+	if state.OptedOut || now.Sub(state.LastInvitedAt) < inviteInterval {
+		return
+	}
+
+	state.LastInvitedAt = now
+	// Another event may be inviting the same user at the same time
+	stored, err := p.storeInviteState(user.Id, oldValue, state)
+	if err != nil {
+		p.API.LogWarn("Failed to store invite state", "userID", user.Id, "error", err.Error())
+		return
+	}
+	if !stored {
+		return
+	}
+
+	message := fmt.Sprintf("%s on GitHub. Your Mattermost username matches the GitHub username @%s, "+
+		"so you may want to get notified about your GitHub activity here: use `/github connect` to connect your GitHub account.\n\n"+
+		"You won't be sent this invite more than once a month. Use `/github settings invites off` to stop getting it.", trigger, login)
+	p.CreateBotDMPost(user.Id, message, "custom_git_invite")
+}
+
+// handleInvitesSetting turns the invites to connect on or off for a user. It doesn't require the
+// user to be connected, as only users who aren't get invited.
+func (p *Plugin) handleInvitesSetting(userID string, parameters []string) string {
+	if len(parameters) != 1 {
+		return "Please specify both a setting and value. Use `/github help` for more usage information."
+	}
+
+	var optedOut bool
+	switch parameters[0] {
+	case settingOn:
+		optedOut = false
+	case settingOff:
+		optedOut = true
+	default:
+		return "Invalid value. Accepted values are: \"on\" or \"off\"."
+	}
+
+	oldValue, state, err := p.getInviteState(userID)
+	if err != nil {
+		p.API.LogWarn("Failed to get invite state", "userID", userID, "error", err.Error())
+		return "Failed to store settings"
+	}
+
+	state.OptedOut = optedOut
+	stored, err := p.storeInviteState(userID, oldValue, state)
+	if err != nil || !stored {
+		if err != nil {
+			p.API.LogWarn("Failed to store invite state", "userID", userID, "error", err.Error())
+		}
+		return "Failed to store settings"
+	}
+
+	return "Settings updated."
+}
+
+// isInvitesSetting checks if a settings command changes the invites setting.
+func isInvitesSetting(action string, parameters []string) bool {
+	return action == "settings" && len(parameters) > 0 && parameters[0] == settingInvites
+}
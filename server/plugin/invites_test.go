@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteUnconnectedUser(t *testing.T) {
+	now := time.Date(2021, 3, 5, 12, 0, 0, 0, time.UTC)
+	trigger := "@bob mentioned you in a comment on [owner/repo#12](https://github.com/owner/repo/issues/12#issuecomment-1)"
+
+	setup := func(enabled bool) (*Plugin, map[string][]byte, *[]*model.Post) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{InviteUnconnectedUsers: enabled})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		api.On("GetUserByUsername", "alice").Return(&model.User{Id: "aliceID", Username: "alice"}, nil)
+		api.On("GetUserByUsername", "connected").Return(&model.User{Id: "connectedID", Username: "connected"}, nil)
+		api.On("GetUserByUsername", "botuser").Return(&model.User{Id: "botuserID", Username: "botuser", IsBot: true}, nil)
+		api.On("GetUserByUsername", mock.AnythingOfType("string")).Return(nil, &model.AppError{Message: "not found"})
+		api.On("GetDirectChannel", mock.AnythingOfType("string"), "botID").Return(func(userID, botID string) *model.Channel {
+			return &model.Channel{Id: userID + "DM"}
+		}, nil)
+		posts := &[]*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			*posts = append(*posts, post)
+			return post
+		}, nil)
+		p.SetAPI(api)
+		store["connectedID"+githubTokenKey] = []byte(`{}`)
+
+		return p, store, posts
+	}
+
+	t.Run("invited at most once a month", func(t *testing.T) {
+		p, store, posts := setup(true)
+
+		p.inviteUnconnectedUser("Alice", trigger, now)
+		require.Len(t, *posts, 1)
+		post := (*posts)[0]
+		assert.Equal(t, "aliceIDDM", post.ChannelId)
+		assert.Equal(t, "custom_git_invite", post.Type)
+		assert.Contains(t, post.Message, trigger+" on GitHub.")
+		assert.Contains(t, post.Message, "`/github connect`")
+		assert.Contains(t, post.Message, "`/github settings invites off`")
+		assert.NotNil(t, store["aliceID"+invitesKey])
+
+		p.inviteUnconnectedUser("alice", trigger, now.Add(29*24*time.Hour))
+		assert.Len(t, *posts, 1)
+
+		p.inviteUnconnectedUser("alice", trigger, now.Add(30*24*time.Hour))
+		assert.Len(t, *posts, 2)
+	})
+
+	t.Run("opted out", func(t *testing.T) {
+		p, _, posts := setup(true)
+
+		assert.Equal(t, "Settings updated.", p.handleInvitesSetting("aliceID", []string{settingOff}))
+		p.inviteUnconnectedUser("alice", trigger, now)
+		assert.Empty(t, *posts)
+
+		assert.Equal(t, "Settings updated.", p.handleInvitesSetting("aliceID", []string{settingOn}))
+		p.inviteUnconnectedUser("alice", trigger, now)
+		assert.Len(t, *posts, 1)
+
+		assert.Equal(t, "Invalid value. Accepted values are: \"on\" or \"off\".", p.handleInvitesSetting("aliceID", []string{"maybe"}))
+	})
+
+	t.Run("not invited", func(t *testing.T) {
+		p, _, posts := setup(true)
+
+		p.inviteUnconnectedUser("connected", trigger, now)
+		p.inviteUnconnectedUser("botuser", trigger, now)
+		p.inviteUnconnectedUser("stranger", trigger, now)
+		assert.Empty(t, *posts)
+
+		p, _, posts = setup(false)
+		p.inviteUnconnectedUser("alice", trigger, now)
+		assert.Empty(t, *posts)
+	})
+}
+
+func TestIsInvitesSetting(t *testing.T) {
+	assert.True(t, isInvitesSetting("settings", []string{"invites", "off"}))
+	assert.False(t, isInvitesSetting("settings", []string{"notifications", "off"}))
+	assert.False(t, isInvitesSetting("settings", nil))
+	assert.False(t, isInvitesSetting("subscriptions", []string{"invites"}))
+}
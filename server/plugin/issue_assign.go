@@ -0,0 +1,179 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// assigneeMe stands for the GitHub account of the user running the assign and unassign commands.
+const assigneeMe = "me"
+
+func (p *Plugin) handleAssign(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	return p.updateAssignment(args, parameters, userInfo, false)
+}
+
+func (p *Plugin) handleUnassign(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	return p.updateAssignment(args, parameters, userInfo, true)
+}
+
+// resolveAssignee returns the GitHub login to assign or unassign, which is the user's own by default.
+// Mattermost @mentions of connected users are resolved to their GitHub login.
+func (p *Plugin) resolveAssignee(name string, userInfo *GitHubUserInfo) string {
+	if name == "" || strings.EqualFold(name, assigneeMe) {
+		return userInfo.GitHubUsername
+	}
+
+	usernames := p.resolveGitHubUsernames([]string{name})
+	if len(usernames) == 0 {
+		return userInfo.GitHubUsername
+	}
+
+	return usernames[0]
+}
+
+// updateAssignment assigns a user to an issue or pull request, or unassigns them, and confirms it
+// with the resulting assignees.
+func (p *Plugin) updateAssignment(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo, remove bool) string {
+	command := "assign"
+	if remove {
+		command = "unassign"
+	}
+	if len(parameters) == 0 || len(parameters) > 2 {
+		return fmt.Sprintf("Please use `/github %s owner/repo#123 [@github-login|me]`.", command)
+	}
+
+	owner, repo, number, err := parseIssueReference(parameters[0], p.getChannelDefaultRepo(args.ChannelId))
+	if err != nil {
+		return err.Error()
+	}
+
+	name := ""
+	if len(parameters) == 2 {
+		name = parameters[1]
+	}
+	assignee := p.resolveAssignee(name, userInfo)
+
+	githubClient, err := p.getGithubClientFor(userInfo, githubWrite)
+	if err != nil {
+		return readOnlyModeMessage
+	}
+
+	ctx := context.Background()
+	fullName := fullNameFromOwnerAndRepo(owner, repo)
+	reference := fmt.Sprintf("%s#%d", fullName, number)
+
+	var issue *github.Issue
+	if remove {
+		issue, _, err = githubClient.Issues.RemoveAssignees(ctx, owner, repo, number, []string{assignee})
+	} else {
+		// GitHub silently ignores the assignees who can't be assigned
+		assignable, _, checkErr := githubClient.Issues.IsAssignee(ctx, owner, repo, assignee)
+		if checkErr != nil {
+			p.API.LogWarn("Failed to check assignee", "repo", fullName, "assignee", assignee, "error", checkErr.Error())
+			return newAPIError(checkErr, fmt.Sprintf("Failed to check if `%s` can be assigned in %s.", assignee, fullName)).Message
+		}
+		if !assignable {
+			return fmt.Sprintf("`%s` can't be assigned in %s. Only users with push access to the repository, and the authors and commenters of an issue, can be assigned.", assignee, fullName)
+		}
+
+		issue, _, err = githubClient.Issues.AddAssignees(ctx, owner, repo, number, []string{assignee})
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to update assignees", "repo", fullName, "number", number, "error", err.Error())
+		return assignmentErrorMessage(err, command, assignee, reference)
+	}
+
+	// Everyone following the thread of a notification about the issue sees the change there
+	if root := p.getIssueThreadRoot(args.RootId, fullName, number); root != nil {
+		actor := args.UserId
+		if user, appErr := p.API.GetUser(args.UserId); appErr == nil {
+			actor = user.Username
+		}
+
+		post := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: root.ChannelId,
+			RootId:    root.Id,
+			Message:   formatAssignment(issue, fmt.Sprintf("@%s %sed", actor, command), assignee, reference, remove),
+		}
+		_, appErr := p.API.CreatePost(post)
+		if appErr == nil {
+			return ""
+		}
+		p.API.LogWarn("Failed to post in issue thread", "rootID", root.Id, "error", appErr.Error())
+	}
+
+	action := "Assigned"
+	if remove {
+		action = "Unassigned"
+	}
+
+	return formatAssignment(issue, action, assignee, reference, remove)
+}
+
+// assignmentErrorMessage describes an error of GitHub updating the assignees of an issue.
+func assignmentErrorMessage(err error, command, assignee, reference string) string {
+	if errResp, ok := err.(*github.ErrorResponse); ok && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnprocessableEntity {
+		message := errResp.Message
+		for _, e := range errResp.Errors {
+			if e.Message != "" {
+				message = e.Message
+			}
+		}
+		return fmt.Sprintf("GitHub couldn't %s `%s` on %s: %s", command, assignee, reference, message)
+	}
+
+	return newAPIError(err, fmt.Sprintf("Failed to %s `%s` on %s.", command, assignee, reference)).Message
+}
+
+func formatAssignment(issue *github.Issue, action, assignee, reference string, remove bool) string {
+	preposition := "to"
+	if remove {
+		preposition = "from"
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "%s `%s` %s [%s](%s).\n", action, assignee, preposition, reference, issue.GetHTMLURL())
+
+	assignees := make([]string, 0, len(issue.Assignees))
+	for _, user := range issue.Assignees {
+		assignees = append(assignees, user.GetLogin())
+	}
+	if len(assignees) == 0 {
+		message.WriteString("It has no assignees.")
+	} else {
+		fmt.Fprintf(&message, "Assignees: %s.", formatGitHubUsernames(assignees))
+	}
+
+	return message.String()
+}
+
+// getIssueThreadRoot returns the root of the thread a command was run in if it's a notification
+// of the plugin about an issue or pull request, or nil otherwise.
+func (p *Plugin) getIssueThreadRoot(rootID, repository string, number int) *model.Post {
+	if rootID == "" {
+		return nil
+	}
+
+	root, appErr := p.API.GetPost(rootID)
+	if appErr != nil || root.UserId != p.BotUserID {
+		return nil
+	}
+
+	postRepository, _ := root.GetProp(postPropRepository).(string)
+	objectType, _ := root.GetProp(postPropObjectType).(string)
+	objectID, _ := root.GetProp(postPropObjectID).(string)
+	if !strings.EqualFold(postRepository, repository) || objectID != strconv.Itoa(number) ||
+		(objectType != objectTypeIssue && objectType != objectTypePullRequest) {
+		return nil
+	}
+
+	return root
+}
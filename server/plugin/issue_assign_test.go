@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestParseIssueReference(t *testing.T) {
+	owner, repo, number, err := parseIssueReference("#7", "owner/repo")
+	require.NoError(t, err)
+	assert.Equal(t, "owner", owner)
+	assert.Equal(t, "repo", repo)
+	assert.Equal(t, 7, number)
+
+	_, _, _, err = parseIssueReference("#7", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "issue")
+}
+
+func TestUpdateAssignment(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo/assignees/alice", "/api/v3/repos/owner/repo/assignees/bob":
+			w.WriteHeader(http.StatusNoContent)
+		case "/api/v3/repos/owner/repo/issues/3/assignees":
+			if r.Method == http.MethodDelete {
+				fmt.Fprint(w, `{"number": 3, "html_url": "https://github.com/owner/repo/issues/3", "assignees": []}`)
+				return
+			}
+			fmt.Fprint(w, `{"number": 3, "html_url": "https://github.com/owner/repo/issues/3", "assignees": [{"login": "bob"}, {"login": "alice"}]}`)
+		case "/api/v3/repos/owner/repo/issues/4/assignees":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message": "Validation Failed", "errors": [{"resource": "Issue", "code": "custom", "message": "Issues can have at most 10 assignees"}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func(settings *UserSettings) (*Plugin, *plugintest.API, *GitHubUserInfo) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+			"owner/repo": {{ChannelID: "channelID", Repository: "owner/repo", Features: "issues"}},
+		}})
+		require.NoError(t, err)
+		store[SubscriptionsKey] = subscriptions
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		userInfo := &GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice", Settings: settings}
+		return p, api, userInfo
+	}
+
+	args := &model.CommandArgs{UserId: "userID", ChannelId: "channelID"}
+
+	t.Run("assign me", func(t *testing.T) {
+		p, _, userInfo := setup(&UserSettings{})
+		requests = nil
+
+		message := p.handleAssign(nil, args, []string{"#3", "me"}, userInfo)
+		assert.Equal(t, "Assigned `alice` to [owner/repo#3](https://github.com/owner/repo/issues/3).\nAssignees: `bob`, `alice`.", message)
+		require.Len(t, requests, 2)
+		assert.Equal(t, "GET /api/v3/repos/owner/repo/assignees/alice ", requests[0])
+		assert.Equal(t, "POST /api/v3/repos/owner/repo/issues/3/assignees {\"assignees\":[\"alice\"]}\n", requests[1])
+	})
+
+	t.Run("not assignable", func(t *testing.T) {
+		p, _, userInfo := setup(&UserSettings{})
+		requests = nil
+
+		message := p.handleAssign(nil, args, []string{"owner/repo#3", "stranger"}, userInfo)
+		assert.Equal(t, "`stranger` can't be assigned in owner/repo. Only users with push access to the repository, and the authors and commenters of an issue, can be assigned.", message)
+		assert.Len(t, requests, 1)
+	})
+
+	t.Run("error of GitHub", func(t *testing.T) {
+		p, _, userInfo := setup(&UserSettings{})
+
+		message := p.handleAssign(nil, args, []string{"owner/repo#4"}, userInfo)
+		assert.Equal(t, "GitHub couldn't assign `alice` on owner/repo#4: Issues can have at most 10 assignees", message)
+	})
+
+	t.Run("unassign", func(t *testing.T) {
+		p, _, userInfo := setup(&UserSettings{})
+		requests = nil
+
+		message := p.handleUnassign(nil, args, []string{"owner/repo#3"}, userInfo)
+		assert.Equal(t, "Unassigned `alice` from [owner/repo#3](https://github.com/owner/repo/issues/3).\nIt has no assignees.", message)
+		require.Len(t, requests, 1)
+		assert.Equal(t, "DELETE /api/v3/repos/owner/repo/issues/3/assignees {\"assignees\":[\"alice\"]}\n", requests[0])
+	})
+
+	t.Run("in a notification thread", func(t *testing.T) {
+		p, api, userInfo := setup(&UserSettings{})
+		root := &model.Post{Id: "rootID", UserId: "botID", ChannelId: "channelID"}
+		root.AddProp(postPropRepository, "owner/repo")
+		root.AddProp(postPropObjectType, objectTypeIssue)
+		root.AddProp(postPropObjectID, "3")
+		api.On("GetPost", "rootID").Return(root, nil)
+		api.On("GetUser", "userID").Return(&model.User{Id: "userID", Username: "mmalice"}, nil)
+		var posted *model.Post
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			posted = post
+			return post
+		}, nil)
+
+		threadArgs := *args
+		threadArgs.RootId = "rootID"
+		message := p.handleAssign(nil, &threadArgs, []string{"#3", "bob"}, userInfo)
+		assert.Empty(t, message)
+		require.NotNil(t, posted)
+		assert.Equal(t, "rootID", posted.RootId)
+		assert.Equal(t, "botID", posted.UserId)
+		assert.Equal(t, "@mmalice assigned `bob` to [owner/repo#3](https://github.com/owner/repo/issues/3).\nAssignees: `bob`, `alice`.", posted.Message)
+
+		// Threads about other issues get an ephemeral confirmation
+		posted = nil
+		root.AddProp(postPropObjectID, "5")
+		message = p.handleAssign(nil, &threadArgs, []string{"#3", "bob"}, userInfo)
+		assert.Equal(t, "Assigned `bob` to [owner/repo#3](https://github.com/owner/repo/issues/3).\nAssignees: `bob`, `alice`.", message)
+		assert.Nil(t, posted)
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		p, _, userInfo := setup(&UserSettings{ReadOnlyMode: true})
+		requests = nil
+
+		assert.Equal(t, readOnlyModeMessage, p.handleAssign(nil, args, []string{"#3"}, userInfo))
+		assert.Empty(t, requests)
+	})
+
+	t.Run("invalid reference", func(t *testing.T) {
+		p, _, userInfo := setup(&UserSettings{})
+
+		assert.Equal(t, "Please use `/github unassign owner/repo#123 [@github-login|me]`.", p.handleUnassign(nil, args, nil, userInfo))
+		assert.Contains(t, p.handleAssign(nil, args, []string{"owner/repo#abc"}, userInfo), "issue")
+	})
+}
@@ -0,0 +1,285 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	issueExportFormatCSV      = "csv"
+	issueExportFormatMarkdown = "md"
+
+	// defaultIssueExportMaxIssues is used when no maximum number of exported issues is configured.
+	defaultIssueExportMaxIssues = 500
+	issueExportPageSize         = 100
+	// issueExportMaxMessageRunes is the size above which a markdown export is uploaded as a file
+	// rather than posted. It's the post size limit every server supports.
+	issueExportMaxMessageRunes = model.POST_MESSAGE_MAX_RUNES_V1
+)
+
+// errIssuesDisabled is returned when exporting the issues of a repository that has them disabled.
+var errIssuesDisabled = errors.New("issues are disabled")
+
+// issueExportOptions are the arguments of /github issue export.
+type issueExportOptions struct {
+	Owner  string
+	Repo   string
+	Labels []string
+	Format string
+}
+
+// parseIssueExportOptions parses the arguments of /github issue export. The repository defaults to
+// the given one when omitted.
+func parseIssueExportOptions(parameters []string, defaultRepo string) (*issueExportOptions, error) {
+	usage := "Please use `/github issue export [owner/repo] [--label bug] [--format csv|md]`."
+
+	options := &issueExportOptions{Labels: []string{}, Format: issueExportFormatCSV}
+	repository := ""
+	for i := 0; i < len(parameters); i++ {
+		parameter := parameters[i]
+		if !isFlag(parameter) {
+			if repository != "" {
+				return nil, errors.New(usage)
+			}
+			repository = parameter
+			continue
+		}
+
+		if i+1 >= len(parameters) {
+			return nil, errors.New(usage)
+		}
+		value := parameters[i+1]
+		i++
+
+		switch parseFlag(parameter) {
+		case "label":
+			for _, label := range strings.Split(value, ",") {
+				if label = strings.TrimSpace(label); label != "" {
+					options.Labels = append(options.Labels, label)
+				}
+			}
+		case "format":
+			if value != issueExportFormatCSV && value != issueExportFormatMarkdown {
+				return nil, errors.Errorf("Unknown format `%s`. Please use `csv` or `md`.", value)
+			}
+			options.Format = value
+		default:
+			return nil, errors.Errorf("Unknown flag `%s`. %s", parameter, usage)
+		}
+	}
+
+	if repository == "" {
+		repository = defaultRepo
+	}
+	if repository == "" {
+		return nil, errors.New("Please provide the repository, e.g. `owner/repo`. It can be omitted in channels subscribed to a single repository.")
+	}
+
+	owner, repo, err := parseRepo(repository)
+	if err != nil || owner == "" || repo == "" {
+		return nil, errors.Errorf("Invalid repository `%s`. Use `owner/repo`.", repository)
+	}
+	options.Owner = owner
+	options.Repo = repo
+
+	return options, nil
+}
+
+// listOpenIssues pages through the open issues of a repository, leaving out pull requests. At most
+// maxIssues issues are returned, the second return value being true if some were left out.
+func listOpenIssues(ctx context.Context, githubClient *github.Client, owner, repo string, labels []string, maxIssues int) ([]*github.Issue, bool, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      labels,
+		ListOptions: github.ListOptions{PerPage: issueExportPageSize},
+	}
+
+	issues := []*github.Issue{}
+	for {
+		page, resp, err := githubClient.Issues.ListByRepo(ctx, owner, repo, opts)
+		if err != nil {
+			var errResp *github.ErrorResponse
+			if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusGone {
+				return nil, false, errIssuesDisabled
+			}
+			return nil, false, err
+		}
+
+		for _, issue := range page {
+			if issue.IsPullRequest() {
+				continue
+			}
+			if len(issues) == maxIssues {
+				return issues, true, nil
+			}
+			issues = append(issues, issue)
+		}
+
+		if resp.NextPage == 0 {
+			return issues, false, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// formatIssueAge formats the time since an issue was opened in days, e.g. 12d.
+func formatIssueAge(createdAt, now time.Time) string {
+	return fmt.Sprintf("%dd", int(now.Sub(createdAt).Hours()/24))
+}
+
+func issueAssigneeLogins(issue *github.Issue) []string {
+	logins := []string{}
+	for _, assignee := range issue.Assignees {
+		logins = append(logins, assignee.GetLogin())
+	}
+
+	return logins
+}
+
+// generateIssuesCSV writes issues as CSV, with a header row.
+func generateIssuesCSV(issues []*github.Issue, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write([]string{"number", "title", "labels", "assignees", "age", "comments", "url"}); err != nil {
+		return nil, err
+	}
+
+	for _, issue := range issues {
+		if err := writer.Write([]string{
+			strconv.Itoa(issue.GetNumber()),
+			issue.GetTitle(),
+			strings.Join(issueLabelNames(issue.Labels), ";"),
+			strings.Join(issueAssigneeLogins(issue), ";"),
+			formatIssueAge(issue.GetCreatedAt(), now),
+			strconv.Itoa(issue.GetComments()),
+			issue.GetHTMLURL(),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// escapeMarkdownTableCell keeps text from breaking out of the cell of a markdown table.
+func escapeMarkdownTableCell(text string) string {
+	text = strings.NewReplacer("\r", "", "\n", " ").Replace(text)
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+// generateIssuesMarkdown writes issues as a markdown table.
+func generateIssuesMarkdown(issues []*github.Issue, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("| # | Title | Labels | Assignees | Age | Comments |\n")
+	b.WriteString("|:--|:------|:-------|:----------|:----|:---------|\n")
+
+	for _, issue := range issues {
+		fmt.Fprintf(&b, "| [%d](%s) | %s | %s | %s | %s | %d |\n",
+			issue.GetNumber(),
+			issue.GetHTMLURL(),
+			escapeMarkdownTableCell(issue.GetTitle()),
+			escapeMarkdownTableCell(strings.Join(issueLabelNames(issue.Labels), ", ")),
+			strings.Join(issueAssigneeLogins(issue), ", "),
+			formatIssueAge(issue.GetCreatedAt(), now),
+			issue.GetComments(),
+		)
+	}
+
+	return b.String()
+}
+
+// exportRepoIssues posts the open issues of a repository in a channel, as a CSV file or a markdown
+// table. A table too large for a post is uploaded as a file instead.
+func (p *Plugin) exportRepoIssues(ctx context.Context, githubClient *github.Client, channelID string, options *issueExportOptions, now time.Time) error {
+	maxIssues := p.getConfiguration().getIssueExportMaxIssues()
+	issues, truncated, err := listOpenIssues(ctx, githubClient, options.Owner, options.Repo, options.Labels, maxIssues)
+	if err != nil {
+		return err
+	}
+
+	fullName := fullNameFromOwnerAndRepo(options.Owner, options.Repo)
+	message := fmt.Sprintf("Export of the %d open %s of %s", len(issues), pluralize(len(issues), "issue", "issues"), fullName)
+	if len(options.Labels) > 0 {
+		message += fmt.Sprintf(" labeled `%s`", strings.Join(options.Labels, "`, `"))
+	}
+	message += "."
+	if truncated {
+		message += fmt.Sprintf(" The export was truncated to the first %d issues.", maxIssues)
+	}
+
+	var data []byte
+	extension := options.Format
+	if options.Format == issueExportFormatMarkdown {
+		table := generateIssuesMarkdown(issues, now)
+		tableMessage := message + "\n\n" + table
+		if len([]rune(tableMessage)) <= issueExportMaxMessageRunes {
+			return p.createExportPost(channelID, tableMessage, nil)
+		}
+		data = []byte(table)
+	} else {
+		data, err = generateIssuesCSV(issues, now)
+		if err != nil {
+			return errors.Wrap(err, "could not generate CSV")
+		}
+	}
+
+	filename := fmt.Sprintf("%s-%s-issues-%s.%s", options.Owner, options.Repo, now.Format("2006-01-02"), extension)
+	fileInfo, appErr := p.API.UploadFile(data, channelID, filename)
+	if appErr != nil {
+		return errors.Wrap(appErr, "could not upload export")
+	}
+
+	return p.createExportPost(channelID, message, []string{fileInfo.Id})
+}
+
+func (p *Plugin) createExportPost(channelID, message string, fileIDs []string) error {
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: channelID,
+		Message:   message,
+		FileIds:   fileIDs,
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		return errors.Wrap(appErr, "could not create post")
+	}
+
+	return nil
+}
+
+func (p *Plugin) handleIssueExport(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	options, err := parseIssueExportOptions(parameters, p.getChannelDefaultRepo(args.ChannelId))
+	if err != nil {
+		return err.Error()
+	}
+
+	fullName := fullNameFromOwnerAndRepo(options.Owner, options.Repo)
+	if err := p.checkOrg(options.Owner); err != nil {
+		return fmt.Sprintf("Can't export the issues of %s: %s.", fullName, err.Error())
+	}
+
+	if err := p.exportRepoIssues(context.Background(), p.getGithubClient(userInfo), args.ChannelId, options, time.Now()); err != nil {
+		if errors.Is(err, errIssuesDisabled) {
+			return fmt.Sprintf("Issues are disabled on %s.", fullName)
+		}
+		p.API.LogWarn("Failed to export issues", "repo", fullName, "error", err.Error())
+		return newAPIError(err, fmt.Sprintf("Encountered an error exporting the issues of %s.", fullName)).Message
+	}
+
+	return ""
+}
@@ -0,0 +1,226 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var issueExportNow = time.Date(2020, 9, 30, 12, 0, 0, 0, time.UTC)
+
+func newExportedIssue(number int, title string) *github.Issue {
+	createdAt := issueExportNow.AddDate(0, 0, -number)
+	return &github.Issue{
+		Number:    github.Int(number),
+		Title:     github.String(title),
+		HTMLURL:   github.String(fmt.Sprintf("https://github.com/owner/repo/issues/%d", number)),
+		CreatedAt: &createdAt,
+		Comments:  github.Int(number * 2),
+	}
+}
+
+// newIssueListHandler serves issues the way GitHub pages through them.
+func newIssueListHandler(t *testing.T, issues []*github.Issue, pages *int) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo/issues", func(w http.ResponseWriter, r *http.Request) {
+		*pages++
+		assert.Equal(t, "open", r.URL.Query().Get("state"))
+
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		start := (page - 1) * perPage
+		end := start + perPage
+		if end < len(issues) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d>; rel="next"`, r.URL.Path, page+1))
+		} else {
+			end = len(issues)
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(issues[start:end]))
+	})
+	mux.HandleFunc("/repos/owner/disabled/issues", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+
+	return mux
+}
+
+func TestParseIssueExportOptions(t *testing.T) {
+	options, err := parseIssueExportOptions([]string{"owner/repo", "--label", "bug,help wanted", "--format", "md"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, &issueExportOptions{Owner: "owner", Repo: "repo", Labels: []string{"bug", "help wanted"}, Format: issueExportFormatMarkdown}, options)
+
+	options, err = parseIssueExportOptions([]string{"--label", "bug"}, "owner/default")
+	require.NoError(t, err)
+	assert.Equal(t, &issueExportOptions{Owner: "owner", Repo: "default", Labels: []string{"bug"}, Format: issueExportFormatCSV}, options)
+
+	_, err = parseIssueExportOptions([]string{}, "")
+	assert.EqualError(t, err, "Please provide the repository, e.g. `owner/repo`. It can be omitted in channels subscribed to a single repository.")
+
+	_, err = parseIssueExportOptions([]string{"owner/repo", "--format", "pdf"}, "")
+	assert.EqualError(t, err, "Unknown format `pdf`. Please use `csv` or `md`.")
+
+	_, err = parseIssueExportOptions([]string{"owner/repo", "--label"}, "")
+	assert.Error(t, err)
+
+	_, err = parseIssueExportOptions([]string{"owner"}, "")
+	assert.EqualError(t, err, "Invalid repository `owner`. Use `owner/repo`.")
+}
+
+func TestGenerateIssues(t *testing.T) {
+	issue := newExportedIssue(3, "Crash | on \"save\"\nsometimes")
+	issue.Labels = []*github.Label{{Name: github.String("bug")}, {Name: github.String("p1")}}
+	issue.Assignees = []*github.User{{Login: github.String("alice")}, {Login: github.String("bob")}}
+	issues := []*github.Issue{issue, newExportedIssue(1, "Typo")}
+
+	t.Run("csv", func(t *testing.T) {
+		data, err := generateIssuesCSV(issues, issueExportNow)
+		require.NoError(t, err)
+		assert.Equal(t, "number,title,labels,assignees,age,comments,url\n"+
+			"3,\"Crash | on \"\"save\"\"\nsometimes\",bug;p1,alice;bob,3d,6,https://github.com/owner/repo/issues/3\n"+
+			"1,Typo,,,1d,2,https://github.com/owner/repo/issues/1\n", string(data))
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		assert.Equal(t, "| # | Title | Labels | Assignees | Age | Comments |\n"+
+			"|:--|:------|:-------|:----------|:----|:---------|\n"+
+			"| [3](https://github.com/owner/repo/issues/3) | Crash \\| on \"save\" sometimes | bug, p1 | alice, bob | 3d | 6 |\n"+
+			"| [1](https://github.com/owner/repo/issues/1) | Typo |  |  | 1d | 2 |\n", generateIssuesMarkdown(issues, issueExportNow))
+	})
+}
+
+func TestListOpenIssues(t *testing.T) {
+	issues := []*github.Issue{}
+	for i := 1; i <= 250; i++ {
+		issues = append(issues, newExportedIssue(i, "Issue"))
+	}
+	// Pull requests are listed with the issues
+	issues[0].PullRequestLinks = &github.PullRequestLinks{URL: github.String("https://api.github.com/repos/owner/repo/pulls/1")}
+
+	pages := 0
+	client := newTestGitHubClient(t, newIssueListHandler(t, issues, &pages))
+
+	t.Run("all issues", func(t *testing.T) {
+		pages = 0
+		listed, truncated, err := listOpenIssues(context.Background(), client, "owner", "repo", nil, 500)
+		require.NoError(t, err)
+		assert.False(t, truncated)
+		assert.Len(t, listed, 249)
+		assert.Equal(t, 2, listed[0].GetNumber())
+		assert.Equal(t, 3, pages)
+	})
+
+	t.Run("capped", func(t *testing.T) {
+		pages = 0
+		listed, truncated, err := listOpenIssues(context.Background(), client, "owner", "repo", nil, 120)
+		require.NoError(t, err)
+		assert.True(t, truncated)
+		assert.Len(t, listed, 120)
+		assert.Equal(t, 121, listed[119].GetNumber())
+		assert.Equal(t, 2, pages)
+	})
+
+	t.Run("cap reached on the last issue", func(t *testing.T) {
+		listed, truncated, err := listOpenIssues(context.Background(), client, "owner", "repo", nil, 249)
+		require.NoError(t, err)
+		assert.False(t, truncated)
+		assert.Len(t, listed, 249)
+	})
+
+	t.Run("issues disabled", func(t *testing.T) {
+		_, _, err := listOpenIssues(context.Background(), client, "owner", "disabled", nil, 500)
+		assert.Equal(t, errIssuesDisabled, err)
+	})
+}
+
+func TestExportRepoIssues(t *testing.T) {
+	issues := []*github.Issue{}
+	for i := 1; i <= 100; i++ {
+		issues = append(issues, newExportedIssue(i, "An issue with a title long enough to fill the table"))
+	}
+
+	pages := 0
+	client := newTestGitHubClient(t, newIssueListHandler(t, issues, &pages))
+
+	setup := func(config *Configuration) (*Plugin, *plugintest.API) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(config)
+		api := &plugintest.API{}
+		p.SetAPI(api)
+		return p, api
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		p, api := setup(&Configuration{IssueExportMaxIssues: 10})
+		defer api.AssertExpectations(t)
+
+		data, err := generateIssuesCSV(issues[:10], issueExportNow)
+		require.NoError(t, err)
+		api.On("UploadFile", data, "channelID", "owner-repo-issues-2020-09-30.csv").Return(&model.FileInfo{Id: "fileID"}, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channelID" &&
+				post.UserId == "botID" &&
+				len(post.FileIds) == 1 && post.FileIds[0] == "fileID" &&
+				post.Message == "Export of the 10 open issues of owner/repo labeled `bug`. The export was truncated to the first 10 issues."
+		})).Return(&model.Post{}, nil).Once()
+
+		options := &issueExportOptions{Owner: "owner", Repo: "repo", Labels: []string{"bug"}, Format: issueExportFormatCSV}
+		require.NoError(t, p.exportRepoIssues(context.Background(), client, "channelID", options, issueExportNow))
+	})
+
+	t.Run("markdown posted", func(t *testing.T) {
+		p, api := setup(&Configuration{IssueExportMaxIssues: 5})
+		defer api.AssertExpectations(t)
+
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return len(post.FileIds) == 0 &&
+				post.Message == "Export of the 5 open issues of owner/repo. The export was truncated to the first 5 issues.\n\n"+generateIssuesMarkdown(issues[:5], issueExportNow)
+		})).Return(&model.Post{}, nil).Once()
+
+		options := &issueExportOptions{Owner: "owner", Repo: "repo", Labels: []string{}, Format: issueExportFormatMarkdown}
+		require.NoError(t, p.exportRepoIssues(context.Background(), client, "channelID", options, issueExportNow))
+	})
+
+	t.Run("markdown too large for a post", func(t *testing.T) {
+		p, api := setup(&Configuration{})
+		defer api.AssertExpectations(t)
+
+		table := generateIssuesMarkdown(issues, issueExportNow)
+		require.Greater(t, len(table), issueExportMaxMessageRunes)
+		api.On("UploadFile", []byte(table), "channelID", "owner-repo-issues-2020-09-30.md").Return(&model.FileInfo{Id: "fileID"}, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return len(post.FileIds) == 1 && post.Message == "Export of the 100 open issues of owner/repo."
+		})).Return(&model.Post{}, nil).Once()
+
+		options := &issueExportOptions{Owner: "owner", Repo: "repo", Labels: []string{}, Format: issueExportFormatMarkdown}
+		require.NoError(t, p.exportRepoIssues(context.Background(), client, "channelID", options, issueExportNow))
+	})
+}
+
+func TestHandleIssueExport(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{GitHubOrg: "mattermost"})
+	api := &plugintest.API{}
+	api.On("KVGet", SubscriptionsKey).Return(nil, nil)
+	p.SetAPI(api)
+
+	message := p.handleIssueExport(&model.CommandArgs{ChannelId: "channelID"}, []string{"owner/repo"}, &GitHubUserInfo{})
+	assert.True(t, strings.HasPrefix(message, "Can't export the issues of owner/repo: only repositories in the mattermost organization are supported"))
+}
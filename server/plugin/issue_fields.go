@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-github/server/plugin/graphql"
+)
+
+// getIssueTypes returns the issue types of a repository. Servers without issue types, such as
+// older GitHub Enterprise versions, have none, so that the webapp hides the dropdown.
+func (p *Plugin) getIssueTypes(w http.ResponseWriter, r *http.Request, userID string) {
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	owner, repo, err := parseRepo(r.URL.Query().Get("repo"))
+	if err != nil {
+		p.writeAPIError(w, &APIErrorResponse{Message: err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	types, err := graphql.NewClient(p.githubConnect(*info.Token)).GetIssueTypes(r.Context(), owner, repo)
+	switch {
+	case err == graphql.ErrUnsupported:
+		types = []graphql.IssueType{}
+	case err == graphql.ErrRepositoryNotFound:
+		p.writeAPIError(w, &APIErrorResponse{ID: apiErrorIDGitHubNotFound, Message: fmt.Sprintf("Couldn't find the repository %s/%s. Please check that you have access to it.", owner, repo), StatusCode: http.StatusNotFound})
+		return
+	case err != nil:
+		p.API.LogWarn("Failed to get issue types", "repo", owner+"/"+repo, "error", err.Error())
+		p.writeAPIError(w, newAPIError(err, "Failed to fetch issue types."))
+		return
+	}
+
+	p.writeJSON(w, types)
+}
+
+// issueFieldErrorReason describes why the type or a project field of a new issue couldn't be set.
+func issueFieldErrorReason(err error) string {
+	switch err {
+	case graphql.ErrUnsupported:
+		return "this GitHub server doesn't support it"
+	case graphql.ErrProjectNotFound:
+		return "the project doesn't exist or you don't have access to it"
+	}
+	if errs, ok := err.(graphql.Errors); ok && len(errs) > 0 && errs[0].Type == "FORBIDDEN" {
+		return "you don't have permission to do so"
+	}
+
+	return err.Error()
+}
+
+// setIssueFields sets the type of a newly created issue, given by name, and adds it to the project
+// with the given number of the repository owner, setting the given project fields by name. The
+// issue is already created, so failures are returned as lines for the reply rather than errors.
+func (p *Plugin) setIssueFields(ctx context.Context, client *graphql.Client, owner, repo string, issue *github.Issue, issueType string, projectNumber int, projectFields map[string]string) []string {
+	failures := []string{}
+	fail := func(what string, err error) {
+		p.API.LogWarn("Failed to set a field of a new issue", "issue", issue.GetHTMLURL(), "field", what, "error", err.Error())
+		failures = append(failures, fmt.Sprintf("Issue created, but couldn't %s: %s.", what, issueFieldErrorReason(err)))
+	}
+
+	if issueType != "" {
+		if err := p.setIssueType(ctx, client, owner, repo, issue.GetNodeID(), issueType); err != nil {
+			fail("set the issue type", err)
+		}
+	}
+
+	if projectNumber <= 0 {
+		return failures
+	}
+
+	project, err := client.GetProject(ctx, owner, projectNumber)
+	if err != nil {
+		fail(fmt.Sprintf("add it to project #%d", projectNumber), err)
+		return failures
+	}
+
+	itemID, err := client.AddProjectItem(ctx, project.ID, issue.GetNodeID())
+	if err != nil {
+		fail(fmt.Sprintf("add it to %s", project.Title), err)
+		return failures
+	}
+
+	names := make([]string, 0, len(projectFields))
+	for name := range projectFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := project.Field(name)
+		if field == nil {
+			fail("set "+name, errors.Errorf("%s has no such field", project.Title))
+			continue
+		}
+
+		value, err := field.Value(projectFields[name])
+		if err == nil {
+			err = client.UpdateProjectItemField(ctx, project.ID, itemID, field.ID, value)
+		}
+		if err != nil {
+			fail("set "+strings.ToLower(field.Name), err)
+		}
+	}
+
+	return failures
+}
+
+// setIssueType sets the type of an issue to the issue type of the repository with the given name.
+func (p *Plugin) setIssueType(ctx context.Context, client *graphql.Client, owner, repo, issueID, name string) error {
+	types, err := client.GetIssueTypes(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	for _, issueType := range types {
+		if strings.EqualFold(issueType.Name, strings.TrimSpace(name)) {
+			return client.UpdateIssueType(ctx, issueID, issueType.ID)
+		}
+	}
+
+	return errors.Errorf("%s isn't an issue type of %s/%s", name, owner, repo)
+}
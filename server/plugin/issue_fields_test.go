@@ -0,0 +1,164 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// issueFieldsTestServer serves issue creation and the GraphQL API. Issue types are unsupported
+// unless issueTypes is set, and the mutations received are recorded.
+type issueFieldsTestServer struct {
+	issueTypes string
+	mutations  []map[string]interface{}
+}
+
+func (s *issueFieldsTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/owner/repo/issues":
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number": 7, "node_id": "I_7", "html_url": "https://github.com/owner/repo/issues/7"}`)
+	case r.URL.Path == "/api/graphql":
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		switch {
+		case strings.Contains(req.Query, "issueTypes"):
+			if s.issueTypes == "" {
+				fmt.Fprint(w, `{"errors": [{"message": "Field 'issueTypes' doesn't exist on type 'Repository'", "type": "undefinedField"}]}`)
+				return
+			}
+			fmt.Fprintf(w, `{"data": {"repository": {"issueTypes": {"nodes": %s}}}}`, s.issueTypes)
+		case strings.Contains(req.Query, "projectV2(number:"):
+			fmt.Fprint(w, `{"data": {"repositoryOwner": {"projectV2": {"id": "PVT_1", "title": "Roadmap", "fields": {"nodes": [
+				{"id": "PVTSSF_1", "name": "Priority", "dataType": "SINGLE_SELECT", "options": [{"id": "o1", "name": "P0"}, {"id": "o2", "name": "P1"}]},
+				{"id": "PVTF_2", "name": "Estimate", "dataType": "NUMBER"}
+			]}}}}}`)
+		case strings.Contains(req.Query, "updateIssueIssueType"):
+			s.mutations = append(s.mutations, req.Variables)
+			fmt.Fprint(w, `{"data": {"updateIssueIssueType": {"issue": {"id": "I_7"}}}}`)
+		case strings.Contains(req.Query, "addProjectV2ItemById"):
+			s.mutations = append(s.mutations, req.Variables)
+			fmt.Fprint(w, `{"data": {"addProjectV2ItemById": {"item": {"id": "PVTI_1"}}}}`)
+		case strings.Contains(req.Query, "updateProjectV2ItemFieldValue"):
+			s.mutations = append(s.mutations, req.Variables)
+			fmt.Fprint(w, `{"data": {"updateProjectV2ItemFieldValue": {"projectV2Item": {"id": "PVTI_1"}}}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func setupIssueFieldsTest(t *testing.T, server *issueFieldsTestServer) (*Plugin, *string, func()) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice"})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(server)
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+	reply := new(string)
+	api := &plugintest.API{}
+	api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+	api.On("SendEphemeralPost", "userID", mock.AnythingOfType("*model.Post")).Return(func(userID string, post *model.Post) *model.Post {
+		*reply = post.Message
+		return post
+	})
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	p.SetAPI(api)
+
+	return p, reply, ts.Close
+}
+
+func TestCreateIssueFields(t *testing.T) {
+	createIssue := func(p *Plugin, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		p.createIssue(w, httptest.NewRequest(http.MethodPost, "/api/v1/createissue", strings.NewReader(body)), "userID")
+		return w
+	}
+
+	t.Run("type and project fields", func(t *testing.T) {
+		server := &issueFieldsTestServer{issueTypes: `[{"id": "IT_1", "name": "Bug", "isEnabled": true}]`}
+		p, reply, closeServer := setupIssueFieldsTest(t, server)
+		defer closeServer()
+
+		w := createIssue(p, `{"title": "Title", "repo": "owner/repo", "channel_id": "channelID", "issue_type": "bug", "project": 3, "project_fields": {"priority": "P1", "Estimate": "2"}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "Created GitHub issue [#7](https://github.com/owner/repo/issues/7)", *reply)
+		assert.Equal(t, []map[string]interface{}{
+			{"issueId": "I_7", "issueTypeId": "IT_1"},
+			{"projectId": "PVT_1", "contentId": "I_7"},
+			{"projectId": "PVT_1", "itemId": "PVTI_1", "fieldId": "PVTF_2", "value": map[string]interface{}{"number": float64(2)}},
+			{"projectId": "PVT_1", "itemId": "PVTI_1", "fieldId": "PVTSSF_1", "value": map[string]interface{}{"singleSelectOptionId": "o2"}},
+		}, server.mutations)
+	})
+
+	t.Run("partial failures", func(t *testing.T) {
+		server := &issueFieldsTestServer{}
+		p, reply, closeServer := setupIssueFieldsTest(t, server)
+		defer closeServer()
+
+		w := createIssue(p, `{"title": "Title", "repo": "owner/repo", "channel_id": "channelID", "issue_type": "Bug", "project": 3, "project_fields": {"Priority": "P9", "Status": "Todo"}}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "Created GitHub issue [#7](https://github.com/owner/repo/issues/7)"+
+			"\nIssue created, but couldn't set the issue type: this GitHub server doesn't support it."+
+			"\nIssue created, but couldn't set priority: \"P9\" is not an option of the field."+
+			"\nIssue created, but couldn't set Status: Roadmap has no such field.", *reply)
+		assert.Equal(t, []map[string]interface{}{{"projectId": "PVT_1", "contentId": "I_7"}}, server.mutations)
+	})
+
+	t.Run("fields without a project", func(t *testing.T) {
+		server := &issueFieldsTestServer{}
+		p, _, closeServer := setupIssueFieldsTest(t, server)
+		defer closeServer()
+
+		w := createIssue(p, `{"title": "Title", "repo": "owner/repo", "channel_id": "channelID", "project_fields": {"Priority": "P0"}}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Please provide the project to set the fields of.")
+	})
+}
+
+func TestGetIssueTypes(t *testing.T) {
+	getIssueTypes := func(server *issueFieldsTestServer) *httptest.ResponseRecorder {
+		p, _, closeServer := setupIssueFieldsTest(t, server)
+		defer closeServer()
+
+		w := httptest.NewRecorder()
+		p.getIssueTypes(w, httptest.NewRequest(http.MethodGet, "/api/v1/issue_types?repo=owner/repo", nil), "userID")
+		return w
+	}
+
+	t.Run("issue types", func(t *testing.T) {
+		w := getIssueTypes(&issueFieldsTestServer{issueTypes: `[{"id": "IT_1", "name": "Bug", "description": "An unexpected problem", "isEnabled": true}]`})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[{"id": "IT_1", "name": "Bug", "description": "An unexpected problem"}]`, w.Body.String())
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		w := getIssueTypes(&issueFieldsTestServer{})
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+	})
+}
@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	issueFormFieldsFlag = "issue-form-fields"
+
+	// issueFormNoResponse is what GitHub puts in the body of an issue for the fields left empty.
+	issueFormNoResponse = "_No response_"
+)
+
+var issueFormCheckboxRegex = regexp.MustCompile(`^[-*] \[([ xX])\] (.*)$`)
+
+// issueFormField is a field of an issue created from an issue form, along with its value.
+type issueFormField struct {
+	Name  string
+	Value string
+}
+
+// ListValue returns the value of the field, indented to stay within a Markdown list item.
+func (f *issueFormField) ListValue() string {
+	return strings.ReplaceAll(f.Value, "\n", "\n  ")
+}
+
+// parseIssueFormFieldNames parses the value of the --issue-form-fields flag, e.g. "Severity,Component".
+func parseIssueFormFieldNames(value string) ([]string, error) {
+	names := []string{}
+	for _, name := range strings.Split(strings.Trim(value, `"`), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		return nil, errors.Errorf("Invalid value for --%s. Use a comma-separated list of field names, e.g. `--%s \"Severity,Component\"`.", issueFormFieldsFlag, issueFormFieldsFlag)
+	}
+
+	return names, nil
+}
+
+// parseIssueForm parses the body of an issue created from an issue form, where each field is a
+// `### Field name` heading followed by its value. It returns false if the body isn't shaped that way.
+func parseIssueForm(body string) ([]*issueFormField, bool) {
+	fields := []*issueFormField{}
+	var current *issueFormField
+	var lines []string
+	inCodeBlock := false
+
+	flush := func() {
+		if current != nil {
+			current.Value = parseIssueFormValue(lines)
+			fields = append(fields, current)
+		}
+		lines = nil
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCodeBlock = !inCodeBlock
+		}
+
+		if !inCodeBlock && strings.HasPrefix(line, "### ") {
+			flush()
+			current = &issueFormField{Name: strings.TrimSpace(strings.TrimPrefix(line, "### "))}
+			continue
+		}
+
+		// Text before the first field means the issue wasn't created from a form
+		if current == nil && strings.TrimSpace(line) != "" {
+			return nil, false
+		}
+
+		lines = append(lines, line)
+	}
+	flush()
+
+	if len(fields) == 0 {
+		return nil, false
+	}
+
+	return fields, true
+}
+
+// parseIssueFormValue returns the value of a field from the lines following its heading. Empty
+// fields have no value, and checkboxes are reduced to the list of checked options.
+func parseIssueFormValue(lines []string) string {
+	value := strings.TrimSpace(strings.Join(lines, "\n"))
+	if value == "" || value == issueFormNoResponse {
+		return ""
+	}
+
+	checked := []string{}
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		match := issueFormCheckboxRegex.FindStringSubmatch(line)
+		if match == nil {
+			return value
+		}
+		if match[1] != " " {
+			checked = append(checked, match[2])
+		}
+	}
+
+	return strings.Join(checked, ", ")
+}
+
+// selectIssueFormFields returns the fields with a value among the given names, in the order of the
+// names. Names are matched case-insensitively.
+func selectIssueFormFields(fields []*issueFormField, names []string) []*issueFormField {
+	selected := []*issueFormField{}
+	for _, name := range names {
+		for _, field := range fields {
+			if strings.EqualFold(field.Name, name) && field.Value != "" {
+				selected = append(selected, field)
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// renderIssueFormFields renders a new issue with only the requested fields of its form instead of
+// its whole body. It returns false if the body isn't an issue form or has none of the fields.
+func renderIssueFormFields(event *github.IssuesEvent, flag string) (string, bool, error) {
+	names, err := parseIssueFormFieldNames(flag)
+	if err != nil {
+		return "", false, err
+	}
+
+	fields, ok := parseIssueForm(event.GetIssue().GetBody())
+	if !ok {
+		return "", false, nil
+	}
+
+	selected := selectIssueFormFields(fields, names)
+	if len(selected) == 0 {
+		return "", false, nil
+	}
+
+	message, err := renderTemplate("newIssueFormFields", map[string]interface{}{
+		"Event":  event,
+		"Fields": selected,
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	return message, true, nil
+}
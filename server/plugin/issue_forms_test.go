@@ -0,0 +1,141 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bugReportFormBody is the body of an issue created from a bug report issue form.
+const bugReportFormBody = "### Severity\n\nHigh\n\n### Component\n\nAPI, Webapp\n\n" +
+	"### Steps to reproduce\n\n1. Open the app\n2. Click on the button\n\n" +
+	"### Relevant log output\n\n```shell\n### not a field\npanic: nil map\n```\n\n" +
+	"### Workaround\n\n_No response_\n\n" +
+	"### Code of Conduct\n\n- [X] I agree to follow this project's Code of Conduct\n- [ ] I searched for existing issues\n"
+
+func TestParseIssueForm(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body     string
+		ok       bool
+		expected []*issueFormField
+	}{
+		"bug report": {
+			body: bugReportFormBody,
+			ok:   true,
+			expected: []*issueFormField{
+				{Name: "Severity", Value: "High"},
+				{Name: "Component", Value: "API, Webapp"},
+				{Name: "Steps to reproduce", Value: "1. Open the app\n2. Click on the button"},
+				{Name: "Relevant log output", Value: "```shell\n### not a field\npanic: nil map\n```"},
+				{Name: "Workaround", Value: ""},
+				{Name: "Code of Conduct", Value: "I agree to follow this project's Code of Conduct"},
+			},
+		},
+		"windows line endings": {
+			body: "### Severity\r\n\r\nLow\r\n",
+			ok:   true,
+			expected: []*issueFormField{
+				{Name: "Severity", Value: "Low"},
+			},
+		},
+		"no checkbox checked": {
+			body: "### Checks\n\n- [ ] First\n- [ ] Second\n",
+			ok:   true,
+			expected: []*issueFormField{
+				{Name: "Checks", Value: ""},
+			},
+		},
+		"checkboxes mixed with text": {
+			body: "### Notes\n\n- [x] Done\nSome text\n",
+			ok:   true,
+			expected: []*issueFormField{
+				{Name: "Notes", Value: "- [x] Done\nSome text"},
+			},
+		},
+		"free-form body": {
+			body: "The app crashes.\n\n### Severity\n\nHigh\n",
+			ok:   false,
+		},
+		"no headings": {
+			body: "The app crashes.",
+			ok:   false,
+		},
+		"empty body": {
+			body: "",
+			ok:   false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fields, ok := parseIssueForm(tc.body)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, fields)
+		})
+	}
+}
+
+func TestSelectIssueFormFields(t *testing.T) {
+	fields, ok := parseIssueForm(bugReportFormBody)
+	require.True(t, ok)
+
+	assert.Equal(t, []*issueFormField{
+		{Name: "Component", Value: "API, Webapp"},
+		{Name: "Severity", Value: "High"},
+	}, selectIssueFormFields(fields, []string{"component", "Workaround", "Severity", "Missing"}))
+	assert.Empty(t, selectIssueFormFields(fields, []string{"Workaround"}))
+}
+
+func TestIssueFormFieldsFlag(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(issueFormFieldsFlag, `"Severity, Steps to reproduce"`))
+	assert.Equal(t, "Severity,Steps to reproduce", flags.IssueFormFields)
+	assert.Equal(t, `--issue-form-fields "Severity,Steps to reproduce"`, flags.String())
+
+	assert.Error(t, flags.SetFlag(issueFormFieldsFlag, `" , "`))
+}
+
+func TestRenderIssueFormFields(t *testing.T) {
+	event := func(body string) *github.IssuesEvent {
+		return &github.IssuesEvent{
+			Action: github.String("opened"),
+			Issue: &github.Issue{
+				Number:  github.Int(1),
+				Title:   github.String("Crash on click"),
+				HTMLURL: github.String("https://github.com/owner/repo/issues/1"),
+				Body:    github.String(body),
+			},
+			Repo:   &github.Repository{FullName: github.String("owner/repo"), HTMLURL: github.String("https://github.com/owner/repo")},
+			Sender: &github.User{Login: github.String("panda"), HTMLURL: github.String("https://github.com/panda")},
+		}
+	}
+
+	t.Run("requested fields", func(t *testing.T) {
+		expected := `
+#### Crash on click
+##### [owner/repo#1](https://github.com/owner/repo/issues/1)
+#new-issue by [panda](https://github.com/panda)
+
+* **Severity:** High
+* **Steps to reproduce:** 1. Open the app
+  2. Click on the button
+`
+
+		message, ok, err := renderIssueFormFields(event(bugReportFormBody), "Severity,Steps to reproduce")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, expected, message)
+	})
+
+	t.Run("fields absent", func(t *testing.T) {
+		_, ok, err := renderIssueFormFields(event(bugReportFormBody), "Priority")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("not an issue form", func(t *testing.T) {
+		_, ok, err := renderIssueFormFields(event("The app crashes."), "Severity")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	liveUpdateFlag = "live-update"
+	issuePostKey   = "_issuepost"
+
+	// defaultIssueLiveUpdateMaxAgeDays is used when no maximum age of updated issue posts is configured.
+	defaultIssueLiveUpdateMaxAgeDays = 30
+)
+
+// issuePost is the post of a new issue in the channel of a subscription updating it.
+type issuePost struct {
+	PostID   string
+	PostedAt time.Time
+}
+
+func issuePostKeyFor(sub *Subscription, issueURL string) string {
+	return hashKey(issuePostKey, sub.ChannelID, issueURL)
+}
+
+// isLiveUpdateAction checks if an action of an issues event changes what the post of a new issue shows.
+func isLiveUpdateAction(action string) bool {
+	return action == "edited" || action == "labeled" || action == "unlabeled"
+}
+
+// formatLiveUpdatedLine returns the line appended to updated issue posts.
+func formatLiveUpdatedLine(updatedAt time.Time) string {
+	return fmt.Sprintf("\n\n_Updated %s_", updatedAt.UTC().Format("Jan 2, 2006 15:04 UTC"))
+}
+
+// renderNewIssueMessage renders the post of a new issue for a subscription, in its render style.
+func (p *Plugin) renderNewIssueMessage(sub *Subscription, event *github.IssuesEvent) (string, error) {
+	style := p.getRenderStyle(sub)
+	message, err := renderStyledTemplate("newIssue", style, event)
+	if err != nil {
+		return "", err
+	}
+
+	// Only the description is replaced by the fields, so other styles are left as they are
+	if sub.Flags.IssueFormFields != "" && style == renderStyleDefault {
+		fieldsMessage, ok, err := renderIssueFormFields(event, sub.Flags.IssueFormFields)
+		if err != nil {
+			p.API.LogWarn("Failed to render issue form fields", "error", err.Error())
+		} else if ok {
+			message = fieldsMessage
+		}
+	}
+
+	return message, nil
+}
+
+// storeIssuePost keeps the post of a new issue for a subscription with the --live-update flag. It
+// expires when the post is too old to be updated.
+func (p *Plugin) storeIssuePost(sub *Subscription, issueURL string, post *model.Post) {
+	value, err := json.Marshal(&issuePost{PostID: post.Id, PostedAt: time.Now()})
+	if err != nil {
+		p.API.LogWarn("Failed to marshal issue post", "error", err.Error())
+		return
+	}
+
+	expiry := int64(p.getConfiguration().getIssueLiveUpdateMaxAge().Seconds())
+	if appErr := p.API.KVSetWithExpiry(issuePostKeyFor(sub, issueURL), value, expiry); appErr != nil {
+		p.API.LogWarn("Failed to store issue post", "channelID", sub.ChannelID, "repo", sub.Repository, "error", appErr.Error())
+	}
+}
+
+// getIssuePost returns the post of a new issue for a subscription, or nil if there is none or it
+// is too old to be updated.
+func (p *Plugin) getIssuePost(sub *Subscription, issueURL string, now time.Time) (*issuePost, error) {
+	value, appErr := p.API.KVGet(issuePostKeyFor(sub, issueURL))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get issue post from KV store")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	stored := &issuePost{}
+	if err := json.Unmarshal(value, stored); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal issue post")
+	}
+	if now.Sub(stored.PostedAt) > p.getConfiguration().getIssueLiveUpdateMaxAge() {
+		return nil, nil
+	}
+
+	return stored, nil
+}
+
+// liveUpdateIssuePost updates the post of a new issue for a subscription with the --live-update
+// flag, rendering it again with the current title and labels of the issue. It returns false if
+// there is no post to update, e.g. because it was deleted or is too old.
+func (p *Plugin) liveUpdateIssuePost(sub *Subscription, event *github.IssuesEvent, now time.Time) bool {
+	stored, err := p.getIssuePost(sub, event.GetIssue().GetHTMLURL(), now)
+	if err != nil {
+		p.API.LogWarn("Failed to get issue post", "channelID", sub.ChannelID, "repo", sub.Repository, "error", err.Error())
+		return false
+	}
+	if stored == nil {
+		return false
+	}
+
+	post, appErr := p.API.GetPost(stored.PostID)
+	if appErr != nil || post.DeleteAt != 0 {
+		return false
+	}
+
+	// The post is rendered as it was for the opened event, with the current state of the issue
+	opened := *event
+	opened.Action = github.String("opened")
+	message, err := p.renderNewIssueMessage(sub, &opened)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return false
+	}
+
+	post.Message = p.applyNotificationTemplate(sub, &opened, message) + formatLiveUpdatedLine(now)
+	if _, appErr := p.API.UpdatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to update issue post", "postID", post.Id, "error", appErr.Error())
+		return false
+	}
+
+	return true
+}
+
+// liveUpdateIssuePosts updates the posts of a new issue for the subscriptions with the --live-update
+// flag. It returns the subscriptions whose post was updated.
+func (p *Plugin) liveUpdateIssuePosts(subs []*Subscription, event *github.IssuesEvent) map[*Subscription]bool {
+	updated := map[*Subscription]bool{}
+	now := time.Now()
+	for _, sub := range subs {
+		if !sub.Flags.LiveUpdate || (!sub.Issues() && !sub.IssueCreations()) {
+			continue
+		}
+
+		if p.liveUpdateIssuePost(sub, event, now) {
+			updated[sub] = true
+		}
+	}
+
+	return updated
+}
@@ -0,0 +1,166 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveUpdateIssuePosts(t *testing.T) {
+	subs, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "liveID", Repository: "owner/repo", Features: "issues", Flags: SubscriptionFlags{LiveUpdate: true}},
+			{ChannelID: "channelID", Repository: "owner/repo", Features: "issues"},
+		},
+	}})
+	require.NoError(t, err)
+
+	setup := func() (*Plugin, map[string][]byte, map[string]*model.Post) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		store[SubscriptionsKey] = subs
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(30*24*60*60)).Return(func(key string, value []byte, _ int64) *model.AppError {
+			store[key] = value
+			return nil
+		})
+
+		// Posts by ID, the webhook handlers reusing their post for every channel
+		posts := map[string]*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			created := post.Clone()
+			created.Id = model.NewId()
+			posts[created.Id] = created
+			return created
+		}, nil)
+		api.On("GetPost", mock.AnythingOfType("string")).Return(func(postID string) *model.Post {
+			return posts[postID].Clone()
+		}, nil)
+		api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			posts[post.Id] = post
+			return post
+		}, nil)
+		p.SetAPI(api)
+
+		return p, store, posts
+	}
+
+	// Issues labeled right after being opened are skipped
+	createdAt := time.Now().Add(-time.Hour)
+	issueEvent := func(action, title string, labels ...string) *github.IssuesEvent {
+		issueLabels := []*github.Label{}
+		for _, label := range labels {
+			issueLabels = append(issueLabels, &github.Label{Name: github.String(label)})
+		}
+
+		event := &github.IssuesEvent{
+			Action: github.String(action),
+			Repo:   &github.Repository{FullName: github.String("owner/repo"), Name: github.String("repo"), Owner: &github.User{Login: github.String("owner")}, HTMLURL: github.String("https://github.com/owner/repo")},
+			Issue: &github.Issue{
+				Number:    github.Int(12),
+				Title:     github.String(title),
+				HTMLURL:   github.String("https://github.com/owner/repo/issues/12"),
+				Labels:    issueLabels,
+				CreatedAt: &createdAt,
+			},
+			Sender: &github.User{Login: github.String("alice")},
+		}
+		if action == "labeled" || action == "unlabeled" {
+			event.Label = &github.Label{Name: github.String(labels[len(labels)-1])}
+		}
+
+		return event
+	}
+
+	postsInChannel := func(posts map[string]*model.Post, channelID string) []*model.Post {
+		inChannel := []*model.Post{}
+		for _, post := range posts {
+			if post.ChannelId == channelID {
+				inChannel = append(inChannel, post)
+			}
+		}
+		return inChannel
+	}
+
+	t.Run("edit after open", func(t *testing.T) {
+		p, _, posts := setup()
+
+		p.postIssueEvent(issueEvent("opened", "Crash on start"), testWebhookDelivery(p))
+		p.postIssueEvent(issueEvent("edited", "Crash on start with an empty config"), testWebhookDelivery(p))
+
+		live := postsInChannel(posts, "liveID")
+		require.Len(t, live, 1)
+		assert.Contains(t, live[0].Message, "#### Crash on start with an empty config\n")
+		assert.Regexp(t, `\n\n_Updated \w+ \d+, \d{4} \d{2}:\d{2} UTC_$`, live[0].Message)
+
+		other := postsInChannel(posts, "channelID")
+		require.Len(t, other, 1)
+		assert.Contains(t, other[0].Message, "#### Crash on start\n")
+	})
+
+	t.Run("label churn", func(t *testing.T) {
+		p, _, posts := setup()
+
+		p.postIssueEvent(issueEvent("opened", "Crash on start"), testWebhookDelivery(p))
+		p.postIssueEvent(issueEvent("labeled", "Crash on start", "bug"), testWebhookDelivery(p))
+		p.postIssueEvent(issueEvent("labeled", "Crash on start", "bug", "crash"), testWebhookDelivery(p))
+		p.postIssueEvent(issueEvent("unlabeled", "Crash on start", "crash"), testWebhookDelivery(p))
+
+		live := postsInChannel(posts, "liveID")
+		require.Len(t, live, 1)
+		assert.Contains(t, live[0].Message, "`crash`")
+		assert.NotContains(t, live[0].Message, "`bug`")
+		assert.Len(t, postsInChannel(posts, "channelID"), 1)
+	})
+
+	t.Run("closing is still posted", func(t *testing.T) {
+		p, _, posts := setup()
+
+		p.postIssueEvent(issueEvent("opened", "Crash on start"), testWebhookDelivery(p))
+		p.postIssueEvent(issueEvent("closed", "Crash on start"), testWebhookDelivery(p))
+
+		assert.Len(t, postsInChannel(posts, "liveID"), 2)
+	})
+
+	t.Run("missing post", func(t *testing.T) {
+		p, _, posts := setup()
+
+		p.postIssueEvent(issueEvent("opened", "Crash on start"), testWebhookDelivery(p))
+		for _, post := range postsInChannel(posts, "liveID") {
+			post.DeleteAt = model.GetMillis()
+		}
+		p.postIssueEvent(issueEvent("edited", "Crash on start with an empty config"), testWebhookDelivery(p))
+
+		live := postsInChannel(posts, "liveID")
+		require.Len(t, live, 1)
+		assert.Contains(t, live[0].Message, "#### Crash on start\n")
+	})
+
+	t.Run("post too old", func(t *testing.T) {
+		p, store, posts := setup()
+
+		p.postIssueEvent(issueEvent("opened", "Crash on start"), testWebhookDelivery(p))
+
+		key := issuePostKeyFor(&Subscription{ChannelID: "liveID"}, "https://github.com/owner/repo/issues/12")
+		stored := &issuePost{}
+		require.NoError(t, json.Unmarshal(store[key], stored))
+		stored.PostedAt = stored.PostedAt.AddDate(0, 0, -31)
+		store[key], err = json.Marshal(stored)
+		require.NoError(t, err)
+
+		p.postIssueEvent(issueEvent("edited", "Crash on start with an empty config"), testWebhookDelivery(p))
+
+		live := postsInChannel(posts, "liveID")
+		require.Len(t, live, 1)
+		assert.Contains(t, live[0].Message, "#### Crash on start\n")
+	})
+}
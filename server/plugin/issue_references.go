@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxLinkedPullRequests caps the number of pull requests closing an issue returned with it.
+	maxLinkedPullRequests = 10
+	// issueTimelinePageSize is the number of timeline events looked at for linked pull requests.
+	issueTimelinePageSize = 100
+)
+
+// closingReferenceRegex matches the references to issues that a pull request closes, e.g.
+// `fixes #12`, `Closes: owner/repo#12` or `resolved https://github.com/owner/repo/issues/12`.
+var closingReferenceRegex = regexp.MustCompile(`(?i)(?:^|[^\w])(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+(?:(?:([\w.-]+)/([\w.-]+))?#(\d+)|https?://[^\s/]+/([\w.-]+)/([\w.-]+)/(?:issues|pull)/(\d+))\b`)
+
+// issueReference is a reference to an issue or pull request.
+type issueReference struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+}
+
+// linkedPullRequest is a pull request closing an issue.
+type linkedPullRequest struct {
+	issueReference
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+}
+
+// issueWithReferences is an issue along with the pull requests closing it.
+type issueWithReferences struct {
+	*github.Issue
+	LinkedPullRequests []*linkedPullRequest `json:"linked_pull_requests,omitempty"`
+}
+
+// pullRequestWithReferences is a pull request along with its reactions and the issues it closes.
+type pullRequestWithReferences struct {
+	*github.PullRequest
+	Reactions        *github.Reactions `json:"reactions,omitempty"`
+	ReferencedIssues []*issueReference `json:"referenced_issues,omitempty"`
+}
+
+// parseClosingReferences returns the issues a pull request body closes with keywords such as
+// `fixes #12`, in order and without duplicates. References without a repository are resolved
+// against the repository of the pull request.
+func parseClosingReferences(body, owner, repo string) []*issueReference {
+	references := []*issueReference{}
+	seen := map[issueReference]bool{}
+
+	for _, match := range closingReferenceRegex.FindAllStringSubmatch(body, -1) {
+		reference := issueReference{Owner: owner, Repo: repo}
+		number := match[3]
+		switch {
+		case match[6] != "":
+			reference.Owner, reference.Repo, number = match[4], match[5], match[6]
+		case match[1] != "":
+			reference.Owner, reference.Repo = match[1], match[2]
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			continue
+		}
+		reference.Number = n
+
+		if seen[reference] {
+			continue
+		}
+		seen[reference] = true
+		references = append(references, &reference)
+	}
+
+	return references
+}
+
+// closesIssue checks if a pull request body closes the given issue.
+func closesIssue(body, prOwner, prRepo, owner, repo string, number int) bool {
+	for _, reference := range parseClosingReferences(body, prOwner, prRepo) {
+		if strings.EqualFold(reference.Owner, owner) && strings.EqualFold(reference.Repo, repo) && reference.Number == number {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listLinkedPullRequests returns the pull requests cross-referencing an issue that close it. GitHub
+// doesn't tell which pull request a `connected` event is about, so only pull requests whose
+// description closes the issue are found.
+func listLinkedPullRequests(ctx context.Context, githubClient *github.Client, owner, repo string, number int) ([]*linkedPullRequest, error) {
+	events, _, err := githubClient.Issues.ListIssueTimeline(ctx, owner, repo, number, &github.ListOptions{PerPage: issueTimelinePageSize})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list issue timeline")
+	}
+
+	linked := []*linkedPullRequest{}
+	seen := map[string]bool{}
+	for _, event := range events {
+		if event.GetEvent() != "cross-referenced" {
+			continue
+		}
+
+		source := event.GetSource().GetIssue()
+		if source == nil || !source.IsPullRequest() || seen[source.GetHTMLURL()] {
+			continue
+		}
+
+		sourceOwner, sourceRepo := parseOwnerAndRepo(source.GetRepository().GetFullName(), "")
+		if source.GetRepository().GetFullName() == "" {
+			sourceOwner, sourceRepo = ownerAndRepoFromHTMLURL(source.GetHTMLURL())
+		}
+		if !closesIssue(source.GetBody(), sourceOwner, sourceRepo, owner, repo, number) {
+			continue
+		}
+
+		seen[source.GetHTMLURL()] = true
+		linked = append(linked, &linkedPullRequest{
+			issueReference: issueReference{Owner: sourceOwner, Repo: sourceRepo, Number: source.GetNumber()},
+			Title:          source.GetTitle(),
+			State:          source.GetState(),
+			HTMLURL:        source.GetHTMLURL(),
+		})
+		if len(linked) == maxLinkedPullRequests {
+			break
+		}
+	}
+
+	return linked, nil
+}
+
+// ownerAndRepoFromHTMLURL returns the owner and repository of an issue or pull request from its
+// URL, e.g. https://github.com/owner/repo/pull/1.
+func ownerAndRepoFromHTMLURL(htmlURL string) (string, string) {
+	parts := strings.Split(htmlURL, "/")
+	if len(parts) < 5 {
+		return "", ""
+	}
+
+	return parts[len(parts)-4], parts[len(parts)-3]
+}
@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestParseClosingReferences(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body     string
+		expected []*issueReference
+	}{
+		"no reference": {
+			body:     "Refactors the parser. See #12.",
+			expected: []*issueReference{},
+		},
+		"keywords": {
+			body: "Fixes #1\nfixed #2, closes #3 and Resolves: #4\nclose #5 fix #6 resolved #7 CLOSED #8 resolve #9",
+			expected: []*issueReference{
+				{Owner: "owner", Repo: "repo", Number: 1},
+				{Owner: "owner", Repo: "repo", Number: 2},
+				{Owner: "owner", Repo: "repo", Number: 3},
+				{Owner: "owner", Repo: "repo", Number: 4},
+				{Owner: "owner", Repo: "repo", Number: 5},
+				{Owner: "owner", Repo: "repo", Number: 6},
+				{Owner: "owner", Repo: "repo", Number: 7},
+				{Owner: "owner", Repo: "repo", Number: 8},
+				{Owner: "owner", Repo: "repo", Number: 9},
+			},
+		},
+		"cross-repo references": {
+			body: "Fixes other/project#12 and closes https://github.com/other/web.app/issues/34.\nResolves https://github.example.com/owner/repo/pull/56",
+			expected: []*issueReference{
+				{Owner: "other", Repo: "project", Number: 12},
+				{Owner: "other", Repo: "web.app", Number: 34},
+				{Owner: "owner", Repo: "repo", Number: 56},
+			},
+		},
+		"duplicates": {
+			body: "Fixes #1. Also closes #1 and owner/repo#1.",
+			expected: []*issueReference{
+				{Owner: "owner", Repo: "repo", Number: 1},
+			},
+		},
+		"keywords within words": {
+			body:     "prefixes #1, suffixes #2, unresolved #3, fixesx #4",
+			expected: []*issueReference{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseClosingReferences(tc.body, "owner", "repo"))
+		})
+	}
+}
+
+func TestGetIssueAndPrByNumberReferences(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	setup := func(t *testing.T, timelineStatus int) *Plugin {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/api/v3/repos/owner/repo/issues/1":
+				fmt.Fprint(w, `{"number": 1, "title": "Crash", "reactions": {"total_count": 3, "+1": 2, "heart": 1}}`)
+			case "/api/v3/repos/owner/repo/pulls/1":
+				fmt.Fprint(w, `{"number": 1, "title": "Fix the crash", "body": "Fixes #2 and fixes other/repo#3, see #4"}`)
+			case "/api/v3/repos/owner/repo/issues/1/timeline":
+				w.WriteHeader(timelineStatus)
+				fmt.Fprint(w, `[
+					{"event": "cross-referenced", "source": {"issue": {"number": 5, "title": "Fix it", "state": "open", "html_url": "https://github.com/other/repo/pull/5", "body": "Closes owner/repo#1", "pull_request": {"url": "https://api.github.com/repos/other/repo/pulls/5"}}}},
+					{"event": "cross-referenced", "source": {"issue": {"number": 6, "html_url": "https://github.com/owner/repo/pull/6", "body": "Related to #1", "pull_request": {"url": "https://api.github.com/repos/owner/repo/pulls/6"}}}},
+					{"event": "cross-referenced", "source": {"issue": {"number": 7, "html_url": "https://github.com/owner/repo/issues/7", "body": "Fixes #1"}}},
+					{"event": "labeled"}
+				]`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(ts.Close)
+
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		return p
+	}
+
+	t.Run("issue with linked pull requests", func(t *testing.T) {
+		p := setup(t, http.StatusOK)
+		w := httptest.NewRecorder()
+		p.getIssueByNumber(w, httptest.NewRequest(http.MethodGet, "/api/v1/issue?owner=owner&repo=repo&number=1", nil), "userID")
+
+		var issue map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issue))
+		assert.Equal(t, "Crash", issue["title"])
+		assert.Equal(t, float64(3), issue["reactions"].(map[string]interface{})["total_count"])
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"owner":    "other",
+			"repo":     "repo",
+			"number":   float64(5),
+			"title":    "Fix it",
+			"state":    "open",
+			"html_url": "https://github.com/other/repo/pull/5",
+		}}, issue["linked_pull_requests"])
+	})
+
+	t.Run("timeline unavailable", func(t *testing.T) {
+		p := setup(t, http.StatusForbidden)
+		w := httptest.NewRecorder()
+		p.getIssueByNumber(w, httptest.NewRequest(http.MethodGet, "/api/v1/issue?owner=owner&repo=repo&number=1", nil), "userID")
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var issue map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issue))
+		assert.Equal(t, "Crash", issue["title"])
+		assert.NotContains(t, issue, "linked_pull_requests")
+	})
+
+	t.Run("pull request with reactions and referenced issues", func(t *testing.T) {
+		p := setup(t, http.StatusOK)
+		w := httptest.NewRecorder()
+		p.getPrByNumber(w, httptest.NewRequest(http.MethodGet, "/api/v1/pr?owner=owner&repo=repo&number=1", nil), "userID")
+
+		var pr map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &pr))
+		assert.Equal(t, "Fix the crash", pr["title"])
+		assert.Equal(t, float64(2), pr["reactions"].(map[string]interface{})["+1"])
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"owner": "owner", "repo": "repo", "number": float64(2)},
+			map[string]interface{}{"owner": "other", "repo": "repo", "number": float64(3)},
+		}, pr["referenced_issues"])
+	})
+}
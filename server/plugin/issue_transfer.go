@@ -0,0 +1,224 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	issuesEventType  = "issues"
+	issueTransferKey = "_issuetransfer"
+
+	// maxIssueTransfers is the number of successive transfers of an issue that are followed.
+	maxIssueTransfers = 10
+	// issueTransferTTL is how long the transfers of issues are recorded for.
+	issueTransferTTL = 90 * 24 * time.Hour
+)
+
+// IssueTransferChanges describes where an issue was transferred to.
+type IssueTransferChanges struct {
+	NewIssue      *github.Issue      `json:"new_issue,omitempty"`
+	NewRepository *github.Repository `json:"new_repository,omitempty"`
+}
+
+// IssueTransferEvent is an issues event with the transferred action. go-github doesn't parse the
+// changes of a transfer yet.
+type IssueTransferEvent struct {
+	Action  *string               `json:"action,omitempty"`
+	Issue   *github.Issue         `json:"issue,omitempty"`
+	Changes *IssueTransferChanges `json:"changes,omitempty"`
+	Repo    *github.Repository    `json:"repository,omitempty"`
+	Sender  *github.User          `json:"sender,omitempty"`
+}
+
+func (e *IssueTransferEvent) GetAction() string {
+	if e == nil || e.Action == nil {
+		return ""
+	}
+	return *e.Action
+}
+
+func (e *IssueTransferEvent) GetIssue() *github.Issue {
+	if e == nil {
+		return nil
+	}
+	return e.Issue
+}
+
+func (e *IssueTransferEvent) GetRepo() *github.Repository {
+	if e == nil {
+		return nil
+	}
+	return e.Repo
+}
+
+func (e *IssueTransferEvent) GetSender() *github.User {
+	if e == nil {
+		return nil
+	}
+	return e.Sender
+}
+
+func (e *IssueTransferEvent) GetNewIssue() *github.Issue {
+	if e == nil || e.Changes == nil {
+		return nil
+	}
+	return e.Changes.NewIssue
+}
+
+func (e *IssueTransferEvent) GetNewRepo() *github.Repository {
+	if e == nil || e.Changes == nil {
+		return nil
+	}
+	return e.Changes.NewRepository
+}
+
+// issueLocation is where an issue was transferred to.
+type issueLocation struct {
+	Repository string `json:"repository"`
+	Number     int    `json:"number"`
+}
+
+func issueTransferKeyFor(repository string, number int) string {
+	return hashKey(issueTransferKey, repository, strconv.Itoa(number))
+}
+
+// parseIssueTransferEvent parses an issues event if it's a transfer. It returns nil for other actions.
+func parseIssueTransferEvent(body []byte) (*IssueTransferEvent, error) {
+	var event *IssueTransferEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	if event.GetAction() != "transferred" {
+		return nil, nil
+	}
+	return event, nil
+}
+
+// storeIssueTransfer records where an issue was transferred to, so that what refers to the issue
+// by its former repository and number can follow it. The transfer is forgotten after a while.
+func (p *Plugin) storeIssueTransfer(repository string, number int, to *issueLocation) error {
+	value, err := json.Marshal(to)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal issue transfer")
+	}
+
+	if appErr := p.API.KVSetWithExpiry(issueTransferKeyFor(repository, number), value, int64(issueTransferTTL/time.Second)); appErr != nil {
+		return errors.Wrap(appErr, "could not store issue transfer in KV store")
+	}
+
+	return nil
+}
+
+// resolveIssueTransfers returns where an issue is after all its recorded transfers, or nil if it
+// wasn't transferred.
+func (p *Plugin) resolveIssueTransfers(repository string, number int) (*issueLocation, error) {
+	var location *issueLocation
+	for i := 0; i < maxIssueTransfers; i++ {
+		value, appErr := p.API.KVGet(issueTransferKeyFor(repository, number))
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "could not get issue transfer from KV store")
+		}
+		if value == nil {
+			break
+		}
+
+		location = &issueLocation{}
+		if err := json.Unmarshal(value, location); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal issue transfer")
+		}
+		repository, number = location.Repository, location.Number
+	}
+
+	return location, nil
+}
+
+// handleIssueTransferEvent handles an issues event with the transferred action.
+func (p *Plugin) handleIssueTransferEvent(event *IssueTransferEvent, delivery *webhookDelivery) {
+	repo := event.GetRepo()
+	newIssue := event.GetNewIssue()
+	newRepo := event.GetNewRepo()
+	if newIssue != nil && newRepo != nil {
+		to := &issueLocation{Repository: newRepo.GetFullName(), Number: newIssue.GetNumber()}
+		if err := p.storeIssueTransfer(repo.GetFullName(), event.GetIssue().GetNumber(), to); err != nil {
+			p.API.LogWarn("Failed to store issue transfer", "repo", repo.GetFullName(), "number", event.GetIssue().GetNumber(), "error", err.Error())
+		}
+	}
+
+	p.postIssueTransferEvent(event, delivery)
+}
+
+func (p *Plugin) postIssueTransferEvent(event *IssueTransferEvent, delivery *webhookDelivery) {
+	repo := event.GetRepo()
+	issue := event.GetIssue()
+
+	subs := delivery.matchSubscriptions(p.GetSubscribedChannelsForRepository(repo))
+	if len(subs) == 0 {
+		return
+	}
+
+	message, err := renderTemplate("transferredIssue", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	post := &model.Post{
+		UserId:  p.BotUserID,
+		Type:    "custom_git_issue",
+		Props:   eventPostProps(repo.GetFullName(), objectTypeIssue, strconv.Itoa(issue.GetNumber()), issuesEventType+".transferred"),
+		Message: message,
+	}
+
+	labels := make([]string, len(issue.Labels))
+	for i, v := range issue.Labels {
+		labels[i] = v.GetName()
+	}
+
+	for _, sub := range subs {
+		if !sub.Issues() {
+			continue
+		}
+
+		if p.excludeSender(event.GetSender(), sub) {
+			continue
+		}
+
+		label := sub.Label()
+		if label != "" && !containsValue(labels, label) {
+			continue
+		}
+
+		post.ChannelId = sub.ChannelID
+		p.postSubscriptionEvent(post, sub, featureIssues, delivery)
+	}
+}
+
+// followAttachedCommentTransfers moves a comment attached to an issue that was since transferred to
+// the issue's new repository and number, and updates the confirmation reply accordingly.
+func (p *Plugin) followAttachedCommentTransfers(comment *attachedComment, reply *model.Post) {
+	location, err := p.resolveIssueTransfers(fullNameFromOwnerAndRepo(comment.Owner, comment.Repo), comment.Number)
+	if err != nil {
+		p.API.LogWarn("Failed to resolve issue transfers", "issue", comment.issueReference(), "error", err.Error())
+		return
+	}
+	if location == nil {
+		return
+	}
+
+	owner, repo, err := parseRepo(location.Repository)
+	if err != nil {
+		return
+	}
+	comment.Owner, comment.Repo, comment.Number = owner, repo, location.Number
+
+	comment.setProps(reply)
+	if _, appErr := p.API.UpdatePost(reply); appErr != nil {
+		p.API.LogWarn("Failed to update attached comment reply", "postID", reply.Id, "error", appErr.Error())
+	}
+}
@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// issueTransferFixture is an issues payload of a transfer as sent by GitHub, trimmed to the fields in use.
+const issueTransferFixture = `{
+  "action": "%s",
+  "issue": {
+    "number": 12,
+    "title": "Crash on startup",
+    "html_url": "https://github.com/owner/repo/issues/12",
+    "labels": []
+  },
+  "changes": {
+    "new_issue": {
+      "number": 34,
+      "html_url": "https://github.com/other-org/other-repo/issues/34"
+    },
+    "new_repository": {
+      "full_name": "other-org/other-repo"
+    }
+  },
+  "repository": {
+    "full_name": "owner/repo",
+    "html_url": "https://github.com/owner/repo",
+    "private": false
+  },
+  "sender": {"login": "panda", "html_url": "https://github.com/panda"}
+}`
+
+func TestParseIssueTransferEvent(t *testing.T) {
+	event, err := parseIssueTransferEvent([]byte(fmt.Sprintf(issueTransferFixture, "transferred")))
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, 12, event.GetIssue().GetNumber())
+	assert.Equal(t, 34, event.GetNewIssue().GetNumber())
+	assert.Equal(t, "other-org/other-repo", event.GetNewRepo().GetFullName())
+
+	event, err = parseIssueTransferEvent([]byte(fmt.Sprintf(issueTransferFixture, "opened")))
+	require.NoError(t, err)
+	assert.Nil(t, event)
+}
+
+func TestTransferredIssueTemplate(t *testing.T) {
+	event, err := parseIssueTransferEvent([]byte(fmt.Sprintf(issueTransferFixture, "transferred")))
+	require.NoError(t, err)
+
+	expected := `
+[\[owner/repo\]](https://github.com/owner/repo) Issue [#12 Crash on startup](https://github.com/owner/repo/issues/12) was transferred to [other-org/other-repo#34](https://github.com/other-org/other-repo/issues/34) by [panda](https://github.com/panda).
+`
+	actual, err := renderTemplate("transferredIssue", event)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestResolveIssueTransfers(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(issueTransferTTL/time.Second)).Return(func(key string, value []byte, _ int64) *model.AppError {
+		store[key] = value
+		return nil
+	})
+	p.SetAPI(api)
+
+	location, err := p.resolveIssueTransfers("owner/repo", 12)
+	require.NoError(t, err)
+	assert.Nil(t, location)
+
+	require.NoError(t, p.storeIssueTransfer("owner/repo", 12, &issueLocation{Repository: "other-org/other-repo", Number: 34}))
+	require.NoError(t, p.storeIssueTransfer("other-org/other-repo", 34, &issueLocation{Repository: "third/repo", Number: 5}))
+
+	location, err = p.resolveIssueTransfers("owner/repo", 12)
+	require.NoError(t, err)
+	assert.Equal(t, &issueLocation{Repository: "third/repo", Number: 5}, location)
+
+	location, err = p.resolveIssueTransfers("other-org/other-repo", 34)
+	require.NoError(t, err)
+	assert.Equal(t, &issueLocation{Repository: "third/repo", Number: 5}, location)
+}
+
+func TestHandleIssueTransferWebhook(t *testing.T) {
+	body := []byte(fmt.Sprintf(issueTransferFixture, "transferred"))
+
+	for name, tc := range map[string]struct {
+		subscriptions map[string][]*Subscription
+	}{
+		"transfer within subscribed repositories": {
+			subscriptions: map[string][]*Subscription{
+				"owner/repo":           {{ChannelID: "channelID", Features: featureIssues, Repository: "owner/repo"}},
+				"other-org/other-repo": {{ChannelID: "channelID", Features: featureIssues, Repository: "other-org/other-repo"}},
+			},
+		},
+		"transfer to an unsubscribed repository": {
+			subscriptions: map[string][]*Subscription{
+				"owner/repo": {{ChannelID: "channelID", Features: featureIssues, Repository: "owner/repo"}},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			subscriptions, err := json.Marshal(&Subscriptions{Repositories: tc.subscriptions})
+			require.NoError(t, err)
+
+			p := NewPlugin()
+			p.BotUserID = "botID"
+			p.setConfiguration(&Configuration{})
+			api := &plugintest.API{}
+			api.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+			api.On("KVGet", SubscriptionsKey).Return(subscriptions, nil)
+			api.On("KVGet", RoutesKey).Return(nil, nil)
+			api.On("KVSetWithExpiry", issueTransferKeyFor("owner/repo", 12), []byte(`{"repository":"other-org/other-repo","number":34}`), int64(issueTransferTTL/time.Second)).Return(nil).Once()
+			api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+			api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+				return post.ChannelId == "channelID" &&
+					post.GetProp(postPropEventType) == "issues.transferred" &&
+					post.GetProp(postPropRepository) == "owner/repo"
+			})).Return(&model.Post{}, nil).Once()
+			mockDeliveryLogs(api)
+			p.SetAPI(api)
+			defer api.AssertExpectations(t)
+
+			require.NoError(t, p.processWebhookEvent(issuesEventType, "", body, false))
+		})
+	}
+}
+
+func TestFollowAttachedCommentTransfers(t *testing.T) {
+	location, err := json.Marshal(&issueLocation{Repository: "other-org/other-repo", Number: 34})
+	require.NoError(t, err)
+
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("KVGet", issueTransferKeyFor("owner/repo", 12)).Return(location, nil)
+	api.On("KVGet", issueTransferKeyFor("other-org/other-repo", 34)).Return(nil, nil)
+	api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.GetProp(propCommentRepo) == "other-org/other-repo" && post.GetProp(propCommentIssueNumber) == "34"
+	})).Return(&model.Post{}, nil).Once()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	comment := &attachedComment{AttachedPostID: "postID", Owner: "owner", Repo: "repo", Number: 12, CommentID: 1}
+	reply := &model.Post{Id: "replyID"}
+	comment.setProps(reply)
+
+	p.followAttachedCommentTransfers(comment, reply)
+	assert.Equal(t, "other-org/other-repo#34", comment.issueReference())
+}
@@ -0,0 +1,401 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	KeywordRulesKey = "keyword_rules"
+
+	// maxKeywordRulesPerChannel caps the number of keyword rules of a channel.
+	maxKeywordRulesPerChannel = 20
+	maxKeywordLength          = 100
+	// keywordExcerptContext is the number of characters kept on each side of a match in the excerpt.
+	keywordExcerptContext = 80
+)
+
+// errTooManyKeywordRules is returned when adding a keyword rule to a channel that has too many.
+var errTooManyKeywordRules = errors.Errorf("A channel can have at most %d keywords. Please remove one first.", maxKeywordRulesPerChannel)
+
+var teamMentionRegex = regexp.MustCompile(`^@[[:alnum:]][\w.-]*/[\w.-]+$`)
+
+// KeywordRule posts the new issues, pull requests and comments mentioning a GitHub team or
+// containing a keyword to a channel, regardless of its subscriptions.
+type KeywordRule struct {
+	Pattern   string
+	CreatorID string
+}
+
+type KeywordRules struct {
+	Channels map[string][]*KeywordRule
+}
+
+// isTeamMention tells if the rule matches mentions of a GitHub team, e.g. @org/team.
+func (r *KeywordRule) isTeamMention() bool {
+	return strings.HasPrefix(r.Pattern, "@")
+}
+
+// regexp returns the expression matching the rule. Team mentions are matched exactly, and keywords
+// as whole words. Both are case-insensitive, like GitHub logins.
+func (r *KeywordRule) regexp() *regexp.Regexp {
+	if r.isTeamMention() {
+		return regexp.MustCompile(`(?i)(?:^|[^\w@/-])(` + regexp.QuoteMeta(r.Pattern) + `)(?:$|[^\w/-])`)
+	}
+
+	return regexp.MustCompile(`(?i)(?:^|[^\w])(` + regexp.QuoteMeta(r.Pattern) + `)(?:$|[^\w])`)
+}
+
+// checkKeywordPattern validates the pattern of a keyword rule.
+func checkKeywordPattern(pattern string) error {
+	if pattern == "" || len(pattern) > maxKeywordLength {
+		return errors.Errorf("Please specify a keyword of at most %d characters or a team mention like `@org/team`.", maxKeywordLength)
+	}
+
+	if strings.HasPrefix(pattern, "@") && !teamMentionRegex.MatchString(pattern) {
+		return errors.Errorf("Invalid team mention %s. Use `@org/team`.", pattern)
+	}
+
+	return nil
+}
+
+func (p *Plugin) GetKeywordRules() (*KeywordRules, error) {
+	var rules *KeywordRules
+
+	value, appErr := p.API.KVGet(KeywordRulesKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get keyword rules from KVStore")
+	}
+
+	if value == nil {
+		return &KeywordRules{Channels: map[string][]*KeywordRule{}}, nil
+	}
+
+	err := json.NewDecoder(bytes.NewReader(value)).Decode(&rules)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not properly decode keyword rules key")
+	}
+
+	return rules, nil
+}
+
+func (p *Plugin) StoreKeywordRules(rules *KeywordRules) error {
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return errors.Wrap(err, "error while converting keyword rules map to json")
+	}
+
+	if appErr := p.API.KVSet(KeywordRulesKey, b); appErr != nil {
+		return errors.Wrap(appErr, "could not store keyword rules in KV store")
+	}
+
+	return nil
+}
+
+// AddKeywordRule stores a keyword rule of a channel. It fails if the channel has too many rules,
+// and does nothing if the channel already has a rule with the same pattern.
+func (p *Plugin) AddKeywordRule(channelID string, rule *KeywordRule) (bool, error) {
+	rules, err := p.GetKeywordRules()
+	if err != nil {
+		return false, errors.Wrap(err, "could not get keyword rules")
+	}
+
+	channelRules := rules.Channels[channelID]
+	for _, r := range channelRules {
+		if strings.EqualFold(r.Pattern, rule.Pattern) {
+			return false, nil
+		}
+	}
+
+	if len(channelRules) >= maxKeywordRulesPerChannel {
+		return false, errTooManyKeywordRules
+	}
+
+	rules.Channels[channelID] = append(channelRules, rule)
+
+	if err := p.StoreKeywordRules(rules); err != nil {
+		return false, errors.Wrap(err, "could not store keyword rules")
+	}
+
+	return true, nil
+}
+
+// DeleteKeywordRule removes the keyword rule of a channel with the given pattern. It reports whether a rule was removed.
+func (p *Plugin) DeleteKeywordRule(channelID, pattern string) (bool, error) {
+	rules, err := p.GetKeywordRules()
+	if err != nil {
+		return false, errors.Wrap(err, "could not get keyword rules")
+	}
+
+	channelRules := rules.Channels[channelID]
+	for index, r := range channelRules {
+		if !strings.EqualFold(r.Pattern, pattern) {
+			continue
+		}
+
+		channelRules = append(channelRules[:index], channelRules[index+1:]...)
+		if len(channelRules) == 0 {
+			delete(rules.Channels, channelID)
+		} else {
+			rules.Channels[channelID] = channelRules
+		}
+
+		if err := p.StoreKeywordRules(rules); err != nil {
+			return false, errors.Wrap(err, "could not store keyword rules")
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// keywordMatch is a match of the keyword rules of a channel in a text.
+type keywordMatch struct {
+	Patterns []string
+	Excerpt  string
+}
+
+// matchKeywordRules returns the patterns of the rules matching the given texts, along with an
+// excerpt around the first match, or nil if none matches.
+func matchKeywordRules(rules []*KeywordRule, texts ...string) *keywordMatch {
+	var match *keywordMatch
+	for _, rule := range rules {
+		re := rule.regexp()
+		for _, text := range texts {
+			loc := re.FindStringSubmatchIndex(text)
+			if loc == nil {
+				continue
+			}
+
+			if match == nil {
+				match = &keywordMatch{Excerpt: highlightExcerpt(text, loc[2], loc[3])}
+			}
+			match.Patterns = append(match.Patterns, rule.Pattern)
+			break
+		}
+	}
+
+	return match
+}
+
+// highlightExcerpt returns the text around a match on a single line, with the match in bold.
+func highlightExcerpt(text string, start, end int) string {
+	before := []rune(text[:start])
+	after := []rune(text[end:])
+
+	prefix, suffix := "", ""
+	if len(before) > keywordExcerptContext {
+		before = before[len(before)-keywordExcerptContext:]
+		prefix = "…"
+	}
+	if len(after) > keywordExcerptContext {
+		after = after[:keywordExcerptContext]
+		suffix = "…"
+	}
+
+	excerpt := prefix + string(before) + "**" + text[start:end] + "**" + string(after) + suffix
+	return strings.Join(strings.Fields(excerpt), " ")
+}
+
+// keywordMention is a new issue, pull request or comment matching the keyword rules of a channel.
+type keywordMention struct {
+	Repo   *github.Repository
+	Sender *github.User
+	Kind   string
+	Title  string
+	URL    string
+	keywordMatch
+}
+
+// postKeywordMentions posts a new issue, pull request or comment to the channels with keyword rules
+// matching its title or body, once per channel. Private repositories are only posted about if the
+// creator of a matching rule has access to them.
+func (p *Plugin) postKeywordMentions(mention *keywordMention, texts ...string) {
+	rules, err := p.GetKeywordRules()
+	if err != nil {
+		p.API.LogWarn("Failed to get keyword rules", "error", err.Error())
+		return
+	}
+
+	channelIDs := make([]string, 0, len(rules.Channels))
+	for channelID := range rules.Channels {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Strings(channelIDs)
+
+	for _, channelID := range channelIDs {
+		channelRules := []*KeywordRule{}
+		for _, rule := range rules.Channels[channelID] {
+			if mention.Repo.GetPrivate() && !p.permissionToRepo(rule.CreatorID, mention.Repo.GetFullName()) {
+				continue
+			}
+			channelRules = append(channelRules, rule)
+		}
+
+		match := matchKeywordRules(channelRules, texts...)
+		if match == nil {
+			continue
+		}
+		mention.keywordMatch = *match
+
+		message, err := renderTemplate("keywordMention", mention)
+		if err != nil {
+			p.API.LogWarn("Failed to render template", "error", err.Error())
+			return
+		}
+
+		post := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: channelID,
+			Message:   message,
+			Type:      "custom_git_keyword",
+		}
+		if _, appErr := p.API.CreatePost(post); appErr != nil {
+			p.API.LogWarn("Failed to post keyword mention", "channelID", channelID, "error", appErr.Error())
+		}
+	}
+}
+
+// handleKeywordMentions posts the new issues, pull requests and comments matching keyword rules.
+func (p *Plugin) handleKeywordMentions(event interface{}) {
+	switch event := event.(type) {
+	case *github.IssuesEvent:
+		if event.GetAction() != "opened" {
+			return
+		}
+		issue := event.GetIssue()
+		p.postKeywordMentions(&keywordMention{
+			Repo: event.GetRepo(), Sender: event.GetSender(), Kind: "issue", Title: issue.GetTitle(), URL: issue.GetHTMLURL(),
+		}, issue.GetTitle(), issue.GetBody())
+	case *github.PullRequestEvent:
+		if event.GetAction() != "opened" {
+			return
+		}
+		pr := event.GetPullRequest()
+		p.postKeywordMentions(&keywordMention{
+			Repo: event.GetRepo(), Sender: event.GetSender(), Kind: "pull request", Title: pr.GetTitle(), URL: pr.GetHTMLURL(),
+		}, pr.GetTitle(), pr.GetBody())
+	case *github.IssueCommentEvent:
+		if event.GetAction() != "created" {
+			return
+		}
+		p.postKeywordMentions(&keywordMention{
+			Repo: event.GetRepo(), Sender: event.GetSender(), Kind: "comment on", Title: event.GetIssue().GetTitle(), URL: event.GetComment().GetHTMLURL(),
+		}, event.GetComment().GetBody())
+	case *github.PullRequestReviewCommentEvent:
+		if event.GetAction() != "created" {
+			return
+		}
+		p.postKeywordMentions(&keywordMention{
+			Repo: event.GetRepo(), Sender: event.GetSender(), Kind: "review comment on", Title: event.GetPullRequest().GetTitle(), URL: event.GetComment().GetHTMLURL(),
+		}, event.GetComment().GetBody())
+	}
+}
+
+func (p *Plugin) handleKeywords(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
+	if !p.isSystemAdmin(args.UserId) {
+		return "Only system administrators can manage keywords."
+	}
+
+	if len(parameters) == 0 {
+		return "Invalid keywords command. Available commands are 'list', 'add' and 'remove'."
+	}
+
+	command := parameters[0]
+	parameters = parameters[1:]
+
+	channelID := args.ChannelId
+	channelName := "this channel"
+	if len(parameters) >= 2 && parameters[len(parameters)-2] == "--channel" {
+		if target := parameters[len(parameters)-1]; target != "here" {
+			channel, err := p.getRouteTargetChannel(args.TeamId, target)
+			if err != nil {
+				return err.Error()
+			}
+			channelID = channel.Id
+			channelName = "~" + channel.Name
+		}
+		parameters = parameters[:len(parameters)-2]
+	}
+
+	switch command {
+	case "list":
+		return p.handleKeywordsList(channelID, channelName)
+	case "add":
+		if len(parameters) != 1 {
+			return "Please specify a single keyword or team mention, e.g. `/github keywords add @org/team`."
+		}
+		return p.handleKeywordsAdd(args, channelID, channelName, strings.Trim(parameters[0], `"`))
+	case "remove":
+		if len(parameters) != 1 {
+			return "Please specify the keyword or team mention to remove."
+		}
+		return p.handleKeywordsRemove(channelID, channelName, strings.Trim(parameters[0], `"`))
+	default:
+		return fmt.Sprintf("Unknown subcommand %v", command)
+	}
+}
+
+func (p *Plugin) handleKeywordsList(channelID, channelName string) string {
+	rules, err := p.GetKeywordRules()
+	if err != nil {
+		return err.Error()
+	}
+
+	channelRules := rules.Channels[channelID]
+	if len(channelRules) == 0 {
+		return fmt.Sprintf("Currently there are no keywords for %s.", channelName)
+	}
+
+	txt := fmt.Sprintf("### Keywords for %s\n", channelName)
+	for _, rule := range channelRules {
+		txt += fmt.Sprintf("* `%s`\n", rule.Pattern)
+	}
+
+	return txt
+}
+
+func (p *Plugin) handleKeywordsAdd(args *model.CommandArgs, channelID, channelName, pattern string) string {
+	if err := checkKeywordPattern(pattern); err != nil {
+		return err.Error()
+	}
+
+	added, err := p.AddKeywordRule(channelID, &KeywordRule{Pattern: pattern, CreatorID: args.UserId})
+	if err == errTooManyKeywordRules {
+		return err.Error()
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to add keyword rule", "channelID", channelID, "error", err.Error())
+		return "Encountered an error trying to add the keyword. Please try again."
+	}
+
+	if !added {
+		return fmt.Sprintf("`%s` is already a keyword for %s.", pattern, channelName)
+	}
+
+	return fmt.Sprintf("New issues, pull requests and comments mentioning `%s` will be posted to %s.", pattern, channelName)
+}
+
+func (p *Plugin) handleKeywordsRemove(channelID, channelName, pattern string) string {
+	removed, err := p.DeleteKeywordRule(channelID, pattern)
+	if err != nil {
+		p.API.LogWarn("Failed to delete keyword rule", "channelID", channelID, "error", err.Error())
+		return "Encountered an error trying to remove the keyword. Please try again."
+	}
+
+	if !removed {
+		return fmt.Sprintf("`%s` is not a keyword for %s.", pattern, channelName)
+	}
+
+	return fmt.Sprintf("Successfully removed the keyword `%s` for %s.", pattern, channelName)
+}
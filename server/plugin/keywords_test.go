@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchKeywordRules(t *testing.T) {
+	rules := []*KeywordRule{{Pattern: "@org/payments"}, {Pattern: "outage"}, {Pattern: "PCI"}}
+
+	for name, tc := range map[string]struct {
+		texts    []string
+		patterns []string
+		excerpt  string
+	}{
+		"no match": {
+			texts: []string{"Refactor the parser", "cc @org/payments-api, outages, pcie"},
+		},
+		"team mention": {
+			texts:    []string{"Refund flow", "cc @Org/Payments for review"},
+			patterns: []string{"@org/payments"},
+			excerpt:  "cc **@Org/Payments** for review",
+		},
+		"keywords in several texts": {
+			texts:    []string{"Outage of the checkout", "Might affect pci\ncompliance"},
+			patterns: []string{"outage", "PCI"},
+			excerpt:  "**Outage** of the checkout",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			match := matchKeywordRules(rules, tc.texts...)
+			if tc.patterns == nil {
+				assert.Nil(t, match)
+				return
+			}
+			require.NotNil(t, match)
+			assert.Equal(t, tc.patterns, match.Patterns)
+			assert.Equal(t, tc.excerpt, match.Excerpt)
+		})
+	}
+}
+
+func TestHighlightExcerpt(t *testing.T) {
+	text := "start " + string(make([]byte, 200)) + " outage\n\nmore"
+	excerpt := highlightExcerpt("a\nlong\ttext about an outage here", 21, 27)
+	assert.Equal(t, "a long text about an **outage** here", excerpt)
+
+	excerpt = highlightExcerpt(text, len(text)-12, len(text)-6)
+	assert.True(t, len(excerpt) < len(text))
+	assert.Contains(t, excerpt, "…")
+	assert.Contains(t, excerpt, "**outage** more")
+}
+
+func TestCheckKeywordPattern(t *testing.T) {
+	assert.NoError(t, checkKeywordPattern("outage"))
+	assert.NoError(t, checkKeywordPattern("@org/team-a"))
+	assert.Error(t, checkKeywordPattern(""))
+	assert.Error(t, checkKeywordPattern("@org"))
+	assert.Error(t, checkKeywordPattern(string(make([]byte, maxKeywordLength+1))))
+}
+
+func TestKeywordsCommand(t *testing.T) {
+	setup := func() (*Plugin, *plugintest.API) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		store := map[string][]byte{}
+		api.On("KVGet", KeywordRulesKey).Return(func(key string) []byte { return store[key] }, nil)
+		api.On("KVSet", KeywordRulesKey, mock.Anything).Return(func(key string, value []byte) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("HasPermissionTo", "adminID", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+		api.On("HasPermissionTo", "userID", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+		p.SetAPI(api)
+		return p, api
+	}
+	args := &model.CommandArgs{UserId: "adminID", ChannelId: "channelID", TeamId: "teamID"}
+
+	t.Run("admin only", func(t *testing.T) {
+		p, _ := setup()
+		message := p.handleKeywords(nil, &model.CommandArgs{UserId: "userID"}, []string{"list"}, nil)
+		assert.Equal(t, "Only system administrators can manage keywords.", message)
+	})
+
+	t.Run("add, list and remove", func(t *testing.T) {
+		p, _ := setup()
+
+		assert.Equal(t, "Currently there are no keywords for this channel.", p.handleKeywords(nil, args, []string{"list"}, nil))
+		assert.Contains(t, p.handleKeywords(nil, args, []string{"add", "@org/payments"}, nil), "will be posted to this channel")
+		assert.Contains(t, p.handleKeywords(nil, args, []string{"add", "outage", "--channel", "here"}, nil), "will be posted to this channel")
+		assert.Equal(t, "`Outage` is already a keyword for this channel.", p.handleKeywords(nil, args, []string{"add", "Outage"}, nil))
+		assert.Equal(t, "### Keywords for this channel\n* `@org/payments`\n* `outage`\n", p.handleKeywords(nil, args, []string{"list"}, nil))
+
+		assert.Equal(t, "Successfully removed the keyword `outage` for this channel.", p.handleKeywords(nil, args, []string{"remove", "outage"}, nil))
+		assert.Equal(t, "`outage` is not a keyword for this channel.", p.handleKeywords(nil, args, []string{"remove", "outage"}, nil))
+		assert.Equal(t, "### Keywords for this channel\n* `@org/payments`\n", p.handleKeywords(nil, args, []string{"list"}, nil))
+	})
+
+	t.Run("other channel", func(t *testing.T) {
+		p, api := setup()
+		api.On("GetChannelByName", "teamID", "incidents", false).Return(&model.Channel{Id: "incidentsID", Name: "incidents", Type: model.CHANNEL_OPEN}, nil)
+
+		assert.Contains(t, p.handleKeywords(nil, args, []string{"add", "outage", "--channel", "~incidents"}, nil), "will be posted to ~incidents")
+		assert.Equal(t, "Currently there are no keywords for this channel.", p.handleKeywords(nil, args, []string{"list"}, nil))
+		assert.Equal(t, "### Keywords for ~incidents\n* `outage`\n", p.handleKeywords(nil, args, []string{"list", "--channel", "~incidents"}, nil))
+	})
+
+	t.Run("cap per channel", func(t *testing.T) {
+		p, _ := setup()
+		for i := 0; i < maxKeywordRulesPerChannel; i++ {
+			_, err := p.AddKeywordRule("channelID", &KeywordRule{Pattern: string(rune('a' + i))})
+			require.NoError(t, err)
+		}
+
+		assert.Contains(t, p.handleKeywords(nil, args, []string{"add", "outage"}, nil), "at most 20 keywords")
+	})
+}
+
+func TestHandleKeywordMentions(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	rules := []byte(`{"Channels": {
+		"channel1": [{"Pattern": "outage", "CreatorID": "adminID"}, {"Pattern": "@org/payments", "CreatorID": "adminID"}],
+		"channel2": [{"Pattern": "refund", "CreatorID": "adminID"}]
+	}}`)
+	api.On("KVGet", KeywordRulesKey).Return(rules, nil)
+	p.SetAPI(api)
+
+	event := &github.IssuesEvent{
+		Action: github.String("opened"),
+		Repo:   &github.Repository{FullName: github.String("owner/repo"), HTMLURL: github.String("https://github.com/owner/repo")},
+		Sender: &github.User{Login: github.String("octocat"), HTMLURL: github.String("https://github.com/octocat")},
+		Issue: &github.Issue{
+			Title:   github.String("Outage of the checkout"),
+			Body:    github.String("Paging @org/payments, the outage started at noon."),
+			HTMLURL: github.String("https://github.com/owner/repo/issues/1"),
+		},
+	}
+
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "channel1"
+	})).Return(&model.Post{}, nil).Once()
+
+	p.handleKeywordMentions(event)
+
+	api.AssertNumberOfCalls(t, "CreatePost", 1)
+	post := api.Calls[len(api.Calls)-1].Arguments.Get(0).(*model.Post)
+	assert.Contains(t, post.Message, "issue [Outage of the checkout](https://github.com/owner/repo/issues/1)")
+	assert.Contains(t, post.Message, "mentions `outage`, `@org/payments`")
+	assert.Contains(t, post.Message, "> **Outage** of the checkout")
+
+	// Only opened issues are posted
+	event.Action = github.String("edited")
+	p.handleKeywordMentions(event)
+	api.AssertNumberOfCalls(t, "CreatePost", 1)
+}
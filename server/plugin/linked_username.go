@@ -0,0 +1,238 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	linkUsernameCommand   = "link-username"
+	unlinkUsernameCommand = "unlink-username"
+
+	linkedUsernameKey      = "_linkedusername"
+	pendingUsernameLinkKey = "_pendingusernamelink"
+	// pendingUsernameLinkTTL is how long users have to add their verification code to GitHub, in seconds.
+	pendingUsernameLinkTTL = 60 * 60
+	// usernameLinkGistPageSize is the number of the most recent public gists looked at for a verification code.
+	usernameLinkGistPageSize = 30
+)
+
+var githubLoginRegex = regexp.MustCompile(`^[[:alnum:]](?:[[:alnum:]-]{0,38})$`)
+
+// linkedUsername is a GitHub username a user linked to their Mattermost account without connecting
+// it. Linked users get notified about their mentions, but there is no token to act on their behalf.
+type linkedUsername struct {
+	GitHubUsername string
+	LinkedAt       time.Time
+}
+
+// pendingUsernameLink is a GitHub username waiting for its owner to prove they own it by adding
+// Code to their profile bio or the description of a public gist.
+type pendingUsernameLink struct {
+	GitHubUsername string
+	Code           string
+}
+
+func (p *Plugin) getLinkedUsername(userID string) (*linkedUsername, error) {
+	value, appErr := p.API.KVGet(userID + linkedUsernameKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get linked username from KV store")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	var linked linkedUsername
+	if err := json.Unmarshal(value, &linked); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal linked username")
+	}
+
+	return &linked, nil
+}
+
+func (p *Plugin) getPendingUsernameLink(userID string) (*pendingUsernameLink, error) {
+	value, appErr := p.API.KVGet(userID + pendingUsernameLinkKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get pending username link from KV store")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	var pending pendingUsernameLink
+	if err := json.Unmarshal(value, &pending); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal pending username link")
+	}
+
+	return &pending, nil
+}
+
+// removeLinkedUsername unlinks the GitHub username a user linked, if any. The mapping of the
+// username is only removed if it still points to the user.
+func (p *Plugin) removeLinkedUsername(userID string) (*linkedUsername, error) {
+	linked, err := p.getLinkedUsername(userID)
+	if err != nil || linked == nil {
+		return nil, err
+	}
+
+	if p.getGitHubToUserIDMapping(linked.GitHubUsername) == userID {
+		if appErr := p.API.KVDelete(linked.GitHubUsername + githubUsernameKey); appErr != nil {
+			return nil, errors.Wrap(appErr, "could not delete username mapping from KV store")
+		}
+	}
+
+	if appErr := p.API.KVDelete(userID + linkedUsernameKey); appErr != nil {
+		return nil, errors.Wrap(appErr, "could not delete linked username from KV store")
+	}
+
+	return linked, nil
+}
+
+// hasUsernameLinkCode checks if a GitHub user added a verification code to their profile bio or to
+// the description of one of their most recent public gists. Only public data is read, without a token.
+func hasUsernameLinkCode(ctx context.Context, githubClient *github.Client, login, code string) (bool, error) {
+	user, _, err := githubClient.Users.Get(ctx, login)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get GitHub user")
+	}
+	if strings.Contains(user.GetBio(), code) {
+		return true, nil
+	}
+
+	gists, _, err := githubClient.Gists.List(ctx, login, &github.GistListOptions{ListOptions: github.ListOptions{PerPage: usernameLinkGistPageSize}})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to list gists")
+	}
+	for _, gist := range gists {
+		if strings.Contains(gist.GetDescription(), code) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// handleLinkUsername starts linking a GitHub username, or verifies the pending link with `verify`.
+// It's available to users who didn't connect their account.
+func (p *Plugin) handleLinkUsername(_ *plugin.Context, args *model.CommandArgs, parameters []string, _ *GitHubUserInfo) string {
+	if len(parameters) != 1 {
+		return "Please specify your GitHub username, e.g. `/github link-username octocat`."
+	}
+
+	if p.isConnected(args.UserId) {
+		return "Your Mattermost account is already connected to GitHub. Linking a username is only needed to get notified without connecting."
+	}
+
+	linked, err := p.getLinkedUsername(args.UserId)
+	if err != nil {
+		p.API.LogWarn("Failed to get linked username", "userID", args.UserId, "error", err.Error())
+		return "Encountered an error linking your GitHub username. Please try again."
+	}
+	if linked != nil {
+		return fmt.Sprintf("You already linked the GitHub username @%s. Run `/github unlink-username` first to link another one.", linked.GitHubUsername)
+	}
+
+	if parameters[0] == "verify" {
+		return p.verifyUsernameLink(args.UserId)
+	}
+
+	login := strings.TrimPrefix(parameters[0], "@")
+	if !githubLoginRegex.MatchString(login) {
+		return fmt.Sprintf("%s is not a valid GitHub username.", parameters[0])
+	}
+	if p.getGitHubToUserIDMapping(login) != "" {
+		return fmt.Sprintf("The GitHub username @%s is already linked to a Mattermost account.", login)
+	}
+
+	pending := &pendingUsernameLink{
+		GitHubUsername: login,
+		Code:           "mattermost-" + model.NewId()[:10],
+	}
+	value, err := json.Marshal(pending)
+	if err != nil {
+		p.API.LogWarn("Failed to marshal pending username link", "error", err.Error())
+		return "Encountered an error linking your GitHub username. Please try again."
+	}
+	if appErr := p.API.KVSetWithExpiry(args.UserId+pendingUsernameLinkKey, value, pendingUsernameLinkTTL); appErr != nil {
+		p.API.LogWarn("Failed to store pending username link", "error", appErr.Error())
+		return "Encountered an error linking your GitHub username. Please try again."
+	}
+
+	return fmt.Sprintf("To prove you own @%s, add the code `%s` to your [GitHub profile bio](%ssettings/profile) or to the description of a public gist, then run `/github link-username verify` within an hour. You can remove the code once verified.",
+		login, pending.Code, p.getBaseURL())
+}
+
+func (p *Plugin) verifyUsernameLink(userID string) string {
+	pending, err := p.getPendingUsernameLink(userID)
+	if err != nil {
+		p.API.LogWarn("Failed to get pending username link", "userID", userID, "error", err.Error())
+		return "Encountered an error verifying your GitHub username. Please try again."
+	}
+	if pending == nil {
+		return "There is no GitHub username to verify. Start with `/github link-username <GitHub username>`."
+	}
+
+	githubClient, err := newGitHubClient(nil, p.getConfiguration())
+	if err != nil {
+		p.API.LogWarn("Failed to create GitHub client", "error", err.Error())
+		return "Encountered an error verifying your GitHub username. Please try again."
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	found, err := hasUsernameLinkCode(ctx, githubClient, pending.GitHubUsername, pending.Code)
+	if err != nil {
+		p.API.LogWarn("Failed to verify username link", "login", pending.GitHubUsername, "error", err.Error())
+		return "Encountered an error reading your GitHub profile. Please try again in a few minutes."
+	}
+	if !found {
+		return fmt.Sprintf("The code `%s` wasn't found in the profile bio of @%s nor in the description of their recent public gists. GitHub can take a minute to show changes, please try again.",
+			pending.Code, pending.GitHubUsername)
+	}
+
+	if mappedUserID := p.getGitHubToUserIDMapping(pending.GitHubUsername); mappedUserID != "" && mappedUserID != userID {
+		return fmt.Sprintf("The GitHub username @%s is already linked to a Mattermost account.", pending.GitHubUsername)
+	}
+
+	value, err := json.Marshal(&linkedUsername{GitHubUsername: pending.GitHubUsername, LinkedAt: time.Now().UTC()})
+	if err != nil {
+		p.API.LogWarn("Failed to marshal linked username", "error", err.Error())
+		return "Encountered an error linking your GitHub username. Please try again."
+	}
+	if appErr := p.API.KVSet(userID+linkedUsernameKey, value); appErr != nil {
+		p.API.LogWarn("Failed to store linked username", "error", appErr.Error())
+		return "Encountered an error linking your GitHub username. Please try again."
+	}
+	if err := p.storeGitHubToUserIDMapping(pending.GitHubUsername, userID); err != nil {
+		p.API.LogWarn("Failed to store username mapping", "error", err.Error())
+		return "Encountered an error linking your GitHub username. Please try again."
+	}
+	if appErr := p.API.KVDelete(userID + pendingUsernameLinkKey); appErr != nil {
+		p.API.LogWarn("Failed to delete pending username link", "error", appErr.Error())
+	}
+
+	return fmt.Sprintf("Linked the GitHub username @%s. You'll now get notified here when you're mentioned, assigned or asked for a review on GitHub. Run `/github connect` to get your todo list, sidebar and more.", pending.GitHubUsername)
+}
+
+func (p *Plugin) handleUnlinkUsername(_ *plugin.Context, args *model.CommandArgs, _ []string, _ *GitHubUserInfo) string {
+	linked, err := p.removeLinkedUsername(args.UserId)
+	if err != nil {
+		p.API.LogWarn("Failed to unlink username", "userID", args.UserId, "error", err.Error())
+		return "Encountered an error unlinking your GitHub username. Please try again."
+	}
+	if linked == nil {
+		return "You didn't link a GitHub username."
+	}
+
+	return fmt.Sprintf("Unlinked the GitHub username @%s. You won't get notified about it anymore.", linked.GitHubUsername)
+}
@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkUsername(t *testing.T) {
+	bio := ""
+	gistDescription := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/users/alice":
+			fmt.Fprintf(w, `{"login": "alice", "bio": %q}`, bio)
+		case "/api/v3/users/alice/gists":
+			fmt.Fprintf(w, `[{"id": "1", "description": "Dotfiles"}, {"id": "2", "description": %q}]`, gistDescription)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func() (*Plugin, map[string][]byte) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		set := func(key string, value []byte) *model.AppError {
+			store[key] = value
+			return nil
+		}
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(set)
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(pendingUsernameLinkTTL)).Return(func(key string, value []byte, _ int64) *model.AppError {
+			return set(key, value)
+		})
+		api.On("KVDelete", mock.AnythingOfType("string")).Return(func(key string) *model.AppError {
+			delete(store, key)
+			return nil
+		})
+		p.SetAPI(api)
+		return p, store
+	}
+	args := &model.CommandArgs{UserId: "userID"}
+	codeRegex := regexp.MustCompile("`(mattermost-[a-z0-9]+)`")
+
+	t.Run("verification handshake", func(t *testing.T) {
+		p, store := setup()
+
+		message := p.handleLinkUsername(nil, args, []string{"@alice"}, nil)
+		match := codeRegex.FindStringSubmatch(message)
+		require.NotNil(t, match, message)
+		code := match[1]
+
+		// The code isn't on GitHub yet
+		bio, gistDescription = "Gopher", "Notes"
+		assert.Contains(t, p.handleLinkUsername(nil, args, []string{"verify"}, nil), "wasn't found in the profile bio of @alice")
+		assert.Empty(t, p.getGitHubToUserIDMapping("alice"))
+
+		gistDescription = "Verifying " + code
+		assert.Contains(t, p.handleLinkUsername(nil, args, []string{"verify"}, nil), "Linked the GitHub username @alice.")
+		assert.Equal(t, "userID", p.getGitHubToUserIDMapping("alice"))
+		assert.NotContains(t, store, "userID"+pendingUsernameLinkKey)
+		assert.NotContains(t, store, "userID"+githubTokenKey, "no token is stored")
+
+		linked, err := p.getLinkedUsername("userID")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", linked.GitHubUsername)
+
+		assert.Equal(t, "You already linked the GitHub username @alice. Run `/github unlink-username` first to link another one.",
+			p.handleLinkUsername(nil, args, []string{"bob"}, nil))
+
+		assert.Equal(t, "Unlinked the GitHub username @alice. You won't get notified about it anymore.", p.handleUnlinkUsername(nil, args, nil, nil))
+		assert.Empty(t, p.getGitHubToUserIDMapping("alice"))
+		assert.Equal(t, "You didn't link a GitHub username.", p.handleUnlinkUsername(nil, args, nil, nil))
+	})
+
+	t.Run("code in the profile bio", func(t *testing.T) {
+		p, _ := setup()
+
+		code := codeRegex.FindStringSubmatch(p.handleLinkUsername(nil, args, []string{"alice"}, nil))[1]
+		bio, gistDescription = "Gopher "+code, ""
+
+		assert.Contains(t, p.handleLinkUsername(nil, args, []string{"verify"}, nil), "Linked the GitHub username @alice.")
+	})
+
+	t.Run("nothing to verify", func(t *testing.T) {
+		p, _ := setup()
+
+		assert.Equal(t, "There is no GitHub username to verify. Start with `/github link-username <GitHub username>`.",
+			p.handleLinkUsername(nil, args, []string{"verify"}, nil))
+	})
+
+	t.Run("username already mapped", func(t *testing.T) {
+		p, store := setup()
+		store["alice"+githubUsernameKey] = []byte("otherUserID")
+
+		assert.Equal(t, "The GitHub username @alice is already linked to a Mattermost account.", p.handleLinkUsername(nil, args, []string{"alice"}, nil))
+	})
+
+	t.Run("connected user", func(t *testing.T) {
+		p, store := setup()
+		info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", GitHubUsername: "alice"})
+		require.NoError(t, err)
+		store["userID"+githubTokenKey] = info
+
+		assert.Contains(t, p.handleLinkUsername(nil, args, []string{"alice"}, nil), "already connected to GitHub")
+	})
+
+	t.Run("invalid username", func(t *testing.T) {
+		p, _ := setup()
+
+		assert.Equal(t, "-alice is not a valid GitHub username.", p.handleLinkUsername(nil, args, []string{"-alice"}, nil))
+	})
+
+	t.Run("connecting replaces the linked username", func(t *testing.T) {
+		p, store := setup()
+		linked, err := json.Marshal(&linkedUsername{GitHubUsername: "alice"})
+		require.NoError(t, err)
+		store["userID"+linkedUsernameKey] = linked
+		store["alice"+githubUsernameKey] = []byte("userID")
+
+		removed, err := p.removeLinkedUsername("userID")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", removed.GitHubUsername)
+		assert.Empty(t, store)
+	})
+}
@@ -60,6 +60,14 @@ const manifestStr = `
         "placeholder": "",
         "default": null
       },
+      {
+        "key": "WebhookPayloadSizeLimit",
+        "display_name": "Webhook Payload Size Limit (MB):",
+        "type": "number",
+        "help_text": "The maximum size of a webhook payload accepted from GitHub, in megabytes. Larger payloads are rejected.",
+        "placeholder": "",
+        "default": 5
+      },
       {
         "key": "EncryptionKey",
         "display_name": "At Rest Encryption Key:",
@@ -80,7 +88,7 @@ const manifestStr = `
         "key": "EnterpriseBaseURL",
         "display_name": "Enterprise Base URL:",
         "type": "text",
-        "help_text": "(Optional) The base URL for using the plugin with a GitHub Enterprise installation. Example: https://github.example.com",
+        "help_text": "(Optional) The base URL for using the plugin with a GitHub Enterprise installation, which may be served under a path. Example: https://github.example.com or https://git.example.com/github",
         "placeholder": "",
         "default": null
       },
@@ -100,6 +108,14 @@ const manifestStr = `
         "placeholder": "",
         "default": true
       },
+      {
+        "key": "RestrictIssueCreationToOrg",
+        "display_name": "Restrict Issue Creation to the Organization:",
+        "type": "bool",
+        "help_text": "(Optional) When true and a GitHub Organization is set, issues can only be created in the repositories of that organization.",
+        "placeholder": "",
+        "default": false
+      },
       {
         "key": "EnablePrivateRepo",
         "display_name": "Enable Private Repositories:",
@@ -108,11 +124,131 @@ const manifestStr = `
         "placeholder": "",
         "default": null
       },
+      {
+        "key": "AllowPersonalAccessTokens",
+        "display_name": "Allow Personal Access Tokens:",
+        "type": "bool",
+        "help_text": "(Optional) When true, users can connect their account with a GitHub personal access token using /github connect token, instead of the OAuth application. This is meant for users whose organization doesn't approve the OAuth application.",
+        "placeholder": "",
+        "default": false
+      },
+      {
+        "key": "UnreadsPageLimit",
+        "display_name": "Unread Notifications Page Limit:",
+        "type": "number",
+        "help_text": "The maximum number of pages of 50 GitHub notifications fetched to show the unread messages of a user.",
+        "placeholder": "",
+        "default": 5
+      },
+      {
+        "key": "DisabledCommands",
+        "display_name": "Disabled Commands:",
+        "type": "text",
+        "help_text": "(Optional) Comma-separated list of slash commands that users can't use, e.g. issue,reviewers. The corresponding actions of the webapp are disabled as well.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "TrustedPluginIDs",
+        "display_name": "Trusted Plugin IDs:",
+        "type": "text",
+        "help_text": "(Optional) Comma-separated list of the IDs of the plugins allowed to get the secrets of the configuration and the GitHub tokens of users. Other plugins get them masked.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "RefreshAllConcurrency",
+        "display_name": "Refresh All Concurrency:",
+        "type": "number",
+        "help_text": "The number of users whose sidebar is refreshed at the same time when a system admin refreshes the sidebars of all users.",
+        "placeholder": "",
+        "default": 5
+      },
+      {
+        "key": "RefreshAllDelay",
+        "display_name": "Refresh All Delay:",
+        "type": "number",
+        "help_text": "The delay in milliseconds between two sidebar refreshes of the same worker when a system admin refreshes the sidebars of all users. Spreads the resulting requests to GitHub.",
+        "placeholder": "",
+        "default": 200
+      },
+      {
+        "key": "IssueExportMaxIssues",
+        "display_name": "Issue Export Maximum:",
+        "type": "number",
+        "help_text": "The maximum number of open issues exported by /github issue export.",
+        "placeholder": "",
+        "default": 500
+      },
+      {
+        "key": "IssueLiveUpdateMaxAgeDays",
+        "display_name": "Issue Live Update Maximum Age (days):",
+        "type": "number",
+        "help_text": "The number of days during which the posts of new issues are updated when their title or labels change, for subscriptions with the --live-update flag.",
+        "placeholder": "",
+        "default": 30
+      },
+      {
+        "key": "EnableEventReplay",
+        "display_name": "Enable Event Replay:",
+        "type": "bool",
+        "help_text": "(Optional) Allow system admins to replay GitHub webhook deliveries, e.g. after fixing a misconfigured subscription. When true, the last received deliveries are kept to make replaying them easier.",
+        "placeholder": "",
+        "default": false
+      },
+      {
+        "key": "NotificationTemplates",
+        "display_name": "Notification Templates:",
+        "type": "longtext",
+        "help_text": "(Optional) Named templates subscriptions can select with --template [name], as a JSON object mapping template names to objects mapping event types to Go text/template strings, e.g. {\"terse\": {\"pull_request\": \"{{.Repo}}: {{.Title}} ({{.Action}} by {{.Sender}}) {{.URL}}\"}}. Event types are pull_request, issues, issue_comment, pull_request_review, pull_request_review_comment, push, create, delete, fork and star. Templates get .Event, .Action, .Repo, .RepoURL, .Sender, .SenderURL, .Title, .URL, .Body (an excerpt) and .Labels, and can use the lower, upper, trim, replace, join and truncate functions. Events without a template for their type, or whose template fails to render, use the built-in rendering.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "CustomWelcomeMessage",
+        "display_name": "Custom Welcome Message:",
+        "type": "longtext",
+        "help_text": "(Optional) Markdown replacing the built-in message sent to users connecting their GitHub account. It can use the {{.GitHubUsername}}, {{.GitHubURL}} and {{.SiteURL}} placeholders. The slash command help is appended to it. Use /github setup welcome to preview it.",
+        "placeholder": "",
+        "default": null
+      },
+      {
+        "key": "SuppressHelpInWelcome",
+        "display_name": "Suppress Help in Welcome Message:",
+        "type": "bool",
+        "help_text": "When true, the slash command help is not appended to the custom welcome message.",
+        "placeholder": "",
+        "default": false
+      },
+      {
+        "key": "InviteUnconnectedUsers",
+        "display_name": "Invite Unconnected Users:",
+        "type": "bool",
+        "help_text": "(Optional) When true, Mattermost users whose username matches a GitHub username mentioned or requested for review on GitHub, and who didn't connect their account, are invited by direct message to connect it. Users are invited at most once a month, and can opt out with /github settings invites off.",
+        "placeholder": "",
+        "default": false
+      },
+      {
+        "key": "AllowSharedTodos",
+        "display_name": "Allow Sharing To Do Lists:",
+        "type": "bool",
+        "help_text": "(Optional) When true, users can share their to do list in the current channel with /github todo --share, after previewing it. The pull requests and issues of private repositories are left out, unless the channel is private or a direct message.",
+        "placeholder": "",
+        "default": true
+      },
+      {
+        "key": "SuggestSubscriptionsOnChannelCreate",
+        "display_name": "Suggest Subscriptions for New Channels:",
+        "type": "bool",
+        "help_text": "(Optional) When true, a new public channel named after a public repository of the GitHub Organization, e.g. payments-service for payments_service, gets a message suggesting to subscribe it to the repository. The repositories are listed daily with the account of a connected System Admin. Nothing is subscribed until a channel member confirms.",
+        "placeholder": "",
+        "default": false
+      },
       {
         "key": "EnableCodePreview",
         "display_name": "Enable Code Previews:",
         "type": "dropdown",
-        "help_text": "Allow the plugin to expand permalinks to GitHub files with an actual preview of the linked file.",
+        "help_text": "Allow the plugin to expand permalinks to GitHub files and gists with an actual preview of the linked file. Secret gists are only previewed when enabled for private repositories.",
         "placeholder": "",
         "default": "public",
         "options": [
@@ -0,0 +1,245 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// diagramLanguages are the code block languages GitHub renders as diagrams or maps. Mattermost only
+// shows their source, so they are posted as plain code blocks linking to the rendered version.
+var diagramLanguages = map[string]bool{
+	"mermaid":  true,
+	"geojson":  true,
+	"topojson": true,
+	"stl":      true,
+}
+
+var (
+	detailsOpenRegex  = regexp.MustCompile(`(?i)<details(\s[^>]*)?>`)
+	detailsCloseRegex = regexp.MustCompile(`(?i)</details\s*>`)
+	summaryRegex      = regexp.MustCompile(`(?is)^\s*<summary(?:\s[^>]*)?>(.*?)</summary\s*>`)
+	htmlTagRegex      = regexp.MustCompile(`<[^>]+>`)
+	taskListItemRegex = regexp.MustCompile(`(?m)^([ \t]*)(?:[-*+]|(\d{1,9}[.)]))[ \t]+\[([ xX])\][ \t]`)
+)
+
+// codeFence is a fenced code block of a Markdown text, with the byte offsets of its first line and
+// of the end of its closing line.
+type codeFence struct {
+	start, end int
+	marker     string
+	language   string
+}
+
+// findCodeFences finds the fenced code blocks of a Markdown text. A block left open runs to the
+// end of the text.
+func findCodeFences(body string) []codeFence {
+	fences := []codeFence{}
+
+	var open *codeFence
+	for offset := 0; offset < len(body); {
+		end := strings.IndexByte(body[offset:], '\n') + 1
+		if end == 0 {
+			end = len(body) - offset
+		}
+		line := body[offset : offset+end]
+		trimmed := strings.TrimRight(line, "\r\n")
+		// Fences may be indented by up to three spaces
+		if indent := len(trimmed) - len(strings.TrimLeft(trimmed, " ")); indent <= 3 {
+			trimmed = trimmed[indent:]
+		}
+
+		if open == nil {
+			if marker := fenceMarker(trimmed); marker != "" {
+				language := strings.TrimSpace(trimmed[len(marker):])
+				if marker[0] != '`' || !strings.Contains(language, "`") {
+					if fields := strings.Fields(language); len(fields) > 0 {
+						language = fields[0]
+					}
+					open = &codeFence{start: offset, marker: marker, language: language}
+				}
+			}
+		} else if marker := fenceMarker(trimmed); marker != "" && marker[0] == open.marker[0] &&
+			len(marker) >= len(open.marker) && strings.TrimSpace(trimmed[len(marker):]) == "" {
+			open.end = offset + end
+			fences = append(fences, *open)
+			open = nil
+		}
+
+		offset += end
+	}
+
+	if open != nil {
+		open.end = len(body)
+		fences = append(fences, *open)
+	}
+
+	return fences
+}
+
+// fenceMarker returns the run of at least three backticks or tildes a line starts with.
+func fenceMarker(line string) string {
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return ""
+	}
+
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	if n < 3 {
+		return ""
+	}
+
+	return line[:n]
+}
+
+// mapOutsideCodeFences applies a function to the parts of a Markdown text outside of its fenced
+// code blocks, and another one to the fenced code blocks.
+func mapOutsideCodeFences(body string, text func(string) string, fence func(string, codeFence) string) string {
+	var b strings.Builder
+
+	last := 0
+	for _, f := range findCodeFences(body) {
+		b.WriteString(text(body[last:f.start]))
+		b.WriteString(fence(body[f.start:f.end], f))
+		last = f.end
+	}
+	b.WriteString(text(body[last:]))
+
+	return b.String()
+}
+
+func inCodeFence(fences []codeFence, offset int) bool {
+	for _, f := range fences {
+		if offset >= f.start && offset < f.end {
+			return true
+		}
+	}
+	return false
+}
+
+// collapseDetails replaces the collapsed <details> sections of a Markdown text, which Mattermost
+// shows as plain text, with a quoted line naming the section and linking to GitHub.
+func collapseDetails(body, htmlURL string) string {
+	for {
+		fences := findCodeFences(body)
+
+		start, end := -1, -1
+		for _, loc := range detailsOpenRegex.FindAllStringIndex(body, -1) {
+			if !inCodeFence(fences, loc[0]) {
+				start = loc[0]
+				break
+			}
+		}
+		if start == -1 {
+			return body
+		}
+
+		// Nested sections are part of the outer one
+		depth := 0
+		opens := detailsOpenRegex.FindAllStringIndex(body[start:], -1)
+		closes := detailsCloseRegex.FindAllStringIndex(body[start:], -1)
+		for i, j := 0, 0; j < len(closes); {
+			if i < len(opens) && opens[i][0] < closes[j][0] {
+				if !inCodeFence(fences, start+opens[i][0]) {
+					depth++
+				}
+				i++
+				continue
+			}
+			if !inCodeFence(fences, start+closes[j][0]) {
+				depth--
+				if depth == 0 {
+					end = start + closes[j][1]
+					break
+				}
+			}
+			j++
+		}
+		if end == -1 {
+			// An unclosed section hides the rest of the text on GitHub
+			end = len(body)
+		}
+
+		openEnd := start + detailsOpenRegex.FindStringIndex(body[start:])[1]
+		summary := "Details"
+		if match := summaryRegex.FindStringSubmatch(body[openEnd:end]); match != nil {
+			if s := strings.Join(strings.Fields(htmlTagRegex.ReplaceAllString(match[1], "")), " "); s != "" {
+				summary = s
+			}
+		}
+
+		line := fmt.Sprintf("> **%s** (collapsed", summary)
+		if htmlURL != "" {
+			line += fmt.Sprintf(", [view on GitHub](%s)", htmlURL)
+		}
+		line += ")"
+
+		// The line is a block of its own
+		prefix := strings.TrimRight(body[:start], " \t")
+		if prefix != "" && !strings.HasSuffix(prefix, "\n\n") {
+			line = strings.TrimRight(prefix, "\n") + "\n\n" + line
+		} else {
+			line = prefix + line
+		}
+		suffix := strings.TrimLeft(body[end:], " \t")
+		if suffix != "" && !strings.HasPrefix(suffix, "\n\n") {
+			suffix = "\n\n" + strings.TrimLeft(suffix, "\n")
+		}
+
+		body = line + suffix
+	}
+}
+
+// normalizeTaskLists writes the items of task lists the way Mattermost renders them as checkboxes.
+func normalizeTaskLists(text string) string {
+	return taskListItemRegex.ReplaceAllStringFunc(text, func(item string) string {
+		match := taskListItemRegex.FindStringSubmatch(item)
+		marker := "-"
+		if match[2] != "" {
+			marker = match[2]
+		}
+		return fmt.Sprintf("%s%s [%s] ", match[1], marker, strings.ToLower(match[3]))
+	})
+}
+
+// sanitizeMarkdown adapts a GitHub-flavored Markdown text, e.g. the body of an issue or a comment,
+// to how Mattermost renders Markdown. It drops HTML comments, collapses <details> sections into a
+// line, turns diagrams into plain code blocks and normalizes task lists. htmlURL links to the
+// original text on GitHub, and may be empty.
+func sanitizeMarkdown(body, htmlURL string) string {
+	if strings.TrimSpace(body) == "" {
+		return ""
+	}
+
+	body = mapOutsideCodeFences(body, func(text string) string {
+		return mdCommentRegex.ReplaceAllString(text, "")
+	}, func(block string, _ codeFence) string {
+		return block
+	})
+
+	body = collapseDetails(body, htmlURL)
+
+	return mapOutsideCodeFences(body, normalizeTaskLists, func(block string, f codeFence) string {
+		if !diagramLanguages[strings.ToLower(f.language)] {
+			return block
+		}
+
+		// Drop the language from the opening line
+		firstLine := strings.IndexByte(block, '\n')
+		if firstLine == -1 {
+			return f.marker
+		}
+		block = block[:strings.Index(block, f.marker)+len(f.marker)] + block[firstLine:]
+
+		if htmlURL == "" {
+			return block
+		}
+		link := fmt.Sprintf("[View diagram on GitHub](%s)", htmlURL)
+		if strings.HasSuffix(block, "\n") {
+			return block + link + "\n"
+		}
+		return block + "\n" + link
+	})
+}
@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeMarkdown(t *testing.T) {
+	const url = "https://github.com/owner/repo/issues/1"
+
+	for name, tc := range map[string]struct {
+		body     string
+		htmlURL  string
+		expected string
+	}{
+		"empty": {
+			body:     " \n ",
+			expected: "",
+		},
+		"plain text": {
+			body:     "Fixes a **bug**.\n\n* one\n* two",
+			expected: "Fixes a **bug**.\n\n* one\n* two",
+		},
+		"html comments": {
+			body:     "<!-- Thanks for contributing!\nPlease describe the change. -->\nAdds a flag.<!-- end -->",
+			expected: "\nAdds a flag.",
+		},
+		"comments in code blocks are kept": {
+			body:     "Use:\n```html\n<!-- keep me -->\n```\n<!-- drop me -->",
+			expected: "Use:\n```html\n<!-- keep me -->\n```\n",
+		},
+		"details": {
+			body:     "Crashes on start.\n<details>\n<summary>Stack <b>trace</b></summary>\n\n```\npanic: oops\n```\n</details>\n\nOn v1.2.",
+			htmlURL:  url,
+			expected: "Crashes on start.\n\n> **Stack trace** (collapsed, [view on GitHub](" + url + "))\n\nOn v1.2.",
+		},
+		"details without summary nor link": {
+			body:     "<details open>hidden</details>",
+			expected: "> **Details** (collapsed)",
+		},
+		"nested details": {
+			body:     "<details><summary>Logs</summary>\n<details><summary>Server</summary>...</details>\n</details>\nDone",
+			htmlURL:  url,
+			expected: "> **Logs** (collapsed, [view on GitHub](" + url + "))\n\nDone",
+		},
+		"unclosed details": {
+			body:     "Intro\n\n<details><summary>Logs</summary>\nnever closed",
+			expected: "Intro\n\n> **Logs** (collapsed)",
+		},
+		"details in code blocks are kept": {
+			body:     "~~~\n<details><summary>Example</summary></details>\n~~~",
+			expected: "~~~\n<details><summary>Example</summary></details>\n~~~",
+		},
+		"mermaid": {
+			body:     "Flow:\n\n```mermaid\ngraph TD;\n  A-->B;\n```\nThe end.",
+			htmlURL:  url,
+			expected: "Flow:\n\n```\ngraph TD;\n  A-->B;\n```\n[View diagram on GitHub](" + url + ")\nThe end.",
+		},
+		"mermaid without link": {
+			body:     "````Mermaid\ngraph TD;\n````",
+			expected: "````\ngraph TD;\n````",
+		},
+		"unclosed mermaid": {
+			body:     "```mermaid\ngraph TD;",
+			htmlURL:  url,
+			expected: "```\ngraph TD;\n[View diagram on GitHub](" + url + ")",
+		},
+		"supported languages are kept": {
+			body:     "```go\nfunc main() {}\n```",
+			htmlURL:  url,
+			expected: "```go\nfunc main() {}\n```",
+		},
+		"task lists": {
+			body:     "* [X] Tests\n+ [ ] Docs\n  - [x] Nested\n1. [X] First\n- [link](https://example.com)",
+			expected: "- [x] Tests\n- [ ] Docs\n  - [x] Nested\n1. [x] First\n- [link](https://example.com)",
+		},
+		"task lists in code blocks are kept": {
+			body:     "```\n* [X] literal\n```",
+			expected: "```\n* [X] literal\n```",
+		},
+		"everything": {
+			body:    "<!-- template -->\n## Checklist\n* [X] Tests\n\n<details>\n<summary>Diagram</summary>\n\n```mermaid\ngraph TD;\n```\n</details>\n\n```mermaid\nsequenceDiagram\n```",
+			htmlURL: url,
+			expected: "\n## Checklist\n- [x] Tests\n\n> **Diagram** (collapsed, [view on GitHub](" + url + "))\n\n" +
+				"```\nsequenceDiagram\n```\n[View diagram on GitHub](" + url + ")",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, sanitizeMarkdown(tc.body, tc.htmlURL))
+		})
+	}
+}
+
+func TestFindCodeFences(t *testing.T) {
+	body := "text\n   ```go run\ncode\n```\n````\n```\nstill code\n````\n``inline``\n~~~\nopen"
+
+	assert.Equal(t, []codeFence{
+		{start: 5, end: 27, marker: "```", language: "go"},
+		{start: 27, end: 52, marker: "````"},
+		{start: 63, end: 71, marker: "~~~"},
+	}, findCodeFences(body))
+}
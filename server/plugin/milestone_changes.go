@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	pullRequestEventType = "pull_request"
+
+	milestoneActionAdded   = "milestoned"
+	milestoneActionRemoved = "demilestoned"
+)
+
+// MilestoneChangeEvent is an issues or pull_request event with the milestoned or demilestoned
+// action. go-github doesn't parse the milestone the issue or pull request was added to or removed
+// from, which isn't on the issue anymore when it's removed.
+type MilestoneChangeEvent struct {
+	Action      *string             `json:"action,omitempty"`
+	Issue       *github.Issue       `json:"issue,omitempty"`
+	PullRequest *github.PullRequest `json:"pull_request,omitempty"`
+	Milestone   *github.Milestone   `json:"milestone,omitempty"`
+	Repo        *github.Repository  `json:"repository,omitempty"`
+	Sender      *github.User        `json:"sender,omitempty"`
+}
+
+func (e *MilestoneChangeEvent) GetAction() string {
+	if e == nil || e.Action == nil {
+		return ""
+	}
+	return *e.Action
+}
+
+func (e *MilestoneChangeEvent) GetMilestone() *github.Milestone {
+	if e == nil {
+		return nil
+	}
+	return e.Milestone
+}
+
+func (e *MilestoneChangeEvent) GetRepo() *github.Repository {
+	if e == nil {
+		return nil
+	}
+	return e.Repo
+}
+
+func (e *MilestoneChangeEvent) GetSender() *github.User {
+	if e == nil {
+		return nil
+	}
+	return e.Sender
+}
+
+// IsPullRequest tells if the milestone of a pull request changed, rather than the one of an issue.
+func (e *MilestoneChangeEvent) IsPullRequest() bool {
+	return e != nil && e.PullRequest != nil
+}
+
+func (e *MilestoneChangeEvent) GetNumber() int {
+	if e.IsPullRequest() {
+		return e.PullRequest.GetNumber()
+	}
+	return e.GetIssue().GetNumber()
+}
+
+func (e *MilestoneChangeEvent) GetTitle() string {
+	if e.IsPullRequest() {
+		return e.PullRequest.GetTitle()
+	}
+	return e.GetIssue().GetTitle()
+}
+
+func (e *MilestoneChangeEvent) GetHTMLURL() string {
+	if e.IsPullRequest() {
+		return e.PullRequest.GetHTMLURL()
+	}
+	return e.GetIssue().GetHTMLURL()
+}
+
+func (e *MilestoneChangeEvent) GetIssue() *github.Issue {
+	if e == nil {
+		return nil
+	}
+	return e.Issue
+}
+
+func (e *MilestoneChangeEvent) labels() []*github.Label {
+	if e.IsPullRequest() {
+		return e.PullRequest.Labels
+	}
+	return e.GetIssue().Labels
+}
+
+// parseMilestoneChangeEvent parses an issues or pull_request event if it's a milestone change. It
+// returns nil for other actions.
+func parseMilestoneChangeEvent(body []byte) (*MilestoneChangeEvent, error) {
+	var event *MilestoneChangeEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	if event.GetAction() != milestoneActionAdded && event.GetAction() != milestoneActionRemoved {
+		return nil, nil
+	}
+	return event, nil
+}
+
+// milestoneChangeRenderer renders the issues and pull requests added to or removed from a milestone.
+type milestoneChangeRenderer struct {
+	p *Plugin
+}
+
+func (r *milestoneChangeRenderer) Feature() string {
+	return featureMilestoneChanges
+}
+
+func (r *milestoneChangeRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*MilestoneChangeEvent)
+	if !ok || !sub.MilestoneChanges() || r.p.excludeSender(event.GetSender(), sub) {
+		return nil, nil
+	}
+
+	if milestone := sub.Milestone(); milestone != "" && milestone != event.GetMilestone().GetTitle() {
+		return nil, nil
+	}
+	if !subscribedToLabels(sub, event.labels()) {
+		return nil, nil
+	}
+
+	message, err := renderTemplate("milestoneChanged", event)
+	if err != nil {
+		return nil, err
+	}
+
+	eventType, objectType := issuesEventType, objectTypeIssue
+	if event.IsPullRequest() {
+		eventType, objectType = pullRequestEventType, objectTypePullRequest
+	}
+
+	return &model.Post{
+		Type:    "custom_git_milestone",
+		Message: r.p.applyNotificationTemplate(sub, event, message),
+		Props:   eventPostProps(event.GetRepo().GetFullName(), objectType, strconv.Itoa(event.GetNumber()), eventType+"."+event.GetAction()),
+	}, nil
+}
+
+func (p *Plugin) postMilestoneChangeEvent(event *MilestoneChangeEvent, delivery *webhookDelivery) {
+	p.postRenderedEvent(&milestoneChangeRenderer{p}, event.GetRepo(), event, delivery)
+}
+
+// MilestoneChanges tells if the subscription includes the issues and pull requests added to or
+// removed from a milestone, either any milestone or the one it filters by.
+func (s *Subscription) MilestoneChanges() bool {
+	return s.hasFeature(featureMilestoneChanges) || s.Milestone() != ""
+}
+
+// Milestone returns the title of the milestone a subscription filters the milestone changes by,
+// given as milestone_changes:"<title>", or an empty string if it doesn't filter them.
+func (s *Subscription) Milestone() string {
+	for _, f := range parseFeatures(s.Features) {
+		if !strings.HasPrefix(f, featureMilestoneChanges+":") {
+			continue
+		}
+
+		milestoneSplit := strings.Split(f, "\"")
+		if len(milestoneSplit) < 3 {
+			return ""
+		}
+
+		return milestoneSplit[1]
+	}
+
+	return ""
+}
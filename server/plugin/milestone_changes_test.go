@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMilestoneChangeEvent(t *testing.T) {
+	event, err := parseMilestoneChangeEvent([]byte(`{"action": "demilestoned", "milestone": {"title": "v2.0"}, "pull_request": {"number": 42, "title": "Add dark mode", "html_url": "https://github.com/owner/repo/pull/42"}}`))
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.True(t, event.IsPullRequest())
+	assert.Equal(t, 42, event.GetNumber())
+	assert.Equal(t, "Add dark mode", event.GetTitle())
+	assert.Equal(t, "https://github.com/owner/repo/pull/42", event.GetHTMLURL())
+	assert.Equal(t, "v2.0", event.GetMilestone().GetTitle())
+
+	event, err = parseMilestoneChangeEvent([]byte(`{"action": "milestoned", "milestone": {"title": "v2.0"}, "issue": {"number": 7}}`))
+	require.NoError(t, err)
+	assert.False(t, event.IsPullRequest())
+	assert.Equal(t, 7, event.GetNumber())
+
+	// Other actions are left to go-github
+	event, err = parseMilestoneChangeEvent([]byte(`{"action": "labeled", "issue": {"number": 7}}`))
+	require.NoError(t, err)
+	assert.Nil(t, event)
+
+	_, err = parseMilestoneChangeEvent([]byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestSubscriptionMilestone(t *testing.T) {
+	for features, expected := range map[string]struct {
+		milestoneChanges bool
+		milestone        string
+	}{
+		"pulls,issues":                              {false, ""},
+		"milestone_changes":                         {true, ""},
+		`pulls,milestone_changes:"v2.0"`:            {true, "v2.0"},
+		`milestone_changes:"Sprint 12",label:"bug"`: {true, "Sprint 12"},
+		"milestone_changes:v2.0":                    {false, ""},
+	} {
+		sub := &Subscription{Features: features}
+		assert.Equal(t, expected.milestoneChanges, sub.MilestoneChanges(), features)
+		assert.Equal(t, expected.milestone, sub.Milestone(), features)
+	}
+}
+
+func TestMilestoneChangeRenderer(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{})
+	p.SetAPI(&plugintest.API{})
+	renderer := &milestoneChangeRenderer{p}
+
+	event := &MilestoneChangeEvent{
+		Action:    github.String(milestoneActionAdded),
+		Issue:     &github.Issue{Number: github.Int(123), Title: github.String("Fix login"), HTMLURL: github.String("https://github.com/owner/repo/issues/123"), Labels: []*github.Label{{Name: github.String("bug")}}},
+		Milestone: &github.Milestone{Title: github.String("v2.0"), HTMLURL: github.String("https://github.com/owner/repo/milestone/3")},
+		Repo:      &github.Repository{FullName: github.String("owner/repo")},
+		Sender:    &github.User{Login: github.String("panda"), HTMLURL: github.String("https://github.com/panda")},
+	}
+
+	for features, rendered := range map[string]bool{
+		"pulls,issues":                             false,
+		"milestone_changes":                        true,
+		`milestone_changes:"v2.0"`:                 true,
+		`milestone_changes:"v1.9"`:                 false,
+		`milestone_changes:"v2.0",label:"bug"`:     true,
+		`milestone_changes:"v2.0",label:"feature"`: false,
+	} {
+		post, err := renderer.Render(event, &Subscription{Features: features})
+		require.NoError(t, err)
+		assert.Equal(t, rendered, post != nil, features)
+	}
+
+	post, err := renderer.Render(event, &Subscription{Features: "milestone_changes"})
+	require.NoError(t, err)
+	assert.Equal(t, "[panda](https://github.com/panda) added [owner/repo#123](https://github.com/owner/repo/issues/123) 'Fix login' to milestone [v2.0](https://github.com/owner/repo/milestone/3).\n", post.Message)
+}
@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	mutedUsersKey = "-muted-users"
+	// mutedUsersVersion is the version of the format of the muted users stored in the KV store.
+	// Lists stored before it are comma-joined usernames.
+	mutedUsersVersion = 1
+
+	// muteScopeChannels mutes a user in the channels subscribed by the muting user, on top of the
+	// direct messages. Mutes without a scope only apply to direct messages.
+	muteScopeChannels = "channels"
+)
+
+// mutedUser is a GitHub user muted by a Mattermost user.
+type mutedUser struct {
+	Username string `json:"username"`
+	// CreatedAt is when the user was muted, or when the legacy list was migrated for older mutes.
+	CreatedAt time.Time `json:"created_at"`
+	Scope     string    `json:"scope,omitempty"`
+}
+
+// mutedUsers are the GitHub users muted by a Mattermost user.
+type mutedUsers struct {
+	Version int          `json:"version"`
+	Users   []*mutedUser `json:"users"`
+}
+
+func mutedUsersKeyFor(userID string) string {
+	return userID + mutedUsersKey
+}
+
+// parseMutedUsers parses the muted users stored in the KV store. It returns true if they were stored
+// in the legacy format.
+func parseMutedUsers(value []byte, now time.Time) (*mutedUsers, bool, error) {
+	muted := &mutedUsers{Version: mutedUsersVersion, Users: []*mutedUser{}}
+	if len(value) == 0 {
+		return muted, false, nil
+	}
+
+	if value[0] == '{' {
+		if err := json.Unmarshal(value, muted); err != nil {
+			return nil, false, errors.Wrap(err, "could not unmarshal muted users")
+		}
+		return muted, false, nil
+	}
+
+	// , is a character not allowed in GitHub usernames, which legacy lists were split on
+	for _, username := range strings.Split(string(value), ",") {
+		if username != "" {
+			muted.Users = append(muted.Users, &mutedUser{Username: username, CreatedAt: now})
+		}
+	}
+
+	return muted, true, nil
+}
+
+// get returns a muted user, or nil if the user isn't muted.
+func (m *mutedUsers) get(username string) *mutedUser {
+	for _, user := range m.Users {
+		if strings.EqualFold(user.Username, username) {
+			return user
+		}
+	}
+
+	return nil
+}
+
+func (m *mutedUsers) usernames() []string {
+	usernames := make([]string, 0, len(m.Users))
+	for _, user := range m.Users {
+		usernames = append(usernames, user.Username)
+	}
+
+	return usernames
+}
+
+// remove unmutes a user. It returns false if the user wasn't muted.
+func (m *mutedUsers) remove(username string) bool {
+	for i, user := range m.Users {
+		if strings.EqualFold(user.Username, username) {
+			m.Users = append(m.Users[:i], m.Users[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// readMutedUsers returns the users muted by a user, without migrating legacy lists.
+func (p *Plugin) readMutedUsers(userID string) (*mutedUsers, bool, error) {
+	value, appErr := p.API.KVGet(mutedUsersKeyFor(userID))
+	if appErr != nil {
+		return nil, false, errors.Wrap(appErr, "could not get muted users from KV store")
+	}
+
+	return parseMutedUsers(value, time.Now())
+}
+
+// getMutedUsers returns the users muted by a user. Legacy lists are migrated on the first read.
+func (p *Plugin) getMutedUsers(userID string) (*mutedUsers, error) {
+	muted, legacy, err := p.readMutedUsers(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if legacy {
+		if err := p.storeMutedUsers(userID, muted); err != nil {
+			// The list is still usable, the migration is tried again on the next read
+			p.API.LogWarn("Failed to migrate muted users", "userID", userID, "error", err.Error())
+		}
+	}
+
+	return muted, nil
+}
+
+func (p *Plugin) storeMutedUsers(userID string, muted *mutedUsers) error {
+	muted.Version = mutedUsersVersion
+	value, err := json.Marshal(muted)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal muted users")
+	}
+
+	if appErr := p.API.KVSet(mutedUsersKeyFor(userID), value); appErr != nil {
+		return errors.Wrap(appErr, "could not store muted users in KV store")
+	}
+
+	return nil
+}
+
+// getMutedUser returns how a user muted a GitHub user, or nil if they didn't.
+func (p *Plugin) getMutedUser(userID, username string) *mutedUser {
+	muted, _, err := p.readMutedUsers(userID)
+	if err != nil {
+		p.API.LogWarn("Failed to get muted users", "userID", userID, "error", err.Error())
+		return nil
+	}
+
+	return muted.get(username)
+}
+
+// senderMutedInChannel checks if the creator of a subscription muted the sender of an event in the
+// channels they subscribed.
+func (p *Plugin) senderMutedInChannel(sender string, sub *Subscription) bool {
+	if sub.CreatorID == "" || sender == "" {
+		return false
+	}
+
+	muted := p.getMutedUser(sub.CreatorID, sender)
+	return muted != nil && muted.Scope == muteScopeChannels
+}
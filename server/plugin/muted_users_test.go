@@ -0,0 +1,191 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMutedUsers(t *testing.T) {
+	now := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("empty", func(t *testing.T) {
+		muted, legacy, err := parseMutedUsers(nil, now)
+		require.NoError(t, err)
+		assert.False(t, legacy)
+		assert.Empty(t, muted.Users)
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		muted, legacy, err := parseMutedUsers([]byte("alice,bob"), now)
+		require.NoError(t, err)
+		assert.True(t, legacy)
+		assert.Equal(t, []*mutedUser{{Username: "alice", CreatedAt: now}, {Username: "bob", CreatedAt: now}}, muted.Users)
+	})
+
+	t.Run("versioned", func(t *testing.T) {
+		muted, legacy, err := parseMutedUsers([]byte(`{"version": 1, "users": [{"username": "alice", "created_at": "2020-08-01T10:00:00Z", "scope": "channels"}]}`), now)
+		require.NoError(t, err)
+		assert.False(t, legacy)
+		require.Len(t, muted.Users, 1)
+		assert.Equal(t, "alice", muted.Users[0].Username)
+		assert.Equal(t, muteScopeChannels, muted.Users[0].Scope)
+		assert.Equal(t, time.Date(2020, 8, 1, 10, 0, 0, 0, time.UTC), muted.Users[0].CreatedAt)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, _, err := parseMutedUsers([]byte(`{"users": `), now)
+		assert.Error(t, err)
+	})
+}
+
+func TestMutedUsersMigration(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store["userID-muted-users"] = []byte("alice,bob")
+	api.On("KVSet", "userID-muted-users", mock.Anything).Return(func(key string, value []byte) *model.AppError {
+		store[key] = value
+		return nil
+	})
+	p.SetAPI(api)
+
+	userInfo := &GitHubUserInfo{UserID: "userID"}
+	assert.Equal(t, []string{"alice", "bob"}, p.getMutedUsernames(userInfo))
+
+	stored := &mutedUsers{}
+	require.NoError(t, json.Unmarshal(store["userID-muted-users"], stored))
+	assert.Equal(t, mutedUsersVersion, stored.Version)
+	assert.Equal(t, []string{"alice", "bob"}, stored.usernames())
+
+	// Migrated lists aren't stored again
+	assert.Equal(t, []string{"alice", "bob"}, p.getMutedUsernames(userInfo))
+	assert.Equal(t, "Your muted users:\n- alice\n- bob\n", p.handleMuteList(nil, userInfo))
+	api.AssertNumberOfCalls(t, "KVSet", 1)
+}
+
+func TestHandleMuteCommand(t *testing.T) {
+	setup := func() (*Plugin, map[string][]byte) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		p.SetAPI(api)
+		return p, store
+	}
+	userInfo := &GitHubUserInfo{UserID: "userID"}
+
+	t.Run("add to a legacy list", func(t *testing.T) {
+		p, store := setup()
+		store["userID-muted-users"] = []byte("alice")
+
+		assert.Equal(t, "`bob` is now muted. You will no longer receive notifications for comments in your PRs and issues.", p.handleMuteCommand(nil, nil, []string{"add", "bob"}, userInfo))
+		assert.Equal(t, "alice is already muted", p.handleMuteCommand(nil, nil, []string{"add", "alice"}, userInfo))
+		assert.Equal(t, "Your muted users:\n- alice\n- bob\n", p.handleMuteCommand(nil, nil, []string{"list"}, userInfo))
+	})
+
+	t.Run("channel scope", func(t *testing.T) {
+		p, _ := setup()
+
+		message := p.handleMuteCommand(nil, nil, []string{"add", "alice", "--scope", "channels"}, userInfo)
+		assert.Contains(t, message, "the channels you subscribed won't get the events of this user")
+		assert.Equal(t, "alice is already muted", p.handleMuteCommand(nil, nil, []string{"add", "alice", "--scope", "channels"}, userInfo))
+		assert.Equal(t, "Your muted users:\n- alice (notifications and subscribed channels)\n", p.handleMuteCommand(nil, nil, []string{"list"}, userInfo))
+
+		// Muting again without a scope narrows the mute to the notifications
+		p.handleMuteCommand(nil, nil, []string{"add", "alice"}, userInfo)
+		assert.Equal(t, "Your muted users:\n- alice\n", p.handleMuteCommand(nil, nil, []string{"list"}, userInfo))
+	})
+
+	t.Run("invalid scope", func(t *testing.T) {
+		p, _ := setup()
+
+		assert.Equal(t, "Invalid scope \"everything\". Use `--scope channels` to mute the user in the channels you subscribed too.", p.handleMuteCommand(nil, nil, []string{"add", "alice", "--scope", "everything"}, userInfo))
+		assert.Equal(t, "Invalid number of parameters supplied to add", p.handleMuteCommand(nil, nil, []string{"add", "alice", "--scope"}, userInfo))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		p, store := setup()
+		store["userID-muted-users"] = []byte("alice,bob")
+
+		assert.Equal(t, "`alice` is no longer muted", p.handleMuteCommand(nil, nil, []string{"delete", "alice"}, userInfo))
+		assert.Equal(t, "Your muted users:\n- bob\n", p.handleMuteCommand(nil, nil, []string{"list"}, userInfo))
+
+		assert.Equal(t, "Unmuted all users", p.handleMuteCommand(nil, nil, []string{"delete-all"}, userInfo))
+		assert.Equal(t, "You have no muted users", p.handleMuteCommand(nil, nil, []string{"list"}, userInfo))
+	})
+}
+
+func TestSenderMutedByReceiver(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store["legacyID-muted-users"] = []byte("alice,bob")
+	store["userID-muted-users"] = []byte(`{"version": 1, "users": [{"username": "alice", "scope": "channels"}, {"username": "bob"}]}`)
+	p.SetAPI(api)
+
+	// Reading the mutes for notifications doesn't migrate legacy lists
+	assert.True(t, p.senderMutedByReceiver("legacyID", "bob"))
+	assert.False(t, p.senderMutedByReceiver("legacyID", "bo"))
+	assert.True(t, p.senderMutedByReceiver("userID", "alice"))
+	assert.True(t, p.senderMutedByReceiver("userID", "Bob"))
+	assert.False(t, p.senderMutedByReceiver("userID", "carol"))
+	assert.False(t, p.senderMutedByReceiver("otherID", "alice"))
+	api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+}
+
+func TestChannelScopedMutes(t *testing.T) {
+	subs, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {{ChannelID: "channelID", CreatorID: "creatorID", Repository: "owner/repo", Features: "issues"}},
+	}})
+	require.NoError(t, err)
+
+	event := &github.IssuesEvent{
+		Action: github.String("opened"),
+		Repo:   &github.Repository{FullName: github.String("owner/repo"), Name: github.String("repo"), Owner: &github.User{Login: github.String("owner")}, HTMLURL: github.String("https://github.com/owner/repo")},
+		Issue:  &github.Issue{Number: github.Int(12), Title: github.String("Crash on start"), HTMLURL: github.String("https://github.com/owner/repo/issues/12")},
+		Sender: &github.User{Login: github.String("alice")},
+	}
+
+	for name, test := range map[string]struct {
+		muted    string
+		expected bool
+	}{
+		// Mutes of other users don't apply to the subscriptions of the creator
+		"not muted":         {"", true},
+		"muted in DMs":      {`{"version": 1, "users": [{"username": "alice"}]}`, true},
+		"muted in channels": {`{"version": 1, "users": [{"username": "alice", "scope": "channels"}]}`, false},
+		"other user muted":  {`{"version": 1, "users": [{"username": "bob", "scope": "channels"}]}`, true},
+		"legacy list":       {"alice", true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := NewPlugin()
+			p.BotUserID = "botID"
+			p.setConfiguration(&Configuration{})
+			api := &plugintest.API{}
+			store := mockKVStore(api)
+			store[SubscriptionsKey] = subs
+			store["creatorID-muted-users"] = []byte(test.muted)
+			store["otherID-muted-users"] = []byte(`{"version": 1, "users": [{"username": "alice", "scope": "channels"}]}`)
+			posted := false
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+				posted = true
+				return post
+			}, nil).Maybe()
+			p.SetAPI(api)
+
+			p.postIssueEvent(event, testWebhookDelivery(p))
+			assert.Equal(t, test.expected, posted)
+		})
+	}
+}
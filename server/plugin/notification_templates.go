@@ -0,0 +1,262 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	templateFlag = "template"
+
+	// notificationTemplateBodyExcerptLength is the maximum length of the body excerpt given to notification templates.
+	notificationTemplateBodyExcerptLength = 300
+)
+
+// notificationTemplateEvents are the event types notification templates can be defined for.
+var notificationTemplateEvents = []string{
+	"pull_request",
+	"issues",
+	"issue_comment",
+	"pull_request_review",
+	"pull_request_review_comment",
+	"push",
+	"create",
+	"delete",
+	"fork",
+	starEventType,
+}
+
+// notificationTemplateFuncMap is the restricted set of functions available to notification
+// templates, on top of the builtin ones of text/template.
+var notificationTemplateFuncMap = template.FuncMap{
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+	"replace": strings.ReplaceAll,
+	"join": func(sep string, values []string) string {
+		return strings.Join(values, sep)
+	},
+	"truncate": func(length int, value string) string {
+		return truncateText(value, length)
+	},
+}
+
+// notificationTemplateData is what notification templates are rendered with.
+type notificationTemplateData struct {
+	// Event is the type of the event, e.g. pull_request.
+	Event string
+	// Action is the action of the event, e.g. opened, if any.
+	Action string
+	// Repo is the full name of the repository, e.g. mattermost/mattermost-server.
+	Repo    string
+	RepoURL string
+	// Sender is the GitHub username of who triggered the event.
+	Sender    string
+	SenderURL string
+	// Title is the title of the pull request or issue, or the ref for pushes, creations and deletions.
+	Title string
+	// URL links to the pull request, issue, comment, review or ref.
+	URL string
+	// Body is the beginning of the description, comment or review, without Markdown comments.
+	Body string
+	// Labels are the names of the labels of the pull request or issue.
+	Labels []string
+}
+
+// truncateText shortens text to at most length runes, ending it with an ellipsis if cut.
+func truncateText(text string, length int) string {
+	runes := []rune(text)
+	if length <= 0 || len(runes) <= length {
+		return text
+	}
+
+	return strings.TrimSpace(string(runes[:length])) + "…"
+}
+
+// parseNotificationTemplates parses the notification templates of the configuration, a JSON object
+// mapping template names to objects mapping event types to templates.
+func parseNotificationTemplates(raw string) (map[string]map[string]*template.Template, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	definitions := map[string]map[string]string{}
+	if err := json.Unmarshal([]byte(raw), &definitions); err != nil {
+		return nil, errors.Wrap(err, "notification templates must be a JSON object mapping template names to objects mapping event types to templates")
+	}
+
+	templates := map[string]map[string]*template.Template{}
+	for name, events := range definitions {
+		if name == "" || strings.ContainsAny(name, " \t\n\"") {
+			return nil, errors.Errorf("invalid notification template name %q", name)
+		}
+
+		templates[name] = map[string]*template.Template{}
+		for event, text := range events {
+			if !SliceContainsString(notificationTemplateEvents, event) {
+				return nil, errors.Errorf("unknown event type %q in notification template %s. Use one of %s", event, name, strings.Join(notificationTemplateEvents, ", "))
+			}
+
+			t, err := template.New(name + "/" + event).Funcs(notificationTemplateFuncMap).Parse(text)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse notification template %s for %s", name, event)
+			}
+			templates[name][event] = t
+		}
+	}
+
+	return templates, nil
+}
+
+// hasNotificationTemplate checks if a notification template with the given name is configured.
+func (c *Configuration) hasNotificationTemplate(name string) bool {
+	_, ok := c.notificationTemplates[name]
+	return ok
+}
+
+// getNotificationTemplate returns the notification template of the given name for an event type, if any.
+func (c *Configuration) getNotificationTemplate(name, event string) *template.Template {
+	return c.notificationTemplates[name][event]
+}
+
+// issueLabelNames returns the names of labels.
+func issueLabelNames(labels []*github.Label) []string {
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		names[i] = label.GetName()
+	}
+
+	return names
+}
+
+// getNotificationTemplateData returns what notification templates are rendered with for an event.
+func getNotificationTemplateData(event interface{}) (string, *notificationTemplateData) {
+	data := &notificationTemplateData{Labels: []string{}}
+	var body string
+
+	switch event := event.(type) {
+	case *github.PullRequestEvent:
+		data.Event = "pull_request"
+		data.Action = event.GetAction()
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = event.GetPullRequest().GetTitle(), event.GetPullRequest().GetHTMLURL()
+		data.Labels = issueLabelNames(event.GetPullRequest().Labels)
+		body = event.GetPullRequest().GetBody()
+	case *github.IssuesEvent:
+		data.Event = "issues"
+		data.Action = event.GetAction()
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = event.GetIssue().GetTitle(), event.GetIssue().GetHTMLURL()
+		data.Labels = issueLabelNames(event.GetIssue().Labels)
+		body = event.GetIssue().GetBody()
+	case *MilestoneChangeEvent:
+		data.Event = issuesEventType
+		if event.IsPullRequest() {
+			data.Event = pullRequestEventType
+		}
+		data.Action = event.GetAction()
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = event.GetTitle(), event.GetHTMLURL()
+		data.Labels = issueLabelNames(event.labels())
+	case *github.IssueCommentEvent:
+		data.Event = "issue_comment"
+		data.Action = event.GetAction()
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = event.GetIssue().GetTitle(), event.GetComment().GetHTMLURL()
+		data.Labels = issueLabelNames(event.GetIssue().Labels)
+		body = event.GetComment().GetBody()
+	case *github.PullRequestReviewEvent:
+		data.Event = "pull_request_review"
+		data.Action = event.GetAction()
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = event.GetPullRequest().GetTitle(), event.GetReview().GetHTMLURL()
+		data.Labels = issueLabelNames(event.GetPullRequest().Labels)
+		body = event.GetReview().GetBody()
+	case *github.PullRequestReviewCommentEvent:
+		data.Event = "pull_request_review_comment"
+		data.Action = event.GetAction()
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = event.GetPullRequest().GetTitle(), event.GetComment().GetHTMLURL()
+		data.Labels = issueLabelNames(event.GetPullRequest().Labels)
+		body = event.GetComment().GetBody()
+	case *github.PushEvent:
+		data.Event = "push"
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = strings.TrimPrefix(event.GetRef(), "refs/heads/"), event.GetCompare()
+		body = event.GetHeadCommit().GetMessage()
+	case *github.CreateEvent:
+		data.Event = "create"
+		data.Action = event.GetRefType()
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = event.GetRef(), event.GetRepo().GetHTMLURL()+"/tree/"+event.GetRef()
+	case *github.DeleteEvent:
+		data.Event = "delete"
+		data.Action = event.GetRefType()
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = event.GetRef(), event.GetRepo().GetHTMLURL()
+	case *github.ForkEvent:
+		data.Event = "fork"
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = event.GetForkee().GetFullName(), event.GetForkee().GetHTMLURL()
+	case *StarEvent:
+		data.Event = starEventType
+		data.Action = event.GetAction()
+		data.Repo, data.RepoURL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()
+		data.Sender, data.SenderURL = event.GetSender().GetLogin(), event.GetSender().GetHTMLURL()
+		data.Title, data.URL = event.GetRepo().GetFullName(), event.GetRepo().GetHTMLURL()+"/stargazers"
+	}
+
+	data.Body = truncateText(strings.TrimSpace(sanitizeMarkdown(body, data.URL)), notificationTemplateBodyExcerptLength)
+
+	return data.Event, data
+}
+
+// applyNotificationTemplate renders an event with the notification template selected by a
+// subscription, if any is defined for the event type. The built-in message is returned otherwise,
+// including when the template fails to render, so that no event is dropped.
+func (p *Plugin) applyNotificationTemplate(sub *Subscription, event interface{}, message string) string {
+	name := sub.Flags.Template
+	if name == "" {
+		return message
+	}
+
+	config := p.getConfiguration()
+	if !config.hasNotificationTemplate(name) {
+		p.API.LogWarn("Unknown notification template of subscription", "template", name, "channelID", sub.ChannelID, "repo", sub.Repository)
+		return message
+	}
+
+	eventType, data := getNotificationTemplateData(event)
+	t := config.getNotificationTemplate(name, eventType)
+	if t == nil {
+		return message
+	}
+
+	var output bytes.Buffer
+	if err := t.Execute(&output, data); err != nil {
+		p.API.LogWarn("Failed to render notification template", "template", name, "event", eventType, "error", err.Error())
+		return message
+	}
+
+	if strings.TrimSpace(output.String()) == "" {
+		p.API.LogWarn("Notification template rendered an empty message", "template", name, "event", eventType)
+		return message
+	}
+
+	return output.String()
+}
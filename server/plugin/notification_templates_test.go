@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNotificationTemplates(t *testing.T) {
+	for name, tc := range map[string]struct {
+		raw      string
+		expected map[string][]string
+		err      string
+	}{
+		"empty": {
+			raw: " ",
+		},
+		"valid": {
+			raw:      `{"terse": {"pull_request": "{{.Title}}", "push": "{{.Repo}}"}, "loud": {"issues": "{{upper .Title}}"}}`,
+			expected: map[string][]string{"terse": {"pull_request", "push"}, "loud": {"issues"}},
+		},
+		"not JSON": {
+			raw: `terse: "{{.Title}}"`,
+			err: "notification templates must be a JSON object",
+		},
+		"unknown event type": {
+			raw: `{"terse": {"release": "{{.Title}}"}}`,
+			err: `unknown event type "release" in notification template terse`,
+		},
+		"invalid name": {
+			raw: `{"release notes": {"pull_request": "{{.Title}}"}}`,
+			err: `invalid notification template name "release notes"`,
+		},
+		"parse error": {
+			raw: `{"terse": {"issues": "{{.Title"}}`,
+			err: "failed to parse notification template terse for issues",
+		},
+		"unknown function": {
+			raw: `{"terse": {"issues": "{{env \"HOME\"}}"}}`,
+			err: "failed to parse notification template terse for issues",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			templates, err := parseNotificationTemplates(tc.raw)
+			if tc.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, templates, len(tc.expected))
+			for name, events := range tc.expected {
+				assert.Len(t, templates[name], len(events))
+				for _, event := range events {
+					assert.NotNil(t, templates[name][event])
+				}
+			}
+		})
+	}
+}
+
+func TestGetNotificationTemplateData(t *testing.T) {
+	event := &github.PullRequestEvent{
+		Action: github.String("opened"),
+		PullRequest: &github.PullRequest{
+			Title:   github.String("Fix the crash"),
+			HTMLURL: github.String("https://github.com/owner/repo/pull/1"),
+			Body:    github.String("<!-- template -->\nFixes the crash on click."),
+			Labels:  []*github.Label{{Name: github.String("bug")}},
+		},
+		Repo:   &github.Repository{FullName: github.String("owner/repo"), HTMLURL: github.String("https://github.com/owner/repo")},
+		Sender: &github.User{Login: github.String("panda"), HTMLURL: github.String("https://github.com/panda")},
+	}
+
+	eventType, data := getNotificationTemplateData(event)
+	assert.Equal(t, "pull_request", eventType)
+	assert.Equal(t, &notificationTemplateData{
+		Event:     "pull_request",
+		Action:    "opened",
+		Repo:      "owner/repo",
+		RepoURL:   "https://github.com/owner/repo",
+		Sender:    "panda",
+		SenderURL: "https://github.com/panda",
+		Title:     "Fix the crash",
+		URL:       "https://github.com/owner/repo/pull/1",
+		Body:      "Fixes the crash on click.",
+		Labels:    []string{"bug"},
+	}, data)
+}
+
+func TestTruncateText(t *testing.T) {
+	assert.Equal(t, "short", truncateText("short", 10))
+	assert.Equal(t, "a long…", truncateText("a long text", 7))
+	assert.Equal(t, "héhé…", truncateText("héhéhé", 4))
+}
+
+func TestApplyNotificationTemplate(t *testing.T) {
+	templates, err := parseNotificationTemplates(`{
+		"terse": {
+			"issues": "{{.Repo}}: {{truncate 10 .Title}} [{{join \", \" .Labels}}] by {{.Sender}}",
+			"push": "{{index .Labels 0}}"
+		}
+	}`)
+	require.NoError(t, err)
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{notificationTemplates: templates})
+	api := &plugintest.API{}
+	p.SetAPI(api)
+
+	issueEvent := &github.IssuesEvent{
+		Action: github.String("opened"),
+		Issue: &github.Issue{
+			Title:  github.String("The app crashes on click"),
+			Labels: []*github.Label{{Name: github.String("bug")}, {Name: github.String("ui")}},
+		},
+		Repo:   &github.Repository{FullName: github.String("owner/repo")},
+		Sender: &github.User{Login: github.String("panda")},
+	}
+
+	t.Run("no template", func(t *testing.T) {
+		sub := &Subscription{}
+		assert.Equal(t, "built-in", p.applyNotificationTemplate(sub, issueEvent, "built-in"))
+	})
+
+	t.Run("template for the event type", func(t *testing.T) {
+		sub := &Subscription{Flags: SubscriptionFlags{Template: "terse"}}
+		assert.Equal(t, "owner/repo: The app cr… [bug, ui] by panda", p.applyNotificationTemplate(sub, issueEvent, "built-in"))
+	})
+
+	t.Run("no template for the event type", func(t *testing.T) {
+		sub := &Subscription{Flags: SubscriptionFlags{Template: "terse"}}
+		assert.Equal(t, "built-in", p.applyNotificationTemplate(sub, &github.CreateEvent{}, "built-in"))
+	})
+
+	t.Run("template failing to render", func(t *testing.T) {
+		api.On("LogWarn", "Failed to render notification template", "template", "terse", "event", "push", "error", mock.AnythingOfType("string")).Return().Once()
+		defer api.AssertExpectations(t)
+
+		sub := &Subscription{Flags: SubscriptionFlags{Template: "terse"}}
+		assert.Equal(t, "built-in", p.applyNotificationTemplate(sub, &github.PushEvent{}, "built-in"))
+	})
+
+	t.Run("template removed from the configuration", func(t *testing.T) {
+		api.On("LogWarn", "Unknown notification template of subscription", "template", "loud", "channelID", "channelID", "repo", "owner/repo").Return().Once()
+		defer api.AssertExpectations(t)
+
+		sub := &Subscription{ChannelID: "channelID", Repository: "owner/repo", Flags: SubscriptionFlags{Template: "loud"}}
+		assert.Equal(t, "built-in", p.applyNotificationTemplate(sub, issueEvent, "built-in"))
+	})
+}
+
+func TestTemplateFlag(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(templateFlag, "release-notes-style"))
+	assert.Equal(t, "release-notes-style", flags.Template)
+	assert.Equal(t, "--template release-notes-style", flags.String())
+
+	assert.Error(t, flags.SetFlag(templateFlag, ""))
+}
@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	orgMembershipKey        = "_orgmembership"
+	orgMembersGenerationKey = "_orgmembersgeneration"
+
+	// orgMembershipCacheTTL is how long the membership of a user is cached, in case a webhook event
+	// was missed.
+	orgMembershipCacheTTL = 6 * time.Hour
+)
+
+// orgMembershipKeyFor returns the key the membership of a user is cached under. Memberships are
+// cached per user checking them, as private members are only visible to the members of the
+// organization, and per generation of the organization, so that they can be invalidated at once.
+func orgMembershipKeyFor(org, generation, viewerID, login string) string {
+	return hashKey(orgMembershipKey, strings.ToLower(org), generation, viewerID, strings.ToLower(login))
+}
+
+func orgMembersGenerationKeyFor(org string) string {
+	return hashKey(orgMembersGenerationKey, strings.ToLower(org))
+}
+
+// getOrgMembersGeneration returns the generation of the cached memberships of an organization.
+func (p *Plugin) getOrgMembersGeneration(org string) (string, error) {
+	value, appErr := p.API.KVGet(orgMembersGenerationKeyFor(org))
+	if appErr != nil {
+		return "", errors.Wrap(appErr, "could not get organization members generation from KV store")
+	}
+
+	return string(value), nil
+}
+
+// invalidateOrgMembers drops the cached memberships of an organization by starting a new generation.
+// The memberships of the previous ones expire on their own.
+func (p *Plugin) invalidateOrgMembers(org string) error {
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if appErr := p.API.KVSet(orgMembersGenerationKeyFor(org), []byte(generation)); appErr != nil {
+		return errors.Wrap(appErr, "could not store organization members generation")
+	}
+
+	return nil
+}
+
+// handleOrganizationEvent drops the cached memberships of an organization when its members change.
+func (p *Plugin) handleOrganizationEvent(event *github.OrganizationEvent) {
+	switch event.GetAction() {
+	case "member_added", "member_removed":
+	default:
+		return
+	}
+
+	org := event.GetOrganization().GetLogin()
+	if org == "" {
+		return
+	}
+
+	if err := p.invalidateOrgMembers(org); err != nil {
+		p.API.LogWarn("Failed to invalidate cached organization members", "org", org, "error", err.Error())
+	}
+}
+
+// isUserOrganizationMember checks if a user is a member of an organization, as seen by the connected
+// user viewer. The answer of GitHub is cached.
+func (p *Plugin) isUserOrganizationMember(viewer *GitHubUserInfo, user *github.User, organization string) bool {
+	if organization == "" {
+		return false
+	}
+
+	key := ""
+	generation, err := p.getOrgMembersGeneration(organization)
+	if err != nil {
+		p.API.LogWarn("Failed to get cached organization members", "org", organization, "error", err.Error())
+	} else {
+		key = orgMembershipKeyFor(organization, generation, viewer.UserID, user.GetLogin())
+		if value, appErr := p.API.KVGet(key); appErr == nil && value != nil {
+			return string(value) == "true"
+		}
+	}
+
+	githubClient := p.githubConnect(*viewer.Token)
+	isMember, _, err := githubClient.Organizations.IsMember(context.Background(), organization, user.GetLogin())
+	if err != nil {
+		p.API.LogWarn("Failled to check if user is org member", "GitHub username", user.GetLogin(), "error", err.Error())
+		return false
+	}
+
+	if key != "" {
+		if appErr := p.API.KVSetWithExpiry(key, []byte(strconv.FormatBool(isMember)), int64(orgMembershipCacheTTL/time.Second)); appErr != nil {
+			p.API.LogWarn("Failed to cache organization membership", "org", organization, "error", appErr.Error())
+		}
+	}
+
+	return isMember
+}
+
+func (p *Plugin) handleAdminRefreshOrgMembers() string {
+	org := strings.TrimSpace(p.getConfiguration().GitHubOrg)
+	if org == "" {
+		return "Organization members are only cached when the plugin is locked to an organization. Set the GitHub Organization in the plugin settings."
+	}
+
+	if err := p.invalidateOrgMembers(org); err != nil {
+		p.API.LogWarn("Failed to invalidate cached organization members", "org", org, "error", err.Error())
+		return fmt.Sprintf("Encountered an error clearing the cached members of %s.", org)
+	}
+
+	return fmt.Sprintf("Cleared the cached members of %s. They are checked again on their next event.", org)
+}
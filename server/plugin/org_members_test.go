@@ -0,0 +1,185 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// mockOrgMembersStore mocks the KV store backing the cached organization members.
+func mockOrgMembersStore(api *plugintest.API) map[string][]byte {
+	store := mockKVStore(api)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+		store[key] = value
+		return nil
+	}).Maybe()
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(orgMembershipCacheTTL.Seconds())).Return(func(key string, value []byte, _ int64) *model.AppError {
+		store[key] = value
+		return nil
+	}).Maybe()
+
+	return store
+}
+
+func TestIsUserOrganizationMember(t *testing.T) {
+	checks := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks[r.URL.Path]++
+		switch r.URL.Path {
+		case "/api/v3/orgs/org/members/alice":
+			w.WriteHeader(http.StatusNoContent)
+		case "/api/v3/orgs/org/members/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	viewer := func(userID string) *GitHubUserInfo {
+		return &GitHubUserInfo{UserID: userID, Token: &oauth2.Token{AccessToken: token}}
+	}
+
+	setup := func() (*Plugin, *plugintest.API, map[string][]byte) {
+		checks = map[string]int{}
+
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		store := mockOrgMembersStore(api)
+		p.SetAPI(api)
+
+		return p, api, store
+	}
+	user := func(login string) *github.User {
+		return &github.User{Login: github.String(login)}
+	}
+
+	t.Run("memberships are cached", func(t *testing.T) {
+		p, _, store := setup()
+
+		assert.True(t, p.isUserOrganizationMember(viewer("viewerID"), user("alice"), "org"))
+		assert.True(t, p.isUserOrganizationMember(viewer("viewerID"), user("Alice"), "org"))
+		assert.False(t, p.isUserOrganizationMember(viewer("viewerID"), user("eve"), "org"))
+		assert.False(t, p.isUserOrganizationMember(viewer("viewerID"), user("eve"), "org"))
+		assert.Equal(t, map[string]int{"/api/v3/orgs/org/members/alice": 1, "/api/v3/orgs/org/members/eve": 1}, checks)
+		assert.Equal(t, []byte("true"), store[orgMembershipKeyFor("org", "", "viewerID", "alice")])
+	})
+
+	t.Run("memberships are cached per viewer", func(t *testing.T) {
+		p, _, store := setup()
+		store[orgMembershipKeyFor("org", "", "memberID", "eve")] = []byte("true")
+
+		assert.True(t, p.isUserOrganizationMember(viewer("memberID"), user("eve"), "org"))
+		assert.False(t, p.isUserOrganizationMember(viewer("outsiderID"), user("eve"), "org"))
+		assert.Equal(t, map[string]int{"/api/v3/orgs/org/members/eve": 1}, checks)
+	})
+
+	t.Run("invalidated memberships are checked again", func(t *testing.T) {
+		p, _, store := setup()
+		store[orgMembershipKeyFor("org", "", "viewerID", "alice")] = []byte("false")
+		require.NoError(t, p.invalidateOrgMembers("org"))
+
+		assert.True(t, p.isUserOrganizationMember(viewer("viewerID"), user("alice"), "org"))
+		assert.Equal(t, 1, checks["/api/v3/orgs/org/members/alice"])
+	})
+
+	t.Run("failed checks aren't cached", func(t *testing.T) {
+		p, api, store := setup()
+		api.On("LogWarn", "Failled to check if user is org member", "GitHub username", "broken", "error", mock.Anything)
+
+		assert.False(t, p.isUserOrganizationMember(viewer("viewerID"), user("broken"), "org"))
+		assert.NotContains(t, store, orgMembershipKeyFor("org", "", "viewerID", "broken"))
+	})
+
+	t.Run("no organization", func(t *testing.T) {
+		p, _, _ := setup()
+
+		assert.False(t, p.isUserOrganizationMember(viewer("viewerID"), user("alice"), ""))
+		assert.Empty(t, checks)
+	})
+}
+
+func TestOrgMembershipWebhooks(t *testing.T) {
+	setup := func() (*Plugin, map[string][]byte) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		store := mockOrgMembersStore(api)
+		api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
+		mockDeliveryLogs(api)
+		p.SetAPI(api)
+
+		return p, store
+	}
+	organizationEvent := func(action string) []byte {
+		return []byte(fmt.Sprintf(`{"action": %q, "membership": {"user": {"login": "bob"}}, "organization": {"login": "org"}}`, action))
+	}
+
+	t.Run("organization events", func(t *testing.T) {
+		p, store := setup()
+
+		require.NoError(t, p.processWebhookEvent("organization", "", organizationEvent("member_invited"), false))
+		assert.NotContains(t, store, orgMembersGenerationKeyFor("org"))
+
+		require.NoError(t, p.processWebhookEvent("organization", "", organizationEvent("member_added"), false))
+		generation := store[orgMembersGenerationKeyFor("org")]
+		assert.NotEmpty(t, generation)
+
+		require.NoError(t, p.processWebhookEvent("organization", "", organizationEvent("member_removed"), false))
+		assert.NotEqual(t, generation, store[orgMembersGenerationKeyFor("org")])
+	})
+
+	t.Run("team membership events", func(t *testing.T) {
+		p, store := setup()
+		event := func(action string) []byte {
+			return []byte(fmt.Sprintf(`{"action": %q, "scope": "team", "member": {"login": "carol"}, "team": {"slug": "core"}, "organization": {"login": "org"}}`, action))
+		}
+
+		// Leaving a team doesn't mean leaving the organization
+		require.NoError(t, p.processWebhookEvent("membership", "", event("removed"), false))
+		assert.NotContains(t, store, orgMembersGenerationKeyFor("org"))
+
+		require.NoError(t, p.processWebhookEvent("membership", "", event("added"), false))
+		assert.Contains(t, store, orgMembersGenerationKeyFor("org"))
+	})
+}
+
+func TestHandleAdminRefreshOrgMembers(t *testing.T) {
+	userInfo := &GitHubUserInfo{UserID: "adminID"}
+
+	t.Run("invalidates the cache", func(t *testing.T) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{GitHubOrg: "org"})
+		api := &plugintest.API{}
+		api.On("HasPermissionTo", "adminID", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+		api.On("KVSet", orgMembersGenerationKeyFor("org"), mock.Anything).Return(nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		message := p.handleAdmin(nil, &model.CommandArgs{UserId: "adminID"}, []string{"refresh-org-members"}, userInfo)
+		assert.Equal(t, "Cleared the cached members of org. They are checked again on their next event.", message)
+	})
+
+	t.Run("not locked to an organization", func(t *testing.T) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{})
+		api := &plugintest.API{}
+		api.On("HasPermissionTo", "adminID", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+		p.SetAPI(api)
+
+		message := p.handleAdmin(nil, &model.CommandArgs{UserId: "adminID"}, []string{"refresh-org-members"}, userInfo)
+		assert.Contains(t, message, "only cached when the plugin is locked to an organization")
+	})
+}
@@ -0,0 +1,184 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	connectTokenParameter   = "token"
+	connectTokenCallbackID  = "connect_token"
+	connectTokenElementName = "token"
+)
+
+var errInvalidPersonalAccessToken = errors.New("GitHub rejected this token. Please make sure it was copied entirely, and that it hasn't expired or been revoked.")
+
+// personalAccessTokenScopes are the scopes of classic personal access tokens the features of the
+// plugin rely on. Each of them is also granted by the broader scopes listed with it.
+var personalAccessTokenScopes = []struct {
+	Scope   string
+	Granted []string
+	Feature string
+}{
+	{Scope: "repo", Granted: []string{"repo"}, Feature: "your private repositories won't be available"},
+	{Scope: "notifications", Granted: []string{"notifications", "repo"}, Feature: "your unread messages and mentions won't be shown"},
+	{Scope: "read:org", Granted: []string{"read:org", "write:org", "admin:org"}, Feature: "the repositories and teams of your organizations won't be available"},
+}
+
+// isConnectTokenCommand reports whether the command is `/github connect token`.
+func isConnectTokenCommand(action string, parameters []string) bool {
+	return action == "connect" && len(parameters) == 1 && parameters[0] == connectTokenParameter
+}
+
+// handleConnectToken opens the dialog users paste their personal access token into. The token is
+// never part of the command, which is echoed and logged. An empty message is returned when the
+// dialog is opened.
+func (p *Plugin) handleConnectToken(args *model.CommandArgs, siteURL string) string {
+	if !p.getConfiguration().AllowPersonalAccessTokens {
+		return "Connecting with a personal access token is disabled. Please ask a System Admin to enable it, or use `/github connect`."
+	}
+
+	tokensURL := strings.TrimSuffix(p.getBaseURL(), "/") + "/settings/tokens"
+	dialog := model.OpenDialogRequest{
+		TriggerId: args.TriggerId,
+		URL:       fmt.Sprintf("%s/plugins/%s/api/v1/connect/token", strings.TrimSuffix(siteURL, "/"), Manifest.Id),
+		Dialog: model.Dialog{
+			CallbackId: connectTokenCallbackID,
+			Title:      "Connect with a Personal Access Token",
+			IntroductionText: fmt.Sprintf("Create a [personal access token](%s) on GitHub and paste it below. "+
+				"For all the features of the plugin, a classic token needs the `repo`, `notifications` and `read:org` scopes.", tokensURL),
+			Elements: []model.DialogElement{{
+				DisplayName: "Personal access token",
+				Name:        connectTokenElementName,
+				Type:        "text",
+				SubType:     "password",
+				HelpText:    "The token is stored encrypted, and is only used to act on GitHub on your behalf.",
+			}},
+			SubmitLabel: "Connect",
+		},
+	}
+
+	if appErr := p.API.OpenInteractiveDialog(dialog); appErr != nil {
+		p.API.LogWarn("Failed to open the personal access token dialog", "error", appErr.Error())
+		return "Encountered an error connecting to GitHub."
+	}
+
+	return ""
+}
+
+// connectWithPersonalAccessToken handles the submission of the personal access token dialog.
+func (p *Plugin) connectWithPersonalAccessToken(w http.ResponseWriter, r *http.Request, userID string) {
+	request := model.SubmitDialogRequestFromJson(r.Body)
+	if request == nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a dialog submission.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	p.writeJSON(w, p.submitPersonalAccessToken(r.Context(), userID, request))
+}
+
+// submitPersonalAccessToken connects the user with the token of the dialog if GitHub accepts it,
+// and warns them about the features the scopes of the token don't allow.
+func (p *Plugin) submitPersonalAccessToken(ctx context.Context, userID string, request *model.SubmitDialogRequest) *model.SubmitDialogResponse {
+	config := p.getConfiguration()
+	if !config.AllowPersonalAccessTokens {
+		return &model.SubmitDialogResponse{Error: "Connecting with a personal access token is disabled."}
+	}
+
+	if request.UserId != userID {
+		return &model.SubmitDialogResponse{Error: "Not authorized, incorrect user."}
+	}
+
+	value, _ := request.Submission[connectTokenElementName].(string)
+	token := strings.TrimSpace(value)
+	if token == "" {
+		return &model.SubmitDialogResponse{Errors: map[string]string{connectTokenElementName: "Please enter a token."}}
+	}
+
+	tok := &oauth2.Token{AccessToken: token}
+	githubClient := p.githubConnect(*tok)
+	gitUser, missingScopes, err := validatePersonalAccessToken(ctx, githubClient, config.EnablePrivateRepo)
+	if err != nil {
+		if err == errInvalidPersonalAccessToken {
+			return &model.SubmitDialogResponse{Errors: map[string]string{connectTokenElementName: err.Error()}}
+		}
+		p.API.LogWarn("Failed to validate personal access token", "userID", userID, "error", err.Error())
+		return &model.SubmitDialogResponse{Error: "Encountered an error checking the token with GitHub."}
+	}
+
+	privateAllowed := config.EnablePrivateRepo && !containsValue(missingScopes, "repo")
+	if _, err = p.connectGitHubAccount(ctx, userID, tok, githubClient, gitUser, privateAllowed, connectionModeToken); err != nil {
+		p.API.LogWarn("Failed to store GitHub user info", "userID", userID, "error", err.Error())
+		return &model.SubmitDialogResponse{Error: "Unable to connect user to GitHub."}
+	}
+
+	if len(missingScopes) > 0 {
+		p.CreateBotDMPost(userID, formatMissingScopes(missingScopes), "")
+	}
+
+	return &model.SubmitDialogResponse{}
+}
+
+// validatePersonalAccessToken returns the GitHub user of a token, and the scopes the plugin relies
+// on the token is missing. The repo scope is only required when private repositories are enabled.
+// Fine-grained tokens have no scopes, they only grant what was chosen on GitHub when creating them.
+func validatePersonalAccessToken(ctx context.Context, githubClient *github.Client, privateRepos bool) (*github.User, []string, error) {
+	gitUser, resp, err := githubClient.Users.Get(ctx, "")
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnauthorized {
+			return nil, nil, errInvalidPersonalAccessToken
+		}
+		return nil, nil, errors.Wrap(err, "could not get the authenticated user")
+	}
+
+	header, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]
+	if !ok {
+		return gitUser, nil, nil
+	}
+
+	scopes := []string{}
+	for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	missing := []string{}
+	for _, required := range personalAccessTokenScopes {
+		if required.Scope == "repo" && !privateRepos {
+			continue
+		}
+
+		granted := false
+		for _, scope := range required.Granted {
+			granted = granted || containsValue(scopes, scope)
+		}
+		if !granted {
+			missing = append(missing, required.Scope)
+		}
+	}
+
+	return gitUser, missing, nil
+}
+
+// formatMissingScopes explains the user what doesn't work with the scopes of their token.
+func formatMissingScopes(missing []string) string {
+	var message strings.Builder
+	message.WriteString("Your personal access token is missing scopes used by the plugin:\n")
+	for _, required := range personalAccessTokenScopes {
+		if containsValue(missing, required.Scope) {
+			fmt.Fprintf(&message, "* Without `%s`, %s.\n", required.Scope, required.Feature)
+		}
+	}
+	message.WriteString("\nTo use these features, add the scopes to the token on GitHub.")
+
+	return message.String()
+}
@@ -0,0 +1,207 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// newPersonalAccessTokenServer returns a GitHub server accepting the tokens with the given scopes.
+// Tokens without scopes are fine-grained tokens, which get no scopes header.
+func newPersonalAccessTokenServer(t *testing.T, tokenScopes map[string]*string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v3/user", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+
+		scopes, ok := tokenScopes[r.Header.Get("Authorization")]
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message": "Bad credentials"}`)
+			return
+		}
+		if scopes != nil {
+			w.Header().Set("X-OAuth-Scopes", *scopes)
+		}
+		fmt.Fprint(w, `{"login": "someone", "html_url": "https://github.com/someone"}`)
+	}))
+}
+
+func TestValidatePersonalAccessToken(t *testing.T) {
+	all := "repo, read:org"
+	partial := "notifications, admin:org"
+	none := ""
+	ts := newPersonalAccessTokenServer(t, map[string]*string{
+		"Bearer all":         &all,
+		"Bearer partial":     &partial,
+		"Bearer none":        &none,
+		"Bearer finegrained": nil,
+	})
+	defer ts.Close()
+
+	config := &Configuration{EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"}
+	validate := func(token string, privateRepos bool) ([]string, error) {
+		client, err := GetGitHubClient(oauth2.Token{AccessToken: token}, config)
+		require.NoError(t, err)
+		gitUser, missing, err := validatePersonalAccessToken(context.Background(), client, privateRepos)
+		if err == nil {
+			assert.Equal(t, "someone", gitUser.GetLogin())
+		}
+		return missing, err
+	}
+
+	for name, test := range map[string]struct {
+		Token        string
+		PrivateRepos bool
+		Missing      []string
+	}{
+		"all scopes":                   {Token: "all", PrivateRepos: true, Missing: []string{}},
+		"broader scopes":               {Token: "partial", Missing: []string{}},
+		"repo missing":                 {Token: "partial", PrivateRepos: true, Missing: []string{"repo"}},
+		"no scopes":                    {Token: "none", PrivateRepos: true, Missing: []string{"repo", "notifications", "read:org"}},
+		"no scopes without private":    {Token: "none", Missing: []string{"notifications", "read:org"}},
+		"fine-grained tokens unscoped": {Token: "finegrained", PrivateRepos: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			missing, err := validate(test.Token, test.PrivateRepos)
+			require.NoError(t, err)
+			assert.Equal(t, test.Missing, missing)
+		})
+	}
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := validate("revoked", true)
+		assert.Equal(t, errInvalidPersonalAccessToken, err)
+	})
+}
+
+func TestSubmitPersonalAccessToken(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	none := ""
+	ts := newPersonalAccessTokenServer(t, map[string]*string{"Bearer valid": &none})
+	defer ts.Close()
+
+	setup := func(allowed bool) (*Plugin, map[string][]byte, *[]*model.Post) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{
+			EncryptionKey:             encryptionKey,
+			EnterpriseBaseURL:         ts.URL + "/",
+			EnterpriseUploadURL:       ts.URL + "/",
+			AllowPersonalAccessTokens: allowed,
+		})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("KVDelete", mock.AnythingOfType("string")).Return(nil)
+		api.On("GetDirectChannel", "userID", "botID").Return(&model.Channel{Id: "dmID"}, nil)
+		posts := &[]*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			*posts = append(*posts, post)
+			return post
+		}, nil)
+		api.On("PublishWebSocketEvent", wsEventConnect, mock.MatchedBy(func(payload map[string]interface{}) bool {
+			return payload["connection_mode"] == connectionModeToken
+		}), &model.WebsocketBroadcast{UserId: "userID"})
+		p.SetAPI(api)
+
+		return p, store, posts
+	}
+	submit := func(p *Plugin, token string) *model.SubmitDialogResponse {
+		return p.submitPersonalAccessToken(context.Background(), "userID", &model.SubmitDialogRequest{
+			UserId:     "userID",
+			Submission: map[string]interface{}{connectTokenElementName: token},
+		})
+	}
+
+	t.Run("connected", func(t *testing.T) {
+		p, store, posts := setup(true)
+
+		assert.Equal(t, &model.SubmitDialogResponse{}, submit(p, " valid "))
+
+		var info GitHubUserInfo
+		require.NoError(t, json.Unmarshal(store["userID"+githubTokenKey], &info))
+		assert.Equal(t, "someone", info.GitHubUsername)
+		assert.Equal(t, connectionModeToken, info.ConnectionMode)
+		assert.True(t, info.isTokenConnection())
+		assert.False(t, info.AllowedPrivateRepos)
+		assert.NotEqual(t, "valid", info.Token.AccessToken)
+		token, err := decrypt([]byte(encryptionKey), info.Token.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "valid", token)
+		assert.Equal(t, []byte("userID"), store["someone"+githubUsernameKey])
+
+		require.Len(t, *posts, 2)
+		assert.Equal(t, "custom_git_welcome", (*posts)[0].Type)
+		assert.Equal(t, "Your personal access token is missing scopes used by the plugin:\n"+
+			"* Without `notifications`, your unread messages and mentions won't be shown.\n"+
+			"* Without `read:org`, the repositories and teams of your organizations won't be available.\n"+
+			"\nTo use these features, add the scopes to the token on GitHub.", (*posts)[1].Message)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		p, store, posts := setup(true)
+
+		assert.Equal(t, &model.SubmitDialogResponse{Errors: map[string]string{connectTokenElementName: errInvalidPersonalAccessToken.Error()}}, submit(p, "revoked"))
+		assert.Equal(t, &model.SubmitDialogResponse{Errors: map[string]string{connectTokenElementName: "Please enter a token."}}, submit(p, " "))
+		assert.Empty(t, store)
+		assert.Empty(t, *posts)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		p, store, _ := setup(false)
+
+		assert.Equal(t, &model.SubmitDialogResponse{Error: "Connecting with a personal access token is disabled."}, submit(p, "valid"))
+		assert.Empty(t, store)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		p, store, _ := setup(true)
+
+		response := p.submitPersonalAccessToken(context.Background(), "otherID", &model.SubmitDialogRequest{
+			UserId:     "userID",
+			Submission: map[string]interface{}{connectTokenElementName: "valid"},
+		})
+		assert.Equal(t, "Not authorized, incorrect user.", response.Error)
+		assert.Empty(t, store)
+	})
+}
+
+func TestHandleConnectToken(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{})
+	args := &model.CommandArgs{UserId: "userID", TriggerId: "triggerID"}
+	assert.Equal(t, "Connecting with a personal access token is disabled. Please ask a System Admin to enable it, or use `/github connect`.",
+		p.handleConnectToken(args, "https://mattermost.example.com"))
+
+	p.setConfiguration(&Configuration{AllowPersonalAccessTokens: true})
+	api := &plugintest.API{}
+	api.On("OpenInteractiveDialog", mock.MatchedBy(func(dialog model.OpenDialogRequest) bool {
+		return dialog.TriggerId == "triggerID" &&
+			dialog.URL == "https://mattermost.example.com/plugins/github/api/v1/connect/token" &&
+			dialog.Dialog.Elements[0].SubType == "password"
+	})).Return(nil).Once()
+	p.SetAPI(api)
+
+	assert.Equal(t, "", p.handleConnectToken(args, "https://mattermost.example.com/"))
+	api.AssertExpectations(t)
+}
+
+func TestIsConnectTokenCommand(t *testing.T) {
+	assert.True(t, isConnectTokenCommand("connect", []string{"token"}))
+	assert.False(t, isConnectTokenCommand("connect", []string{"private"}))
+	assert.False(t, isConnectTokenCommand("connect", []string{"token", "ghp_secret"}))
+	assert.False(t, isConnectTokenCommand("disconnect", []string{"token"}))
+}
@@ -0,0 +1,226 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	pathsFlag = "paths"
+
+	pullRequestFilesKey = "_prfiles"
+	// pullRequestFilesCacheTTL is how long the changed files of a pull request are cached, in seconds.
+	pullRequestFilesCacheTTL = 5 * 60
+	// pullRequestFilesPageSize caps the number of changed files of a pull request matched against path patterns.
+	pullRequestFilesPageSize = 100
+)
+
+// compilePathPattern turns a glob pattern into a regular expression matching whole file paths.
+// `*` and `?` don't match `/`, while `**` matches any number of directories. A pattern ending
+// with `/` matches everything under that directory.
+func compilePathPattern(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimPrefix(pattern, "/")
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					expr.WriteString("(?:.*/)?")
+				} else {
+					expr.WriteString(".*")
+				}
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end <= 0 {
+				return nil, errors.Errorf("unterminated character class in %q", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 == len(pattern) {
+				return nil, errors.Errorf("trailing backslash in %q", pattern)
+			}
+			i++
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid pattern %q", pattern)
+	}
+
+	return re, nil
+}
+
+// parsePathPatterns parses the value of the --paths flag, e.g. "services/payments/**,docs/payments/*".
+func parsePathPatterns(value string) ([]*regexp.Regexp, error) {
+	patterns := []*regexp.Regexp{}
+	for _, pattern := range strings.Split(strings.Trim(value, `"`), ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		re, err := compilePathPattern(pattern)
+		if err != nil {
+			return nil, errors.Errorf("Invalid value for --%s: %s.", pathsFlag, err.Error())
+		}
+		patterns = append(patterns, re)
+	}
+
+	if len(patterns) == 0 {
+		return nil, errors.Errorf("Invalid value for --%s. Use a comma-separated list of path patterns, e.g. `--%s \"services/payments/**,docs/payments/*\"`.", pathsFlag, pathsFlag)
+	}
+
+	return patterns, nil
+}
+
+// normalizePathPatterns returns the canonical form of the value of the --paths flag.
+func normalizePathPatterns(value string) string {
+	patterns := []string{}
+	for _, pattern := range strings.Split(strings.Trim(value, `"`), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return strings.Join(patterns, ",")
+}
+
+// matchesAnyPath checks if any of the files matches any of the patterns.
+func matchesAnyPath(patterns []*regexp.Regexp, files []string) bool {
+	for _, file := range files {
+		for _, pattern := range patterns {
+			if pattern.MatchString(file) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// pushedFiles returns the files added, modified or removed by the commits of a push.
+func pushedFiles(commits []*github.HeadCommit) []string {
+	files := []string{}
+	for _, commit := range commits {
+		files = append(files, commit.Added...)
+		files = append(files, commit.Modified...)
+		files = append(files, commit.Removed...)
+	}
+
+	return files
+}
+
+// pushMatchesPaths checks if a push touches the paths a subscription is filtered by, if any.
+func (p *Plugin) pushMatchesPaths(sub *Subscription, commits []*github.HeadCommit) bool {
+	if sub.Flags.Paths == "" {
+		return true
+	}
+
+	patterns, err := parsePathPatterns(sub.Flags.Paths)
+	if err != nil {
+		p.API.LogWarn("Invalid path patterns", "channelID", sub.ChannelID, "repo", sub.Repository, "error", err.Error())
+		return true
+	}
+
+	return matchesAnyPath(patterns, pushedFiles(commits))
+}
+
+// getPullRequestFiles returns the first changed files of a pull request, listed with the client of
+// the subscription creator and cached for each head commit.
+func (p *Plugin) getPullRequestFiles(sub *Subscription, event *github.PullRequestEvent) ([]string, error) {
+	repo := event.GetRepo()
+	pr := event.GetPullRequest()
+	key := hashKey(pullRequestFilesKey, repo.GetFullName(), pr.GetHead().GetSHA())
+
+	value, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get pull request files from KV store")
+	}
+	if value != nil {
+		files := []string{}
+		if err := json.Unmarshal(value, &files); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal pull request files")
+		}
+		return files, nil
+	}
+
+	info, apiErr := p.getGitHubUserInfo(sub.CreatorID)
+	if apiErr != nil {
+		return nil, errors.Wrap(apiErr, "failed to get subscription creator info")
+	}
+
+	commitFiles, _, err := p.githubConnect(*info.Token).PullRequests.ListFiles(context.Background(), repo.GetOwner().GetLogin(), repo.GetName(), pr.GetNumber(), &github.ListOptions{PerPage: pullRequestFilesPageSize})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pull request files")
+	}
+
+	files := []string{}
+	for _, file := range commitFiles {
+		files = append(files, file.GetFilename())
+	}
+
+	value, err = json.Marshal(files)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal pull request files")
+	}
+	if appErr := p.API.KVSetWithExpiry(key, value, pullRequestFilesCacheTTL); appErr != nil {
+		p.API.LogWarn("Failed to cache pull request files", "repo", repo.GetFullName(), "number", pr.GetNumber(), "error", appErr.Error())
+	}
+
+	return files, nil
+}
+
+// pullRequestMatchesPaths checks if a pull request changes the paths a subscription is filtered by,
+// if any. When the changed files can't be listed, or only some of them are listed, the pull
+// request is deemed to match rather than risking dropping its notification.
+func (p *Plugin) pullRequestMatchesPaths(sub *Subscription, event *github.PullRequestEvent) bool {
+	if sub.Flags.Paths == "" {
+		return true
+	}
+
+	patterns, err := parsePathPatterns(sub.Flags.Paths)
+	if err != nil {
+		p.API.LogWarn("Invalid path patterns", "channelID", sub.ChannelID, "repo", sub.Repository, "error", err.Error())
+		return true
+	}
+
+	files, err := p.getPullRequestFiles(sub, event)
+	if err != nil {
+		p.API.LogWarn("Failed to get pull request files to match paths", "repo", event.GetRepo().GetFullName(), "number", event.GetPullRequest().GetNumber(), "error", err.Error())
+		return true
+	}
+
+	if matchesAnyPath(patterns, files) {
+		return true
+	}
+
+	return len(files) < event.GetPullRequest().GetChangedFiles()
+}
@@ -0,0 +1,175 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestCompilePathPattern(t *testing.T) {
+	for pattern, tc := range map[string]struct {
+		matches    []string
+		nonMatches []string
+	}{
+		"services/payments/**": {
+			matches:    []string{"services/payments/main.go", "services/payments/api/handler.go"},
+			nonMatches: []string{"services/billing/main.go", "services/payments"},
+		},
+		"docs/payments/*": {
+			matches:    []string{"docs/payments/README.md"},
+			nonMatches: []string{"docs/payments/api/README.md", "docs/README.md"},
+		},
+		"**/*.go": {
+			matches:    []string{"main.go", "server/plugin/plugin.go"},
+			nonMatches: []string{"webapp/index.js", "main.go.orig"},
+		},
+		"docs/": {
+			matches:    []string{"docs/index.md", "docs/a/b.md"},
+			nonMatches: []string{"webapp/docs/index.md"},
+		},
+		"/Makefile": {
+			matches:    []string{"Makefile"},
+			nonMatches: []string{"build/Makefile"},
+		},
+		"file?.[ch]": {
+			matches:    []string{"file1.c", "filea.h"},
+			nonMatches: []string{"file1.go", "file/.c", "file12.c"},
+		},
+		"[!.]*": {
+			matches:    []string{"main.go"},
+			nonMatches: []string{".gitignore"},
+		},
+	} {
+		t.Run(pattern, func(t *testing.T) {
+			re, err := compilePathPattern(pattern)
+			require.NoError(t, err)
+			for _, file := range tc.matches {
+				assert.True(t, re.MatchString(file), file)
+			}
+			for _, file := range tc.nonMatches {
+				assert.False(t, re.MatchString(file), file)
+			}
+		})
+	}
+
+	for _, pattern := range []string{"docs/[a-", "docs/[]", "docs\\", "[z-a]"} {
+		_, err := compilePathPattern(pattern)
+		assert.Error(t, err, pattern)
+	}
+}
+
+func TestPathsFlag(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(pathsFlag, `"services/payments/**, docs/payments/*"`))
+	assert.Equal(t, "services/payments/**,docs/payments/*", flags.Paths)
+	assert.Equal(t, `--paths "services/payments/**,docs/payments/*"`, flags.String())
+
+	// The flag round-trips through its string form
+	parsed := SubscriptionFlags{}
+	require.NoError(t, parsed.SetFlag(pathsFlag, `"services/payments/**,docs/payments/*"`))
+	assert.Equal(t, flags, parsed)
+
+	assert.Error(t, flags.SetFlag(pathsFlag, `"docs/[a-"`))
+	assert.Error(t, flags.SetFlag(pathsFlag, `" , "`))
+}
+
+func TestPushMatchesPaths(t *testing.T) {
+	p := NewPlugin()
+	commits := []*github.HeadCommit{
+		{Added: []string{"README.md"}},
+		{Modified: []string{"services/billing/main.go"}, Removed: []string{"services/payments/old.go"}},
+	}
+
+	assert.True(t, p.pushMatchesPaths(&Subscription{}, commits))
+	assert.True(t, p.pushMatchesPaths(&Subscription{Flags: SubscriptionFlags{Paths: "services/payments/**"}}, commits))
+	assert.False(t, p.pushMatchesPaths(&Subscription{Flags: SubscriptionFlags{Paths: "docs/**,webapp/**"}}, commits))
+}
+
+func TestPullRequestMatchesPaths(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "creatorID", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	event := func(changedFiles int) *github.PullRequestEvent {
+		return &github.PullRequestEvent{
+			Action: github.String("opened"),
+			PullRequest: &github.PullRequest{
+				Number:       github.Int(1),
+				ChangedFiles: github.Int(changedFiles),
+				Head:         &github.PullRequestBranch{SHA: github.String("abc")},
+			},
+			Repo: &github.Repository{FullName: github.String("owner/repo"), Name: github.String("repo"), Owner: &github.User{Login: github.String("owner")}},
+		}
+	}
+
+	setup := func(status int) (*Plugin, *plugintest.API, *int) {
+		requests := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if r.URL.Path != "/api/v3/repos/owner/repo/pulls/1/files" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(status)
+			fmt.Fprint(w, `[{"filename": "services/payments/main.go"}, {"filename": "README.md"}]`)
+		}))
+		t.Cleanup(ts.Close)
+
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		store["creatorID"+githubTokenKey] = info
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(pullRequestFilesCacheTTL)).Return(func(key string, value []byte, ttl int64) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		return p, api, &requests
+	}
+
+	t.Run("matching files", func(t *testing.T) {
+		p, _, requests := setup(http.StatusOK)
+		sub := &Subscription{CreatorID: "creatorID", Flags: SubscriptionFlags{Paths: "services/payments/**"}}
+
+		assert.True(t, p.pullRequestMatchesPaths(sub, event(2)))
+		assert.True(t, p.pullRequestMatchesPaths(sub, event(2)))
+		assert.Equal(t, 1, *requests, "the changed files are cached")
+	})
+
+	t.Run("no matching file", func(t *testing.T) {
+		p, _, _ := setup(http.StatusOK)
+		sub := &Subscription{CreatorID: "creatorID", Flags: SubscriptionFlags{Paths: "docs/**"}}
+
+		assert.False(t, p.pullRequestMatchesPaths(sub, event(2)))
+	})
+
+	t.Run("more changed files than listed", func(t *testing.T) {
+		p, _, _ := setup(http.StatusOK)
+		sub := &Subscription{CreatorID: "creatorID", Flags: SubscriptionFlags{Paths: "docs/**"}}
+
+		assert.True(t, p.pullRequestMatchesPaths(sub, event(150)))
+	})
+
+	t.Run("failing to list the files", func(t *testing.T) {
+		p, api, _ := setup(http.StatusInternalServerError)
+		sub := &Subscription{CreatorID: "creatorID", Flags: SubscriptionFlags{Paths: "docs/**"}}
+
+		assert.True(t, p.pullRequestMatchesPaths(sub, event(2)))
+		api.AssertCalled(t, "LogWarn", "Failed to get pull request files to match paths", "repo", "owner/repo", "number", 1, "error", mock.AnythingOfType("string"))
+	})
+}
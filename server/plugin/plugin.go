@@ -12,9 +12,11 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/go-github/v31/github"
 	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-plugin-api/cluster"
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
 	"github.com/pkg/errors"
@@ -26,24 +28,39 @@ const (
 	githubUsernameKey    = "_githubusername"
 	githubPrivateRepoKey = "_githubprivate"
 
-	wsEventConnect     = "connect"
-	wsEventDisconnect  = "disconnect"
-	wsEventRefresh     = "refresh"
-	wsEventCreateIssue = "createIssue"
-
-	settingButtonsTeam   = "team"
-	settingNotifications = "notifications"
-	settingReminders     = "reminders"
-	settingOn            = "on"
-	settingOff           = "off"
+	wsEventConnect      = "connect"
+	wsEventDisconnect   = "disconnect"
+	wsEventRefresh      = "refresh"
+	wsEventCreateIssue  = "createIssue"
+	wsEventConfigUpdate = "config_update"
+
+	settingButtonsTeam     = "team"
+	settingNotifications   = "notifications"
+	settingReminders       = "reminders"
+	settingWeeklySummary   = "weekly-summary"
+	settingDiscoverable    = "discoverable"
+	settingReadOnly        = "read-only"
+	settingConsolidatedDMs = "consolidated-dms"
+	settingUrgentDMs       = "urgent-dms"
+	settingOn              = "on"
+	settingOff             = "off"
 
 	notificationReasonSubscribed = "subscribed"
+
+	// defaultUnreadsPageLimit is used when no limit of pages of notifications is configured.
+	defaultUnreadsPageLimit = 5
+
+	// oauthAppRestrictionsMessage is part of the error GitHub returns when an organization restricts third-party access.
+	oauthAppRestrictionsMessage = "OAuth App access restrictions"
+	oauthAppRestrictionsDocsURL = "https://docs.github.com/articles/restricting-access-to-your-organization-s-data/"
 )
 
 type Plugin struct {
 	plugin.MattermostPlugin
 	// githubPermalinkRegex is used to parse github permalinks in post messages.
 	githubPermalinkRegex *regexp.Regexp
+	// githubGistRegex is used to parse gist permalinks in post messages.
+	githubGistRegex *regexp.Regexp
 
 	BotUserID string
 
@@ -57,26 +74,77 @@ type Plugin struct {
 	configuration *Configuration
 
 	router *mux.Router
+
+	// weeklyDigestJob posts the weekly digests of the subscriptions asking for them.
+	weeklyDigestJob *cluster.Job
+	// weeklySummaryJob sends the weekly summaries of the users asking for them.
+	weeklySummaryJob *cluster.Job
+	// postRetryJob retries the subscription posts that failed to be created.
+	postRetryJob *cluster.Job
+	// reviewSLAJob nudges about the review requests pending for longer than the SLA of their subscriptions.
+	reviewSLAJob *cluster.Job
+	// resumeSubscriptionsJob resumes the subscriptions whose pause is over or whose channel was unarchived.
+	resumeSubscriptionsJob *cluster.Job
+	// refreshAllJob refreshes the sidebars of all users when a system admin asks for it.
+	refreshAllJob *cluster.Job
+	// tokenHealthJob checks the tokens of the connected users, disconnecting those whose token was revoked.
+	tokenHealthJob *cluster.Job
+	// channelSuggestionsJob refreshes the repositories suggested to the new channels named after them.
+	channelSuggestionsJob *cluster.Job
+	// cancelSidebarWarmup stops the prefetch of the sidebars of recently active users.
+	cancelSidebarWarmup context.CancelFunc
+	// lastSeenWrites holds when the last seen time of each user was last stored by this server.
+	lastSeenWrites sync.Map
+	// webhookDeliveryWrites holds when the last webhook delivery of each repository was last stored by this server.
+	webhookDeliveryWrites sync.Map
+
+	// subscriptionIndex keeps the subscriptions in memory for the webhook events.
+	subscriptionIndex subscriptionIndex
+	// routeIndex keeps the routes in memory for the webhook events.
+	routeIndex routeIndex
+	// webhookQueue processes the webhook deliveries in a pool of workers.
+	webhookQueue *webhookQueue
+	// eventRouter routes the webhook events to their handlers.
+	eventRouter *EventRouter
 }
 
 // NewPlugin returns an instance of a Plugin.
 func NewPlugin() *Plugin {
 	p := &Plugin{
 		githubPermalinkRegex: regexp.MustCompile(`https?://(?P<haswww>www\.)?github\.com/(?P<user>[\w-]+)/(?P<repo>[\w-]+)/blob/(?P<commit>\w+)/(?P<path>[\w-/.]+)#(?P<line>[\w-]+)?`),
+		githubGistRegex:      regexp.MustCompile(`https?://gist\.github\.com/(?P<user>[\w-]+)/(?P<id>[0-9a-fA-F]+)(?:/(?P<revision>[0-9a-fA-F]{40}))?#(?P<anchor>file-[\w-]+)`),
 	}
 
+	p.eventRouter = p.newEventRouter()
+
 	p.CommandHandlers = map[string]CommandHandleFunc{
-		"subscriptions": p.handleSubscriptions,
-		"subscribe":     p.handleSubscribe,
-		"unsubscribe":   p.handleUnsubscribe,
-		"disconnect":    p.handleDisconnect,
-		"todo":          p.handleTodo,
-		"mute":          p.handleMuteCommand,
-		"me":            p.handleMe,
-		"help":          p.handleHelp,
-		"":              p.handleHelp,
-		"settings":      p.handleSettings,
-		"issue":         p.handleIssue,
+		"subscriptions":       p.handleSubscriptions,
+		"subscribe":           p.handleSubscribe,
+		"unsubscribe":         p.handleUnsubscribe,
+		"disconnect":          p.handleDisconnect,
+		"todo":                p.handleTodo,
+		"mute":                p.handleMuteCommand,
+		"me":                  p.handleMe,
+		"whois":               p.handleWhois,
+		"help":                p.handleHelp,
+		"":                    p.handleHelp,
+		"settings":            p.handleSettings,
+		"issue":               p.handleIssue,
+		"reviewers":           p.handleReviewers,
+		"assign":              p.handleAssign,
+		"unassign":            p.handleUnassign,
+		"channel-settings":    p.handleChannelSettings,
+		"export-events":       p.handleExportEvents,
+		"webhook":             p.handleWebhookCommand,
+		"admin":               p.handleAdmin,
+		"setup":               p.handleSetup,
+		"keywords":            p.handleKeywords,
+		"workflow":            p.handleWorkflow,
+		"pr":                  p.handlePR,
+		"search":              p.handleSearch,
+		"watch":               p.handleWatch,
+		linkUsernameCommand:   p.handleLinkUsername,
+		unlinkUsernameCommand: p.handleUnlinkUsername,
 	}
 
 	return p
@@ -98,12 +166,16 @@ func GetGitHubClient(token oauth2.Token, config *Configuration) (*github.Client,
 	ts := oauth2.StaticTokenSource(&token)
 	tc := oauth2.NewClient(context.Background(), ts)
 
+	return newGitHubClient(tc, config)
+}
+
+// newGitHubClient returns a client of GitHub or GitHub Enterprise making requests with the given HTTP client.
+func newGitHubClient(tc *http.Client, config *Configuration) (*github.Client, error) {
 	if config.EnterpriseBaseURL == "" || config.EnterpriseUploadURL == "" {
 		return github.NewClient(tc), nil
 	}
 
-	baseURL, _ := url.Parse(config.EnterpriseBaseURL)
-	baseURL.Path = path.Join(baseURL.Path, "api", "v3")
+	baseURL, _ := url.Parse(getAPIBaseURL(config.EnterpriseBaseURL))
 
 	uploadURL, _ := url.Parse(config.EnterpriseUploadURL)
 	uploadURL.Path = path.Join(uploadURL.Path, "api", "v3")
@@ -156,6 +228,120 @@ func (p *Plugin) OnActivate() error {
 
 	registerGitHubToUsernameMappingCallback(p.getGitHubToUsernameMapping)
 
+	job, err := cluster.Schedule(p.API, weeklyDigestJobKey, cluster.MakeWaitForRoundedInterval(weeklyDigestJobInterval), p.postWeeklyDigests)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule weekly digest job")
+	}
+	p.weeklyDigestJob = job
+
+	summaryJob, err := cluster.Schedule(p.API, weeklySummaryJobKey, cluster.MakeWaitForRoundedInterval(weeklyDigestJobInterval), p.postWeeklySummaries)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule weekly summary job")
+	}
+	p.weeklySummaryJob = summaryJob
+
+	// Posts queued before a restart are retried right away.
+	p.drainPostRetryQueue()
+
+	retryJob, err := cluster.Schedule(p.API, postRetryJobKey, cluster.MakeWaitForInterval(postRetryJobInterval), p.drainPostRetryQueue)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule post retry job")
+	}
+	p.postRetryJob = retryJob
+
+	refreshAllJob, err := cluster.Schedule(p.API, refreshAllJobKey, cluster.MakeWaitForInterval(refreshAllJobInterval), p.refreshAllSidebars)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule refresh job")
+	}
+	p.refreshAllJob = refreshAllJob
+
+	reviewSLAJob, err := cluster.Schedule(p.API, reviewSLAJobKey, cluster.MakeWaitForRoundedInterval(reviewSLAJobInterval), p.nudgeOverdueReviews)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule review SLA job")
+	}
+	p.reviewSLAJob = reviewSLAJob
+
+	resumeSubscriptionsJob, err := cluster.Schedule(p.API, resumeSubscriptionsJobKey, cluster.MakeWaitForInterval(resumeSubscriptionsJobInterval), p.resumeSubscriptions)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule subscription resume job")
+	}
+	p.resumeSubscriptionsJob = resumeSubscriptionsJob
+
+	tokenHealthJob, err := cluster.Schedule(p.API, tokenHealthJobKey, cluster.MakeWaitForInterval(tokenHealthJobInterval), p.checkTokenHealth)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule token health job")
+	}
+	p.tokenHealthJob = tokenHealthJob
+
+	channelSuggestionsJob, err := cluster.Schedule(p.API, channelSuggestionsJobKey, cluster.MakeWaitForInterval(channelSuggestionsJobInterval), p.refreshSuggestionRepositories)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule channel suggestions job")
+	}
+	p.channelSuggestionsJob = channelSuggestionsJob
+
+	warmupCtx, cancel := context.WithCancel(context.Background())
+	p.cancelSidebarWarmup = cancel
+	go p.warmUpSidebars(warmupCtx)
+
+	p.startWebhookQueue()
+
+	return nil
+}
+
+func (p *Plugin) OnDeactivate() error {
+	p.stopWebhookQueue()
+
+	if p.cancelSidebarWarmup != nil {
+		p.cancelSidebarWarmup()
+	}
+
+	if p.weeklyDigestJob != nil {
+		if err := p.weeklyDigestJob.Close(); err != nil {
+			p.API.LogWarn("Failed to close weekly digest job", "error", err.Error())
+		}
+	}
+	if p.weeklySummaryJob != nil {
+		if err := p.weeklySummaryJob.Close(); err != nil {
+			p.API.LogWarn("Failed to close weekly summary job", "error", err.Error())
+		}
+	}
+
+	if p.postRetryJob != nil {
+		if err := p.postRetryJob.Close(); err != nil {
+			p.API.LogWarn("Failed to close post retry job", "error", err.Error())
+		}
+	}
+
+	if p.reviewSLAJob != nil {
+		if err := p.reviewSLAJob.Close(); err != nil {
+			p.API.LogWarn("Failed to close review SLA job", "error", err.Error())
+		}
+	}
+
+	if p.refreshAllJob != nil {
+		if err := p.refreshAllJob.Close(); err != nil {
+			p.API.LogWarn("Failed to close refresh job", "error", err.Error())
+		}
+	}
+
+	if p.resumeSubscriptionsJob != nil {
+		if err := p.resumeSubscriptionsJob.Close(); err != nil {
+			p.API.LogWarn("Failed to close subscription resume job", "error", err.Error())
+		}
+	}
+
+	if p.tokenHealthJob != nil {
+		if err := p.tokenHealthJob.Close(); err != nil {
+			p.API.LogWarn("Failed to close token health job", "error", err.Error())
+		}
+	}
+
+	if p.channelSuggestionsJob != nil {
+		if err := p.channelSuggestionsJob.Close(); err != nil {
+			p.API.LogWarn("Failed to close channel suggestions job", "error", err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -192,10 +378,16 @@ func (p *Plugin) MessageWillBePosted(c *plugin.Context, post *model.Post) (*mode
 	ghClient := p.githubConnect(*info.Token)
 
 	replacements := p.getReplacements(msg)
-	post.Message = p.makeReplacements(msg, replacements, ghClient)
+	msg = p.makeReplacements(msg, replacements, ghClient)
+	gistReplacements := p.getGistReplacements(msg)
+	post.Message = p.makeGistReplacements(msg, gistReplacements, ghClient)
 	return post, ""
 }
 
+func (p *Plugin) MessageHasBeenUpdated(c *plugin.Context, newPost, oldPost *model.Post) {
+	p.promptAttachedCommentsUpdate(newPost, oldPost)
+}
+
 func (p *Plugin) getOAuthConfig(privateAllowed bool) *oauth2.Config {
 	config := p.getConfiguration()
 
@@ -231,12 +423,43 @@ type GitHubUserInfo struct {
 	LastToDoPostAt      int64
 	Settings            *UserSettings
 	AllowedPrivateRepos bool
+	RestrictedOrgs      []string
+	// TimezoneOffset is the offset of the timezone of the user in minutes behind UTC, as last sent
+	// by their browser.
+	TimezoneOffset int
+	// ConnectionMode is how the user connected their account. It is empty for the users who
+	// connected with OAuth before personal access tokens were allowed.
+	ConnectionMode string `json:",omitempty"`
+}
+
+// How users connect their account.
+const (
+	connectionModeOAuth = "oauth"
+	connectionModeToken = "token"
+)
+
+// isTokenConnection reports whether the user connected with a personal access token, which GitHub
+// never refreshes or expires on behalf of the plugin.
+func (info *GitHubUserInfo) isTokenConnection() bool {
+	return info.ConnectionMode == connectionModeToken
 }
 
 type UserSettings struct {
-	SidebarButtons string `json:"sidebar_buttons"`
-	DailyReminder  bool   `json:"daily_reminder"`
-	Notifications  bool   `json:"notifications"`
+	SidebarButtons        string `json:"sidebar_buttons"`
+	DailyReminder         bool   `json:"daily_reminder"`
+	Notifications         bool   `json:"notifications"`
+	WeeklySummary         bool   `json:"weekly_summary"`
+	WeeklySummarySchedule string `json:"weekly_summary_schedule,omitempty"`
+	// Discoverable allows resolving the GitHub login of the user to them for other users. It is
+	// nil for users who never changed it, who are discoverable.
+	Discoverable *bool `json:"discoverable,omitempty"`
+	// ReadOnlyMode prevents the plugin from making changes on GitHub with the account of the user.
+	ReadOnlyMode bool `json:"read_only_mode,omitempty"`
+	// ConsolidatedDMs posts the personal notifications of a day in a single thread.
+	ConsolidatedDMs bool `json:"consolidated_dms,omitempty"`
+	// UrgentDMsAsRootPosts keeps posting mentions and review requests on their own when the
+	// notifications are consolidated.
+	UrgentDMsAsRootPosts bool `json:"urgent_dms_as_root_posts,omitempty"`
 }
 
 func (p *Plugin) storeGitHubUserInfo(info *GitHubUserInfo) error {
@@ -268,7 +491,7 @@ func (p *Plugin) getGitHubUserInfo(userID string) (*GitHubUserInfo, *APIErrorRes
 
 	infoBytes, appErr := p.API.KVGet(userID + githubTokenKey)
 	if appErr != nil || infoBytes == nil {
-		return nil, &APIErrorResponse{ID: apiErrorIDNotConnected, Message: "Must connect user account to GitHub first.", StatusCode: http.StatusBadRequest}
+		return nil, newAPIError(ErrNotConnected, "")
 	}
 
 	if err := json.Unmarshal(infoBytes, &userInfo); err != nil {
@@ -314,6 +537,10 @@ func (p *Plugin) disconnectGitHubAccount(userID string) {
 		return
 	}
 
+	if err := p.backupUserSettings(userInfo); err != nil {
+		p.API.LogWarn("Failed to back up user settings", "userID", userID, "error", err.Error())
+	}
+
 	if appErr := p.API.KVDelete(userID + githubTokenKey); appErr != nil {
 		p.API.LogWarn("Failed to delete github token from KV store", "userID", userID, "error", appErr.Error())
 	}
@@ -322,6 +549,8 @@ func (p *Plugin) disconnectGitHubAccount(userID string) {
 		p.API.LogWarn("Failed to delete github token from KV store", "userID", userID, "error", appErr.Error())
 	}
 
+	p.clearSidebarCache(userID)
+
 	user, appErr := p.API.GetUser(userID)
 	if appErr != nil {
 		p.API.LogWarn("Failed to get user props", "userID", userID, "error", appErr.Error())
@@ -336,6 +565,8 @@ func (p *Plugin) disconnectGitHubAccount(userID string) {
 		}
 	}
 
+	p.warnSubscriptionCreatorDisconnected(userID)
+
 	p.API.PublishWebSocketEvent(
 		wsEventDisconnect,
 		nil,
@@ -377,43 +608,83 @@ func (p *Plugin) CreateBotDMPost(userID, message, postType string) {
 }
 
 func (p *Plugin) PostToDo(info *GitHubUserInfo) {
-	text, err := p.GetToDo(context.Background(), info.GitHubUsername, p.githubConnect(*info.Token))
-	if err != nil {
+	if err := p.postToDoDigest(context.Background(), info); err != nil {
 		p.API.LogWarn("Failed to get todo text", "userID", info.UserID, "error", err.Error())
 		return
 	}
+}
 
-	p.CreateBotDMPost(info.UserID, text, "custom_git_todo")
+// toDoFetchTimeout bounds each of the GitHub calls of the to do list, so that a slow call only
+// leaves out its own section.
+var toDoFetchTimeout = 10 * time.Second
+
+// toDoData is what the to do list is made of. A section whose call failed has its error set.
+type toDoData struct {
+	reviews            *github.IssuesSearchResult
+	reviewsErr         error
+	notifications      []*github.Notification
+	notificationsErr   error
+	yourPrs            *github.IssuesSearchResult
+	yourPrsErr         error
+	yourAssignments    *github.IssuesSearchResult
+	yourAssignmentsErr error
 }
 
-func (p *Plugin) GetToDo(ctx context.Context, username string, githubClient *github.Client) (string, error) {
-	config := p.getConfiguration()
-	baseURL := p.getBaseURL()
+func (d *toDoData) failed() bool {
+	return d.reviewsErr != nil && d.notificationsErr != nil && d.yourPrsErr != nil && d.yourAssignmentsErr != nil
+}
 
-	issueResults, _, err := githubClient.Search.Issues(ctx, getReviewSearchQuery(username, config.GitHubOrg), &github.SearchOptions{})
-	if err != nil {
-		return "", errors.Wrap(err, "Error occurred while searching for reviews")
-	}
+// runConcurrently runs the fetches at the same time, each bounded by toDoFetchTimeout, and waits
+// for all of them. A failed fetch doesn't cancel the others.
+func runConcurrently(ctx context.Context, fetches ...func(ctx context.Context)) {
+	var wg sync.WaitGroup
+	for _, fetch := range fetches {
+		wg.Add(1)
+		go func(fetch func(ctx context.Context)) {
+			defer wg.Done()
+
+			fetchCtx, cancel := context.WithTimeout(ctx, toDoFetchTimeout)
+			defer cancel()
+			fetch(fetchCtx)
+		}(fetch)
+	}
+	wg.Wait()
+}
 
-	notifications, _, err := githubClient.Activity.ListNotifications(ctx, &github.NotificationListOptions{})
-	if err != nil {
-		return "", errors.Wrap(err, "error occurred while listing notifications")
-	}
+// fetchToDoData gets the review requests, notifications, pull requests and assignments of a user
+// from GitHub concurrently.
+func (p *Plugin) fetchToDoData(ctx context.Context, username string, githubClient *github.Client) *toDoData {
+	org := p.getConfiguration().GitHubOrg
+	data := &toDoData{}
 
-	yourPrs, _, err := githubClient.Search.Issues(ctx, getYourPrsSearchQuery(username, config.GitHubOrg), &github.SearchOptions{})
-	if err != nil {
-		return "", errors.Wrap(err, "error occurred while searching for PRs")
-	}
+	runConcurrently(ctx,
+		func(ctx context.Context) {
+			data.reviews, _, data.reviewsErr = githubClient.Search.Issues(ctx, getReviewSearchQuery(username, org), &github.SearchOptions{})
+		},
+		func(ctx context.Context) {
+			data.notifications, _, data.notificationsErr = githubClient.Activity.ListNotifications(ctx, &github.NotificationListOptions{})
+		},
+		func(ctx context.Context) {
+			data.yourPrs, _, data.yourPrsErr = githubClient.Search.Issues(ctx, getYourPrsSearchQuery(username, org), &github.SearchOptions{})
+		},
+		func(ctx context.Context) {
+			data.yourAssignments, _, data.yourAssignmentsErr = githubClient.Search.Issues(ctx, getYourAssigneeSearchQuery(username, org), &github.SearchOptions{})
+		},
+	)
 
-	yourAssignments, _, err := githubClient.Search.Issues(ctx, getYourAssigneeSearchQuery(username, config.GitHubOrg), &github.SearchOptions{})
-	if err != nil {
-		return "", errors.Wrap(err, "error occurred while searching for assignments")
+	for section, err := range map[string]error{"reviews": data.reviewsErr, "notifications": data.notificationsErr, "yourprs": data.yourPrsErr, "assignments": data.yourAssignmentsErr} {
+		if err != nil {
+			p.API.LogWarn("Failed to fetch to do section", "username", username, "section", section, "error", err.Error())
+		}
 	}
 
-	text := "##### Unread Messages\n"
+	return data
+}
 
-	notificationCount := 0
-	notificationContent := ""
+// relevantToDoNotifications returns the notifications listed in the to do list, leaving out those
+// of subscribed repositories and of other organizations.
+func (p *Plugin) relevantToDoNotifications(notifications []*github.Notification) []*github.Notification {
+	relevant := []*github.Notification{}
 	for _, n := range notifications {
 		if n.GetReason() == notificationReasonSubscribed {
 			continue
@@ -428,51 +699,96 @@ func (p *Plugin) GetToDo(ctx context.Context, username string, githubClient *git
 			continue
 		}
 
+		relevant = append(relevant, n)
+	}
+
+	return relevant
+}
+
+// getToDoNotificationURL returns the URL of the subject of a notification, at its latest comment if any.
+func getToDoNotificationURL(baseURL string, n *github.Notification) string {
+	issueURL := n.GetSubject().GetURL()
+	issueNumIndex := strings.LastIndex(issueURL, "/")
+	issueNum := issueURL[issueNumIndex+1:]
+	subjectURL := n.GetSubject().GetURL()
+	if n.GetSubject().GetLatestCommentURL() != "" {
+		subjectURL = n.GetSubject().GetLatestCommentURL()
+	}
+
+	return fixGithubNotificationSubjectURL(baseURL, subjectURL, issueNum)
+}
+
+// GetToDo renders the to do list of a user. Sections whose call to GitHub failed are marked as
+// such, and an error is only returned if all of them failed.
+func (p *Plugin) GetToDo(ctx context.Context, username string, githubClient *github.Client) (string, error) {
+	data := p.fetchToDoData(ctx, username, githubClient)
+	if data.failed() {
+		return "", errors.Wrap(data.reviewsErr, "error occurred while fetching the to do list")
+	}
+
+	return p.renderToDo(data, 0), nil
+}
+
+// renderToDo renders the to do list as text. The first actionItems items of the unread messages,
+// review requests and assignments are left out, as they are attached to the post with quick actions.
+func (p *Plugin) renderToDo(data *toDoData, actionItems int) string {
+	baseURL := p.getBaseURL()
+	issueResults, yourPrs, yourAssignments := data.reviews, data.yourPrs, data.yourAssignments
+
+	text := "##### Unread Messages\n"
+
+	notificationCount := 0
+	notificationContent := ""
+	for i, n := range p.relevantToDoNotifications(data.notifications) {
+		notificationCount++
+		if i < actionItems {
+			continue
+		}
+
 		notificationSubject := n.GetSubject()
 		notificationType := notificationSubject.GetType()
 		switch notificationType {
 		case "RepositoryVulnerabilityAlert":
-			message := fmt.Sprintf("[Vulnerability Alert for %v](%v)", n.GetRepository().GetFullName(), fixGithubNotificationSubjectURL(n.GetSubject().GetURL(), ""))
+			message := fmt.Sprintf("[Vulnerability Alert for %v](%v)", n.GetRepository().GetFullName(), fixGithubNotificationSubjectURL(baseURL, n.GetSubject().GetURL(), ""))
 			notificationContent += fmt.Sprintf("* %v\n", message)
 		default:
-			issueURL := n.GetSubject().GetURL()
-			issueNumIndex := strings.LastIndex(issueURL, "/")
-			issueNum := issueURL[issueNumIndex+1:]
-			subjectURL := n.GetSubject().GetURL()
-			if n.GetSubject().GetLatestCommentURL() != "" {
-				subjectURL = n.GetSubject().GetLatestCommentURL()
-			}
-
-			notificationTitle := notificationSubject.GetTitle()
-			notificationURL := fixGithubNotificationSubjectURL(subjectURL, issueNum)
-			notificationContent += getToDoDisplayText(baseURL, notificationTitle, notificationURL, notificationType)
+			notificationContent += getToDoDisplayText(baseURL, notificationSubject.GetTitle(), getToDoNotificationURL(baseURL, n), notificationType)
 		}
-
-		notificationCount++
 	}
 
-	if notificationCount == 0 {
+	if data.notificationsErr != nil {
+		text += "⚠️ couldn't load unread messages\n"
+	} else if notificationCount == 0 {
 		text += "You don't have any unread messages.\n"
 	} else {
 		text += fmt.Sprintf("You have %v unread messages:\n", notificationCount)
+		text += toDoActionItemsNote(notificationCount, actionItems)
 		text += notificationContent
 	}
 
 	text += "##### Review Requests\n"
 
-	if issueResults.GetTotal() == 0 {
+	if data.reviewsErr != nil {
+		text += "⚠️ couldn't load review requests\n"
+	} else if issueResults.GetTotal() == 0 {
 		text += "You don't have any pull requests awaiting your review.\n"
 	} else {
 		text += fmt.Sprintf("You have %v pull requests awaiting your review:\n", issueResults.GetTotal())
+		text += toDoActionItemsNote(len(issueResults.Issues), actionItems)
 
-		for _, pr := range issueResults.Issues {
+		for i, pr := range issueResults.Issues {
+			if i < actionItems {
+				continue
+			}
 			text += getToDoDisplayText(baseURL, pr.GetTitle(), pr.GetHTMLURL(), "")
 		}
 	}
 
 	text += "##### Your Open Pull Requests\n"
 
-	if yourPrs.GetTotal() == 0 {
+	if data.yourPrsErr != nil {
+		text += "⚠️ couldn't load your open pull requests\n"
+	} else if yourPrs.GetTotal() == 0 {
 		text += "You don't have any open pull requests.\n"
 	} else {
 		text += fmt.Sprintf("You have %v open pull requests:\n", yourPrs.GetTotal())
@@ -484,72 +800,45 @@ func (p *Plugin) GetToDo(ctx context.Context, username string, githubClient *git
 
 	text += "##### Your Assignments\n"
 
-	if yourAssignments.GetTotal() == 0 {
+	if data.yourAssignmentsErr != nil {
+		text += "⚠️ couldn't load your assignments\n"
+	} else if yourAssignments.GetTotal() == 0 {
 		text += "You don't have any assignments.\n"
 	} else {
 		text += fmt.Sprintf("You have %v assignments:\n", yourAssignments.GetTotal())
+		text += toDoActionItemsNote(len(yourAssignments.Issues), actionItems)
 
-		for _, assign := range yourAssignments.Issues {
+		for i, assign := range yourAssignments.Issues {
+			if i < actionItems {
+				continue
+			}
 			text += getToDoDisplayText(baseURL, assign.GetTitle(), assign.GetHTMLURL(), "")
 		}
 	}
 
-	return text, nil
+	return text
 }
 
-func (p *Plugin) HasUnreads(info *GitHubUserInfo) bool {
-	username := info.GitHubUsername
-	ctx := context.Background()
-	githubClient := p.githubConnect(*info.Token)
-	config := p.getConfiguration()
-
-	query := getReviewSearchQuery(username, config.GitHubOrg)
-	issues, _, err := githubClient.Search.Issues(ctx, query, &github.SearchOptions{})
-	if err != nil {
-		p.API.LogWarn("Failed to search for review", "query", query, "error", err.Error())
-		return false
-	}
-
-	query = getYourPrsSearchQuery(username, config.GitHubOrg)
-	yourPrs, _, err := githubClient.Search.Issues(ctx, query, &github.SearchOptions{})
-	if err != nil {
-		p.API.LogWarn("Failed to search for PRs", "query", query, "error", "error", err.Error())
-		return false
-	}
-
-	query = getYourAssigneeSearchQuery(username, config.GitHubOrg)
-	yourAssignments, _, err := githubClient.Search.Issues(ctx, query, &github.SearchOptions{})
-	if err != nil {
-		p.API.LogWarn("Failed to search for assignments", "query", query, "error", "error", err.Error())
-		return false
+// toDoActionItemsNote points to the items of a section of the to do list attached with quick actions.
+func toDoActionItemsNote(count, actionItems int) string {
+	if actionItems == 0 || count == 0 {
+		return ""
 	}
-
-	relevantNotifications := false
-	notifications, _, err := githubClient.Activity.ListNotifications(ctx, &github.NotificationListOptions{})
-	if err != nil {
-		p.API.LogWarn("Failed to list notifications", "error", err.Error())
-		return false
+	if count <= actionItems {
+		return "* _See below._\n"
 	}
 
-	for _, n := range notifications {
-		if n.GetReason() == notificationReasonSubscribed {
-			continue
-		}
-
-		if n.GetRepository() == nil {
-			p.API.LogError("Unable to get repository for notification in todo list. Skipping.")
-			continue
-		}
+	return fmt.Sprintf("* _The first %d are below._\n", actionItems)
+}
 
-		if p.checkOrg(n.GetRepository().GetOwner().GetLogin()) != nil {
-			continue
-		}
+// HasUnreads tells if the to do list of a user has anything in it. Sections whose call to GitHub
+// failed count as empty.
+func (p *Plugin) HasUnreads(ctx context.Context, info *GitHubUserInfo) bool {
+	data := p.fetchToDoData(ctx, info.GitHubUsername, p.githubConnect(*info.Token))
 
-		relevantNotifications = true
-		break
-	}
+	relevantNotifications := len(p.relevantToDoNotifications(data.notifications)) > 0
 
-	if issues.GetTotal() == 0 && !relevantNotifications && yourPrs.GetTotal() == 0 && yourAssignments.GetTotal() == 0 {
+	if data.reviews.GetTotal() == 0 && !relevantNotifications && data.yourPrs.GetTotal() == 0 && data.yourAssignments.GetTotal() == 0 {
 		return false
 	}
 
@@ -567,18 +856,27 @@ func (p *Plugin) checkOrg(org string) error {
 	return nil
 }
 
-func (p *Plugin) isUserOrganizationMember(githubClient *github.Client, user *github.User, organization string) bool {
-	if organization == "" {
-		return false
+// findOAuthRestrictedOrgs returns the organizations that deny the plugin access to their data
+// because they have OAuth App access restrictions enabled.
+func findOAuthRestrictedOrgs(ctx context.Context, githubClient *github.Client, orgs []string) []string {
+	restrictedOrgs := []string{}
+	for _, org := range orgs {
+		_, _, err := githubClient.Organizations.GetOrgMembership(ctx, "", org)
+		if isOAuthAppRestrictionError(err) {
+			restrictedOrgs = append(restrictedOrgs, org)
+		}
 	}
 
-	isMember, _, err := githubClient.Organizations.IsMember(context.Background(), organization, *user.Login)
-	if err != nil {
-		p.API.LogWarn("Failled to check if user is org member", "GitHub username", *user.Login, "error", err.Error())
+	return restrictedOrgs
+}
+
+func isOAuthAppRestrictionError(err error) bool {
+	var gerr *github.ErrorResponse
+	if !errors.As(err, &gerr) || gerr.Response == nil || gerr.Response.StatusCode != http.StatusForbidden {
 		return false
 	}
 
-	return isMember
+	return strings.Contains(gerr.Message, oauthAppRestrictionsMessage)
 }
 
 func (p *Plugin) isOrganizationLocked() bool {
@@ -588,6 +886,12 @@ func (p *Plugin) isOrganizationLocked() bool {
 	return configOrg != ""
 }
 
+func (p *Plugin) isSystemAdmin(userID string) bool {
+	return p.API.HasPermissionTo(userID, model.PERMISSION_MANAGE_SYSTEM)
+}
+
+// sendRefreshEvent tells the webapp of a user to refresh the sidebar. The webapp fetches the
+// sections and saved searches it shows itself, so that no search is run here for every event.
 func (p *Plugin) sendRefreshEvent(userID string) {
 	p.API.PublishWebSocketEvent(
 		wsEventRefresh,
@@ -596,13 +900,9 @@ func (p *Plugin) sendRefreshEvent(userID string) {
 	)
 }
 
+// getBaseURL returns the base URL of the web interface of GitHub, ending with a slash.
 func (p *Plugin) getBaseURL() string {
-	config := p.getConfiguration()
-	if config.EnterpriseBaseURL != "" {
-		return config.EnterpriseBaseURL
-	}
-
-	return "https://github.com/"
+	return getWebBaseURL(p.getConfiguration().EnterpriseBaseURL)
 }
 
 // getUsername returns the GitHub username for a given Mattermost user,
@@ -0,0 +1,231 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// newTestGitHubClient returns a GitHub client sending its requests to the given handler.
+func newTestGitHubClient(t *testing.T, handler http.Handler) *github.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	return client
+}
+
+func TestFindOAuthRestrictedOrgs(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user/memberships/orgs/restricted", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "Although you appear to have the correct authorization credentials, the `+
+			"`restricted`"+` organization has enabled OAuth App access restrictions, meaning that data access to third-parties is limited.",
+			"documentation_url": "https://docs.github.com/articles/restricting-access-to-your-organization-s-data/"}`)
+	})
+	mux.HandleFunc("/user/memberships/orgs/forbidden", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "Resource not accessible by integration"}`)
+	})
+	mux.HandleFunc("/user/memberships/orgs/member", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"state": "active", "role": "member"}`)
+	})
+	mux.HandleFunc("/user/memberships/orgs/other", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	})
+
+	client := newTestGitHubClient(t, mux)
+
+	for name, test := range map[string]struct {
+		orgs     []string
+		expected []string
+	}{
+		"no orgs":                       {orgs: nil, expected: []string{}},
+		"member of org":                 {orgs: []string{"member"}, expected: []string{}},
+		"not a member of org":           {orgs: []string{"other"}, expected: []string{}},
+		"forbidden for another reason":  {orgs: []string{"forbidden"}, expected: []string{}},
+		"org with restrictions":         {orgs: []string{"restricted"}, expected: []string{"restricted"}},
+		"org with restrictions and not": {orgs: []string{"member", "restricted", "other"}, expected: []string{"restricted"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, findOAuthRestrictedOrgs(context.Background(), client, test.orgs))
+		})
+	}
+}
+
+func TestOrganizationWarning(t *testing.T) {
+	for org, warns := range map[string]bool{
+		"":                    false,
+		" mattermost ":        false,
+		"mattermost-plugins":  false,
+		"mattermost,github":   true,
+		"mattermost, github":  true,
+		"mattermost github":   true,
+		"mattermost;github":   true,
+		"  mattermost,github": true,
+	} {
+		warning := (&Configuration{GitHubOrg: org}).organizationWarning()
+		assert.Equal(t, warns, warning != "", org)
+	}
+
+	assert.Equal(t, `The GitHub Organization "a,b" lists several organizations, but the plugin can only be locked to a single one. Set one organization, or none to allow all of them.`, (&Configuration{GitHubOrg: "a,b "}).organizationWarning())
+}
+
+func TestUnknownDisabledCommands(t *testing.T) {
+	p := NewPlugin()
+
+	assert.Empty(t, (&Configuration{DisabledCommands: "issue, Reviewers"}).unknownDisabledCommands(p.CommandHandlers))
+	assert.Equal(t, []string{"isue", "merge"}, (&Configuration{DisabledCommands: "isue,todo, merge"}).unknownDisabledCommands(p.CommandHandlers))
+
+	// Unknown commands are ignored, leaving the others disabled
+	config := &Configuration{DisabledCommands: "isue,todo"}
+	assert.True(t, config.isCommandDisabled("todo"))
+	assert.False(t, config.isCommandDisabled("issue"))
+}
+
+func TestOnConfigurationChangeConfigUpdate(t *testing.T) {
+	p := NewPlugin()
+	org := "mattermost"
+	api := &plugintest.API{}
+	api.On("LoadPluginConfiguration", mock.AnythingOfType("*plugin.Configuration")).Return(func(dest interface{}) error {
+		dest.(*Configuration).GitHubOrg = org
+		return nil
+	})
+	api.On("GetBundlePath").Return("../..", nil)
+	api.On("RegisterCommand", mock.AnythingOfType("*model.Command")).Return(nil)
+	api.On("PublishWebSocketEvent", wsEventConfigUpdate, mock.Anything, &model.WebsocketBroadcast{}).Return()
+	p.SetAPI(api)
+
+	// Activating doesn't broadcast, webapps get the configuration when connecting
+	require.NoError(t, p.OnConfigurationChange())
+	api.AssertNotCalled(t, "PublishWebSocketEvent", wsEventConfigUpdate, mock.Anything, mock.Anything)
+
+	require.NoError(t, p.OnConfigurationChange())
+	api.AssertNotCalled(t, "PublishWebSocketEvent", wsEventConfigUpdate, mock.Anything, mock.Anything)
+
+	org = "mattermost-plugins"
+	require.NoError(t, p.OnConfigurationChange())
+	api.AssertCalled(t, "PublishWebSocketEvent", wsEventConfigUpdate, mock.MatchedBy(func(data map[string]interface{}) bool {
+		return data["organization"] == "mattermost-plugins"
+	}), &model.WebsocketBroadcast{})
+}
+
+func TestGetToDo(t *testing.T) {
+	const delay = 200 * time.Millisecond
+
+	// Each search is slow, and the notifications fail
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		query := r.URL.Query().Get("q")
+		switch {
+		case strings.Contains(query, "review-requested:"):
+			fmt.Fprint(w, `{"total_count": 1, "items": [{"title": "Review me", "html_url": "https://github.com/owner/repo/pull/1"}]}`)
+		case strings.Contains(query, "author:"):
+			fmt.Fprint(w, `{"total_count": 0, "items": []}`)
+		default:
+			time.Sleep(time.Second)
+			fmt.Fprint(w, `{"total_count": 0, "items": []}`)
+		}
+	})
+	mux.HandleFunc("/notifications", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client := newTestGitHubClient(t, mux)
+
+	setup := func() *Plugin {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{})
+		api := &plugintest.API{}
+		api.On("LogWarn", "Failed to fetch to do section", "username", "alice", "section", mock.AnythingOfType("string"), "error", mock.AnythingOfType("string"))
+		p.SetAPI(api)
+		return p
+	}
+
+	defaultTimeout := toDoFetchTimeout
+	toDoFetchTimeout = delay + 300*time.Millisecond
+	defer func() { toDoFetchTimeout = defaultTimeout }()
+
+	t.Run("partial rendering", func(t *testing.T) {
+		start := time.Now()
+		text, err := setup().GetToDo(context.Background(), "alice", client)
+		require.NoError(t, err)
+
+		// The sequential calls would take at least three delays, the timed out one more
+		assert.Less(t, int64(time.Since(start)), int64(toDoFetchTimeout+delay))
+		assert.Contains(t, text, "##### Unread Messages\n⚠️ couldn't load unread messages\n")
+		assert.Contains(t, text, "You have 1 pull requests awaiting your review:\n")
+		assert.Contains(t, text, "[Review me](https://github.com/owner/repo/pull/1)")
+		assert.Contains(t, text, "You don't have any open pull requests.\n")
+		assert.Contains(t, text, "##### Your Assignments\n⚠️ couldn't load your assignments\n")
+	})
+
+	t.Run("all sections failed", func(t *testing.T) {
+		failing := newTestGitHubClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+
+		_, err := setup().GetToDo(context.Background(), "alice", failing)
+		assert.Error(t, err)
+	})
+
+	t.Run("canceled by the caller", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), delay/2)
+		defer cancel()
+
+		start := time.Now()
+		_, err := setup().GetToDo(ctx, "alice", client)
+		assert.Error(t, err)
+		assert.Less(t, int64(time.Since(start)), int64(delay))
+	})
+}
+
+func TestHasUnreads(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info := &GitHubUserInfo{UserID: "userID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: token}}
+
+	reviews := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v3/notifications":
+			w.WriteHeader(http.StatusBadGateway)
+		case strings.Contains(r.URL.Query().Get("q"), "review-requested:"):
+			fmt.Fprintf(w, `{"total_count": %d}`, reviews)
+		default:
+			fmt.Fprint(w, `{"total_count": 0}`)
+		}
+	}))
+	defer ts.Close()
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+	api := &plugintest.API{}
+	api.On("LogWarn", "Failed to fetch to do section", "username", "alice", "section", "notifications", "error", mock.AnythingOfType("string"))
+	p.SetAPI(api)
+
+	assert.False(t, p.HasUnreads(context.Background(), info))
+
+	// The failed notifications don't hide the review requests
+	reviews = 2
+	assert.True(t, p.HasUnreads(context.Background(), info))
+}
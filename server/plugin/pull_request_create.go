@@ -0,0 +1,258 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+const (
+	pullRequestTitleFlag = "title"
+	pullRequestBodyFlag  = "body"
+	pullRequestDraftFlag = "draft"
+)
+
+// pullRequestCreate is a request to create a pull request, from the API or the pr command.
+type pullRequestCreate struct {
+	Repo  string `json:"repo"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Draft bool   `json:"draft"`
+	// PostID is the post the pull request is created from, if any. The confirmation is posted in its
+	// thread, or in the channel of ChannelID otherwise.
+	PostID    string `json:"post_id"`
+	ChannelID string `json:"channel_id"`
+}
+
+// pullRequestCreateErrorMessage describes an error of GitHub creating a pull request.
+func pullRequestCreateErrorMessage(err error, req *pullRequestCreate) string {
+	errResp, ok := err.(*github.ErrorResponse)
+	if !ok || errResp.Response == nil {
+		return "Encountered an error creating the pull request."
+	}
+
+	switch errResp.Response.StatusCode {
+	case http.StatusUnprocessableEntity:
+		for _, e := range errResp.Errors {
+			switch {
+			case strings.HasPrefix(e.Message, "No commits between"):
+				return fmt.Sprintf("There are no commits between `%s` and `%s` to open a pull request for.", req.Base, req.Head)
+			case strings.HasPrefix(e.Message, "A pull request already exists"):
+				return fmt.Sprintf("A pull request already exists for `%s`.", req.Head)
+			}
+		}
+		return fmt.Sprintf("GitHub couldn't create the pull request: %s", errResp.Message)
+	case http.StatusNotFound, http.StatusForbidden:
+		return fmt.Sprintf("You don't have permission to create pull requests in %s.", req.Repo)
+	}
+
+	return "Encountered an error creating the pull request."
+}
+
+// createPullRequest creates a pull request of a head branch, and posts a confirmation with its link.
+// The head must have commits ahead of the base, which defaults to the default branch.
+func (p *Plugin) createPullRequest(ctx context.Context, userID string, userInfo *GitHubUserInfo, req *pullRequestCreate) (*github.PullRequest, *APIErrorResponse) {
+	owner, repo := parseOwnerAndRepo(req.Repo, p.getBaseURL())
+	if owner == "" || repo == "" {
+		return nil, &APIErrorResponse{Message: "Please provide a valid repo name.", StatusCode: http.StatusBadRequest}
+	}
+	req.Repo = fullNameFromOwnerAndRepo(owner, repo)
+
+	if req.Head == "" {
+		return nil, &APIErrorResponse{Message: "Please provide the branch to create a pull request from.", StatusCode: http.StatusBadRequest}
+	}
+
+	if req.PostID == "" && req.ChannelID == "" {
+		return nil, &APIErrorResponse{Message: "Please provide either a postID or a channelID", StatusCode: http.StatusBadRequest}
+	}
+
+	if err := p.checkOrg(owner); err != nil {
+		return nil, &APIErrorResponse{Message: err.Error(), StatusCode: http.StatusForbidden}
+	}
+
+	var post *model.Post
+	permalink := ""
+	if req.PostID != "" {
+		var appErr *model.AppError
+		post, appErr = p.API.GetPost(req.PostID)
+		if appErr != nil || post == nil {
+			return nil, &APIErrorResponse{Message: "failed to load post " + req.PostID, StatusCode: http.StatusNotFound}
+		}
+
+		username, err := p.getUsername(post.UserId)
+		if err != nil {
+			return nil, &APIErrorResponse{Message: "failed to get username", StatusCode: http.StatusInternalServerError}
+		}
+
+		permalink = p.getPermaLink(req.PostID)
+		footer := fmt.Sprintf("_Pull request created from a [Mattermost message](%v) *by %s*._", permalink, username)
+		if req.Body != "" {
+			footer = "\n\n" + footer
+		}
+		req.Body += footer
+	}
+
+	githubClient, err := p.getGithubClientFor(userInfo, githubWrite)
+	if err != nil {
+		return nil, newAPIError(err, "Failed to create the pull request.")
+	}
+
+	ghRepo, _, err := githubClient.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		p.API.LogDebug("Failed to get repository", "repo", req.Repo, "error", err.Error())
+		return nil, &APIErrorResponse{Message: fmt.Sprintf("Couldn't find the repository %s. Please check that you have access to it.", req.Repo), StatusCode: http.StatusNotFound}
+	}
+	if req.Base == "" {
+		req.Base = ghRepo.GetDefaultBranch()
+	}
+
+	// Branches of forks are given as user:branch, and are left for GitHub to check
+	if !strings.Contains(req.Head, ":") {
+		if _, _, err = githubClient.Repositories.GetBranch(ctx, owner, repo, req.Head); err != nil {
+			p.API.LogDebug("Failed to get head branch", "repo", req.Repo, "branch", req.Head, "error", err.Error())
+			return nil, &APIErrorResponse{Message: fmt.Sprintf("Couldn't find the branch `%s` in %s.", req.Head, req.Repo), StatusCode: http.StatusNotFound}
+		}
+
+		comparison, _, err := githubClient.Repositories.CompareCommits(ctx, owner, repo, req.Base, req.Head)
+		if err != nil {
+			p.API.LogDebug("Failed to compare branches", "repo", req.Repo, "base", req.Base, "head", req.Head, "error", err.Error())
+			return nil, &APIErrorResponse{Message: fmt.Sprintf("Couldn't compare `%s` with `%s` in %s.", req.Head, req.Base, req.Repo), StatusCode: http.StatusBadRequest}
+		}
+		if comparison.GetAheadBy() == 0 {
+			return nil, &APIErrorResponse{Message: fmt.Sprintf("There are no commits between `%s` and `%s` to open a pull request for.", req.Base, req.Head), StatusCode: http.StatusUnprocessableEntity}
+		}
+	}
+
+	if req.Title == "" {
+		req.Title = req.Head
+	}
+
+	pr, _, err := githubClient.PullRequests.Create(ctx, owner, repo, &github.NewPullRequest{
+		Title: github.String(req.Title),
+		Head:  github.String(req.Head),
+		Base:  github.String(req.Base),
+		Body:  github.String(req.Body),
+		Draft: github.Bool(req.Draft),
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to create pull request", "repo", req.Repo, "head", req.Head, "error", err.Error())
+		statusCode := http.StatusInternalServerError
+		if errResp, ok := err.(*github.ErrorResponse); ok && errResp.Response != nil {
+			statusCode = errResp.Response.StatusCode
+		}
+		return nil, &APIErrorResponse{Message: pullRequestCreateErrorMessage(err, req), StatusCode: statusCode}
+	}
+
+	rootID := ""
+	channelID := req.ChannelID
+	kind := "pull request"
+	if req.Draft {
+		kind = "draft pull request"
+	}
+	message := fmt.Sprintf("Created %s [%s#%d](%s) from `%s` into `%s`", kind, req.Repo, pr.GetNumber(), pr.GetHTMLURL(), req.Head, req.Base)
+	if post != nil {
+		rootID = post.Id
+		if post.RootId != "" {
+			rootID = post.RootId
+		}
+		channelID = post.ChannelId
+		message += fmt.Sprintf(" from a [message](%s)", permalink)
+	}
+
+	reply := &model.Post{
+		Message:   message,
+		ChannelId: channelID,
+		RootId:    rootID,
+		ParentId:  rootID,
+		UserId:    userID,
+	}
+	if _, appErr := p.API.CreatePost(reply); appErr != nil {
+		p.API.LogWarn("Failed to post pull request confirmation", "channelID", channelID, "error", appErr.Error())
+	} else {
+		p.trackSelfEvent(selfEventPullRequestOpened, pr.GetHTMLURL(), userInfo.GitHubUsername, channelID)
+	}
+
+	// The new pull request shows in the sidebar of its author
+	p.sendRefreshEvent(userID)
+
+	return pr, nil
+}
+
+func (p *Plugin) createPR(w http.ResponseWriter, r *http.Request, userID string) {
+	req := &pullRequestCreate{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.API.LogWarn("Error decoding JSON body", "error", err.Error())
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a JSON object.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	pr, apiErr := p.createPullRequest(r.Context(), userID, info, req)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	p.writeJSON(w, pr)
+}
+
+func (p *Plugin) handlePR(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	usage := "Please use `/github pr create owner/repo head-branch [base-branch] [--title \"title\"] [--body \"body\"] [--draft]`."
+	if len(parameters) == 0 || parameters[0] != "create" {
+		return usage
+	}
+
+	req := &pullRequestCreate{ChannelID: args.ChannelId}
+	positional := []string{}
+	for i := 1; i < len(parameters); i++ {
+		if !isFlag(parameters[i]) {
+			positional = append(positional, parameters[i])
+			continue
+		}
+
+		switch flag := parseFlag(parameters[i]); flag {
+		case pullRequestDraftFlag:
+			req.Draft = true
+		case pullRequestTitleFlag, pullRequestBodyFlag:
+			if i+1 >= len(parameters) {
+				return fmt.Sprintf("The flag --%s requires a value.", flag)
+			}
+			i++
+			value := strings.Trim(parameters[i], `"`)
+			if flag == pullRequestTitleFlag {
+				req.Title = value
+			} else {
+				req.Body = value
+			}
+		default:
+			return fmt.Sprintf("Unknown flag --%s. %s", flag, usage)
+		}
+	}
+
+	if len(positional) < 2 || len(positional) > 3 {
+		return usage
+	}
+	req.Repo, req.Head = positional[0], positional[1]
+	if len(positional) == 3 {
+		req.Base = positional[2]
+	}
+
+	if _, apiErr := p.createPullRequest(context.Background(), args.UserId, userInfo, req); apiErr != nil {
+		return apiErr.Message
+	}
+
+	return ""
+}
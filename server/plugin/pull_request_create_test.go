@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestCreatePullRequest(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	userInfo := &GitHubUserInfo{UserID: "userID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: token}}
+
+	var created map[string]interface{}
+	createStatus := http.StatusCreated
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo":
+			fmt.Fprint(w, `{"full_name": "owner/repo", "default_branch": "main"}`)
+		case "/api/v3/repos/owner/repo/branches/feature", "/api/v3/repos/owner/repo/branches/merged":
+			fmt.Fprint(w, `{"name": "feature"}`)
+		case "/api/v3/repos/owner/repo/compare/main...feature", "/api/v3/repos/owner/repo/compare/release...feature":
+			fmt.Fprint(w, `{"ahead_by": 2}`)
+		case "/api/v3/repos/owner/repo/compare/main...merged":
+			fmt.Fprint(w, `{"ahead_by": 0}`)
+		case "/api/v3/repos/owner/repo/pulls":
+			body, _ := ioutil.ReadAll(r.Body)
+			require.NoError(t, json.Unmarshal(body, &created))
+			w.WriteHeader(createStatus)
+			if createStatus != http.StatusCreated {
+				fmt.Fprint(w, `{"message": "Validation Failed", "errors": [{"resource": "PullRequest", "code": "custom", "message": "A pull request already exists for owner:feature."}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"number": 7, "html_url": "https://github.com/owner/repo/pull/7"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func() (*Plugin, *plugintest.API, *[]*model.Post) {
+		created = nil
+		createStatus = http.StatusCreated
+
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		posts := &[]*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			*posts = append(*posts, post)
+			return post
+		}, nil)
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), []byte("1"), int64(selfEventTTL)).Return(nil).Maybe()
+		api.On("PublishWebSocketEvent", wsEventRefresh, mock.Anything, &model.WebsocketBroadcast{UserId: "userID"}).Return()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		return p, api, posts
+	}
+	args := &model.CommandArgs{UserId: "userID", ChannelId: "channelID"}
+
+	t.Run("command", func(t *testing.T) {
+		p, api, posts := setup()
+
+		message := p.handlePR(nil, args, []string{"create", "owner/repo", "feature", `--title`, `"Add feature"`, "--draft"}, userInfo)
+		assert.Empty(t, message)
+		assert.Equal(t, map[string]interface{}{"title": "Add feature", "head": "feature", "base": "main", "body": "", "draft": true}, created)
+		require.Len(t, *posts, 1)
+		assert.Equal(t, "channelID", (*posts)[0].ChannelId)
+		assert.Equal(t, "Created draft pull request [owner/repo#7](https://github.com/owner/repo/pull/7) from `feature` into `main`", (*posts)[0].Message)
+		api.AssertCalled(t, "PublishWebSocketEvent", wsEventRefresh, mock.Anything, &model.WebsocketBroadcast{UserId: "userID"})
+	})
+
+	t.Run("base and default title", func(t *testing.T) {
+		p, _, _ := setup()
+
+		assert.Empty(t, p.handlePR(nil, args, []string{"create", "owner/repo", "feature", "release"}, userInfo))
+		assert.Equal(t, "release", created["base"])
+		assert.Equal(t, "feature", created["title"])
+	})
+
+	t.Run("no commits ahead", func(t *testing.T) {
+		p, _, posts := setup()
+
+		assert.Equal(t, "There are no commits between `main` and `merged` to open a pull request for.", p.handlePR(nil, args, []string{"create", "owner/repo", "merged"}, userInfo))
+		assert.Nil(t, created)
+		assert.Empty(t, *posts)
+	})
+
+	t.Run("unknown branch", func(t *testing.T) {
+		p, _, _ := setup()
+
+		assert.Equal(t, "Couldn't find the branch `typo` in owner/repo.", p.handlePR(nil, args, []string{"create", "owner/repo", "typo"}, userInfo))
+	})
+
+	t.Run("already exists", func(t *testing.T) {
+		p, _, _ := setup()
+		createStatus = http.StatusUnprocessableEntity
+
+		assert.Equal(t, "A pull request already exists for `feature`.", p.handlePR(nil, args, []string{"create", "owner/repo", "feature"}, userInfo))
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		p, _, _ := setup()
+
+		assert.Contains(t, p.handlePR(nil, args, []string{"create", "owner/repo"}, userInfo), "Please use `/github pr create")
+		assert.Contains(t, p.handlePR(nil, args, []string{"create", "owner/repo", "feature", "--reviewer", "bob"}, userInfo), "Unknown flag --reviewer.")
+	})
+
+	t.Run("API from a post", func(t *testing.T) {
+		p, api, posts := setup()
+		info, err := json.Marshal(userInfo)
+		require.NoError(t, err)
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		api.On("GetPost", "postID").Return(&model.Post{Id: "postID", RootId: "rootID", ChannelId: "postChannelID", UserId: "authorID"}, nil)
+		api.On("KVGet", "authorID"+githubTokenKey).Return(nil, nil)
+		api.On("GetUser", "authorID").Return(&model.User{Username: "bob"}, nil)
+		api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: model.NewString("http://mattermost.example.com")}})
+
+		body, err := json.Marshal(&pullRequestCreate{Repo: "owner/repo", Head: "feature", Title: "Add feature", Body: "Details", PostID: "postID"})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		p.createPR(w, httptest.NewRequest(http.MethodPost, "/api/v1/createpr", bytes.NewReader(body)), "userID")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, created["body"], "Details\n\n_Pull request created from a [Mattermost message](")
+		assert.Contains(t, created["body"], "*by `@bob`*._")
+		require.Len(t, *posts, 1)
+		assert.Equal(t, "postChannelID", (*posts)[0].ChannelId)
+		assert.Equal(t, "rootID", (*posts)[0].RootId)
+	})
+}
@@ -0,0 +1,240 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-github/server/plugin/graphql"
+)
+
+const (
+	// maxPullRequestStatuses caps the number of pull requests of a single status request.
+	maxPullRequestStatuses = 50
+	// pullRequestStatusWorkers is the number of pull requests fetched concurrently with the REST API.
+	pullRequestStatusWorkers = 5
+
+	// gitHubAPICostHeader tells what a response cost against the GitHub rate limit of the user: the
+	// points of the GraphQL queries, or the number of REST requests when GraphQL is unavailable.
+	gitHubAPICostHeader = "X-GitHub-API-Cost"
+)
+
+// PullRequestStatusRequest identifies a pull request whose status is requested.
+type PullRequestStatusRequest struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+}
+
+// PullRequestStatus is the CI and review state of a pull request. Error is set instead of the
+// state when the pull request couldn't be fetched.
+type PullRequestStatus struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+	// State is open, closed or merged.
+	State string `json:"state,omitempty"`
+	// Mergeable is mergeable, conflicting or unknown while GitHub computes it.
+	Mergeable string `json:"mergeable,omitempty"`
+	// ReviewDecision is approved, changes_requested, review_required, or empty when no review is
+	// required. It's only known when GraphQL is available.
+	ReviewDecision string `json:"review_decision,omitempty"`
+	// Checks is the combined state of the checks of the head commit, i.e. success, failure, pending
+	// or error, or empty if it has none. Only commit statuses are combined when GraphQL is unavailable.
+	Checks string            `json:"checks,omitempty"`
+	Labels []string          `json:"labels,omitempty"`
+	Error  *APIErrorResponse `json:"error,omitempty"`
+}
+
+func (s *PullRequestStatus) fullName() string {
+	return fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(s.Owner, s.Repo), s.Number)
+}
+
+func (s *PullRequestStatus) setFromGraphQL(details *graphql.PullRequestDetails) {
+	s.State = strings.ToLower(details.State)
+	s.Mergeable = strings.ToLower(details.Mergeable)
+	s.ReviewDecision = strings.ToLower(details.ReviewDecision)
+	s.Checks = strings.ToLower(details.CheckState)
+	if s.Checks == "expected" {
+		s.Checks = "pending"
+	}
+
+	for _, label := range details.Labels {
+		s.Labels = append(s.Labels, label.Name)
+	}
+}
+
+func (s *PullRequestStatus) setFromREST(pr *github.PullRequest, combined *github.CombinedStatus) {
+	s.State = pr.GetState()
+	if pr.GetMerged() {
+		s.State = "merged"
+	}
+
+	switch {
+	case pr.Mergeable == nil:
+		s.Mergeable = "unknown"
+	case pr.GetMergeable():
+		s.Mergeable = "mergeable"
+	default:
+		s.Mergeable = "conflicting"
+	}
+
+	// The combined state of a commit without statuses is pending
+	if combined.GetTotalCount() > 0 {
+		s.Checks = combined.GetState()
+	}
+
+	for _, label := range capLabels(pr.Labels) {
+		s.Labels = append(s.Labels, label.GetName())
+	}
+}
+
+// graphQLItemError returns the class of the error GraphQL gave for a pull request it couldn't resolve.
+func graphQLItemError(err graphql.Error) error {
+	switch err.Type {
+	case "NOT_FOUND", "":
+		// Pull requests missing without an error weren't found either
+		return errors.Wrap(ErrGitHubNotFound, err.Message)
+	case "FORBIDDEN":
+		return errors.Wrap(ErrGitHubForbidden, err.Message)
+	case "RATE_LIMITED":
+		return errors.Wrap(ErrRateLimited, err.Message)
+	default:
+		return errors.Wrap(ErrInternal, err.Message)
+	}
+}
+
+func (p *Plugin) getPullRequestsStatus(w http.ResponseWriter, r *http.Request, userID string) {
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	var req []*PullRequestStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a JSON array of pull requests.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if len(req) > maxPullRequestStatuses {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: fmt.Sprintf("Please provide at most %d pull requests.", maxPullRequestStatuses), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	for _, pr := range req {
+		if pr == nil || pr.Owner == "" || pr.Repo == "" || pr.Number <= 0 {
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide the owner, repo and number of each pull request.", StatusCode: http.StatusBadRequest})
+			return
+		}
+	}
+
+	githubClient := p.githubConnect(*info.Token)
+
+	statuses, cost := p.fetchPullRequestsStatus(r.Context(), githubClient, req)
+
+	w.Header().Set(gitHubAPICostHeader, strconv.Itoa(cost))
+	p.writeJSON(w, statuses)
+}
+
+// fetchPullRequestsStatus fetches the status of pull requests with batched GraphQL queries, or
+// with the REST API if GraphQL fails. Pull requests outside of the organization the plugin is
+// locked to aren't fetched. It also returns what the requests cost, see gitHubAPICostHeader.
+func (p *Plugin) fetchPullRequestsStatus(ctx context.Context, githubClient *github.Client, prs []*PullRequestStatusRequest) ([]*PullRequestStatus, int) {
+	statuses := make([]*PullRequestStatus, len(prs))
+	refs := []graphql.PullRequestRef{}
+	fetched := []*PullRequestStatus{}
+	for i, pr := range prs {
+		statuses[i] = &PullRequestStatus{Owner: pr.Owner, Repo: pr.Repo, Number: pr.Number}
+
+		if err := p.checkOrg(pr.Owner); err != nil {
+			statuses[i].Error = &APIErrorResponse{ID: apiErrorIDGitHubForbidden, Message: err.Error(), StatusCode: http.StatusForbidden}
+			continue
+		}
+
+		refs = append(refs, graphql.PullRequestRef{Owner: pr.Owner, Repo: pr.Repo, Number: pr.Number})
+		fetched = append(fetched, statuses[i])
+	}
+
+	if len(fetched) == 0 {
+		return statuses, 0
+	}
+
+	result, err := graphql.NewClient(githubClient).GetPullRequests(ctx, refs)
+	if err != nil {
+		p.API.LogDebug("Failed to fetch PR status with GraphQL, falling back to the REST API", "error", err.Error())
+		return statuses, p.fetchPullRequestsStatusREST(ctx, githubClient, fetched)
+	}
+
+	for i, status := range fetched {
+		if details := result.Details[i]; details != nil {
+			status.setFromGraphQL(details)
+			continue
+		}
+
+		status.Error = newAPIError(graphQLItemError(result.Errors[i]), fmt.Sprintf("Failed to fetch %s.", status.fullName()))
+	}
+
+	return statuses, result.Cost
+}
+
+// fetchPullRequestsStatusREST fills the given statuses with the REST API, with a bounded number of
+// concurrent requests. It returns the number of requests made.
+func (p *Plugin) fetchPullRequestsStatusREST(ctx context.Context, githubClient *github.Client, statuses []*PullRequestStatus) int {
+	var lock sync.Mutex
+	requests := 0
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, pullRequestStatusWorkers)
+	for _, status := range statuses {
+		workers <- struct{}{}
+
+		status := status
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			made, err := fetchPullRequestStatusREST(ctx, githubClient, status)
+
+			lock.Lock()
+			requests += made
+			lock.Unlock()
+
+			if err != nil {
+				status.Error = newAPIError(err, fmt.Sprintf("Failed to fetch %s.", status.fullName()))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return requests
+}
+
+// fetchPullRequestStatusREST fills the status of a pull request with the REST API. It returns the
+// number of requests made.
+func fetchPullRequestStatusREST(ctx context.Context, githubClient *github.Client, status *PullRequestStatus) (int, error) {
+	pr, _, err := githubClient.PullRequests.Get(ctx, status.Owner, status.Repo, status.Number)
+	if err != nil {
+		return 1, errors.Wrap(err, "could not get pull request")
+	}
+
+	combined, _, err := githubClient.Repositories.GetCombinedStatus(ctx, status.Owner, status.Repo, pr.GetHead().GetSHA(), nil)
+	if err != nil {
+		return 2, errors.Wrap(err, "could not get combined status")
+	}
+
+	status.setFromREST(pr, combined)
+
+	return 2, nil
+}
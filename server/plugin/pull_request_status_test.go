@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestGetPullRequestsStatus(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	graphQLAvailable := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/graphql":
+			if !graphQLAvailable {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			fmt.Fprint(w, `{"data": {
+				"pr0": {"pullRequest": {
+					"state": "OPEN",
+					"mergeable": "MERGEABLE",
+					"reviewDecision": "CHANGES_REQUESTED",
+					"commits": {"nodes": [{"commit": {"statusCheckRollup": {"state": "EXPECTED"}}}]},
+					"labels": {"nodes": [{"name": "release-blocker", "color": "b60205"}]}
+				}},
+				"pr1": null,
+				"pr2": {"pullRequest": null},
+				"rateLimit": {"cost": 1}
+			}, "errors": [
+				{"type": "NOT_FOUND", "path": ["pr1"], "message": "Could not resolve to a Repository with the name 'acme/missing'."},
+				{"type": "FORBIDDEN", "path": ["pr2", "pullRequest"], "message": "Resource protected by organization SAML enforcement."}
+			]}`)
+		case "/api/v3/repos/acme/server/pulls/1":
+			fmt.Fprint(w, `{"number": 1, "state": "closed", "merged": true, "mergeable": false, "labels": [{"name": "release-blocker"}], "head": {"sha": "abc"}}`)
+		case "/api/v3/repos/acme/server/commits/abc/status":
+			fmt.Fprint(w, `{"state": "success", "total_count": 2}`)
+		case "/api/v3/repos/acme/secret/pulls/3":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "Resource protected by organization SAML enforcement."}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/", GitHubOrg: "acme"})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store["userID"+githubTokenKey] = info
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	p.SetAPI(api)
+
+	getStatus := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		p.getPullRequestsStatus(w, httptest.NewRequest(http.MethodPost, "/api/v1/prs/status", strings.NewReader(body)), "userID")
+		return w
+	}
+	body := `[
+		{"owner": "acme", "repo": "server", "number": 1},
+		{"owner": "acme", "repo": "missing", "number": 2},
+		{"owner": "acme", "repo": "secret", "number": 3},
+		{"owner": "other", "repo": "server", "number": 4}
+	]`
+
+	t.Run("GraphQL", func(t *testing.T) {
+		graphQLAvailable = true
+
+		w := getStatus(body)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1", w.Header().Get(gitHubAPICostHeader))
+
+		var statuses []*PullRequestStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+		require.Len(t, statuses, 4)
+
+		assert.Equal(t, &PullRequestStatus{Owner: "acme", Repo: "server", Number: 1, State: "open", Mergeable: "mergeable", ReviewDecision: "changes_requested", Checks: "pending", Labels: []string{"release-blocker"}}, statuses[0])
+
+		require.NotNil(t, statuses[1].Error)
+		assert.Equal(t, apiErrorIDGitHubNotFound, statuses[1].Error.ID)
+		assert.Equal(t, http.StatusNotFound, statuses[1].Error.StatusCode)
+		assert.Contains(t, statuses[1].Error.Message, "Failed to fetch acme/missing#2.")
+		assert.Empty(t, statuses[1].State)
+
+		require.NotNil(t, statuses[2].Error)
+		assert.Equal(t, apiErrorIDGitHubForbidden, statuses[2].Error.ID)
+
+		// Not fetched because of the organization lock
+		require.NotNil(t, statuses[3].Error)
+		assert.Equal(t, apiErrorIDGitHubForbidden, statuses[3].Error.ID)
+		assert.Equal(t, "only repositories in the acme organization are supported", statuses[3].Error.Message)
+	})
+
+	t.Run("REST fallback", func(t *testing.T) {
+		graphQLAvailable = false
+
+		w := getStatus(body)
+		require.Equal(t, http.StatusOK, w.Code)
+		// Two requests for the found pull request, and one for each of the others
+		assert.Equal(t, "4", w.Header().Get(gitHubAPICostHeader))
+
+		var statuses []*PullRequestStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+		require.Len(t, statuses, 4)
+
+		assert.Equal(t, &PullRequestStatus{Owner: "acme", Repo: "server", Number: 1, State: "merged", Mergeable: "conflicting", Checks: "success", Labels: []string{"release-blocker"}}, statuses[0])
+		require.NotNil(t, statuses[1].Error)
+		assert.Equal(t, apiErrorIDGitHubNotFound, statuses[1].Error.ID)
+		require.NotNil(t, statuses[2].Error)
+		assert.Equal(t, apiErrorIDGitHubForbidden, statuses[2].Error.ID)
+		require.NotNil(t, statuses[3].Error)
+		assert.Equal(t, apiErrorIDGitHubForbidden, statuses[3].Error.ID)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		w := getStatus(`{"owner": "acme"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = getStatus(`[{"owner": "acme", "repo": "server"}]`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Please provide the owner, repo and number of each pull request.")
+
+		prs := []string{}
+		for i := 0; i <= maxPullRequestStatuses; i++ {
+			prs = append(prs, fmt.Sprintf(`{"owner": "acme", "repo": "server", "number": %d}`, i+1))
+		}
+		w = getStatus("[" + strings.Join(prs, ",") + "]")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Please provide at most 50 pull requests.")
+	})
+}
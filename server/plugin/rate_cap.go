@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	maxPerHourFlag = "max-per-hour"
+	rateCapKey     = "_ratecap"
+
+	// maxRateCap is the largest value of the --max-per-hour flag.
+	maxRateCap = 1000
+	// rateCapBucket is the period the posts of a subscription are counted over.
+	rateCapBucket = time.Hour
+	// rateCapUpdateAttempts is the number of times a concurrent update of a counter is retried.
+	rateCapUpdateAttempts = 5
+)
+
+// parseRateCap parses the value of the --max-per-hour flag.
+func parseRateCap(value string) (int, error) {
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 || limit > maxRateCap {
+		return 0, errors.Errorf("Invalid value %q for --%s. Use a number of notifications between 1 and %d, e.g. `30`.", value, maxPerHourFlag, maxRateCap)
+	}
+
+	return limit, nil
+}
+
+// rateCapCounter counts the posts of a subscription during an hour. It is stored with the time
+// left in the hour as its expiry, so it's removed at the end of the bucket.
+type rateCapCounter struct {
+	Bucket time.Time
+	Posted int
+	// Suppressed is the number of events not posted since the cap was reached.
+	Suppressed int
+	// PostID is the post telling the cap was reached, updated with the number of suppressed events.
+	PostID string `json:",omitempty"`
+}
+
+func rateCapKeyFor(sub *Subscription) string {
+	return hashKey(rateCapKey, sub.ChannelID, sub.Repository)
+}
+
+// expiry returns the number of seconds left in the bucket of the counter, at least one.
+func (c *rateCapCounter) expiry(now time.Time) int64 {
+	left := c.Bucket.Add(rateCapBucket).Sub(now).Seconds()
+	return int64(math.Max(1, math.Ceil(left)))
+}
+
+// next counts an event, returning whether it can be posted.
+func (c *rateCapCounter) next(limit int) bool {
+	if c.Posted < limit {
+		c.Posted++
+		return true
+	}
+
+	c.Suppressed++
+	return false
+}
+
+func rateCapMessage(sub *Subscription, limit, suppressed int) string {
+	return fmt.Sprintf("Rate cap of %d notifications per hour reached for %s, suppressing further notifications for this hour (%d suppressed so far).",
+		limit, sub.Repository, suppressed)
+}
+
+// updateRateCapCounter applies the update to the counter of the current bucket of a subscription.
+// The counter of a previous bucket is replaced by a new one. The counter is updated atomically, as
+// webhooks can be handled concurrently.
+func (p *Plugin) updateRateCapCounter(sub *Subscription, now time.Time, update func(counter *rateCapCounter)) (*rateCapCounter, error) {
+	key := rateCapKeyFor(sub)
+	bucket := now.Truncate(rateCapBucket)
+
+	for attempt := 0; attempt < rateCapUpdateAttempts; attempt++ {
+		oldValue, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "could not get rate cap counter from KV store")
+		}
+
+		counter := &rateCapCounter{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, counter); err != nil {
+				return nil, errors.Wrap(err, "could not unmarshal rate cap counter")
+			}
+		}
+		if !counter.Bucket.Equal(bucket) {
+			counter = &rateCapCounter{Bucket: bucket}
+		}
+
+		update(counter)
+
+		newValue, err := json.Marshal(counter)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not marshal rate cap counter")
+		}
+
+		stored, appErr := p.API.KVSetWithOptions(key, newValue, model.PluginKVSetOptions{
+			Atomic:          true,
+			OldValue:        oldValue,
+			ExpireInSeconds: counter.expiry(now),
+		})
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "could not store rate cap counter in KV store")
+		}
+		if stored {
+			return counter, nil
+		}
+	}
+
+	return nil, errors.New("too many concurrent updates of the rate cap counter")
+}
+
+// applyRateCap counts an event of a subscription with the --max-per-hour flag, returning whether it
+// can be posted. Once the cap is reached, a single post tells the channel, and is updated with the
+// number of suppressed events until the end of the hour.
+func (p *Plugin) applyRateCap(sub *Subscription, limit int, now time.Time) (bool, error) {
+	var shouldPost bool
+	counter, err := p.updateRateCapCounter(sub, now, func(counter *rateCapCounter) {
+		shouldPost = counter.next(limit)
+	})
+	if err != nil {
+		return false, err
+	}
+	if shouldPost {
+		return true, nil
+	}
+
+	if err := p.updateRateCapPost(sub, limit, counter, now); err != nil {
+		p.API.LogWarn("Failed to update rate cap post", "channelID", sub.ChannelID, "repo", sub.Repository, "error", err.Error())
+	}
+
+	return false, nil
+}
+
+// updateRateCapPost creates the post telling the cap of a subscription was reached on the first
+// suppressed event, and updates it with the number of suppressed events on the next ones.
+func (p *Plugin) updateRateCapPost(sub *Subscription, limit int, counter *rateCapCounter, now time.Time) error {
+	message := rateCapMessage(sub, limit, counter.Suppressed)
+	if counter.PostID != "" {
+		post, appErr := p.API.GetPost(counter.PostID)
+		if appErr != nil {
+			return errors.Wrap(appErr, "could not get post")
+		}
+		post.Message = message
+		if _, appErr := p.API.UpdatePost(post); appErr != nil {
+			return errors.Wrap(appErr, "could not update post")
+		}
+		return nil
+	}
+
+	// Only the first suppressed event creates the post, the next ones update it once it's stored
+	if counter.Suppressed != 1 {
+		return nil
+	}
+
+	created := p.createSubscriptionPost(&model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: sub.ChannelID,
+		Message:   message,
+	}, sub)
+	if created == nil {
+		return nil
+	}
+
+	_, err := p.updateRateCapCounter(sub, now, func(counter *rateCapCounter) {
+		// The hour may have ended in the meantime
+		if counter.Suppressed > 0 {
+			counter.PostID = created.Id
+		}
+	})
+	return err
+}
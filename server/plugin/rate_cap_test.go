@@ -0,0 +1,172 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateCap(t *testing.T) {
+	limit, err := parseRateCap("30")
+	require.NoError(t, err)
+	assert.Equal(t, 30, limit)
+
+	for _, value := range []string{"0", "-1", "1001", "many", ""} {
+		_, err := parseRateCap(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestMaxPerHourFlag(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(maxPerHourFlag, "30"))
+	assert.Equal(t, 30, flags.MaxPerHour)
+	assert.Equal(t, "--max-per-hour 30", flags.String())
+
+	assert.Error(t, flags.SetFlag(maxPerHourFlag, "0"))
+}
+
+func TestApplyRateCap(t *testing.T) {
+	sub := &Subscription{
+		ChannelID:  "channelID",
+		Repository: "owner/repo",
+		Flags:      SubscriptionFlags{MaxPerHour: 2},
+	}
+	hour := time.Date(2021, 3, 5, 12, 0, 0, 0, time.UTC)
+
+	setup := func() (*Plugin, map[string][]byte, map[string]*model.Post, *[]int64) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		expiries := &[]int64{}
+		api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(func(key string, value []byte, opts model.PluginKVSetOptions) bool {
+			if !bytes.Equal(store[key], opts.OldValue) {
+				return false
+			}
+			store[key] = value
+			*expiries = append(*expiries, opts.ExpireInSeconds)
+			return true
+		}, nil)
+
+		posts := map[string]*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			created := post.Clone()
+			created.Id = model.NewId()
+			posts[created.Id] = created
+			return created
+		}, nil)
+		api.On("GetPost", mock.AnythingOfType("string")).Return(func(postID string) *model.Post {
+			return posts[postID].Clone()
+		}, nil)
+		api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			posts[post.Id] = post
+			return post
+		}, nil)
+		p.SetAPI(api)
+
+		return p, store, posts, expiries
+	}
+
+	apply := func(t *testing.T, p *Plugin, now time.Time) bool {
+		shouldPost, err := p.applyRateCap(sub, 2, now)
+		require.NoError(t, err)
+		return shouldPost
+	}
+
+	t.Run("cap crossing", func(t *testing.T) {
+		p, store, posts, expiries := setup()
+
+		assert.True(t, apply(t, p, hour.Add(10*time.Minute)))
+		assert.True(t, apply(t, p, hour.Add(20*time.Minute)))
+		assert.Empty(t, posts)
+
+		assert.False(t, apply(t, p, hour.Add(30*time.Minute)))
+		require.Len(t, posts, 1)
+		var capPost *model.Post
+		for _, post := range posts {
+			capPost = post
+		}
+		assert.Equal(t, "botID", capPost.UserId)
+		assert.Equal(t, "channelID", capPost.ChannelId)
+		assert.Equal(t, "Rate cap of 2 notifications per hour reached for owner/repo, suppressing further notifications for this hour (1 suppressed so far).", capPost.Message)
+
+		assert.False(t, apply(t, p, hour.Add(40*time.Minute)))
+		require.Len(t, posts, 1)
+		assert.Equal(t, "Rate cap of 2 notifications per hour reached for owner/repo, suppressing further notifications for this hour (2 suppressed so far).", posts[capPost.Id].Message)
+
+		var counter rateCapCounter
+		require.NoError(t, json.Unmarshal(store[rateCapKeyFor(sub)], &counter))
+		assert.Equal(t, rateCapCounter{Bucket: hour, Posted: 2, Suppressed: 2, PostID: capPost.Id}, counter)
+
+		// The counter expires at the end of the hour
+		assert.Equal(t, []int64{50 * 60, 40 * 60, 30 * 60, 30 * 60, 20 * 60}, *expiries)
+	})
+
+	t.Run("bucket rollover", func(t *testing.T) {
+		p, store, posts, _ := setup()
+
+		for i := 0; i < 3; i++ {
+			apply(t, p, hour.Add(50*time.Minute))
+		}
+		require.Len(t, posts, 1)
+
+		// A counter left from the previous hour is replaced
+		assert.True(t, apply(t, p, hour.Add(time.Hour)))
+		var counter rateCapCounter
+		require.NoError(t, json.Unmarshal(store[rateCapKeyFor(sub)], &counter))
+		assert.Equal(t, rateCapCounter{Bucket: hour.Add(time.Hour), Posted: 1}, counter)
+
+		assert.True(t, apply(t, p, hour.Add(time.Hour+time.Minute)))
+		assert.False(t, apply(t, p, hour.Add(time.Hour+2*time.Minute)))
+		assert.Len(t, posts, 2)
+	})
+}
+
+func TestPostSubscriptionEventRateCap(t *testing.T) {
+	sub := &Subscription{
+		ChannelID:  "channelID",
+		Repository: "owner/repo",
+		Flags:      SubscriptionFlags{MaxPerHour: 1},
+	}
+
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(func(key string, value []byte, opts model.PluginKVSetOptions) bool {
+		store[key] = value
+		return true
+	}, nil)
+	messages := []string{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		messages = append(messages, post.Message)
+		return post
+	}, nil)
+	p.SetAPI(api)
+
+	delivery := testWebhookDelivery(p)
+	p.postSubscriptionEvent(&model.Post{Message: "push 1"}, sub, featurePushes, delivery)
+	p.postSubscriptionEvent(&model.Post{Message: "push 2"}, sub, featurePushes, delivery)
+	// Critical features bypass the cap
+	p.postSubscriptionEvent(&model.Post{Message: "failure"}, sub, featureWorkflowFailure, delivery)
+	p.postSubscriptionEvent(&model.Post{Message: "alert"}, sub, featureBranchProtection, delivery)
+
+	assert.Equal(t, []string{
+		"push 1",
+		"Rate cap of 1 notifications per hour reached for owner/repo, suppressing further notifications for this hour (1 suppressed so far).",
+		"failure",
+		"alert",
+	}, messages)
+
+	summary := delivery.summary()
+	assert.Equal(t, 3, summary.Posted)
+	assert.Equal(t, map[string]int{filterReasonRateCapped: 1}, summary.Filtered)
+}
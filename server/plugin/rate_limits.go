@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+// RateLimitStatus is the state of a GitHub rate limit of a user.
+type RateLimitStatus struct {
+	Remaining int       `json:"remaining"`
+	Limit     int       `json:"limit"`
+	Reset     time.Time `json:"reset"`
+}
+
+// RateLimitsStatus are the rate limits of a user the plugin depends on: the core one of the REST
+// API, and the much lower one of the search API used by the sidebar.
+type RateLimitsStatus struct {
+	Core   *RateLimitStatus `json:"core"`
+	Search *RateLimitStatus `json:"search"`
+}
+
+func newRateLimitStatus(rate *github.Rate) *RateLimitStatus {
+	if rate == nil {
+		return nil
+	}
+
+	return &RateLimitStatus{Remaining: rate.Remaining, Limit: rate.Limit, Reset: rate.Reset.Time}
+}
+
+// getRateLimits fetches the rate limits of the user of a client. Fetching them doesn't count
+// against the rate limits.
+func getRateLimits(ctx context.Context, githubClient *github.Client) (*RateLimitsStatus, error) {
+	limits, _, err := githubClient.RateLimits(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get rate limits")
+	}
+
+	return &RateLimitsStatus{Core: newRateLimitStatus(limits.GetCore()), Search: newRateLimitStatus(limits.GetSearch())}, nil
+}
+
+// formatThousands formats a number with commas between the groups of thousands, e.g. 4,200.
+func formatThousands(n int) string {
+	if n < 0 {
+		return "-" + formatThousands(-n)
+	}
+
+	digits := strconv.Itoa(n)
+	for i := len(digits) - 3; i > 0; i -= 3 {
+		digits = digits[:i] + "," + digits[i:]
+	}
+
+	return digits
+}
+
+// formatRateLimitReset formats the time until a rate limit resets in minutes, rounded up, e.g. 12m.
+func formatRateLimitReset(reset, now time.Time) string {
+	if !reset.After(now) {
+		return "now"
+	}
+
+	return fmt.Sprintf("%dm", int(math.Ceil(reset.Sub(now).Minutes())))
+}
+
+// formatRateLimits renders the rate limits of a user, e.g. "API quota: 4,200/5,000 core
+// remaining, resets in 12m". It returns an empty string when none is known.
+func formatRateLimits(limits *RateLimitsStatus, now time.Time) string {
+	parts := []string{}
+	for _, limit := range []struct {
+		name   string
+		status *RateLimitStatus
+	}{
+		{name: "core", status: limits.Core},
+		{name: "search", status: limits.Search},
+	} {
+		if limit.status == nil {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s/%s %s remaining, resets in %s", formatThousands(limit.status.Remaining), formatThousands(limit.status.Limit), limit.name, formatRateLimitReset(limit.status.Reset, now)))
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return "API quota: " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestFormatRateLimits(t *testing.T) {
+	now := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	for n, expected := range map[int]string{0: "0", 30: "30", 999: "999", 4200: "4,200", 15000: "15,000", 1234567: "1,234,567", -4200: "-4,200"} {
+		assert.Equal(t, expected, formatThousands(n))
+	}
+
+	assert.Equal(t, "API quota: 4,200/5,000 core remaining, resets in 12m; 0/30 search remaining, resets in 1m", formatRateLimits(&RateLimitsStatus{
+		Core:   &RateLimitStatus{Remaining: 4200, Limit: 5000, Reset: now.Add(11*time.Minute + 30*time.Second)},
+		Search: &RateLimitStatus{Remaining: 0, Limit: 30, Reset: now.Add(20 * time.Second)},
+	}, now))
+	assert.Equal(t, "API quota: 5,000/5,000 core remaining, resets in now", formatRateLimits(&RateLimitsStatus{Core: &RateLimitStatus{Remaining: 5000, Limit: 5000, Reset: now}}, now))
+	assert.Empty(t, formatRateLimits(&RateLimitsStatus{}, now))
+}
+
+func TestRateLimitsOfUser(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice", Settings: &UserSettings{}})
+	require.NoError(t, err)
+
+	reset := time.Now().Add(12 * time.Minute).Unix()
+	rateLimitStatus := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/user":
+			fmt.Fprint(w, `{"login": "alice", "html_url": "https://github.com/alice", "avatar_url": "https://avatars.example.com/alice"}`)
+		case "/api/v3/rate_limit":
+			w.WriteHeader(rateLimitStatus)
+			fmt.Fprintf(w, `{"resources": {"core": {"limit": 5000, "remaining": 4200, "reset": %d}, "search": {"limit": 30, "remaining": 28, "reset": %d}}}`, reset, reset)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func() *Plugin {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+		return p
+	}
+	userInfo := &GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: "token"}}
+
+	t.Run("me", func(t *testing.T) {
+		rateLimitStatus = http.StatusOK
+
+		message := setup().handleMe(nil, &model.CommandArgs{UserId: "userID"}, nil, userInfo)
+		assert.Contains(t, message, "[alice](https://github.com/alice)")
+		assert.Contains(t, message, "\n\nAPI quota: 4,200/5,000 core remaining, resets in ")
+		assert.Contains(t, message, "; 28/30 search remaining")
+	})
+
+	t.Run("me without rate limits", func(t *testing.T) {
+		rateLimitStatus = http.StatusInternalServerError
+
+		message := setup().handleMe(nil, &model.CommandArgs{UserId: "userID"}, nil, userInfo)
+		assert.Contains(t, message, "[alice](https://github.com/alice)")
+		assert.NotContains(t, message, "API quota")
+	})
+
+	getConnected := func(url string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		r.Header.Set("Mattermost-User-ID", "userID")
+		setup().getConnected(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Equal(t, true, resp["connected"])
+		return resp
+	}
+
+	t.Run("connected", func(t *testing.T) {
+		rateLimitStatus = http.StatusOK
+
+		assert.NotContains(t, getConnected("/api/v1/connected"), "rate_limits")
+
+		resp := getConnected("/api/v1/connected?include_rate=true")
+		require.Contains(t, resp, "rate_limits")
+		limits := resp["rate_limits"].(map[string]interface{})
+		assert.Equal(t, float64(4200), limits["core"].(map[string]interface{})["remaining"])
+		assert.Equal(t, float64(30), limits["search"].(map[string]interface{})["limit"])
+	})
+
+	t.Run("connected without rate limits", func(t *testing.T) {
+		rateLimitStatus = http.StatusInternalServerError
+
+		assert.NotContains(t, getConnected("/api/v1/connected?include_rate=true"), "rate_limits")
+	})
+}
@@ -0,0 +1,34 @@
+package plugin
+
+import (
+	"github.com/google/go-github/v31/github"
+)
+
+// readOnlyModeMessage is shown to users in read-only mode asking for a change on GitHub.
+const readOnlyModeMessage = "Read-only mode is on, so the plugin won't make changes on GitHub with your account. Turn it off with `/github settings read-only off`."
+
+// githubOperation tells what a GitHub client of a user is used for.
+type githubOperation int
+
+const (
+	// githubRead only fetches data from GitHub.
+	githubRead githubOperation = iota
+	// githubWrite makes changes on GitHub, e.g. creating an issue or requesting reviewers.
+	githubWrite
+)
+
+// isReadOnly checks if the user turned on read-only mode, e.g. because their token has more
+// scopes than they want the plugin to use.
+func (s *UserSettings) isReadOnly() bool {
+	return s != nil && s.ReadOnlyMode
+}
+
+// getGithubClientFor returns a GitHub client of a user for an operation. It returns ErrReadOnlyMode
+// for changes on GitHub if the user is in read-only mode.
+func (p *Plugin) getGithubClientFor(userInfo *GitHubUserInfo, operation githubOperation) (*github.Client, error) {
+	if operation == githubWrite && userInfo.Settings.isReadOnly() {
+		return nil, ErrReadOnlyMode
+	}
+
+	return p.githubConnect(*userInfo.Token), nil
+}
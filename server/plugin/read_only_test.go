@@ -0,0 +1,164 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestGetGithubClientFor(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{})
+	api := &plugintest.API{}
+	p.SetAPI(api)
+
+	info := &GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: "token"}, Settings: &UserSettings{}}
+	client, err := p.getGithubClientFor(info, githubWrite)
+	require.NoError(t, err)
+	assert.NotNil(t, client)
+
+	info.Settings.ReadOnlyMode = true
+	client, err = p.getGithubClientFor(info, githubRead)
+	require.NoError(t, err)
+	assert.NotNil(t, client)
+
+	_, err = p.getGithubClientFor(info, githubWrite)
+	assert.Equal(t, ErrReadOnlyMode, err)
+
+	// Users connected before settings existed aren't in read-only mode
+	info.Settings = nil
+	_, err = p.getGithubClientFor(info, githubWrite)
+	assert.NoError(t, err)
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{
+		UserID:         "userID",
+		Token:          &oauth2.Token{AccessToken: token},
+		GitHubUsername: "alice",
+		Settings:       &UserSettings{ReadOnlyMode: true},
+	})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected %s request to %s in read-only mode", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo/labels":
+			fmt.Fprint(w, `[{"name": "bug"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func() *Plugin {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		api.On("KVGet", SubscriptionsKey).Return(nil, nil)
+		api.On("GetPost", "postID").Return(&model.Post{Id: "postID", UserId: "userID", ChannelId: "channelID", Message: "message"}, nil)
+		api.On("GetUser", "userID").Return(&model.User{Id: "userID", Username: "alice"}, nil)
+		api.On("GetConfig").Return(&model.Config{})
+		p.SetAPI(api)
+		return p
+	}
+
+	userInfo := func(p *Plugin) *GitHubUserInfo {
+		userInfo, apiErr := p.getGitHubUserInfo("userID")
+		require.Nil(t, apiErr)
+		return userInfo
+	}
+
+	assertRefused := func(t *testing.T, w *httptest.ResponseRecorder) {
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), `"id":"read_only_mode"`)
+		assert.Contains(t, w.Body.String(), "/github settings read-only off")
+	}
+
+	t.Run("create issue", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		body := strings.NewReader(`{"title": "Title", "repo": "owner/repo", "channel_id": "channelID"}`)
+		setup().createIssue(w, httptest.NewRequest(http.MethodPost, "/api/v1/createissue", body), "userID")
+		assertRefused(t, w)
+	})
+
+	t.Run("create issue comment", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		body := strings.NewReader(`{"post_id": "postID", "owner": "owner", "repo": "repo", "number": 12, "comment": "comment"}`)
+		setup().createIssueComment(w, httptest.NewRequest(http.MethodPost, "/api/v1/createissuecomment", body), "userID")
+		assertRefused(t, w)
+	})
+
+	t.Run("update reviewers", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		body := strings.NewReader(`{"owner": "owner", "repo": "repo", "number": 12, "reviewers": ["bob"]}`)
+		setup().updatePrReviewers(w, httptest.NewRequest(http.MethodPost, "/api/v1/reviewers", body), "userID")
+		assertRefused(t, w)
+	})
+
+	t.Run("create discussion", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		body := strings.NewReader(`{"repo": "owner/repo", "category_id": "categoryID", "title": "Title", "channel_id": "channelID"}`)
+		setup().createDiscussion(w, httptest.NewRequest(http.MethodPost, "/api/v1/creatediscussion", body), "userID")
+		assertRefused(t, w)
+	})
+
+	t.Run("create pull request", func(t *testing.T) {
+		p := setup()
+		_, apiErr := p.createPullRequest(context.Background(), "userID", userInfo(p), &pullRequestCreate{Repo: "owner/repo", Head: "feature", Title: "Title", ChannelID: "channelID"})
+		require.NotNil(t, apiErr)
+		assert.Equal(t, apiErrorIDReadOnlyMode, apiErr.ID)
+		assert.Equal(t, readOnlyModeMessage, apiErr.Message)
+	})
+
+	t.Run("reviewers command", func(t *testing.T) {
+		p := setup()
+		message := p.handleReviewers(nil, &model.CommandArgs{ChannelId: "channelID"}, []string{"add", "owner/repo#12", "bob"}, userInfo(p))
+		assert.Equal(t, readOnlyModeMessage, message)
+	})
+
+	t.Run("workflow run command", func(t *testing.T) {
+		p := setup()
+		message := p.handleWorkflowRun(&model.CommandArgs{ChannelId: "channelID"}, "owner/repo", "ci.yml", "main", nil, userInfo(p))
+		assert.Equal(t, readOnlyModeMessage, message)
+	})
+
+	t.Run("attached comment action", func(t *testing.T) {
+		reply := &model.Post{Id: "replyID", UserId: "userID", ChannelId: "channelID", RootId: "postID"}
+		(&attachedComment{AttachedPostID: "postID", Owner: "owner", Repo: "repo", Number: 12, CommentID: 34}).setProps(reply)
+
+		p := setup()
+		p.API.(*plugintest.API).On("GetPost", "replyID").Return(reply, nil)
+		message := p.runAttachedCommentAction("userID", &model.PostActionIntegrationRequest{PostId: "promptID"}, attachedCommentActionDelete, "replyID")
+		assert.Equal(t, readOnlyModeMessage, message)
+	})
+
+	t.Run("reads keep working", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		setup().getLabels(w, httptest.NewRequest(http.MethodGet, "/api/v1/labels?repo=owner/repo", nil), "userID")
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"name":"bug"`)
+	})
+}
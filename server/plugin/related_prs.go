@@ -0,0 +1,205 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	relatedPRsFlag = "related-prs"
+
+	// maxRelatedPRs is the number of related pull requests listed under the post of a new issue.
+	maxRelatedPRs = 3
+	// maxRelatedPRKeywords caps the keywords of the title of an issue searched for, as GitHub allows
+	// at most five OR operators in a search.
+	maxRelatedPRKeywords = 5
+	// relatedPRsSearchTimeout bounds the search for related pull requests.
+	relatedPRsSearchTimeout = 5 * time.Second
+)
+
+// relatedPRsStopWords are the words of issue titles too common to tell pull requests apart.
+var relatedPRsStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "when": true, "not": true, "from": true,
+	"does": true, "doesn": true, "can": true, "cannot": true, "should": true, "into": true,
+	"are": true, "was": true, "this": true, "that": true, "after": true, "before": true,
+	"bug": true, "issue": true, "error": true, "fix": true, "add": true, "support": true,
+}
+
+// relatedPR is a pull request that may be related to an issue.
+type relatedPR struct {
+	PullRequest *github.Issue
+	// Matched are the keywords of the title of the issue found in the title of the pull request.
+	Matched []string
+	// References tells if the pull request mentions the issue.
+	References bool
+}
+
+func (r *relatedPR) score() int {
+	score := len(r.Matched)
+	if r.References {
+		score += maxRelatedPRKeywords
+	}
+	return score
+}
+
+// titleKeywords returns the distinct words of an issue title worth searching for, in order.
+func titleKeywords(title string) []string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	keywords := []string{}
+	for _, word := range words {
+		if len([]rune(word)) < 3 || relatedPRsStopWords[word] || containsValue(keywords, word) {
+			continue
+		}
+		keywords = append(keywords, word)
+		if len(keywords) == maxRelatedPRKeywords {
+			break
+		}
+	}
+
+	return keywords
+}
+
+// relatedPRsQuery returns the search of the open pull requests of a repository with any of the keywords.
+func relatedPRsQuery(repository string, keywords []string) string {
+	return fmt.Sprintf("repo:%s is:pr is:open %s", repository, strings.Join(keywords, " OR "))
+}
+
+// referencesIssue checks if the text of a pull request mentions an issue by its number or URL.
+func referencesIssue(text string, number int, issueURL string) bool {
+	if issueURL != "" && strings.Contains(text, issueURL) {
+		return true
+	}
+
+	reference := "#" + strconv.Itoa(number)
+	for index := strings.Index(text, reference); index != -1; {
+		end := index + len(reference)
+		if end == len(text) || !unicode.IsDigit(rune(text[end])) {
+			return true
+		}
+
+		next := strings.Index(text[end:], reference)
+		if next == -1 {
+			break
+		}
+		index = end + next
+	}
+
+	return false
+}
+
+// rankRelatedPRs keeps the plausible matches among the pull requests found for an issue: those
+// sharing at least two keywords with it, or all of them if it has fewer, and those mentioning it.
+func rankRelatedPRs(issue *github.Issue, keywords []string, pullRequests []*github.Issue) []*relatedPR {
+	minMatched := 2
+	if len(keywords) < minMatched {
+		minMatched = len(keywords)
+	}
+
+	related := []*relatedPR{}
+	for _, pr := range pullRequests {
+		titleWords := titleKeywords(pr.GetTitle())
+		candidate := &relatedPR{
+			PullRequest: pr,
+			References:  referencesIssue(pr.GetBody(), issue.GetNumber(), issue.GetHTMLURL()),
+		}
+		for _, keyword := range keywords {
+			if containsValue(titleWords, keyword) || strings.Contains(strings.ToLower(pr.GetTitle()), keyword) {
+				candidate.Matched = append(candidate.Matched, keyword)
+			}
+		}
+
+		if candidate.References || (minMatched > 0 && len(candidate.Matched) >= minMatched) {
+			related = append(related, candidate)
+		}
+	}
+
+	sort.SliceStable(related, func(i, j int) bool {
+		return related[i].score() > related[j].score()
+	})
+	if len(related) > maxRelatedPRs {
+		related = related[:maxRelatedPRs]
+	}
+
+	return related
+}
+
+// findRelatedPullRequests searches the open pull requests of a repository which may already fix an issue.
+func findRelatedPullRequests(ctx context.Context, githubClient *github.Client, repository string, issue *github.Issue) ([]*relatedPR, error) {
+	keywords := titleKeywords(issue.GetTitle())
+	if len(keywords) == 0 {
+		return nil, nil
+	}
+
+	result, _, err := githubClient.Search.Issues(ctx, relatedPRsQuery(repository, keywords), &github.SearchOptions{
+		ListOptions: github.ListOptions{PerPage: 20},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rankRelatedPRs(issue, keywords, searchResultIssues(result)), nil
+}
+
+func formatRelatedPRs(related []*relatedPR) string {
+	var message strings.Builder
+	message.WriteString("#### Possibly related open pull requests\n")
+	for _, r := range related {
+		pr := r.PullRequest
+		hints := []string{}
+		if r.References {
+			hints = append(hints, "mentions this issue")
+		}
+		if len(r.Matched) > 0 {
+			hints = append(hints, fmt.Sprintf("title shares `%s`", strings.Join(r.Matched, "`, `")))
+		}
+
+		fmt.Fprintf(&message, "* [#%d %s](%s) by %s - %s\n", pr.GetNumber(), pr.GetTitle(), pr.GetHTMLURL(), pr.GetUser().GetLogin(), strings.Join(hints, ", "))
+	}
+
+	return strings.TrimSuffix(message.String(), "\n")
+}
+
+// postRelatedPullRequests replies to the post of a new issue with the open pull requests which may
+// already fix it, for a subscription with the --related-prs flag. The search is made with the client
+// of the creator of the subscription, and gives up after a short time. Nothing is posted if no
+// plausible match is found.
+func (p *Plugin) postRelatedPullRequests(ctx context.Context, sub *Subscription, event *github.IssuesEvent, root *model.Post) {
+	info, apiErr := p.getGitHubUserInfo(sub.CreatorID)
+	if apiErr != nil {
+		p.API.LogDebug("Not searching related pull requests, the subscription creator isn't connected", "channelID", sub.ChannelID, "creatorID", sub.CreatorID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, relatedPRsSearchTimeout)
+	defer cancel()
+
+	related, err := findRelatedPullRequests(ctx, p.githubConnect(*info.Token), event.GetRepo().GetFullName(), event.GetIssue())
+	if err != nil {
+		p.API.LogDebug("Failed to search related pull requests", "repo", event.GetRepo().GetFullName(), "issue", event.GetIssue().GetNumber(), "error", err.Error())
+		return
+	}
+	if len(related) == 0 {
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: root.ChannelId,
+		RootId:    root.Id,
+		Message:   formatRelatedPRs(related),
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to post related pull requests", "channelID", root.ChannelId, "error", appErr.Error())
+	}
+}
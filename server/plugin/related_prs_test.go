@@ -0,0 +1,200 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestRelatedPRsFlag(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(relatedPRsFlag, "true"))
+	assert.True(t, flags.RelatedPRs)
+	assert.Equal(t, "--related-prs true", flags.String())
+
+	assert.Error(t, flags.SetFlag(relatedPRsFlag, "sometimes"))
+}
+
+func TestTitleKeywords(t *testing.T) {
+	assert.Equal(t, []string{"crash", "uploading", "avatar", "safari"}, titleKeywords("Bug: crash when uploading an avatar on Safari"))
+	assert.Equal(t, []string{"one", "two", "three", "four", "five"}, titleKeywords("one two three four five six"))
+	assert.Equal(t, []string{"login"}, titleKeywords("Login login LOGIN"))
+	assert.Empty(t, titleKeywords("Fix the bug"))
+
+	assert.Equal(t, "repo:owner/repo is:pr is:open crash OR avatar", relatedPRsQuery("owner/repo", []string{"crash", "avatar"}))
+}
+
+func TestReferencesIssue(t *testing.T) {
+	assert.True(t, referencesIssue("Fixes #12", 12, ""))
+	assert.True(t, referencesIssue("See #123 and #12.", 12, ""))
+	assert.False(t, referencesIssue("See #123", 12, ""))
+	assert.True(t, referencesIssue("See https://github.com/owner/repo/issues/12", 12, "https://github.com/owner/repo/issues/12"))
+	assert.False(t, referencesIssue("", 12, ""))
+}
+
+func TestRankRelatedPRs(t *testing.T) {
+	issue := &github.Issue{Number: github.Int(12), Title: github.String("Crash when uploading an avatar"), HTMLURL: github.String("https://github.com/owner/repo/issues/12")}
+	keywords := titleKeywords(issue.GetTitle())
+	pr := func(number int, title, body string) *github.Issue {
+		return &github.Issue{Number: github.Int(number), Title: github.String(title), Body: github.String(body)}
+	}
+
+	related := rankRelatedPRs(issue, keywords, []*github.Issue{
+		pr(1, "Fix avatar crash", ""),
+		pr(2, "Avatar colors", ""),
+		pr(3, "Refactor the upload code", "Fixes #12"),
+		pr(4, "Fix crash uploading large avatar", ""),
+		pr(5, "Crash on avatar uploading", ""),
+	})
+
+	require.Len(t, related, 3)
+	assert.Equal(t, 3, related[0].PullRequest.GetNumber())
+	assert.True(t, related[0].References)
+	assert.Equal(t, 4, related[1].PullRequest.GetNumber())
+	assert.Equal(t, []string{"crash", "uploading", "avatar"}, related[1].Matched)
+	assert.Equal(t, 5, related[2].PullRequest.GetNumber())
+}
+
+func TestPostRelatedPullRequests(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "creator-token")
+	require.NoError(t, err)
+
+	// The handler of a timed out search may still run after postRelatedPullRequests returns, so the
+	// state it shares with the subtests is guarded, and slowDone tells when it returned.
+	var lock sync.Mutex
+	var queries []string
+	delay := time.Duration(0)
+	slowDone := make(chan struct{}, 1)
+	getQueries := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return queries
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v3/search/issues", r.URL.Path)
+		assert.Equal(t, "Bearer creator-token", r.Header.Get("Authorization"))
+		lock.Lock()
+		queries = append(queries, r.URL.Query().Get("q"))
+		slow := delay
+		lock.Unlock()
+		if slow > 0 {
+			defer func() { slowDone <- struct{}{} }()
+			select {
+			case <-time.After(slow):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"total_count": 2, "items": [
+			{"number": 7, "title": "Fix crash when uploading avatars", "html_url": "https://github.com/owner/repo/pull/7", "user": {"login": "alice"}},
+			{"number": 8, "title": "Update dependencies", "html_url": "https://github.com/owner/repo/pull/8", "user": {"login": "bob"}}
+		]}`)
+	}))
+	defer ts.Close()
+
+	setup := func(t *testing.T) (*Plugin, *[]*model.Post) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		info, err := json.Marshal(&GitHubUserInfo{UserID: "creatorID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "creator"})
+		require.NoError(t, err)
+		api.On("KVGet", "creatorID"+githubTokenKey).Return(info, nil)
+		api.On("KVGet", "strangerID"+githubTokenKey).Return(nil, nil)
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		posts := &[]*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			*posts = append(*posts, post)
+			return post
+		}, nil)
+		p.SetAPI(api)
+		lock.Lock()
+		queries = nil
+		lock.Unlock()
+
+		return p, posts
+	}
+
+	event := &github.IssuesEvent{
+		Action: github.String("opened"),
+		Repo:   &github.Repository{FullName: github.String("owner/repo")},
+		Issue:  &github.Issue{Number: github.Int(12), Title: github.String("Crash when uploading an avatar"), HTMLURL: github.String("https://github.com/owner/repo/issues/12")},
+	}
+	root := &model.Post{Id: "rootID", ChannelId: "channelID"}
+	sub := &Subscription{ChannelID: "channelID", CreatorID: "creatorID", Repository: "owner/repo", Features: "issues", Flags: SubscriptionFlags{RelatedPRs: true}}
+
+	t.Run("related pull requests", func(t *testing.T) {
+		p, posts := setup(t)
+
+		p.postRelatedPullRequests(context.Background(), sub, event, root)
+		assert.Equal(t, []string{"repo:owner/repo is:pr is:open crash OR uploading OR avatar"}, getQueries())
+		require.Len(t, *posts, 1)
+		post := (*posts)[0]
+		assert.Equal(t, "rootID", post.RootId)
+		assert.Equal(t, "channelID", post.ChannelId)
+		assert.Equal(t, "botID", post.UserId)
+		assert.Equal(t, "#### Possibly related open pull requests\n"+
+			"* [#7 Fix crash when uploading avatars](https://github.com/owner/repo/pull/7) by alice - title shares `crash`, `uploading`, `avatar`", post.Message)
+	})
+
+	t.Run("no plausible match", func(t *testing.T) {
+		p, posts := setup(t)
+
+		other := *event
+		other.Issue = &github.Issue{Number: github.Int(13), Title: github.String("Slow avatar rendering")}
+		p.postRelatedPullRequests(context.Background(), sub, &other, root)
+		assert.Len(t, getQueries(), 1)
+		assert.Empty(t, *posts)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		p, posts := setup(t)
+		lock.Lock()
+		delay = 200 * time.Millisecond
+		lock.Unlock()
+		defer func() {
+			lock.Lock()
+			delay = 0
+			lock.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		p.postRelatedPullRequests(ctx, sub, event, root)
+		assert.Less(t, int64(time.Since(start)), int64(150*time.Millisecond))
+		assert.Empty(t, *posts)
+
+		select {
+		case <-slowDone:
+		case <-time.After(time.Second):
+			t.Fatal("the search handler didn't return")
+		}
+	})
+
+	t.Run("creator not connected", func(t *testing.T) {
+		p, posts := setup(t)
+
+		stranger := *sub
+		stranger.CreatorID = "strangerID"
+		p.postRelatedPullRequests(context.Background(), &stranger, event, root)
+		assert.Empty(t, getQueries())
+		assert.Empty(t, *posts)
+	})
+}
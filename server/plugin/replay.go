@@ -0,0 +1,255 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// recentDeliveriesKey holds the IDs of the kept deliveries, newest first. Each delivery is stored
+	// under its own key, so that keeping one doesn't rewrite the others.
+	recentDeliveriesKey = "recent_deliveries"
+	recentDeliveryKey   = "_recentdelivery"
+
+	// maxRecentDeliveries is the number of received webhook deliveries kept for replaying them.
+	maxRecentDeliveries = 20
+	// maxRecentDeliveryBodySize is the size in bytes past which the body of a kept delivery is truncated.
+	maxRecentDeliveryBodySize = 16 * 1024
+	// recentDeliveryTTL is how long a kept delivery is stored, in case it couldn't be deleted when
+	// newer ones replaced it.
+	recentDeliveryTTL = 7 * 24 * time.Hour
+	// recentDeliveriesUpdateAttempts is the number of times a concurrent update of the deliveries is retried.
+	recentDeliveriesUpdateAttempts = 5
+)
+
+// recentDelivery is a webhook delivery received recently.
+type recentDelivery struct {
+	DeliveryID string    `json:"delivery_id"`
+	Event      string    `json:"event"`
+	ReceivedAt time.Time `json:"received_at"`
+	Size       int       `json:"size"`
+	// Body is the payload of the delivery, truncated past maxRecentDeliveryBodySize bytes.
+	Body      string `json:"body"`
+	Truncated bool   `json:"truncated"`
+}
+
+// replayRequest is a webhook delivery to replay, as copied from the delivery page of GitHub.
+type replayRequest struct {
+	// Headers are the headers of the delivery. Only X-GitHub-Event and X-GitHub-Delivery are used.
+	Headers map[string]string `json:"headers"`
+	Payload json.RawMessage   `json:"payload"`
+}
+
+// markReplayed returns a copy of a post marked as created by a replayed event.
+func markReplayed(post *model.Post) *model.Post {
+	props := model.StringInterface{}
+	for key, value := range post.GetProps() {
+		props[key] = value
+	}
+	props[postPropReplayed] = true
+
+	replayedPost := post.Clone()
+	replayedPost.SetProps(props)
+	return replayedPost
+}
+
+func recentDeliveryKeyFor(deliveryID string) string {
+	return hashKey(recentDeliveryKey, deliveryID)
+}
+
+// getRecentDeliveryIDs returns the IDs of the kept deliveries, newest first, along with the stored
+// value they were read from.
+func (p *Plugin) getRecentDeliveryIDs() ([]string, []byte, error) {
+	value, appErr := p.API.KVGet(recentDeliveriesKey)
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "could not get recent deliveries from KV store")
+	}
+
+	deliveryIDs := []string{}
+	if value == nil {
+		return deliveryIDs, nil, nil
+	}
+
+	if err := json.Unmarshal(value, &deliveryIDs); err != nil {
+		return nil, nil, errors.Wrap(err, "could not unmarshal recent deliveries")
+	}
+
+	return deliveryIDs, value, nil
+}
+
+// getRecentDeliveries returns the kept deliveries, newest first.
+func (p *Plugin) getRecentDeliveries() ([]*recentDelivery, error) {
+	deliveryIDs, _, err := p.getRecentDeliveryIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []*recentDelivery{}
+	for _, deliveryID := range deliveryIDs {
+		value, appErr := p.API.KVGet(recentDeliveryKeyFor(deliveryID))
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "could not get recent delivery from KV store")
+		}
+		if value == nil {
+			continue
+		}
+
+		var delivery *recentDelivery
+		if err := json.Unmarshal(value, &delivery); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal recent delivery")
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// addRecentDelivery keeps a delivery, dropping the oldest one when maxRecentDeliveries are kept.
+func (p *Plugin) addRecentDelivery(delivery *recentDelivery) error {
+	value, err := json.Marshal(delivery)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal recent delivery")
+	}
+	if appErr := p.API.KVSetWithExpiry(recentDeliveryKeyFor(delivery.DeliveryID), value, int64(recentDeliveryTTL/time.Second)); appErr != nil {
+		return errors.Wrap(appErr, "could not store recent delivery in KV store")
+	}
+
+	for attempt := 0; attempt < recentDeliveriesUpdateAttempts; attempt++ {
+		deliveryIDs, oldValue, err := p.getRecentDeliveryIDs()
+		if err != nil {
+			return err
+		}
+
+		deliveryIDs = append([]string{delivery.DeliveryID}, deliveryIDs...)
+		var dropped []string
+		if len(deliveryIDs) > maxRecentDeliveries {
+			dropped = deliveryIDs[maxRecentDeliveries:]
+			deliveryIDs = deliveryIDs[:maxRecentDeliveries]
+		}
+
+		newValue, err := json.Marshal(deliveryIDs)
+		if err != nil {
+			return errors.Wrap(err, "could not marshal recent deliveries")
+		}
+
+		stored, appErr := p.API.KVCompareAndSet(recentDeliveriesKey, oldValue, newValue)
+		if appErr != nil {
+			return errors.Wrap(appErr, "could not store recent deliveries in KV store")
+		}
+		if !stored {
+			continue
+		}
+
+		for _, deliveryID := range dropped {
+			if appErr := p.API.KVDelete(recentDeliveryKeyFor(deliveryID)); appErr != nil {
+				p.API.LogWarn("Failed to delete recent delivery", "delivery", deliveryID, "error", appErr.Error())
+			}
+		}
+		return nil
+	}
+
+	return errors.New("recent deliveries were changed concurrently too many times")
+}
+
+// newRecentDelivery returns the delivery to keep of a received one, or nil if event replay is disabled.
+func (p *Plugin) newRecentDelivery(eventType, deliveryID string, body []byte) *recentDelivery {
+	if !p.getConfiguration().EnableEventReplay {
+		return nil
+	}
+
+	delivery := &recentDelivery{
+		DeliveryID: deliveryID,
+		Event:      eventType,
+		ReceivedAt: time.Now().UTC(),
+		Size:       len(body),
+		Body:       string(body),
+	}
+	if len(body) > maxRecentDeliveryBodySize {
+		delivery.Body = string(body[:maxRecentDeliveryBodySize])
+		delivery.Truncated = true
+	}
+
+	return delivery
+}
+
+// recordRecentDelivery keeps a received delivery, if there is one to keep. It's called by the
+// workers of the webhook queue, so that GitHub isn't kept waiting while it's stored.
+func (p *Plugin) recordRecentDelivery(delivery *recentDelivery) {
+	if delivery == nil {
+		return
+	}
+
+	if err := p.addRecentDelivery(delivery); err != nil {
+		p.API.LogWarn("Failed to record recent delivery", "delivery", delivery.DeliveryID, "error", err.Error())
+	}
+}
+
+// checkEventReplayAllowed rejects the requests of users other than system admins, and all requests
+// when event replay is disabled.
+func (p *Plugin) checkEventReplayAllowed(handler HTTPHandlerFuncWithUser) HTTPHandlerFuncWithUser {
+	return func(w http.ResponseWriter, r *http.Request, userID string) {
+		if !p.getConfiguration().EnableEventReplay {
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Event replay is disabled. A system admin can enable it in the plugin settings.", StatusCode: http.StatusForbidden})
+			return
+		}
+
+		if !p.isSystemAdmin(userID) {
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Only system administrators can replay events.", StatusCode: http.StatusForbidden})
+			return
+		}
+
+		handler(w, r, userID)
+	}
+}
+
+func (p *Plugin) replayEvent(w http.ResponseWriter, r *http.Request, userID string) {
+	req := &replayRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a JSON object.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	headers := http.Header{}
+	for name, value := range req.Headers {
+		headers.Set(name, value)
+	}
+
+	eventType := headers.Get("X-GitHub-Event")
+	if eventType == "" {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide the X-GitHub-Event header of the delivery.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if len(req.Payload) == 0 {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide the payload of the delivery.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	deliveryID := headers.Get("X-GitHub-Delivery")
+	p.API.LogInfo("Replaying webhook event", "event", eventType, "delivery", deliveryID, "userID", userID)
+
+	if err := p.processWebhookEvent(eventType, deliveryID, req.Payload, true); err != nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to parse the payload: " + err.Error(), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	p.writeJSON(w, map[string]string{
+		"event":       eventType,
+		"delivery_id": deliveryID,
+	})
+}
+
+func (p *Plugin) getRecentDeliveriesList(w http.ResponseWriter, r *http.Request, userID string) {
+	deliveries, err := p.getRecentDeliveries()
+	if err != nil {
+		p.API.LogWarn("Failed to get recent deliveries", "error", err.Error())
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to get the recent deliveries.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, deliveries)
+}
@@ -0,0 +1,192 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkReplayed(t *testing.T) {
+	post := &model.Post{Message: "message", Props: model.StringInterface{postPropEventType: "create"}}
+
+	replayed := markReplayed(post)
+	assert.Equal(t, true, replayed.GetProp(postPropReplayed))
+	assert.Equal(t, "create", replayed.GetProp(postPropEventType))
+	assert.Equal(t, "message", replayed.Message)
+
+	assert.Nil(t, post.GetProp(postPropReplayed))
+}
+
+func TestRecordRecentDelivery(t *testing.T) {
+	t.Run("replay disabled", func(t *testing.T) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{})
+		api := &plugintest.API{}
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		delivery := p.newRecentDelivery("push", "deliveryID", []byte("{}"))
+		assert.Nil(t, delivery)
+		p.recordRecentDelivery(delivery)
+	})
+
+	t.Run("oldest deliveries are dropped", func(t *testing.T) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EnableEventReplay: true})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(recentDeliveryTTL/time.Second)).Return(func(key string, value []byte, _ int64) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("KVDelete", mock.AnythingOfType("string")).Return(func(key string) *model.AppError {
+			delete(store, key)
+			return nil
+		})
+		p.SetAPI(api)
+
+		for i := 0; i < maxRecentDeliveries+5; i++ {
+			p.recordRecentDelivery(p.newRecentDelivery("push", "delivery"+string(rune('A'+i)), []byte("{}")))
+		}
+		p.recordRecentDelivery(p.newRecentDelivery("push", "large", []byte(strings.Repeat("a", maxRecentDeliveryBodySize+1))))
+
+		deliveries, err := p.getRecentDeliveries()
+		require.NoError(t, err)
+		require.Len(t, deliveries, maxRecentDeliveries)
+
+		assert.Equal(t, "large", deliveries[0].DeliveryID)
+		assert.True(t, deliveries[0].Truncated)
+		assert.Len(t, deliveries[0].Body, maxRecentDeliveryBodySize)
+		assert.Equal(t, maxRecentDeliveryBodySize+1, deliveries[0].Size)
+
+		assert.Equal(t, "delivery"+string(rune('A'+maxRecentDeliveries+4)), deliveries[1].DeliveryID)
+		assert.False(t, deliveries[1].Truncated)
+		assert.Equal(t, "{}", deliveries[1].Body)
+
+		// The dropped deliveries are deleted
+		assert.Nil(t, store[recentDeliveryKeyFor("deliveryA")])
+		assert.Len(t, store, maxRecentDeliveries+1)
+	})
+
+	t.Run("recorded by the webhook queue", func(t *testing.T) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{WebhookSecret: testWebhookSecret, EnableEventReplay: true})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		api.On("KVSetWithExpiry", recentDeliveryKeyFor("deliveryID"), mock.Anything, int64(recentDeliveryTTL/time.Second)).Return(func(key string, value []byte, _ int64) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		recorded := make(chan *recentDelivery, 1)
+		p.webhookQueue = newWebhookQueue(1, 10, func(job *webhookJob) {
+			p.recordRecentDelivery(job.Recent)
+			recorded <- job.Recent
+		}, nil)
+
+		body := []byte(`{"zen": "Keep it logically awesome."}`)
+		req := newWebhookRequest(bytes.NewReader(body), signWebhookBody(body))
+		req.Header.Set("X-GitHub-Delivery", "deliveryID")
+		w := httptest.NewRecorder()
+		p.handleWebhook(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		delivery := <-recorded
+		require.True(t, p.webhookQueue.close(time.Second))
+		assert.Equal(t, "deliveryID", delivery.DeliveryID)
+		assert.Equal(t, string(body), delivery.Body)
+		assert.Equal(t, `["deliveryID"]`, string(store[recentDeliveriesKey]))
+	})
+}
+
+func TestCheckEventReplayAllowed(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request, userID string) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	for name, tc := range map[string]struct {
+		enabled        bool
+		isAdmin        bool
+		expectedStatus int
+	}{
+		"replay disabled": {enabled: false, isAdmin: true, expectedStatus: http.StatusForbidden},
+		"not an admin":    {enabled: true, isAdmin: false, expectedStatus: http.StatusForbidden},
+		"allowed":         {enabled: true, isAdmin: true, expectedStatus: http.StatusOK},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := NewPlugin()
+			p.setConfiguration(&Configuration{EnableEventReplay: tc.enabled})
+			api := &plugintest.API{}
+			api.On("HasPermissionTo", "userID", model.PERMISSION_MANAGE_SYSTEM).Return(tc.isAdmin)
+			p.SetAPI(api)
+
+			w := httptest.NewRecorder()
+			p.checkEventReplayAllowed(handler)(w, httptest.NewRequest(http.MethodPost, "/api/v1/admin/replay", nil), "userID")
+			assert.Equal(t, tc.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestReplayEvent(t *testing.T) {
+	subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "channelID", Features: featureCreates, Repository: "owner/repo"},
+		},
+	}})
+	require.NoError(t, err)
+
+	t.Run("event replayed", func(t *testing.T) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{EnableEventReplay: true})
+		api := &plugintest.API{}
+		api.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+		api.On("KVGet", SubscriptionsKey).Return(subscriptions, nil)
+		api.On("KVGet", RoutesKey).Return(nil, nil)
+		api.On("LogInfo", "Replaying webhook event", "event", "create", "delivery", "deliveryID", "userID", "userID")
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channelID" && post.GetProp(postPropReplayed) == true
+		})).Return(&model.Post{}, nil).Once()
+		logged := mockDeliveryLogs(api)
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		body := `{
+			"headers": {"x-github-event": "create", "X-GitHub-Delivery": "deliveryID"},
+			"payload": {"ref": "feature", "ref_type": "branch", "repository": {"full_name": "owner/repo"}, "sender": {"login": "alice"}}
+		}`
+		w := httptest.NewRecorder()
+		p.replayEvent(w, httptest.NewRequest(http.MethodPost, "/api/v1/admin/replay", strings.NewReader(body)), "userID")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		require.Len(t, logged(), 1)
+		summary := logged()[0]
+		assert.Equal(t, "deliveryID", summary.Context["delivery"])
+		assert.Equal(t, true, summary.Context["replayed"])
+		assert.Equal(t, 1, summary.Context["posted"])
+	})
+
+	t.Run("missing event type", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		p.SetAPI(api)
+
+		w := httptest.NewRecorder()
+		body := `{"headers": {}, "payload": {}}`
+		p.replayEvent(w, httptest.NewRequest(http.MethodPost, "/api/v1/admin/replay", strings.NewReader(body)), "userID")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,90 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxResolvedLogins caps the number of GitHub logins resolved by a single request.
+const maxResolvedLogins = 50
+
+// ResolvedUser is the Mattermost user a GitHub login maps to.
+type ResolvedUser struct {
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// isDiscoverable checks if the GitHub login of a user may be resolved to them for other users.
+// Users who get notifications are already exposed through them.
+func (s *UserSettings) isDiscoverable() bool {
+	if s.Notifications {
+		return true
+	}
+
+	return s.Discoverable == nil || *s.Discoverable
+}
+
+// resolveGitHubLogin returns the Mattermost user a GitHub login maps to, or nil if there is none
+// or the user isn't discoverable. Only the KV store and the Mattermost server are queried.
+func (p *Plugin) resolveGitHubLogin(login string) *ResolvedUser {
+	userID := p.getGitHubToUserIDMapping(login)
+	if userID == "" {
+		return nil
+	}
+
+	infoBytes, appErr := p.API.KVGet(userID + githubTokenKey)
+	if appErr != nil || infoBytes == nil {
+		return nil
+	}
+
+	// The token isn't needed, so it's left encrypted
+	var info GitHubUserInfo
+	if err := json.Unmarshal(infoBytes, &info); err != nil {
+		p.API.LogWarn("Failed to unmarshal GitHub user info", "userID", userID, "error", err.Error())
+		return nil
+	}
+	if info.Settings == nil || !info.Settings.isDiscoverable() {
+		return nil
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return nil
+	}
+
+	return &ResolvedUser{UserID: user.Id, Username: user.Username}
+}
+
+func (p *Plugin) resolveUsers(w http.ResponseWriter, r *http.Request, _ string) {
+	type ResolveUsersRequest struct {
+		Logins []string `json:"logins"`
+	}
+
+	req := &ResolveUsersRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a JSON object with a logins field.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	if len(req.Logins) > maxResolvedLogins {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: fmt.Sprintf("Please provide at most %d logins.", maxResolvedLogins), StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	resolved := map[string]*ResolvedUser{}
+	for _, login := range req.Logins {
+		login = strings.TrimSpace(login)
+		if login == "" {
+			continue
+		}
+		if _, ok := resolved[login]; ok {
+			continue
+		}
+
+		resolved[login] = p.resolveGitHubLogin(login)
+	}
+
+	p.writeJSON(w, resolved)
+}
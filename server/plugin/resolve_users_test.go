@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestResolveUsers(t *testing.T) {
+	off := false
+
+	p := NewPlugin()
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	for login, settings := range map[string]*UserSettings{
+		"alice": {Notifications: true},
+		"bob":   {Notifications: false},
+		"carol": {Notifications: false, Discoverable: &off},
+		"dave":  {Notifications: true, Discoverable: &off},
+	} {
+		userID := login + "ID"
+		info, err := json.Marshal(&GitHubUserInfo{UserID: userID, GitHubUsername: login, Token: &oauth2.Token{AccessToken: "encrypted"}, Settings: settings})
+		require.NoError(t, err)
+		store[userID+githubTokenKey] = info
+		store[login+githubUsernameKey] = []byte(userID)
+		api.On("GetUser", userID).Return(&model.User{Id: userID, Username: "mm-" + login}, nil)
+	}
+	p.SetAPI(api)
+
+	resolve := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		p.resolveUsers(w, httptest.NewRequest(http.MethodPost, "/api/v1/resolve_users", strings.NewReader(body)), "userID")
+		return w
+	}
+
+	t.Run("mapped and unmapped logins", func(t *testing.T) {
+		w := resolve(`{"logins": ["alice", "bob", "unknown"]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{
+			"alice": {"user_id": "aliceID", "username": "mm-alice"},
+			"bob": {"user_id": "bobID", "username": "mm-bob"},
+			"unknown": null
+		}`, w.Body.String())
+	})
+
+	t.Run("opted out", func(t *testing.T) {
+		w := resolve(`{"logins": ["carol", "dave"]}`)
+		require.Equal(t, http.StatusOK, w.Code)
+		// Users getting notifications are resolved anyway
+		assert.JSONEq(t, `{"carol": null, "dave": {"user_id": "daveID", "username": "mm-dave"}}`, w.Body.String())
+	})
+
+	t.Run("too many logins", func(t *testing.T) {
+		logins := []string{}
+		for i := 0; i <= maxResolvedLogins; i++ {
+			logins = append(logins, fmt.Sprintf("%q", fmt.Sprintf("user%d", i)))
+		}
+
+		w := resolve(`{"logins": [` + strings.Join(logins, ",") + `]}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Please provide at most 50 logins.")
+	})
+}
@@ -0,0 +1,259 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-api/cluster"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	postRetryQueueKey = "post_retry_queue"
+	postRetryJobKey   = "post_retry"
+	postRetryMutexKey = "post_retry_mutex"
+
+	postRetryJobInterval = time.Minute
+	// postRetryBaseDelay is the delay before the first retry. It doubles with every attempt, up to postRetryMaxDelay.
+	postRetryBaseDelay = time.Minute
+	postRetryMaxDelay  = time.Hour
+	// postRetryMaxAge is how long a post is retried before giving up on it.
+	postRetryMaxAge = 24 * time.Hour
+	// postRetryMaxQueueSize caps the number of queued posts. The oldest ones are dropped first.
+	postRetryMaxQueueSize = 500
+	// postRetryUpdateAttempts is the number of times a concurrent update of the queue is retried.
+	postRetryUpdateAttempts = 5
+)
+
+// queuedPost is a subscription post that failed to be created and waits to be retried.
+type queuedPost struct {
+	ID            string
+	ChannelID     string
+	CreatorID     string
+	Repository    string
+	Message       string
+	Type          string
+	Props         model.StringInterface `json:",omitempty"`
+	Attempts      int
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+}
+
+func (q *queuedPost) post(botUserID string) *model.Post {
+	return &model.Post{
+		UserId:    botUserID,
+		ChannelId: q.ChannelID,
+		Message:   q.Message,
+		Type:      q.Type,
+		Props:     q.Props,
+	}
+}
+
+// postRetryDelay returns the delay before the next attempt after the given number of failed attempts.
+func postRetryDelay(attempts int) time.Duration {
+	delay := postRetryBaseDelay
+	for i := 1; i < attempts && delay < postRetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > postRetryMaxDelay {
+		return postRetryMaxDelay
+	}
+
+	return delay
+}
+
+// createSubscriptionPost creates a post for a subscription, queueing it to be retried if it fails.
+// The subscriptions of an archived channel are paused instead. It returns the created post, or nil
+// if it failed.
+func (p *Plugin) createSubscriptionPost(post *model.Post, sub *Subscription) *model.Post {
+	created, appErr := p.API.CreatePost(post)
+	if appErr == nil {
+		return created
+	}
+
+	if p.pauseArchivedChannelSubscriptions(post.ChannelId) {
+		return nil
+	}
+
+	p.API.LogWarn("Error webhook post", "post", post, "error", appErr.Error())
+
+	if err := p.enqueuePostRetry(post, sub); err != nil {
+		p.API.LogWarn("Failed to queue webhook post for retry", "channelID", post.ChannelId, "error", err.Error())
+	}
+
+	return nil
+}
+
+func (p *Plugin) enqueuePostRetry(post *model.Post, sub *Subscription) error {
+	now := time.Now()
+	queued := &queuedPost{
+		ID:            model.NewId(),
+		ChannelID:     post.ChannelId,
+		CreatorID:     sub.CreatorID,
+		Repository:    sub.Repository,
+		Message:       post.Message,
+		Type:          post.Type,
+		Props:         post.GetProps(),
+		Attempts:      1,
+		CreatedAt:     now,
+		NextAttemptAt: now.Add(postRetryDelay(1)),
+	}
+
+	return p.updatePostRetryQueue(func(queue []*queuedPost) []*queuedPost {
+		queue = append(queue, queued)
+		if len(queue) > postRetryMaxQueueSize {
+			p.API.LogWarn("Post retry queue is full, dropping the oldest posts", "dropped", len(queue)-postRetryMaxQueueSize)
+			queue = queue[len(queue)-postRetryMaxQueueSize:]
+		}
+		return queue
+	})
+}
+
+func (p *Plugin) getPostRetryQueue() ([]*queuedPost, []byte, error) {
+	value, appErr := p.API.KVGet(postRetryQueueKey)
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "could not get post retry queue from KV store")
+	}
+
+	var queue []*queuedPost
+	if value != nil {
+		if err := json.Unmarshal(value, &queue); err != nil {
+			return nil, nil, errors.Wrap(err, "could not unmarshal post retry queue")
+		}
+	}
+
+	return queue, value, nil
+}
+
+// updatePostRetryQueue applies update to the stored queue, retrying if the queue is changed concurrently.
+func (p *Plugin) updatePostRetryQueue(update func(queue []*queuedPost) []*queuedPost) error {
+	for i := 0; i < postRetryUpdateAttempts; i++ {
+		queue, oldValue, err := p.getPostRetryQueue()
+		if err != nil {
+			return err
+		}
+
+		var newValue []byte
+		if queue = update(queue); len(queue) > 0 {
+			newValue, err = json.Marshal(queue)
+			if err != nil {
+				return errors.Wrap(err, "could not marshal post retry queue")
+			}
+		}
+
+		ok, appErr := p.API.KVCompareAndSet(postRetryQueueKey, oldValue, newValue)
+		if appErr != nil {
+			return errors.Wrap(appErr, "could not store post retry queue")
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	return errors.New("post retry queue was changed concurrently too many times")
+}
+
+// drainPostRetryQueue retries the queued posts that are due, making sure only one server of a cluster does it at a time.
+func (p *Plugin) drainPostRetryQueue() {
+	mutex, err := cluster.NewMutex(p.API, postRetryMutexKey)
+	if err != nil {
+		p.API.LogWarn("Failed to create post retry mutex", "error", err.Error())
+		return
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	p.retryQueuedPosts()
+}
+
+// retryQueuedPosts retries the queued posts that are due. Posts to deleted channels are dropped, and
+// the subscription creator is notified of posts that could not be delivered within postRetryMaxAge.
+func (p *Plugin) retryQueuedPosts() {
+	queue, _, err := p.getPostRetryQueue()
+	if err != nil {
+		p.API.LogWarn("Failed to get post retry queue", "error", err.Error())
+		return
+	}
+
+	now := time.Now()
+	done := map[string]bool{}
+	rescheduled := map[string]*queuedPost{}
+	for _, queued := range queue {
+		if now.Before(queued.NextAttemptAt) {
+			continue
+		}
+
+		if !p.isChannelActive(queued.ChannelID) {
+			done[queued.ID] = true
+			continue
+		}
+
+		if _, appErr := p.API.CreatePost(queued.post(p.BotUserID)); appErr == nil {
+			done[queued.ID] = true
+			continue
+		}
+
+		queued.Attempts++
+		if now.Sub(queued.CreatedAt) >= postRetryMaxAge {
+			p.notifyPostDeliveryFailure(queued)
+			done[queued.ID] = true
+			continue
+		}
+
+		queued.NextAttemptAt = now.Add(postRetryDelay(queued.Attempts))
+		rescheduled[queued.ID] = queued
+	}
+
+	if len(done) == 0 && len(rescheduled) == 0 {
+		return
+	}
+
+	err = p.updatePostRetryQueue(func(queue []*queuedPost) []*queuedPost {
+		var remaining []*queuedPost
+		for _, queued := range queue {
+			if done[queued.ID] {
+				continue
+			}
+			if updated, ok := rescheduled[queued.ID]; ok {
+				queued = updated
+			}
+			remaining = append(remaining, queued)
+		}
+		return remaining
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to update post retry queue", "error", err.Error())
+	}
+}
+
+// isChannelActive checks that a channel still exists and isn't archived. Channels that can't be
+// checked for another reason are considered active so that their posts are retried.
+func (p *Plugin) isChannelActive(channelID string) bool {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return appErr.StatusCode != http.StatusNotFound
+	}
+
+	return channel.DeleteAt == 0
+}
+
+func (p *Plugin) notifyPostDeliveryFailure(queued *queuedPost) {
+	p.API.LogWarn("Giving up on webhook post", "channelID", queued.ChannelID, "repo", queued.Repository, "attempts", queued.Attempts)
+
+	if queued.CreatorID == "" {
+		return
+	}
+
+	channelName := queued.ChannelID
+	if channel, appErr := p.API.GetChannel(queued.ChannelID); appErr == nil {
+		channelName = "~" + channel.Name
+	}
+
+	message := fmt.Sprintf("A notification for your subscription to %s could not be delivered to %s after %d attempts.", queued.Repository, channelName, queued.Attempts)
+	p.CreateBotDMPost(queued.CreatorID, message, "")
+}
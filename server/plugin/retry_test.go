@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Minute, postRetryDelay(1))
+	assert.Equal(t, 2*time.Minute, postRetryDelay(2))
+	assert.Equal(t, 4*time.Minute, postRetryDelay(3))
+	assert.Equal(t, 32*time.Minute, postRetryDelay(6))
+	assert.Equal(t, time.Hour, postRetryDelay(7))
+	assert.Equal(t, time.Hour, postRetryDelay(100))
+}
+
+// matchQueue matches a stored post retry queue against the given check.
+func matchQueue(t *testing.T, check func(queue []*queuedPost) bool) interface{} {
+	return mock.MatchedBy(func(value []byte) bool {
+		var queue []*queuedPost
+		require.NoError(t, json.Unmarshal(value, &queue))
+		return check(queue)
+	})
+}
+
+func TestCreateSubscriptionPost(t *testing.T) {
+	sub := &Subscription{ChannelID: "channelID", CreatorID: "creatorID", Repository: "owner/repo"}
+
+	t.Run("post created", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		post := &model.Post{ChannelId: "channelID", Message: "message"}
+		api.On("CreatePost", post).Return(post, nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.createSubscriptionPost(post, sub)
+	})
+
+	t.Run("post queued on failure", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		post := &model.Post{ChannelId: "channelID", Message: "message", Type: "custom_git_pr"}
+		api.On("CreatePost", post).Return(nil, &model.AppError{Message: "failed"}).Once()
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID"}, nil).Once()
+		api.On("LogWarn", "Error webhook post", "post", post, "error", mock.Anything).Once()
+		api.On("KVGet", postRetryQueueKey).Return(nil, nil).Once()
+		api.On("KVCompareAndSet", postRetryQueueKey, []byte(nil), matchQueue(t, func(queue []*queuedPost) bool {
+			return len(queue) == 1 &&
+				queue[0].ChannelID == "channelID" &&
+				queue[0].CreatorID == "creatorID" &&
+				queue[0].Repository == "owner/repo" &&
+				queue[0].Message == "message" &&
+				queue[0].Type == "custom_git_pr" &&
+				queue[0].Attempts == 1 &&
+				queue[0].NextAttemptAt.After(time.Now())
+		})).Return(true, nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.createSubscriptionPost(post, sub)
+	})
+}
+
+func TestRetryQueuedPosts(t *testing.T) {
+	now := time.Now()
+
+	setupPlugin := func(t *testing.T, queued *queuedPost) (*Plugin, *plugintest.API, []byte) {
+		value, err := json.Marshal([]*queuedPost{queued})
+		require.NoError(t, err)
+
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		api.On("KVGet", postRetryQueueKey).Return(value, nil)
+		p.SetAPI(api)
+
+		return p, api, value
+	}
+
+	isQueuedPost := func(post *model.Post) bool {
+		return post.ChannelId == "channelID"
+	}
+
+	t.Run("not due yet", func(t *testing.T) {
+		p, api, _ := setupPlugin(t, &queuedPost{ID: "id", ChannelID: "channelID", Attempts: 1, CreatedAt: now, NextAttemptAt: now.Add(time.Minute)})
+		defer api.AssertExpectations(t)
+
+		p.retryQueuedPosts()
+	})
+
+	t.Run("successful retry", func(t *testing.T) {
+		p, api, value := setupPlugin(t, &queuedPost{ID: "id", ChannelID: "channelID", Message: "message", Attempts: 1, CreatedAt: now, NextAttemptAt: now})
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID"}, nil)
+		api.On("CreatePost", &model.Post{UserId: "botID", ChannelId: "channelID", Message: "message"}).Return(&model.Post{}, nil).Once()
+		api.On("KVCompareAndSet", postRetryQueueKey, value, []byte(nil)).Return(true, nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.retryQueuedPosts()
+	})
+
+	t.Run("failed retry is rescheduled", func(t *testing.T) {
+		p, api, value := setupPlugin(t, &queuedPost{ID: "id", ChannelID: "channelID", Attempts: 1, CreatedAt: now, NextAttemptAt: now})
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID"}, nil)
+		api.On("CreatePost", mock.MatchedBy(isQueuedPost)).Return(nil, &model.AppError{Message: "failed"}).Once()
+		api.On("KVCompareAndSet", postRetryQueueKey, value, matchQueue(t, func(queue []*queuedPost) bool {
+			return len(queue) == 1 && queue[0].Attempts == 2 && queue[0].NextAttemptAt.After(now.Add(time.Minute))
+		})).Return(true, nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.retryQueuedPosts()
+	})
+
+	t.Run("deleted channel", func(t *testing.T) {
+		p, api, value := setupPlugin(t, &queuedPost{ID: "id", ChannelID: "channelID", Attempts: 1, CreatedAt: now, NextAttemptAt: now})
+		api.On("GetChannel", "channelID").Return(nil, &model.AppError{Message: "not found", StatusCode: http.StatusNotFound})
+		api.On("KVCompareAndSet", postRetryQueueKey, value, []byte(nil)).Return(true, nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.retryQueuedPosts()
+	})
+
+	t.Run("archived channel", func(t *testing.T) {
+		p, api, value := setupPlugin(t, &queuedPost{ID: "id", ChannelID: "channelID", Attempts: 1, CreatedAt: now, NextAttemptAt: now})
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", DeleteAt: model.GetMillis()}, nil)
+		api.On("KVCompareAndSet", postRetryQueueKey, value, []byte(nil)).Return(true, nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.retryQueuedPosts()
+	})
+
+	t.Run("giving up", func(t *testing.T) {
+		p, api, value := setupPlugin(t, &queuedPost{
+			ID:            "id",
+			ChannelID:     "channelID",
+			CreatorID:     "creatorID",
+			Repository:    "owner/repo",
+			Attempts:      10,
+			CreatedAt:     now.Add(-postRetryMaxAge - time.Minute),
+			NextAttemptAt: now,
+		})
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "town-square"}, nil)
+		api.On("CreatePost", mock.MatchedBy(isQueuedPost)).Return(nil, &model.AppError{Message: "failed"}).Once()
+		api.On("LogWarn", "Giving up on webhook post", "channelID", "channelID", "repo", "owner/repo", "attempts", 11).Once()
+		api.On("GetDirectChannel", "creatorID", "botID").Return(&model.Channel{Id: "dmChannelID"}, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "dmChannelID" &&
+				post.Message == "A notification for your subscription to owner/repo could not be delivered to ~town-square after 11 attempts."
+		})).Return(&model.Post{}, nil).Once()
+		api.On("KVCompareAndSet", postRetryQueueKey, value, []byte(nil)).Return(true, nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.retryQueuedPosts()
+	})
+}
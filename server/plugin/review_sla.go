@@ -0,0 +1,485 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	reviewSLAFlag          = "review-sla"
+	reviewSLADMFlag        = "review-sla-dm"
+	reviewRequestKey       = "_reviewrequest"
+	reviewRequestsIndexKey = "review_requests_index"
+	reviewSLAJobKey        = "review_sla"
+	reviewSLADMPostType    = "custom_git_review_sla"
+
+	// legacyReviewRequestsKey held all the pending review requests before they got a key each.
+	legacyReviewRequestsKey = "review_requests"
+
+	// reviewSLAJobInterval is how often the scheduler looks for overdue review requests.
+	reviewSLAJobInterval = 15 * time.Minute
+	// reviewRequestMaxAge is how long a pending review request is kept, in case the event
+	// completing it was missed.
+	reviewRequestMaxAge          = 30 * 24 * time.Hour
+	reviewRequestsUpdateAttempts = 5
+)
+
+// reviewRequest is a pending review request of a user on a pull request.
+type reviewRequest struct {
+	Repo        string
+	Private     bool `json:",omitempty"`
+	Number      int
+	Title       string
+	URL         string
+	Reviewer    *github.User
+	RequestedAt time.Time
+	// NudgedChannels holds when the request was listed as overdue in each channel.
+	NudgedChannels map[string]time.Time `json:",omitempty"`
+	// DMNudgedAt is when the reviewer was sent a direct message about the request being overdue.
+	DMNudgedAt time.Time
+}
+
+func (r *reviewRequest) key() string {
+	return fmt.Sprintf("%s#%d@%s", r.Repo, r.Number, r.Reviewer.GetLogin())
+}
+
+// pullRequestKeyPrefix is the prefix of the keys of the review requests of a pull request.
+func pullRequestKeyPrefix(repo string, number int) string {
+	return fmt.Sprintf("%s#%d@", repo, number)
+}
+
+func reviewRequestKeyFor(key string) string {
+	return hashKey(reviewRequestKey, key)
+}
+
+// Waiting returns for how long the review has been waiting, in hours.
+func (r *reviewRequest) Waiting(now time.Time) string {
+	return strconv.Itoa(int(now.Sub(r.RequestedAt).Hours())) + "h"
+}
+
+// parseReviewSLA parses the value of the --review-sla flag, e.g. 24h.
+func parseReviewSLA(value string) (time.Duration, error) {
+	sla, err := time.ParseDuration(value)
+	if err != nil || sla < time.Hour {
+		return 0, errors.Errorf("Invalid value %q for --%s. Use a duration of at least an hour, e.g. `24h`.", value, reviewSLAFlag)
+	}
+
+	return sla, nil
+}
+
+// getReviewRequestsIndex returns the keys of the pending review requests.
+func (p *Plugin) getReviewRequestsIndex() ([]string, []byte, error) {
+	value, appErr := p.API.KVGet(reviewRequestsIndexKey)
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "could not get review requests index from KV store")
+	}
+
+	keys := []string{}
+	if value != nil {
+		if err := json.Unmarshal(value, &keys); err != nil {
+			return nil, nil, errors.Wrap(err, "could not unmarshal review requests index")
+		}
+	}
+
+	return keys, value, nil
+}
+
+// updateReviewRequestsIndex applies update to the keys of the pending review requests, retrying if
+// they are changed concurrently.
+func (p *Plugin) updateReviewRequestsIndex(update func(keys []string) []string) error {
+	for i := 0; i < reviewRequestsUpdateAttempts; i++ {
+		keys, oldValue, err := p.getReviewRequestsIndex()
+		if err != nil {
+			return err
+		}
+
+		var newValue []byte
+		if keys = update(keys); len(keys) > 0 {
+			newValue, err = json.Marshal(keys)
+			if err != nil {
+				return errors.Wrap(err, "could not marshal review requests index")
+			}
+		}
+
+		ok, appErr := p.API.KVCompareAndSet(reviewRequestsIndexKey, oldValue, newValue)
+		if appErr != nil {
+			return errors.Wrap(appErr, "could not store review requests index")
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	return errors.New("review requests index was changed concurrently too many times")
+}
+
+// storeReviewRequest stores a pending review request until reviewRequestMaxAge after it was made.
+func (p *Plugin) storeReviewRequest(request *reviewRequest, now time.Time) error {
+	expireIn := int64(request.RequestedAt.Add(reviewRequestMaxAge).Sub(now).Seconds())
+	if expireIn <= 0 {
+		return nil
+	}
+
+	value, err := json.Marshal(request)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal review request")
+	}
+
+	if appErr := p.API.KVSetWithExpiry(reviewRequestKeyFor(request.key()), value, expireIn); appErr != nil {
+		return errors.Wrap(appErr, "could not store review request")
+	}
+
+	return nil
+}
+
+// addReviewRequest stores a pending review request, unless the reviewer already has one on the pull request.
+func (p *Plugin) addReviewRequest(request *reviewRequest, now time.Time) error {
+	value, appErr := p.API.KVGet(reviewRequestKeyFor(request.key()))
+	if appErr != nil {
+		return errors.Wrap(appErr, "could not get review request from KV store")
+	}
+	if value != nil {
+		return nil
+	}
+
+	if err := p.storeReviewRequest(request, now); err != nil {
+		return err
+	}
+
+	return p.updateReviewRequestsIndex(func(keys []string) []string {
+		if SliceContainsString(keys, request.key()) {
+			return keys
+		}
+		return append(keys, request.key())
+	})
+}
+
+// getReviewRequests returns the pending review requests. Requests that expired are dropped from the index.
+func (p *Plugin) getReviewRequests() ([]*reviewRequest, error) {
+	keys, _, err := p.getReviewRequestsIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	requests := []*reviewRequest{}
+	expired := map[string]bool{}
+	for _, key := range keys {
+		value, appErr := p.API.KVGet(reviewRequestKeyFor(key))
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "could not get review request from KV store")
+		}
+		if value == nil {
+			expired[key] = true
+			continue
+		}
+
+		var request *reviewRequest
+		if err := json.Unmarshal(value, &request); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal review request")
+		}
+		requests = append(requests, request)
+	}
+
+	if len(expired) > 0 {
+		err := p.updateReviewRequestsIndex(func(keys []string) []string {
+			kept := []string{}
+			for _, key := range keys {
+				if !expired[key] {
+					kept = append(kept, key)
+				}
+			}
+			return kept
+		})
+		if err != nil {
+			p.API.LogWarn("Failed to drop expired review requests", "error", err.Error())
+		}
+	}
+
+	return requests, nil
+}
+
+// removeReviewRequests drops the pending review requests whose key matches the given function.
+func (p *Plugin) removeReviewRequests(matches func(key string) bool) error {
+	removed := []string{}
+	err := p.updateReviewRequestsIndex(func(keys []string) []string {
+		removed = []string{}
+		kept := []string{}
+		for _, key := range keys {
+			if matches(key) {
+				removed = append(removed, key)
+			} else {
+				kept = append(kept, key)
+			}
+		}
+		return kept
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range removed {
+		if appErr := p.API.KVDelete(reviewRequestKeyFor(key)); appErr != nil {
+			return errors.Wrap(appErr, "could not delete review request")
+		}
+	}
+
+	return nil
+}
+
+// migrateReviewRequests gives the review requests stored in the legacy list a key each.
+func (p *Plugin) migrateReviewRequests(now time.Time) error {
+	value, appErr := p.API.KVGet(legacyReviewRequestsKey)
+	if appErr != nil {
+		return errors.Wrap(appErr, "could not get legacy review requests from KV store")
+	}
+	if value == nil {
+		return nil
+	}
+
+	var requests []*reviewRequest
+	if err := json.Unmarshal(value, &requests); err != nil {
+		return errors.Wrap(err, "could not unmarshal legacy review requests")
+	}
+
+	for _, request := range requests {
+		if err := p.addReviewRequest(request, now); err != nil {
+			return err
+		}
+	}
+
+	if appErr := p.API.KVDelete(legacyReviewRequestsKey); appErr != nil {
+		return errors.Wrap(appErr, "could not delete legacy review requests")
+	}
+
+	return nil
+}
+
+// hasReviewSLA checks if any of the subscriptions has a review SLA.
+func hasReviewSLA(subs []*Subscription) bool {
+	for _, sub := range subs {
+		if sub.Flags.ReviewSLA != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trackReviewRequests keeps track of the pending review requests of the pull requests of
+// repositories subscribed with a review SLA.
+func (p *Plugin) trackReviewRequests(event *github.PullRequestEvent) {
+	repo := event.GetRepo().GetFullName()
+	pr := event.GetPullRequest()
+
+	var err error
+	switch event.GetAction() {
+	case "review_requested":
+		if event.RequestedReviewer == nil || !hasReviewSLA(p.GetSubscribedChannelsForRepository(event.GetRepo())) {
+			return
+		}
+
+		request := &reviewRequest{
+			Repo:        repo,
+			Private:     event.GetRepo().GetPrivate(),
+			Number:      pr.GetNumber(),
+			Title:       pr.GetTitle(),
+			URL:         pr.GetHTMLURL(),
+			Reviewer:    &github.User{Login: github.String(event.GetRequestedReviewer().GetLogin()), HTMLURL: github.String(event.GetRequestedReviewer().GetHTMLURL())},
+			RequestedAt: time.Now().UTC(),
+		}
+		err = p.addReviewRequest(request, request.RequestedAt)
+	case "review_request_removed":
+		if event.RequestedReviewer == nil {
+			return
+		}
+
+		removed := pullRequestKeyPrefix(repo, pr.GetNumber()) + event.GetRequestedReviewer().GetLogin()
+		err = p.removeReviewRequests(func(key string) bool {
+			return key == removed
+		})
+	case "closed":
+		prefix := pullRequestKeyPrefix(repo, pr.GetNumber())
+		err = p.removeReviewRequests(func(key string) bool {
+			return strings.HasPrefix(key, prefix)
+		})
+	default:
+		return
+	}
+
+	if err != nil {
+		p.API.LogWarn("Failed to track review request", "repo", repo, "number", pr.GetNumber(), "action", event.GetAction(), "error", err.Error())
+	}
+}
+
+// completeReviewRequest drops the pending review request of the author of a submitted review.
+func (p *Plugin) completeReviewRequest(event *github.PullRequestReviewEvent) {
+	if event.GetAction() != "submitted" {
+		return
+	}
+
+	repo := event.GetRepo().GetFullName()
+	number := event.GetPullRequest().GetNumber()
+	completed := pullRequestKeyPrefix(repo, number) + event.GetReview().GetUser().GetLogin()
+	err := p.removeReviewRequests(func(key string) bool {
+		return key == completed
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to complete review request", "repo", repo, "number", number, "error", err.Error())
+	}
+}
+
+// overdueReviews is the list of overdue review requests posted to a channel.
+type overdueReviews struct {
+	SLA      time.Duration
+	Now      time.Time
+	Requests []*reviewRequest
+}
+
+// SLAHours returns the SLA in hours.
+func (o *overdueReviews) SLAHours() int {
+	return int(o.SLA.Hours())
+}
+
+// nudgeOverdueReviews posts the review requests pending for longer than the SLA of the subscriptions
+// of their repository, once per channel, and optionally sends a direct message to the reviewers.
+// Requests expire reviewRequestMaxAge after being made. It's run periodically by the scheduler.
+func (p *Plugin) nudgeOverdueReviews() {
+	now := time.Now().UTC()
+	if err := p.migrateReviewRequests(now); err != nil {
+		p.API.LogWarn("Failed to migrate review requests", "error", err.Error())
+	}
+
+	requests, err := p.getReviewRequests()
+	if err != nil {
+		p.API.LogWarn("Failed to get review requests", "error", err.Error())
+		return
+	}
+	if len(requests) == 0 {
+		return
+	}
+
+	nudgedPosts := map[string][]string{}
+	dmNudged := map[string]bool{}
+
+	// Channels subscribed to several repositories get a list per SLA
+	overdueByPost := map[string]*overdueReviews{}
+	channelByPost := map[string]string{}
+	posts := []string{}
+	for _, request := range requests {
+		repo := &github.Repository{FullName: github.String(request.Repo), Private: github.Bool(request.Private)}
+		for _, sub := range p.GetSubscribedChannelsForRepository(repo) {
+			if sub.Flags.ReviewSLA == "" {
+				continue
+			}
+
+			sla, err := parseReviewSLA(sub.Flags.ReviewSLA)
+			if err != nil {
+				p.API.LogWarn("Invalid review SLA", "repo", sub.Repository, "channelID", sub.ChannelID, "error", err.Error())
+				continue
+			}
+
+			if now.Sub(request.RequestedAt) < sla {
+				continue
+			}
+
+			if sub.Flags.ReviewSLADM && request.DMNudgedAt.IsZero() && !dmNudged[request.key()] {
+				p.sendReviewSLADM(request, now)
+				dmNudged[request.key()] = true
+			}
+
+			if _, ok := request.NudgedChannels[sub.ChannelID]; ok {
+				continue
+			}
+
+			post := sub.ChannelID + "/" + sla.String()
+			if SliceContainsString(nudgedPosts[request.key()], post) {
+				continue
+			}
+
+			overdue := overdueByPost[post]
+			if overdue == nil {
+				overdue = &overdueReviews{SLA: sla, Now: now}
+				overdueByPost[post] = overdue
+				channelByPost[post] = sub.ChannelID
+				posts = append(posts, post)
+			}
+			overdue.Requests = append(overdue.Requests, request)
+			nudgedPosts[request.key()] = append(nudgedPosts[request.key()], post)
+		}
+	}
+
+	posted := map[string]bool{}
+	for _, key := range posts {
+		message, err := renderTemplate("overdueReviews", overdueByPost[key])
+		if err != nil {
+			p.API.LogWarn("Failed to render template", "error", err.Error())
+			continue
+		}
+
+		post := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: channelByPost[key],
+			Message:   message,
+			Type:      "custom_git_overdue_reviews",
+		}
+		if _, appErr := p.API.CreatePost(post); appErr != nil {
+			p.API.LogWarn("Failed to post overdue reviews", "channelID", post.ChannelId, "error", appErr.Error())
+			continue
+		}
+		posted[key] = true
+	}
+
+	for _, request := range requests {
+		key := request.key()
+		updated := dmNudged[key]
+		if updated {
+			request.DMNudgedAt = now
+		}
+
+		for _, post := range nudgedPosts[key] {
+			if !posted[post] {
+				continue
+			}
+			if request.NudgedChannels == nil {
+				request.NudgedChannels = map[string]time.Time{}
+			}
+			request.NudgedChannels[channelByPost[post]] = now
+			updated = true
+		}
+
+		if !updated {
+			continue
+		}
+		if err := p.storeReviewRequest(request, now); err != nil {
+			p.API.LogWarn("Failed to record review nudges", "repo", request.Repo, "number", request.Number, "error", err.Error())
+		}
+	}
+}
+
+// sendReviewSLADM sends a direct message about an overdue review request to the reviewer, if
+// connected with notifications enabled.
+func (p *Plugin) sendReviewSLADM(request *reviewRequest, now time.Time) {
+	userID := p.getGitHubToUserIDMapping(request.Reviewer.GetLogin())
+	if userID == "" {
+		return
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil || !info.Settings.Notifications {
+		return
+	}
+
+	if request.Private && !p.permissionToRepo(userID, request.Repo) {
+		return
+	}
+
+	message := fmt.Sprintf("Your review of [%s#%d %s](%s) has been waiting %s.", request.Repo, request.Number, request.Title, request.URL, request.Waiting(now))
+	p.CreateBotDMPost(userID, message, reviewSLADMPostType)
+}
@@ -0,0 +1,246 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestParseReviewSLA(t *testing.T) {
+	sla, err := parseReviewSLA("24h")
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, sla)
+
+	for _, value := range []string{"", "1d", "30m", "-24h"} {
+		_, err := parseReviewSLA(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestReviewSLAFlags(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(reviewSLAFlag, "24h"))
+	flags.AddFlag(reviewSLADMFlag)
+	assert.Equal(t, "--review-sla 24h,--review-sla-dm", flags.String())
+
+	assert.Error(t, flags.SetFlag(reviewSLAFlag, "soon"))
+}
+
+func setupReviewSLA(t *testing.T, subs ...*Subscription) (*Plugin, *plugintest.API, map[string][]byte) {
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("int64")).Return(func(key string, value []byte, expireInSeconds int64) *model.AppError {
+		store[key] = value
+		return nil
+	}).Maybe()
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(func(key string) *model.AppError {
+		delete(store, key)
+		return nil
+	}).Maybe()
+	p.SetAPI(api)
+
+	subscriptions := &Subscriptions{Repositories: map[string][]*Subscription{}}
+	for _, sub := range subs {
+		subscriptions.Repositories[sub.Repository] = append(subscriptions.Repositories[sub.Repository], sub)
+	}
+	value, err := json.Marshal(subscriptions)
+	require.NoError(t, err)
+	store[SubscriptionsKey] = value
+
+	return p, api, store
+}
+
+func storedReviewRequests(t *testing.T, p *Plugin) []*reviewRequest {
+	requests, err := p.getReviewRequests()
+	require.NoError(t, err)
+	return requests
+}
+
+func reviewRequestEvent(action, reviewer string) *github.PullRequestEvent {
+	return &github.PullRequestEvent{
+		Action: github.String(action),
+		PullRequest: &github.PullRequest{
+			Number:  github.Int(123),
+			Title:   github.String("Fix the crash"),
+			HTMLURL: github.String("https://github.com/owner/repo/pull/123"),
+		},
+		RequestedReviewer: &github.User{Login: github.String(reviewer), HTMLURL: github.String("https://github.com/" + reviewer)},
+		Repo:              &github.Repository{FullName: github.String("owner/repo")},
+	}
+}
+
+func TestTrackReviewRequests(t *testing.T) {
+	sub := &Subscription{ChannelID: "channelID", Repository: "owner/repo", Features: featurePulls, Flags: SubscriptionFlags{ReviewSLA: "24h"}}
+
+	t.Run("request then review", func(t *testing.T) {
+		p, _, _ := setupReviewSLA(t, sub)
+
+		p.trackReviewRequests(reviewRequestEvent("review_requested", "alice"))
+		p.trackReviewRequests(reviewRequestEvent("review_requested", "bob"))
+		p.trackReviewRequests(reviewRequestEvent("review_requested", "alice"))
+		requests := storedReviewRequests(t, p)
+		require.Len(t, requests, 2)
+		assert.Equal(t, "alice", requests[0].Reviewer.GetLogin())
+		assert.Equal(t, 123, requests[0].Number)
+
+		p.completeReviewRequest(&github.PullRequestReviewEvent{
+			Action:      github.String("submitted"),
+			Review:      &github.PullRequestReview{User: &github.User{Login: github.String("alice")}},
+			PullRequest: &github.PullRequest{Number: github.Int(123)},
+			Repo:        &github.Repository{FullName: github.String("owner/repo")},
+		})
+		requests = storedReviewRequests(t, p)
+		require.Len(t, requests, 1)
+		assert.Equal(t, "bob", requests[0].Reviewer.GetLogin())
+	})
+
+	t.Run("request then removal", func(t *testing.T) {
+		p, _, _ := setupReviewSLA(t, sub)
+
+		p.trackReviewRequests(reviewRequestEvent("review_requested", "alice"))
+		p.trackReviewRequests(reviewRequestEvent("review_request_removed", "alice"))
+		assert.Empty(t, storedReviewRequests(t, p))
+	})
+
+	t.Run("pull request closed", func(t *testing.T) {
+		p, _, _ := setupReviewSLA(t, sub)
+
+		p.trackReviewRequests(reviewRequestEvent("review_requested", "alice"))
+		p.trackReviewRequests(reviewRequestEvent("review_requested", "bob"))
+		p.trackReviewRequests(reviewRequestEvent("closed", ""))
+		assert.Empty(t, storedReviewRequests(t, p))
+	})
+
+	t.Run("no subscription with a review SLA", func(t *testing.T) {
+		p, _, _ := setupReviewSLA(t, &Subscription{ChannelID: "channelID", Repository: "owner/repo", Features: featurePulls})
+
+		p.trackReviewRequests(reviewRequestEvent("review_requested", "alice"))
+		assert.Empty(t, storedReviewRequests(t, p))
+	})
+}
+
+func TestNudgeOverdueReviews(t *testing.T) {
+	now := time.Now().UTC()
+	storeRequests := func(t *testing.T, store map[string][]byte, requests ...*reviewRequest) {
+		keys := []string{}
+		for _, request := range requests {
+			value, err := json.Marshal(request)
+			require.NoError(t, err)
+			store[reviewRequestKeyFor(request.key())] = value
+			keys = append(keys, request.key())
+		}
+		value, err := json.Marshal(keys)
+		require.NoError(t, err)
+		store[reviewRequestsIndexKey] = value
+	}
+	request := func(reviewer string, requestedAt time.Time) *reviewRequest {
+		return &reviewRequest{
+			Repo:        "owner/repo",
+			Number:      123,
+			Title:       "Fix the crash",
+			URL:         "https://github.com/owner/repo/pull/123",
+			Reviewer:    &github.User{Login: github.String(reviewer), HTMLURL: github.String("https://github.com/" + reviewer)},
+			RequestedAt: requestedAt,
+		}
+	}
+
+	t.Run("overdue requests posted once", func(t *testing.T) {
+		p, api, store := setupReviewSLA(t, &Subscription{ChannelID: "channelID", Repository: "owner/repo", Flags: SubscriptionFlags{ReviewSLA: "24h"}})
+		storeRequests(t, store, request("alice", now.Add(-31*time.Hour-time.Minute)), request("bob", now.Add(-2*time.Hour)))
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channelID" && post.Message == "\n#### Reviews waiting for more than 24h\n"+
+				"* [alice](https://github.com/alice) has had [owner/repo#123 Fix the crash](https://github.com/owner/repo/pull/123) waiting 31h\n"
+		})).Return(&model.Post{}, nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.nudgeOverdueReviews()
+		requests := storedReviewRequests(t, p)
+		require.Len(t, requests, 2)
+		assert.Contains(t, requests[0].NudgedChannels, "channelID")
+		assert.Empty(t, requests[1].NudgedChannels)
+
+		// The same requests aren't listed again
+		p.nudgeOverdueReviews()
+	})
+
+	t.Run("expired requests dropped", func(t *testing.T) {
+		p, api, store := setupReviewSLA(t, &Subscription{ChannelID: "channelID", Repository: "owner/repo", Flags: SubscriptionFlags{ReviewSLA: "24h"}})
+		storeRequests(t, store, request("alice", now.Add(-reviewRequestMaxAge-time.Hour)), request("bob", now.Add(-time.Hour)))
+		delete(store, reviewRequestKeyFor(request("alice", now).key()))
+
+		p.nudgeOverdueReviews()
+		assert.Equal(t, `["owner/repo#123@bob"]`, string(store[reviewRequestsIndexKey]))
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("own SLA of each subscription", func(t *testing.T) {
+		p, api, store := setupReviewSLA(t,
+			&Subscription{ChannelID: "channelID", Repository: "owner/repo", Flags: SubscriptionFlags{ReviewSLA: "48h"}},
+			&Subscription{ChannelID: "channelID", Repository: "owner/other", Flags: SubscriptionFlags{ReviewSLA: "24h"}},
+		)
+		other := request("bob", now.Add(-25*time.Hour))
+		other.Repo = "owner/other"
+		storeRequests(t, store, request("alice", now.Add(-49*time.Hour)), other)
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channelID" && strings.HasPrefix(post.Message, "\n#### Reviews waiting for more than 48h\n* [alice]") && !strings.Contains(post.Message, "bob")
+		})).Return(&model.Post{}, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channelID" && strings.HasPrefix(post.Message, "\n#### Reviews waiting for more than 24h\n* [bob]")
+		})).Return(&model.Post{}, nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.nudgeOverdueReviews()
+	})
+
+	t.Run("legacy requests migrated", func(t *testing.T) {
+		p, _, store := setupReviewSLA(t)
+		value, err := json.Marshal([]*reviewRequest{request("alice", now.Add(-time.Hour)), request("bob", now.Add(-time.Hour))})
+		require.NoError(t, err)
+		store[legacyReviewRequestsKey] = value
+
+		p.nudgeOverdueReviews()
+		assert.NotContains(t, store, legacyReviewRequestsKey)
+		assert.Len(t, storedReviewRequests(t, p), 2)
+	})
+
+	t.Run("overdue reviewers sent a direct message", func(t *testing.T) {
+		p, api, store := setupReviewSLA(t, &Subscription{ChannelID: "channelID", Repository: "owner/repo", Flags: SubscriptionFlags{ReviewSLA: "24h", ReviewSLADM: true}})
+		storeRequests(t, store, request("alice", now.Add(-25*time.Hour)), request("bob", now.Add(-25*time.Hour)))
+		store["alice"+githubUsernameKey] = []byte("aliceID")
+		store["bob"+githubUsernameKey] = []byte("bobID")
+		encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey})
+		token, err := encrypt([]byte(encryptionKey), "token")
+		require.NoError(t, err)
+		for userID, notifications := range map[string]bool{"aliceID": true, "bobID": false} {
+			info, err := json.Marshal(&GitHubUserInfo{UserID: userID, Token: &oauth2.Token{AccessToken: token}, Settings: &UserSettings{Notifications: notifications}})
+			require.NoError(t, err)
+			store[userID+githubTokenKey] = info
+		}
+		api.On("GetDirectChannel", "aliceID", "botID").Return(&model.Channel{Id: "dmID"}, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "dmID" && post.Message == "Your review of [owner/repo#123 Fix the crash](https://github.com/owner/repo/pull/123) has been waiting 25h."
+		})).Return(&model.Post{}, nil).Once()
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool { return post.ChannelId == "channelID" })).Return(&model.Post{}, nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.nudgeOverdueReviews()
+		for _, request := range storedReviewRequests(t, p) {
+			assert.False(t, request.DMNudgedAt.IsZero())
+		}
+
+		// Reviewers aren't sent a direct message again
+		p.nudgeOverdueReviews()
+	})
+}
@@ -0,0 +1,188 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// reviewThreadEventType is the type of the webhook events sent when a review thread is resolved or unresolved.
+const reviewThreadEventType = "pull_request_review_thread"
+
+// PullRequestReviewThread is a thread of review comments on a pull request.
+type PullRequestReviewThread struct {
+	NodeID   *string                      `json:"node_id,omitempty"`
+	Comments []*github.PullRequestComment `json:"comments,omitempty"`
+}
+
+// PullRequestReviewThreadEvent is triggered when a review thread is resolved or unresolved. go-github
+// doesn't support it yet.
+type PullRequestReviewThreadEvent struct {
+	Action      *string                  `json:"action,omitempty"`
+	Thread      *PullRequestReviewThread `json:"thread,omitempty"`
+	PullRequest *github.PullRequest      `json:"pull_request,omitempty"`
+	Repo        *github.Repository       `json:"repository,omitempty"`
+	Sender      *github.User             `json:"sender,omitempty"`
+}
+
+func (e *PullRequestReviewThreadEvent) GetAction() string {
+	if e == nil || e.Action == nil {
+		return ""
+	}
+	return *e.Action
+}
+
+func (e *PullRequestReviewThreadEvent) GetPullRequest() *github.PullRequest {
+	if e == nil {
+		return nil
+	}
+	return e.PullRequest
+}
+
+func (e *PullRequestReviewThreadEvent) GetRepo() *github.Repository {
+	if e == nil {
+		return nil
+	}
+	return e.Repo
+}
+
+func (e *PullRequestReviewThreadEvent) GetSender() *github.User {
+	if e == nil {
+		return nil
+	}
+	return e.Sender
+}
+
+// firstComment returns the comment starting the thread.
+func (e *PullRequestReviewThreadEvent) firstComment() *github.PullRequestComment {
+	if e == nil || e.Thread == nil || len(e.Thread.Comments) == 0 {
+		return nil
+	}
+	return e.Thread.Comments[0]
+}
+
+// GetThreadURL returns the link to the thread, falling back to the pull request.
+func (e *PullRequestReviewThreadEvent) GetThreadURL() string {
+	if url := e.firstComment().GetHTMLURL(); url != "" {
+		return url
+	}
+	return e.GetPullRequest().GetHTMLURL()
+}
+
+// GetThreadSummary returns the first line of the comment starting the thread.
+func (e *PullRequestReviewThreadEvent) GetThreadSummary() string {
+	body := strings.TrimSpace(e.firstComment().GetBody())
+	return strings.TrimSpace(strings.SplitN(body, "\n", 2)[0])
+}
+
+func parseReviewThreadEvent(body []byte) (*PullRequestReviewThreadEvent, error) {
+	var event *PullRequestReviewThreadEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (p *Plugin) postReviewThreadEvent(event *PullRequestReviewThreadEvent, delivery *webhookDelivery) {
+	action := event.GetAction()
+	if action != "resolved" && action != "unresolved" {
+		return
+	}
+
+	repo := event.GetRepo()
+
+	subs := delivery.matchSubscriptions(p.GetSubscribedChannelsForRepository(repo))
+	if len(subs) == 0 {
+		return
+	}
+
+	message, err := renderTemplate("reviewThread", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	post := &model.Post{
+		UserId:  p.BotUserID,
+		Type:    "custom_git_review_thread",
+		Props:   eventPostProps(repo.GetFullName(), objectTypePullRequest, strconv.Itoa(event.GetPullRequest().GetNumber()), reviewThreadEventType+"."+action),
+		Message: message,
+	}
+
+	labels := make([]string, len(event.GetPullRequest().Labels))
+	for i, v := range event.GetPullRequest().Labels {
+		labels[i] = v.GetName()
+	}
+
+	for _, sub := range subs {
+		if !sub.PullReviews() {
+			continue
+		}
+
+		if p.excludeSender(event.GetSender(), sub) {
+			continue
+		}
+
+		label := sub.Label()
+		if label != "" && !containsValue(labels, label) {
+			continue
+		}
+
+		post.ChannelId = sub.ChannelID
+		p.postSubscriptionEvent(post, sub, featurePullReviews, delivery)
+	}
+}
+
+// handleReviewThreadNotification notifies the authors of the comments of a thread when it's resolved
+// or unresolved by someone else.
+func (p *Plugin) handleReviewThreadNotification(event *PullRequestReviewThreadEvent) {
+	action := event.GetAction()
+	if (action != "resolved" && action != "unresolved") || event.Thread == nil {
+		return
+	}
+
+	sender := event.GetSender().GetLogin()
+	notified := map[string]bool{}
+	var message string
+
+	for _, comment := range event.Thread.Comments {
+		author := comment.GetUser().GetLogin()
+		if author == "" || author == sender || notified[author] {
+			continue
+		}
+		notified[author] = true
+
+		userID := p.getGitHubToUserIDMapping(author)
+		if userID == "" {
+			continue
+		}
+
+		info, apiErr := p.getGitHubUserInfo(userID)
+		if apiErr != nil || !info.Settings.Notifications {
+			continue
+		}
+
+		if event.GetRepo().GetPrivate() && !p.permissionToRepo(userID, event.GetRepo().GetFullName()) {
+			continue
+		}
+
+		if p.senderMutedByReceiver(userID, sender) {
+			p.API.LogDebug("Review thread resolver is muted, skipping notification")
+			continue
+		}
+
+		if message == "" {
+			var err error
+			message, err = renderTemplate("reviewThreadNotification", event)
+			if err != nil {
+				p.API.LogWarn("Failed to render template", "error", err.Error())
+				return
+			}
+		}
+
+		p.CreateNotificationDMPost(userID, message, "custom_git_review_thread", false)
+	}
+}
@@ -0,0 +1,186 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+// reviewThreadFixture is a pull_request_review_thread payload as sent by GitHub, trimmed to the fields in use.
+const reviewThreadFixture = `{
+  "action": "%s",
+  "thread": {
+    "node_id": "PRRT_kwDOAbc",
+    "comments": [
+      {
+        "id": 101,
+        "body": "Should this be configurable?\nIt's hardcoded for now.",
+        "html_url": "https://github.com/mattermost/mattermost-plugin-github/pull/42#discussion_r101",
+        "user": {"login": "alice", "html_url": "https://github.com/alice"}
+      },
+      {
+        "id": 102,
+        "body": "Done.",
+        "html_url": "https://github.com/mattermost/mattermost-plugin-github/pull/42#discussion_r102",
+        "user": {"login": "panda", "html_url": "https://github.com/panda"}
+      },
+      {
+        "id": 103,
+        "body": "Thanks!",
+        "html_url": "https://github.com/mattermost/mattermost-plugin-github/pull/42#discussion_r103",
+        "user": {"login": "alice", "html_url": "https://github.com/alice"}
+      }
+    ]
+  },
+  "pull_request": {
+    "number": 42,
+    "title": "Leverage git-get-head",
+    "html_url": "https://github.com/mattermost/mattermost-plugin-github/pull/42",
+    "labels": []
+  },
+  "repository": {
+    "full_name": "mattermost/mattermost-plugin-github",
+    "html_url": "https://github.com/mattermost/mattermost-plugin-github",
+    "private": false
+  },
+  "sender": {"login": "panda", "html_url": "https://github.com/panda"}
+}`
+
+func loadReviewThreadFixture(t *testing.T, action string) *PullRequestReviewThreadEvent {
+	event, err := parseReviewThreadEvent([]byte(fmt.Sprintf(reviewThreadFixture, action)))
+	require.NoError(t, err)
+	return event
+}
+
+func TestParseReviewThreadEvent(t *testing.T) {
+	event := loadReviewThreadFixture(t, "resolved")
+
+	assert.Equal(t, "resolved", event.GetAction())
+	assert.Equal(t, 42, event.GetPullRequest().GetNumber())
+	assert.Equal(t, "mattermost/mattermost-plugin-github", event.GetRepo().GetFullName())
+	assert.Equal(t, "panda", event.GetSender().GetLogin())
+	assert.Equal(t, "https://github.com/mattermost/mattermost-plugin-github/pull/42#discussion_r101", event.GetThreadURL())
+	assert.Equal(t, "Should this be configurable?", event.GetThreadSummary())
+
+	t.Run("no comments", func(t *testing.T) {
+		event := &PullRequestReviewThreadEvent{PullRequest: event.PullRequest}
+		assert.Equal(t, "https://github.com/mattermost/mattermost-plugin-github/pull/42", event.GetThreadURL())
+		assert.Equal(t, "", event.GetThreadSummary())
+	})
+}
+
+func TestReviewThreadTemplates(t *testing.T) {
+	for _, action := range []string{"resolved", "unresolved"} {
+		t.Run(action, func(t *testing.T) {
+			event := loadReviewThreadFixture(t, action)
+
+			expected := `
+[\[mattermost/mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) [panda](https://github.com/panda) ` + action + ` a review thread on [#42](https://github.com/mattermost/mattermost-plugin-github/pull/42#discussion_r101): 'Should this be configurable?'
+`
+			actual, err := renderTemplate("reviewThread", event)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+
+			expected = `
+[panda](https://github.com/panda) ` + action + ` a review thread you commented on in [mattermost/mattermost-plugin-github#42](https://github.com/mattermost/mattermost-plugin-github/pull/42#discussion_r101): 'Should this be configurable?'
+`
+			actual, err = renderTemplate("reviewThreadNotification", event)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestPostReviewThreadEvent(t *testing.T) {
+	subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"mattermost/mattermost-plugin-github": {
+			{ChannelID: "reviewsChannelID", Features: featurePullReviews, Repository: "mattermost/mattermost-plugin-github"},
+			{ChannelID: "issuesChannelID", Features: featureIssues, Repository: "mattermost/mattermost-plugin-github"},
+		},
+	}})
+	require.NoError(t, err)
+
+	for _, action := range []string{"resolved", "unresolved"} {
+		t.Run(action, func(t *testing.T) {
+			p := NewPlugin()
+			p.BotUserID = "botID"
+			api := &plugintest.API{}
+			api.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+			api.On("KVGet", SubscriptionsKey).Return(subscriptions, nil)
+			api.On("KVGet", RoutesKey).Return(nil, nil)
+			api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+				return post.ChannelId == "reviewsChannelID" &&
+					post.Type == "custom_git_review_thread" &&
+					post.GetProp(postPropEventType) == "pull_request_review_thread."+action
+			})).Return(&model.Post{}, nil).Once()
+			p.SetAPI(api)
+			defer api.AssertExpectations(t)
+
+			p.postReviewThreadEvent(loadReviewThreadFixture(t, action), testWebhookDelivery(p))
+		})
+	}
+
+	t.Run("other action", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.postReviewThreadEvent(loadReviewThreadFixture(t, "created"), testWebhookDelivery(p))
+	})
+}
+
+func TestHandleReviewThreadNotification(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	userInfo := func(t *testing.T, notifications bool) []byte {
+		token, err := encrypt([]byte(encryptionKey), "token")
+		require.NoError(t, err)
+		value, err := json.Marshal(&GitHubUserInfo{
+			UserID:   "aliceID",
+			Token:    &oauth2.Token{AccessToken: token},
+			Settings: &UserSettings{Notifications: notifications},
+		})
+		require.NoError(t, err)
+		return value
+	}
+
+	setupPlugin := func(t *testing.T, notifications bool) (*Plugin, *plugintest.API) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey})
+		api := &plugintest.API{}
+		api.On("KVGet", "alice"+githubUsernameKey).Return([]byte("aliceID"), nil)
+		api.On("KVGet", "aliceID"+githubTokenKey).Return(userInfo(t, notifications), nil)
+		p.SetAPI(api)
+		return p, api
+	}
+
+	for _, action := range []string{"resolved", "unresolved"} {
+		t.Run(action, func(t *testing.T) {
+			p, api := setupPlugin(t, true)
+			api.On("KVGet", "aliceID-muted-users").Return(nil, nil)
+			api.On("GetDirectChannel", "aliceID", "botID").Return(&model.Channel{Id: "dmChannelID"}, nil).Once()
+			api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+				return post.ChannelId == "dmChannelID" && post.Type == "custom_git_review_thread"
+			})).Return(&model.Post{}, nil).Once()
+			defer api.AssertExpectations(t)
+
+			// The resolver, panda, isn't notified and alice is notified once
+			p.handleReviewThreadNotification(loadReviewThreadFixture(t, action))
+		})
+	}
+
+	t.Run("notifications off", func(t *testing.T) {
+		p, api := setupPlugin(t, false)
+		defer api.AssertExpectations(t)
+
+		p.handleReviewThreadNotification(loadReviewThreadFixture(t, "resolved"))
+	})
+}
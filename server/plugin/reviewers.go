@@ -0,0 +1,228 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+// reviewersUpdate is the outcome of adding or removing reviewers of a pull request.
+type reviewersUpdate struct {
+	// Updated are the reviewers that were added or removed.
+	Updated []string `json:"updated"`
+	// Failed maps the reviewers that couldn't be added or removed to the reason why.
+	Failed map[string]string `json:"failed,omitempty"`
+	// Reviewers is the complete set of reviewers requested on the pull request after the update.
+	Reviewers []string `json:"reviewers"`
+}
+
+// parsePullRequestReference parses a pull request reference of the form owner/repo#123. When only
+// a number is given, the repository defaults to defaultRepo.
+func parsePullRequestReference(reference, defaultRepo string) (owner, repo string, number int, err error) {
+	return parseNumberedReference(reference, defaultRepo, "pull request")
+}
+
+// parseIssueReference parses an issue reference the same way as parsePullRequestReference.
+func parseIssueReference(reference, defaultRepo string) (owner, repo string, number int, err error) {
+	return parseNumberedReference(reference, defaultRepo, "issue")
+}
+
+// parseNumberedReference parses a reference of the form owner/repo#123 to an issue or pull request,
+// named kind in the errors.
+func parseNumberedReference(reference, defaultRepo, kind string) (owner, repo string, number int, err error) {
+	fullName := defaultRepo
+	numberPart := strings.TrimPrefix(reference, "#")
+	if index := strings.LastIndex(reference, "#"); index > 0 {
+		fullName = reference[:index]
+		numberPart = reference[index+1:]
+	}
+
+	number, err = strconv.Atoi(numberPart)
+	if err != nil || number <= 0 {
+		return "", "", 0, errors.Errorf("Invalid %s `%s`. Use `owner/repo#123`.", kind, reference)
+	}
+
+	if fullName == "" {
+		return "", "", 0, errors.Errorf("No repository given for %s `%s`. Use `owner/repo#123`.", kind, reference)
+	}
+
+	owner, repo, err = parseRepo(fullName)
+	if err != nil {
+		return "", "", 0, errors.Errorf("Invalid repository `%s`. Use `owner/repo#123`.", fullName)
+	}
+
+	return owner, repo, number, nil
+}
+
+// getChannelDefaultRepo returns the repository of a channel if it's subscribed to exactly one repository.
+func (p *Plugin) getChannelDefaultRepo(channelID string) string {
+	subs, err := p.GetSubscriptionsByChannel(channelID)
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions for channel", "channelID", channelID, "error", err.Error())
+		return ""
+	}
+
+	repository := ""
+	for _, sub := range subs {
+		if !strings.Contains(sub.Repository, "/") {
+			continue
+		}
+		if repository != "" && repository != sub.Repository {
+			return ""
+		}
+		repository = sub.Repository
+	}
+
+	return repository
+}
+
+// resolveGitHubUsernames maps Mattermost @mentions to the GitHub usernames of connected users.
+// Other names are taken as GitHub usernames.
+func (p *Plugin) resolveGitHubUsernames(names []string) []string {
+	usernames := []string{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if strings.HasPrefix(name, "@") {
+			name = strings.TrimPrefix(name, "@")
+			if user, appErr := p.API.GetUserByUsername(name); appErr == nil {
+				if info, apiErr := p.getGitHubUserInfo(user.Id); apiErr == nil {
+					name = info.GitHubUsername
+				}
+			}
+		}
+
+		if !containsValue(usernames, name) {
+			usernames = append(usernames, name)
+		}
+	}
+
+	return usernames
+}
+
+// updateReviewers requests or removes reviewers of a pull request. Reviewers are updated one at a
+// time so that a reviewer GitHub refuses doesn't prevent the others from being updated.
+func updateReviewers(ctx context.Context, githubClient *github.Client, owner, repo string, number int, reviewers []string, remove bool) (*reviewersUpdate, error) {
+	update := &reviewersUpdate{
+		Updated: []string{},
+		Failed:  map[string]string{},
+	}
+
+	for _, reviewer := range reviewers {
+		request := github.ReviewersRequest{Reviewers: []string{reviewer}}
+
+		var err error
+		if remove {
+			_, err = githubClient.PullRequests.RemoveReviewers(ctx, owner, repo, number, request)
+		} else {
+			_, _, err = githubClient.PullRequests.RequestReviewers(ctx, owner, repo, number, request)
+		}
+
+		if err != nil {
+			update.Failed[reviewer] = reviewerErrorMessage(err, owner, repo)
+			continue
+		}
+
+		update.Updated = append(update.Updated, reviewer)
+	}
+
+	current, _, err := githubClient.PullRequests.ListReviewers(ctx, owner, repo, number, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list reviewers")
+	}
+
+	update.Reviewers = []string{}
+	for _, user := range current.Users {
+		update.Reviewers = append(update.Reviewers, user.GetLogin())
+	}
+	for _, team := range current.Teams {
+		update.Reviewers = append(update.Reviewers, fullNameFromOwnerAndRepo(owner, team.GetSlug()))
+	}
+	sort.Strings(update.Reviewers)
+
+	return update, nil
+}
+
+// reviewerErrorMessage describes why a reviewer couldn't be updated. GitHub rejects review requests
+// for several reasons with the same status, so its message is looked at too.
+func reviewerErrorMessage(err error, owner, repo string) string {
+	errResp, ok := err.(*github.ErrorResponse)
+	if !ok || errResp.Response == nil {
+		return "unexpected error"
+	}
+
+	switch errResp.Response.StatusCode {
+	case http.StatusUnprocessableEntity:
+		// The details of a validation failure are more helpful than its message
+		message := errResp.Message
+		details := []string{}
+		for _, e := range errResp.Errors {
+			if e.Message != "" {
+				details = append(details, strings.TrimSuffix(e.Message, "."))
+			}
+		}
+		if len(details) > 0 {
+			message = strings.Join(details, "; ")
+		}
+
+		switch {
+		case strings.Contains(message, "not a collaborator"):
+			return fmt.Sprintf("not a collaborator of %s", fullNameFromOwnerAndRepo(owner, repo))
+		case strings.Contains(message, "pull request author"):
+			return "the author of a pull request can't review it"
+		case message != "":
+			return strings.TrimSuffix(message, ".")
+		}
+	case http.StatusNotFound:
+		return "pull request not found"
+	}
+
+	return "unexpected error"
+}
+
+func formatReviewersUpdate(update *reviewersUpdate, owner, repo string, number int, remove bool) string {
+	action := "Requested a review from"
+	if remove {
+		action = "Removed"
+	}
+
+	var message strings.Builder
+	if len(update.Updated) > 0 {
+		fmt.Fprintf(&message, "%s %s on %s#%d.\n", action, formatGitHubUsernames(update.Updated), fullNameFromOwnerAndRepo(owner, repo), number)
+	}
+
+	failed := make([]string, 0, len(update.Failed))
+	for reviewer := range update.Failed {
+		failed = append(failed, reviewer)
+	}
+	sort.Strings(failed)
+	for _, reviewer := range failed {
+		fmt.Fprintf(&message, "Couldn't update `%s`: %s.\n", reviewer, update.Failed[reviewer])
+	}
+
+	if len(update.Reviewers) == 0 {
+		message.WriteString("The pull request has no requested reviewers.")
+	} else {
+		fmt.Fprintf(&message, "Requested reviewers: %s.", formatGitHubUsernames(update.Reviewers))
+	}
+
+	return message.String()
+}
+
+func formatGitHubUsernames(usernames []string) string {
+	formatted := make([]string, 0, len(usernames))
+	for _, username := range usernames {
+		formatted = append(formatted, "`"+username+"`")
+	}
+
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePullRequestReference(t *testing.T) {
+	for name, test := range map[string]struct {
+		reference      string
+		defaultRepo    string
+		expectedOwner  string
+		expectedRepo   string
+		expectedNumber int
+		expectedErr    bool
+	}{
+		"full reference":                   {reference: "owner/repo#123", expectedOwner: "owner", expectedRepo: "repo", expectedNumber: 123},
+		"full reference with default repo": {reference: "owner/repo#123", defaultRepo: "other/repo", expectedOwner: "owner", expectedRepo: "repo", expectedNumber: 123},
+		"number with default repo":         {reference: "#123", defaultRepo: "owner/repo", expectedOwner: "owner", expectedRepo: "repo", expectedNumber: 123},
+		"bare number with default repo":    {reference: "123", defaultRepo: "owner/repo", expectedOwner: "owner", expectedRepo: "repo", expectedNumber: 123},
+		"number without default repo":      {reference: "#123", expectedErr: true},
+		"missing number":                   {reference: "owner/repo", expectedErr: true},
+		"invalid number":                   {reference: "owner/repo#abc", expectedErr: true},
+		"invalid repository":               {reference: "owner#123", expectedErr: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			owner, repo, number, err := parsePullRequestReference(test.reference, test.defaultRepo)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedOwner, owner)
+			assert.Equal(t, test.expectedRepo, repo)
+			assert.Equal(t, test.expectedNumber, number)
+		})
+	}
+}
+
+func TestUpdateReviewers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/repo/pulls/1/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			var request github.ReviewersRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			switch request.Reviewers[0] {
+			case "stranger":
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				fmt.Fprint(w, `{"message": "Reviews may only be requested from collaborators. One or more of the users or teams you specified is not a collaborator of the owner/repo repository."}`)
+				return
+			case "author":
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				fmt.Fprint(w, `{"message": "Review cannot be requested from pull request author."}`)
+				return
+			case "ghost":
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				fmt.Fprint(w, `{"message": "Validation Failed", "errors": [{"resource": "PullRequest", "code": "custom", "message": "Could not resolve to a User with the login of 'ghost'."}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"number": 1}`)
+		case http.MethodGet:
+			fmt.Fprint(w, `{"users": [{"login": "bob"}, {"login": "alice"}], "teams": [{"slug": "core"}]}`)
+		}
+	})
+
+	client := newTestGitHubClient(t, mux)
+
+	update, err := updateReviewers(context.Background(), client, "owner", "repo", 1, []string{"alice", "stranger", "author", "ghost"}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"alice"}, update.Updated)
+	assert.Equal(t, map[string]string{
+		"stranger": "not a collaborator of owner/repo",
+		"author":   "the author of a pull request can't review it",
+		"ghost":    "Could not resolve to a User with the login of 'ghost'",
+	}, update.Failed)
+	assert.Equal(t, []string{"alice", "bob", "owner/core"}, update.Reviewers)
+
+	assert.Equal(t, "Requested a review from `alice` on owner/repo#1.\n"+
+		"Couldn't update `author`: the author of a pull request can't review it.\n"+
+		"Couldn't update `ghost`: Could not resolve to a User with the login of 'ghost'.\n"+
+		"Couldn't update `stranger`: not a collaborator of owner/repo.\n"+
+		"Requested reviewers: `alice`, `bob`, `owner/core`.", formatReviewersUpdate(update, "owner", "repo", 1, false))
+}
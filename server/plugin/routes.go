@@ -0,0 +1,267 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const RoutesKey = "routes"
+
+// Route forwards the events of the given features for a repository to an additional channel,
+// on top of the channels subscribed to that repository.
+type Route struct {
+	ChannelID  string
+	CreatorID  string
+	Features   string
+	Repository string
+}
+
+type Routes struct {
+	Repositories map[string][]*Route
+}
+
+// routeIndex keeps the routes in memory, by repository and organization, so that webhook events
+// don't decode all of them from the KV store. It's loaded at the same revision as the subscription
+// index.
+type routeIndex struct {
+	mu           sync.RWMutex
+	loaded       bool
+	revision     string
+	repositories map[string][]*Route
+}
+
+// get returns the routes of the given repositories or organizations if the index is loaded at the
+// given revision.
+func (i *routeIndex) get(revision string, keys ...string) ([]*Route, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if !i.loaded || i.revision != revision {
+		return nil, false
+	}
+
+	return copyRoutes(i.repositories, keys...), true
+}
+
+// set loads the routes into the index at the given revision.
+func (i *routeIndex) set(revision string, routes *Routes) {
+	repositories := make(map[string][]*Route, len(routes.Repositories))
+	for key := range routes.Repositories {
+		repositories[key] = copyRoutes(routes.Repositories, key)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.loaded = true
+	i.revision = revision
+	i.repositories = repositories
+}
+
+// copyRoutes returns copies of the routes of the given repositories or organizations, so that the
+// index can't be changed through the routes it's loaded with or returns.
+func copyRoutes(repositories map[string][]*Route, keys ...string) []*Route {
+	routes := []*Route{}
+	for _, key := range keys {
+		for _, route := range repositories[key] {
+			copied := *route
+			routes = append(routes, &copied)
+		}
+	}
+
+	return routes
+}
+
+func (p *Plugin) GetRoutes() (*Routes, error) {
+	var routes *Routes
+
+	value, appErr := p.API.KVGet(RoutesKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get routes from KVStore")
+	}
+
+	if value == nil {
+		return &Routes{Repositories: map[string][]*Route{}}, nil
+	}
+
+	err := json.NewDecoder(bytes.NewReader(value)).Decode(&routes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not properly decode routes key")
+	}
+
+	return routes, nil
+}
+
+func (p *Plugin) StoreRoutes(r *Routes) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "error while converting routes map to json")
+	}
+
+	if appErr := p.API.KVSet(RoutesKey, b); appErr != nil {
+		return errors.Wrap(appErr, "could not store routes in KV store")
+	}
+
+	revision, err := p.storeSubscriptionsRevision()
+	if err != nil {
+		return err
+	}
+	p.routeIndex.set(revision, r)
+
+	return nil
+}
+
+// getIndexedRoutes returns the routes of the given repositories or organizations from the route
+// index, reloading it from the KV store unless it's loaded at the given revision.
+func (p *Plugin) getIndexedRoutes(revision string, keys ...string) ([]*Route, error) {
+	if routes, ok := p.routeIndex.get(revision, keys...); ok {
+		return routes, nil
+	}
+
+	routes, err := p.GetRoutes()
+	if err != nil {
+		return nil, err
+	}
+	p.routeIndex.set(revision, routes)
+
+	return copyRoutes(routes.Repositories, keys...), nil
+}
+
+// AddRoute stores a route, replacing any existing route of the same repository to the same channel.
+func (p *Plugin) AddRoute(route *Route) error {
+	routes, err := p.GetRoutes()
+	if err != nil {
+		return errors.Wrap(err, "could not get routes")
+	}
+
+	repoRoutes := routes.Repositories[route.Repository]
+	exists := false
+	for index, r := range repoRoutes {
+		if r.ChannelID == route.ChannelID {
+			repoRoutes[index] = route
+			exists = true
+			break
+		}
+	}
+
+	if !exists {
+		repoRoutes = append(repoRoutes, route)
+	}
+
+	routes.Repositories[route.Repository] = repoRoutes
+
+	if err := p.StoreRoutes(routes); err != nil {
+		return errors.Wrap(err, "could not store routes")
+	}
+
+	return nil
+}
+
+// DeleteRoute removes the route of a repository to a channel. It reports whether a route was removed.
+func (p *Plugin) DeleteRoute(repo, channelID string) (bool, error) {
+	routes, err := p.GetRoutes()
+	if err != nil {
+		return false, errors.Wrap(err, "could not get routes")
+	}
+
+	repoRoutes := routes.Repositories[repo]
+	for index, r := range repoRoutes {
+		if r.ChannelID != channelID {
+			continue
+		}
+
+		repoRoutes = append(repoRoutes[:index], repoRoutes[index+1:]...)
+		if len(repoRoutes) == 0 {
+			delete(routes.Repositories, repo)
+		} else {
+			routes.Repositories[repo] = repoRoutes
+		}
+
+		if err := p.StoreRoutes(routes); err != nil {
+			return false, errors.Wrap(err, "could not store routes")
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// ListRoutes returns all routes sorted by repository.
+func (p *Plugin) ListRoutes() ([]*Route, error) {
+	routes, err := p.GetRoutes()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get routes")
+	}
+
+	var list []*Route
+	for _, repoRoutes := range routes.Repositories {
+		list = append(list, repoRoutes...)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Repository == list[j].Repository {
+			return list[i].ChannelID < list[j].ChannelID
+		}
+		return list[i].Repository < list[j].Repository
+	})
+
+	return list, nil
+}
+
+// getRoutedSubscriptions converts the routes matching a repository into subscriptions of the target channels.
+// Features already covered by a subscription of the target channel are dropped to avoid posting twice.
+func (p *Plugin) getRoutedSubscriptions(repo *github.Repository, revision string, subs []*Subscription) []*Subscription {
+	name := repo.GetFullName()
+	org := strings.Split(name, "/")[0]
+
+	repoRoutes, err := p.getIndexedRoutes(revision, name, fullNameFromOwnerAndRepo(org, ""))
+	if err != nil {
+		p.API.LogWarn("Failed to get routes", "error", err.Error())
+		return nil
+	}
+
+	routedSubs := []*Subscription{}
+	for _, route := range repoRoutes {
+		features := parseFeatures(route.Features)
+		for _, sub := range subs {
+			if sub.ChannelID == route.ChannelID {
+				features = arrayDifference(features, parseFeatures(sub.Features))
+			}
+		}
+
+		if !hasEventFeature(features) {
+			continue
+		}
+
+		if repo.GetPrivate() && !p.permissionToRepo(route.CreatorID, name) {
+			continue
+		}
+
+		routedSubs = append(routedSubs, &Subscription{
+			ChannelID:  route.ChannelID,
+			CreatorID:  route.CreatorID,
+			Features:   strings.Join(features, ","),
+			Repository: route.Repository,
+		})
+	}
+
+	return routedSubs
+}
+
+// hasEventFeature reports whether the features contain anything besides label filters.
+func hasEventFeature(features []string) bool {
+	for _, f := range features {
+		if !strings.HasPrefix(f, "label:") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,141 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pluginWithMockedRoutes returns mocked plugin for given subscriptions and routes of a repository
+func pluginWithMockedRoutes(repo string, subscriptions []*Subscription, routes []*Route) *Plugin {
+	p := NewPlugin()
+	mockPluginAPI := &plugintest.API{}
+
+	subs := Subscriptions{Repositories: map[string][]*Subscription{}}
+	subs.Repositories[repo] = subscriptions
+	jsn, _ := json.Marshal(subs)
+	mockPluginAPI.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+	mockPluginAPI.On("KVGet", SubscriptionsKey).Return(jsn, nil)
+
+	r := Routes{Repositories: map[string][]*Route{}}
+	r.Repositories[repo] = routes
+	jsn, _ = json.Marshal(r)
+	mockPluginAPI.On("KVGet", RoutesKey).Return(jsn, nil)
+
+	p.SetAPI(mockPluginAPI)
+	return p
+}
+
+func TestPlugin_GetSubscribedChannelsForRepositoryWithRoutes(t *testing.T) {
+	repo := &github.Repository{FullName: github.String("owner/repo")}
+
+	tests := []struct {
+		name   string
+		plugin *Plugin
+		want   []*Subscription
+	}{
+		{
+			name:   "no subscriptions and no routes",
+			plugin: pluginWithMockedRoutes("owner/repo", nil, nil),
+			want:   nil,
+		},
+		{
+			name: "route without subscription",
+			plugin: pluginWithMockedRoutes("owner/repo", nil, []*Route{
+				{ChannelID: "2", Features: "pulls", Repository: "owner/repo"},
+			}),
+			want: []*Subscription{
+				{ChannelID: "2", Features: "pulls", Repository: "owner/repo"},
+			},
+		},
+		{
+			name: "route to another channel",
+			plugin: pluginWithMockedRoutes("owner/repo", []*Subscription{
+				{ChannelID: "1", Features: "pulls", Repository: "owner/repo"},
+			}, []*Route{
+				{ChannelID: "2", Features: "creates", Repository: "owner/repo"},
+			}),
+			want: []*Subscription{
+				{ChannelID: "1", Features: "pulls", Repository: "owner/repo"},
+				{ChannelID: "2", Features: "creates", Repository: "owner/repo"},
+			},
+		},
+		{
+			name: "route overlapping with a subscription of the target channel",
+			plugin: pluginWithMockedRoutes("owner/repo", []*Subscription{
+				{ChannelID: "1", Features: "pulls,issues", Repository: "owner/repo"},
+			}, []*Route{
+				{ChannelID: "1", Features: "pulls,creates", Repository: "owner/repo"},
+			}),
+			want: []*Subscription{
+				{ChannelID: "1", Features: "pulls,issues", Repository: "owner/repo"},
+				{ChannelID: "1", Features: "creates", Repository: "owner/repo"},
+			},
+		},
+		{
+			name: "route fully covered by a subscription of the target channel",
+			plugin: pluginWithMockedRoutes("owner/repo", []*Subscription{
+				{ChannelID: "1", Features: "pulls,issues", Repository: "owner/repo"},
+			}, []*Route{
+				{ChannelID: "1", Features: `pulls,label:"bug"`, Repository: "owner/repo"},
+			}),
+			want: []*Subscription{
+				{ChannelID: "1", Features: "pulls,issues", Repository: "owner/repo"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.plugin.GetSubscribedChannelsForRepository(repo)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckFeatureList(t *testing.T) {
+	assert.NoError(t, checkFeatureList("pulls,issues"))
+	assert.EqualError(t, checkFeatureList("pulls,push"), "Invalid feature(s) provided: push")
+	assert.EqualError(t, checkFeatureList("issues,issue_creations"), "Feature list cannot contain both issue and issue_creations")
+	assert.EqualError(t, checkFeatureList(`pushes,label:"bug"`), "Feature list must have \"pulls\" or \"issues\" when using a label.")
+}
+
+func TestRouteIndex(t *testing.T) {
+	p, store := setupSubscriptionIndexTest(t, &Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {{ChannelID: "channel1", Features: "pulls", Repository: "owner/repo"}},
+	}})
+	repo := &github.Repository{FullName: github.String("owner/repo")}
+
+	t.Run("loaded lazily", func(t *testing.T) {
+		assert.Equal(t, []string{"channel1"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+		assert.Equal(t, []string{"channel1"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+		assert.Equal(t, 1, store.routesReads())
+	})
+
+	t.Run("updated when storing routes", func(t *testing.T) {
+		require.NoError(t, p.AddRoute(&Route{ChannelID: "channel2", Features: "issues", Repository: "owner/"}))
+		reads := store.routesReads()
+
+		assert.Equal(t, []string{"channel1", "channel2"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+		assert.Equal(t, []string{"channel1", "channel2"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+		assert.Equal(t, reads, store.routesReads())
+	})
+
+	t.Run("reloaded when another node stores routes", func(t *testing.T) {
+		value, err := json.Marshal(&Routes{Repositories: map[string][]*Route{
+			"owner/repo": {{ChannelID: "channel3", Features: "pulls", Repository: "owner/repo"}},
+		}})
+		require.NoError(t, err)
+		store.set(RoutesKey, value)
+		store.set(SubscriptionsRevisionKey, []byte(model.NewId()))
+		reads := store.routesReads()
+
+		assert.Equal(t, []string{"channel1", "channel3"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+		assert.Equal(t, reads+1, store.routesReads())
+	})
+}
@@ -0,0 +1,188 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	sampleCounterKey = "_samplecounter"
+
+	// sampleCounterUpdateAttempts is the number of times a concurrent update of a counter is retried.
+	sampleCounterUpdateAttempts = 5
+)
+
+// sampleRate is the rate of a --sample flag: at most Posted out of every Of events are posted.
+type sampleRate struct {
+	Posted int
+	Of     int
+}
+
+// parseSampleRate parses a rate of the form 1/10.
+func parseSampleRate(value string) (*sampleRate, error) {
+	invalid := errors.Errorf("Invalid value %q for --%s. Use a rate like `1/10` to post at most 1 out of every 10 events.", value, sampleFlag)
+
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+
+	posted, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, invalid
+	}
+	of, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, invalid
+	}
+
+	if posted <= 0 || of <= posted {
+		return nil, invalid
+	}
+
+	return &sampleRate{Posted: posted, Of: of}, nil
+}
+
+// sampleCounter counts the events of a feature for a sampled subscription.
+type sampleCounter struct {
+	// Position is the position of the next event in the current window of events. It rolls over
+	// at the end of the window.
+	Position int
+	// Skipped is the number of events skipped since the last posted one.
+	Skipped int
+}
+
+func sampleCounterKeyFor(sub *Subscription, feature string) string {
+	return hashKey(sampleCounterKey, sub.ChannelID, sub.Repository, feature)
+}
+
+// next counts an event, returning whether it should be posted and, if so, the number of events
+// skipped since the last posted one.
+func (c *sampleCounter) next(rate *sampleRate) (bool, int) {
+	position := c.Position
+	c.Position = (c.Position + 1) % rate.Of
+
+	if position >= rate.Posted {
+		c.Skipped++
+		return false, 0
+	}
+
+	skipped := c.Skipped
+	c.Skipped = 0
+	return true, skipped
+}
+
+// sampleEvent counts an event of a feature for a subscription, returning whether it should be posted
+// and the number of events skipped since the last posted one. The counter is updated atomically, as
+// webhooks can be handled concurrently.
+func (p *Plugin) sampleEvent(sub *Subscription, feature string, rate *sampleRate) (bool, int, error) {
+	key := sampleCounterKeyFor(sub, feature)
+
+	for attempt := 0; attempt < sampleCounterUpdateAttempts; attempt++ {
+		oldValue, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return false, 0, errors.Wrap(appErr, "could not get sample counter from KV store")
+		}
+
+		counter := &sampleCounter{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, counter); err != nil {
+				return false, 0, errors.Wrap(err, "could not unmarshal sample counter")
+			}
+		}
+
+		post, skipped := counter.next(rate)
+
+		newValue, err := json.Marshal(counter)
+		if err != nil {
+			return false, 0, errors.Wrap(err, "could not marshal sample counter")
+		}
+
+		stored, appErr := p.API.KVCompareAndSet(key, oldValue, newValue)
+		if appErr != nil {
+			return false, 0, errors.Wrap(appErr, "could not store sample counter in KV store")
+		}
+		if stored {
+			return post, skipped, nil
+		}
+	}
+
+	return false, 0, errors.New("too many concurrent updates of the sample counter")
+}
+
+// postSubscriptionEvent creates the post of an event for a subscription, unless the subscription
+// samples the events of the feature and this one is skipped. It returns the created post, or nil if
+// none was.
+func (p *Plugin) postSubscriptionEvent(post *model.Post, sub *Subscription, feature string, delivery *webhookDelivery) *model.Post {
+	if delivery.Replayed {
+		post = markReplayed(post)
+	}
+
+	if sub.Flags.Sample == "" || criticalFeatures[feature] {
+		return p.createDeliveryPost(post, sub, feature, delivery)
+	}
+
+	rate, err := parseSampleRate(sub.Flags.Sample)
+	if err != nil {
+		delivery.subscriptionLogger(sub).Warnf("Invalid sample rate: %s", err.Error())
+		return p.createDeliveryPost(post, sub, feature, delivery)
+	}
+
+	shouldPost, skipped, err := p.sampleEvent(sub, feature, rate)
+	if err != nil {
+		// Posting too much beats missing events
+		delivery.subscriptionLogger(sub).Warnf("Failed to sample event: %s", err.Error())
+		return p.createDeliveryPost(post, sub, feature, delivery)
+	}
+	if !shouldPost {
+		delivery.recordFiltered(filterReasonSampled)
+		return nil
+	}
+
+	if skipped > 0 {
+		// The post is shared with the other subscriptions
+		sampledPost := post.Clone()
+		sampledPost.Message += fmt.Sprintf("\n\n_…and %d similar %s since the last post._", skipped, pluralize(skipped, "event", "events"))
+		post = sampledPost
+	}
+
+	return p.createDeliveryPost(post, sub, feature, delivery)
+}
+
+// createDeliveryPost creates the post of a delivery for a subscription, and counts it. Events of
+// subscriptions over their rate cap aren't posted.
+func (p *Plugin) createDeliveryPost(post *model.Post, sub *Subscription, feature string, delivery *webhookDelivery) *model.Post {
+	if sub.Flags.MaxPerHour > 0 && !criticalFeatures[feature] && !delivery.Replayed {
+		shouldPost, err := p.applyRateCap(sub, sub.Flags.MaxPerHour, time.Now())
+		if err != nil {
+			// Posting too much beats missing events
+			delivery.subscriptionLogger(sub).Warnf("Failed to apply rate cap: %s", err.Error())
+		} else if !shouldPost {
+			delivery.recordFiltered(filterReasonRateCapped)
+			return nil
+		}
+	}
+
+	created := p.createSubscriptionPost(post, sub)
+	if created == nil {
+		delivery.recordFiltered(filterReasonPostFailed)
+		return nil
+	}
+
+	delivery.recordPosted()
+	return created
+}
+
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return singular
+	}
+
+	return plural
+}
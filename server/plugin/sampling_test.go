@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSampleRate(t *testing.T) {
+	rate, err := parseSampleRate("1/10")
+	require.NoError(t, err)
+	assert.Equal(t, &sampleRate{Posted: 1, Of: 10}, rate)
+
+	rate, err = parseSampleRate("3/4")
+	require.NoError(t, err)
+	assert.Equal(t, &sampleRate{Posted: 3, Of: 4}, rate)
+
+	for _, value := range []string{"", "10", "1/", "/10", "a/10", "1/b", "0/10", "10/10", "11/10", "-1/10", "1/10/100"} {
+		_, err := parseSampleRate(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestSampleFlag(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(sampleFlag, "1/10"))
+	assert.Equal(t, "1/10", flags.Sample)
+	assert.Equal(t, "--sample 1/10", flags.String())
+
+	assert.Error(t, flags.SetFlag(sampleFlag, "10"))
+}
+
+func TestSampleCounterNext(t *testing.T) {
+	t.Run("counter rolls over", func(t *testing.T) {
+		rate := &sampleRate{Posted: 1, Of: 3}
+		counter := &sampleCounter{}
+
+		type result struct {
+			post    bool
+			skipped int
+		}
+		var results []result
+		for i := 0; i < 7; i++ {
+			post, skipped := counter.next(rate)
+			results = append(results, result{post, skipped})
+		}
+
+		assert.Equal(t, []result{
+			{true, 0},
+			{false, 0},
+			{false, 0},
+			{true, 2},
+			{false, 0},
+			{false, 0},
+			{true, 2},
+		}, results)
+		assert.Equal(t, 1, counter.Position)
+	})
+
+	t.Run("several events posted per window", func(t *testing.T) {
+		rate := &sampleRate{Posted: 2, Of: 3}
+		counter := &sampleCounter{}
+
+		var posted []bool
+		for i := 0; i < 6; i++ {
+			post, _ := counter.next(rate)
+			posted = append(posted, post)
+		}
+
+		assert.Equal(t, []bool{true, true, false, true, true, false}, posted)
+	})
+}
+
+// mockKVStore backs the KVGet and KVCompareAndSet calls of a mocked API with a map.
+func mockKVStore(api *plugintest.API) map[string][]byte {
+	store := map[string][]byte{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte {
+		return store[key]
+	}, nil)
+	api.On("KVCompareAndSet", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(func(key string, oldValue, newValue []byte) bool {
+		if !bytes.Equal(store[key], oldValue) {
+			return false
+		}
+		store[key] = newValue
+		return true
+	}, nil).Maybe()
+
+	return store
+}
+
+func TestPostSubscriptionEvent(t *testing.T) {
+	sub := &Subscription{
+		ChannelID:  "channelID",
+		Repository: "owner/repo",
+		Flags:      SubscriptionFlags{Sample: "1/3"},
+	}
+
+	setup := func() (*Plugin, *plugintest.API, *[]string) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		mockKVStore(api)
+
+		messages := &[]string{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			*messages = append(*messages, post.Message)
+			return post
+		}, nil)
+		p.SetAPI(api)
+
+		return p, api, messages
+	}
+
+	t.Run("skipped events are counted in the next post", func(t *testing.T) {
+		p, _, messages := setup()
+
+		for i := 0; i < 4; i++ {
+			p.postSubscriptionEvent(&model.Post{ChannelId: "channelID", Message: "push"}, sub, featurePushes, testWebhookDelivery(p))
+		}
+
+		assert.Equal(t, []string{
+			"push",
+			"push\n\n_…and 2 similar events since the last post._",
+		}, *messages)
+	})
+
+	t.Run("features are sampled independently", func(t *testing.T) {
+		p, _, messages := setup()
+
+		p.postSubscriptionEvent(&model.Post{Message: "push 1"}, sub, featurePushes, testWebhookDelivery(p))
+		p.postSubscriptionEvent(&model.Post{Message: "push 2"}, sub, featurePushes, testWebhookDelivery(p))
+		p.postSubscriptionEvent(&model.Post{Message: "issue 1"}, sub, featureIssues, testWebhookDelivery(p))
+		p.postSubscriptionEvent(&model.Post{Message: "push 3"}, sub, featurePushes, testWebhookDelivery(p))
+		p.postSubscriptionEvent(&model.Post{Message: "issue 2"}, sub, featureIssues, testWebhookDelivery(p))
+		p.postSubscriptionEvent(&model.Post{Message: "push 4"}, sub, featurePushes, testWebhookDelivery(p))
+
+		assert.Equal(t, []string{
+			"push 1",
+			"issue 1",
+			"push 4\n\n_…and 2 similar events since the last post._",
+		}, *messages)
+	})
+
+	t.Run("subscriptions are sampled independently", func(t *testing.T) {
+		p, _, messages := setup()
+		other := &Subscription{
+			ChannelID:  "otherChannelID",
+			Repository: "owner/repo",
+			Flags:      SubscriptionFlags{Sample: "1/3"},
+		}
+
+		p.postSubscriptionEvent(&model.Post{Message: "push 1"}, sub, featurePushes, testWebhookDelivery(p))
+		p.postSubscriptionEvent(&model.Post{Message: "push 1"}, other, featurePushes, testWebhookDelivery(p))
+
+		assert.Equal(t, []string{"push 1", "push 1"}, *messages)
+	})
+
+	t.Run("critical features are never sampled", func(t *testing.T) {
+		p, api, messages := setup()
+
+		for i := 0; i < 3; i++ {
+			p.postSubscriptionEvent(&model.Post{Message: "failure"}, sub, "workflow_failure", testWebhookDelivery(p))
+		}
+
+		assert.Equal(t, []string{"failure", "failure", "failure"}, *messages)
+		api.AssertNotCalled(t, "KVGet", mock.Anything)
+	})
+
+	t.Run("shared post is left untouched", func(t *testing.T) {
+		p, _, _ := setup()
+		post := &model.Post{Message: "push"}
+
+		for i := 0; i < 4; i++ {
+			p.postSubscriptionEvent(post, sub, featurePushes, testWebhookDelivery(p))
+		}
+
+		assert.Equal(t, "push", post.Message)
+	})
+}
@@ -0,0 +1,274 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	savedSearchesKey = "_savedsearches"
+
+	// maxSavedSearchesPerUser caps the number of saved searches of a user. They are all run on every
+	// refresh of the sidebar, and GitHub allows only a few searches per minute.
+	maxSavedSearchesPerUser  = 5
+	maxSavedSearchNameLength = 30
+	// savedSearchTopItems is the number of items returned with the count of a saved search.
+	savedSearchTopItems = 5
+)
+
+var savedSearchNameRegex = regexp.MustCompile(`^[\w-]+$`)
+
+// SavedSearch is a GitHub search of a user, shown as a section of the sidebar.
+type SavedSearch struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// SavedSearchResult is the outcome of running a saved search.
+type SavedSearchResult struct {
+	Name  string          `json:"name"`
+	Query string          `json:"query"`
+	Total int             `json:"total"`
+	Items []*github.Issue `json:"items"`
+	// Error tells why the search failed, e.g. because the rate limit was exceeded.
+	Error string `json:"error,omitempty"`
+}
+
+// checkSavedSearchName validates the name of a saved search.
+func checkSavedSearchName(name string) error {
+	if len(name) > maxSavedSearchNameLength || !savedSearchNameRegex.MatchString(name) {
+		return errors.Errorf("Please name the search with at most %d letters, digits, `-` and `_`, e.g. `urgent-reviews`.", maxSavedSearchNameLength)
+	}
+
+	return nil
+}
+
+func (p *Plugin) getSavedSearches(userID string) ([]*SavedSearch, error) {
+	value, appErr := p.API.KVGet(userID + savedSearchesKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get saved searches from KV store")
+	}
+
+	searches := []*SavedSearch{}
+	if value == nil {
+		return searches, nil
+	}
+
+	if err := json.Unmarshal(value, &searches); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal saved searches")
+	}
+
+	return searches, nil
+}
+
+func (p *Plugin) storeSavedSearches(userID string, searches []*SavedSearch) error {
+	value, err := json.Marshal(searches)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal saved searches")
+	}
+
+	if appErr := p.API.KVSet(userID+savedSearchesKey, value); appErr != nil {
+		return errors.Wrap(appErr, "could not store saved searches in KV store")
+	}
+
+	return nil
+}
+
+// searchSavedSearch runs the query of a saved search, scoped to the configured organization.
+func (p *Plugin) searchSavedSearch(ctx context.Context, githubClient *github.Client, query string, perPage int) (*github.IssuesSearchResult, error) {
+	result, _, err := githubClient.Search.Issues(ctx, getSavedSearchQuery(query, p.getConfiguration().GitHubOrg), &github.SearchOptions{ListOptions: github.ListOptions{PerPage: perPage}})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// runSavedSearches runs the saved searches of a user. A failed search is reported in its result
+// rather than failing the others.
+func (p *Plugin) runSavedSearches(ctx context.Context, githubClient *github.Client, searches []*SavedSearch) []*SavedSearchResult {
+	results := []*SavedSearchResult{}
+	for _, search := range searches {
+		searchResult := &SavedSearchResult{Name: search.Name, Query: search.Query, Items: []*github.Issue{}}
+
+		result, err := p.searchSavedSearch(ctx, githubClient, search.Query, savedSearchTopItems)
+		if err != nil {
+			p.API.LogWarn("Failed to run saved search", "name", search.Name, "error", err.Error())
+			searchResult.Error = newAPIError(err, "The search failed.").Message
+		} else {
+			searchResult.Total = result.GetTotal()
+			searchResult.Items = result.Issues
+			if len(searchResult.Items) > savedSearchTopItems {
+				searchResult.Items = searchResult.Items[:savedSearchTopItems]
+			}
+		}
+
+		results = append(results, searchResult)
+	}
+
+	return results
+}
+
+// getSavedSearchResults runs the saved searches of a connected user. It returns nil if the user
+// has none.
+func (p *Plugin) getSavedSearchResults(ctx context.Context, userID string) ([]*SavedSearchResult, *APIErrorResponse) {
+	searches, err := p.getSavedSearches(userID)
+	if err != nil {
+		p.API.LogWarn("Failed to get saved searches", "userID", userID, "error", err.Error())
+		return nil, newAPIError(err, "Failed to get your saved searches.")
+	}
+	if len(searches) == 0 {
+		return nil, nil
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	return p.runSavedSearches(ctx, p.githubConnect(*info.Token), searches), nil
+}
+
+func (p *Plugin) getSavedSearchesList(w http.ResponseWriter, r *http.Request, userID string) {
+	results, apiErr := p.getSavedSearchResults(r.Context(), userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+	if results == nil {
+		results = []*SavedSearchResult{}
+	}
+
+	p.writeJSON(w, results)
+}
+
+func (p *Plugin) handleSearch(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	usage := "Please use `/github search save [name] \"[query]\"`, `/github search list` or `/github search delete [name]`."
+	if len(parameters) == 0 {
+		return usage
+	}
+
+	switch parameters[0] {
+	case "save":
+		if len(parameters) < 3 {
+			return usage
+		}
+		return p.handleSearchSave(args.UserId, parameters[1], strings.Trim(strings.Join(parameters[2:], " "), `"`), userInfo)
+	case "list":
+		return p.handleSearchList(args.UserId)
+	case "delete":
+		if len(parameters) != 2 {
+			return usage
+		}
+		return p.handleSearchDelete(args.UserId, parameters[1])
+	default:
+		return fmt.Sprintf("Unknown subcommand %v", parameters[0])
+	}
+}
+
+func (p *Plugin) handleSearchSave(userID, name, query string, userInfo *GitHubUserInfo) string {
+	if err := checkSavedSearchName(name); err != nil {
+		return err.Error()
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "Please provide the query of the search, e.g. `\"is:pr review-requested:@me label:urgent\"`."
+	}
+
+	searches, err := p.getSavedSearches(userID)
+	if err != nil {
+		p.API.LogWarn("Failed to get saved searches", "userID", userID, "error", err.Error())
+		return "Encountered an error saving the search."
+	}
+
+	var saved *SavedSearch
+	for _, search := range searches {
+		if search.Name == name {
+			saved = search
+		}
+	}
+	if saved == nil && len(searches) >= maxSavedSearchesPerUser {
+		return fmt.Sprintf("You can have at most %d saved searches. Please delete one first.", maxSavedSearchesPerUser)
+	}
+
+	// A single result is enough to tell if GitHub understands the query
+	result, err := p.searchSavedSearch(context.Background(), p.getGithubClient(userInfo), query, 1)
+	if err != nil {
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnprocessableEntity {
+			return fmt.Sprintf("GitHub can't run the search `%s`: %s", query, errResp.Message)
+		}
+		p.API.LogWarn("Failed to try saved search", "userID", userID, "error", err.Error())
+		return newAPIError(err, "Encountered an error trying the search.").Message
+	}
+
+	if saved != nil {
+		saved.Query = query
+	} else {
+		searches = append(searches, &SavedSearch{Name: name, Query: query})
+	}
+
+	if err := p.storeSavedSearches(userID, searches); err != nil {
+		p.API.LogWarn("Failed to store saved searches", "userID", userID, "error", err.Error())
+		return "Encountered an error saving the search."
+	}
+
+	p.sendRefreshEvent(userID)
+
+	return fmt.Sprintf("Saved the search `%s`, currently matching %d %s. It shows in your GitHub sidebar.", name, result.GetTotal(), pluralize(result.GetTotal(), "item", "items"))
+}
+
+func (p *Plugin) handleSearchList(userID string) string {
+	searches, err := p.getSavedSearches(userID)
+	if err != nil {
+		p.API.LogWarn("Failed to get saved searches", "userID", userID, "error", err.Error())
+		return "Encountered an error getting your saved searches."
+	}
+
+	if len(searches) == 0 {
+		return "You have no saved searches. Save one with `/github search save [name] \"[query]\"`."
+	}
+
+	txt := "### Your saved searches\n"
+	for _, search := range searches {
+		txt += fmt.Sprintf("* `%s` - `%s`\n", search.Name, search.Query)
+	}
+
+	return txt
+}
+
+func (p *Plugin) handleSearchDelete(userID, name string) string {
+	searches, err := p.getSavedSearches(userID)
+	if err != nil {
+		p.API.LogWarn("Failed to get saved searches", "userID", userID, "error", err.Error())
+		return "Encountered an error deleting the search."
+	}
+
+	kept := []*SavedSearch{}
+	for _, search := range searches {
+		if search.Name != name {
+			kept = append(kept, search)
+		}
+	}
+	if len(kept) == len(searches) {
+		return fmt.Sprintf("You have no saved search named `%s`.", name)
+	}
+
+	if err := p.storeSavedSearches(userID, kept); err != nil {
+		p.API.LogWarn("Failed to store saved searches", "userID", userID, "error", err.Error())
+		return "Encountered an error deleting the search."
+	}
+
+	p.sendRefreshEvent(userID)
+
+	return fmt.Sprintf("Deleted the saved search `%s`.", name)
+}
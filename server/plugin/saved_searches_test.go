@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestSavedSearches(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	userInfo := &GitHubUserInfo{UserID: "userID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: token}}
+	info, err := json.Marshal(userInfo)
+	require.NoError(t, err)
+
+	queries := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/search/issues" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		query := r.URL.Query().Get("q")
+		queries = append(queries, query+" per_page:"+r.URL.Query().Get("per_page"))
+		w.Header().Set("Content-Type", "application/json")
+		switch query {
+		case "is:pr label:urgent org:mattermost", "is:pr org:mattermost":
+			fmt.Fprint(w, `{"total_count": 12, "items": [{"number": 1}, {"number": 2}]}`)
+		case "label:broken org:mattermost":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"message": "Validation Failed", "errors": [{"message": "The search is longer than 256 characters."}]}`)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func(searches ...*SavedSearch) (*Plugin, *plugintest.API, map[string][]byte) {
+		queries = []string{}
+
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{GitHubOrg: "mattermost", EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		store["userID"+githubTokenKey] = info
+		if len(searches) > 0 {
+			value, err := json.Marshal(searches)
+			require.NoError(t, err)
+			store["userID"+savedSearchesKey] = value
+		}
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("PublishWebSocketEvent", wsEventRefresh, mock.Anything, &model.WebsocketBroadcast{UserId: "userID"}).Maybe()
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		return p, api, store
+	}
+	args := &model.CommandArgs{UserId: "userID"}
+
+	storedSearches := func(t *testing.T, store map[string][]byte) []*SavedSearch {
+		searches := []*SavedSearch{}
+		require.NoError(t, json.Unmarshal(store["userID"+savedSearchesKey], &searches))
+		return searches
+	}
+
+	t.Run("save", func(t *testing.T) {
+		p, api, store := setup()
+
+		message := p.handleSearch(nil, args, []string{"save", "urgent-reviews", `"is:pr`, `label:urgent"`}, userInfo)
+		assert.Equal(t, "Saved the search `urgent-reviews`, currently matching 12 items. It shows in your GitHub sidebar.", message)
+		assert.Equal(t, []*SavedSearch{{Name: "urgent-reviews", Query: "is:pr label:urgent"}}, storedSearches(t, store))
+		// The query is only tried, the webapp fetches the results when refreshing the sidebar
+		assert.Equal(t, []string{"is:pr label:urgent org:mattermost per_page:1"}, queries)
+		api.AssertCalled(t, "PublishWebSocketEvent", wsEventRefresh, map[string]interface{}(nil), &model.WebsocketBroadcast{UserId: "userID"})
+	})
+
+	t.Run("save replaces the query of a search with the same name", func(t *testing.T) {
+		p, _, store := setup(&SavedSearch{Name: "urgent-reviews", Query: "is:issue"})
+
+		p.handleSearch(nil, args, []string{"save", "urgent-reviews", `"is:pr label:urgent"`}, userInfo)
+		assert.Equal(t, []*SavedSearch{{Name: "urgent-reviews", Query: "is:pr label:urgent"}}, storedSearches(t, store))
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		p, _, store := setup()
+
+		message := p.handleSearch(nil, args, []string{"save", "long", `"is:toolong"`}, userInfo)
+		assert.Equal(t, "GitHub can't run the search `is:toolong`: Validation Failed", message)
+		assert.Nil(t, store["userID"+savedSearchesKey])
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		p, _, _ := setup()
+
+		message := p.handleSearch(nil, args, []string{"save", "urgent/reviews", `"is:pr"`}, userInfo)
+		assert.Contains(t, message, "Please name the search")
+		assert.Empty(t, queries)
+	})
+
+	t.Run("too many searches", func(t *testing.T) {
+		searches := []*SavedSearch{}
+		for i := 0; i < maxSavedSearchesPerUser; i++ {
+			searches = append(searches, &SavedSearch{Name: fmt.Sprintf("search%d", i), Query: "is:pr"})
+		}
+		p, _, _ := setup(searches...)
+
+		message := p.handleSearch(nil, args, []string{"save", "another", `"is:pr"`}, userInfo)
+		assert.Equal(t, "You can have at most 5 saved searches. Please delete one first.", message)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		p, _, _ := setup()
+		assert.Contains(t, p.handleSearch(nil, args, []string{"list"}, userInfo), "You have no saved searches.")
+
+		p, _, _ = setup(&SavedSearch{Name: "urgent-reviews", Query: "is:pr label:urgent"})
+		assert.Equal(t, "### Your saved searches\n* `urgent-reviews` - `is:pr label:urgent`\n", p.handleSearch(nil, args, []string{"list"}, userInfo))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		p, _, store := setup(&SavedSearch{Name: "urgent-reviews", Query: "is:pr label:urgent"}, &SavedSearch{Name: "prs", Query: "is:pr"})
+
+		assert.Equal(t, "You have no saved search named `other`.", p.handleSearch(nil, args, []string{"delete", "other"}, userInfo))
+		assert.Equal(t, "Deleted the saved search `urgent-reviews`.", p.handleSearch(nil, args, []string{"delete", "urgent-reviews"}, userInfo))
+		assert.Equal(t, []*SavedSearch{{Name: "prs", Query: "is:pr"}}, storedSearches(t, store))
+	})
+
+	t.Run("endpoint", func(t *testing.T) {
+		p, _, _ := setup(&SavedSearch{Name: "urgent-reviews", Query: "is:pr label:urgent"}, &SavedSearch{Name: "broken", Query: "label:broken"})
+
+		w := httptest.NewRecorder()
+		p.getSavedSearchesList(w, httptest.NewRequest(http.MethodGet, "/api/v1/saved_searches", nil), "userID")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		results := []*SavedSearchResult{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+		require.Len(t, results, 2)
+		assert.Equal(t, "urgent-reviews", results[0].Name)
+		assert.Equal(t, 12, results[0].Total)
+		assert.Len(t, results[0].Items, 2)
+		assert.Equal(t, "broken", results[1].Name)
+		assert.Equal(t, "The search failed.", results[1].Error)
+		assert.Empty(t, results[1].Items)
+	})
+
+	t.Run("endpoint without saved searches", func(t *testing.T) {
+		p, _, _ := setup()
+
+		w := httptest.NewRecorder()
+		p.getSavedSearchesList(w, httptest.NewRequest(http.MethodGet, "/api/v1/saved_searches", nil), "userID")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[]`, w.Body.String())
+		assert.Empty(t, queries)
+	})
+}
@@ -0,0 +1,53 @@
+package plugin
+
+import (
+	"strings"
+)
+
+const (
+	selfEventKey = "_selfevent"
+
+	// selfEventTTL is how long a change made through the plugin is remembered, in seconds. GitHub
+	// usually delivers the webhook of the change within seconds, so it's kept short to not hide
+	// unrelated events.
+	selfEventTTL = 2 * 60
+)
+
+// The kinds of changes made through the plugin whose webhook events are suppressed.
+const (
+	selfEventIssueOpened       = "issues.opened"
+	selfEventIssueComment      = "issue_comment.created"
+	selfEventPullRequestOpened = "pull_request.opened"
+)
+
+func selfEventKeyFor(kind, objectURL, githubUsername, channelID string) string {
+	return hashKey(selfEventKey, kind, objectURL, strings.ToLower(githubUsername), channelID)
+}
+
+// trackSelfEvent remembers a change made through the plugin by a GitHub user, whose confirmation
+// was posted in a channel. The event GitHub sends for it isn't posted in that channel again.
+func (p *Plugin) trackSelfEvent(kind, objectURL, githubUsername, channelID string) {
+	if objectURL == "" || githubUsername == "" || channelID == "" {
+		return
+	}
+
+	if appErr := p.API.KVSetWithExpiry(selfEventKeyFor(kind, objectURL, githubUsername, channelID), []byte("1"), selfEventTTL); appErr != nil {
+		p.API.LogWarn("Failed to track change made through the plugin", "url", objectURL, "error", appErr.Error())
+	}
+}
+
+// isSelfEvent returns true if an event is for a change made through the plugin by the sender of
+// the event, whose confirmation was already posted in the channel. Only exact matches count.
+func (p *Plugin) isSelfEvent(kind, objectURL, githubUsername, channelID string) bool {
+	if objectURL == "" || githubUsername == "" {
+		return false
+	}
+
+	value, appErr := p.API.KVGet(selfEventKeyFor(kind, objectURL, githubUsername, channelID))
+	if appErr != nil {
+		p.API.LogWarn("Failed to get change made through the plugin", "url", objectURL, "error", appErr.Error())
+		return false
+	}
+
+	return value != nil
+}
@@ -0,0 +1,169 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+const (
+	selfEventIssueURL   = "https://github.com/owner/repo/issues/12"
+	selfEventCommentURL = "https://github.com/owner/repo/issues/12#issuecomment-1"
+)
+
+func setupSelfEventTest(t *testing.T) (*Plugin, *plugintest.API, *[]*model.Post) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "aliceID", GitHubUsername: "Alice", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/owner/repo/issues":
+			fmt.Fprintf(w, `{"number": 12, "html_url": %q}`, selfEventIssueURL)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/owner/repo/issues/12":
+			fmt.Fprint(w, `{"number": 12, "state": "open"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/repos/owner/repo/issues/12/comments":
+			fmt.Fprintf(w, `{"id": 1, "html_url": %q}`, selfEventCommentURL)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	subs, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "channelID", Repository: "owner/repo", Features: "issues,issue_comments"},
+			{ChannelID: "otherID", Repository: "owner/repo", Features: "issues,issue_comments"},
+		},
+	}})
+	require.NoError(t, err)
+
+	siteURL := "https://mattermost.example.com"
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store[SubscriptionsKey] = subs
+	store["aliceID"+githubTokenKey] = info
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+		store[key] = value
+		return nil
+	})
+	// The webhook handlers reuse their post for every channel, so copies are kept
+	posts := &[]*model.Post{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		*posts = append(*posts, post.Clone())
+		return post
+	}, nil)
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), []byte("1"), int64(selfEventTTL)).Return(func(key string, value []byte, _ int64) *model.AppError {
+		store[key] = value
+		return nil
+	})
+	api.On("GetPost", "postID").Return(&model.Post{Id: "postID", UserId: "aliceID", ChannelId: "channelID"}, nil)
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	api.On("GetChannel", mock.AnythingOfType("string")).Return(&model.Channel{}, nil)
+	api.On("GetUser", "aliceID").Return(&model.User{Id: "aliceID", Username: "alice"}, nil)
+	p.SetAPI(api)
+
+	return p, api, posts
+}
+
+func selfEventPostChannels(posts []*model.Post) []string {
+	channels := []string{}
+	for _, post := range posts {
+		channels = append(channels, post.ChannelId)
+	}
+	return channels
+}
+
+func TestSelfEventCreateIssue(t *testing.T) {
+	p, _, posts := setupSelfEventTest(t)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"title": "Title", "repo": "owner/repo", "post_id": "postID"}`)
+	p.createIssue(w, httptest.NewRequest(http.MethodPost, "/api/v1/createissue", body), "aliceID")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, *posts, 1)
+	*posts = nil
+
+	event := func(sender, url string) *github.IssuesEvent {
+		return &github.IssuesEvent{
+			Action: github.String("opened"),
+			Repo:   &github.Repository{FullName: github.String("owner/repo"), Name: github.String("repo"), Owner: &github.User{Login: github.String("owner")}},
+			Issue:  &github.Issue{Number: github.Int(12), HTMLURL: github.String(url), Title: github.String("Title"), User: &github.User{Login: github.String(sender)}},
+			Sender: &github.User{Login: github.String(sender)},
+		}
+	}
+
+	t.Run("only the channel with the confirmation is skipped", func(t *testing.T) {
+		*posts = nil
+		p.postIssueEvent(event("alice", selfEventIssueURL), testWebhookDelivery(p))
+		assert.Equal(t, []string{"otherID"}, selfEventPostChannels(*posts))
+	})
+
+	t.Run("another sender is posted", func(t *testing.T) {
+		*posts = nil
+		p.postIssueEvent(event("bob", selfEventIssueURL), testWebhookDelivery(p))
+		assert.Equal(t, []string{"channelID", "otherID"}, selfEventPostChannels(*posts))
+	})
+
+	t.Run("another issue is posted", func(t *testing.T) {
+		*posts = nil
+		p.postIssueEvent(event("alice", "https://github.com/owner/repo/issues/13"), testWebhookDelivery(p))
+		assert.Equal(t, []string{"channelID", "otherID"}, selfEventPostChannels(*posts))
+	})
+}
+
+func TestSelfEventCreateIssueComment(t *testing.T) {
+	p, api, posts := setupSelfEventTest(t)
+	api.On("KVGet", "postID"+attachedCommentsKey).Return(nil, nil)
+
+	w := httptest.NewRecorder()
+	body := strings.NewReader(`{"post_id": "postID", "owner": "owner", "repo": "repo", "number": 12, "comment": "comment"}`)
+	p.createIssueComment(w, httptest.NewRequest(http.MethodPost, "/api/v1/createissuecomment", body), "aliceID")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, *posts, 1)
+
+	event := func(sender, url string) *github.IssueCommentEvent {
+		return &github.IssueCommentEvent{
+			Action:  github.String("created"),
+			Repo:    &github.Repository{FullName: github.String("owner/repo"), Name: github.String("repo"), Owner: &github.User{Login: github.String("owner")}},
+			Issue:   &github.Issue{Number: github.Int(12), HTMLURL: github.String(selfEventIssueURL), Title: github.String("Title")},
+			Comment: &github.IssueComment{HTMLURL: github.String(url), Body: github.String("comment"), User: &github.User{Login: github.String(sender)}},
+			Sender:  &github.User{Login: github.String(sender)},
+		}
+	}
+
+	t.Run("only the channel with the confirmation is skipped", func(t *testing.T) {
+		*posts = nil
+		p.postIssueCommentEvent(event("alice", selfEventCommentURL), testWebhookDelivery(p))
+		assert.Equal(t, []string{"otherID"}, selfEventPostChannels(*posts))
+	})
+
+	t.Run("another sender is posted", func(t *testing.T) {
+		*posts = nil
+		p.postIssueCommentEvent(event("bob", selfEventCommentURL), testWebhookDelivery(p))
+		assert.Equal(t, []string{"channelID", "otherID"}, selfEventPostChannels(*posts))
+	})
+
+	t.Run("another comment is posted", func(t *testing.T) {
+		*posts = nil
+		p.postIssueCommentEvent(event("alice", "https://github.com/owner/repo/issues/12#issuecomment-2"), testWebhookDelivery(p))
+		assert.Equal(t, []string{"channelID", "otherID"}, selfEventPostChannels(*posts))
+	})
+}
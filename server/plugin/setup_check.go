@@ -0,0 +1,229 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+var (
+	// setupCheckTimeout is the time allowed to each check of `/github setup test`.
+	setupCheckTimeout = 10 * time.Second
+	// setupPingTimeout is how long `/github setup test` waits for the ping of the webhook to be delivered.
+	setupPingTimeout = 20 * time.Second
+	// setupPingPollInterval is how often the delivery of the ping is checked.
+	setupPingPollInterval = 500 * time.Millisecond
+)
+
+// setupCheck is an item of the checklist of `/github setup test`.
+type setupCheck struct {
+	Name   string
+	Passed bool
+	// Hint tells how to fix a failed check.
+	Hint string
+}
+
+// setupChecks collects the results of the checks of `/github setup test`. Checks depending on a
+// failed one are reported as skipped.
+type setupChecks struct {
+	checks []*setupCheck
+}
+
+func (s *setupChecks) add(name string, err error, hint string) bool {
+	check := &setupCheck{Name: name, Passed: err == nil}
+	if err != nil {
+		check.Hint = fmt.Sprintf("%s. %s", strings.TrimSuffix(err.Error(), "."), hint)
+	}
+	s.checks = append(s.checks, check)
+	return err == nil
+}
+
+func (s *setupChecks) skip(name string) {
+	s.checks = append(s.checks, &setupCheck{Name: name, Hint: "Skipped because a previous check failed."})
+}
+
+// getWebhookURL returns the URL GitHub webhooks must be configured to deliver to.
+func (p *Plugin) getWebhookURL() (string, error) {
+	siteURL := p.API.GetConfig().ServiceSettings.SiteURL
+	if siteURL == nil || *siteURL == "" {
+		return "", errors.New("the Site URL of Mattermost is not set")
+	}
+
+	return fmt.Sprintf("%s/plugins/%s/webhook", strings.TrimSuffix(*siteURL, "/"), Manifest.Id), nil
+}
+
+// findPluginHook returns the webhook delivering to the plugin among the given ones, or nil.
+func findPluginHook(hooks []*github.Hook, webhookURL string) *github.Hook {
+	for _, hook := range hooks {
+		url, _ := hook.Config["url"].(string)
+		if strings.EqualFold(strings.TrimSuffix(url, "/"), webhookURL) {
+			return hook
+		}
+	}
+	return nil
+}
+
+// findConfiguredWebhook looks for the webhook of the plugin on a repository, then on its owner.
+// It returns the hook along with the target it's configured on.
+func (p *Plugin) findConfiguredWebhook(ctx context.Context, githubClient *github.Client, owner, repo, webhookURL string) (*github.Hook, string, error) {
+	hooks, _, err := githubClient.Repositories.ListHooks(ctx, owner, repo, &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "could not list the webhooks of %s", fullNameFromOwnerAndRepo(owner, repo))
+	}
+	if hook := findPluginHook(hooks, webhookURL); hook != nil {
+		return hook, fullNameFromOwnerAndRepo(owner, repo), nil
+	}
+
+	// The webhook may be configured on the organization. Users can't have webhooks, so errors are ignored.
+	hooks, _, err = githubClient.Organizations.ListHooks(ctx, owner, &github.ListOptions{PerPage: 100})
+	if err == nil {
+		if hook := findPluginHook(hooks, webhookURL); hook != nil {
+			return hook, owner, nil
+		}
+	}
+
+	return nil, "", errors.Errorf("no webhook of %s or %s delivers to %s", fullNameFromOwnerAndRepo(owner, repo), owner, webhookURL)
+}
+
+// pingWebhook asks GitHub to ping a webhook and waits for the ping to be delivered, which only
+// succeeds if the webhook secret matches.
+func (p *Plugin) pingWebhook(ctx context.Context, githubClient *github.Client, hook *github.Hook, owner, repo, target string) error {
+	requestedAt := time.Now()
+
+	var err error
+	if target == owner {
+		_, err = githubClient.Organizations.PingHook(ctx, owner, hook.GetID())
+	} else {
+		_, err = githubClient.Repositories.PingHook(ctx, owner, repo, hook.GetID())
+	}
+	if err != nil {
+		return errors.Wrap(err, "could not request a ping")
+	}
+
+	// The ping may be handled by another server of the cluster, so its delivery is watched in the KV store
+	ticker := time.NewTicker(setupPingPollInterval)
+	defer ticker.Stop()
+	for {
+		info, err := p.getWebhookInfo(target)
+		if err != nil {
+			return err
+		}
+		if info != nil && info.HookID == hook.GetID() && !info.PingedAt.Before(requestedAt) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.New("the ping wasn't received in time")
+		case <-ticker.C:
+		}
+	}
+}
+
+// runSetupChecks checks the configuration of the plugin, the connection of the user, the webhook
+// of a repository and that the bot can post in a channel.
+func (p *Plugin) runSetupChecks(userInfo *GitHubUserInfo, channelID, owner, repo string) []*setupCheck {
+	checks := &setupChecks{}
+
+	checks.add("OAuth configuration", p.getConfiguration().IsValid(),
+		"Set the GitHub OAuth Client ID, Client Secret and the At Rest Encryption Key in the plugin settings.")
+
+	githubClient := p.githubConnect(*userInfo.Token)
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupCheckTimeout)
+	_, _, err := githubClient.Users.Get(ctx, "")
+	cancel()
+	connected := checks.add("Your connection to GitHub", err,
+		"Reconnect with `/github disconnect` and `/github connect`, and check the OAuth application on GitHub.")
+
+	webhookURL, err := p.getWebhookURL()
+	var hook *github.Hook
+	var target string
+	if connected && err == nil {
+		ctx, cancel = context.WithTimeout(context.Background(), setupCheckTimeout)
+		hook, target, err = p.findConfiguredWebhook(ctx, githubClient, owner, repo, webhookURL)
+		cancel()
+	}
+	if connected {
+		checks.add(fmt.Sprintf("Webhook for %s", fullNameFromOwnerAndRepo(owner, repo)), err,
+			"Add a webhook on the repository or its organization as explained in the plugin documentation. Listing webhooks requires admin access to the repository.")
+	} else {
+		checks.skip(fmt.Sprintf("Webhook for %s", fullNameFromOwnerAndRepo(owner, repo)))
+	}
+
+	if hook != nil {
+		ctx, cancel = context.WithTimeout(context.Background(), setupPingTimeout)
+		err = p.pingWebhook(ctx, githubClient, hook, owner, repo, target)
+		cancel()
+		checks.add("Webhook secret", err,
+			"Make sure the secret of the webhook on GitHub matches the Webhook Secret in the plugin settings, and that GitHub can reach Mattermost.")
+	} else {
+		checks.skip("Webhook secret")
+	}
+
+	var botErr error
+	if !p.API.HasPermissionToChannel(p.BotUserID, channelID, model.PERMISSION_CREATE_POST) {
+		botErr = errors.New("the bot can't post in this channel")
+	}
+	checks.add("Bot posting in this channel", botErr,
+		"Add the GitHub bot to the channel, or check the permissions of the channel.")
+
+	return checks.checks
+}
+
+// formatSetupChecks renders the results of `/github setup test` as a checklist.
+func formatSetupChecks(repository string, checks []*setupCheck) string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "#### GitHub setup test for %s\n", repository)
+
+	for _, check := range checks {
+		mark := ":white_check_mark:"
+		if !check.Passed {
+			mark = ":x:"
+		}
+
+		fmt.Fprintf(&message, "* %s %s", mark, check.Name)
+		if check.Hint != "" {
+			fmt.Fprintf(&message, " - %s", check.Hint)
+		}
+		message.WriteString("\n")
+	}
+
+	return message.String()
+}
+
+func (p *Plugin) handleSetup(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if !p.isSystemAdmin(args.UserId) {
+		return "Only system administrators can test the setup."
+	}
+
+	if len(parameters) == 1 && parameters[0] == "welcome" {
+		return "Users connecting their account will get this welcome message:\n\n---\n" + p.getWelcomeMessage(userInfo.GitHubUsername, p.getBaseURL()+userInfo.GitHubUsername, "")
+	}
+
+	if len(parameters) > 0 && parameters[0] == "announcement" {
+		return p.handleSetupAnnouncement(args, parameters[1:])
+	}
+
+	if len(parameters) != 2 || parameters[0] != "test" {
+		return "Invalid setup command. Use `/github setup test owner/repo`, `/github setup welcome` or `/github setup announcement --channels town-square,engineering`."
+	}
+
+	owner, repo := parseOwnerAndRepo(parameters[1], p.getBaseURL())
+	if owner == "" || repo == "" {
+		return "Please specify a repository as `owner/repo`."
+	}
+
+	go func() {
+		checks := p.runSetupChecks(userInfo, args.ChannelId, owner, repo)
+		p.postSetupChecks(args.UserId, args.ChannelId, fullNameFromOwnerAndRepo(owner, repo), checks)
+	}()
+
+	return "Testing the setup. The results will be sent to you in a direct message."
+}
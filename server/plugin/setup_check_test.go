@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestRunSetupChecks(t *testing.T) {
+	defer func(timeout, interval time.Duration) {
+		setupPingTimeout, setupPingPollInterval = timeout, interval
+	}(setupPingTimeout, setupPingPollInterval)
+	setupPingTimeout = 200 * time.Millisecond
+	setupPingPollInterval = 10 * time.Millisecond
+
+	siteURL := "https://mattermost.example.com"
+	webhookURL := "https://mattermost.example.com/plugins/github/webhook"
+
+	type server struct {
+		userStatus int
+		hookURL    string
+		delivered  bool
+	}
+
+	run := func(t *testing.T, s server) []*setupCheck {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/api/v3/user":
+				w.WriteHeader(s.userStatus)
+				fmt.Fprint(w, `{"login": "alice"}`)
+			case "/api/v3/repos/owner/repo/hooks":
+				fmt.Fprintf(w, `[{"id": 1, "config": {"url": %q}}]`, s.hookURL)
+			case "/api/v3/orgs/owner/hooks":
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"message": "Not Found"}`)
+			case "/api/v3/repos/owner/repo/hooks/1/pings":
+				if s.delivered {
+					info, err := json.Marshal(&WebhookInfo{HookID: 1, PingedAt: time.Now()})
+					require.NoError(t, err)
+					store[hashKey(webhookInfoKey, "owner/repo")] = info
+				}
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer ts.Close()
+
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{
+			GitHubOAuthClientID:     "clientID",
+			GitHubOAuthClientSecret: "clientSecret",
+			EncryptionKey:           "abcdefghijklmnopqrstuvwxyz123456",
+			EnterpriseBaseURL:       ts.URL,
+			EnterpriseUploadURL:     ts.URL,
+		})
+		api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		api.On("HasPermissionToChannel", "botID", "channelID", model.PERMISSION_CREATE_POST).Return(true)
+		p.SetAPI(api)
+
+		return p.runSetupChecks(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: "token"}}, "channelID", "owner", "repo")
+	}
+
+	passed := func(checks []*setupCheck) []bool {
+		results := []bool{}
+		for _, check := range checks {
+			results = append(results, check.Passed)
+		}
+		return results
+	}
+
+	t.Run("everything works", func(t *testing.T) {
+		checks := run(t, server{userStatus: http.StatusOK, hookURL: webhookURL, delivered: true})
+		assert.Equal(t, []bool{true, true, true, true, true}, passed(checks))
+	})
+
+	t.Run("ping not delivered", func(t *testing.T) {
+		checks := run(t, server{userStatus: http.StatusOK, hookURL: webhookURL})
+		assert.Equal(t, []bool{true, true, true, false, true}, passed(checks))
+		assert.Contains(t, checks[3].Hint, "the ping wasn't received in time. Make sure the secret of the webhook")
+	})
+
+	t.Run("no webhook", func(t *testing.T) {
+		checks := run(t, server{userStatus: http.StatusOK, hookURL: "https://elsewhere.example.com/webhook"})
+		assert.Equal(t, []bool{true, true, false, false, true}, passed(checks))
+		assert.Contains(t, checks[2].Hint, "no webhook of owner/repo or owner delivers to "+webhookURL)
+		assert.Equal(t, "Skipped because a previous check failed.", checks[3].Hint)
+	})
+
+	t.Run("not connected", func(t *testing.T) {
+		checks := run(t, server{userStatus: http.StatusUnauthorized, hookURL: webhookURL, delivered: true})
+		assert.Equal(t, []bool{true, false, false, false, true}, passed(checks))
+		assert.Equal(t, "Skipped because a previous check failed.", checks[2].Hint)
+	})
+}
+
+func TestFormatSetupChecks(t *testing.T) {
+	checks := []*setupCheck{
+		{Name: "OAuth configuration", Passed: true},
+		{Name: "Webhook secret", Hint: "the ping wasn't received in time. Check the secret."},
+	}
+
+	assert.Equal(t, "#### GitHub setup test for owner/repo\n"+
+		"* :white_check_mark: OAuth configuration\n"+
+		"* :x: Webhook secret - the ping wasn't received in time. Check the secret.\n",
+		formatSetupChecks("owner/repo", checks))
+}
+
+func TestHandleSetup(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("HasPermissionTo", "adminID", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+	api.On("HasPermissionTo", "userID", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+	p.SetAPI(api)
+
+	assert.Equal(t, "Only system administrators can test the setup.",
+		p.handleSetup(nil, &model.CommandArgs{UserId: "userID"}, []string{"test", "owner/repo"}, nil))
+	assert.Equal(t, "Invalid setup command. Use `/github setup test owner/repo`, `/github setup welcome` or `/github setup announcement --channels town-square,engineering`.",
+		p.handleSetup(nil, &model.CommandArgs{UserId: "adminID"}, []string{"test"}, nil))
+	assert.Equal(t, "Please specify a repository as `owner/repo`.",
+		p.handleSetup(nil, &model.CommandArgs{UserId: "adminID"}, []string{"test", "owner"}, nil))
+}
@@ -0,0 +1,226 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	setupSubscribeActionSubscribe = "subscribe"
+	setupSubscribeActionDecline   = "decline"
+
+	setupSubscribeContextAction     = "action"
+	setupSubscribeContextRepository = "repository"
+	setupSubscribeContextChannelID  = "channel_id"
+	// setupSubscribeContextSelected is set by Mattermost to the option chosen in a select.
+	setupSubscribeContextSelected = "selected_option"
+)
+
+// setupChecksPassed reports whether all checks of `/github setup test` passed.
+func setupChecksPassed(checks []*setupCheck) bool {
+	for _, check := range checks {
+		if !check.Passed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isChannelSubscribedToRepository reports whether a channel has a subscription to a repository.
+func (p *Plugin) isChannelSubscribedToRepository(channelID, repository string) (bool, error) {
+	subs, err := p.findRepositorySubscriptions(repository)
+	if err != nil {
+		return false, err
+	}
+
+	for _, sub := range subs {
+		if sub.ChannelID == channelID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (p *Plugin) getSetupSubscribeAction(name, action, repository, channelID string) *model.PostAction {
+	actionContext := map[string]interface{}{
+		setupSubscribeContextAction:     action,
+		setupSubscribeContextRepository: repository,
+	}
+	if channelID != "" {
+		actionContext[setupSubscribeContextChannelID] = channelID
+	}
+
+	return &model.PostAction{
+		Name: name,
+		Integration: &model.PostActionIntegration{
+			URL:     fmt.Sprintf("/plugins/%s/api/v1/setup/subscribe", Manifest.Id),
+			Context: actionContext,
+		},
+	}
+}
+
+// getSetupSubscribePrompt returns the prompt offering to subscribe the channel `/github setup test`
+// was run from to the tested repository, or nil if the channel is already subscribed. When run
+// from a direct or group message, the admin is asked which channel to subscribe instead.
+func (p *Plugin) getSetupSubscribePrompt(channelID, repository string) (*model.SlackAttachment, error) {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get channel")
+	}
+
+	decline := p.getSetupSubscribeAction("No thanks", setupSubscribeActionDecline, repository, "")
+
+	if channel.Type == model.CHANNEL_DIRECT || channel.Type == model.CHANNEL_GROUP {
+		choose := p.getSetupSubscribeAction("Choose a channel", setupSubscribeActionSubscribe, repository, "")
+		choose.Type = model.POST_ACTION_TYPE_SELECT
+		choose.DataSource = "channels"
+
+		return &model.SlackAttachment{
+			Text:    fmt.Sprintf("Subscribe a channel to %s now?", repository),
+			Actions: []*model.PostAction{choose, decline},
+		}, nil
+	}
+
+	subscribed, err := p.isChannelSubscribedToRepository(channelID, repository)
+	if err != nil {
+		return nil, err
+	}
+	if subscribed {
+		return nil, nil
+	}
+
+	return &model.SlackAttachment{
+		Text: fmt.Sprintf("Subscribe ~%s to %s now?", channel.Name, repository),
+		Actions: []*model.PostAction{
+			p.getSetupSubscribeAction("Subscribe", setupSubscribeActionSubscribe, repository, channelID),
+			decline,
+		},
+	}, nil
+}
+
+// postSetupChecks sends the results of `/github setup test` to the admin. When all checks passed,
+// subscribing a channel to the repository is offered along.
+func (p *Plugin) postSetupChecks(userID, channelID, repository string, checks []*setupCheck) {
+	dmChannelID, err := p.getBotDMChannelID(userID)
+	if err != nil {
+		p.API.LogWarn("Failed to get the bot DM channel for the setup test", "userID", userID, "error", err.Error())
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: dmChannelID,
+		Message:   formatSetupChecks(repository, checks),
+	}
+
+	if setupChecksPassed(checks) {
+		prompt, err := p.getSetupSubscribePrompt(channelID, repository)
+		if err != nil {
+			p.API.LogWarn("Failed to prepare the subscription prompt of the setup test", "channelID", channelID, "repo", repository, "error", err.Error())
+		} else if prompt != nil {
+			model.ParseSlackAttachment(post, []*model.SlackAttachment{prompt})
+		}
+	}
+
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to post the setup test results", "userID", userID, "error", appErr.Error())
+	}
+}
+
+func (p *Plugin) handleSetupSubscribeAction(w http.ResponseWriter, r *http.Request, userID string) {
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a post action request.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	p.writeJSON(w, p.runSetupSubscribeAction(userID, request))
+}
+
+// setupSubscribeOutcome replaces the prompt of the setup test results with its outcome, keeping
+// the checklist.
+func (p *Plugin) setupSubscribeOutcome(request *model.PostActionIntegrationRequest, outcome string) *model.PostActionIntegrationResponse {
+	message := outcome
+	if post, appErr := p.API.GetPost(request.PostId); appErr == nil {
+		message = post.Message + "\n" + outcome
+	}
+
+	return &model.PostActionIntegrationResponse{Update: &model.Post{Message: message}}
+}
+
+// runSetupSubscribeAction subscribes a channel to the repository tested by `/github setup test`
+// with the default features, as `/github subscriptions add` would, or dismisses the prompt.
+func (p *Plugin) runSetupSubscribeAction(userID string, request *model.PostActionIntegrationRequest) *model.PostActionIntegrationResponse {
+	response := &model.PostActionIntegrationResponse{}
+
+	action, _ := request.Context[setupSubscribeContextAction].(string)
+	repository, _ := request.Context[setupSubscribeContextRepository].(string)
+
+	switch action {
+	case setupSubscribeActionDecline:
+		return p.setupSubscribeOutcome(request, fmt.Sprintf("No channel was subscribed to %s.", repository))
+	case setupSubscribeActionSubscribe:
+	default:
+		response.EphemeralText = fmt.Sprintf("Unknown action %q.", action)
+		return response
+	}
+
+	if !p.isSystemAdmin(userID) {
+		response.EphemeralText = "Only system administrators can subscribe channels from the setup test."
+		return response
+	}
+	if p.getConfiguration().isCommandDisabled("subscriptions") {
+		response.EphemeralText = disabledCommandMessage
+		return response
+	}
+
+	channelID, _ := request.Context[setupSubscribeContextChannelID].(string)
+	if channelID == "" {
+		channelID, _ = request.Context[setupSubscribeContextSelected].(string)
+	}
+	if channelID == "" {
+		response.EphemeralText = "Please choose a channel to subscribe."
+		return response
+	}
+
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		p.API.LogWarn("Failed to get channel", "channelID", channelID, "error", appErr.Error())
+		response.EphemeralText = "Encountered an error subscribing the channel."
+		return response
+	}
+	if _, appErr := p.API.GetChannelMember(channelID, userID); appErr != nil {
+		response.EphemeralText = fmt.Sprintf("You must be a member of ~%s to subscribe it.", channel.Name)
+		return response
+	}
+
+	subscribed, err := p.isChannelSubscribedToRepository(channelID, repository)
+	if err != nil {
+		p.API.LogWarn("Failed to check the subscriptions of a channel", "channelID", channelID, "error", err.Error())
+		response.EphemeralText = "Encountered an error subscribing the channel."
+		return response
+	}
+	if subscribed {
+		return p.setupSubscribeOutcome(request, fmt.Sprintf("~%s is already subscribed to %s.", channel.Name, repository))
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		response.EphemeralText = "You must connect your account to GitHub first. Either click on the GitHub logo in the bottom left of the screen or enter `/github connect`."
+		return response
+	}
+
+	owner, repo := parseOwnerAndRepo(repository, p.getBaseURL())
+	if err := p.Subscribe(context.Background(), p.getGithubClient(info), userID, owner, repo, channelID, defaultSubscriptionFeatures, SubscriptionFlags{}); err != nil {
+		response.EphemeralText = err.Error()
+		return response
+	}
+
+	return p.setupSubscribeOutcome(request, fmt.Sprintf("Successfully subscribed ~%s to %s.", channel.Name, repository))
+}
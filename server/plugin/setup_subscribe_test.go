@@ -0,0 +1,196 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestPostSetupChecks(t *testing.T) {
+	passed := []*setupCheck{{Name: "OAuth configuration", Passed: true}, {Name: "Webhook secret", Passed: true}}
+
+	setup := func(subs map[string][]*Subscription) (*Plugin, *[]*model.Post) {
+		p, api, _, posts := setupSubscriptionCreatorTest(t, &Configuration{}, subs)
+		api.On("GetDirectChannel", "adminID", "botID").Return(&model.Channel{Id: "dmID", Type: model.CHANNEL_DIRECT}, nil)
+		api.On("GetChannel", "dmID").Return(&model.Channel{Id: "dmID", Type: model.CHANNEL_DIRECT}, nil)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		return p, posts
+	}
+
+	t.Run("channel not subscribed", func(t *testing.T) {
+		p, posts := setup(nil)
+
+		p.postSetupChecks("adminID", "channelID", "owner/repo", passed)
+		require.Len(t, *posts, 1)
+		post := (*posts)[0]
+		assert.Equal(t, "dmID", post.ChannelId)
+		assert.Equal(t, formatSetupChecks("owner/repo", passed), post.Message)
+
+		attachments := post.Attachments()
+		require.Len(t, attachments, 1)
+		assert.Equal(t, "Subscribe ~town-square to owner/repo now?", attachments[0].Text)
+		require.Len(t, attachments[0].Actions, 2)
+		subscribe := attachments[0].Actions[0]
+		assert.Equal(t, "Subscribe", subscribe.Name)
+		assert.Equal(t, "/plugins/github/api/v1/setup/subscribe", subscribe.Integration.URL)
+		assert.Equal(t, map[string]interface{}{"action": "subscribe", "repository": "owner/repo", "channel_id": "channelID"}, subscribe.Integration.Context)
+		assert.Equal(t, "No thanks", attachments[0].Actions[1].Name)
+	})
+
+	t.Run("channel already subscribed", func(t *testing.T) {
+		p, posts := setup(map[string][]*Subscription{
+			"owner/repo": {{ChannelID: "channelID", CreatorID: "aliceID", Features: "pulls", Repository: "owner/repo"}},
+		})
+
+		p.postSetupChecks("adminID", "channelID", "owner/repo", passed)
+		require.Len(t, *posts, 1)
+		assert.Empty(t, (*posts)[0].Attachments())
+	})
+
+	t.Run("started from a direct message", func(t *testing.T) {
+		p, posts := setup(nil)
+
+		p.postSetupChecks("adminID", "dmID", "owner/repo", passed)
+		require.Len(t, *posts, 1)
+		attachments := (*posts)[0].Attachments()
+		require.Len(t, attachments, 1)
+		assert.Equal(t, "Subscribe a channel to owner/repo now?", attachments[0].Text)
+		choose := attachments[0].Actions[0]
+		assert.Equal(t, model.POST_ACTION_TYPE_SELECT, choose.Type)
+		assert.Equal(t, "channels", choose.DataSource)
+		assert.NotContains(t, choose.Integration.Context, "channel_id")
+	})
+
+	t.Run("failed checks", func(t *testing.T) {
+		p, posts := setup(nil)
+
+		p.postSetupChecks("adminID", "channelID", "owner/repo", []*setupCheck{{Name: "Webhook secret", Hint: "the ping wasn't received in time."}})
+		require.Len(t, *posts, 1)
+		assert.Empty(t, (*posts)[0].Attachments())
+	})
+}
+
+func TestRunSetupSubscribeAction(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "adminID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo":
+			fmt.Fprint(w, `{"full_name": "owner/repo"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func(subs map[string][]*Subscription) *Plugin {
+		p, api, store, _ := setupSubscriptionCreatorTest(t, &Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"}, subs)
+		store["adminID"+githubTokenKey] = info
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("int64")).Return(func(key string, value []byte, ttl int64) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("HasPermissionTo", "adminID", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+		api.On("HasPermissionTo", "userID", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Name: "town-square", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannel", "otherID").Return(&model.Channel{Id: "otherID", Name: "engineering", Type: model.CHANNEL_OPEN}, nil)
+		api.On("GetChannelMember", mock.AnythingOfType("string"), "adminID").Return(&model.ChannelMember{}, nil)
+		api.On("GetPost", "postID").Return(&model.Post{Id: "postID", Message: "#### GitHub setup test for owner/repo\n"}, nil)
+		return p
+	}
+	request := func(action string, context map[string]interface{}) *model.PostActionIntegrationRequest {
+		request := &model.PostActionIntegrationRequest{
+			PostId:    "postID",
+			ChannelId: "dmID",
+			Context:   map[string]interface{}{"action": action, "repository": "owner/repo"},
+		}
+		for key, value := range context {
+			request.Context[key] = value
+		}
+		return request
+	}
+
+	t.Run("confirm", func(t *testing.T) {
+		p := setup(map[string][]*Subscription{})
+
+		response := p.runSetupSubscribeAction("adminID", request(setupSubscribeActionSubscribe, map[string]interface{}{"channel_id": "channelID"}))
+		assert.Empty(t, response.EphemeralText)
+		require.NotNil(t, response.Update)
+		assert.Equal(t, "#### GitHub setup test for owner/repo\n\nSuccessfully subscribed ~town-square to owner/repo.", response.Update.Message)
+
+		subs, err := p.GetSubscriptionsByChannel("channelID")
+		require.NoError(t, err)
+		require.Len(t, subs, 1)
+		assert.Equal(t, "owner/repo", subs[0].Repository)
+		assert.Equal(t, "adminID", subs[0].CreatorID)
+		assert.Equal(t, defaultSubscriptionFeatures, subs[0].Features)
+	})
+
+	t.Run("confirm a channel chosen from a direct message", func(t *testing.T) {
+		p := setup(map[string][]*Subscription{})
+
+		response := p.runSetupSubscribeAction("adminID", request(setupSubscribeActionSubscribe, map[string]interface{}{"selected_option": "otherID"}))
+		require.NotNil(t, response.Update)
+		assert.Contains(t, response.Update.Message, "Successfully subscribed ~engineering to owner/repo.")
+
+		subscribed, err := p.isChannelSubscribedToRepository("otherID", "owner/repo")
+		require.NoError(t, err)
+		assert.True(t, subscribed)
+	})
+
+	t.Run("decline", func(t *testing.T) {
+		p := setup(map[string][]*Subscription{})
+
+		response := p.runSetupSubscribeAction("adminID", request(setupSubscribeActionDecline, nil))
+		require.NotNil(t, response.Update)
+		assert.Equal(t, "#### GitHub setup test for owner/repo\n\nNo channel was subscribed to owner/repo.", response.Update.Message)
+
+		subs, err := p.GetSubscriptionsByChannel("channelID")
+		require.NoError(t, err)
+		assert.Empty(t, subs)
+	})
+
+	t.Run("already subscribed", func(t *testing.T) {
+		p := setup(map[string][]*Subscription{
+			"owner/repo": {{ChannelID: "channelID", CreatorID: "bobID", Features: "issues", Repository: "owner/repo"}},
+		})
+
+		response := p.runSetupSubscribeAction("adminID", request(setupSubscribeActionSubscribe, map[string]interface{}{"channel_id": "channelID"}))
+		require.NotNil(t, response.Update)
+		assert.Contains(t, response.Update.Message, "~town-square is already subscribed to owner/repo.")
+
+		subs, err := p.GetSubscriptionsByChannel("channelID")
+		require.NoError(t, err)
+		require.Len(t, subs, 1)
+		assert.Equal(t, "issues", subs[0].Features, "the subscription is left unchanged")
+		assert.Equal(t, "bobID", subs[0].CreatorID)
+	})
+
+	t.Run("not a system admin", func(t *testing.T) {
+		p := setup(map[string][]*Subscription{})
+
+		response := p.runSetupSubscribeAction("userID", request(setupSubscribeActionSubscribe, map[string]interface{}{"channel_id": "channelID"}))
+		assert.Nil(t, response.Update)
+		assert.Equal(t, "Only system administrators can subscribe channels from the setup test.", response.EphemeralText)
+	})
+
+	t.Run("no channel chosen", func(t *testing.T) {
+		p := setup(map[string][]*Subscription{})
+
+		response := p.runSetupSubscribeAction("adminID", request(setupSubscribeActionSubscribe, nil))
+		assert.Equal(t, "Please choose a channel to subscribe.", response.EphemeralText)
+	})
+}
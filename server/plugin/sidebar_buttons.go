@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	settingSidebar = "sidebar"
+	// settingSidebarDefault goes back to all the sidebar buttons.
+	settingSidebarDefault = "default"
+
+	sidebarButtonPrs         = "prs"
+	sidebarButtonReviews     = "reviews"
+	sidebarButtonAssignments = "assignments"
+	sidebarButtonUnreads     = "unreads"
+	sidebarButtonRefresh     = "refresh"
+
+	wsEventSidebarButtons = "sidebar_buttons"
+)
+
+// defaultSidebarButtons are the sidebar buttons shown to users who didn't choose theirs, in order.
+var defaultSidebarButtons = []string{sidebarButtonPrs, sidebarButtonReviews, sidebarButtonAssignments, sidebarButtonUnreads, sidebarButtonRefresh}
+
+// sidebarButtonSections maps the sidebar buttons showing items to the section of the sidebar they
+// are fetched as. The refresh button has no items.
+var sidebarButtonSections = map[string]string{
+	sidebarButtonPrs:         sidebarYourPrs,
+	sidebarButtonReviews:     sidebarReviews,
+	sidebarButtonAssignments: sidebarAssignments,
+	sidebarButtonUnreads:     sidebarUnreads,
+}
+
+// parseSidebarButtons parses a comma-separated ordered list of sidebar buttons. An empty value and
+// the legacy team value, stored for all users before the buttons could be chosen, are all buttons.
+func parseSidebarButtons(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == settingButtonsTeam || value == settingSidebarDefault {
+		return defaultSidebarButtons, nil
+	}
+
+	buttons := []string{}
+	for _, button := range strings.Split(value, ",") {
+		button = strings.ToLower(strings.TrimSpace(button))
+		if button == "" {
+			continue
+		}
+		if !SliceContainsString(defaultSidebarButtons, button) {
+			return nil, errors.Errorf("Unknown sidebar button %q. Accepted buttons are: %s.", button, strings.Join(defaultSidebarButtons, ", "))
+		}
+		if SliceContainsString(buttons, button) {
+			return nil, errors.Errorf("The sidebar button %q is listed more than once.", button)
+		}
+		buttons = append(buttons, button)
+	}
+
+	if len(buttons) == 0 {
+		return nil, errors.Errorf("Please list at least one sidebar button, e.g. `%s,%s,%s`.", sidebarButtonPrs, sidebarButtonReviews, sidebarButtonRefresh)
+	}
+
+	return buttons, nil
+}
+
+// sidebarButtons returns the sidebar buttons chosen by the user, or all of them if the stored
+// value can't be parsed.
+func (s *UserSettings) sidebarButtons() []string {
+	if s == nil {
+		return defaultSidebarButtons
+	}
+
+	buttons, err := parseSidebarButtons(s.SidebarButtons)
+	if err != nil {
+		return defaultSidebarButtons
+	}
+
+	return buttons
+}
+
+// handleSidebarSetting stores the sidebar buttons given to `/github settings sidebar`, which may
+// be separated by commas and spaces.
+func handleSidebarSetting(settings *UserSettings, parameters []string) (string, error) {
+	value := strings.Join(parameters, ",")
+	buttons, err := parseSidebarButtons(value)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(value) == settingSidebarDefault {
+		settings.SidebarButtons = settingButtonsTeam
+		return "Your sidebar shows all the buttons again.", nil
+	}
+
+	settings.SidebarButtons = strings.Join(buttons, ",")
+	return fmt.Sprintf("Your sidebar now shows: %s.", strings.Join(buttons, ", ")), nil
+}
+
+// sendSidebarButtonsEvent tells the webapp of a user which sidebar buttons to show.
+func (p *Plugin) sendSidebarButtonsEvent(userID string, buttons []string) {
+	p.API.PublishWebSocketEvent(
+		wsEventSidebarButtons,
+		map[string]interface{}{"sidebar_button_list": buttons},
+		&model.WebsocketBroadcast{UserId: userID},
+	)
+}
+
+// lhsContent is the content of several sidebar sections, keyed by their button. Sections that
+// couldn't be fetched have an error instead, so that the webapp keeps showing their last items.
+type lhsContent struct {
+	Sections map[string]json.RawMessage `json:"sections"`
+	Errors   map[string]string          `json:"errors,omitempty"`
+}
+
+// getLHSContent returns the items of several sidebar sections at once. Only the sections given in
+// the sections parameter, or else the ones of the buttons chosen by the user, are fetched, so that
+// hidden buttons don't use the API quota of the user.
+func (p *Plugin) getLHSContent(w http.ResponseWriter, r *http.Request, userID string) {
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		p.writeAPIError(w, apiErr)
+		return
+	}
+
+	buttons := info.Settings.sidebarButtons()
+	if sections := r.URL.Query().Get("sections"); sections != "" {
+		var err error
+		buttons, err = parseSidebarButtons(sections)
+		if err != nil {
+			p.writeAPIError(w, &APIErrorResponse{ID: "", Message: err.Error(), StatusCode: http.StatusBadRequest})
+			return
+		}
+	}
+
+	refresh := isSidebarRefresh(r)
+	githubClient := p.githubConnect(*info.Token)
+	content := &lhsContent{Sections: map[string]json.RawMessage{}, Errors: map[string]string{}}
+	for _, button := range buttons {
+		section, ok := sidebarButtonSections[button]
+		if !ok {
+			continue
+		}
+
+		if refresh {
+			p.clearCachedSidebarSection(userID, section)
+		} else if cached := p.getCachedSidebarSection(userID, section); cached != nil {
+			content.Sections[button] = cached
+			continue
+		}
+
+		data, _, err := p.fetchSidebarSection(r.Context(), githubClient, info.GitHubUsername, section, "")
+		if err != nil {
+			p.API.LogWarn("Failed to fetch sidebar section", "userID", userID, "section", section, "error", err.Error())
+			content.Errors[button] = newAPIError(err, fmt.Sprintf("Failed to fetch the %s of the sidebar.", button)).Message
+			continue
+		}
+
+		value, err := json.Marshal(data)
+		if err != nil {
+			p.API.LogWarn("Failed to marshal sidebar section", "section", section, "error", err.Error())
+			content.Errors[button] = fmt.Sprintf("Failed to fetch the %s of the sidebar.", button)
+			continue
+		}
+		content.Sections[button] = value
+	}
+
+	// Users showing both sections see the pull requests requested from their teams in the reviews only
+	if assignments, ok := content.Sections[sidebarButtonAssignments]; ok {
+		if reviews, ok := content.Sections[sidebarButtonReviews]; ok {
+			deduplicated, err := withoutReviews(assignments, reviews)
+			if err != nil {
+				p.API.LogWarn("Failed to deduplicate assignments", "error", err.Error())
+			} else {
+				content.Sections[sidebarButtonAssignments] = deduplicated
+			}
+		}
+	}
+
+	p.writeJSON(w, content)
+}
@@ -0,0 +1,249 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestParseSidebarButtons(t *testing.T) {
+	for _, value := range []string{"", " ", settingButtonsTeam, settingSidebarDefault} {
+		buttons, err := parseSidebarButtons(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, []string{"prs", "reviews", "assignments", "unreads", "refresh"}, buttons, value)
+	}
+
+	buttons, err := parseSidebarButtons("Refresh, prs,,reviews")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"refresh", "prs", "reviews"}, buttons)
+
+	_, err = parseSidebarButtons("prs,mentions")
+	assert.EqualError(t, err, `Unknown sidebar button "mentions". Accepted buttons are: prs, reviews, assignments, unreads, refresh.`)
+	_, err = parseSidebarButtons("prs,reviews,prs")
+	assert.EqualError(t, err, `The sidebar button "prs" is listed more than once.`)
+	_, err = parseSidebarButtons(",")
+	assert.EqualError(t, err, "Please list at least one sidebar button, e.g. `prs,reviews,refresh`.")
+
+	// Unparsable stored values show all buttons
+	assert.Equal(t, defaultSidebarButtons, (&UserSettings{SidebarButtons: "left"}).sidebarButtons())
+	assert.Equal(t, []string{"unreads"}, (&UserSettings{SidebarButtons: "unreads"}).sidebarButtons())
+}
+
+func TestHandleSidebarSetting(t *testing.T) {
+	settings := &UserSettings{SidebarButtons: settingButtonsTeam}
+
+	message, err := handleSidebarSetting(settings, []string{"prs,", "reviews,refresh"})
+	require.NoError(t, err)
+	assert.Equal(t, "Your sidebar now shows: prs, reviews, refresh.", message)
+	assert.Equal(t, "prs,reviews,refresh", settings.SidebarButtons)
+
+	_, err = handleSidebarSetting(settings, []string{"prs,issues"})
+	assert.Error(t, err)
+	assert.Equal(t, "prs,reviews,refresh", settings.SidebarButtons)
+
+	message, err = handleSidebarSetting(settings, []string{"default"})
+	require.NoError(t, err)
+	assert.Equal(t, "Your sidebar shows all the buttons again.", message)
+	assert.Equal(t, settingButtonsTeam, settings.SidebarButtons)
+}
+
+func TestHandleSettingsSidebar(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EncryptionKey: "abcdefghijklmnopqrstuvwxyz123456"})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+		store[key] = value
+		return nil
+	})
+	api.On("PublishWebSocketEvent", mock.Anything, mock.Anything, mock.Anything)
+	p.SetAPI(api)
+
+	userInfo := &GitHubUserInfo{UserID: "userID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: "token"}, Settings: &UserSettings{SidebarButtons: settingButtonsTeam}}
+	assert.Equal(t, "Your sidebar now shows: reviews, refresh.", p.handleSettings(nil, nil, []string{"sidebar", "reviews,refresh"}, userInfo))
+
+	stored := &GitHubUserInfo{}
+	require.NoError(t, json.Unmarshal(store["userID"+githubTokenKey], stored))
+	assert.Equal(t, "reviews,refresh", stored.Settings.SidebarButtons)
+	api.AssertCalled(t, "PublishWebSocketEvent", wsEventSidebarButtons, map[string]interface{}{"sidebar_button_list": []string{"reviews", "refresh"}}, &model.WebsocketBroadcast{UserId: "userID"})
+
+	assert.Equal(t, "Unknown sidebar button \"issues\". Accepted buttons are: prs, reviews, assignments, unreads, refresh.", p.handleSettings(nil, nil, []string{"sidebar", "issues"}, userInfo))
+}
+
+func TestGetLHSContent(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+
+	var searches []string
+	notifications := 0
+	failNotifications := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v3/search/issues"):
+			query := r.URL.Query().Get("q")
+			searches = append(searches, query)
+			number := 1
+			if strings.Contains(query, "review-requested") {
+				number = 2
+			}
+			fmt.Fprintf(w, `{"total_count": 1, "items": [{"number": %d}]}`, number)
+		case strings.HasPrefix(r.URL.Path, "/api/v3/notifications"):
+			notifications++
+			if failNotifications {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, `{"message": "Server Error"}`)
+				return
+			}
+			fmt.Fprint(w, `[]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func(sidebarButtons string) (*Plugin, map[string][]byte) {
+		searches = nil
+		notifications = 0
+		failNotifications = false
+
+		info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice", Settings: &UserSettings{SidebarButtons: sidebarButtons}})
+		require.NoError(t, err)
+
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		store["userID"+githubTokenKey] = info
+		api.On("KVDelete", mock.AnythingOfType("string")).Return(func(key string) *model.AppError {
+			delete(store, key)
+			return nil
+		})
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+		return p, store
+	}
+
+	getContent := func(p *Plugin, url string) (int, *lhsContent) {
+		w := httptest.NewRecorder()
+		p.getLHSContent(w, httptest.NewRequest(http.MethodGet, url, nil), "userID")
+
+		content := &lhsContent{}
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), content))
+		}
+		return w.Code, content
+	}
+
+	t.Run("sections of the chosen buttons", func(t *testing.T) {
+		p, _ := setup("reviews,refresh")
+
+		code, content := getContent(p, "/api/v1/lhs-content")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, map[string]json.RawMessage{"reviews": json.RawMessage(`[{"number":2}]`)}, content.Sections)
+		assert.Empty(t, content.Errors)
+		require.Len(t, searches, 1)
+		assert.Contains(t, searches[0], "review-requested:alice")
+		assert.Zero(t, notifications)
+	})
+
+	t.Run("legacy team value", func(t *testing.T) {
+		p, _ := setup(settingButtonsTeam)
+
+		code, content := getContent(p, "/api/v1/lhs-content")
+		require.Equal(t, http.StatusOK, code)
+		assert.Len(t, content.Sections, 4)
+		assert.Equal(t, 1, notifications)
+	})
+
+	t.Run("failing section", func(t *testing.T) {
+		p, _ := setup("prs,unreads")
+		failNotifications = true
+
+		code, content := getContent(p, "/api/v1/lhs-content")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, map[string]json.RawMessage{"prs": json.RawMessage(`[{"number":1}]`)}, content.Sections)
+		assert.Contains(t, content.Errors, "unreads")
+	})
+
+	t.Run("requested sections", func(t *testing.T) {
+		p, store := setup(settingButtonsTeam)
+		store[sidebarCacheKeyFor("userID", sidebarUnreads)] = []byte(`[{"id":"1"}]`)
+
+		code, content := getContent(p, "/api/v1/lhs-content?sections=unreads,prs")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, map[string]json.RawMessage{
+			"prs":     json.RawMessage(`[{"number":1}]`),
+			"unreads": json.RawMessage(`[{"id":"1"}]`),
+		}, content.Sections)
+		assert.Len(t, searches, 1)
+		assert.Zero(t, notifications, "prefetched unreads are served")
+		assert.NotContains(t, store, sidebarCacheKeyFor("userID", sidebarUnreads))
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		p, store := setup(settingButtonsTeam)
+		store[sidebarCacheKeyFor("userID", sidebarUnreads)] = []byte(`[{"id":"1"}]`)
+
+		code, content := getContent(p, "/api/v1/lhs-content?sections=unreads&refresh=true")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, map[string]json.RawMessage{"unreads": json.RawMessage(`[]`)}, content.Sections)
+		assert.Equal(t, 1, notifications, "prefetched unreads are skipped")
+		assert.NotContains(t, store, sidebarCacheKeyFor("userID", sidebarUnreads))
+	})
+
+	t.Run("invalid sections", func(t *testing.T) {
+		p, _ := setup(settingButtonsTeam)
+
+		code, _ := getContent(p, "/api/v1/lhs-content?sections=prs,stars")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Empty(t, searches)
+	})
+}
+
+func TestUpdateSettingsSidebarButtons(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice", Settings: &UserSettings{SidebarButtons: settingButtonsTeam}})
+	require.NoError(t, err)
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store["userID"+githubTokenKey] = info
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+		store[key] = value
+		return nil
+	})
+	api.On("PublishWebSocketEvent", mock.Anything, mock.Anything, mock.Anything)
+	p.SetAPI(api)
+
+	w := httptest.NewRecorder()
+	p.updateSettings(w, httptest.NewRequest(http.MethodPost, "/api/v1/settings", strings.NewReader(`{"sidebar_buttons": "prs,refresh", "notifications": true}`)), "userID")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "prs,refresh", resp["sidebar_buttons"])
+	assert.Equal(t, true, resp["notifications"])
+	assert.Equal(t, []interface{}{"prs", "refresh"}, resp["sidebar_button_list"])
+	api.AssertCalled(t, "PublishWebSocketEvent", wsEventSidebarButtons, map[string]interface{}{"sidebar_button_list": []string{"prs", "refresh"}}, &model.WebsocketBroadcast{UserId: "userID"})
+
+	w = httptest.NewRecorder()
+	p.updateSettings(w, httptest.NewRequest(http.MethodPost, "/api/v1/settings", strings.NewReader(`{"sidebar_buttons": "prs,stars"}`)), "userID")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
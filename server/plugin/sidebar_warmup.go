@@ -0,0 +1,282 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	lastSeenKey        = "_lastseen"
+	sidebarCacheKey    = "_sidebarcache"
+	sidebarWarmupKey   = "sidebar_warmup"
+	sidebarReviews     = "reviews"
+	sidebarYourPrs     = "yourprs"
+	sidebarAssignments = "assignments"
+	sidebarUnreads     = "unreads"
+
+	// lastSeenTTL is how long a user counts as recently active after using the sidebar, in seconds.
+	lastSeenTTL = 3 * 24 * 60 * 60
+	// lastSeenWriteInterval throttles how often the last seen time of a user is stored by a server.
+	lastSeenWriteInterval = time.Hour
+	// sidebarCacheTTL is how long prefetched sidebar data is served, in seconds.
+	sidebarCacheTTL = 15 * 60
+	// sidebarWarmupTTL is how long a warm-up claimed by a server keeps the others from warming up, in seconds.
+	sidebarWarmupTTL = 15 * 60
+	// sidebarWarmupConcurrency is the number of users whose sidebar is prefetched at the same time.
+	sidebarWarmupConcurrency = 2
+)
+
+var (
+	sidebarSections = []string{sidebarReviews, sidebarYourPrs, sidebarAssignments, sidebarUnreads}
+
+	// sidebarWarmupDelay is the pause of a warm-up worker between two users, spreading the warm-up over
+	// several minutes to stay clear of the secondary rate limits of GitHub.
+	sidebarWarmupDelay = 2 * time.Second
+)
+
+func sidebarCacheKeyFor(userID, section string) string {
+	return hashKey(sidebarCacheKey, userID, section)
+}
+
+// trackLastSeen records when users last used the sidebar, so that the sidebars of recently active
+// users are prefetched after a restart.
+func (p *Plugin) trackLastSeen(handler HTTPHandlerFuncWithUser) HTTPHandlerFuncWithUser {
+	return func(w http.ResponseWriter, r *http.Request, userID string) {
+		now := time.Now()
+		if last, ok := p.lastSeenWrites.Load(userID); !ok || now.Sub(last.(time.Time)) > lastSeenWriteInterval {
+			p.lastSeenWrites.Store(userID, now)
+			if appErr := p.API.KVSetWithExpiry(userID+lastSeenKey, []byte(strconv.FormatInt(now.Unix(), 10)), lastSeenTTL); appErr != nil {
+				p.API.LogWarn("Failed to store last seen time", "userID", userID, "error", appErr.Error())
+			}
+		}
+
+		handler(w, r, userID)
+	}
+}
+
+// recentlyActiveUserIDs returns the IDs of the users who used the sidebar recently among KV store keys.
+func recentlyActiveUserIDs(keys []string) []string {
+	userIDs := []string{}
+	for _, key := range keys {
+		if strings.HasSuffix(key, lastSeenKey) {
+			userIDs = append(userIDs, strings.TrimSuffix(key, lastSeenKey))
+		}
+	}
+
+	return userIDs
+}
+
+// fetchSidebarSection gets the data of a section of the sidebar of a user from GitHub. The pull
+// requests and issues are filtered by label if one is given.
+func (p *Plugin) fetchSidebarSection(ctx context.Context, githubClient *github.Client, username, section, label string) (interface{}, *github.Response, error) {
+	org := p.getConfiguration().GitHubOrg
+
+	var query string
+	switch section {
+	case sidebarReviews:
+		query = getReviewSearchQuery(username, org)
+	case sidebarYourPrs:
+		query = getYourPrsSearchQuery(username, org)
+	case sidebarAssignments:
+		return p.fetchAssignments(ctx, githubClient, username, label)
+	case sidebarUnreads:
+		notifications, err := p.getUnreadsData(ctx, githubClient, time.Time{})
+		return notifications, nil, err
+	default:
+		return nil, nil, errors.Errorf("unknown sidebar section %s", section)
+	}
+
+	if label != "" {
+		query += " " + labelSearchQualifier(label)
+	}
+
+	result, resp, err := githubClient.Search.Issues(ctx, query, &github.SearchOptions{})
+	if err != nil {
+		return nil, resp, errors.Wrapf(err, "failed to search for %s", query)
+	}
+
+	for _, issue := range result.Issues {
+		issue.Labels = capLabels(issue.Labels)
+	}
+
+	return result.Issues, resp, nil
+}
+
+// getCachedSidebarSection returns the prefetched data of a section of the sidebar of a user, if
+// any. Prefetched data is only served once, so that refreshing the sidebar gets fresh data.
+func (p *Plugin) getCachedSidebarSection(userID, section string) []byte {
+	key := sidebarCacheKeyFor(userID, section)
+	value, appErr := p.API.KVGet(key)
+	if appErr != nil || value == nil {
+		return nil
+	}
+
+	if appErr := p.API.KVDelete(key); appErr != nil {
+		p.API.LogWarn("Failed to delete prefetched sidebar data", "userID", userID, "section", section, "error", appErr.Error())
+	}
+
+	return value
+}
+
+// writeCachedSidebarSection writes the prefetched data of a section of the sidebar of a user, if
+// any. Explicit refreshes of the sidebar discard the prefetched data instead.
+func (p *Plugin) writeCachedSidebarSection(w http.ResponseWriter, r *http.Request, userID, section string) bool {
+	if isSidebarRefresh(r) {
+		p.clearCachedSidebarSection(userID, section)
+		return false
+	}
+
+	value := p.getCachedSidebarSection(userID, section)
+	if value == nil {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(value); err != nil {
+		p.API.LogWarn("Failed to write prefetched sidebar data", "error", err.Error())
+	}
+	return true
+}
+
+// isSidebarRefresh reports whether a request for sidebar data comes from an explicit refresh,
+// which must get fresh data.
+func isSidebarRefresh(r *http.Request) bool {
+	return r.URL.Query().Get("refresh") == "true"
+}
+
+func (p *Plugin) clearCachedSidebarSection(userID, section string) {
+	if appErr := p.API.KVDelete(sidebarCacheKeyFor(userID, section)); appErr != nil {
+		p.API.LogWarn("Failed to delete prefetched sidebar data", "userID", userID, "section", section, "error", appErr.Error())
+	}
+}
+
+// clearSidebarCache deletes the prefetched sidebar data of a user, e.g. when they connect another
+// GitHub account.
+func (p *Plugin) clearSidebarCache(userID string) {
+	for _, section := range sidebarSections {
+		p.clearCachedSidebarSection(userID, section)
+	}
+}
+
+// prefetchSidebar stores the data of all sections of the sidebar of a user, and returns the
+// remaining search rate limit of the user, or -1 if unknown.
+func (p *Plugin) prefetchSidebar(ctx context.Context, userID string) (int, error) {
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		return -1, errors.New(apiErr.Message)
+	}
+
+	githubClient := p.githubConnect(*info.Token)
+	remaining := -1
+	for _, section := range sidebarSections {
+		data, resp, err := p.fetchSidebarSection(ctx, githubClient, info.GitHubUsername, section, "")
+		if resp != nil {
+			remaining = resp.Rate.Remaining
+		}
+		if err != nil {
+			return remaining, errors.Wrapf(err, "failed to fetch %s", section)
+		}
+
+		value, err := json.Marshal(data)
+		if err != nil {
+			return remaining, errors.Wrapf(err, "failed to marshal %s", section)
+		}
+
+		if appErr := p.API.KVSetWithExpiry(sidebarCacheKeyFor(userID, section), value, sidebarCacheTTL); appErr != nil {
+			return remaining, errors.Wrapf(appErr, "failed to store %s", section)
+		}
+	}
+
+	return remaining, nil
+}
+
+// warmUpSidebars prefetches the sidebars of recently active users after a restart, a few at a time,
+// so that the requests of all webapps reloading at once are served from the cache. Only the first
+// server of a cluster to start does so. It stops when ctx is canceled.
+func (p *Plugin) warmUpSidebars(ctx context.Context) {
+	claimed, appErr := p.API.KVSetWithOptions(sidebarWarmupKey, []byte(time.Now().UTC().Format(time.RFC3339)), model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: sidebarWarmupTTL,
+	})
+	if appErr != nil {
+		p.API.LogWarn("Failed to claim sidebar warm-up", "error", appErr.Error())
+		return
+	}
+	if !claimed {
+		p.API.LogDebug("Sidebar warm-up already done by another server")
+		return
+	}
+
+	userIDs := []string{}
+	for page := 0; ; page++ {
+		keys, appErr := p.API.KVList(page, refreshAllPageSize)
+		if appErr != nil {
+			p.API.LogWarn("Failed to list keys for sidebar warm-up", "error", appErr.Error())
+			return
+		}
+
+		userIDs = append(userIDs, recentlyActiveUserIDs(keys)...)
+
+		if len(keys) < refreshAllPageSize {
+			break
+		}
+	}
+
+	p.API.LogDebug("Starting sidebar warm-up", "users", len(userIDs))
+
+	var lock sync.Mutex
+	done, failures := 0, 0
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, sidebarWarmupConcurrency)
+	for _, userID := range userIDs {
+		select {
+		case <-ctx.Done():
+		case workers <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			wg.Wait()
+			p.API.LogDebug("Sidebar warm-up canceled", "progress", fmt.Sprintf("%d/%d", done, len(userIDs)))
+			return
+		}
+
+		userID := userID
+		wg.Add(1)
+		go func() {
+			defer func() {
+				select {
+				case <-ctx.Done():
+				case <-time.After(sidebarWarmupDelay):
+				}
+				<-workers
+				wg.Done()
+			}()
+
+			remaining, err := p.prefetchSidebar(ctx, userID)
+
+			lock.Lock()
+			defer lock.Unlock()
+			done++
+			if err != nil {
+				failures++
+				p.API.LogDebug("Failed to prefetch sidebar", "userID", userID, "progress", fmt.Sprintf("%d/%d", done, len(userIDs)), "search_rate_limit_remaining", remaining, "error", err.Error())
+				return
+			}
+			p.API.LogDebug("Prefetched sidebar", "userID", userID, "progress", fmt.Sprintf("%d/%d", done, len(userIDs)), "search_rate_limit_remaining", remaining)
+		}()
+	}
+	wg.Wait()
+
+	p.API.LogDebug("Finished sidebar warm-up", "users", len(userIDs), "failures", failures)
+}
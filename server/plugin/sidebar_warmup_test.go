@@ -0,0 +1,198 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestTrackLastSeen(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("KVSetWithExpiry", "userID"+lastSeenKey, mock.Anything, int64(lastSeenTTL)).Return(nil).Once()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	calls := 0
+	handler := p.trackLastSeen(func(w http.ResponseWriter, r *http.Request, userID string) {
+		calls++
+	})
+
+	// The last seen time is only stored once in a while
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/reviews", nil), "userID")
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/reviews", nil), "userID")
+	assert.Equal(t, 2, calls)
+}
+
+func TestRecentlyActiveUserIDs(t *testing.T) {
+	keys := []string{"user1" + lastSeenKey, "user1" + githubTokenKey, "user2" + lastSeenKey, "subscriptions"}
+	assert.Equal(t, []string{"user1", "user2"}, recentlyActiveUserIDs(keys))
+}
+
+func TestWriteCachedSidebarSection(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("KVGet", sidebarCacheKeyFor("userID", sidebarReviews)).Return([]byte(`[{"number":1}]`), nil).Once()
+	api.On("KVDelete", sidebarCacheKeyFor("userID", sidebarReviews)).Return(nil).Once()
+	api.On("KVGet", sidebarCacheKeyFor("userID", sidebarReviews)).Return(nil, nil)
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/reviews", nil)
+	w := httptest.NewRecorder()
+	require.True(t, p.writeCachedSidebarSection(w, r, "userID", sidebarReviews))
+	assert.Equal(t, `[{"number":1}]`, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	assert.False(t, p.writeCachedSidebarSection(httptest.NewRecorder(), r, "userID", sidebarReviews))
+
+	t.Run("refresh", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("KVDelete", sidebarCacheKeyFor("userID", sidebarReviews)).Return(nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/reviews?refresh=true", nil)
+		assert.False(t, p.writeCachedSidebarSection(httptest.NewRecorder(), r, "userID", sidebarReviews))
+	})
+}
+
+func TestClearSidebarCache(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	for _, section := range sidebarSections {
+		api.On("KVDelete", sidebarCacheKeyFor("userID", section)).Return(nil).Once()
+	}
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	p.clearSidebarCache("userID")
+}
+
+func TestWarmUpSidebars(t *testing.T) {
+	defer func(delay time.Duration) { sidebarWarmupDelay = delay }(sidebarWarmupDelay)
+	sidebarWarmupDelay = 0
+
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "user1", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice"})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/api/v3/search/issues"):
+			w.Header().Set("X-RateLimit-Remaining", "27")
+			fmt.Fprint(w, `{"total_count": 1, "items": [{"number": 1}]}`)
+		case strings.HasPrefix(r.URL.Path, "/api/v3/notifications"):
+			fmt.Fprint(w, `[]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func(claimed bool) (*Plugin, *plugintest.API) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+		api := &plugintest.API{}
+		api.On("KVSetWithOptions", sidebarWarmupKey, mock.Anything, mock.MatchedBy(func(opts model.PluginKVSetOptions) bool {
+			return opts.Atomic && opts.OldValue == nil && opts.ExpireInSeconds == sidebarWarmupTTL
+		})).Return(claimed, nil).Once()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("LogDebug", mock.Anything).Maybe()
+		p.SetAPI(api)
+		return p, api
+	}
+
+	t.Run("claimed by another server", func(t *testing.T) {
+		p, api := setup(false)
+		defer api.AssertExpectations(t)
+
+		p.warmUpSidebars(context.Background())
+		api.AssertNotCalled(t, "KVList", mock.Anything, mock.Anything)
+	})
+
+	t.Run("sidebars prefetched", func(t *testing.T) {
+		p, api := setup(true)
+		api.On("KVList", 0, refreshAllPageSize).Return([]string{"user1" + lastSeenKey, "user1" + githubTokenKey, "user2" + githubTokenKey}, nil).Once()
+		api.On("KVGet", "user1"+githubTokenKey).Return(info, nil)
+		for _, section := range []string{sidebarReviews, sidebarYourPrs} {
+			api.On("KVSetWithExpiry", sidebarCacheKeyFor("user1", section), []byte(`[{"number":1}]`), int64(sidebarCacheTTL)).Return(nil).Once()
+		}
+		// GraphQL isn't served, so the assignments are only those of the REST search
+		api.On("KVSetWithExpiry", sidebarCacheKeyFor("user1", sidebarAssignments), []byte(`[{"number":1,"type":"issue","source":"assignee"}]`), int64(sidebarCacheTTL)).Return(nil).Once()
+		api.On("KVSetWithExpiry", sidebarCacheKeyFor("user1", sidebarUnreads), []byte(`[]`), int64(sidebarCacheTTL)).Return(nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.warmUpSidebars(context.Background())
+		api.AssertCalled(t, "LogDebug", "Prefetched sidebar", "userID", "user1", "progress", "1/1", "search_rate_limit_remaining", 27)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		p, api := setup(true)
+		api.On("KVList", 0, refreshAllPageSize).Return([]string{"user1" + lastSeenKey}, nil).Once()
+		defer api.AssertExpectations(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		p.warmUpSidebars(ctx)
+		api.AssertNotCalled(t, "KVGet", "user1"+githubTokenKey)
+	})
+}
+
+func TestGetYourPrsByLabel(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice"})
+	require.NoError(t, err)
+
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("q")
+		labels := []string{}
+		for i := 0; i < 12; i++ {
+			labels = append(labels, fmt.Sprintf(`{"name": "label%d"}`, i))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"total_count": 1, "items": [{"number": 1, "labels": [%s]}]}`, strings.Join(labels, ", "))
+	}))
+	defer ts.Close()
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+	api := &plugintest.API{}
+	api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	// Prefetched data isn't read, as it isn't filtered
+	w := httptest.NewRecorder()
+	p.getYourPrs(w, httptest.NewRequest(http.MethodGet, "/api/v1/yourprs?label=needs-qa", nil), "userID")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `is:pr is:open author:alice archived:false  label:"needs-qa"`, query)
+
+	var issues []*github.Issue
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &issues))
+	require.Len(t, issues, 1)
+	assert.Len(t, issues[0].Labels, 10)
+}
@@ -0,0 +1,240 @@
+package plugin
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// starEventType is the type of the webhook events sent when a repository is starred or unstarred.
+	starEventType = "star"
+
+	starMilestonesFlag = "star-milestones"
+
+	starMilestoneKey = "_starmilestone"
+	// starMilestoneUpdateAttempts is the number of times a concurrent update of a milestone is retried.
+	starMilestoneUpdateAttempts = 5
+)
+
+// StarEvent is triggered when a repository is starred or unstarred. The StarEvent of go-github
+// lacks the repository and the sender.
+type StarEvent struct {
+	Action *string            `json:"action,omitempty"`
+	Repo   *github.Repository `json:"repository,omitempty"`
+	Sender *github.User       `json:"sender,omitempty"`
+}
+
+func (e *StarEvent) GetAction() string {
+	if e == nil || e.Action == nil {
+		return ""
+	}
+	return *e.Action
+}
+
+func (e *StarEvent) GetRepo() *github.Repository {
+	if e == nil {
+		return nil
+	}
+	return e.Repo
+}
+
+func (e *StarEvent) GetSender() *github.User {
+	if e == nil {
+		return nil
+	}
+	return e.Sender
+}
+
+func parseStarEvent(body []byte) (*StarEvent, error) {
+	var event *StarEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// starMilestone is the data of the post of a star milestone.
+type starMilestone struct {
+	*StarEvent
+	Milestone int
+}
+
+// parseStarMilestones parses a comma-delimited list of star counts, returned in increasing order.
+func parseStarMilestones(value string) ([]int, error) {
+	invalid := errors.Errorf("Invalid value %q for --%s. Use a comma-delimited list of star counts, e.g. `100,500,1000`.", value, starMilestonesFlag)
+
+	milestones := []int{}
+	for _, part := range strings.Split(value, ",") {
+		milestone, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || milestone <= 0 {
+			return nil, invalid
+		}
+		if !containsInt(milestones, milestone) {
+			milestones = append(milestones, milestone)
+		}
+	}
+	sort.Ints(milestones)
+
+	return milestones, nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// reachedStarMilestone returns the highest milestone reached by a star count, or 0 if none is reached.
+func reachedStarMilestone(milestones []int, stars int) int {
+	reached := 0
+	for _, milestone := range milestones {
+		if milestone <= stars {
+			reached = milestone
+		}
+	}
+	return reached
+}
+
+func starMilestoneKeyFor(sub *Subscription) string {
+	return hashKey(starMilestoneKey, sub.ChannelID, sub.Repository)
+}
+
+// claimStarMilestone records the milestone reached by the stars of a repository for a subscription,
+// returning whether it should be posted. A milestone is posted once, when it's higher than the last
+// recorded one. The first milestone recorded is only posted if the star count just crossed it, so
+// that subscribing to a popular repository doesn't post a milestone reached long ago.
+func (p *Plugin) claimStarMilestone(sub *Subscription, milestone, stars int) (bool, error) {
+	key := starMilestoneKeyFor(sub)
+
+	for attempt := 0; attempt < starMilestoneUpdateAttempts; attempt++ {
+		oldValue, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return false, errors.Wrap(appErr, "could not get star milestone from KV store")
+		}
+
+		post := stars == milestone
+		if oldValue != nil {
+			last, err := strconv.Atoi(string(oldValue))
+			if err != nil {
+				return false, errors.Wrap(err, "could not parse star milestone")
+			}
+			if milestone <= last {
+				return false, nil
+			}
+			post = true
+		}
+
+		stored, appErr := p.API.KVCompareAndSet(key, oldValue, []byte(strconv.Itoa(milestone)))
+		if appErr != nil {
+			return false, errors.Wrap(appErr, "could not store star milestone in KV store")
+		}
+		if stored {
+			return post, nil
+		}
+	}
+
+	return false, errors.New("too many concurrent updates of the star milestone")
+}
+
+func (p *Plugin) postStarEvent(event *StarEvent, delivery *webhookDelivery) {
+	if event.GetAction() != "created" {
+		return
+	}
+
+	repo := event.GetRepo()
+
+	subs := delivery.matchSubscriptions(p.GetSubscribedChannelsForRepository(repo))
+	if len(subs) == 0 {
+		return
+	}
+
+	for _, sub := range subs {
+		if !sub.Stars() {
+			continue
+		}
+
+		if p.excludeSender(event.GetSender(), sub) {
+			continue
+		}
+
+		if sub.Flags.StarMilestones == "" {
+			p.postNewStar(event, sub, delivery)
+			continue
+		}
+
+		p.postStarMilestone(event, sub, delivery)
+	}
+}
+
+// postNewStar posts every star of a repository, for subscriptions without milestones.
+func (p *Plugin) postNewStar(event *StarEvent, sub *Subscription, delivery *webhookDelivery) {
+	message, err := renderTemplate("newStar", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	repo := event.GetRepo()
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: sub.ChannelID,
+		Type:      "custom_git_star",
+		Message:   p.applyNotificationTemplate(sub, event, message),
+		Props:     eventPostProps(repo.GetFullName(), objectTypeRepository, repo.GetFullName(), starEventType),
+	}
+	p.postSubscriptionEvent(post, sub, featureStars, delivery)
+}
+
+// postStarMilestone posts the milestone reached by the stars of a repository, if it wasn't posted yet.
+func (p *Plugin) postStarMilestone(event *StarEvent, sub *Subscription, delivery *webhookDelivery) {
+	milestones, err := parseStarMilestones(sub.Flags.StarMilestones)
+	if err != nil {
+		p.API.LogWarn("Invalid star milestones", "channelID", sub.ChannelID, "repo", sub.Repository, "error", err.Error())
+		return
+	}
+
+	repo := event.GetRepo()
+	stars := repo.GetStargazersCount()
+	milestone := reachedStarMilestone(milestones, stars)
+	if milestone == 0 {
+		return
+	}
+
+	shouldPost, err := p.claimStarMilestone(sub, milestone, stars)
+	if err != nil {
+		p.API.LogWarn("Failed to claim star milestone", "channelID", sub.ChannelID, "repo", sub.Repository, "error", err.Error())
+		return
+	}
+	if !shouldPost {
+		return
+	}
+
+	message, err := renderTemplate("starMilestone", &starMilestone{StarEvent: event, Milestone: milestone})
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: sub.ChannelID,
+		Type:      "custom_git_star",
+		Message:   message,
+		Props:     eventPostProps(repo.GetFullName(), objectTypeRepository, repo.GetFullName(), starEventType+".milestone"),
+	}
+
+	// Milestones are rare enough to never be sampled, but count towards the rate cap
+	if delivery.Replayed {
+		post = markReplayed(post)
+	}
+	p.createDeliveryPost(post, sub, featureStars, delivery)
+}
@@ -0,0 +1,177 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStarMilestones(t *testing.T) {
+	milestones, err := parseStarMilestones("1000, 100,500,100")
+	require.NoError(t, err)
+	assert.Equal(t, []int{100, 500, 1000}, milestones)
+
+	for _, value := range []string{"", "100,", "-5", "0", "1k"} {
+		_, err := parseStarMilestones(value)
+		assert.Error(t, err, value)
+	}
+
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(starMilestonesFlag, "1000,100"))
+	assert.Equal(t, "--star-milestones 100,1000", flags.String())
+}
+
+func TestReachedStarMilestone(t *testing.T) {
+	milestones := []int{100, 500, 1000}
+
+	assert.Equal(t, 0, reachedStarMilestone(milestones, 99))
+	assert.Equal(t, 100, reachedStarMilestone(milestones, 100))
+	assert.Equal(t, 100, reachedStarMilestone(milestones, 499))
+	assert.Equal(t, 1000, reachedStarMilestone(milestones, 25000))
+}
+
+func TestPostStarEvent(t *testing.T) {
+	setup := func(subs ...*Subscription) (*Plugin, map[string][]byte, *[]*model.Post) {
+		subs = append(subs, &Subscription{ChannelID: "pushesChannelID", Features: featurePushes, Repository: "owner/repo"})
+		subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{"owner/repo": subs}})
+		require.NoError(t, err)
+
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		store[SubscriptionsKey] = subscriptions
+		posts := &[]*model.Post{}
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			*posts = append(*posts, post.Clone())
+			return post
+		}, nil)
+		p.SetAPI(api)
+
+		return p, store, posts
+	}
+
+	star := func(action string, stars int) *StarEvent {
+		return &StarEvent{
+			Action: github.String(action),
+			Repo:   &github.Repository{FullName: github.String("owner/repo"), HTMLURL: github.String("https://github.com/owner/repo"), StargazersCount: github.Int(stars)},
+			Sender: &github.User{Login: github.String("alice"), HTMLURL: github.String("https://github.com/alice")},
+		}
+	}
+
+	t.Run("every star without milestones", func(t *testing.T) {
+		p, _, posts := setup(&Subscription{ChannelID: "starsChannelID", Features: featureStars, Repository: "owner/repo"})
+
+		p.postStarEvent(star("created", 41), testWebhookDelivery(p))
+		p.postStarEvent(star("deleted", 40), testWebhookDelivery(p))
+		p.postStarEvent(star("created", 41), testWebhookDelivery(p))
+
+		require.Len(t, *posts, 2)
+		assert.Equal(t, "starsChannelID", (*posts)[0].ChannelId)
+		assert.Equal(t, "custom_git_star", (*posts)[0].Type)
+		assert.Equal(t, "[\\[owner/repo\\]](https://github.com/owner/repo) starred by [alice](https://github.com/alice), now at 41 stars", strings.TrimSpace((*posts)[0].Message))
+	})
+
+	t.Run("milestones", func(t *testing.T) {
+		sub := &Subscription{ChannelID: "starsChannelID", Features: featureStars, Repository: "owner/repo", Flags: SubscriptionFlags{StarMilestones: "100,500,1000"}}
+		p, store, posts := setup(sub)
+
+		for _, stars := range []int{98, 99, 100, 99, 100, 101, 499} {
+			p.postStarEvent(star("created", stars), testWebhookDelivery(p))
+		}
+		require.Len(t, *posts, 1, "a milestone is posted once, even if the star count drops below it")
+		assert.Equal(t, "[\\[owner/repo\\]](https://github.com/owner/repo) reached **100 stars** :star: The latest one is from [alice](https://github.com/alice)", strings.TrimSpace((*posts)[0].Message))
+		assert.Equal(t, "star.milestone", (*posts)[0].GetProp(postPropEventType))
+		assert.Equal(t, []byte("100"), store[starMilestoneKeyFor(sub)])
+
+		// Missed events don't hold back the next milestone
+		p.postStarEvent(star("created", 503), testWebhookDelivery(p))
+		require.Len(t, *posts, 2)
+		assert.Contains(t, (*posts)[1].Message, "reached **500 stars**")
+	})
+
+	t.Run("milestone reached before subscribing", func(t *testing.T) {
+		sub := &Subscription{ChannelID: "starsChannelID", Features: featureStars, Repository: "owner/repo", Flags: SubscriptionFlags{StarMilestones: "100,500,1000"}}
+		p, store, posts := setup(sub)
+
+		p.postStarEvent(star("created", 720), testWebhookDelivery(p))
+		assert.Empty(t, *posts)
+		assert.Equal(t, []byte("500"), store[starMilestoneKeyFor(sub)])
+
+		p.postStarEvent(star("created", 1000), testWebhookDelivery(p))
+		require.Len(t, *posts, 1)
+		assert.Contains(t, (*posts)[0].Message, "reached **1000 stars**")
+	})
+
+	t.Run("milestones over the rate cap", func(t *testing.T) {
+		sub := &Subscription{ChannelID: "starsChannelID", Features: featureStars, Repository: "owner/repo", Flags: SubscriptionFlags{StarMilestones: "100,500", MaxPerHour: 1}}
+		p, store, posts := setup(sub)
+		p.API.(*plugintest.API).On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("model.PluginKVSetOptions")).Return(func(key string, value []byte, opts model.PluginKVSetOptions) bool {
+			store[key] = value
+			return true
+		}, nil)
+
+		delivery := testWebhookDelivery(p)
+		p.postStarEvent(star("created", 100), delivery)
+		p.postStarEvent(star("created", 500), delivery)
+
+		require.Len(t, *posts, 2)
+		assert.Contains(t, (*posts)[0].Message, "reached **100 stars**")
+		assert.Contains(t, (*posts)[1].Message, "Rate cap of 1 notifications per hour reached")
+		assert.Equal(t, 1, delivery.summary().Filtered[filterReasonRateCapped])
+	})
+
+	t.Run("milestones of each subscription", func(t *testing.T) {
+		other := &Subscription{ChannelID: "otherChannelID", Features: featureStars, Repository: "owner/repo", Flags: SubscriptionFlags{StarMilestones: "50,100"}}
+		p, _, posts := setup(&Subscription{ChannelID: "starsChannelID", Features: featureStars, Repository: "owner/repo", Flags: SubscriptionFlags{StarMilestones: "100"}}, other)
+
+		p.postStarEvent(star("created", 50), testWebhookDelivery(p))
+		p.postStarEvent(star("created", 100), testWebhookDelivery(p))
+
+		channels := []string{}
+		for _, post := range *posts {
+			channels = append(channels, post.ChannelId)
+		}
+		assert.Equal(t, []string{"otherChannelID", "starsChannelID", "otherChannelID"}, channels)
+	})
+}
+
+func TestPostForkEvent(t *testing.T) {
+	subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "forksChannelID", Features: "pulls,forks", Repository: "owner/repo"},
+			{ChannelID: "pullsChannelID", Features: featurePulls, Repository: "owner/repo"},
+		},
+	}})
+	require.NoError(t, err)
+
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	p.setConfiguration(&Configuration{})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store[SubscriptionsKey] = subscriptions
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "forksChannelID" &&
+			post.Type == "custom_git_fork" &&
+			strings.TrimSpace(post.Message) == "[\\[owner/repo\\]](https://github.com/owner/repo) forked by [alice](https://github.com/alice) to [alice/repo](https://github.com/alice/repo)" &&
+			post.GetProp(postPropObjectID) == "alice/repo"
+	})).Return(&model.Post{}, nil).Once()
+	p.SetAPI(api)
+
+	p.postForkEvent(&github.ForkEvent{
+		Forkee: &github.Repository{FullName: github.String("alice/repo"), HTMLURL: github.String("https://github.com/alice/repo")},
+		Repo:   &github.Repository{FullName: github.String("owner/repo"), HTMLURL: github.String("https://github.com/owner/repo")},
+		Sender: &github.User{Login: github.String("alice"), HTMLURL: github.String("https://github.com/alice")},
+	}, testWebhookDelivery(p))
+
+	api.AssertNumberOfCalls(t, "CreatePost", 1)
+}
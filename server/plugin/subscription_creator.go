@@ -0,0 +1,201 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+var errSubscriptionNotFound = errors.New("subscription not found")
+
+// describeSubscriptionCreator tells who created a subscription and when, e.g. "added by @alice on
+// 2024-03-02". The parts that aren't known are left out.
+func (p *Plugin) describeSubscriptionCreator(sub *Subscription) string {
+	description := ""
+	if sub.CreatorID != "" {
+		if user, appErr := p.API.GetUser(sub.CreatorID); appErr == nil {
+			description = "added by @" + user.Username
+		}
+	}
+
+	if !sub.CreatedAt.IsZero() {
+		if description == "" {
+			description = "added"
+		}
+		description += " on " + sub.CreatedAt.Format("2006-01-02")
+	}
+
+	return description
+}
+
+// getSubscriptionsByCreator returns the subscriptions created by a user, grouped by channel.
+func (p *Plugin) getSubscriptionsByCreator(userID string) (map[string][]*Subscription, error) {
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get subscriptions")
+	}
+
+	byChannel := map[string][]*Subscription{}
+	for repo, repoSubs := range subs.Repositories {
+		for _, sub := range repoSubs {
+			if sub.CreatorID != userID {
+				continue
+			}
+			// this is needed to be backwards compatible
+			if len(sub.Repository) == 0 {
+				sub.Repository = repo
+			}
+			byChannel[sub.ChannelID] = append(byChannel[sub.ChannelID], sub)
+		}
+	}
+
+	return byChannel, nil
+}
+
+// warnSubscriptionCreatorDisconnected lets the channels subscribed by a user know that the user
+// disconnected their GitHub account. Events of private repositories are only delivered to
+// subscriptions whose creator can access the repository, so they stop until someone else takes over.
+func (p *Plugin) warnSubscriptionCreatorDisconnected(userID string) {
+	byChannel, err := p.getSubscriptionsByCreator(userID)
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions of disconnected user", "userID", userID, "error", err.Error())
+		return
+	}
+	if len(byChannel) == 0 {
+		return
+	}
+
+	creator := "A user"
+	if user, appErr := p.API.GetUser(userID); appErr == nil {
+		creator = "@" + user.Username
+	}
+
+	for channelID, subs := range byChannel {
+		repos := make([]string, 0, len(subs))
+		for _, sub := range subs {
+			repos = append(repos, "`"+strings.Trim(sub.Repository, "/")+"`")
+		}
+		sort.Strings(repos)
+
+		message := fmt.Sprintf("%s, who created the subscriptions of this channel to %s, disconnected their GitHub account. "+
+			"Events of private repositories may no longer be posted until someone re-adds the subscriptions or takes them over with `/github subscriptions claim owner[/repo]`.",
+			creator, strings.Join(repos, ", "))
+
+		post := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: channelID,
+			Message:   message,
+		}
+		if _, appErr := p.API.CreatePost(post); appErr != nil {
+			p.API.LogWarn("Failed to post subscription creator warning", "channelID", channelID, "error", appErr.Error())
+		}
+	}
+}
+
+// setSubscriptionCreator makes a user the creator of the subscription of a channel to a repository
+// or organization, returning the previous creator.
+func (p *Plugin) setSubscriptionCreator(channelID, repository, userID string) (string, error) {
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		return "", errors.Wrap(err, "could not get subscriptions")
+	}
+
+	for _, sub := range subs.Repositories[repository] {
+		if sub.ChannelID != channelID {
+			continue
+		}
+
+		previous := sub.CreatorID
+		sub.CreatorID = userID
+		if err := p.StoreSubscriptions(subs); err != nil {
+			return "", errors.Wrap(err, "could not store subscriptions")
+		}
+		return previous, nil
+	}
+
+	return "", errSubscriptionNotFound
+}
+
+// canAccessSubscriptionTarget checks if a GitHub client can access the repository or organization
+// of a subscription.
+func canAccessSubscriptionTarget(ctx context.Context, githubClient *github.Client, owner, repo string) (bool, error) {
+	var resp *github.Response
+	var err error
+	if repo == "" {
+		_, resp, err = githubClient.Organizations.Get(ctx, owner)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			_, resp, err = githubClient.Users.Get(ctx, owner)
+		}
+	} else {
+		_, resp, err = githubClient.Repositories.Get(ctx, owner, repo)
+	}
+
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (p *Plugin) handleSubscriptionsClaim(args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) != 1 {
+		return "Please specify a repository or organization: `/github subscriptions claim owner[/repo]`."
+	}
+
+	owner, repo := parseOwnerAndRepo(parameters[0], p.getBaseURL())
+	if owner == "" {
+		return "Invalid repository."
+	}
+	repository := fullNameFromOwnerAndRepo(owner, repo)
+	name := strings.Trim(repository, "/")
+
+	if err := p.checkOrg(owner); err != nil {
+		return err.Error()
+	}
+
+	ok, err := canAccessSubscriptionTarget(context.Background(), p.getGithubClient(userInfo), owner, repo)
+	if err != nil {
+		p.API.LogWarn("Failed to check access to subscription", "repo", name, "error", err.Error())
+		return fmt.Sprintf("Encountered an error checking your access to %s. Please try again.", name)
+	}
+	if !ok {
+		return fmt.Sprintf("Your GitHub account can't access %s, so you can't take over its subscription.", name)
+	}
+
+	previous, err := p.setSubscriptionCreator(args.ChannelId, repository, args.UserId)
+	if err == errSubscriptionNotFound {
+		return fmt.Sprintf("This channel isn't subscribed to %s.", name)
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to claim subscription", "repo", name, "error", err.Error())
+		return "Encountered an error trying to take over the subscription. Please try again."
+	}
+	if previous == args.UserId {
+		return fmt.Sprintf("You already are the creator of the subscription to %s.", name)
+	}
+
+	claimer := "Someone"
+	if user, appErr := p.API.GetUser(args.UserId); appErr == nil {
+		claimer = "@" + user.Username
+	}
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: args.ChannelId,
+		Message:   fmt.Sprintf("%s took over the subscription of this channel to `%s`.", claimer, name),
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to post subscription claim", "channelID", args.ChannelId, "error", appErr.Error())
+		return fmt.Sprintf("You are now the creator of the subscription to %s.", name)
+	}
+
+	return ""
+}
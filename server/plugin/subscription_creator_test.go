@@ -0,0 +1,148 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func setupSubscriptionCreatorTest(t *testing.T, config *Configuration, subs map[string][]*Subscription) (*Plugin, *plugintest.API, map[string][]byte, *[]*model.Post) {
+	value, err := json.Marshal(&Subscriptions{Repositories: subs})
+	require.NoError(t, err)
+
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	p.setConfiguration(config)
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store[SubscriptionsKey] = value
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+		store[key] = value
+		return nil
+	})
+	api.On("GetUser", "aliceID").Return(&model.User{Id: "aliceID", Username: "alice"}, nil)
+	api.On("GetUser", "bobID").Return(&model.User{Id: "bobID", Username: "bob"}, nil)
+	api.On("GetUser", mock.AnythingOfType("string")).Return(nil, &model.AppError{Message: "not found"})
+	posts := &[]*model.Post{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		*posts = append(*posts, post)
+		return post
+	}, nil)
+	p.SetAPI(api)
+
+	return p, api, store, posts
+}
+
+func TestHandleSubscriptionsListCreator(t *testing.T) {
+	p, _, _, _ := setupSubscriptionCreatorTest(t, &Configuration{}, map[string][]*Subscription{
+		"owner/repo": {{ChannelID: "channelID", CreatorID: "aliceID", CreatedAt: time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC), Features: "pulls", Repository: "owner/repo"}},
+		"owner/old":  {{ChannelID: "channelID", CreatorID: "aliceID", Features: "pulls", Repository: "owner/old"}},
+		"owner/gone": {{ChannelID: "channelID", CreatorID: "deletedID", Features: "pulls", Repository: "owner/gone"}},
+	})
+
+	message := p.handleSubscriptionsList(nil, &model.CommandArgs{ChannelId: "channelID"}, nil, nil)
+	assert.Contains(t, message, "* `owner/repo` - pulls - render style: `default` - added by @alice on 2024-03-02\n")
+	assert.Contains(t, message, "* `owner/old` - pulls - render style: `default` - added by @alice\n")
+	assert.Contains(t, message, "* `owner/gone` - pulls - render style: `default`\n")
+}
+
+func TestWarnSubscriptionCreatorDisconnected(t *testing.T) {
+	p, _, _, posts := setupSubscriptionCreatorTest(t, &Configuration{}, map[string][]*Subscription{
+		"owner/repo":  {{ChannelID: "channel1", CreatorID: "aliceID", Repository: "owner/repo"}, {ChannelID: "channel2", CreatorID: "bobID", Repository: "owner/repo"}},
+		"owner/other": {{ChannelID: "channel1", CreatorID: "aliceID", Repository: "owner/other"}},
+		"owner/":      {{ChannelID: "channel3", CreatorID: "aliceID", Repository: "owner/"}},
+	})
+
+	p.warnSubscriptionCreatorDisconnected("aliceID")
+
+	messages := map[string]string{}
+	for _, post := range *posts {
+		messages[post.ChannelId] = post.Message
+	}
+	require.Len(t, messages, 2)
+	assert.Contains(t, messages["channel1"], "@alice, who created the subscriptions of this channel to `owner/other`, `owner/repo`, disconnected their GitHub account.")
+	assert.Contains(t, messages["channel3"], "to `owner`, disconnected")
+	assert.Contains(t, messages["channel3"], "`/github subscriptions claim owner[/repo]`")
+}
+
+func TestHandleSubscriptionsClaim(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo", "/api/v3/repos/owner/unsubscribed":
+			fmt.Fprint(w, `{"full_name": "owner/repo", "private": true}`)
+		case "/api/v3/orgs/owner":
+			fmt.Fprint(w, `{"login": "owner"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	userInfo := &GitHubUserInfo{UserID: "bobID", GitHubUsername: "bob", Token: &oauth2.Token{AccessToken: token}}
+	config := &Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"}
+	args := &model.CommandArgs{UserId: "bobID", ChannelId: "channelID"}
+
+	setup := func() (*Plugin, map[string][]byte, *[]*model.Post) {
+		p, _, store, posts := setupSubscriptionCreatorTest(t, config, map[string][]*Subscription{
+			"owner/repo":    {{ChannelID: "channelID", CreatorID: "aliceID", Repository: "owner/repo"}, {ChannelID: "otherID", CreatorID: "aliceID", Repository: "owner/repo"}},
+			"owner/private": {{ChannelID: "channelID", CreatorID: "aliceID", Repository: "owner/private"}},
+			"owner/":        {{ChannelID: "channelID", CreatorID: "aliceID", Repository: "owner/"}},
+		})
+		return p, store, posts
+	}
+	creators := func(t *testing.T, store map[string][]byte, repository string) []string {
+		var subs Subscriptions
+		require.NoError(t, json.Unmarshal(store[SubscriptionsKey], &subs))
+		creators := []string{}
+		for _, sub := range subs.Repositories[repository] {
+			creators = append(creators, sub.CreatorID)
+		}
+		return creators
+	}
+
+	t.Run("repository", func(t *testing.T) {
+		p, store, posts := setup()
+
+		assert.Empty(t, p.handleSubscriptionsClaim(args, []string{"owner/repo"}, userInfo))
+		assert.Equal(t, []string{"bobID", "aliceID"}, creators(t, store, "owner/repo"))
+		require.Len(t, *posts, 1)
+		assert.Equal(t, "channelID", (*posts)[0].ChannelId)
+		assert.Equal(t, "@bob took over the subscription of this channel to `owner/repo`.", (*posts)[0].Message)
+
+		assert.Equal(t, "You already are the creator of the subscription to owner/repo.", p.handleSubscriptionsClaim(args, []string{"owner/repo"}, userInfo))
+	})
+
+	t.Run("organization", func(t *testing.T) {
+		p, store, _ := setup()
+
+		assert.Empty(t, p.handleSubscriptionsClaim(args, []string{"owner"}, userInfo))
+		assert.Equal(t, []string{"bobID"}, creators(t, store, "owner/"))
+	})
+
+	t.Run("no access", func(t *testing.T) {
+		p, store, posts := setup()
+
+		assert.Equal(t, "Your GitHub account can't access owner/private, so you can't take over its subscription.", p.handleSubscriptionsClaim(args, []string{"owner/private"}, userInfo))
+		assert.Equal(t, []string{"aliceID"}, creators(t, store, "owner/private"))
+		assert.Empty(t, *posts)
+	})
+
+	t.Run("not subscribed", func(t *testing.T) {
+		p, _, _ := setup()
+
+		assert.Equal(t, "This channel isn't subscribed to owner/unsubscribed.", p.handleSubscriptionsClaim(args, []string{"owner/unsubscribed"}, userInfo))
+	})
+}
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"sync"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// SubscriptionsRevisionKey holds a random revision changed whenever the subscriptions or the routes
+// are stored. It tells the nodes of a cluster that their subscription index and route index are
+// stale, as the plugin API doesn't allow broadcasting events to them.
+const SubscriptionsRevisionKey = "subscriptions_revision"
+
+// subscriptionIndex keeps the subscriptions in memory, by repository and organization, so that
+// webhook events don't decode all of them from the KV store.
+type subscriptionIndex struct {
+	mu           sync.RWMutex
+	loaded       bool
+	revision     string
+	repositories map[string][]*Subscription
+}
+
+// get returns the subscriptions to the given repositories or organizations if the index is loaded
+// at the given revision.
+func (i *subscriptionIndex) get(revision string, keys ...string) ([]*Subscription, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if !i.loaded || i.revision != revision {
+		return nil, false
+	}
+
+	return copySubscriptions(i.repositories, keys...), true
+}
+
+// set loads the subscriptions into the index at the given revision.
+func (i *subscriptionIndex) set(revision string, subs *Subscriptions) {
+	repositories := make(map[string][]*Subscription, len(subs.Repositories))
+	for key := range subs.Repositories {
+		repositories[key] = copySubscriptions(subs.Repositories, key)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.loaded = true
+	i.revision = revision
+	i.repositories = repositories
+}
+
+// copySubscriptions returns copies of the subscriptions to the given repositories or organizations,
+// so that the index can't be changed through the subscriptions it's loaded with or returns.
+func copySubscriptions(repositories map[string][]*Subscription, keys ...string) []*Subscription {
+	subs := []*Subscription{}
+	for _, key := range keys {
+		for _, sub := range repositories[key] {
+			copied := *sub
+			subs = append(subs, &copied)
+		}
+	}
+
+	return subs
+}
+
+// getSubscriptionsRevision returns the revision of the stored subscriptions, or an empty string if
+// they were never stored with one.
+func (p *Plugin) getSubscriptionsRevision() (string, error) {
+	value, appErr := p.API.KVGet(SubscriptionsRevisionKey)
+	if appErr != nil {
+		return "", errors.Wrap(appErr, "could not get subscriptions revision from KV store")
+	}
+
+	return string(value), nil
+}
+
+// storeSubscriptionsRevision marks the subscriptions as changed and returns their new revision.
+func (p *Plugin) storeSubscriptionsRevision() (string, error) {
+	revision := model.NewId()
+	if appErr := p.API.KVSet(SubscriptionsRevisionKey, []byte(revision)); appErr != nil {
+		return "", errors.Wrap(appErr, "could not store subscriptions revision in KV store")
+	}
+
+	return revision, nil
+}
+
+// getIndexedSubscriptions returns the subscriptions to the given repositories or organizations.
+// They are read from the subscription index, which is reloaded from the KV store when it's cold
+// or the subscriptions were changed since it was loaded, possibly by another node.
+func (p *Plugin) getIndexedSubscriptions(keys ...string) ([]*Subscription, error) {
+	revision, err := p.getSubscriptionsRevision()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.getIndexedSubscriptionsAt(revision, keys...)
+}
+
+// getIndexedSubscriptionsAt returns the subscriptions to the given repositories or organizations
+// from the subscription index, reloading it unless it's loaded at the given revision.
+func (p *Plugin) getIndexedSubscriptionsAt(revision string, keys ...string) ([]*Subscription, error) {
+	if subs, ok := p.subscriptionIndex.get(revision, keys...); ok {
+		return subs, nil
+	}
+
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get subscriptions")
+	}
+	p.subscriptionIndex.set(revision, subs)
+
+	return copySubscriptions(subs.Repositories, keys...), nil
+}
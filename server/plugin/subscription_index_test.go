@@ -0,0 +1,188 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// subscriptionsKVStore is a KV store of subscriptions safe for concurrent use, which counts how many
+// times the subscriptions and the routes are read.
+type subscriptionsKVStore struct {
+	mu         sync.Mutex
+	values     map[string][]byte
+	reads      int
+	routeReads int
+}
+
+func (s *subscriptionsKVStore) get(key string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch key {
+	case SubscriptionsKey:
+		s.reads++
+	case RoutesKey:
+		s.routeReads++
+	}
+	return s.values[key]
+}
+
+func (s *subscriptionsKVStore) set(key string, value []byte) *model.AppError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+	return nil
+}
+
+func (s *subscriptionsKVStore) subscriptionReads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.reads
+}
+
+func (s *subscriptionsKVStore) routesReads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.routeReads
+}
+
+func setupSubscriptionIndexTest(t testing.TB, subs *Subscriptions) (*Plugin, *subscriptionsKVStore) {
+	value, err := json.Marshal(subs)
+	require.NoError(t, err)
+	store := &subscriptionsKVStore{values: map[string][]byte{SubscriptionsKey: value}}
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{})
+	api := &plugintest.API{}
+	api.On("KVGet", mock.AnythingOfType("string")).Return(store.get, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(store.set)
+	p.SetAPI(api)
+
+	return p, store
+}
+
+func channelIDs(subs []*Subscription) []string {
+	ids := []string{}
+	for _, sub := range subs {
+		ids = append(ids, sub.ChannelID)
+	}
+	return ids
+}
+
+func TestSubscriptionIndex(t *testing.T) {
+	p, store := setupSubscriptionIndexTest(t, &Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo":  {{ChannelID: "channel1", Repository: "owner/repo"}},
+		"owner/":      {{ChannelID: "channel2", Repository: "owner/"}},
+		"other/repo":  {{ChannelID: "channel3", Repository: "other/repo"}},
+		"owner/other": {{ChannelID: "channel4", Repository: "owner/other"}},
+	}})
+	repo := &github.Repository{FullName: github.String("owner/repo")}
+
+	t.Run("loaded lazily", func(t *testing.T) {
+		assert.Equal(t, []string{"channel1", "channel2"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+		assert.Equal(t, []string{"channel1", "channel2"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+		assert.Equal(t, 1, store.subscriptionReads())
+	})
+
+	t.Run("returned subscriptions can't change the index", func(t *testing.T) {
+		subs := p.GetSubscribedChannelsForRepository(repo)
+		subs[0].ChannelID = "changed"
+
+		assert.Equal(t, []string{"channel1", "channel2"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+	})
+
+	t.Run("updated when storing subscriptions", func(t *testing.T) {
+		require.NoError(t, p.AddSubscription("owner/repo", &Subscription{ChannelID: "channel5", Repository: "owner/repo"}))
+		reads := store.subscriptionReads()
+
+		assert.Equal(t, []string{"channel1", "channel5", "channel2"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+		assert.Equal(t, reads, store.subscriptionReads())
+	})
+
+	t.Run("reloaded when another node stores subscriptions", func(t *testing.T) {
+		value, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+			"owner/repo": {{ChannelID: "channel6", Repository: "owner/repo"}},
+		}})
+		require.NoError(t, err)
+		store.set(SubscriptionsKey, value)
+		store.set(SubscriptionsRevisionKey, []byte(model.NewId()))
+		reads := store.subscriptionReads()
+
+		assert.Equal(t, []string{"channel6"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+		assert.Equal(t, reads+1, store.subscriptionReads())
+	})
+}
+
+func TestSubscriptionIndexConcurrency(t *testing.T) {
+	p, _ := setupSubscriptionIndexTest(t, &Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/": {{ChannelID: "org", Repository: "owner/"}},
+	}})
+	repo := &github.Repository{FullName: github.String("owner/repo")}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				subs := p.GetSubscribedChannelsForRepository(repo)
+				if !assert.NotEmpty(t, subs) {
+					return
+				}
+				// The organization subscription is always delivered, after the repository ones
+				assert.Equal(t, "org", subs[len(subs)-1].ChannelID)
+				for _, sub := range subs[:len(subs)-1] {
+					assert.Equal(t, "owner/repo", sub.Repository)
+				}
+			}
+		}()
+	}
+
+	// Subscribing and unsubscribing happens while webhook events are processed
+	for i := 0; i < 50; i++ {
+		channelID := fmt.Sprintf("channel%d", i%5)
+		require.NoError(t, p.AddSubscription("owner/repo", &Subscription{ChannelID: channelID, Repository: "owner/repo"}))
+		if i%2 == 1 {
+			require.NoError(t, p.Unsubscribe(channelID, "owner/repo"))
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	assert.ElementsMatch(t, []string{"channel1", "channel3", "org"}, channelIDs(p.GetSubscribedChannelsForRepository(repo)))
+}
+
+func BenchmarkGetSubscribedChannelsForRepository(b *testing.B) {
+	subs := &Subscriptions{Repositories: map[string][]*Subscription{}}
+	for i := 0; i < 500; i++ {
+		repository := fmt.Sprintf("owner/repo%d", i)
+		subs.Repositories[repository] = []*Subscription{{ChannelID: model.NewId(), CreatorID: model.NewId(), Features: "pulls,issues", Repository: repository}}
+	}
+	p, _ := setupSubscriptionIndexTest(b, subs)
+	repo := &github.Repository{FullName: github.String("owner/repo42")}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.GetSubscribedChannelsForRepository(repo)
+	}
+}
@@ -0,0 +1,379 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	resumeSubscriptionsJobKey      = "resume_subscriptions"
+	resumeSubscriptionsJobInterval = 15 * time.Minute
+
+	pauseReasonChannelArchived = "the channel was archived"
+
+	// minSubscriptionPause is the shortest pause of /github subscriptions pause.
+	minSubscriptionPause = time.Minute
+)
+
+func (s *Subscription) paused() bool {
+	return !s.PausedAt.IsZero()
+}
+
+// pauseExpired tells if the pause of a subscription paused for a duration is over.
+func (s *Subscription) pauseExpired(now time.Time) bool {
+	return s.paused() && !s.PausedUntil.IsZero() && !s.PausedUntil.After(now)
+}
+
+// parseSubscriptionPause parses the duration of /github subscriptions pause, e.g. 90m, 12h or 7d.
+func parseSubscriptionPause(value string) (time.Duration, error) {
+	var duration time.Duration
+	var err error
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		var n int
+		n, err = strconv.Atoi(days)
+		duration = time.Duration(n) * 24 * time.Hour
+	} else {
+		duration, err = time.ParseDuration(value)
+	}
+	if err != nil || duration < minSubscriptionPause {
+		return 0, errors.Errorf("Invalid duration %q. Use a duration of at least a minute, e.g. `90m`, `12h` or `7d`, or none to pause until resumed.", value)
+	}
+
+	return duration, nil
+}
+
+// formatPauseRemaining formats the rest of a pause in days, hours and minutes, rounded up, e.g. 2d 3h.
+func formatPauseRemaining(remaining time.Duration) string {
+	minutes := int((remaining + time.Minute - 1) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+
+	days, hours := minutes/(24*60), minutes/60%24
+	minutes %= 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+func formatPauseEnd(until time.Time) string {
+	return until.UTC().Format("2006-01-02 15:04 MST")
+}
+
+// describeSubscriptionPause tells when and why a subscription was paused, e.g. "paused on
+// 2024-03-02: the channel was archived", or "paused on 2024-03-02 until 2024-03-09 10:00 UTC (6d
+// 23h left)".
+func describeSubscriptionPause(sub *Subscription, now time.Time) string {
+	description := "paused on " + sub.PausedAt.Format("2006-01-02")
+	switch {
+	case !sub.PausedUntil.IsZero():
+		description += fmt.Sprintf(" until %s (%s left)", formatPauseEnd(sub.PausedUntil), formatPauseRemaining(sub.PausedUntil.Sub(now)))
+	case sub.PauseReason == "":
+		description += " until resumed"
+	}
+	if sub.PauseReason != "" {
+		description += ": " + sub.PauseReason
+	}
+
+	return description
+}
+
+// pauseArchivedChannelSubscriptions pauses the subscriptions of a channel if it is archived, so that
+// their events stop failing to be posted. It returns whether the channel is archived.
+func (p *Plugin) pauseArchivedChannelSubscriptions(channelID string) bool {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil || channel.DeleteAt == 0 {
+		return false
+	}
+
+	paused, err := p.pauseChannelSubscriptions(channelID, time.Unix(0, channel.DeleteAt*int64(time.Millisecond)).UTC(), pauseReasonChannelArchived)
+	if err != nil {
+		p.API.LogWarn("Failed to pause the subscriptions of an archived channel", "channelID", channelID, "error", err.Error())
+		return true
+	}
+	if paused > 0 {
+		p.API.LogInfo("Paused the subscriptions of an archived channel", "channelID", channelID, "subscriptions", paused)
+	}
+
+	return true
+}
+
+// pauseChannelSubscriptions pauses the active subscriptions of a channel, returning how many were paused.
+func (p *Plugin) pauseChannelSubscriptions(channelID string, pausedAt time.Time, reason string) (int, error) {
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not get subscriptions")
+	}
+
+	paused := 0
+	for _, repoSubs := range subs.Repositories {
+		for _, sub := range repoSubs {
+			if sub.ChannelID != channelID || sub.paused() {
+				continue
+			}
+			sub.PausedAt = pausedAt
+			sub.PauseReason = reason
+			paused++
+		}
+	}
+	if paused == 0 {
+		return 0, nil
+	}
+
+	if err := p.StoreSubscriptions(subs); err != nil {
+		return 0, errors.Wrap(err, "could not store subscriptions")
+	}
+
+	return paused, nil
+}
+
+// resumeUnarchivedSubscriptions resumes the subscriptions paused while their channel was archived
+// once the channel is unarchived, and lets the channel know.
+func (p *Plugin) resumeUnarchivedSubscriptions() {
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions to resume", "error", err.Error())
+		return
+	}
+
+	byChannel := map[string][]*Subscription{}
+	for repo, repoSubs := range subs.Repositories {
+		for _, sub := range repoSubs {
+			if sub.PauseReason != pauseReasonChannelArchived {
+				continue
+			}
+			// this is needed to be backwards compatible
+			if len(sub.Repository) == 0 {
+				sub.Repository = repo
+			}
+			byChannel[sub.ChannelID] = append(byChannel[sub.ChannelID], sub)
+		}
+	}
+
+	resumed := map[string][]string{}
+	for channelID, channelSubs := range byChannel {
+		channel, appErr := p.API.GetChannel(channelID)
+		if appErr != nil || channel.DeleteAt != 0 {
+			continue
+		}
+
+		for _, sub := range channelSubs {
+			sub.PausedAt = time.Time{}
+			sub.PauseReason = ""
+			resumed[channelID] = append(resumed[channelID], strings.Trim(sub.Repository, "/"))
+		}
+	}
+	if len(resumed) == 0 {
+		return
+	}
+
+	if err := p.StoreSubscriptions(subs); err != nil {
+		p.API.LogWarn("Failed to store resumed subscriptions", "error", err.Error())
+		return
+	}
+
+	for channelID, repos := range resumed {
+		sort.Strings(repos)
+		for i, repo := range repos {
+			repos[i] = "`" + repo + "`"
+		}
+		post := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: channelID,
+			Message: fmt.Sprintf("Subscriptions resumed: this channel is no longer archived, so events of %s are posted again. "+
+				"Events that happened while it was archived weren't posted.", strings.Join(repos, ", ")),
+		}
+		if _, appErr := p.API.CreatePost(post); appErr != nil {
+			p.API.LogWarn("Failed to post subscriptions resumed notice", "channelID", channelID, "error", appErr.Error())
+		}
+	}
+}
+
+// setSubscriptionPause pauses the subscription of a channel to a repository or organization until
+// a deadline, or until it's resumed if the deadline is zero. A zero pausedAt resumes it instead.
+// It returns whether the subscription was paused before.
+func (p *Plugin) setSubscriptionPause(channelID, repository string, pausedAt, pausedUntil time.Time) (bool, error) {
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		return false, errors.Wrap(err, "could not get subscriptions")
+	}
+
+	for _, sub := range subs.Repositories[repository] {
+		if sub.ChannelID != channelID {
+			continue
+		}
+
+		// this is needed to be backwards compatible, and to resume the subscription once the pause is over
+		if len(sub.Repository) == 0 {
+			sub.Repository = repository
+		}
+
+		wasPaused := sub.paused()
+		sub.PausedAt = pausedAt
+		sub.PausedUntil = pausedUntil
+		sub.PauseReason = ""
+		if err := p.StoreSubscriptions(subs); err != nil {
+			return false, errors.Wrap(err, "could not store subscriptions")
+		}
+		return wasPaused, nil
+	}
+
+	return false, errSubscriptionNotFound
+}
+
+// resumeSubscriptions resumes the subscriptions whose pause is over, and those paused while their
+// channel was archived once it's unarchived.
+func (p *Plugin) resumeSubscriptions() {
+	p.resumeExpiredPauses(time.Now())
+	p.resumeUnarchivedSubscriptions()
+}
+
+// resumeExpiredPauses resumes the subscriptions whose pause is over, and lets their channels know.
+// Their events are already delivered in the meantime, as an expired pause doesn't hold them back.
+func (p *Plugin) resumeExpiredPauses(now time.Time) {
+	subs, err := p.GetSubscriptions()
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions to resume", "error", err.Error())
+		return
+	}
+
+	resumed := []*Subscription{}
+	for repo, repoSubs := range subs.Repositories {
+		for _, sub := range repoSubs {
+			if !sub.pauseExpired(now) {
+				continue
+			}
+			// this is needed to be backwards compatible
+			if len(sub.Repository) == 0 {
+				sub.Repository = repo
+			}
+			sub.PausedAt = time.Time{}
+			sub.PausedUntil = time.Time{}
+			resumed = append(resumed, sub)
+		}
+	}
+	if len(resumed) == 0 {
+		return
+	}
+
+	if err := p.StoreSubscriptions(subs); err != nil {
+		p.API.LogWarn("Failed to store resumed subscriptions", "error", err.Error())
+		return
+	}
+
+	for _, sub := range resumed {
+		post := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: sub.ChannelID,
+			Message:   fmt.Sprintf("Notifications resumed: the pause of `%s` is over.", strings.Trim(sub.Repository, "/")),
+		}
+		if _, appErr := p.API.CreatePost(post); appErr != nil {
+			p.API.LogWarn("Failed to post notifications resumed notice", "channelID", sub.ChannelID, "error", appErr.Error())
+		}
+	}
+}
+
+// parseSubscriptionPauseTarget parses the repository or organization of a pause or resume command,
+// returning its stored and displayed names. Both are empty if it's invalid.
+func (p *Plugin) parseSubscriptionPauseTarget(target string) (string, string) {
+	owner, repo := parseOwnerAndRepo(target, p.getBaseURL())
+	if owner == "" {
+		return "", ""
+	}
+	repository := fullNameFromOwnerAndRepo(owner, repo)
+
+	return repository, strings.Trim(repository, "/")
+}
+
+// postSubscriptionPauseConfirmation lets the channel know a user paused or resumed a subscription.
+// If the post fails, the confirmation is returned to be sent to the user only.
+func (p *Plugin) postSubscriptionPauseConfirmation(args *model.CommandArgs, message string) string {
+	user := "Someone"
+	if u, appErr := p.API.GetUser(args.UserId); appErr == nil {
+		user = "@" + u.Username
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: args.ChannelId,
+		Message:   user + " " + message,
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to post subscription pause confirmation", "channelID", args.ChannelId, "error", appErr.Error())
+		return "You " + message
+	}
+
+	return ""
+}
+
+func (p *Plugin) handleSubscriptionsPause(args *model.CommandArgs, parameters []string) string {
+	if len(parameters) == 0 || len(parameters) > 2 {
+		return "Please specify a repository or organization, and optionally a duration: `/github subscriptions pause owner[/repo] [duration]`."
+	}
+
+	repository, name := p.parseSubscriptionPauseTarget(parameters[0])
+	if repository == "" {
+		return "Invalid repository."
+	}
+
+	now := time.Now().UTC()
+	pausedUntil := time.Time{}
+	if len(parameters) == 2 {
+		duration, err := parseSubscriptionPause(parameters[1])
+		if err != nil {
+			return err.Error()
+		}
+		pausedUntil = now.Add(duration)
+	}
+
+	_, err := p.setSubscriptionPause(args.ChannelId, repository, now, pausedUntil)
+	if err == errSubscriptionNotFound {
+		return fmt.Sprintf("This channel isn't subscribed to %s.", name)
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to pause subscription", "repo", name, "error", err.Error())
+		return "Encountered an error trying to pause the subscription. Please try again."
+	}
+
+	if pausedUntil.IsZero() {
+		return p.postSubscriptionPauseConfirmation(args, fmt.Sprintf("paused the notifications of `%s` in this channel until they're resumed with `/github subscriptions resume %s`.", name, name))
+	}
+
+	return p.postSubscriptionPauseConfirmation(args, fmt.Sprintf("paused the notifications of `%s` in this channel until %s. They resume automatically after that.", name, formatPauseEnd(pausedUntil)))
+}
+
+func (p *Plugin) handleSubscriptionsResume(args *model.CommandArgs, parameters []string) string {
+	if len(parameters) != 1 {
+		return "Please specify a repository or organization: `/github subscriptions resume owner[/repo]`."
+	}
+
+	repository, name := p.parseSubscriptionPauseTarget(parameters[0])
+	if repository == "" {
+		return "Invalid repository."
+	}
+
+	wasPaused, err := p.setSubscriptionPause(args.ChannelId, repository, time.Time{}, time.Time{})
+	if err == errSubscriptionNotFound {
+		return fmt.Sprintf("This channel isn't subscribed to %s.", name)
+	}
+	if err != nil {
+		p.API.LogWarn("Failed to resume subscription", "repo", name, "error", err.Error())
+		return "Encountered an error trying to resume the subscription. Please try again."
+	}
+	if !wasPaused {
+		return fmt.Sprintf("The subscription of this channel to %s isn't paused.", name)
+	}
+
+	return p.postSubscriptionPauseConfirmation(args, fmt.Sprintf("resumed the notifications of `%s` in this channel. Events of the pause weren't posted.", name))
+}
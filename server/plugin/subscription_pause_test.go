@@ -0,0 +1,215 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseArchivedChannelSubscriptions(t *testing.T) {
+	archivedAt := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	p, api, store, _ := setupSubscriptionCreatorTest(t, &Configuration{}, map[string][]*Subscription{
+		"owner/repo": {{ChannelID: "archivedID", Repository: "owner/repo"}, {ChannelID: "activeID", Repository: "owner/repo"}},
+		"owner/":     {{ChannelID: "archivedID", Repository: "owner/"}},
+	})
+	api.On("GetChannel", "archivedID").Return(&model.Channel{Id: "archivedID", DeleteAt: archivedAt.UnixNano() / int64(time.Millisecond)}, nil)
+	api.On("GetChannel", "activeID").Return(&model.Channel{Id: "activeID"}, nil)
+	api.On("LogInfo", "Paused the subscriptions of an archived channel", "channelID", "archivedID", "subscriptions", 2).Once()
+
+	assert.False(t, p.pauseArchivedChannelSubscriptions("activeID"))
+	assert.True(t, p.pauseArchivedChannelSubscriptions("archivedID"))
+	// Already paused subscriptions are left as they are
+	assert.True(t, p.pauseArchivedChannelSubscriptions("archivedID"))
+	api.AssertNumberOfCalls(t, "LogInfo", 1)
+
+	var subs Subscriptions
+	require.NoError(t, json.Unmarshal(store[SubscriptionsKey], &subs))
+	assert.Equal(t, archivedAt, subs.Repositories["owner/repo"][0].PausedAt)
+	assert.Equal(t, pauseReasonChannelArchived, subs.Repositories["owner/repo"][0].PauseReason)
+	assert.False(t, subs.Repositories["owner/repo"][1].paused())
+	assert.True(t, subs.Repositories["owner/"][0].paused())
+
+	channels := []string{}
+	for _, sub := range p.GetSubscribedChannelsForRepository(&github.Repository{FullName: github.String("owner/repo")}) {
+		channels = append(channels, sub.ChannelID)
+	}
+	assert.Equal(t, []string{"activeID"}, channels)
+}
+
+func TestResumeUnarchivedSubscriptions(t *testing.T) {
+	pausedAt := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	p, api, store, posts := setupSubscriptionCreatorTest(t, &Configuration{}, map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "unarchivedID", Repository: "owner/repo", Features: "pulls", PausedAt: pausedAt, PauseReason: pauseReasonChannelArchived},
+			{ChannelID: "archivedID", Repository: "owner/repo", PausedAt: pausedAt, PauseReason: pauseReasonChannelArchived},
+		},
+		"owner/": {{ChannelID: "unarchivedID", Repository: "owner/", PausedAt: pausedAt, PauseReason: pauseReasonChannelArchived}},
+	})
+	api.On("GetChannel", "unarchivedID").Return(&model.Channel{Id: "unarchivedID"}, nil)
+	api.On("GetChannel", "archivedID").Return(&model.Channel{Id: "archivedID", DeleteAt: 1}, nil)
+
+	message := p.handleSubscriptionsList(nil, &model.CommandArgs{ChannelId: "unarchivedID"}, nil, nil)
+	assert.Contains(t, message, "* `owner/repo` - pulls - render style: `default` - paused on 2024-03-02: the channel was archived\n")
+
+	p.resumeUnarchivedSubscriptions()
+
+	var subs Subscriptions
+	require.NoError(t, json.Unmarshal(store[SubscriptionsKey], &subs))
+	assert.False(t, subs.Repositories["owner/repo"][0].paused())
+	assert.Empty(t, subs.Repositories["owner/repo"][0].PauseReason)
+	assert.True(t, subs.Repositories["owner/repo"][1].paused())
+	assert.False(t, subs.Repositories["owner/"][0].paused())
+
+	require.Len(t, *posts, 1)
+	assert.Equal(t, "unarchivedID", (*posts)[0].ChannelId)
+	assert.Contains(t, (*posts)[0].Message, "Subscriptions resumed: this channel is no longer archived, so events of `owner`, `owner/repo` are posted again.")
+
+	// Nothing is left to resume
+	p.resumeUnarchivedSubscriptions()
+	assert.Len(t, *posts, 1)
+	api.AssertNotCalled(t, "LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestParseSubscriptionPause(t *testing.T) {
+	for value, expected := range map[string]time.Duration{"90m": 90 * time.Minute, "12h": 12 * time.Hour, "7d": 7 * 24 * time.Hour, "1m": time.Minute} {
+		duration, err := parseSubscriptionPause(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, duration)
+	}
+
+	for _, value := range []string{"30s", "0d", "-1h", "d", "1w", "soon"} {
+		_, err := parseSubscriptionPause(value)
+		assert.Error(t, err, value)
+	}
+
+	for remaining, expected := range map[time.Duration]string{
+		10 * time.Second:                "1m",
+		12*time.Minute + 10*time.Second: "13m",
+		2 * time.Hour:                   "2h 0m",
+		3*time.Hour + 20*time.Minute:    "3h 20m",
+		51 * time.Hour:                  "2d 3h",
+	} {
+		assert.Equal(t, expected, formatPauseRemaining(remaining))
+	}
+}
+
+func TestHandleSubscriptionsPause(t *testing.T) {
+	setup := func(t *testing.T) (*Plugin, map[string][]byte, *[]*model.Post) {
+		p, _, store, posts := setupSubscriptionCreatorTest(t, &Configuration{}, map[string][]*Subscription{
+			"owner/repo": {{ChannelID: "channelID", Repository: "owner/repo", Features: "pulls"}, {ChannelID: "otherID", Repository: "owner/repo", Features: "pulls"}},
+		})
+		return p, store, posts
+	}
+	args := &model.CommandArgs{UserId: "aliceID", ChannelId: "channelID"}
+	repository := &github.Repository{FullName: github.String("owner/repo")}
+	subscribedChannels := func(p *Plugin) []string {
+		channels := []string{}
+		for _, sub := range p.GetSubscribedChannelsForRepository(repository) {
+			channels = append(channels, sub.ChannelID)
+		}
+		return channels
+	}
+	storedSubscription := func(t *testing.T, store map[string][]byte) *Subscription {
+		var subs Subscriptions
+		require.NoError(t, json.Unmarshal(store[SubscriptionsKey], &subs))
+		return subs.Repositories["owner/repo"][0]
+	}
+
+	t.Run("indefinite pause", func(t *testing.T) {
+		p, store, posts := setup(t)
+
+		assert.Empty(t, p.handleSubscriptions(nil, args, []string{"pause", "owner/repo"}, nil))
+		require.Len(t, *posts, 1)
+		assert.Equal(t, "channelID", (*posts)[0].ChannelId)
+		assert.Equal(t, "@alice paused the notifications of `owner/repo` in this channel until they're resumed with `/github subscriptions resume owner/repo`.", (*posts)[0].Message)
+
+		sub := storedSubscription(t, store)
+		assert.True(t, sub.paused())
+		assert.True(t, sub.PausedUntil.IsZero())
+		assert.Empty(t, sub.PauseReason)
+		assert.Equal(t, "pulls", sub.Features)
+		assert.Equal(t, []string{"otherID"}, subscribedChannels(p))
+
+		message := p.handleSubscriptions(nil, args, []string{"list"}, nil)
+		assert.Contains(t, message, "* `owner/repo` - pulls - render style: `default` - paused on "+time.Now().UTC().Format("2006-01-02")+" until resumed\n")
+
+		assert.Empty(t, p.handleSubscriptions(nil, args, []string{"resume", "owner/repo"}, nil))
+		require.Len(t, *posts, 2)
+		assert.Equal(t, "@alice resumed the notifications of `owner/repo` in this channel. Events of the pause weren't posted.", (*posts)[1].Message)
+		assert.False(t, storedSubscription(t, store).paused())
+		assert.Equal(t, []string{"channelID", "otherID"}, subscribedChannels(p))
+
+		assert.Equal(t, "The subscription of this channel to owner/repo isn't paused.", p.handleSubscriptions(nil, args, []string{"resume", "owner/repo"}, nil))
+	})
+
+	t.Run("timed pause", func(t *testing.T) {
+		p, store, posts := setup(t)
+
+		assert.Empty(t, p.handleSubscriptions(nil, args, []string{"pause", "owner/repo", "2h"}, nil))
+		require.Len(t, *posts, 1)
+		sub := storedSubscription(t, store)
+		assert.WithinDuration(t, time.Now().Add(2*time.Hour), sub.PausedUntil, time.Minute)
+		assert.Equal(t, "@alice paused the notifications of `owner/repo` in this channel until "+formatPauseEnd(sub.PausedUntil)+". They resume automatically after that.", (*posts)[0].Message)
+
+		message := p.handleSubscriptions(nil, args, []string{"list"}, nil)
+		assert.Contains(t, message, " - paused on "+sub.PausedAt.Format("2006-01-02")+" until "+formatPauseEnd(sub.PausedUntil)+" (2h 0m left)\n")
+		assert.Equal(t, []string{"otherID"}, subscribedChannels(p))
+		require.Len(t, *posts, 1)
+
+		// The pause is over
+		var subs Subscriptions
+		require.NoError(t, json.Unmarshal(store[SubscriptionsKey], &subs))
+		subs.Repositories["owner/repo"][0].PausedUntil = time.Now().Add(-time.Minute)
+		require.NoError(t, p.StoreSubscriptions(&subs))
+
+		// Events are delivered again before the job resumes the subscription, without side effects
+		assert.Equal(t, []string{"channelID", "otherID"}, subscribedChannels(p))
+		require.Len(t, *posts, 1)
+		assert.True(t, storedSubscription(t, store).paused())
+
+		p.resumeSubscriptions()
+		require.Len(t, *posts, 2)
+		assert.Equal(t, "channelID", (*posts)[1].ChannelId)
+		assert.Equal(t, "Notifications resumed: the pause of `owner/repo` is over.", (*posts)[1].Message)
+		sub = storedSubscription(t, store)
+		assert.False(t, sub.paused())
+		assert.True(t, sub.PausedUntil.IsZero())
+		assert.Equal(t, []string{"channelID", "otherID"}, subscribedChannels(p))
+
+		// The notice is only posted once
+		p.resumeSubscriptions()
+		assert.Len(t, *posts, 2)
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		p, store, _ := setup(t)
+
+		assert.Empty(t, p.handleSubscriptions(nil, args, []string{"pause", "owner/repo", "7d"}, nil))
+		assert.Equal(t, "Successfully unsubscribed from owner/repo.", p.handleSubscriptions(nil, args, []string{"delete", "owner/repo"}, nil))
+
+		assert.Equal(t, "This channel isn't subscribed to owner/repo.", p.handleSubscriptions(nil, args, []string{"pause", "owner/repo"}, nil))
+		assert.Equal(t, "This channel isn't subscribed to owner/repo.", p.handleSubscriptions(nil, args, []string{"resume", "owner/repo"}, nil))
+
+		// Subscribing again starts without the pause
+		require.NoError(t, p.AddSubscription("owner/repo", &Subscription{ChannelID: "channelID", Repository: "owner/repo", Features: "issues"}))
+		sub := storedSubscription(t, store)
+		require.Equal(t, "otherID", sub.ChannelID)
+		assert.Equal(t, []string{"otherID", "channelID"}, subscribedChannels(p))
+	})
+
+	t.Run("invalid commands", func(t *testing.T) {
+		p, _, posts := setup(t)
+
+		assert.Equal(t, "Please specify a repository or organization, and optionally a duration: `/github subscriptions pause owner[/repo] [duration]`.", p.handleSubscriptions(nil, args, []string{"pause"}, nil))
+		assert.Equal(t, "Invalid duration \"soon\". Use a duration of at least a minute, e.g. `90m`, `12h` or `7d`, or none to pause until resumed.", p.handleSubscriptions(nil, args, []string{"pause", "owner/repo", "soon"}, nil))
+		assert.Equal(t, "This channel isn't subscribed to owner/other.", p.handleSubscriptions(nil, args, []string{"pause", "owner/other"}, nil))
+		assert.Equal(t, "Please specify a repository or organization: `/github subscriptions resume owner[/repo]`.", p.handleSubscriptions(nil, args, []string{"resume"}, nil))
+		assert.Empty(t, *posts)
+	})
+}
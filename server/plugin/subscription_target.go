@@ -0,0 +1,26 @@
+package plugin
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// subscriptionTargetFlag subscribes another channel than the current one. It isn't stored with
+	// the subscription, which is added to the target channel like to any other.
+	subscriptionTargetFlag = "target"
+	// subscriptionTargetDM targets the direct message channel of the creator with the bot, so they
+	// get notifications privately without creating a channel.
+	subscriptionTargetDM = "dm"
+)
+
+// getBotDMChannelID returns the ID of the direct message channel of a user with the bot, where
+// the subscriptions targeting it are stored. It's the channel the /github commands of the user run
+// in when they're used in the direct messages with the bot, so they list and delete these subscriptions.
+func (p *Plugin) getBotDMChannelID(userID string) (string, error) {
+	channel, appErr := p.API.GetDirectChannel(userID, p.BotUserID)
+	if appErr != nil {
+		return "", errors.Wrap(appErr, "could not get the direct message channel with the bot")
+	}
+
+	return channel.Id, nil
+}
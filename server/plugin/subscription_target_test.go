@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestSubscriptionsTargetDM(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo":
+			fmt.Fprint(w, `{"full_name": "owner/repo", "private": true}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func() (*Plugin, map[string][]byte) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("int64")).Return(func(key string, value []byte, ttl int64) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("GetDirectChannel", "userID", "botID").Return(&model.Channel{Id: "dmChannelID", Type: model.CHANNEL_DIRECT}, nil)
+		api.On("GetUser", "userID").Return(&model.User{Id: "userID", Username: "alice"}, nil)
+		p.SetAPI(api)
+
+		return p, store
+	}
+	userInfo := &GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: "token"}}
+	channelArgs := &model.CommandArgs{UserId: "userID", ChannelId: "channelID"}
+	dmArgs := &model.CommandArgs{UserId: "userID", ChannelId: "dmChannelID"}
+
+	t.Run("add, list and delete", func(t *testing.T) {
+		p, _ := setup()
+
+		message := p.handleSubscriptions(nil, channelArgs, []string{"add", "owner/repo", "pulls", "--target", "dm"}, userInfo)
+		assert.Equal(t, "Successfully subscribed to repo in your direct messages with the bot.", message)
+
+		subs, err := p.GetSubscriptionsByChannel("dmChannelID")
+		require.NoError(t, err)
+		require.Len(t, subs, 1)
+		assert.Equal(t, "userID", subs[0].CreatorID)
+		assert.Equal(t, "pulls", subs[0].Features)
+		// The target isn't stored as a flag
+		assert.Empty(t, subs[0].Flags.String())
+
+		channelSubs, err := p.GetSubscriptionsByChannel("channelID")
+		require.NoError(t, err)
+		assert.Empty(t, channelSubs)
+
+		// Commands run in the direct messages with the bot manage its subscriptions
+		message = p.handleSubscriptions(nil, dmArgs, []string{"list"}, userInfo)
+		assert.Contains(t, message, "* `owner/repo` - pulls - render style: `default`")
+		assert.Equal(t, "Currently there are no subscriptions in this channel", p.handleSubscriptions(nil, channelArgs, []string{"list"}, userInfo))
+
+		message = p.handleSubscriptions(nil, dmArgs, []string{"delete", "owner/repo"}, userInfo)
+		assert.Equal(t, "Successfully unsubscribed from owner/repo.", message)
+
+		subs, err = p.GetSubscriptionsByChannel("dmChannelID")
+		require.NoError(t, err)
+		assert.Empty(t, subs)
+	})
+
+	t.Run("no duplicate subscriptions", func(t *testing.T) {
+		p, store := setup()
+
+		p.handleSubscriptions(nil, channelArgs, []string{"add", "owner/repo", "pulls", "--target", "dm"}, userInfo)
+		// Running the command in the direct messages with the bot targets the same channel
+		message := p.handleSubscriptions(nil, dmArgs, []string{"add", "owner/repo", "issues", "--target", "dm"}, userInfo)
+		assert.Equal(t, "Successfully subscribed to repo in your direct messages with the bot.", message)
+
+		var subs *Subscriptions
+		require.NoError(t, json.Unmarshal(store[SubscriptionsKey], &subs))
+		require.Len(t, subs.Repositories["owner/repo"], 1)
+		assert.Equal(t, "issues", subs.Repositories["owner/repo"][0].Features)
+	})
+
+	t.Run("channel subscriptions are kept apart", func(t *testing.T) {
+		p, _ := setup()
+
+		message := p.handleSubscriptions(nil, channelArgs, []string{"add", "owner/repo", "pulls"}, userInfo)
+		assert.Contains(t, message, "**Warning:** You subscribed to a private repository.")
+		p.handleSubscriptions(nil, channelArgs, []string{"add", "owner/repo", "--target", "dm"}, userInfo)
+
+		channelSubs, err := p.GetSubscriptionsByChannel("channelID")
+		require.NoError(t, err)
+		assert.Len(t, channelSubs, 1)
+		dmSubs, err := p.GetSubscriptionsByChannel("dmChannelID")
+		require.NoError(t, err)
+		assert.Len(t, dmSubs, 1)
+	})
+
+	t.Run("invalid targets", func(t *testing.T) {
+		p, _ := setup()
+
+		message := p.handleSubscriptions(nil, channelArgs, []string{"add", "owner/repo", "--target", "~town-square"}, userInfo)
+		assert.Equal(t, "The flag --target only supports `dm`, to subscribe your direct messages with the bot.", message)
+
+		message = p.handleSubscriptions(nil, channelArgs, []string{"add", "owner/repo", "--target"}, userInfo)
+		assert.Equal(t, "The flag --target only supports `dm`, to subscribe your direct messages with the bot.", message)
+
+		message = p.handleSubscriptions(nil, channelArgs, []string{"add", "owner/repo", "--sync-header", "true", "--target", "dm"}, userInfo)
+		assert.Equal(t, "Unable to set --sync-header flag. Direct messages have no channel header to sync.", message)
+	})
+}
@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v31/github"
 	"github.com/pkg/errors"
@@ -15,17 +17,163 @@ import (
 const (
 	SubscriptionsKey     = "subscriptions"
 	excludeOrgMemberFlag = "exclude-org-member"
+	weeklyDigestFlag     = "weekly-digest"
+	renderStyleFlag      = "render-style"
+	syncHeaderFlag       = "sync-header"
+	sampleFlag           = "sample"
+	mentionUsersFlag     = "mention-users"
 )
 
+// flagValueCounts holds the number of values taken by the flags that are not simple switches.
+var flagValueCounts = map[string]int{
+	weeklyDigestFlag: 2,
+	renderStyleFlag:  1,
+	syncHeaderFlag:   1,
+	sampleFlag:       1,
+	mentionUsersFlag: 1,
+
+	issueFormFieldsFlag:  1,
+	templateFlag:         1,
+	reviewSLAFlag:        1,
+	pathsFlag:            1,
+	starMilestonesFlag:   1,
+	coalesceCommentsFlag: 1,
+	liveUpdateFlag:       1,
+	relatedPRsFlag:       1,
+	maxPerHourFlag:       1,
+	suggestReviewersFlag: 1,
+	logExcerptFlag:       1,
+}
+
 type SubscriptionFlags struct {
 	ExcludeOrgMembers bool
+	WeeklyDigest      string `json:",omitempty"`
+	RenderStyle       string `json:",omitempty"`
+	SyncHeader        bool   `json:",omitempty"`
+	Sample            string `json:",omitempty"`
+	IssueFormFields   string `json:",omitempty"`
+	Template          string `json:",omitempty"`
+	ReviewSLA         string `json:",omitempty"`
+	ReviewSLADM       bool   `json:",omitempty"`
+	Paths             string `json:",omitempty"`
+	StarMilestones    string `json:",omitempty"`
+	MentionUsers      bool   `json:",omitempty"`
+	CoalesceComments  string `json:",omitempty"`
+	LiveUpdate        bool   `json:",omitempty"`
+	RelatedPRs        bool   `json:",omitempty"`
+	MaxPerHour        int    `json:",omitempty"`
+	SuggestReviewers  bool   `json:",omitempty"`
+	IncludeLogExcerpt bool   `json:",omitempty"`
 }
 
 func (s *SubscriptionFlags) AddFlag(flag string) {
-	switch flag { // nolint:gocritic // It's expected that more flags get added.
+	switch flag {
 	case excludeOrgMemberFlag:
 		s.ExcludeOrgMembers = true
+	case reviewSLADMFlag:
+		s.ReviewSLADM = true
+	}
+}
+
+// SetFlag sets a flag taking a value, validating the value.
+func (s *SubscriptionFlags) SetFlag(flag, value string) error {
+	switch flag {
+	case weeklyDigestFlag:
+		if _, err := parseWeeklySchedule(value); err != nil {
+			return err
+		}
+		s.WeeklyDigest = value
+	case renderStyleFlag:
+		if err := checkRenderStyle(value); err != nil {
+			return err
+		}
+		s.RenderStyle = value
+	case syncHeaderFlag:
+		syncHeader, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf("Invalid value %q for --%s. Use `true` or `false`.", value, syncHeaderFlag)
+		}
+		s.SyncHeader = syncHeader
+	case sampleFlag:
+		if _, err := parseSampleRate(value); err != nil {
+			return err
+		}
+		s.Sample = value
+	case issueFormFieldsFlag:
+		names, err := parseIssueFormFieldNames(value)
+		if err != nil {
+			return err
+		}
+		s.IssueFormFields = strings.Join(names, ",")
+	case templateFlag:
+		if value == "" || strings.ContainsAny(value, " \"") {
+			return errors.Errorf("Invalid value %q for --%s. Use the name of a notification template configured by a system admin.", value, templateFlag)
+		}
+		s.Template = value
+	case reviewSLAFlag:
+		if _, err := parseReviewSLA(value); err != nil {
+			return err
+		}
+		s.ReviewSLA = value
+	case pathsFlag:
+		if _, err := parsePathPatterns(value); err != nil {
+			return err
+		}
+		s.Paths = normalizePathPatterns(value)
+	case starMilestonesFlag:
+		milestones, err := parseStarMilestones(value)
+		if err != nil {
+			return err
+		}
+		parts := make([]string, len(milestones))
+		for i, milestone := range milestones {
+			parts[i] = strconv.Itoa(milestone)
+		}
+		s.StarMilestones = strings.Join(parts, ",")
+	case mentionUsersFlag:
+		mentionUsers, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf("Invalid value %q for --%s. Use `true` or `false`.", value, mentionUsersFlag)
+		}
+		s.MentionUsers = mentionUsers
+	case coalesceCommentsFlag:
+		if _, err := parseCoalesceWindow(value); err != nil {
+			return err
+		}
+		s.CoalesceComments = value
+	case liveUpdateFlag:
+		liveUpdate, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf("Invalid value %q for --%s. Use `true` or `false`.", value, liveUpdateFlag)
+		}
+		s.LiveUpdate = liveUpdate
+	case relatedPRsFlag:
+		relatedPRs, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf("Invalid value %q for --%s. Use `true` or `false`.", value, relatedPRsFlag)
+		}
+		s.RelatedPRs = relatedPRs
+	case maxPerHourFlag:
+		limit, err := parseRateCap(value)
+		if err != nil {
+			return err
+		}
+		s.MaxPerHour = limit
+	case suggestReviewersFlag:
+		suggestReviewers, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf("Invalid value %q for --%s. Use `true` or `false`.", value, suggestReviewersFlag)
+		}
+		s.SuggestReviewers = suggestReviewers
+	case logExcerptFlag:
+		includeLogExcerpt, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.Errorf("Invalid value %q for --%s. Use `true` or `false`.", value, logExcerptFlag)
+		}
+		s.IncludeLogExcerpt = includeLogExcerpt
 	}
+
+	return nil
 }
 
 func (s SubscriptionFlags) String() string {
@@ -36,64 +184,223 @@ func (s SubscriptionFlags) String() string {
 		flags = append(flags, flag)
 	}
 
+	if s.WeeklyDigest != "" {
+		flag := "--" + weeklyDigestFlag + " " + s.WeeklyDigest
+		flags = append(flags, flag)
+	}
+
+	if s.RenderStyle != "" {
+		flag := "--" + renderStyleFlag + " " + s.RenderStyle
+		flags = append(flags, flag)
+	}
+
+	if s.SyncHeader {
+		flag := "--" + syncHeaderFlag + " true"
+		flags = append(flags, flag)
+	}
+
+	if s.Sample != "" {
+		flag := "--" + sampleFlag + " " + s.Sample
+		flags = append(flags, flag)
+	}
+
+	if s.IssueFormFields != "" {
+		flag := "--" + issueFormFieldsFlag + " \"" + s.IssueFormFields + "\""
+		flags = append(flags, flag)
+	}
+
+	if s.Template != "" {
+		flag := "--" + templateFlag + " " + s.Template
+		flags = append(flags, flag)
+	}
+
+	if s.ReviewSLA != "" {
+		flag := "--" + reviewSLAFlag + " " + s.ReviewSLA
+		flags = append(flags, flag)
+	}
+
+	if s.ReviewSLADM {
+		flag := "--" + reviewSLADMFlag
+		flags = append(flags, flag)
+	}
+
+	if s.Paths != "" {
+		flag := "--" + pathsFlag + " \"" + s.Paths + "\""
+		flags = append(flags, flag)
+	}
+
+	if s.StarMilestones != "" {
+		flag := "--" + starMilestonesFlag + " " + s.StarMilestones
+		flags = append(flags, flag)
+	}
+
+	if s.MentionUsers {
+		flag := "--" + mentionUsersFlag + " true"
+		flags = append(flags, flag)
+	}
+
+	if s.CoalesceComments != "" {
+		flag := "--" + coalesceCommentsFlag + " " + s.CoalesceComments
+		flags = append(flags, flag)
+	}
+
+	if s.LiveUpdate {
+		flag := "--" + liveUpdateFlag + " true"
+		flags = append(flags, flag)
+	}
+
+	if s.RelatedPRs {
+		flag := "--" + relatedPRsFlag + " true"
+		flags = append(flags, flag)
+	}
+
+	if s.MaxPerHour > 0 {
+		flag := "--" + maxPerHourFlag + " " + strconv.Itoa(s.MaxPerHour)
+		flags = append(flags, flag)
+	}
+
+	if s.SuggestReviewers {
+		flag := "--" + suggestReviewersFlag + " true"
+		flags = append(flags, flag)
+	}
+
+	if s.IncludeLogExcerpt {
+		flag := "--" + logExcerptFlag + " true"
+		flags = append(flags, flag)
+	}
+
 	return strings.Join(flags, ",")
 }
 
+// flagValueCount returns the number of values the given flag takes.
+func flagValueCount(flag string) int {
+	return flagValueCounts[flag]
+}
+
 type Subscription struct {
-	ChannelID  string
-	CreatorID  string
-	Features   string
-	Flags      SubscriptionFlags
-	Repository string
+	ChannelID string
+	CreatorID string
+	// CreatedAt is zero for subscriptions created before it was recorded.
+	CreatedAt time.Time
+	// PausedAt is when the subscription was paused, e.g. because its channel was archived. Events
+	// aren't posted for paused subscriptions. It is zero for active subscriptions.
+	PausedAt    time.Time
+	PauseReason string
+	// PausedUntil is when a subscription paused with /github subscriptions pause resumes. It is
+	// zero for subscriptions paused until they're resumed.
+	PausedUntil time.Time
+	Features    string
+	Flags       SubscriptionFlags
+	Repository  string
+
+	// features caches the parsed Features, which are parsed again whenever they change.
+	features       map[string]bool
+	parsedFeatures string
 }
 
 type Subscriptions struct {
 	Repositories map[string][]*Subscription
 }
 
+// parseFeatures splits a comma-separated list of features, dropping blank and duplicate entries.
+func parseFeatures(features string) []string {
+	parsed := []string{}
+	for _, f := range strings.Split(features, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || SliceContainsString(parsed, f) {
+			continue
+		}
+		parsed = append(parsed, f)
+	}
+
+	return parsed
+}
+
+// normalizeFeatures returns the canonical form of a comma-separated list of features.
+func normalizeFeatures(features string) string {
+	return strings.Join(parseFeatures(features), ",")
+}
+
+// hasFeature tells if the subscription has exactly the given feature.
+func (s *Subscription) hasFeature(feature string) bool {
+	if s.features == nil || s.parsedFeatures != s.Features {
+		s.features = map[string]bool{}
+		for _, f := range parseFeatures(s.Features) {
+			s.features[f] = true
+		}
+		s.parsedFeatures = s.Features
+	}
+
+	return s.features[feature]
+}
+
 func (s *Subscription) Pulls() bool {
-	return strings.Contains(s.Features, featurePulls)
+	return s.hasFeature(featurePulls)
 }
 
 func (s *Subscription) IssueCreations() bool {
-	return strings.Contains(s.Features, "issue_creations")
+	return s.hasFeature(featureIssueCreation)
 }
 
 func (s *Subscription) Issues() bool {
-	return strings.Contains(s.Features, featureIssues)
+	return s.hasFeature(featureIssues)
 }
 
 func (s *Subscription) Pushes() bool {
-	return strings.Contains(s.Features, "pushes")
+	return s.hasFeature(featurePushes)
 }
 
 func (s *Subscription) Creates() bool {
-	return strings.Contains(s.Features, "creates")
+	return s.hasFeature(featureCreates)
 }
 
 func (s *Subscription) Deletes() bool {
-	return strings.Contains(s.Features, "deletes")
+	return s.hasFeature(featureDeletes)
 }
 
 func (s *Subscription) IssueComments() bool {
-	return strings.Contains(s.Features, "issue_comments")
+	return s.hasFeature(featureIssueComments)
 }
 
 func (s *Subscription) PullReviews() bool {
-	return strings.Contains(s.Features, "pull_reviews")
+	return s.hasFeature(featurePullReviews)
+}
+
+func (s *Subscription) BranchProtection() bool {
+	return s.hasFeature(featureBranchProtection)
+}
+
+func (s *Subscription) Forks() bool {
+	return s.hasFeature(featureForks)
+}
+
+func (s *Subscription) Stars() bool {
+	return s.hasFeature(featureStars)
+}
+
+func (s *Subscription) WorkflowFailures() bool {
+	return s.hasFeature(featureWorkflowFailure)
+}
+
+func (s *Subscription) Wiki() bool {
+	return s.hasFeature(featureWiki)
 }
 
 func (s *Subscription) Label() string {
-	if !strings.Contains(s.Features, "label:") {
-		return ""
-	}
+	for _, f := range parseFeatures(s.Features) {
+		if !strings.HasPrefix(f, "label:") {
+			continue
+		}
 
-	labelSplit := strings.Split(s.Features, "\"")
-	if len(labelSplit) < 3 {
-		return ""
+		labelSplit := strings.Split(f, "\"")
+		if len(labelSplit) < 3 {
+			return ""
+		}
+
+		return labelSplit[1]
 	}
 
-	return labelSplit[1]
+	return ""
 }
 
 func (s *Subscription) ExcludeOrgMembers() bool {
@@ -113,7 +420,44 @@ func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, use
 		return errors.Errorf("Unable to set --exclude-org-member flag. The GitHub plugin is not locked to a single organization.")
 	}
 
+	if flags.SyncHeader && repo == "" {
+		return errors.Errorf("Unable to set --%s flag. The channel header can only be synced with a repository.", syncHeaderFlag)
+	}
+
+	if flags.ReviewSLADM && flags.ReviewSLA == "" {
+		return errors.Errorf("Unable to set --%s flag. It requires the --%s flag.", reviewSLADMFlag, reviewSLAFlag)
+	}
+
+	if flags.StarMilestones != "" && !SliceContainsString(parseFeatures(features), featureStars) {
+		return errors.Errorf("Unable to set --%s flag. It requires the %s feature.", starMilestonesFlag, featureStars)
+	}
+
+	if flags.CoalesceComments != "" && !SliceContainsString(parseFeatures(features), featureIssueComments) {
+		return errors.Errorf("Unable to set --%s flag. It requires the %s feature.", coalesceCommentsFlag, featureIssueComments)
+	}
+
+	if flags.LiveUpdate && !SliceContainsString(parseFeatures(features), featureIssues) && !SliceContainsString(parseFeatures(features), featureIssueCreation) {
+		return errors.Errorf("Unable to set --%s flag. It requires the %s or %s feature.", liveUpdateFlag, featureIssues, featureIssueCreation)
+	}
+
+	if flags.RelatedPRs && !SliceContainsString(parseFeatures(features), featureIssues) && !SliceContainsString(parseFeatures(features), featureIssueCreation) {
+		return errors.Errorf("Unable to set --%s flag. It requires the %s or %s feature.", relatedPRsFlag, featureIssues, featureIssueCreation)
+	}
+
+	if flags.SuggestReviewers && !SliceContainsString(parseFeatures(features), featurePulls) {
+		return errors.Errorf("Unable to set --%s flag. It requires the %s feature.", suggestReviewersFlag, featurePulls)
+	}
+
+	if flags.IncludeLogExcerpt && !SliceContainsString(parseFeatures(features), featureWorkflowFailure) {
+		return errors.Errorf("Unable to set --%s flag. It requires the %s feature.", logExcerptFlag, featureWorkflowFailure)
+	}
+
+	if flags.Template != "" && !p.getConfiguration().hasNotificationTemplate(flags.Template) {
+		return errors.Errorf("Unable to set --%s flag. There is no notification template named %s.", templateFlag, flags.Template)
+	}
+
 	var err error
+	var ghRepo *github.Repository
 
 	if repo == "" {
 		var ghOrg *github.Organization
@@ -126,7 +470,6 @@ func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, use
 			}
 		}
 	} else {
-		var ghRepo *github.Repository
 		ghRepo, _, err = githubClient.Repositories.Get(ctx, owner, repo)
 
 		if ghRepo == nil {
@@ -142,7 +485,8 @@ func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, use
 	sub := &Subscription{
 		ChannelID:  channelID,
 		CreatorID:  userID,
-		Features:   features,
+		CreatedAt:  time.Now().UTC(),
+		Features:   normalizeFeatures(features),
 		Repository: fullNameFromOwnerAndRepo(owner, repo),
 		Flags:      flags,
 	}
@@ -151,6 +495,16 @@ func (p *Plugin) Subscribe(ctx context.Context, githubClient *github.Client, use
 		return errors.Wrap(err, "could not add subscription")
 	}
 
+	if err := p.markPendingWebhook(sub.Repository, channelID); err != nil {
+		p.API.LogWarn("Failed to mark pending webhook", "channelID", channelID, "repo", sub.Repository, "error", err.Error())
+	}
+
+	if flags.SyncHeader {
+		if err := p.syncChannelHeader(channelID, ghRepo); err != nil {
+			p.API.LogWarn("Failed to sync channel header", "channelID", channelID, "repo", ghRepo.GetFullName(), "error", err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -239,6 +593,13 @@ func (p *Plugin) GetSubscriptions() (*Subscriptions, error) {
 		return nil, errors.Wrap(err, "could not properly decode subscriptions key")
 	}
 
+	// Subscriptions stored before features were normalized are migrated to the canonical form
+	for _, subs := range subscriptions.Repositories {
+		for _, sub := range subs {
+			sub.Features = normalizeFeatures(sub.Features)
+		}
+	}
+
 	return subscriptions, nil
 }
 
@@ -252,42 +613,53 @@ func (p *Plugin) StoreSubscriptions(s *Subscriptions) error {
 		return errors.Wrap(appErr, "could not store subscriptions in KV store")
 	}
 
+	revision, err := p.storeSubscriptionsRevision()
+	if err != nil {
+		return err
+	}
+	p.subscriptionIndex.set(revision, s)
+
 	return nil
 }
 
 func (p *Plugin) GetSubscribedChannelsForRepository(repo *github.Repository) []*Subscription {
 	name := repo.GetFullName()
 	org := strings.Split(name, "/")[0]
-	subs, err := p.GetSubscriptions()
+
+	revision, err := p.getSubscriptionsRevision()
 	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions", "repo", name, "error", err.Error())
 		return nil
 	}
 
-	// Add subscriptions for the specific repo
-	subsForRepo := []*Subscription{}
-	if subs.Repositories[name] != nil {
-		subsForRepo = append(subsForRepo, subs.Repositories[name]...)
-	}
-
-	// Add subscriptions for the organization
-	orgKey := fullNameFromOwnerAndRepo(org, "")
-	if subs.Repositories[orgKey] != nil {
-		subsForRepo = append(subsForRepo, subs.Repositories[orgKey]...)
-	}
-
-	if len(subsForRepo) == 0 {
+	// Add subscriptions for the specific repo and the organization
+	subsForRepo, err := p.getIndexedSubscriptionsAt(revision, name, fullNameFromOwnerAndRepo(org, ""))
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions", "repo", name, "error", err.Error())
 		return nil
 	}
 
 	subsToReturn := []*Subscription{}
 
+	now := time.Now()
 	for _, sub := range subsForRepo {
+		// Subscriptions whose pause is over are resumed by a scheduled job
+		if sub.paused() && !sub.pauseExpired(now) {
+			continue
+		}
 		if repo.GetPrivate() && !p.permissionToRepo(sub.CreatorID, name) {
 			continue
 		}
 		subsToReturn = append(subsToReturn, sub)
 	}
 
+	// Add subscriptions for the channels events are routed to
+	subsToReturn = append(subsToReturn, p.getRoutedSubscriptions(repo, revision, subsToReturn)...)
+
+	if len(subsToReturn) == 0 {
+		return nil
+	}
+
 	return subsToReturn
 }
 
@@ -308,20 +680,26 @@ func (p *Plugin) Unsubscribe(channelID string, repo string) error {
 		return nil
 	}
 
-	removed := false
+	var removed *Subscription
 	for index, sub := range repoSubs {
 		if sub.ChannelID == channelID {
 			repoSubs = append(repoSubs[:index], repoSubs[index+1:]...)
-			removed = true
+			removed = sub
 			break
 		}
 	}
 
-	if removed {
+	if removed != nil {
 		subs.Repositories[repoWithOwner] = repoSubs
 		if err := p.StoreSubscriptions(subs); err != nil {
 			return errors.Wrap(err, "could not store subscriptions")
 		}
+
+		if removed.Flags.SyncHeader {
+			if err := p.removeSyncedChannelHeader(channelID, repoWithOwner); err != nil {
+				p.API.LogWarn("Failed to remove synced channel header", "channelID", channelID, "repo", repoWithOwner, "error", err.Error())
+			}
+		}
 	}
 
 	return nil
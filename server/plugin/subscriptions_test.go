@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func CheckError(t *testing.T, wantErr bool, err error) {
@@ -24,6 +27,7 @@ func pluginWithMockedSubs(subscriptions []*Subscription) *Plugin {
 	subs := Subscriptions{Repositories: map[string][]*Subscription{}}
 	subs.Repositories[""] = subscriptions
 	jsn, _ := json.Marshal(subs)
+	mockPluginAPI.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
 	mockPluginAPI.On("KVGet", SubscriptionsKey).Return(jsn, nil)
 	p.SetAPI(mockPluginAPI)
 	return p
@@ -114,3 +118,152 @@ func TestPlugin_GetSubscriptionsByChannel(t *testing.T) {
 		})
 	}
 }
+
+func TestSubscriptionFeatures(t *testing.T) {
+	t.Run("exact matches only", func(t *testing.T) {
+		sub := &Subscription{Features: "pulls_merged,issue_comments,pull_reviews"}
+		assert.False(t, sub.Pulls())
+		assert.False(t, sub.Issues())
+		assert.True(t, sub.IssueComments())
+		assert.True(t, sub.PullReviews())
+	})
+
+	t.Run("features changed after being checked", func(t *testing.T) {
+		sub := &Subscription{Features: "issues"}
+		assert.True(t, sub.Issues())
+
+		sub.Features = "pulls"
+		assert.False(t, sub.Issues())
+		assert.True(t, sub.Pulls())
+	})
+
+	t.Run("label", func(t *testing.T) {
+		assert.Equal(t, "bug", (&Subscription{Features: `pulls,label:"bug"`}).Label())
+		assert.Equal(t, "", (&Subscription{Features: "pulls"}).Label())
+		assert.Equal(t, "", (&Subscription{Features: "pulls,label:bug"}).Label())
+	})
+}
+
+func TestNormalizeFeatures(t *testing.T) {
+	assert.Equal(t, "pulls,issues,label:\"bug\"", normalizeFeatures(` pulls, issues,,label:"bug",pulls`))
+	assert.Equal(t, "", normalizeFeatures(""))
+	assert.Equal(t, []string{}, parseFeatures(" , "))
+}
+
+func TestGetSubscriptionsMigratesLegacyFeatures(t *testing.T) {
+	legacy := []byte(`{"Repositories": {"owner/repo": [
+		{"ChannelID": "channel1", "Features": "pulls, issues,label:\"bug\",pulls", "Repository": "owner/repo"},
+		{"ChannelID": "channel2", "Features": "pushes,creates,deletes", "Repository": "owner/repo"}
+	]}}`)
+
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+	api.On("KVGet", SubscriptionsKey).Return(legacy, nil)
+	p.SetAPI(api)
+
+	subs, err := p.GetSubscriptions()
+	require.NoError(t, err)
+
+	first, second := subs.Repositories["owner/repo"][0], subs.Repositories["owner/repo"][1]
+	assert.Equal(t, `pulls,issues,label:"bug"`, first.Features)
+	assert.True(t, first.Pulls())
+	assert.True(t, first.Issues())
+	assert.False(t, first.Pushes())
+	assert.Equal(t, "bug", first.Label())
+
+	assert.Equal(t, "pushes,creates,deletes", second.Features)
+	assert.True(t, second.Pushes())
+	assert.True(t, second.Creates())
+	assert.True(t, second.Deletes())
+	assert.False(t, second.Pulls())
+	assert.Equal(t, "", second.Label())
+}
+
+func TestPullRequestEventSkipsOtherPullFeatures(t *testing.T) {
+	subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "channel1", Features: "pulls_merged", Repository: "owner/repo"},
+			{ChannelID: "channel2", Features: "pulls", Repository: "owner/repo"},
+		},
+	}})
+	require.NoError(t, err)
+
+	body := []byte(`{
+		"action": "opened",
+		"number": 1,
+		"pull_request": {"number": 1, "title": "Fix", "html_url": "https://github.com/owner/repo/pull/1", "user": {"login": "panda"}},
+		"repository": {"full_name": "owner/repo", "html_url": "https://github.com/owner/repo", "private": false},
+		"sender": {"login": "panda"}
+	}`)
+
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	p.setConfiguration(&Configuration{})
+	api := &plugintest.API{}
+	api.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+	api.On("KVGet", SubscriptionsKey).Return(subscriptions, nil)
+	api.On("KVGet", RoutesKey).Return(nil, nil)
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "channel2"
+	})).Return(&model.Post{}, nil).Once()
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything)
+	mockDeliveryLogs(api)
+	p.SetAPI(api)
+
+	require.NoError(t, p.processWebhookEvent("pull_request", "", body, false))
+	api.AssertNumberOfCalls(t, "CreatePost", 1)
+}
+
+func TestMentionUsersFlag(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(mentionUsersFlag, "true"))
+	assert.Equal(t, "--mention-users true", flags.String())
+	require.NoError(t, flags.SetFlag(mentionUsersFlag, "false"))
+	assert.Equal(t, "", flags.String())
+	assert.Error(t, flags.SetFlag(mentionUsersFlag, "yes"))
+}
+
+func TestPushEventMentionsUsers(t *testing.T) {
+	subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "mentionsChannelID", Features: featurePushes, Repository: "owner/repo", Flags: SubscriptionFlags{MentionUsers: true}},
+			{ChannelID: "plainChannelID", Features: featurePushes, Repository: "owner/repo"},
+		},
+	}})
+	require.NoError(t, err)
+
+	body := []byte(`{
+		"ref": "refs/heads/main",
+		"compare": "https://github.com/owner/repo/compare/a...b",
+		"commits": [{"id": "a10867b14bb761a232cd80139fbd4c0d33264240", "message": "Fix", "author": {"name": "Maria", "username": "marianunez"}, "committer": {"name": "Maria"}}],
+		"repository": {"full_name": "owner/repo", "html_url": "https://github.com/owner/repo", "private": false},
+		"sender": {"login": "panda", "html_url": "https://github.com/panda"}
+	}`)
+
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	p.setConfiguration(&Configuration{})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store[SubscriptionsKey] = subscriptions
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+	messages := map[string]string{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		messages[post.ChannelId] = post.Message
+		return post
+	}, nil)
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything)
+	mockDeliveryLogs(api)
+	p.SetAPI(api)
+
+	withGitHubUserNameMapping(func(t *testing.T) {
+		require.NoError(t, p.processWebhookEvent("push", "", body, false))
+	})(t)
+
+	require.Len(t, messages, 2)
+	assert.Contains(t, messages["mentionsChannelID"], "Fix - @maria.nunez\n")
+	assert.Contains(t, messages["plainChannelID"], "Fix - Maria\n")
+}
@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	teamMembersKey = "_teammembers"
+
+	// teamMembersCacheTTL is how long the members of a team are cached, in seconds. The cache is also
+	// invalidated when a membership event is received.
+	teamMembersCacheTTL = 60 * 60
+	teamMembersPageSize = 100
+)
+
+func teamMembersKeyFor(org, slug string) string {
+	return hashKey(teamMembersKey, org, slug)
+}
+
+// listTeamMembers returns the logins of the members of a team.
+func listTeamMembers(ctx context.Context, githubClient *github.Client, org, slug string) ([]string, error) {
+	members := []string{}
+
+	opts := &github.TeamListTeamMembersOptions{ListOptions: github.ListOptions{PerPage: teamMembersPageSize}}
+	for {
+		users, resp, err := githubClient.Teams.ListTeamMembersBySlug(ctx, org, slug, opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list team members")
+		}
+
+		for _, user := range users {
+			members = append(members, user.GetLogin())
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return members, nil
+}
+
+// getTeamMembers returns the logins of the members of a team, from the cache if possible. The team
+// is listed with the client of the first subscription creator allowed to.
+func (p *Plugin) getTeamMembers(org, slug string, subs []*Subscription) ([]string, error) {
+	key := teamMembersKeyFor(org, slug)
+
+	value, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get team members from KV store")
+	}
+	if value != nil {
+		members := []string{}
+		if err := json.Unmarshal(value, &members); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal team members")
+		}
+		return members, nil
+	}
+
+	var lastErr error = errors.New("no subscription creator to list the team members with")
+	for _, sub := range subs {
+		info, apiErr := p.getGitHubUserInfo(sub.CreatorID)
+		if apiErr != nil {
+			continue
+		}
+
+		members, err := listTeamMembers(context.Background(), p.githubConnect(*info.Token), org, slug)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		value, err := json.Marshal(members)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not marshal team members")
+		}
+		if appErr := p.API.KVSetWithExpiry(key, value, teamMembersCacheTTL); appErr != nil {
+			p.API.LogWarn("Failed to cache team members", "team", org+"/"+slug, "error", appErr.Error())
+		}
+
+		return members, nil
+	}
+
+	return nil, lastErr
+}
+
+// handleTeamReviewRequestNotification notifies the connected members of a team a review was requested from.
+func (p *Plugin) handleTeamReviewRequestNotification(event *github.PullRequestEvent) {
+	repo := event.GetRepo()
+	team := event.GetRequestedTeam()
+	org := event.GetOrganization().GetLogin()
+	if org == "" {
+		org = repo.GetOwner().GetLogin()
+	}
+
+	subs := p.GetSubscribedChannelsForRepository(repo)
+	members, err := p.getTeamMembers(org, team.GetSlug(), subs)
+	if err != nil {
+		p.API.LogWarn("Failed to get team members", "team", org+"/"+team.GetSlug(), "error", err.Error())
+		return
+	}
+
+	message, err := renderTemplate("teamReviewRequestNotification", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	author := event.GetPullRequest().GetUser().GetLogin()
+	sender := event.GetSender().GetLogin()
+	for _, member := range members {
+		if member == author || member == sender {
+			continue
+		}
+
+		userID := p.getGitHubToUserIDMapping(member)
+		if userID == "" || !p.isConnected(userID) {
+			continue
+		}
+
+		info, apiErr := p.getGitHubUserInfo(userID)
+		if apiErr != nil || !info.Settings.Notifications {
+			continue
+		}
+
+		if p.senderMutedByReceiver(userID, author) {
+			continue
+		}
+
+		if repo.GetPrivate() && !p.permissionToRepo(userID, repo.GetFullName()) {
+			continue
+		}
+
+		p.CreateNotificationDMPost(userID, message, "custom_git_review_request", true)
+		p.sendRefreshEvent(userID)
+	}
+}
+
+// handleMembershipEvent invalidates the cached members of a team when they change. Members added
+// to a team may be new to its organization too.
+func (p *Plugin) handleMembershipEvent(event *github.MembershipEvent) {
+	if event.GetScope() != "team" {
+		return
+	}
+
+	org := event.GetOrg().GetLogin()
+	slug := event.GetTeam().GetSlug()
+	if appErr := p.API.KVDelete(teamMembersKeyFor(org, slug)); appErr != nil {
+		p.API.LogWarn("Failed to invalidate cached team members", "team", org+"/"+slug, "error", appErr.Error())
+	}
+
+	if event.GetAction() == "added" {
+		if err := p.invalidateOrgMembers(org); err != nil {
+			p.API.LogWarn("Failed to invalidate cached organization members", "org", org, "error", err.Error())
+		}
+	}
+}
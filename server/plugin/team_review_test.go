@@ -0,0 +1,103 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestListTeamMembers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/org/teams/core/members", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `[{"login": "carol"}]`)
+			return
+		}
+
+		w.Header().Set("Link", `<https://api.github.com/orgs/org/teams/core/members?page=2>; rel="next"`)
+		fmt.Fprint(w, `[{"login": "alice"}, {"login": "bob"}]`)
+	})
+
+	members, err := listTeamMembers(context.Background(), newTestGitHubClient(t, mux), "org", "core")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob", "carol"}, members)
+}
+
+func TestHandleTeamReviewRequestNotification(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+
+	userInfo := func(userID string, notifications bool) []byte {
+		value, err := json.Marshal(&GitHubUserInfo{
+			UserID:   userID,
+			Token:    &oauth2.Token{AccessToken: token},
+			Settings: &UserSettings{Notifications: notifications},
+		})
+		require.NoError(t, err)
+		return value
+	}
+
+	members, err := json.Marshal([]string{"author", "sender", "notified", "muting", "silent", "unmapped"})
+	require.NoError(t, err)
+
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey})
+	api := &plugintest.API{}
+	api.On("KVGet", SubscriptionsRevisionKey).Return(nil, nil)
+	api.On("KVGet", SubscriptionsKey).Return(nil, nil)
+	api.On("KVGet", RoutesKey).Return(nil, nil)
+	api.On("KVGet", teamMembersKeyFor("org", "core")).Return(members, nil)
+	api.On("KVGet", "notified"+githubUsernameKey).Return([]byte("notifiedID"), nil)
+	api.On("KVGet", "muting"+githubUsernameKey).Return([]byte("mutingID"), nil)
+	api.On("KVGet", "silent"+githubUsernameKey).Return([]byte("silentID"), nil)
+	api.On("KVGet", "unmapped"+githubUsernameKey).Return(nil, nil)
+	api.On("KVGet", "notifiedID"+githubTokenKey).Return(userInfo("notifiedID", true), nil)
+	api.On("KVGet", "mutingID"+githubTokenKey).Return(userInfo("mutingID", true), nil)
+	api.On("KVGet", "silentID"+githubTokenKey).Return(userInfo("silentID", false), nil)
+	api.On("KVGet", "notifiedID-muted-users").Return(nil, nil)
+	api.On("KVGet", "mutingID-muted-users").Return([]byte("author"), nil)
+	api.On("GetDirectChannel", "notifiedID", "botID").Return(&model.Channel{Id: "dmChannelID"}, nil).Once()
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "dmChannelID" && post.Type == "custom_git_review_request"
+	})).Return(&model.Post{}, nil).Once()
+	api.On("PublishWebSocketEvent", wsEventRefresh, map[string]interface{}(nil), &model.WebsocketBroadcast{UserId: "notifiedID"}).Once()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	p.handleTeamReviewRequestNotification(&github.PullRequestEvent{
+		Action:        github.String("review_requested"),
+		Repo:          &github.Repository{FullName: github.String("org/repo"), Owner: &github.User{Login: github.String("org")}},
+		Organization:  &github.Organization{Login: github.String("org")},
+		Sender:        &github.User{Login: github.String("sender")},
+		RequestedTeam: &github.Team{Name: github.String("Core"), Slug: github.String("core")},
+		PullRequest:   &github.PullRequest{Number: github.Int(42), User: &github.User{Login: github.String("author")}},
+	})
+}
+
+func TestHandleMembershipEvent(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("KVDelete", teamMembersKeyFor("org", "core")).Return(nil).Once()
+	api.On("KVSet", orgMembersGenerationKeyFor("org"), mock.Anything).Return(nil).Once()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	p.handleMembershipEvent(&github.MembershipEvent{
+		Action: github.String("added"),
+		Scope:  github.String("team"),
+		Org:    &github.Organization{Login: github.String("org")},
+		Team:   &github.Team{Slug: github.String("core")},
+	})
+}
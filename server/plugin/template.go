@@ -51,12 +51,9 @@ func init() {
 	// Resolve a GitHub username to the corresponding Mattermost username, if linked.
 	funcMap["lookupMattermostUsername"] = lookupMattermostUsername
 
-	// Trim away markdown comments in the text
-	funcMap["removeComments"] = func(body string) string {
-		if len(strings.TrimSpace(body)) == 0 {
-			return ""
-		}
-		return mdCommentRegex.ReplaceAllString(body, "")
+	// Adapt the GitHub-flavored Markdown of a body to Mattermost, linking to it on GitHub
+	funcMap["sanitizeBody"] = func(htmlURL, body string) string {
+		return sanitizeMarkdown(body, htmlURL)
 	}
 
 	// Replace any GitHub username with its corresponding Mattermost username, if any
@@ -85,6 +82,16 @@ func init() {
 	// Escape characters not allowed in URL path
 	funcMap["pathEscape"] = url.PathEscape
 
+	// Link to a GitHub user, on the same GitHub instance as another user's profile URL
+	funcMap["userURL"] = func(profileURL, login string) string {
+		u, err := url.Parse(profileURL)
+		if err != nil || u.Host == "" {
+			return "https://github.com/" + url.PathEscape(login)
+		}
+		u.Path = "/" + login
+		return u.String()
+	}
+
 	// Transform multiple variables to dictionary
 	funcMap["dict"] = func(values ...interface{}) (map[string]interface{}, error) {
 		if len(values)%2 != 0 {
@@ -181,14 +188,21 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 {{- end -}}
 `))
 
-	template.Must(masterTemplate.New("newPR").Funcs(funcMap).Parse(`
+	// Templates suffixed with a render style are used in its place by subscriptions using that style.
+	template.Must(masterTemplate.New("newPR-skip-body").Funcs(funcMap).Parse(`
 #### {{.GetPullRequest.GetTitle}}
 ##### {{template "eventRepoPullRequest" .}}
 #new-pull-request by {{template "user" .GetSender}}
 {{- template "labels" dict "Labels" .GetPullRequest.Labels "RepositoryURL" .GetRepo.GetHTMLURL  }}
 {{- template "assignee" .GetPullRequest }}
+`))
+
+	template.Must(masterTemplate.New("newPR").Funcs(funcMap).Parse(`{{template "newPR-skip-body" .}}
+{{.GetPullRequest.GetBody | sanitizeBody .GetPullRequest.GetHTMLURL | replaceAllGitHubUsernames}}
+`))
 
-{{.GetPullRequest.GetBody | removeComments | replaceAllGitHubUsernames}}
+	template.Must(masterTemplate.New("newPR-collapsed").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} New pull request {{template "pullRequest" .GetPullRequest}} was opened by {{template "user" .GetSender}}.
 `))
 
 	template.Must(masterTemplate.New("closedPR").Funcs(funcMap).Parse(`
@@ -206,16 +220,26 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 
 	template.Must(masterTemplate.New("pullRequestMentionNotification").Funcs(funcMap).Parse(`
 {{template "user" .GetSender}} mentioned you on [{{.GetRepo.GetFullName}}#{{.GetPullRequest.GetNumber}}]({{.GetPullRequest.GetHTMLURL}}) - {{.GetPullRequest.GetTitle}}:
-{{.GetPullRequest.GetBody | trimBody | quote | replaceAllGitHubUsernames}}`))
+{{.GetPullRequest.GetBody | trimBody | sanitizeBody .GetPullRequest.GetHTMLURL | quote | replaceAllGitHubUsernames}}`))
 
-	template.Must(masterTemplate.New("newIssue").Funcs(funcMap).Parse(`
+	template.Must(masterTemplate.New("newIssue-skip-body").Funcs(funcMap).Parse(`
 #### {{.GetIssue.GetTitle}}
 ##### {{template "eventRepoIssue" .}}
 #new-issue by {{template "user" .GetSender}}
 {{- template "labels" dict "Labels" .GetIssue.Labels "RepositoryURL" .GetRepo.GetHTMLURL  }}
 {{- template "assignee" .GetIssue }}
+`))
 
-{{.GetIssue.GetBody | removeComments | replaceAllGitHubUsernames}}
+	template.Must(masterTemplate.New("newIssue").Funcs(funcMap).Parse(`{{template "newIssue-skip-body" .}}
+{{.GetIssue.GetBody | sanitizeBody .GetIssue.GetHTMLURL | replaceAllGitHubUsernames}}
+`))
+
+	template.Must(masterTemplate.New("newIssueFormFields").Funcs(funcMap).Parse(`{{template "newIssue-skip-body" .Event}}
+{{range .Fields}}* **{{.Name}}:** {{.ListValue | replaceAllGitHubUsernames}}
+{{end}}`))
+
+	template.Must(masterTemplate.New("newIssue-collapsed").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} New issue {{template "issue" .GetIssue}} was opened by {{template "user" .GetSender}}.
 `))
 
 	template.Must(masterTemplate.New("closedIssue").Funcs(funcMap).Parse(`
@@ -226,17 +250,71 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 #### {{.GetIssue.GetTitle}}
 ##### {{template "eventRepoIssue" .}}
 #issue-labeled ` + "`{{.GetLabel.GetName}}`" + ` by {{template "user" .GetSender}}.
+`))
+
+	template.Must(masterTemplate.New("milestoneChanged").Funcs(funcMap).Parse(`
+{{- $added := eq .GetAction "milestoned" -}}
+{{template "user" .GetSender}} {{if $added}}added{{else}}removed{{end}} [{{.GetRepo.GetFullName}}#{{.GetNumber}}]({{.GetHTMLURL}}) '{{.GetTitle}}' {{if $added}}to{{else}}from{{end}} milestone [{{.GetMilestone.GetTitle}}]({{.GetMilestone.GetHTMLURL}})
+{{- if .GetMilestone.DueOn}}, due {{dateInZone "Jan 2, 2006" .GetMilestone.GetDueOn "UTC"}}{{end}}.
 `))
 
 	template.Must(masterTemplate.New("reopenedIssue").Funcs(funcMap).Parse(`
 {{template "repo" .GetRepo}} Issue {{template "issue" .GetIssue}} reopened by {{template "user" .GetSender}}.
 `))
 
-	template.Must(masterTemplate.New("pushedCommits").Funcs(funcMap).Parse(`
-{{template "user" .GetSender}} {{if .GetForced}}force-{{end}}pushed [{{len .Commits}} new commit{{if ne (len .Commits) 1}}s{{end}}]({{.GetCompare}}) to [\[{{.GetRepo.GetFullName}}:{{.GetRef | trimRef}}\]]({{.GetRepo.GetHTMLURL}}/tree/{{.GetRef | trimRef}}):
-{{range .Commits -}}
-[` + "`{{.GetID | substr 0 6}}`" + `]({{.GetURL}}) {{.GetMessage}} - {{.GetCommitter.GetName}}
+	// The commitAuthor template mentions the Mattermost user of the author of a commit, if known
+	// and not the sender of the event. Other authors link to their GitHub profile, if known.
+	template.Must(masterTemplate.New("commitAuthor").Parse(`
+{{- $login := .Commit.GetAuthor.GetLogin}}
+{{- $mattermostUsername := ""}}
+{{- if and $login (ne (lower $login) (lower .Sender.GetLogin))}}{{$mattermostUsername = $login | lookupMattermostUsername}}{{end}}
+{{- if $mattermostUsername}}@{{$mattermostUsername}}
+{{- else if $login}}[{{$login}}]({{userURL .Sender.GetHTMLURL $login}})
+{{- else}}{{.Commit.GetAuthor.GetName}}
+{{- end -}}
+`))
+
+	// The total number of commits is the push size when only part of the commits are included.
+	template.Must(masterTemplate.New("pushedCommitsList").Funcs(funcMap).Parse(`
+{{- $event := .Event}}{{$total := len $event.Commits}}{{if gt $event.GetSize $total}}{{$total = $event.GetSize}}{{end -}}
+{{template "user" $event.GetSender}} {{if $event.GetForced}}force-{{end}}pushed [{{$total}} new commit{{if ne $total 1}}s{{end}}]({{$event.GetCompare}}) to [\[{{$event.GetRepo.GetFullName}}:{{$event.GetRef | trimRef}}\]]({{$event.GetRepo.GetHTMLURL}}/tree/{{$event.GetRef | trimRef}}):
+{{range $event.Commits -}}
+[` + "`{{.GetID | substr 0 6}}`" + `]({{.GetURL}}) {{.GetMessage}} - {{if $.MentionUsers}}{{template "commitAuthor" dict "Commit" . "Sender" $event.GetSender}}{{else}}{{.GetCommitter.GetName}}{{end}}
+{{end -}}
+{{if gt $total (len $event.Commits)}}and {{sub $total (len $event.Commits)}} more commit{{if ne (sub $total (len $event.Commits)) 1}}s{{end}}
 {{end -}}
+`))
+
+	template.Must(masterTemplate.New("pushedCommits").Funcs(funcMap).Parse(`
+{{template "pushedCommitsList" dict "Event" . "MentionUsers" false}}`))
+
+	// Used by subscriptions with the --mention-users flag.
+	template.Must(masterTemplate.New("pushedCommitsWithMentions").Funcs(funcMap).Parse(`
+{{template "pushedCommitsList" dict "Event" . "MentionUsers" true}}`))
+
+	template.Must(masterTemplate.New("newFork").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} forked by {{template "user" .GetSender}} to [{{.GetForkee.GetFullName}}]({{.GetForkee.GetHTMLURL}})
+`))
+
+	// Deliveries touching many pages are summarized with a link to the history of the wiki.
+	template.Must(masterTemplate.New("wikiPages").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} Wiki updated by {{template "user" .GetSender}}:
+{{- if gt (len .Pages) 5}} {{len .Pages}} pages were created or edited, see the [wiki history]({{.GetRepo.GetHTMLURL}}/wiki/_history).
+{{- else}}
+{{range .Pages}}* [{{.GetTitle}}]({{.GetHTMLURL}}) {{.GetAction}}
+{{end}}{{end}}`))
+
+	template.Must(masterTemplate.New("wikiPages-collapsed").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} {{if eq (len .Pages) 1}}{{with index .Pages 0}}Wiki page [{{.GetTitle}}]({{.GetHTMLURL}}) was {{.GetAction}}{{end}}
+{{- else}}[{{len .Pages}} wiki pages]({{.GetRepo.GetHTMLURL}}/wiki/_history) were created or edited{{end}} by {{template "user" .GetSender}}.
+`))
+
+	template.Must(masterTemplate.New("newStar").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} starred by {{template "user" .GetSender}}, now at {{.GetRepo.GetStargazersCount}} stars
+`))
+
+	template.Must(masterTemplate.New("starMilestone").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} reached **{{.Milestone}} stars** :star: The latest one is from {{template "user" .GetSender}}
 `))
 
 	template.Must(masterTemplate.New("newCreateMessage").Funcs(funcMap).Parse(`
@@ -250,7 +328,7 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 	template.Must(masterTemplate.New("issueComment").Funcs(funcMap).Parse(`
 {{template "repo" .GetRepo}} New comment by {{template "user" .GetSender}} on {{template "issue" .Issue}}:
 
-{{.GetComment.GetBody | trimBody | replaceAllGitHubUsernames}}
+{{.GetComment.GetBody | trimBody | sanitizeBody .GetComment.GetHTMLURL | replaceAllGitHubUsernames}}
 `))
 
 	template.Must(masterTemplate.New("pullRequestReviewEvent").Funcs(funcMap).Parse(`
@@ -260,29 +338,48 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 {{- else if eq .GetReview.GetState "CHANGES_REQUESTED"}} requested changes on
 {{- end }} {{template "pullRequest" .GetPullRequest}}:
 
-{{.Review.GetBody | replaceAllGitHubUsernames}}
+{{.Review.GetBody | sanitizeBody .Review.GetHTMLURL | replaceAllGitHubUsernames}}
 `))
 
 	template.Must(masterTemplate.New("newReviewComment").Funcs(funcMap).Parse(`
 {{template "repo" .GetRepo}} New review comment by {{template "user" .GetSender}} on {{template "pullRequest" .GetPullRequest}}:
 
 {{.GetComment.GetDiffHunk}}
-{{.GetComment.GetBody | trimBody | replaceAllGitHubUsernames}}
+{{.GetComment.GetBody | trimBody | sanitizeBody .GetComment.GetHTMLURL | replaceAllGitHubUsernames}}
+`))
+
+	template.Must(masterTemplate.New("reviewThread").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} {{template "user" .GetSender}} {{.GetAction}} a review thread on [#{{.GetPullRequest.GetNumber}}]({{.GetThreadURL}}): '{{.GetThreadSummary | replaceAllGitHubUsernames}}'
+`))
+
+	template.Must(masterTemplate.New("reviewThreadNotification").Funcs(funcMap).Parse(`
+{{template "user" .GetSender}} {{.GetAction}} a review thread you commented on in [{{.GetRepo.GetFullName}}#{{.GetPullRequest.GetNumber}}]({{.GetThreadURL}}): '{{.GetThreadSummary | replaceAllGitHubUsernames}}'
+`))
+
+	template.Must(masterTemplate.New("transferredIssue").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} Issue {{template "issue" .GetIssue}} was transferred to [{{.GetNewRepo.GetFullName}}#{{.GetNewIssue.GetNumber}}]({{.GetNewIssue.GetHTMLURL}}) by {{template "user" .GetSender}}.
+`))
+
+	template.Must(masterTemplate.New("branchProtectionRule").Funcs(funcMap).Parse(`
+{{template "repo" .GetRepo}} {{template "user" .GetSender}} {{.GetAction}} the branch protection rule for ` + "`{{.GetRule.GetName}}`" + `
+{{- range .GetSettingDescriptions}}
+* {{.}}
+{{- end}}
 `))
 
 	template.Must(masterTemplate.New("commentMentionNotification").Funcs(funcMap).Parse(`
 {{template "user" .GetSender}} mentioned you on [{{.GetRepo.GetFullName}}#{{.Issue.GetNumber}}]({{.GetComment.GetHTMLURL}}) - {{.Issue.GetTitle}}:
-{{.GetComment.GetBody | trimBody | quote | replaceAllGitHubUsernames}}
+{{.GetComment.GetBody | trimBody | sanitizeBody .GetComment.GetHTMLURL | quote | replaceAllGitHubUsernames}}
 `))
 
 	template.Must(masterTemplate.New("commentAuthorPullRequestNotification").Funcs(funcMap).Parse(`
 {{template "user" .GetSender}} commented on your pull request {{template "eventRepoIssueFullLinkWithTitle" .}}:
-{{.GetComment.GetBody | trimBody | quote | replaceAllGitHubUsernames}}
+{{.GetComment.GetBody | trimBody | sanitizeBody .GetComment.GetHTMLURL | quote | replaceAllGitHubUsernames}}
 `))
 
 	template.Must(masterTemplate.New("commentAuthorIssueNotification").Funcs(funcMap).Parse(`
 {{template "user" .GetSender}} commented on your issue {{template "eventRepoIssueFullLinkWithTitle" .}}:
-{{.GetComment.GetBody | trimBody | quote | replaceAllGitHubUsernames}}
+{{.GetComment.GetBody | trimBody | sanitizeBody .GetComment.GetHTMLURL | quote | replaceAllGitHubUsernames}}
 `))
 
 	template.Must(masterTemplate.New("pullRequestNotification").Funcs(funcMap).Parse(`
@@ -295,6 +392,10 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 {{- else if eq .GetAction "reopened" }} reopened your pull request
 {{- else if eq .GetAction "assigned" }} assigned you to pull request
 {{- end }} {{template "eventRepoPullRequestWithTitle" .}}
+`))
+
+	template.Must(masterTemplate.New("teamReviewRequestNotification").Funcs(funcMap).Parse(`
+{{template "user" .GetSender}} requested a review from your team **{{.GetRequestedTeam.GetName}}** on {{template "eventRepoPullRequestWithTitle" .}}
 `))
 
 	template.Must(masterTemplate.New("issueNotification").Funcs(funcMap).Parse(`
@@ -311,20 +412,85 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 {{- else if eq .GetReview.GetState "changes_requested" }} requested changes on your pull request
 {{- else if eq .GetReview.GetState "commented" }} commented on your pull request
 {{- end }} {{template "reviewRepoPullRequestWithTitle" .}}
-{{if .GetReview.GetBody}}{{.Review.GetBody | trimBody | quote | replaceAllGitHubUsernames}}
+{{if .GetReview.GetBody}}{{.Review.GetBody | trimBody | sanitizeBody .Review.GetHTMLURL | quote | replaceAllGitHubUsernames}}
 {{else}}{{end}}`))
 
+	template.Must(masterTemplate.New("digestCount").Parse(
+		`{{if .Available}}{{.Count}}{{else}}unavailable{{end}}`,
+	))
+
+	template.Must(masterTemplate.New("weeklyDigest").Funcs(funcMap).Parse(`
+#### Weekly digest for {{.Repository}}
+{{dateInZone "Jan 2" .From "UTC"}} - {{dateInZone "Jan 2, 2006" .To "UTC"}}
+##### Merged pull requests: {{template "digestCount" .MergedPRs}}
+{{range .TopMergedPRs -}}
+* {{template "issue" .}} by {{template "user" .GetUser}} - {{.GetComments}} comment{{if ne .GetComments 1}}s{{end}}
+{{end -}}
+##### New issues: {{template "digestCount" .OpenedIssues}}
+##### Closed issues: {{template "digestCount" .ClosedIssues}}
+##### New contributors: {{template "digestCount" .NewContributors}}
+##### Releases: {{template "digestCount" .Releases}}
+`))
+
+	template.Must(masterTemplate.New("weeklySummaryItems").Parse(
+		`{{range .Items}}* {{template "issue" .}}
+{{end}}`,
+	))
+
+	template.Must(masterTemplate.New("weeklySummary").Funcs(funcMap).Parse(`
+#### Your GitHub week
+{{dateInZone "Jan 2" .From "UTC"}} - {{dateInZone "Jan 2, 2006" .To "UTC"}}
+##### Merged pull requests: {{template "digestCount" .MergedPRs}}
+{{template "weeklySummaryItems" .MergedPRs -}}
+##### Reviewed pull requests: {{template "digestCount" .Reviews}}
+{{template "weeklySummaryItems" .Reviews -}}
+##### Closed issues: {{template "digestCount" .ClosedIssues}}
+{{template "weeklySummaryItems" .ClosedIssues -}}
+##### Awaiting your review: {{template "digestCount" .AwaitingReviews}}
+{{template "weeklySummaryItems" .AwaitingReviews -}}
+`))
+
+	template.Must(masterTemplate.New("overdueReviews").Funcs(funcMap).Parse(`
+#### Reviews waiting for more than {{.SLAHours}}h
+{{range .Requests -}}
+* {{template "user" .Reviewer}} has had [{{.Repo}}#{{.Number}} {{.Title}}]({{.URL}}) waiting {{.Waiting $.Now}}
+{{end -}}
+`))
+
+	template.Must(masterTemplate.New("keywordMention").Funcs(funcMap).Parse(`
+{{- template "repo" .Repo }} {{ .Kind }} [{{ .Title }}]({{ .URL }}) by {{ template "user" .Sender }} mentions {{ range $i, $p := .Patterns }}{{ if $i }}, {{ end }}` + "`{{ $p }}`" + `{{ end }}
+> {{ .Excerpt }}
+`))
+
+	template.Must(masterTemplate.New("workflowRunCompleted").Funcs(funcMap).Parse(`
+{{- with .GetWorkflowRun }}[{{ $.GetWorkflow.GetName }} #{{ .GetRunNumber }}]({{ .GetHTMLURL }}) on ` + "`{{ .GetHeadBranch }}`" + ` completed: **{{ .GetConclusion }}**{{ end -}}
+`))
+
+	template.Must(masterTemplate.New("workflowRunFailed").Funcs(funcMap).Parse(`
+{{- template "repo" .GetRepo }} workflow {{ with .GetWorkflowRun }}[{{ $.GetWorkflow.GetName }} #{{ .GetRunNumber }}]({{ .GetHTMLURL }}) failed on ` + "`{{ .GetHeadBranch }}`" + `{{ if ne .GetConclusion "failure" }} ({{ .GetConclusion }}){{ end }}{{ end }}, triggered by {{ template "user" .GetSender -}}
+`))
+
 	template.Must(masterTemplate.New("helpText").Parse("" +
 		"* `/github connect{{if .EnablePrivateRepo}} [private]{{end}}` - Connect your Mattermost account to your GitHub account.\n" +
 		"{{if .EnablePrivateRepo}}" +
 		"  * `private` is optional. If used, read access to your private repositories will be requested." +
 		"If these repositories send webhook events to this Mattermost server, you will be notified of changes to those repositories.\n" +
 		"{{end}}" +
+		"{{if .AllowPersonalAccessTokens}}" +
+		"* `/github connect token` - Connect your GitHub account with a personal access token, for example when your organization doesn't approve the plugin. A dialog asks for the token, and you're told about the missing scopes\n" +
+		"{{end}}" +
 		"* `/github disconnect` - Disconnect your Mattermost account from your GitHub account\n" +
+		"* `/github link-username <GitHub username>` - Get notified about your GitHub mentions, assignments and review requests without connecting your account. You'll be asked to add a code to your GitHub profile bio or a public gist, then to run `/github link-username verify`\n" +
+		"* `/github unlink-username` - Stop getting notified about the GitHub username you linked\n" +
 		"* `/github help` - Display Slash Command help text\n" +
 		"* `/github todo` - Get a list of unread messages and pull requests awaiting your review\n" +
+		"{{if .AllowSharedTodos}}" +
+		"  * `/github todo --share` previews the list as it would be posted in the current channel, to share it for example during a stand-up. Items of private repositories are left out in public channels\n" +
+		"{{end}}" +
 		"* `/github subscriptions list` - Will list the current channel subscriptions\n" +
 		"* `/github subscriptions add owner[/repo] [features] [flags]` - Subscribe the current channel to receive notifications about opened pull requests and issues for an organization or repository\n" +
+		"  * `/github subscriptions add owner[/repo] [features] [flags] --target dm` subscribes your direct messages with the bot instead, to get the notifications privately. Run `/github subscriptions list` and `/github subscriptions delete` there to manage these subscriptions\n" +
+		"  * `/github subscriptions add --from-header [features] [flags]` subscribes to the repository linked in the channel header instead. If several are linked, you'll be asked to pick one by its position, e.g. `--from-header 2`\n" +
 		"  * `features` is a comma-delimited list of one or more the following:\n" +
 		"    * `issues` - includes new and closed issues\n" +
 		"    * `pulls` - includes new and closed pull requests\n" +
@@ -333,19 +499,87 @@ Assignees: {{range $i, $el := .Assignees -}} {{- if $i}}, {{end}}{{template "use
 		"    * `deletes` - includes branch and tag deletions\n" +
 		"    * `issue_comments` - includes new issue comments\n" +
 		"    * `issue_creations` - includes new issues only \n" +
-		"    * `pull_reviews` - includes pull request reviews\n" +
-		"    * `label:<labelname>` - limit pull request and issue events to only this label. Must include `pulls` or `issues` in feature list when using a label.\n" +
+		"    * `pull_reviews` - includes pull request reviews and resolved review threads\n" +
+		"    * `branch_protection` - includes created, edited and deleted branch protection rules. When the plugin is locked to an organization, only for subscriptions created by its members\n" +
+		"    * `forks` - includes new forks\n" +
+		"    * `stars` - includes new stars. Use `--star-milestones` on popular repositories\n" +
+		"    * `wiki` - includes the wiki pages created or edited\n" +
+		"    * `workflow_failure` - includes the failed and timed out GitHub Actions workflow runs, which the webhook sends as `workflow_run` events. They are always posted, whatever the `--sample` and `--max-per-hour` flags\n" +
+		"    * `milestone_changes` - includes the issues and pull requests added to or removed from a milestone. Use `milestone_changes:\"<milestone>\"` to only include the changes of one milestone, e.g. `milestone_changes:\"v2.0\"`\n" +
+		"    * `label:<labelname>` - limit pull request and issue events to only this label. Must include `pulls`, `issues` or `milestone_changes` in feature list when using a label.\n" +
 		"    * Defaults to `pulls,issues,creates,deletes`\n" +
 		"  * `flags` currently supported:\n" +
 		"    * `--exclude-org-member` - events triggered by organization members will not be delivered (the GitHub organization config should be set, otherwise this flag has not effect)\n" +
+		"    * `--render-style [style]` - how to render new pull requests and issues: `default`, `skip-body` to leave out their description, or `collapsed` for a single line. Overrides the channel setting\n" +
+		"    * `--issue-form-fields \"Field 1,Field 2\"` - for issues created from an issue form, show only the given fields of the form instead of the whole description of new issues\n" +
+		"    * `--star-milestones 100,500,1000` - with the `stars` feature, only post when the number of stars of the repository reaches one of the given counts, each one once\n" +
+		"    * `--template [name]` - render notifications with a notification template configured by a system admin, falling back to the built-in rendering for the events it doesn't cover\n" +
+		"    * `--paths \"pattern,pattern\"` - only post pushes and pull requests touching files matching the given glob patterns, e.g. `--paths \"services/payments/**,docs/payments/*\"`. `**` matches any number of directories\n" +
+		"    * `--review-sla [duration]` - post the review requests pending for longer than the given duration, e.g. `--review-sla 24h`. Add `--review-sla-dm` to also send a direct message to the connected reviewers\n" +
+		"    * `--sample [rate]` - post at most a number out of every few events of each feature, e.g. `--sample 1/10` for 1 out of every 10 events. The next posted event tells how many were skipped\n" +
+		"    * `--suggest-reviewers true` - with the `pulls` feature, add the owners of the changed files to the posts of new pull requests as suggested reviewers, based on the CODEOWNERS file of the repository. The files are listed with the GitHub account of the creator of the subscription\n" +
+		"    * `--include-log-excerpt true` - with the `workflow_failure` feature, add the last lines of the log of the failed job before the error to the post. The log is downloaded with the GitHub account of the creator of the subscription, and left out if it can't be or is too large\n" +
+		"    * `--max-per-hour [count]` - post at most the given number of notifications per hour, e.g. `--max-per-hour 30`. Once the cap is reached, a single post tells how many notifications were suppressed until the end of the hour. Workflow failures and security alerts are always posted\n" +
+		"    * `--live-update true` - with the `issues` or `issue_creations` feature, update the post of a new issue when its title or labels change instead of posting again. Closing and reopening issues are still posted\n" +
+		"    * `--related-prs true` - with the `issues` or `issue_creations` feature, reply to the post of a new issue with up to 3 open pull requests whose title shares its keywords or which mention it. The search is made with the GitHub account of the creator of the subscription\n" +
+		"    * `--coalesce-comments [duration]` - with the `issue_comments` feature, merge the comments on an issue posted within the given duration of the first one into its post, e.g. `--coalesce-comments 2m`. Mentions and direct notifications are still sent for every comment\n" +
+		"    * `--mention-users true` - mention the authors of pushed commits who connected their GitHub account, instead of showing their name. The pusher isn't mentioned for their own commits\n" +
+		"    * `--sync-header true` - keep the channel header in sync with the description and default branch of the repository. Any existing header is kept after the synced part\n" +
+		"    * `--weekly-digest [day] [hh:mm]` - post a weekly digest of merged pull requests, issues, new contributors and releases on the given day and time (UTC), e.g. `--weekly-digest monday 09:00`\n" +
 		"* `/github subscriptions delete owner[/repo]` - Unsubscribe the current channel from a repository\n" +
-		"* `/github me` - Display the connected GitHub account\n" +
+		"* `/github subscriptions pause owner[/repo] [duration]` - Pause the notifications of the subscription of the current channel without deleting it, until resumed or for a duration, e.g. `12h` or `7d`\n" +
+		"* `/github subscriptions resume owner[/repo]` - Resume the notifications of a paused subscription of the current channel\n" +
+		"* `/github subscriptions claim owner[/repo]` - Become the creator of the subscription of the current channel. Events of private repositories are only posted while its creator can access them\n" +
+		"* `/github subscriptions route add owner[/repo] features ~channel` - (System Admin) Route events of the given features to another channel\n" +
+		"* `/github subscriptions route list` - (System Admin) List the subscription routes\n" +
+		"* `/github subscriptions route delete owner[/repo] ~channel` - (System Admin) Stop routing events to a channel\n" +
+		"* `/github channel-settings get` - Display the settings of the current channel\n" +
+		"* `/github channel-settings render-style [style]` - Set the render style of all subscriptions of the current channel that don't set their own: `default`, `skip-body` or `collapsed`\n" +
+		"* `/github channel-settings unset render-style` - Go back to the default render style for the current channel\n" +
+		"* `/github export-events [days]` - (System Admin) Export the GitHub notifications posted in the current channel over the last days to a CSV file, e.g. `30d`. Defaults to 7 days\n" +
+		"* `/github webhook info owner/repo` - Display the webhook configuration received from GitHub for a repository and when its last event was delivered\n" +
+		"* `/github setup test owner/repo` - (System Admin) Check the plugin configuration, your connection to GitHub, the webhook of a repository and its secret, and that the bot can post in the current channel. The results are sent as a direct message, offering to subscribe the channel to the repository when all checks pass\n" +
+		"* `/github setup welcome` - (System Admin) Preview the welcome message sent to users connecting their GitHub account\n" +
+		"* `/github setup announcement --channels town-square,engineering` - (System Admin) Announce the plugin in the given channels of your teams, with a link to connect an account. The result of each channel is reported back\n" +
+		"* `/github admin refresh-all` - (System Admin) Refresh the GitHub sidebar of all connected users, e.g. after a maintenance of GitHub\n" +
+		"* `/github admin sync-usernames` - (System Admin) Map the members of the organization to the Mattermost users with their public email, so they get notified without connecting their account\n" +
+		"* `/github admin refresh-org-members` - (System Admin) List the members of the organization again. They are cached for `--exclude-org-member` and kept current by the `organization` and `membership` webhook events\n" +
+		"* `/github admin subscriptions find owner[/repo]` - (System Admin) List the channels subscribed to a repository, with their creators and features\n" +
+		"* `/github admin subscriptions remove owner[/repo] --channel <channel ID|all>` - (System Admin) Remove the subscriptions of one or all channels to a repository. The affected channels are notified\n" +
+		"* `/github admin webhook-queue` - (System Admin) Show the activity of the queue of webhook events: the queued, processed and rejected events, and how long they wait and take to be processed\n" +
+		"* `/github keywords add <keyword|@org/team> [--channel here|~channel]` - (System Admin) Post new issues, pull requests and comments mentioning a keyword or a GitHub team to a channel. Keywords are matched as case-insensitive whole words\n" +
+		"* `/github keywords list [--channel here|~channel]` - (System Admin) List the keywords posted to a channel\n" +
+		"* `/github keywords remove <keyword|@org/team> [--channel here|~channel]` - (System Admin) Stop posting mentions of a keyword to a channel\n" +
+		"* `/github me` - Display the connected GitHub account and how much of its GitHub API quota is left\n" +
+		"* `/github whois @mattermost-user|github-login` - Tell if a user is connected to GitHub and their membership of the organization, or which user a GitHub account is connected to. Only users who are discoverable are shown\n" +
+		"* `/github me activity [days]` - Summarize your GitHub activity by repository over the last days, e.g. `7d`. Defaults to 7 days\n" +
+		"* `/github issue export [owner/repo] [--label bug] [--format csv|md]` - Post the open issues of a repository in the channel, as a CSV file or a markdown table. The repository can be omitted if the channel is subscribed to a single one\n" +
+		"* `/github assign owner/repo#number [@github-login|me]` - Assign a user to an issue or pull request, yourself by default. Run in the thread of a notification about the issue, the change is posted in the thread\n" +
+		"* `/github unassign owner/repo#number [@github-login|me]` - Unassign a user from an issue or pull request, yourself by default\n" +
+		"* `/github reviewers add owner/repo#number usernames` - Request a review of a pull request from a comma-delimited list of GitHub usernames or Mattermost @mentions\n" +
+		"* `/github reviewers remove owner/repo#number usernames` - Remove requested reviewers from a pull request\n" +
+		"  * `owner/repo` can be omitted if the channel is subscribed to a single repository\n" +
+		"* `/github workflow list owner/repo` - List the workflows of a repository that can be run with `workflow_dispatch`, with their inputs\n" +
+		"* `/github workflow run owner/repo workflow-file.yml ref [key=value ...]` - Run a workflow on a branch or tag with the given inputs. A reply is posted in the thread when the run completes, if the repository webhook sends `workflow_run` events\n" +
+		"* `/github pr create owner/repo head-branch [base-branch] [--title \"title\"] [--body \"body\"] [--draft]` - Create a pull request of a branch into the default branch of the repository, or the given base branch. The pull request is linked in the channel\n" +
+		"* `/github search save [name] \"[query]\"` - Save a GitHub search, e.g. `\"is:pr review-requested:@me label:urgent\"`. It shows with its count as a section of your GitHub sidebar\n" +
+		"* `/github search list` - List your saved searches\n" +
+		"* `/github search delete [name]` - Delete a saved search\n" +
+		"* `/github watch list` - List the repositories you watch on GitHub\n" +
+		"* `/github watch owner/repo [all|participating|ignore]` - Show or change the notifications GitHub sends you for a repository. `ignore` only mutes the notifications of GitHub, not the subscriptions of channels\n" +
+		"  * `owner/repo` can be omitted if the channel is subscribed to a single repository\n" +
 		"* `/github settings [setting] [value]` - Update your user settings\n" +
-		"  * `setting` can be `notifications` or `reminders`\n" +
+		"  * `setting` can be `notifications`, `reminders`, `weekly-summary`, `discoverable`, `read-only`, `consolidated-dms`, `urgent-dms` or `invites`\n" +
+		"  * `/github settings discoverable off` stops showing your Mattermost account next to your GitHub login, unless you get notifications\n" +
+		"  * `/github settings read-only on` prevents the plugin from making changes on GitHub with your account, e.g. creating issues or comments. Reading from GitHub keeps working\n" +
+		"  * `/github settings consolidated-dms on` posts your notifications of a day as replies in a single \"GitHub activity\" thread. With `/github settings urgent-dms on`, mentions and review requests are still posted on their own\n" +
+		"  * `/github settings invites off` stops the monthly invites to connect your account sent when your Mattermost username is mentioned on GitHub. It works without a connected account\n" +
+		"  * `/github settings weekly-summary on [day] [hh:mm]` sends you a summary of your week on the given day and time (UTC), `sunday 18:00` by default\n" +
+		"  * `/github settings sidebar prs,reviews,refresh` only shows the given sidebar buttons, in that order. The buttons are `prs`, `reviews`, `assignments`, `unreads` and `refresh`. Use `/github settings sidebar default` to show them all again\n" +
 		"  * `value` can be `on` or `off`\n" +
 		"* `/github mute` - Managed muted GitHub users. You will not receive notifications for comments in your PRs and issues from those users.\n" +
 		"  * `/github mute list` - list your muted GitHub users\n" +
-		"  * `/github mute add [username]` - add a GitHub user to your muted list\n" +
+		"  * `/github mute add [username] [--scope channels]` - add a GitHub user to your muted list. With `--scope channels`, the channels you subscribed don't get the events of this user either\n" +
 		"  * `/github mute delete [username]` - remove a GitHub user from your muted list\n" +
 		"  * `/github mute delete-all` - unmute all GitHub users\n"))
 }
@@ -362,6 +596,15 @@ func lookupMattermostUsername(githubUsername string) string {
 	return gitHubToUsernameMappingCallback(githubUsername)
 }
 
+// renderStyledTemplate renders the variant of a template for the given render style, if there is one.
+func renderStyledTemplate(name, style string, data interface{}) (string, error) {
+	if style != renderStyleDefault && masterTemplate.Lookup(name+"-"+style) != nil {
+		name += "-" + style
+	}
+
+	return renderTemplate(name, data)
+}
+
 func renderTemplate(name string, data interface{}) (string, error) {
 	var output bytes.Buffer
 	t := masterTemplate.Lookup(name)
@@ -228,6 +228,36 @@ func TestUserTemplate(t *testing.T) {
 }
 
 func TestNewPRMessageTemplate(t *testing.T) {
+	t.Run("skip body", func(t *testing.T) {
+		expected := `
+#### Leverage git-get-head
+##### [mattermost-plugin-github#42](https://github.com/mattermost/mattermost-plugin-github/pull/42)
+#new-pull-request by [panda](https://github.com/panda)
+`
+
+		actual, err := renderStyledTemplate("newPR", renderStyleSkipBody, &github.PullRequestEvent{
+			Repo:        &repo,
+			PullRequest: &pullRequest,
+			Sender:      &user,
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("collapsed", func(t *testing.T) {
+		expected := `
+[\[mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) New pull request [#42 Leverage git-get-head](https://github.com/mattermost/mattermost-plugin-github/pull/42) was opened by [panda](https://github.com/panda).
+`
+
+		actual, err := renderStyledTemplate("newPR", renderStyleCollapsed, &github.PullRequestEvent{
+			Repo:        &repo,
+			PullRequest: &pullRequest,
+			Sender:      &user,
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
 	t.Run("without mentions", func(t *testing.T) {
 		expected := `
 #### Leverage git-get-head
@@ -360,6 +390,20 @@ func TestPullRequestLabelledTemplate(t *testing.T) {
 }
 
 func TestNewIssueTemplate(t *testing.T) {
+	t.Run("collapsed", func(t *testing.T) {
+		expected := `
+[\[mattermost-plugin-github\]](https://github.com/mattermost/mattermost-plugin-github) New issue [#1 Implement git-get-head](https://github.com/mattermost/mattermost-plugin-github/issues/1) was opened by [panda](https://github.com/panda).
+`
+
+		actual, err := renderStyledTemplate("newIssue", renderStyleCollapsed, &github.IssuesEvent{
+			Repo:   &repo,
+			Issue:  &issue,
+			Sender: &user,
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
 	t.Run("without mentions", func(t *testing.T) {
 		expected := `
 #### Implement git-get-head
@@ -620,6 +664,83 @@ func TestPushedCommitsTemplate(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, expected, actual)
 	})
+
+	t.Run("truncated commits", func(t *testing.T) {
+		expected := `
+[panda](https://github.com/panda) pushed [3 new commits](https://github.com/mattermost/mattermost-plugin-github/compare/master...branch) to [\[mattermost-plugin-github:branch\]](https://github.com/mattermost/mattermost-plugin-github/tree/branch):
+[` + "`a10867`" + `](https://github.com/mattermost/mattermost-plugin-github/commit/a10867b14bb761a232cd80139fbd4c0d33264240) Leverage git-get-head - panda
+and 2 more commits
+`
+
+		actual, err := renderTemplate("pushedCommits", &github.PushEvent{
+			Repo:   &pushEventRepository,
+			Sender: &user,
+			Forced: bToP(false),
+			Size:   iToP(3),
+			Commits: []*github.HeadCommit{
+				{
+					ID:      sToP("a10867b14bb761a232cd80139fbd4c0d33264240"),
+					URL:     sToP("https://github.com/mattermost/mattermost-plugin-github/commit/a10867b14bb761a232cd80139fbd4c0d33264240"),
+					Message: sToP("Leverage git-get-head"),
+					Committer: &github.CommitAuthor{
+						Name: sToP("panda"),
+					},
+				},
+			},
+			Compare: sToP("https://github.com/mattermost/mattermost-plugin-github/compare/master...branch"),
+			Ref:     sToP("refs/heads/branch"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	})
+
+	t.Run("mentioning commit authors", withGitHubUserNameMapping(func(t *testing.T) {
+		expected := `
+@pandabot pushed [4 new commits](https://github.com/mattermost/mattermost-plugin-github/compare/master...branch) to [\[mattermost-plugin-github:branch\]](https://github.com/mattermost/mattermost-plugin-github/tree/branch):
+[` + "`a10867`" + `](https://github.com/mattermost/mattermost-plugin-github/commit/a10867b14bb761a232cd80139fbd4c0d33264240) Leverage git-get-head - @maria.nunez
+[` + "`a20867`" + `](https://github.com/mattermost/mattermost-plugin-github/commit/a20867b14bb761a232cd80139fbd4c0d33264240) Merge master - [panda](https://github.com/panda)
+[` + "`a30867`" + `](https://github.com/mattermost/mattermost-plugin-github/commit/a30867b14bb761a232cd80139fbd4c0d33264240) Fix typo - [unknown-user](https://github.com/unknown-user)
+[` + "`a40867`" + `](https://github.com/mattermost/mattermost-plugin-github/commit/a40867b14bb761a232cd80139fbd4c0d33264240) Update docs - Jane Doe
+`
+
+		commit := func(id, message string, author *github.CommitAuthor) *github.HeadCommit {
+			return &github.HeadCommit{
+				ID:        sToP(id),
+				URL:       sToP("https://github.com/mattermost/mattermost-plugin-github/commit/" + id),
+				Message:   sToP(message),
+				Author:    author,
+				Committer: &github.CommitAuthor{Name: sToP("GitHub")},
+			}
+		}
+
+		actual, err := renderTemplate("pushedCommitsWithMentions", &github.PushEvent{
+			Repo:   &pushEventRepository,
+			Sender: &user,
+			Forced: bToP(false),
+			Commits: []*github.HeadCommit{
+				commit("a10867b14bb761a232cd80139fbd4c0d33264240", "Leverage git-get-head", &github.CommitAuthor{Name: sToP("Maria"), Login: sToP("marianunez")}),
+				// The sender isn't mentioned for their own commits
+				commit("a20867b14bb761a232cd80139fbd4c0d33264240", "Merge master", &github.CommitAuthor{Name: sToP("Panda"), Login: sToP("panda")}),
+				commit("a30867b14bb761a232cd80139fbd4c0d33264240", "Fix typo", &github.CommitAuthor{Name: sToP("Unknown"), Login: sToP("unknown-user")}),
+				commit("a40867b14bb761a232cd80139fbd4c0d33264240", "Update docs", &github.CommitAuthor{Name: sToP("Jane Doe")}),
+			},
+			Compare: sToP("https://github.com/mattermost/mattermost-plugin-github/compare/master...branch"),
+			Ref:     sToP("refs/heads/branch"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+
+		// Without the flag, the committers are named
+		actual, err = renderTemplate("pushedCommits", &github.PushEvent{
+			Repo:    &pushEventRepository,
+			Sender:  &user,
+			Commits: []*github.HeadCommit{commit("a10867b14bb761a232cd80139fbd4c0d33264240", "Leverage git-get-head", &github.CommitAuthor{Login: sToP("marianunez")})},
+			Compare: sToP("https://github.com/mattermost/mattermost-plugin-github/compare/master...branch"),
+			Ref:     sToP("refs/heads/branch"),
+		})
+		require.NoError(t, err)
+		require.Contains(t, actual, "Leverage git-get-head - GitHub\n")
+	}))
 }
 
 func TestCreateMessageTemplate(t *testing.T) {
@@ -1082,6 +1203,26 @@ func TestPullRequestNotification(t *testing.T) {
 	})
 }
 
+func TestTeamReviewRequestNotification(t *testing.T) {
+	expected := `
+[panda](https://github.com/panda) requested a review from your team **Core** on [mattermost-plugin-github#42](https://github.com/mattermost/mattermost-plugin-github/pull/42) - Leverage git-get-head
+`
+
+	actual, err := renderTemplate("teamReviewRequestNotification", &github.PullRequestEvent{
+		Repo:   &repo,
+		Action: sToP("review_requested"),
+		Sender: &user,
+		Number: iToP(42),
+		RequestedTeam: &github.Team{
+			Name: sToP("Core"),
+			Slug: sToP("core"),
+		},
+		PullRequest: &pullRequest,
+	})
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
 func TestIssueNotification(t *testing.T) {
 	t.Run("closed", func(t *testing.T) {
 		expected := `
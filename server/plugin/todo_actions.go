@@ -0,0 +1,355 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// toDoActionItemsLimit is the number of items of each section of the to do list with quick
+	// actions. The others are only listed as links, as posts have a limited number of actions.
+	toDoActionItemsLimit = 5
+
+	toDoActionApprove  = "approve"
+	toDoActionViewDiff = "view_diff"
+	toDoActionMarkRead = "mark_read"
+	toDoActionUnassign = "unassign"
+
+	toDoContextAction     = "action"
+	toDoContextUserID     = "user_id"
+	toDoContextAttachment = "attachment"
+	toDoContextRepo       = "repo"
+	toDoContextNumber     = "number"
+	toDoContextThreadID   = "thread_id"
+	toDoContextURL        = "url"
+
+	// toDoDiffFilesLimit is the number of changed files listed by the View diff action.
+	toDoDiffFilesLimit = 10
+)
+
+func (p *Plugin) getToDoAction(name, action string, actionContext map[string]interface{}) *model.PostAction {
+	actionContext[toDoContextAction] = action
+	return &model.PostAction{
+		Name: name,
+		Integration: &model.PostActionIntegration{
+			URL:     fmt.Sprintf("/plugins/%s/api/v1/todo/action", Manifest.Id),
+			Context: actionContext,
+		},
+	}
+}
+
+// toDoItemFromContext reads the issue or pull request of a quick action.
+func toDoItemFromContext(actionContext map[string]interface{}) (owner, repo string, number int, ok bool) {
+	repository, _ := actionContext[toDoContextRepo].(string)
+	numberValue, _ := actionContext[toDoContextNumber].(string)
+
+	owner, repo, err := parseRepo(repository)
+	if err != nil {
+		return "", "", 0, false
+	}
+	number, err = strconv.Atoi(numberValue)
+	if err != nil {
+		return "", "", 0, false
+	}
+
+	return owner, repo, number, true
+}
+
+// searchResultIssues returns the issues of a search, which is nil if it failed.
+func searchResultIssues(result *github.IssuesSearchResult) []*github.Issue {
+	if result == nil {
+		return nil
+	}
+	return result.Issues
+}
+
+// getToDoAttachments returns the quick actions on the first items of the sections of the to do list
+// of a user. The actions only work for this user.
+func (p *Plugin) getToDoAttachments(userID string, data *toDoData) []*model.SlackAttachment {
+	attachments := []*model.SlackAttachment{}
+
+	// The attachment is updated with the result of its action, so its index is part of the context
+	newContext := func(extra map[string]interface{}) map[string]interface{} {
+		actionContext := map[string]interface{}{
+			toDoContextUserID:     userID,
+			toDoContextAttachment: strconv.Itoa(len(attachments)),
+		}
+		for key, value := range extra {
+			actionContext[key] = value
+		}
+		return actionContext
+	}
+
+	notifications := p.relevantToDoNotifications(data.notifications)
+	for i, n := range notifications {
+		if i == toDoActionItemsLimit {
+			break
+		}
+
+		attachment := &model.SlackAttachment{
+			Title:     n.GetSubject().GetTitle(),
+			TitleLink: getToDoNotificationURL(p.getBaseURL(), n),
+			Text:      n.GetRepository().GetFullName(),
+		}
+		if i == 0 {
+			attachment.Pretext = "Unread messages"
+		}
+		attachment.Actions = []*model.PostAction{
+			p.getToDoAction("Mark notification read", toDoActionMarkRead, newContext(map[string]interface{}{toDoContextThreadID: n.GetID()})),
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	for i, pr := range searchResultIssues(data.reviews) {
+		if i == toDoActionItemsLimit {
+			break
+		}
+
+		owner, repo := getRepoOwnerAndNameFromURL(pr.GetRepositoryURL())
+		itemContext := func() map[string]interface{} {
+			return newContext(map[string]interface{}{
+				toDoContextRepo:   fullNameFromOwnerAndRepo(owner, repo),
+				toDoContextNumber: strconv.Itoa(pr.GetNumber()),
+				toDoContextURL:    pr.GetHTMLURL(),
+			})
+		}
+
+		attachment := &model.SlackAttachment{
+			Title:     pr.GetTitle(),
+			TitleLink: pr.GetHTMLURL(),
+			Text:      fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(owner, repo), pr.GetNumber()),
+		}
+		if i == 0 {
+			attachment.Pretext = "Review requests"
+		}
+		attachment.Actions = []*model.PostAction{
+			p.getToDoAction("Approve", toDoActionApprove, itemContext()),
+			p.getToDoAction("View diff", toDoActionViewDiff, itemContext()),
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	for i, issue := range searchResultIssues(data.yourAssignments) {
+		if i == toDoActionItemsLimit {
+			break
+		}
+
+		owner, repo := getRepoOwnerAndNameFromURL(issue.GetRepositoryURL())
+		attachment := &model.SlackAttachment{
+			Title:     issue.GetTitle(),
+			TitleLink: issue.GetHTMLURL(),
+			Text:      fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(owner, repo), issue.GetNumber()),
+		}
+		if i == 0 {
+			attachment.Pretext = "Assignments"
+		}
+		attachment.Actions = []*model.PostAction{
+			p.getToDoAction("Unassign me", toDoActionUnassign, newContext(map[string]interface{}{
+				toDoContextRepo:   fullNameFromOwnerAndRepo(owner, repo),
+				toDoContextNumber: strconv.Itoa(issue.GetNumber()),
+			})),
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments
+}
+
+// postToDoDigest sends the to do list of a user as a direct message, with quick actions on its
+// first items.
+func (p *Plugin) postToDoDigest(ctx context.Context, info *GitHubUserInfo) error {
+	data := p.fetchToDoData(ctx, info.GitHubUsername, p.githubConnect(*info.Token))
+	if data.failed() {
+		return errors.Wrap(data.reviewsErr, "error occurred while fetching the to do list")
+	}
+
+	channel, appErr := p.API.GetDirectChannel(info.UserID, p.BotUserID)
+	if appErr != nil {
+		return errors.Wrap(appErr, "couldn't get bot's DM channel")
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: channel.Id,
+		Message:   p.renderToDo(data, toDoActionItemsLimit),
+		Type:      "custom_git_todo",
+	}
+	if attachments := p.getToDoAttachments(info.UserID, data); len(attachments) > 0 {
+		model.ParseSlackAttachment(post, attachments)
+	}
+
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		return errors.Wrap(appErr, "failed to create DM post")
+	}
+
+	return nil
+}
+
+func (p *Plugin) handleToDoAction(w http.ResponseWriter, r *http.Request, userID string) {
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a post action request.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	p.writeJSON(w, p.runToDoAction(r.Context(), userID, request))
+}
+
+// runToDoAction runs a quick action of the to do list. Actions changing something on GitHub update
+// the to do list with their result.
+func (p *Plugin) runToDoAction(ctx context.Context, userID string, request *model.PostActionIntegrationRequest) *model.PostActionIntegrationResponse {
+	response := &model.PostActionIntegrationResponse{}
+
+	action, _ := request.Context[toDoContextAction].(string)
+	ownerID, _ := request.Context[toDoContextUserID].(string)
+	if ownerID != userID {
+		response.EphemeralText = "Only the owner of this to do list can use its actions."
+		return response
+	}
+
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		response.EphemeralText = apiErr.Message
+		return response
+	}
+
+	operation := githubWrite
+	if action == toDoActionViewDiff {
+		operation = githubRead
+	}
+	githubClient, err := p.getGithubClientFor(info, operation)
+	if err != nil {
+		response.EphemeralText = readOnlyModeMessage
+		return response
+	}
+
+	var result string
+	switch action {
+	case toDoActionMarkRead:
+		threadID, _ := request.Context[toDoContextThreadID].(string)
+		if _, err := githubClient.Activity.MarkThreadRead(ctx, threadID); err != nil {
+			p.API.LogWarn("Failed to mark notification read", "userID", userID, "error", err.Error())
+			response.EphemeralText = newAPIError(err, "Encountered an error marking the notification read.").Message
+			return response
+		}
+		result = "Marked as read."
+	case toDoActionViewDiff:
+		owner, repo, number, ok := toDoItemFromContext(request.Context)
+		if !ok {
+			response.EphemeralText = "The to do item is invalid."
+			return response
+		}
+
+		url, _ := request.Context[toDoContextURL].(string)
+		text, err := getToDoDiffSummary(ctx, githubClient, owner, repo, number, url)
+		if err != nil {
+			reference := fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(owner, repo), number)
+			p.API.LogWarn("Failed to get pull request diff", "pr", reference, "error", err.Error())
+			text = newAPIError(err, fmt.Sprintf("Encountered an error getting the changes of %s.", reference)).Message
+		}
+		response.EphemeralText = text
+		return response
+	case toDoActionApprove:
+		owner, repo, number, ok := toDoItemFromContext(request.Context)
+		if !ok {
+			response.EphemeralText = "The to do item is invalid."
+			return response
+		}
+
+		reference := fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(owner, repo), number)
+		review := &github.PullRequestReviewRequest{Event: github.String("APPROVE")}
+		if _, _, err := githubClient.PullRequests.CreateReview(ctx, owner, repo, number, review); err != nil {
+			p.API.LogWarn("Failed to approve pull request", "pr", reference, "error", err.Error())
+			response.EphemeralText = newAPIError(err, fmt.Sprintf("Encountered an error approving %s.", reference)).Message
+			return response
+		}
+		result = fmt.Sprintf("You approved %s.", reference)
+	case toDoActionUnassign:
+		owner, repo, number, ok := toDoItemFromContext(request.Context)
+		if !ok {
+			response.EphemeralText = "The to do item is invalid."
+			return response
+		}
+
+		reference := fmt.Sprintf("%s#%d", fullNameFromOwnerAndRepo(owner, repo), number)
+		if _, _, err := githubClient.Issues.RemoveAssignees(ctx, owner, repo, number, []string{info.GitHubUsername}); err != nil {
+			p.API.LogWarn("Failed to unassign user", "issue", reference, "error", err.Error())
+			response.EphemeralText = newAPIError(err, fmt.Sprintf("Encountered an error unassigning you from %s.", reference)).Message
+			return response
+		}
+		result = fmt.Sprintf("You're no longer assigned to %s.", reference)
+	default:
+		response.EphemeralText = fmt.Sprintf("Unknown action %q.", action)
+		return response
+	}
+
+	indexValue, _ := request.Context[toDoContextAttachment].(string)
+	index, err := strconv.Atoi(indexValue)
+	if err != nil {
+		index = -1
+	}
+	if update := p.getToDoPostUpdate(request.PostId, index, action, result); update != nil {
+		response.Update = update
+	} else {
+		response.EphemeralText = result
+	}
+
+	return response
+}
+
+// getToDoPostUpdate returns the to do list with the result of an action on one of its items, in place
+// of the action. It returns nil if the post can't be updated.
+func (p *Plugin) getToDoPostUpdate(postID string, index int, action, result string) *model.Post {
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		p.API.LogWarn("Failed to get to do post", "postID", postID, "error", appErr.Error())
+		return nil
+	}
+
+	attachments := post.Attachments()
+	if index < 0 || index >= len(attachments) {
+		return nil
+	}
+
+	attachment := attachments[index]
+	actions := []*model.PostAction{}
+	for _, postAction := range attachment.Actions {
+		if postAction.Integration == nil || postAction.Integration.Context[toDoContextAction] != action {
+			actions = append(actions, postAction)
+		}
+	}
+	attachment.Actions = actions
+	attachment.Text += "\n:white_check_mark: " + result
+
+	model.ParseSlackAttachment(post, attachments)
+	return post
+}
+
+// getToDoDiffSummary describes the changes of a pull request, listing its first changed files.
+func getToDoDiffSummary(ctx context.Context, githubClient *github.Client, owner, repo string, number int, url string) (string, error) {
+	pr, _, err := githubClient.PullRequests.Get(ctx, owner, repo, number)
+	if err != nil {
+		return "", errors.Wrap(err, "could not get pull request")
+	}
+
+	files, _, err := githubClient.PullRequests.ListFiles(ctx, owner, repo, number, &github.ListOptions{PerPage: toDoDiffFilesLimit})
+	if err != nil {
+		return "", errors.Wrap(err, "could not list pull request files")
+	}
+
+	text := fmt.Sprintf("[%s](%s) changes %d %s, +%d -%d:\n", pr.GetTitle(), url+"/files", pr.GetChangedFiles(), pluralize(pr.GetChangedFiles(), "file", "files"), pr.GetAdditions(), pr.GetDeletions())
+	for _, file := range files {
+		text += fmt.Sprintf("* `%s` +%d -%d\n", file.GetFilename(), file.GetAdditions(), file.GetDeletions())
+	}
+	if pr.GetChangedFiles() > len(files) {
+		text += fmt.Sprintf("* …and %d more\n", pr.GetChangedFiles()-len(files))
+	}
+
+	return text, nil
+}
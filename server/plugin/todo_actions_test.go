@@ -0,0 +1,244 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func newToDoTestData(reviews int) *toDoData {
+	repo := &github.Repository{FullName: github.String("owner/repo"), Owner: &github.User{Login: github.String("owner")}}
+	data := &toDoData{
+		notifications: []*github.Notification{{
+			ID:         github.String("41"),
+			Reason:     github.String(notificationReasonSubscribed),
+			Repository: repo,
+			Subject:    &github.NotificationSubject{Title: github.String("Watched"), URL: github.String("https://api.github.com/repos/owner/repo/issues/9"), Type: github.String("Issue")},
+		}, {
+			ID:         github.String("42"),
+			Reason:     github.String("mention"),
+			Repository: repo,
+			Subject:    &github.NotificationSubject{Title: github.String("Mentioned"), URL: github.String("https://api.github.com/repos/owner/repo/issues/10"), Type: github.String("Issue")},
+		}},
+		reviews:         &github.IssuesSearchResult{},
+		yourPrs:         &github.IssuesSearchResult{},
+		yourAssignments: &github.IssuesSearchResult{},
+	}
+
+	for i := 1; i <= reviews; i++ {
+		data.reviews.Issues = append(data.reviews.Issues, &github.Issue{
+			Number:        github.Int(i),
+			Title:         github.String(fmt.Sprintf("Review %d", i)),
+			HTMLURL:       github.String(fmt.Sprintf("https://github.com/owner/repo/pull/%d", i)),
+			RepositoryURL: github.String("https://api.github.com/repos/owner/repo"),
+		})
+	}
+	data.yourAssignments.Issues = []*github.Issue{{
+		Number:        github.Int(3),
+		Title:         github.String("Assigned"),
+		HTMLURL:       github.String("https://github.com/owner/repo/issues/3"),
+		RepositoryURL: github.String("https://api.github.com/repos/owner/repo"),
+	}}
+
+	return data
+}
+
+func TestGetToDoAttachments(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{})
+	p.SetAPI(&plugintest.API{})
+
+	attachments := p.getToDoAttachments("userID", newToDoTestData(7))
+
+	// A notification, the first 5 review requests and an assignment
+	require.Len(t, attachments, 7)
+
+	assert.Equal(t, "Unread messages", attachments[0].Pretext)
+	assert.Equal(t, "Mentioned", attachments[0].Title)
+	require.Len(t, attachments[0].Actions, 1)
+	assert.Equal(t, "Mark notification read", attachments[0].Actions[0].Name)
+	assert.Equal(t, map[string]interface{}{
+		toDoContextAction:     toDoActionMarkRead,
+		toDoContextUserID:     "userID",
+		toDoContextAttachment: "0",
+		toDoContextThreadID:   "42",
+	}, attachments[0].Actions[0].Integration.Context)
+
+	assert.Equal(t, "Review requests", attachments[1].Pretext)
+	assert.Equal(t, "owner/repo#1", attachments[1].Text)
+	require.Len(t, attachments[1].Actions, 2)
+	assert.Equal(t, "Approve", attachments[1].Actions[0].Name)
+	assert.Equal(t, "View diff", attachments[1].Actions[1].Name)
+	assert.Equal(t, "Review 5", attachments[5].Title)
+	assert.Empty(t, attachments[2].Pretext)
+
+	assert.Equal(t, "Assignments", attachments[6].Pretext)
+	require.Len(t, attachments[6].Actions, 1)
+	assert.Equal(t, map[string]interface{}{
+		toDoContextAction:     toDoActionUnassign,
+		toDoContextUserID:     "userID",
+		toDoContextAttachment: "6",
+		toDoContextRepo:       "owner/repo",
+		toDoContextNumber:     "3",
+	}, attachments[6].Actions[0].Integration.Context)
+
+	// Sections that failed have no actions
+	data := newToDoTestData(1)
+	data.reviews = nil
+	assert.Len(t, p.getToDoAttachments("userID", data), 2)
+}
+
+func TestRenderToDoWithActions(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{})
+	p.SetAPI(&plugintest.API{})
+
+	data := newToDoTestData(7)
+	data.reviews.Total = github.Int(7)
+	data.yourAssignments.Total = github.Int(1)
+
+	// The items attached with quick actions aren't listed again
+	text := p.renderToDo(data, toDoActionItemsLimit)
+	assert.Contains(t, text, "You have 1 unread messages:\n* _See below._\n##### Review Requests")
+	assert.Contains(t, text, "You have 7 pull requests awaiting your review:\n* _The first 5 are below._\n")
+	assert.NotContains(t, text, "Review 5")
+	assert.Contains(t, text, "Review 6")
+	assert.Contains(t, text, "Review 7")
+	assert.NotContains(t, text, "Assigned")
+
+	text = p.renderToDo(data, 0)
+	assert.Contains(t, text, "Review 1")
+	assert.Contains(t, text, "Assigned")
+	assert.NotContains(t, text, "below")
+}
+
+func TestRunToDoAction(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+string(body))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo/pulls/1/reviews":
+			fmt.Fprint(w, `{"id": 1, "state": "APPROVED"}`)
+		case "/api/v3/repos/owner/repo/pulls/1":
+			fmt.Fprint(w, `{"title": "Review 1", "changed_files": 12, "additions": 30, "deletions": 4}`)
+		case "/api/v3/repos/owner/repo/pulls/1/files":
+			fmt.Fprint(w, `[{"filename": "server/plugin.go", "additions": 20, "deletions": 4}]`)
+		case "/api/v3/notifications/threads/42":
+			w.WriteHeader(http.StatusResetContent)
+		case "/api/v3/repos/owner/repo/issues/3/assignees":
+			fmt.Fprint(w, `{"number": 3}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func(settings *UserSettings) (*Plugin, []*model.SlackAttachment) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice", Settings: settings})
+		require.NoError(t, err)
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		attachments := p.getToDoAttachments("userID", newToDoTestData(1))
+		post := &model.Post{Id: "postID", Type: "custom_git_todo"}
+		model.ParseSlackAttachment(post, attachments)
+		api.On("GetPost", "postID").Return(func(string) *model.Post { return post.Clone() }, nil)
+
+		return p, attachments
+	}
+
+	run := func(p *Plugin, userID string, action *model.PostAction) *model.PostActionIntegrationResponse {
+		requests = nil
+		return p.runToDoAction(context.Background(), userID, &model.PostActionIntegrationRequest{
+			UserId:  userID,
+			PostId:  "postID",
+			Context: action.Integration.Context,
+		})
+	}
+
+	t.Run("approve", func(t *testing.T) {
+		p, attachments := setup(&UserSettings{})
+
+		response := run(p, "userID", attachments[1].Actions[0])
+		assert.Empty(t, response.EphemeralText)
+		assert.Equal(t, []string{`POST /api/v3/repos/owner/repo/pulls/1/reviews {"event":"APPROVE"}` + "\n"}, requests)
+
+		require.NotNil(t, response.Update)
+		updated := response.Update.Attachments()
+		require.Len(t, updated, 3)
+		assert.Equal(t, "owner/repo#1\n:white_check_mark: You approved owner/repo#1.", updated[1].Text)
+		require.Len(t, updated[1].Actions, 1)
+		assert.Equal(t, "View diff", updated[1].Actions[0].Name)
+		assert.Len(t, updated[0].Actions, 1)
+	})
+
+	t.Run("view diff", func(t *testing.T) {
+		p, attachments := setup(&UserSettings{ReadOnlyMode: true})
+
+		response := run(p, "userID", attachments[1].Actions[1])
+		assert.Nil(t, response.Update)
+		assert.Equal(t, "[Review 1](https://github.com/owner/repo/pull/1/files) changes 12 files, +30 -4:\n"+
+			"* `server/plugin.go` +20 -4\n"+
+			"* …and 11 more\n", response.EphemeralText)
+	})
+
+	t.Run("mark notification read", func(t *testing.T) {
+		p, attachments := setup(&UserSettings{})
+
+		response := run(p, "userID", attachments[0].Actions[0])
+		assert.Equal(t, []string{"PATCH /api/v3/notifications/threads/42 "}, requests)
+		require.NotNil(t, response.Update)
+		updated := response.Update.Attachments()
+		assert.Empty(t, updated[0].Actions)
+		assert.Equal(t, "owner/repo\n:white_check_mark: Marked as read.", updated[0].Text)
+	})
+
+	t.Run("unassign", func(t *testing.T) {
+		p, attachments := setup(&UserSettings{})
+
+		response := run(p, "userID", attachments[2].Actions[0])
+		assert.Equal(t, []string{`DELETE /api/v3/repos/owner/repo/issues/3/assignees {"assignees":["alice"]}` + "\n"}, requests)
+		require.NotNil(t, response.Update)
+		assert.Equal(t, "owner/repo#3\n:white_check_mark: You're no longer assigned to owner/repo#3.", response.Update.Attachments()[2].Text)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		p, attachments := setup(&UserSettings{})
+
+		response := run(p, "otherID", attachments[1].Actions[0])
+		assert.Equal(t, "Only the owner of this to do list can use its actions.", response.EphemeralText)
+		assert.Nil(t, response.Update)
+		assert.Empty(t, requests)
+	})
+
+	t.Run("read-only mode", func(t *testing.T) {
+		p, attachments := setup(&UserSettings{ReadOnlyMode: true})
+
+		response := run(p, "userID", attachments[1].Actions[0])
+		assert.Equal(t, readOnlyModeMessage, response.EphemeralText)
+		assert.Empty(t, requests)
+	})
+}
@@ -0,0 +1,278 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	toDoShareFlag = "--share"
+	toDoShareKey  = "_todoshare"
+
+	// toDoShareTTL is how long the preview of a shared to do list can be confirmed, in seconds.
+	toDoShareTTL = 10 * 60
+
+	toDoShareActionConfirm = "share"
+	toDoShareActionCancel  = "cancel"
+
+	toDoShareContextID = "share_id"
+)
+
+// sharedToDo is the to do list of a user previewed before being shared in a channel.
+type sharedToDo struct {
+	UserID    string
+	ChannelID string
+	RootID    string
+	Message   string
+}
+
+func toDoShareKeyFor(shareID string) string {
+	return hashKey(toDoShareKey, shareID)
+}
+
+// isToDoShareChannelPrivate tells if the items of private repositories can be shared in a channel.
+func isToDoShareChannelPrivate(channel *model.Channel) bool {
+	return channel.Type == model.CHANNEL_PRIVATE || channel.Type == model.CHANNEL_DIRECT || channel.Type == model.CHANNEL_GROUP
+}
+
+// filterSearchResult removes the issues of the private repositories from a search result, and
+// returns the number of removed issues.
+func filterSearchResult(result *github.IssuesSearchResult, isPrivate func(repoURL string) bool) int {
+	if result == nil {
+		return 0
+	}
+
+	public := []*github.Issue{}
+	for _, issue := range result.Issues {
+		if !isPrivate(issue.GetRepositoryURL()) {
+			public = append(public, issue)
+		}
+	}
+
+	removed := len(result.Issues) - len(public)
+	result.Issues = public
+	result.Total = github.Int(result.GetTotal() - removed)
+	return removed
+}
+
+// removePrivateToDoItems removes the items of private repositories from the to do list, and returns
+// the number of removed items. Repositories whose visibility can't be checked count as private.
+func removePrivateToDoItems(ctx context.Context, githubClient *github.Client, data *toDoData) int {
+	private := map[string]bool{}
+	isPrivate := func(repoURL string) bool {
+		if isPrivate, ok := private[repoURL]; ok {
+			return isPrivate
+		}
+
+		owner, repo := getRepoOwnerAndNameFromURL(repoURL)
+		repository, _, err := githubClient.Repositories.Get(ctx, owner, repo)
+		private[repoURL] = err != nil || repository.GetPrivate()
+		return private[repoURL]
+	}
+
+	removed := 0
+	notifications := []*github.Notification{}
+	for _, n := range data.notifications {
+		if n.GetRepository().GetPrivate() {
+			removed++
+			continue
+		}
+		notifications = append(notifications, n)
+	}
+	data.notifications = notifications
+
+	for _, result := range []*github.IssuesSearchResult{data.reviews, data.yourPrs, data.yourAssignments} {
+		removed += filterSearchResult(result, isPrivate)
+	}
+
+	return removed
+}
+
+// storeSharedToDo stores the preview of a to do list until it's confirmed, and returns its ID.
+func (p *Plugin) storeSharedToDo(shared *sharedToDo) (string, error) {
+	value, err := json.Marshal(shared)
+	if err != nil {
+		return "", errors.Wrap(err, "could not marshal shared to do list")
+	}
+
+	shareID := model.NewId()
+	if appErr := p.API.KVSetWithExpiry(toDoShareKeyFor(shareID), value, toDoShareTTL); appErr != nil {
+		return "", errors.Wrap(appErr, "could not store shared to do list in KV store")
+	}
+
+	return shareID, nil
+}
+
+func (p *Plugin) getSharedToDo(shareID string) (*sharedToDo, error) {
+	value, appErr := p.API.KVGet(toDoShareKeyFor(shareID))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get shared to do list from KV store")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	shared := &sharedToDo{}
+	if err := json.Unmarshal(value, shared); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal shared to do list")
+	}
+
+	return shared, nil
+}
+
+func (p *Plugin) deleteSharedToDo(shareID string) {
+	if appErr := p.API.KVDelete(toDoShareKeyFor(shareID)); appErr != nil {
+		p.API.LogWarn("Failed to delete shared to do list", "shareID", shareID, "error", appErr.Error())
+	}
+}
+
+func (p *Plugin) getToDoShareAction(name, action, userID, shareID string) *model.PostAction {
+	return &model.PostAction{
+		Name: name,
+		Integration: &model.PostActionIntegration{
+			URL: fmt.Sprintf("/plugins/%s/api/v1/todo/share", Manifest.Id),
+			Context: map[string]interface{}{
+				toDoContextAction:  action,
+				toDoContextUserID:  userID,
+				toDoShareContextID: shareID,
+			},
+		},
+	}
+}
+
+// handleShareToDo previews the to do list of a user as it would be shared in the current channel,
+// with buttons to share it or cancel. An empty message is returned when the preview is sent.
+func (p *Plugin) handleShareToDo(ctx context.Context, args *model.CommandArgs, userInfo *GitHubUserInfo) string {
+	if !p.getConfiguration().AllowSharedTodos {
+		return "Sharing the to do list is disabled. Please ask a System Admin to enable it."
+	}
+
+	channel, appErr := p.API.GetChannel(args.ChannelId)
+	if appErr != nil {
+		p.API.LogWarn("Failed to get channel", "channelID", args.ChannelId, "error", appErr.Error())
+		return "Encountered an error sharing your to do items."
+	}
+
+	githubClient := p.getGithubClient(userInfo)
+	data := p.fetchToDoData(ctx, userInfo.GitHubUsername, githubClient)
+	if data.failed() {
+		return "Encountered an error getting your to do items."
+	}
+
+	removed := 0
+	if !isToDoShareChannelPrivate(channel) {
+		removed = removePrivateToDoItems(ctx, githubClient, data)
+	}
+
+	sharer := "someone"
+	if user, appErr := p.API.GetUser(args.UserId); appErr == nil {
+		sharer = "@" + user.Username
+	}
+
+	message := fmt.Sprintf("#### To do list shared by %s\n%s", sharer, p.renderToDo(data, 0))
+	shareID, err := p.storeSharedToDo(&sharedToDo{
+		UserID:    args.UserId,
+		ChannelID: args.ChannelId,
+		RootID:    args.RootId,
+		Message:   message,
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to store shared to do list", "userID", args.UserId, "error", err.Error())
+		return "Encountered an error sharing your to do items."
+	}
+
+	intro := "This is the to do list that will be shared in this channel. Only you can see this preview."
+	if removed > 0 {
+		intro += fmt.Sprintf(" %d %s of private repositories %s left out, as this channel is public.", removed, pluralize(removed, "item", "items"), pluralize(removed, "is", "are"))
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: args.ChannelId,
+		RootId:    args.RootId,
+		Message:   intro,
+	}
+	model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+		Text: message,
+		Actions: []*model.PostAction{
+			p.getToDoShareAction("Share", toDoShareActionConfirm, args.UserId, shareID),
+			p.getToDoShareAction("Cancel", toDoShareActionCancel, args.UserId, shareID),
+		},
+	}})
+	p.API.SendEphemeralPost(args.UserId, post)
+
+	return ""
+}
+
+func (p *Plugin) handleToDoShareAction(w http.ResponseWriter, r *http.Request, userID string) {
+	request := model.PostActionIntegrationRequestFromJson(r.Body)
+	if request == nil {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Please provide a post action request.", StatusCode: http.StatusBadRequest})
+		return
+	}
+
+	p.writeJSON(w, p.runToDoShareAction(userID, request))
+}
+
+// runToDoShareAction shares the previewed to do list in its channel, or cancels sharing it. The
+// preview is replaced with the outcome.
+func (p *Plugin) runToDoShareAction(userID string, request *model.PostActionIntegrationRequest) *model.PostActionIntegrationResponse {
+	response := &model.PostActionIntegrationResponse{}
+
+	action, _ := request.Context[toDoContextAction].(string)
+	ownerID, _ := request.Context[toDoContextUserID].(string)
+	shareID, _ := request.Context[toDoShareContextID].(string)
+	if ownerID != userID {
+		response.EphemeralText = "Only the owner of this to do list can share it."
+		return response
+	}
+
+	shared, err := p.getSharedToDo(shareID)
+	if err != nil {
+		p.API.LogWarn("Failed to get shared to do list", "userID", userID, "error", err.Error())
+		response.EphemeralText = "Encountered an error sharing your to do items."
+		return response
+	}
+	if shared == nil || shared.UserID != userID {
+		response.Update = &model.Post{Message: "This preview expired. Use `/github todo --share` to share your to do list again."}
+		return response
+	}
+
+	switch action {
+	case toDoShareActionCancel:
+		p.deleteSharedToDo(shareID)
+		response.Update = &model.Post{Message: "Your to do list wasn't shared."}
+		return response
+	case toDoShareActionConfirm:
+	default:
+		response.EphemeralText = fmt.Sprintf("Unknown action %q.", action)
+		return response
+	}
+
+	if !p.getConfiguration().AllowSharedTodos {
+		response.Update = &model.Post{Message: "Sharing the to do list is disabled. Please ask a System Admin to enable it."}
+		return response
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: shared.ChannelID,
+		RootId:    shared.RootID,
+		Message:   shared.Message,
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to share to do list", "userID", userID, "channelID", shared.ChannelID, "error", appErr.Error())
+		response.EphemeralText = "Encountered an error sharing your to do items."
+		return response
+	}
+
+	p.deleteSharedToDo(shareID)
+	response.Update = &model.Post{Message: "Shared your to do list in this channel."}
+	return response
+}
@@ -0,0 +1,243 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestIsToDoShareChannelPrivate(t *testing.T) {
+	assert.False(t, isToDoShareChannelPrivate(&model.Channel{Type: model.CHANNEL_OPEN}))
+	assert.True(t, isToDoShareChannelPrivate(&model.Channel{Type: model.CHANNEL_PRIVATE}))
+	assert.True(t, isToDoShareChannelPrivate(&model.Channel{Type: model.CHANNEL_DIRECT}))
+	assert.True(t, isToDoShareChannelPrivate(&model.Channel{Type: model.CHANNEL_GROUP}))
+}
+
+func TestFilterSearchResult(t *testing.T) {
+	result := &github.IssuesSearchResult{
+		Total: github.Int(3),
+		Issues: []*github.Issue{
+			{Number: github.Int(1), RepositoryURL: github.String("https://api.github.com/repos/owner/public")},
+			{Number: github.Int(2), RepositoryURL: github.String("https://api.github.com/repos/owner/private")},
+			{Number: github.Int(3), RepositoryURL: github.String("https://api.github.com/repos/owner/public")},
+		},
+	}
+
+	removed := filterSearchResult(result, func(repoURL string) bool {
+		return repoURL == "https://api.github.com/repos/owner/private"
+	})
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, 2, result.GetTotal())
+	require.Len(t, result.Issues, 2)
+	assert.Equal(t, 1, result.Issues[0].GetNumber())
+	assert.Equal(t, 3, result.Issues[1].GetNumber())
+
+	assert.Equal(t, 0, filterSearchResult(nil, func(string) bool { return true }))
+}
+
+func TestRemovePrivateToDoItems(t *testing.T) {
+	requests := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/public":
+			fmt.Fprint(w, `{"full_name": "owner/public", "private": false}`)
+		case "/api/v3/repos/owner/private":
+			fmt.Fprint(w, `{"full_name": "owner/private", "private": true}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := GetGitHubClient(oauth2.Token{AccessToken: "token"}, &Configuration{EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+	require.NoError(t, err)
+
+	issue := func(number int, repo string) *github.Issue {
+		return &github.Issue{Number: github.Int(number), RepositoryURL: github.String(ts.URL + "/api/v3/repos/owner/" + repo)}
+	}
+	data := &toDoData{
+		notifications: []*github.Notification{
+			{ID: github.String("1"), Repository: &github.Repository{Private: github.Bool(false)}},
+			{ID: github.String("2"), Repository: &github.Repository{Private: github.Bool(true)}},
+		},
+		reviews:         &github.IssuesSearchResult{Total: github.Int(2), Issues: []*github.Issue{issue(1, "public"), issue(2, "private")}},
+		yourPrs:         &github.IssuesSearchResult{Total: github.Int(1), Issues: []*github.Issue{issue(3, "private")}},
+		yourAssignments: &github.IssuesSearchResult{Total: github.Int(2), Issues: []*github.Issue{issue(4, "public"), issue(5, "missing")}},
+	}
+
+	removed := removePrivateToDoItems(context.Background(), client, data)
+
+	// The notification of the private repository, two private issues and the unknown one
+	assert.Equal(t, 4, removed)
+	require.Len(t, data.notifications, 1)
+	assert.Equal(t, "1", data.notifications[0].GetID())
+	require.Len(t, data.reviews.Issues, 1)
+	assert.Equal(t, 1, data.reviews.Issues[0].GetNumber())
+	assert.Empty(t, data.yourPrs.Issues)
+	assert.Equal(t, 0, data.yourPrs.GetTotal())
+	require.Len(t, data.yourAssignments.Issues, 1)
+	assert.Equal(t, 4, data.yourAssignments.Issues[0].GetNumber())
+
+	// The visibility of each repository is only fetched once
+	assert.Equal(t, 1, requests["/api/v3/repos/owner/private"])
+}
+
+func TestShareToDo(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/search/issues":
+			if r.URL.Query().Get("q") == getReviewSearchQuery("alice", "") {
+				fmt.Fprintf(w, `{"total_count": 2, "items": [
+					{"number": 1, "title": "Public review", "html_url": "https://github.com/owner/public/pull/1", "repository_url": "%[1]s/api/v3/repos/owner/public"},
+					{"number": 2, "title": "Private review", "html_url": "https://github.com/owner/private/pull/2", "repository_url": "%[1]s/api/v3/repos/owner/private"}
+				]}`, "http://"+r.Host)
+				return
+			}
+			fmt.Fprint(w, `{"total_count": 0, "items": []}`)
+		case "/api/v3/notifications":
+			fmt.Fprint(w, `[]`)
+		case "/api/v3/repos/owner/public":
+			fmt.Fprint(w, `{"private": false}`)
+		case "/api/v3/repos/owner/private":
+			fmt.Fprint(w, `{"private": true}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	userInfo := &GitHubUserInfo{UserID: "userID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: "token"}}
+	args := &model.CommandArgs{UserId: "userID", ChannelId: "channelID", RootId: "rootID"}
+
+	setup := func(t *testing.T, channelType string) (*Plugin, *plugintest.API, *model.Post) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{AllowSharedTodos: true, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		api.On("GetChannel", "channelID").Return(&model.Channel{Id: "channelID", Type: channelType}, nil)
+		api.On("GetUser", "userID").Return(&model.User{Id: "userID", Username: "alice.mm"}, nil)
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+		store := map[string][]byte{}
+		api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, int64(toDoShareTTL)).Return(func(key string, value []byte, _ int64) *model.AppError {
+			store[key] = value
+			return nil
+		})
+		api.On("KVGet", mock.AnythingOfType("string")).Return(func(key string) []byte { return store[key] }, nil)
+		api.On("KVDelete", mock.AnythingOfType("string")).Return(func(key string) *model.AppError {
+			delete(store, key)
+			return nil
+		})
+
+		preview := &model.Post{}
+		api.On("SendEphemeralPost", "userID", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+			preview = args.Get(1).(*model.Post)
+		}).Return(nil)
+		p.SetAPI(api)
+
+		assert.Empty(t, p.handleShareToDo(context.Background(), args, userInfo))
+		return p, api, preview
+	}
+
+	run := func(p *Plugin, userID string, preview *model.Post, action int) *model.PostActionIntegrationResponse {
+		return p.runToDoShareAction(userID, &model.PostActionIntegrationRequest{
+			UserId:  userID,
+			Context: preview.Attachments()[0].Actions[action].Integration.Context,
+		})
+	}
+
+	t.Run("preview and share in a public channel", func(t *testing.T) {
+		p, api, preview := setup(t, model.CHANNEL_OPEN)
+
+		assert.Equal(t, "botID", preview.UserId)
+		assert.Equal(t, "This is the to do list that will be shared in this channel. Only you can see this preview. 1 item of private repositories is left out, as this channel is public.", preview.Message)
+		attachments := preview.Attachments()
+		require.Len(t, attachments, 1)
+		assert.Contains(t, attachments[0].Text, "#### To do list shared by @alice.mm\n")
+		assert.Contains(t, attachments[0].Text, "Public review")
+		assert.NotContains(t, attachments[0].Text, "Private review")
+		require.Len(t, attachments[0].Actions, 2)
+		assert.Equal(t, "Share", attachments[0].Actions[0].Name)
+		assert.Equal(t, "Cancel", attachments[0].Actions[1].Name)
+
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			assert.Equal(t, "botID", post.UserId)
+			assert.Equal(t, "channelID", post.ChannelId)
+			assert.Equal(t, "rootID", post.RootId)
+			assert.Equal(t, attachments[0].Text, post.Message)
+			return post
+		}, nil).Once()
+
+		response := run(p, "userID", preview, 0)
+		assert.Empty(t, response.EphemeralText)
+		require.NotNil(t, response.Update)
+		assert.Equal(t, "Shared your to do list in this channel.", response.Update.Message)
+
+		// The preview can only be shared once
+		response = run(p, "userID", preview, 0)
+		assert.Equal(t, "This preview expired. Use `/github todo --share` to share your to do list again.", response.Update.Message)
+		api.AssertNumberOfCalls(t, "CreatePost", 1)
+	})
+
+	t.Run("private items in a private channel", func(t *testing.T) {
+		_, _, preview := setup(t, model.CHANNEL_PRIVATE)
+
+		assert.Equal(t, "This is the to do list that will be shared in this channel. Only you can see this preview.", preview.Message)
+		assert.Contains(t, preview.Attachments()[0].Text, "Private review")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		p, api, preview := setup(t, model.CHANNEL_DIRECT)
+
+		response := run(p, "userID", preview, 1)
+		require.NotNil(t, response.Update)
+		assert.Equal(t, "Your to do list wasn't shared.", response.Update.Message)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+
+		response = run(p, "userID", preview, 0)
+		assert.Equal(t, "This preview expired. Use `/github todo --share` to share your to do list again.", response.Update.Message)
+	})
+
+	t.Run("other user", func(t *testing.T) {
+		p, api, preview := setup(t, model.CHANNEL_OPEN)
+
+		response := run(p, "otherID", preview, 0)
+		assert.Equal(t, "Only the owner of this to do list can share it.", response.EphemeralText)
+		assert.Nil(t, response.Update)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("disabled after the preview", func(t *testing.T) {
+		p, api, preview := setup(t, model.CHANNEL_OPEN)
+		p.setConfiguration(&Configuration{})
+
+		response := run(p, "userID", preview, 0)
+		assert.Equal(t, "Sharing the to do list is disabled. Please ask a System Admin to enable it.", response.Update.Message)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{})
+		api := &plugintest.API{}
+		p.SetAPI(api)
+
+		assert.Equal(t, "Sharing the to do list is disabled. Please ask a System Admin to enable it.", p.handleShareToDo(context.Background(), args, userInfo))
+		api.AssertNotCalled(t, "SendEphemeralPost", mock.Anything, mock.Anything)
+	})
+}
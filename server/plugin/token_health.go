@@ -0,0 +1,285 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	tokenHealthJobKey   = "token_health"
+	tokenHealthStateKey = "token_health_state"
+	tokenCheckKey       = "_tokencheck"
+
+	// tokenHealthJobInterval is the delay between two runs of the token health checks. A run checks
+	// at most tokenHealthChecksPerRun tokens, waiting tokenHealthCheckDelay between them, so that
+	// the checks are spread over the day.
+	tokenHealthJobInterval  = 5 * time.Minute
+	tokenHealthChecksPerRun = 10
+	tokenHealthCheckDelay   = 2 * time.Second
+	// tokenHealthCheckInterval is how often the token of a user is checked.
+	tokenHealthCheckInterval = 24 * time.Hour
+
+	tokenHealthy = "healthy"
+	tokenRevoked = "revoked"
+)
+
+var errTokenCheckRateLimited = errors.New("rate limited while checking token")
+
+// tokenCheck is the result of the last check of the token of a user.
+type tokenCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Result    string    `json:"result"`
+}
+
+// tokenHealthCounts counts the connections by the result of the check of their token.
+type tokenHealthCounts struct {
+	Healthy     int       `json:"healthy"`
+	Revoked     int       `json:"revoked"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+}
+
+func (c *tokenHealthCounts) add(result string) {
+	switch result {
+	case tokenHealthy:
+		c.Healthy++
+	case tokenRevoked:
+		c.Revoked++
+	}
+}
+
+// tokenHealthState is the progress of the current pass of the token health checks over the
+// connected users.
+type tokenHealthState struct {
+	// UserIDs are the connected users listed when the pass started, and Offset the next one to check.
+	// They are listed all at once, as the users disconnected by the pass would shift the pages of the
+	// KV store keys.
+	UserIDs []string          `json:"user_ids,omitempty"`
+	Offset  int               `json:"offset"`
+	Current tokenHealthCounts `json:"current"`
+	// Last holds the counts of the last complete pass.
+	Last *tokenHealthCounts `json:"last,omitempty"`
+}
+
+func (p *Plugin) getTokenHealthState() (*tokenHealthState, error) {
+	value, appErr := p.API.KVGet(tokenHealthStateKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get token health state from KV store")
+	}
+
+	state := &tokenHealthState{}
+	if value == nil {
+		return state, nil
+	}
+
+	if err := json.Unmarshal(value, state); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal token health state")
+	}
+
+	return state, nil
+}
+
+func (p *Plugin) storeTokenHealthState(state *tokenHealthState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal token health state")
+	}
+
+	if appErr := p.API.KVSet(tokenHealthStateKey, value); appErr != nil {
+		return errors.Wrap(appErr, "could not store token health state in KV store")
+	}
+
+	return nil
+}
+
+// getTokenCheck returns the last check of the token of a user, or nil if it wasn't checked in the
+// last day.
+func (p *Plugin) getTokenCheck(userID string) (*tokenCheck, error) {
+	value, appErr := p.API.KVGet(userID + tokenCheckKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get token check from KV store")
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	var check *tokenCheck
+	if err := json.Unmarshal(value, &check); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal token check")
+	}
+
+	return check, nil
+}
+
+func (p *Plugin) storeTokenCheck(userID string, check *tokenCheck) error {
+	value, err := json.Marshal(check)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal token check")
+	}
+
+	if appErr := p.API.KVSetWithExpiry(userID+tokenCheckKey, value, int64(tokenHealthCheckInterval/time.Second)); appErr != nil {
+		return errors.Wrap(appErr, "could not store token check in KV store")
+	}
+
+	return nil
+}
+
+// checkToken makes a cheap authenticated request with the token of a user. Only a 401 response
+// means the token is revoked, errTokenCheckRateLimited is returned when the rate limit is exceeded.
+func (p *Plugin) checkToken(ctx context.Context, info *GitHubUserInfo) (string, error) {
+	_, _, err := p.githubConnect(*info.Token).Users.Get(ctx, "")
+	if err == nil {
+		return tokenHealthy, nil
+	}
+
+	var rateLimitErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseErr) {
+		return "", errTokenCheckRateLimited
+	}
+
+	var errResp *github.ErrorResponse
+	if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusUnauthorized {
+		return tokenRevoked, nil
+	}
+
+	return "", err
+}
+
+// handleRevokedToken disconnects a user whose token GitHub rejects, and asks them to connect their
+// account again.
+func (p *Plugin) handleRevokedToken(info *GitHubUserInfo) {
+	p.API.LogInfo("Disconnecting user whose GitHub token was revoked", "userID", info.UserID, "GitHub username", info.GitHubUsername)
+	p.disconnectGitHubAccount(info.UserID)
+
+	message := fmt.Sprintf("Your GitHub account `%s` was disconnected because GitHub no longer accepts its token, for example after it was revoked by an organization policy or a password reset. "+
+		"Use `/github connect` to connect it again.", info.GitHubUsername)
+	if info.isTokenConnection() {
+		// Personal access tokens expire on GitHub, and are never refreshed by the plugin
+		message = fmt.Sprintf("Your GitHub account `%s` was disconnected because GitHub no longer accepts its personal access token, for example after it expired or was revoked. "+
+			"Create a new token on GitHub, then use `/github connect token` to connect again.", info.GitHubUsername)
+		p.CreateBotDMPost(info.UserID, message, "")
+		return
+	}
+	if siteURL := p.API.GetConfig().ServiceSettings.SiteURL; siteURL != nil && *siteURL != "" {
+		message += fmt.Sprintf(" [Click here to connect your GitHub account.](%s/plugins/%s/oauth/connect)", *siteURL, Manifest.Id)
+	}
+	p.CreateBotDMPost(info.UserID, message, "")
+}
+
+// runTokenHealthChecks checks the tokens of the next connected users not checked in the last day,
+// and disconnects the users whose token was revoked. It stops after a few checks, or as soon as
+// GitHub rate limits them, and resumes with the next run of the job.
+func (p *Plugin) runTokenHealthChecks(ctx context.Context, now time.Time, delay time.Duration) error {
+	state, err := p.getTokenHealthState()
+	if err != nil {
+		return err
+	}
+
+	if state.UserIDs == nil {
+		state.UserIDs, err = p.listConnectedUserIDs()
+		if err != nil {
+			return err
+		}
+		state.Offset = 0
+	}
+
+	checks := 0
+	for ; state.Offset < len(state.UserIDs); state.Offset++ {
+		userID := state.UserIDs[state.Offset]
+
+		check, err := p.getTokenCheck(userID)
+		if err != nil {
+			p.API.LogWarn("Failed to get token check", "userID", userID, "error", err.Error())
+		}
+		if check != nil && now.Sub(check.CheckedAt) < tokenHealthCheckInterval {
+			state.Current.add(check.Result)
+			continue
+		}
+
+		if checks == tokenHealthChecksPerRun {
+			return p.storeTokenHealthState(state)
+		}
+		if checks > 0 {
+			time.Sleep(delay)
+		}
+		checks++
+
+		info, apiErr := p.getGitHubUserInfo(userID)
+		if apiErr != nil {
+			p.API.LogWarn("Failed to get user info for token check", "userID", userID, "error", apiErr.Error())
+			continue
+		}
+
+		result, err := p.checkToken(ctx, info)
+		if err == errTokenCheckRateLimited {
+			p.API.LogDebug("Token health checks rate limited, resuming later", "userID", userID)
+			return p.storeTokenHealthState(state)
+		}
+		if err != nil {
+			p.API.LogWarn("Failed to check token", "userID", userID, "error", err.Error())
+			continue
+		}
+
+		state.Current.add(result)
+		if result == tokenRevoked {
+			p.handleRevokedToken(info)
+			continue
+		}
+
+		if err := p.storeTokenCheck(userID, &tokenCheck{CheckedAt: now, Result: result}); err != nil {
+			p.API.LogWarn("Failed to store token check", "userID", userID, "error", err.Error())
+		}
+	}
+
+	completed := state.Current
+	completed.CompletedAt = now
+	state.Last = &completed
+	state.Current = tokenHealthCounts{}
+	state.UserIDs = nil
+	state.Offset = 0
+
+	return p.storeTokenHealthState(state)
+}
+
+func (p *Plugin) checkTokenHealth() {
+	if err := p.runTokenHealthChecks(context.Background(), time.Now(), tokenHealthCheckDelay); err != nil {
+		p.API.LogWarn("Failed to run token health checks", "error", err.Error())
+	}
+}
+
+// adminStatus is the status of the plugin shown to system admins for debugging.
+type adminStatus struct {
+	TokenHealth *tokenHealthStatus `json:"token_health"`
+}
+
+// tokenHealthStatus counts the connections checked in the current pass of the token health checks,
+// and in the last complete one.
+type tokenHealthStatus struct {
+	Current tokenHealthCounts  `json:"current"`
+	Last    *tokenHealthCounts `json:"last"`
+}
+
+func (p *Plugin) getAdminStatus(w http.ResponseWriter, r *http.Request, userID string) {
+	if !p.isSystemAdmin(userID) {
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Only system administrators can get the status of the plugin.", StatusCode: http.StatusForbidden})
+		return
+	}
+
+	state, err := p.getTokenHealthState()
+	if err != nil {
+		p.API.LogWarn("Failed to get token health state", "error", err.Error())
+		p.writeAPIError(w, &APIErrorResponse{ID: "", Message: "Failed to get the status of the token health checks.", StatusCode: http.StatusInternalServerError})
+		return
+	}
+
+	p.writeJSON(w, &adminStatus{
+		TokenHealth: &tokenHealthStatus{Current: state.Current, Last: state.Last},
+	})
+}
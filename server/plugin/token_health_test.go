@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestRunTokenHealthChecks(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	now := time.Date(2021, 3, 5, 12, 0, 0, 0, time.UTC)
+
+	rateLimited := true
+	var checked []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v3/user", r.URL.Path)
+		token := r.Header.Get("Authorization")
+		checked = append(checked, token)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case token == "Bearer revoked":
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message": "Bad credentials"}`)
+		case token == "Bearer limited" && rateLimited:
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "API rate limit exceeded"}`)
+		default:
+			fmt.Fprint(w, `{"login": "someone"}`)
+		}
+	}))
+	defer ts.Close()
+
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(func(key string, value []byte) *model.AppError {
+		store[key] = value
+		return nil
+	})
+	api.On("KVSetWithExpiry", mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("int64")).Return(func(key string, value []byte, expiry int64) *model.AppError {
+		assert.Equal(t, int64(24*60*60), expiry)
+		store[key] = value
+		return nil
+	})
+	api.On("KVDelete", mock.AnythingOfType("string")).Return(func(key string) *model.AppError {
+		delete(store, key)
+		return nil
+	})
+	api.On("KVList", mock.AnythingOfType("int"), mock.AnythingOfType("int")).Return(func(page, perPage int) []string {
+		keys := []string{}
+		for key := range store {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if page*perPage >= len(keys) {
+			return []string{}
+		}
+		keys = keys[page*perPage:]
+		if len(keys) > perPage {
+			keys = keys[:perPage]
+		}
+		return keys
+	}, nil)
+
+	for userID, token := range map[string]string{"aliceID": "good", "bobID": "revoked", "carolID": "limited", "daveID": "good"} {
+		encrypted, err := encrypt([]byte(encryptionKey), token)
+		require.NoError(t, err)
+		info, err := json.Marshal(&GitHubUserInfo{UserID: userID, GitHubUsername: userID + "-gh", Token: &oauth2.Token{AccessToken: encrypted}, Settings: &UserSettings{}})
+		require.NoError(t, err)
+		store[userID+githubTokenKey] = info
+		store[userID+"-gh"+githubUsernameKey] = []byte(userID)
+	}
+	// Dave was checked a few hours ago
+	store["daveID"+tokenCheckKey] = []byte(`{"checked_at": "2021-03-05T08:00:00Z", "result": "healthy"}`)
+
+	api.On("GetUser", mock.AnythingOfType("string")).Return(func(userID string) *model.User {
+		return &model.User{Id: userID}
+	}, nil)
+	api.On("PublishWebSocketEvent", wsEventDisconnect, mock.Anything, mock.Anything)
+	api.On("LogInfo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything)
+	siteURL := "https://mattermost.example.com"
+	api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+	api.On("GetDirectChannel", mock.AnythingOfType("string"), "botID").Return(func(userID, botID string) *model.Channel {
+		return &model.Channel{Id: userID + "DM"}
+	}, nil)
+	dms := map[string][]string{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		dms[post.ChannelId] = append(dms[post.ChannelId], post.Message)
+		return post
+	}, nil)
+	p.SetAPI(api)
+
+	getState := func() *tokenHealthState {
+		state, err := p.getTokenHealthState()
+		require.NoError(t, err)
+		return state
+	}
+
+	// The checks stop when rate limited
+	require.NoError(t, p.runTokenHealthChecks(context.Background(), now, 0))
+	assert.Equal(t, []string{"Bearer good", "Bearer revoked", "Bearer limited"}, checked)
+	assert.Equal(t, tokenHealthCounts{Healthy: 1, Revoked: 1}, getState().Current)
+	assert.Nil(t, getState().Last)
+	// The users of the pass were listed before Bob was disconnected
+	assert.Equal(t, []string{"aliceID", "bobID", "carolID", "daveID"}, getState().UserIDs)
+	assert.Equal(t, 2, getState().Offset)
+
+	check, err := p.getTokenCheck("aliceID")
+	require.NoError(t, err)
+	assert.Equal(t, &tokenCheck{CheckedAt: now, Result: tokenHealthy}, check)
+	assert.Nil(t, store["carolID"+tokenCheckKey])
+
+	// Bob was disconnected and asked to connect again
+	assert.Nil(t, store["bobID"+githubTokenKey])
+	assert.Nil(t, store["bobID-gh"+githubUsernameKey])
+	require.Len(t, dms["bobIDDM"], 1)
+	assert.Contains(t, dms["bobIDDM"][0], "Your GitHub account `bobID-gh` was disconnected")
+	assert.Contains(t, dms["bobIDDM"][0], "(https://mattermost.example.com/plugins/github/oauth/connect)")
+	assert.Len(t, dms, 1)
+
+	// The next run resumes with Carol, and counts Dave without checking him again
+	rateLimited = false
+	checked = nil
+	require.NoError(t, p.runTokenHealthChecks(context.Background(), now.Add(5*time.Minute), 0))
+	assert.Equal(t, []string{"Bearer limited"}, checked)
+	state := getState()
+	assert.Equal(t, tokenHealthCounts{}, state.Current)
+	assert.Equal(t, &tokenHealthCounts{Healthy: 3, Revoked: 1, CompletedAt: now.Add(5 * time.Minute)}, state.Last)
+	assert.Nil(t, state.UserIDs)
+	api.AssertNumberOfCalls(t, "KVList", 1)
+
+	// Users are checked at most once a day
+	checked = nil
+	require.NoError(t, p.runTokenHealthChecks(context.Background(), now.Add(10*time.Minute), 0))
+	assert.Empty(t, checked)
+	assert.Equal(t, &tokenHealthCounts{Healthy: 3, CompletedAt: now.Add(10 * time.Minute)}, getState().Last)
+
+	require.NoError(t, p.runTokenHealthChecks(context.Background(), now.Add(25*time.Hour), 0))
+	assert.Equal(t, []string{"Bearer good", "Bearer limited", "Bearer good"}, checked)
+
+	t.Run("admin status", func(t *testing.T) {
+		api.On("HasPermissionTo", "adminID", model.PERMISSION_MANAGE_SYSTEM).Return(true)
+		api.On("HasPermissionTo", "userID", model.PERMISSION_MANAGE_SYSTEM).Return(false)
+
+		w := httptest.NewRecorder()
+		p.getAdminStatus(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/status", nil), "adminID")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var status adminStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		assert.Equal(t, &tokenHealthCounts{Healthy: 3, CompletedAt: now.Add(25 * time.Hour)}, status.TokenHealth.Last)
+
+		w = httptest.NewRecorder()
+		p.getAdminStatus(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/status", nil), "userID")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
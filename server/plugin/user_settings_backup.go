@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const userSettingsBackupKey = "_githubsettingsbackup"
+
+// userSettingsBackup is what is kept of the connection of a user once they disconnect, so that it can
+// be restored when they connect again.
+type userSettingsBackup struct {
+	Settings *UserSettings `json:"settings"`
+	// LastToDoPostAt is when the last daily reminder was posted, so that reconnecting doesn't post another one the same day.
+	LastToDoPostAt int64 `json:"last_todo_post_at"`
+}
+
+// backupUserSettings keeps the settings of a user who is disconnecting.
+func (p *Plugin) backupUserSettings(info *GitHubUserInfo) error {
+	if info.Settings == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(&userSettingsBackup{
+		Settings:       info.Settings,
+		LastToDoPostAt: info.LastToDoPostAt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not marshal user settings backup")
+	}
+
+	if appErr := p.API.KVSet(info.UserID+userSettingsBackupKey, value); appErr != nil {
+		return errors.Wrap(appErr, "could not store user settings backup in KV store")
+	}
+
+	return nil
+}
+
+// getPreviousUserSettings returns the settings of a previous connection of a user, or nil if there
+// is none. The stored user info is used if still there, otherwise the backup made at disconnect time.
+// The stored token isn't decrypted, as it's about to be replaced.
+func (p *Plugin) getPreviousUserSettings(userID string) (*userSettingsBackup, error) {
+	value, appErr := p.API.KVGet(userID + githubTokenKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get user info from KV store")
+	}
+	if value != nil {
+		var info GitHubUserInfo
+		if err := json.Unmarshal(value, &info); err != nil {
+			return nil, errors.Wrap(err, "could not unmarshal user info")
+		}
+		if info.Settings != nil {
+			return &userSettingsBackup{Settings: info.Settings, LastToDoPostAt: info.LastToDoPostAt}, nil
+		}
+	}
+
+	value, appErr = p.API.KVGet(userID + userSettingsBackupKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get user settings backup from KV store")
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	var backup userSettingsBackup
+	if err := json.Unmarshal(value, &backup); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal user settings backup")
+	}
+	if backup.Settings == nil {
+		return nil, nil
+	}
+
+	return &backup, nil
+}
+
+// restoreUserSettings applies the settings of a previous connection to the user info of a new one.
+// It returns true if settings were restored.
+func (p *Plugin) restoreUserSettings(info *GitHubUserInfo) bool {
+	previous, err := p.getPreviousUserSettings(info.UserID)
+	if err != nil {
+		p.API.LogWarn("Failed to get previous user settings", "userID", info.UserID, "error", err.Error())
+		return false
+	}
+	if previous == nil {
+		return false
+	}
+
+	info.Settings = previous.Settings
+	if previous.LastToDoPostAt != 0 {
+		info.LastToDoPostAt = previous.LastToDoPostAt
+	}
+
+	return true
+}
+
+// deleteUserSettingsBackup deletes the backup of the settings of a user once they are connected again.
+func (p *Plugin) deleteUserSettingsBackup(userID string) {
+	if appErr := p.API.KVDelete(userID + userSettingsBackupKey); appErr != nil {
+		p.API.LogWarn("Failed to delete user settings backup", "userID", userID, "error", appErr.Error())
+	}
+}
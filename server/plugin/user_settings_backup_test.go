@@ -0,0 +1,207 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestBackupUserSettings(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("KVSet", "userID"+userSettingsBackupKey, mock.MatchedBy(func(value []byte) bool {
+		return string(value) == `{"settings":{"sidebar_buttons":"left","daily_reminder":false,"notifications":true,"weekly_summary":false},"last_todo_post_at":42}`
+	})).Return(nil).Once()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	err := p.backupUserSettings(&GitHubUserInfo{
+		UserID:         "userID",
+		LastToDoPostAt: 42,
+		Settings:       &UserSettings{SidebarButtons: "left", Notifications: true},
+	})
+	require.NoError(t, err)
+}
+
+func TestGetPreviousUserSettings(t *testing.T) {
+	settings := &UserSettings{SidebarButtons: "left", Notifications: true}
+
+	t.Run("fresh connect", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(nil, nil)
+		api.On("KVGet", "userID"+userSettingsBackupKey).Return(nil, nil)
+		p.SetAPI(api)
+
+		previous, err := p.getPreviousUserSettings("userID")
+		require.NoError(t, err)
+		assert.Nil(t, previous)
+	})
+
+	t.Run("remaining user info", func(t *testing.T) {
+		info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", LastToDoPostAt: 42, Settings: settings})
+		require.NoError(t, err)
+
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		p.SetAPI(api)
+
+		previous, err := p.getPreviousUserSettings("userID")
+		require.NoError(t, err)
+		assert.Equal(t, &userSettingsBackup{Settings: settings, LastToDoPostAt: 42}, previous)
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		backup, err := json.Marshal(&userSettingsBackup{Settings: settings, LastToDoPostAt: 42})
+		require.NoError(t, err)
+
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(nil, nil)
+		api.On("KVGet", "userID"+userSettingsBackupKey).Return(backup, nil)
+		p.SetAPI(api)
+
+		previous, err := p.getPreviousUserSettings("userID")
+		require.NoError(t, err)
+		assert.Equal(t, &userSettingsBackup{Settings: settings, LastToDoPostAt: 42}, previous)
+	})
+}
+
+func TestCompleteConnectUserToGitHubSettings(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login/oauth/access_token":
+			fmt.Fprint(w, `{"access_token": "token", "token_type": "bearer"}`)
+		case "/api/v3/user":
+			fmt.Fprint(w, `{"login": "alice", "html_url": "https://github.com/alice"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	state, err := json.Marshal(&OAuthState{UserID: "userID", Token: "stateToken"})
+	require.NoError(t, err)
+
+	setup := func(backup []byte) (*Plugin, *plugintest.API, *GitHubUserInfo, *string) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{
+			EncryptionKey:       encryptionKey,
+			EnterpriseBaseURL:   ts.URL,
+			EnterpriseUploadURL: ts.URL,
+		})
+
+		stored := &GitHubUserInfo{}
+		welcome := new(string)
+
+		api := &plugintest.API{}
+		api.On("KVGet", "stateToken").Return(state, nil)
+		api.On("KVDelete", "stateToken").Return(nil)
+		api.On("KVCompareAndDelete", "userID"+connectAttemptKey, []byte("stateToken")).Return(true, nil)
+		api.On("KVGet", "userID"+githubTokenKey).Return(nil, nil)
+		api.On("KVGet", "userID"+userSettingsBackupKey).Return(backup, nil)
+		api.On("KVGet", "userID"+linkedUsernameKey).Return(nil, nil)
+		api.On("KVSet", "userID"+githubTokenKey, mock.Anything).Return(func(key string, value []byte) *model.AppError {
+			require.NoError(t, json.Unmarshal(value, stored))
+			return nil
+		}).Once()
+		api.On("KVSet", "alice"+githubUsernameKey, []byte("userID")).Return(nil)
+		for _, section := range sidebarSections {
+			api.On("KVDelete", sidebarCacheKeyFor("userID", section)).Return(nil).Once()
+		}
+		api.On("GetDirectChannel", "userID", "botID").Return(&model.Channel{Id: "dmChannelID"}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			if post.Type == "custom_git_welcome" {
+				*welcome = post.Message
+			}
+			return post
+		}, nil)
+		api.On("PublishWebSocketEvent", wsEventConnect, mock.Anything, mock.Anything).Return()
+		p.SetAPI(api)
+
+		return p, api, stored, welcome
+	}
+
+	request := func() *http.Request {
+		return httptest.NewRequest(http.MethodGet, "/oauth/complete?code=code&state=stateToken", nil)
+	}
+
+	t.Run("fresh connect", func(t *testing.T) {
+		p, api, stored, welcome := setup(nil)
+		defer api.AssertExpectations(t)
+
+		p.completeConnectUserToGitHub(httptest.NewRecorder(), request(), "userID")
+
+		assert.Equal(t, &UserSettings{SidebarButtons: settingButtonsTeam, DailyReminder: true, Notifications: true}, stored.Settings)
+		assert.NotZero(t, stored.LastToDoPostAt)
+		assert.NotContains(t, *welcome, "have been restored")
+		api.AssertNotCalled(t, "KVDelete", "userID"+userSettingsBackupKey)
+	})
+
+	t.Run("reconnect with backup", func(t *testing.T) {
+		settings := &UserSettings{SidebarButtons: "left", DailyReminder: false, Notifications: false}
+		backup, err := json.Marshal(&userSettingsBackup{Settings: settings, LastToDoPostAt: 42})
+		require.NoError(t, err)
+
+		p, api, stored, welcome := setup(backup)
+		api.On("KVDelete", "userID"+userSettingsBackupKey).Return(nil).Once()
+		defer api.AssertExpectations(t)
+
+		p.completeConnectUserToGitHub(httptest.NewRecorder(), request(), "userID")
+
+		assert.Equal(t, settings, stored.Settings)
+		assert.Equal(t, int64(42), stored.LastToDoPostAt)
+		assert.Equal(t, "alice", stored.GitHubUsername)
+		assert.True(t, strings.Contains(*welcome, "settings from your previous connection have been restored"))
+	})
+}
+
+func TestDisconnectGitHubAccountBacksUpSettings(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+
+	info, err := json.Marshal(&GitHubUserInfo{
+		UserID:         "userID",
+		Token:          &oauth2.Token{AccessToken: token},
+		GitHubUsername: "alice",
+		LastToDoPostAt: 42,
+		Settings:       &UserSettings{SidebarButtons: "left"},
+	})
+	require.NoError(t, err)
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey})
+	api := &plugintest.API{}
+	api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+	api.On("KVGet", SubscriptionsKey).Return(nil, nil)
+	api.On("KVSet", "userID"+userSettingsBackupKey, mock.MatchedBy(func(value []byte) bool {
+		return string(value) == `{"settings":{"sidebar_buttons":"left","daily_reminder":false,"notifications":false,"weekly_summary":false},"last_todo_post_at":42}`
+	})).Return(nil).Once()
+	api.On("KVDelete", "userID"+githubTokenKey).Return(nil)
+	api.On("KVDelete", "alice"+githubUsernameKey).Return(nil)
+	for _, section := range sidebarSections {
+		api.On("KVDelete", sidebarCacheKeyFor("userID", section)).Return(nil).Once()
+	}
+	api.On("GetUser", "userID").Return(&model.User{Props: model.StringMap{}}, nil)
+	api.On("PublishWebSocketEvent", wsEventDisconnect, mock.Anything, mock.Anything).Return()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	p.disconnectGitHubAccount("userID")
+}
@@ -0,0 +1,118 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const usernameSyncPageSize = 100
+
+// connectCallToAction is appended to the notifications of users whose GitHub username was mapped
+// by syncing usernames, but who didn't connect their account.
+const connectCallToAction = "\n\n---\n_You received this notification because your GitHub account was matched to your Mattermost account by email. Run `/github connect` to get your todo list, sidebar and more._"
+
+// usernameSyncResult is the outcome of matching the members of an organization to Mattermost users.
+type usernameSyncResult struct {
+	Members int `json:"members"`
+	// Mapped are the GitHub usernames newly mapped to a Mattermost user.
+	Mapped []string `json:"mapped"`
+	// Skipped is the number of members already mapped, e.g. by connecting their account.
+	Skipped int `json:"skipped"`
+	// Unmatched is the number of members without a public email matching a Mattermost user.
+	Unmatched int `json:"unmatched"`
+}
+
+// isConnected checks if a user connected their GitHub account, as opposed to having their username mapped.
+func (p *Plugin) isConnected(userID string) bool {
+	value, appErr := p.API.KVGet(userID + githubTokenKey)
+	return appErr == nil && value != nil
+}
+
+// withConnectCallToAction invites users who didn't connect their GitHub account to do so in the
+// notifications they receive.
+func (p *Plugin) withConnectCallToAction(userID, message string) string {
+	if p.isConnected(userID) {
+		return message
+	}
+
+	return message + connectCallToAction
+}
+
+// syncUsernameMappings maps the members of an organization to the Mattermost users having their
+// public email, so they get notified without connecting their account. No token is stored, and
+// existing mappings, such as those of connected users, are never overwritten.
+func (p *Plugin) syncUsernameMappings(ctx context.Context, githubClient *github.Client, org string) (*usernameSyncResult, error) {
+	result := &usernameSyncResult{Mapped: []string{}}
+
+	opts := &github.ListMembersOptions{ListOptions: github.ListOptions{PerPage: usernameSyncPageSize}}
+	for {
+		members, resp, err := githubClient.Organizations.ListMembers(ctx, org, opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list organization members")
+		}
+
+		for _, member := range members {
+			result.Members++
+			login := member.GetLogin()
+
+			if p.getGitHubToUserIDMapping(login) != "" {
+				result.Skipped++
+				continue
+			}
+
+			// Emails aren't part of the list of members
+			user, _, err := githubClient.Users.Get(ctx, login)
+			if err != nil {
+				p.API.LogWarn("Failed to get GitHub user", "login", login, "error", err.Error())
+				result.Unmatched++
+				continue
+			}
+
+			if user.GetEmail() == "" {
+				result.Unmatched++
+				continue
+			}
+
+			mmUser, appErr := p.API.GetUserByEmail(user.GetEmail())
+			if appErr != nil {
+				result.Unmatched++
+				continue
+			}
+
+			// Connected users are mapped to the account they connected
+			if p.isConnected(mmUser.Id) {
+				result.Skipped++
+				continue
+			}
+
+			if err := p.storeGitHubToUserIDMapping(login, mmUser.Id); err != nil {
+				return nil, err
+			}
+			result.Mapped = append(result.Mapped, login)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	sort.Strings(result.Mapped)
+
+	return result, nil
+}
+
+func formatUsernameSyncResult(org string, result *usernameSyncResult) string {
+	message := fmt.Sprintf("Synced the usernames of the %d members of %s: %d mapped, %d already mapped, %d without a matching public email.",
+		result.Members, org, len(result.Mapped), result.Skipped, result.Unmatched)
+
+	if len(result.Mapped) > 0 {
+		message += "\nNewly mapped: " + formatGitHubUsernames(result.Mapped)
+	}
+
+	return message
+}
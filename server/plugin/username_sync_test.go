@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncUsernameMappings(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/org/members", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"login": "connected"}, {"login": "alice"}, {"login": "noemail"}, {"login": "unknown"}, {"login": "bob"}]`)
+	})
+	for login, email := range map[string]string{
+		"alice":   "alice@example.com",
+		"noemail": "",
+		"unknown": "unknown@example.com",
+		"bob":     "bob@example.com",
+	} {
+		login, email := login, email
+		mux.HandleFunc("/users/"+login, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"login": %q, "email": %q}`, login, email)
+		})
+	}
+	githubClient := newTestGitHubClient(t, mux)
+
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("KVGet", "connected"+githubUsernameKey).Return([]byte("connectedID"), nil)
+	for _, login := range []string{"alice", "noemail", "unknown", "bob"} {
+		api.On("KVGet", login+githubUsernameKey).Return(nil, nil)
+	}
+	api.On("GetUserByEmail", "alice@example.com").Return(&model.User{Id: "aliceID"}, nil)
+	api.On("GetUserByEmail", "unknown@example.com").Return(nil, &model.AppError{Message: "not found"})
+	api.On("GetUserByEmail", "bob@example.com").Return(&model.User{Id: "bobID"}, nil)
+	api.On("KVGet", "aliceID"+githubTokenKey).Return(nil, nil)
+	// bob connected their account with another GitHub username
+	api.On("KVGet", "bobID"+githubTokenKey).Return([]byte("{}"), nil)
+	api.On("KVSet", "alice"+githubUsernameKey, []byte("aliceID")).Return(nil).Once()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	result, err := p.syncUsernameMappings(context.Background(), githubClient, "org")
+	require.NoError(t, err)
+	assert.Equal(t, &usernameSyncResult{Members: 5, Mapped: []string{"alice"}, Skipped: 2, Unmatched: 2}, result)
+}
+
+func TestWithConnectCallToAction(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("KVGet", "connectedID"+githubTokenKey).Return([]byte("{}"), nil)
+	api.On("KVGet", "mappedID"+githubTokenKey).Return(nil, nil)
+	p.SetAPI(api)
+
+	assert.Equal(t, "message", p.withConnectCallToAction("connectedID", "message"))
+	assert.Equal(t, "message"+connectCallToAction, p.withConnectCallToAction("mappedID", "message"))
+}
@@ -6,7 +6,9 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"path"
@@ -33,8 +35,24 @@ func getYourAssigneeSearchQuery(username, org string) string {
 	return buildSearchQuery("is:open assignee:%v archived:false %v", username, org)
 }
 
-func getIssuesSearchQuery(org, searchTerm string) string {
+// getTeamReviewSearchQuery builds the query searching for the pull requests whose review was only
+// requested from a team of the user, and not from them directly.
+func getTeamReviewSearchQuery(username, org string) string {
+	return buildSearchQuery("is:pr is:open review-requested:%[1]v -user-review-requested:%[1]v archived:false %[2]v", username, org)
+}
+
+// labelSearchQualifier returns the search qualifier matching the issues and pull requests with a label.
+func labelSearchQualifier(label string) string {
+	// Search queries can't escape quotes, and label names rarely have any
+	return fmt.Sprintf(`label:"%s"`, strings.ReplaceAll(label, `"`, ""))
+}
+
+// getIssuesSearchQuery builds the query searching for open issues, and optionally pull requests.
+func getIssuesSearchQuery(org, searchTerm string, includePullRequests bool) string {
 	query := "is:open is:issue archived:false %v %v"
+	if includePullRequests {
+		query = "is:open archived:false %v %v"
+	}
 	orgField := ""
 	if len(org) != 0 {
 		orgField = fmt.Sprintf("org:%v", org)
@@ -43,6 +61,15 @@ func getIssuesSearchQuery(org, searchTerm string) string {
 	return fmt.Sprintf(query, orgField, searchTerm)
 }
 
+// getSavedSearchQuery scopes the query of a saved search to the organization, if any.
+func getSavedSearchQuery(query, org string) string {
+	if len(org) == 0 {
+		return query
+	}
+
+	return fmt.Sprintf("%v org:%v", query, org)
+}
+
 func buildSearchQuery(query, username, org string) string {
 	orgField := ""
 	if len(org) != 0 {
@@ -160,20 +187,51 @@ func parseGitHubUsernamesFromText(text string) []string {
 	return usernames
 }
 
-func fixGithubNotificationSubjectURL(url, issueNum string) string {
-	url = strings.Replace(url, "api.", "", 1)
-	url = strings.Replace(url, "repos/", "", 1)
-	url = strings.Replace(url, "/pulls/", "/pull/", 1)
-	url = strings.Replace(url, "/commits/", "/commit/", 1)
-	url = strings.Replace(url, "/api/v3", "", 1)
-	url = strings.Replace(url, "comments/", issueNum+"#issuecomment-", 1)
-	return url
+// fixGithubNotificationSubjectURL converts the API URL of the subject of a notification to its URL
+// on the web interface under baseURL. Comments are linked on their issue or pull request, given by
+// issueNum. URLs that aren't API URLs are returned as is.
+func fixGithubNotificationSubjectURL(baseURL, apiURL, issueNum string) string {
+	apiPath, ok := trimAPIBaseURL(apiURL, baseURL)
+	if !ok {
+		return apiURL
+	}
+
+	parts := strings.Split(strings.TrimPrefix(apiPath, "repos/"), "/")
+	if len(parts) >= 4 {
+		switch {
+		case parts[2] == "issues" && parts[3] == "comments" && len(parts) == 5:
+			parts = append(parts[:3], issueNum+"#issuecomment-"+parts[4])
+		case parts[2] == "pulls" && parts[3] == "comments" && len(parts) == 5:
+			parts = append(parts[:2], "pull", issueNum+"#discussion_r"+parts[4])
+		case parts[2] == "pulls":
+			parts[2] = "pull"
+		case parts[2] == "commits":
+			parts[2] = "commit"
+		case parts[2] == "dependabot" && parts[3] == "alerts":
+			parts = append(parts[:2], append([]string{"security", "dependabot"}, parts[4:]...)...)
+		}
+	}
+
+	return getWebBaseURL(baseURL) + strings.Join(parts, "/")
 }
 
 func fullNameFromOwnerAndRepo(owner, repo string) string {
 	return fmt.Sprintf("%s/%s", owner, repo)
 }
 
+// isRepoInOrg checks if a repository, given by its full name, belongs to an organization. GitHub
+// logins are case-insensitive.
+func isRepoInOrg(fullName, org string) bool {
+	owner := strings.Split(fullName, "/")[0]
+	return strings.EqualFold(owner, strings.TrimSpace(org))
+}
+
+// hashKey builds a KV store key of bounded length out of arbitrary parts, ending with the given suffix.
+func hashKey(suffix string, parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "/")))
+	return hex.EncodeToString(sum[:16]) + suffix
+}
+
 func isFlag(text string) bool {
 	return strings.HasPrefix(text, "--")
 }
@@ -296,8 +354,14 @@ func isInsideLink(msg string, index int) bool {
 
 // getCodeMarkdown returns the constructed markdown for a permalink.
 func getCodeMarkdown(user, repo, repoPath, word, lines string, isTruncated bool) string {
-	final := fmt.Sprintf("\n[%s/%s/%s](%s)\n", user, repo, repoPath, word)
-	ext := path.Ext(repoPath)
+	return getCodeBlockMarkdown(fmt.Sprintf("%s/%s/%s", user, repo, repoPath), repoPath, word, lines, isTruncated)
+}
+
+// getCodeBlockMarkdown returns a link titled title to word followed by a code block of lines,
+// highlighted according to the extension of the file.
+func getCodeBlockMarkdown(title, filePath, word, lines string, isTruncated bool) string {
+	final := fmt.Sprintf("\n[%s](%s)\n", title, word)
+	ext := path.Ext(filePath)
 	// remove the preceding dot
 	if len(ext) > 1 {
 		ext = strings.TrimPrefix(ext, ".")
@@ -40,17 +40,18 @@ func TestFixGithubNotificationSubjectURL(t *testing.T) {
 		Text     string
 		Expected string
 		IssueNum string
+		BaseURL  string
 	}{
 		{Text: "https://api.github.com/repos/jwilander/mattermost-webapp/issues/123", Expected: "https://github.com/jwilander/mattermost-webapp/issues/123"},
 		{Text: "https://api.github.com/repos/jwilander/mattermost-webapp/pulls/123", Expected: "https://github.com/jwilander/mattermost-webapp/pull/123"},
-		{Text: "https://enterprise.github.com/api/v3/jwilander/mattermost-webapp/issues/123", Expected: "https://enterprise.github.com/jwilander/mattermost-webapp/issues/123"},
-		{Text: "https://enterprise.github.com/api/v3/jwilander/mattermost-webapp/pull/123", Expected: "https://enterprise.github.com/jwilander/mattermost-webapp/pull/123"},
+		{Text: "https://enterprise.github.com/api/v3/jwilander/mattermost-webapp/issues/123", Expected: "https://enterprise.github.com/jwilander/mattermost-webapp/issues/123", BaseURL: "https://enterprise.github.com/"},
+		{Text: "https://enterprise.github.com/api/v3/jwilander/mattermost-webapp/pull/123", Expected: "https://enterprise.github.com/jwilander/mattermost-webapp/pull/123", BaseURL: "https://enterprise.github.com/"},
 		{Text: "https://api.github.com/repos/mattermost/mattermost-server/commits/cc6c385d3e8903546fc6fc856bf468ad09b70913", Expected: "https://github.com/mattermost/mattermost-server/commit/cc6c385d3e8903546fc6fc856bf468ad09b70913"},
 		{Text: "https://api.github.com/repos/user/rate_my_cakes/issues/comments/655139214", Expected: "https://github.com/user/rate_my_cakes/issues/4#issuecomment-655139214", IssueNum: "4"},
 	}
 
 	for _, tc := range tcs {
-		assert.Equal(t, tc.Expected, fixGithubNotificationSubjectURL(tc.Text, tc.IssueNum))
+		assert.Equal(t, tc.Expected, fixGithubNotificationSubjectURL(getWebBaseURL(tc.BaseURL), tc.Text, tc.IssueNum))
 	}
 }
 
@@ -132,6 +133,24 @@ func TestContainsValue(t *testing.T) {
 	}
 }
 
+func TestIsRepoInOrg(t *testing.T) {
+	tcs := []struct {
+		FullName string
+		Org      string
+		Expected bool
+	}{
+		{FullName: "mattermost/mattermost-server", Org: "mattermost", Expected: true},
+		{FullName: "Mattermost/mattermost-server", Org: "mattermost", Expected: true},
+		{FullName: "mattermost/mattermost-server", Org: " MatterMost ", Expected: true},
+		{FullName: "someone/mattermost-server", Org: "mattermost", Expected: false},
+		{FullName: "mattermost-fork/mattermost-server", Org: "mattermost", Expected: false},
+	}
+
+	for _, tc := range tcs {
+		assert.Equal(t, tc.Expected, isRepoInOrg(tc.FullName, tc.Org), tc.FullName)
+	}
+}
+
 func TestGetLineNumbers(t *testing.T) {
 	tcs := []struct {
 		input      string
@@ -254,3 +273,15 @@ func TestGetToDoDisplayText(t *testing.T) {
 		})
 	}
 }
+
+func TestGetIssuesSearchQuery(t *testing.T) {
+	assert.Equal(t, "is:open is:issue archived:false org:mattermost bug", getIssuesSearchQuery("mattermost", "bug", false))
+	assert.Equal(t, "is:open archived:false org:mattermost bug", getIssuesSearchQuery("mattermost", "bug", true))
+	assert.Equal(t, "is:open is:issue archived:false  bug", getIssuesSearchQuery("", "bug", false))
+}
+
+func TestLabelSearchQualifier(t *testing.T) {
+	assert.Equal(t, `label:"needs-qa"`, labelSearchQualifier("needs-qa"))
+	assert.Equal(t, `label:"good first issue"`, labelSearchQualifier("good first issue"))
+	assert.Equal(t, `label:"won't fix"`, labelSearchQualifier(`won't "fix"`))
+}
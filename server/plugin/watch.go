@@ -0,0 +1,197 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+// The watch states of a repository, as named in GitHub.
+const (
+	watchStateAll           = "all"
+	watchStateParticipating = "participating"
+	watchStateIgnore        = "ignore"
+)
+
+const (
+	// watchedListLength is the number of watched repositories listed by /github watch list.
+	watchedListLength = 30
+	// maxWatchedPages caps the pages of watched repositories fetched to count them.
+	maxWatchedPages = 10
+	watchedPageSize = 100
+)
+
+var watchStates = []string{watchStateAll, watchStateParticipating, watchStateIgnore}
+
+// listWatchedRepositories returns the repositories the user watches, at most maxWatchedPages pages of
+// them. It returns true if there are more.
+func listWatchedRepositories(ctx context.Context, githubClient *github.Client) ([]*github.Repository, bool, error) {
+	repositories := []*github.Repository{}
+	opts := &github.ListOptions{PerPage: watchedPageSize}
+	for page := 0; page < maxWatchedPages; page++ {
+		watched, resp, err := githubClient.Activity.ListWatched(ctx, "", opts)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "could not list watched repositories")
+		}
+		repositories = append(repositories, watched...)
+
+		if resp.NextPage == 0 {
+			return repositories, false, nil
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return repositories, true, nil
+}
+
+// getWatchState returns how the user watches a repository. Users who don't watch a repository are
+// notified when they participate, which is the participating state.
+func getWatchState(ctx context.Context, githubClient *github.Client, owner, repo string) (string, error) {
+	subscription, _, err := githubClient.Activity.GetRepositorySubscription(ctx, owner, repo)
+	if err != nil {
+		return "", errors.Wrap(err, "could not get repository subscription")
+	}
+
+	switch {
+	case subscription == nil:
+		return watchStateParticipating, nil
+	case subscription.GetIgnored():
+		return watchStateIgnore, nil
+	default:
+		return watchStateAll, nil
+	}
+}
+
+// setWatchState changes how the user watches a repository.
+func setWatchState(ctx context.Context, githubClient *github.Client, owner, repo, state string) error {
+	var err error
+	switch state {
+	case watchStateAll:
+		_, _, err = githubClient.Activity.SetRepositorySubscription(ctx, owner, repo, &github.Subscription{Subscribed: github.Bool(true)})
+	case watchStateIgnore:
+		_, _, err = githubClient.Activity.SetRepositorySubscription(ctx, owner, repo, &github.Subscription{Ignored: github.Bool(true)})
+	case watchStateParticipating:
+		// Not watching a repository is how GitHub notifies of participation only
+		_, err = githubClient.Activity.DeleteRepositorySubscription(ctx, owner, repo)
+	default:
+		return errors.Errorf("unknown watch state %q", state)
+	}
+	if err != nil {
+		return errors.Wrap(err, "could not set repository subscription")
+	}
+
+	return nil
+}
+
+// describeWatchState tells what GitHub notifies the user of for a watch state of a repository.
+func describeWatchState(fullName, state string) string {
+	switch state {
+	case watchStateAll:
+		return fmt.Sprintf("You're watching %s. GitHub notifies you of all its activity.", fullName)
+	case watchStateIgnore:
+		return fmt.Sprintf("You're ignoring %s. GitHub doesn't notify you of its activity, even when you're mentioned. "+
+			"This only mutes the notifications of GitHub, channels subscribed to %s still get its events.", fullName, fullName)
+	default:
+		return fmt.Sprintf("You're not watching %s. GitHub notifies you only when you participate or are mentioned.", fullName)
+	}
+}
+
+func formatWatchedRepositories(repositories []*github.Repository, more bool) string {
+	if len(repositories) == 0 {
+		return "You don't watch any repository."
+	}
+
+	count := fmt.Sprintf("%d", len(repositories))
+	if more {
+		count = "more than " + count
+	}
+
+	message := fmt.Sprintf("You watch %s %s", count, pluralize(len(repositories), "repository", "repositories"))
+	if len(repositories) > watchedListLength {
+		message += fmt.Sprintf(", here are the first %d", watchedListLength)
+		repositories = repositories[:watchedListLength]
+	}
+	message += ":\n"
+
+	for _, repository := range repositories {
+		message += fmt.Sprintf("* [%s](%s)\n", repository.GetFullName(), repository.GetHTMLURL())
+	}
+
+	return message
+}
+
+func (p *Plugin) handleWatch(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	usage := "Please use `/github watch list` or `/github watch [owner/repo] [all|participating|ignore]`."
+	ctx := context.Background()
+
+	if len(parameters) == 1 && parameters[0] == "list" {
+		repositories, more, err := listWatchedRepositories(ctx, p.getGithubClient(userInfo))
+		if err != nil {
+			p.API.LogWarn("Failed to list watched repositories", "userID", userInfo.UserID, "error", err.Error())
+			return newAPIError(err, "Encountered an error listing the repositories you watch.").Message
+		}
+
+		return formatWatchedRepositories(repositories, more)
+	}
+
+	// The repository can be left out in channels subscribed to a single one
+	repository := ""
+	if len(parameters) > 0 && !SliceContainsString(watchStates, parameters[0]) {
+		repository = parameters[0]
+		parameters = parameters[1:]
+	} else {
+		repository = p.getChannelDefaultRepo(args.ChannelId)
+		if repository == "" {
+			return usage
+		}
+	}
+	if len(parameters) > 1 {
+		return usage
+	}
+
+	owner, repo, err := parseRepo(repository)
+	if err != nil {
+		return usage
+	}
+	if err := p.checkOrg(owner); err != nil {
+		return fmt.Sprintf("Can't watch %s: %s.", repository, err.Error())
+	}
+
+	githubClient := p.getGithubClient(userInfo)
+	ghRepo, _, err := githubClient.Repositories.Get(ctx, owner, repo)
+	if err != nil {
+		p.API.LogDebug("Failed to get repository", "repo", repository, "error", err.Error())
+		return newAPIError(err, fmt.Sprintf("Encountered an error getting the repository %s.", repository)).Message
+	}
+	fullName := ghRepo.GetFullName()
+
+	if len(parameters) == 0 {
+		state, err := getWatchState(ctx, githubClient, owner, repo)
+		if err != nil {
+			p.API.LogWarn("Failed to get watch state", "repo", fullName, "error", err.Error())
+			return newAPIError(err, fmt.Sprintf("Encountered an error getting how you watch %s.", fullName)).Message
+		}
+
+		return describeWatchState(fullName, state)
+	}
+
+	state := parameters[0]
+	if !SliceContainsString(watchStates, state) {
+		return fmt.Sprintf("Invalid watch state %q. Use `all`, `participating` or `ignore`.", state)
+	}
+
+	githubClient, err = p.getGithubClientFor(userInfo, githubWrite)
+	if err != nil {
+		return readOnlyModeMessage
+	}
+	if err := setWatchState(ctx, githubClient, owner, repo, state); err != nil {
+		p.API.LogWarn("Failed to set watch state", "repo", fullName, "state", state, "error", err.Error())
+		return newAPIError(err, fmt.Sprintf("Encountered an error changing how you watch %s.", fullName)).Message
+	}
+
+	return describeWatchState(fullName, state)
+}
@@ -0,0 +1,143 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestFormatWatchedRepositories(t *testing.T) {
+	assert.Equal(t, "You don't watch any repository.", formatWatchedRepositories(nil, false))
+
+	repositories := []*github.Repository{}
+	for i := 1; i <= 31; i++ {
+		fullName := fmt.Sprintf("owner/repo%d", i)
+		repositories = append(repositories, &github.Repository{FullName: github.String(fullName), HTMLURL: github.String("https://github.com/" + fullName)})
+	}
+
+	assert.Equal(t, "You watch 1 repository:\n* [owner/repo1](https://github.com/owner/repo1)\n", formatWatchedRepositories(repositories[:1], false))
+
+	message := formatWatchedRepositories(repositories, false)
+	assert.True(t, strings.HasPrefix(message, "You watch 31 repositories, here are the first 30:\n"))
+	assert.Contains(t, message, "* [owner/repo30](https://github.com/owner/repo30)\n")
+	assert.NotContains(t, message, "owner/repo31")
+
+	assert.True(t, strings.HasPrefix(formatWatchedRepositories(repositories, true), "You watch more than 31 repositories"))
+}
+
+func TestHandleWatch(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+
+	subscription := ""
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v3/user/subscriptions":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `<`+"http://"+r.Host+r.URL.Path+`?page=2>; rel="next"`)
+				fmt.Fprint(w, `[{"full_name": "owner/repo", "html_url": "https://github.com/owner/repo"}]`)
+				return
+			}
+			fmt.Fprint(w, `[{"full_name": "owner/other", "html_url": "https://github.com/owner/other"}]`)
+		case r.URL.Path == "/api/v3/repos/owner/repo":
+			fmt.Fprint(w, `{"full_name": "owner/repo"}`)
+		case r.URL.Path == "/api/v3/repos/owner/repo/subscription" && r.Method == http.MethodGet:
+			if subscription == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, subscription)
+		case r.URL.Path == "/api/v3/repos/owner/repo/subscription" && r.Method == http.MethodPut:
+			var body github.Subscription
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			data, err := json.Marshal(&body)
+			require.NoError(t, err)
+			subscription = string(data)
+			fmt.Fprint(w, subscription)
+		case r.URL.Path == "/api/v3/repos/owner/repo/subscription" && r.Method == http.MethodDelete:
+			subscription = ""
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	subs, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {{ChannelID: "channelID", Repository: "owner/repo", Features: "pulls"}},
+	}})
+	require.NoError(t, err)
+
+	watch := func(settings *UserSettings, parameters ...string) string {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		api.On("KVGet", SubscriptionsKey).Return(subs, nil)
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		userInfo := &GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: "token"}, Settings: settings}
+		return p.handleWatch(nil, &model.CommandArgs{UserId: "userID", ChannelId: "channelID"}, parameters, userInfo)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		message := watch(&UserSettings{}, "list")
+		assert.Equal(t, "You watch 2 repositories:\n* [owner/repo](https://github.com/owner/repo)\n* [owner/other](https://github.com/owner/other)\n", message)
+	})
+
+	t.Run("state of a repository that isn't watched", func(t *testing.T) {
+		subscription = ""
+		assert.Equal(t, "You're not watching owner/repo. GitHub notifies you only when you participate or are mentioned.", watch(&UserSettings{}, "owner/repo"))
+	})
+
+	t.Run("watch all", func(t *testing.T) {
+		subscription = ""
+		assert.Equal(t, "You're watching owner/repo. GitHub notifies you of all its activity.", watch(&UserSettings{}, "owner/repo", "all"))
+		assert.JSONEq(t, `{"subscribed": true}`, subscription)
+	})
+
+	t.Run("ignore the default repository", func(t *testing.T) {
+		message := watch(&UserSettings{}, "ignore")
+		assert.Contains(t, message, "You're ignoring owner/repo.")
+		assert.Contains(t, message, "channels subscribed to owner/repo still get its events")
+		assert.JSONEq(t, `{"ignored": true}`, subscription)
+
+		assert.Contains(t, watch(&UserSettings{}), "You're ignoring owner/repo.")
+	})
+
+	t.Run("participating", func(t *testing.T) {
+		subscription = `{"subscribed": true}`
+		assert.Contains(t, watch(&UserSettings{}, "owner/repo", "participating"), "You're not watching owner/repo.")
+		assert.Empty(t, subscription)
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		assert.Equal(t, "Invalid watch state \"everything\". Use `all`, `participating` or `ignore`.", watch(&UserSettings{}, "owner/repo", "everything"))
+	})
+
+	t.Run("repository the user can't see", func(t *testing.T) {
+		message := watch(&UserSettings{}, "owner/secret", "all")
+		assert.Equal(t, "Encountered an error getting the repository owner/secret. GitHub couldn't find it, or your GitHub account can't access it.", message)
+	})
+
+	t.Run("read-only mode", func(t *testing.T) {
+		subscription = ""
+		requests = nil
+		assert.Equal(t, readOnlyModeMessage, watch(&UserSettings{ReadOnlyMode: true}, "owner/repo", "all"))
+		assert.Equal(t, []string{"GET /api/v3/repos/owner/repo"}, requests)
+	})
+}
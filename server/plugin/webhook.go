@@ -5,15 +5,64 @@ import (
 	"crypto/hmac"
 	"crypto/sha1" //nolint:gosec // GitHub webhooks are signed using sha1 https://developer.github.com/webhooks/.
 	"encoding/hex"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v31/github"
 	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
 )
 
+const (
+	// defaultWebhookPayloadSizeLimit is used when no payload size limit is configured.
+	defaultWebhookPayloadSizeLimit = 5 * 1024 * 1024
+
+	// maxPushCommitsRendered caps the number of commits listed in a push notification.
+	maxPushCommitsRendered = 20
+)
+
+// webhookReadTimeout is the time allowed to read the body of a webhook request, independently of processing it.
+var webhookReadTimeout = 10 * time.Second
+
+var (
+	errWebhookReadTimeout     = errors.New("timed out reading webhook body")
+	errWebhookPayloadTooLarge = errors.New("webhook body too large")
+)
+
+// readBodyWithTimeout reads the whole body, giving up and closing it if it takes longer than the
+// timeout. Bodies longer than limit bytes aren't read past the limit.
+func readBodyWithTimeout(body io.ReadCloser, limit int64, timeout time.Duration) ([]byte, error) {
+	type result struct {
+		body []byte
+		err  error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		b, err := ioutil.ReadAll(io.LimitReader(body, limit+1))
+		if err == nil && int64(len(b)) > limit {
+			b, err = nil, errWebhookPayloadTooLarge
+		}
+		done <- result{b, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-done:
+		return res.body, res.err
+	case <-timer.C:
+		_ = body.Close()
+		return nil, errWebhookReadTimeout
+	}
+}
+
 func verifyWebhookSignature(secret []byte, signature string, body []byte) (bool, error) {
 	const signaturePrefix = "sha1="
 	const signatureLength = 45
@@ -61,92 +110,71 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 
 	signature := r.Header.Get("X-Hub-Signature")
 
-	body, err := ioutil.ReadAll(r.Body)
+	limit := config.getWebhookPayloadSizeLimit()
+	body, err := readBodyWithTimeout(r.Body, limit, webhookReadTimeout)
 	if err != nil {
-		http.Error(w, "Bad request body", http.StatusBadRequest)
+		switch {
+		case err == errWebhookPayloadTooLarge:
+			p.API.LogWarn("Webhook payload too large", "event", github.WebHookType(r), "delivery", github.DeliveryID(r), "limit", limit)
+			http.Error(w, "Payload too large", http.StatusRequestEntityTooLarge)
+		case err == errWebhookReadTimeout:
+			p.API.LogWarn("Timed out reading webhook payload", "event", github.WebHookType(r), "delivery", github.DeliveryID(r))
+			http.Error(w, "Timed out reading request body", http.StatusRequestTimeout)
+		default:
+			http.Error(w, "Bad request body", http.StatusBadRequest)
+		}
 		return
 	}
 
-	valid, err := verifyWebhookSignature([]byte(config.WebhookSecret), signature, body)
-	if err != nil {
+	if err := p.webhookParser().Verify(signature, body); err != nil {
+		if err == errInvalidWebhookSignature {
+			p.recordWebhookSignatureFailure(body, time.Now())
+			http.Error(w, "Not authorized", http.StatusUnauthorized)
+			return
+		}
+
 		p.API.LogWarn("Failed to verify webhook signature", "error", err.Error())
 		http.Error(w, "", http.StatusInternalServerError)
 		return
 	}
 
-	if !valid {
-		http.Error(w, "Not authorized", http.StatusUnauthorized)
-		return
+	// The deliveries are parsed before being queued, so that GitHub is told about the ones which can't
+	// be parsed.
+	payload, err := p.webhookParser().Parse(github.WebHookType(r), body)
+	var target string
+	if err == nil {
+		target, err = parseWebhookTarget(body)
 	}
-
-	event, err := github.ParseWebHook(github.WebHookType(r), body)
 	if err != nil {
-		p.API.LogDebug("GitHub webhook content type should be set to \"application/json\"", "error", err.Error)
+		p.API.LogDebug("GitHub webhook content type should be set to \"application/json\"", "error", err.Error())
 		http.Error(w, "wrong mime-type. should be \"application/json\"", http.StatusBadRequest)
 		return
 	}
 
-	var repo *github.Repository
-	var handler func()
-
-	switch event := event.(type) {
-	case *github.PullRequestEvent:
-		repo = event.GetRepo()
-		handler = func() {
-			p.postPullRequestEvent(event)
-			p.handlePullRequestNotification(event)
-			p.handlePRDescriptionMentionNotification(event)
-		}
-	case *github.IssuesEvent:
-		repo = event.GetRepo()
-		handler = func() {
-			p.postIssueEvent(event)
-			p.handleIssueNotification(event)
-		}
-	case *github.IssueCommentEvent:
-		repo = event.GetRepo()
-		handler = func() {
-			p.postIssueCommentEvent(event)
-			p.handleCommentMentionNotification(event)
-			p.handleCommentAuthorNotification(event)
-		}
-	case *github.PullRequestReviewEvent:
-		repo = event.GetRepo()
-		handler = func() {
-			p.postPullRequestReviewEvent(event)
-			p.handlePullRequestReviewNotification(event)
-		}
-	case *github.PullRequestReviewCommentEvent:
-		repo = event.GetRepo()
-		handler = func() {
-			p.postPullRequestReviewCommentEvent(event)
-		}
-	case *github.PushEvent:
-		repo = ConvertPushEventRepositoryToRepository(event.GetRepo())
-		handler = func() {
-			p.postPushEvent(event)
-		}
-	case *github.CreateEvent:
-		repo = event.GetRepo()
-		handler = func() {
-			p.postCreateEvent(event)
-		}
-	case *github.DeleteEvent:
-		repo = event.GetRepo()
-		handler = func() {
-			p.postDeleteEvent(event)
-		}
-	}
-
-	if repo == nil || handler == nil {
+	p.resetWebhookSignatureFailures(target)
+	recent := p.newRecentDelivery(github.WebHookType(r), github.DeliveryID(r), body)
+	if p.webhookQueue == nil {
+		p.recordRecentDelivery(recent)
+		p.routeWebhookEvent(github.WebHookType(r), github.DeliveryID(r), payload, false)
 		return
 	}
 
-	if repo.GetPrivate() && !config.EnablePrivateRepo {
+	// The events are processed by the workers of the queue, so that bursts of deliveries don't hold
+	// as many requests open.
+	err = p.webhookQueue.enqueue(&webhookJob{
+		EventType:  github.WebHookType(r),
+		DeliveryID: github.DeliveryID(r),
+		Payload:    payload,
+		Target:     target,
+		ReceivedAt: time.Now(),
+		Recent:     recent,
+	})
+	if err != nil {
+		p.API.LogWarn("Rejected webhook event", "event", github.WebHookType(r), "delivery", github.DeliveryID(r), "error", err.Error())
+		w.Header().Set("Retry-After", strconv.Itoa(webhookRetryAfter))
+		http.Error(w, "Too many webhook events, please retry later", http.StatusServiceUnavailable)
 		return
 	}
-
-	handler()
 }
 
 func (p *Plugin) permissionToRepo(userID string, ownerAndRepo string) bool {
@@ -190,98 +218,98 @@ func (p *Plugin) excludeConfigOrgMember(user *github.User, subscription *Subscri
 		return false
 	}
 
-	githubClient := p.githubConnect(*info.Token)
 	organization := p.getConfiguration().GitHubOrg
 
-	return p.isUserOrganizationMember(githubClient, user, organization)
+	return p.isUserOrganizationMember(info, user, organization)
 }
 
-func (p *Plugin) postPullRequestEvent(event *github.PullRequestEvent) {
-	repo := event.GetRepo()
+// excludeSender checks if the events of a sender are left out of the channel of a subscription,
+// because they are an org member excluded by the subscription or are muted by its creator.
+func (p *Plugin) excludeSender(sender *github.User, subscription *Subscription) bool {
+	return p.excludeConfigOrgMember(sender, subscription) || p.senderMutedInChannel(sender.GetLogin(), subscription)
+}
 
-	subs := p.GetSubscribedChannelsForRepository(repo)
-	if len(subs) == 0 {
-		return
+// subscribedToLabels checks if a subscription filtering by label subscribed to one of the labels
+// of an issue or pull request.
+func subscribedToLabels(sub *Subscription, labels []*github.Label) bool {
+	label := sub.Label()
+	if label == "" {
+		return true
 	}
 
-	action := event.GetAction()
-	if action != "opened" && action != "labeled" && action != "closed" {
-		return
+	for _, l := range labels {
+		if l.GetName() == label {
+			return true
+		}
 	}
 
-	pr := event.GetPullRequest()
-	eventLabel := event.GetLabel().GetName()
-	labels := make([]string, len(pr.Labels))
-	for i, v := range pr.Labels {
-		labels[i] = v.GetName()
-	}
+	return false
+}
 
-	newPRMessage, err := renderTemplate("newPR", event)
-	if err != nil {
-		p.API.LogWarn("Failed to render template", "error", err.Error())
-		return
-	}
+// pullRequestRenderer renders the pull requests opened, labeled and closed.
+type pullRequestRenderer struct {
+	p *Plugin
+}
 
-	closedPRMessage, err := renderTemplate("closedPR", event)
-	if err != nil {
-		p.API.LogWarn("Failed to render template", "error", err.Error())
-		return
-	}
+func (r *pullRequestRenderer) Feature() string {
+	return featurePulls
+}
 
-	post := &model.Post{
-		UserId: p.BotUserID,
-		Type:   "custom_git_pr",
+func (r *pullRequestRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*github.PullRequestEvent)
+	if !ok || !sub.Pulls() || r.p.excludeSender(event.GetSender(), sub) {
+		return nil, nil
 	}
 
-	for _, sub := range subs {
-		if !sub.Pulls() {
-			continue
-		}
-
-		if p.excludeConfigOrgMember(event.GetSender(), sub) {
-			continue
-		}
-
-		label := sub.Label()
-
-		contained := false
-		for _, v := range labels {
-			if v == label {
-				contained = true
-			}
-		}
+	pr := event.GetPullRequest()
+	if !subscribedToLabels(sub, pr.Labels) {
+		return nil, nil
+	}
 
-		if !contained && label != "" {
-			continue
+	var message string
+	var err error
+	switch event.GetAction() {
+	case "opened":
+		message, err = renderStyledTemplate("newPR", r.p.getRenderStyle(sub), event)
+	case "labeled":
+		if sub.Label() == "" || sub.Label() != event.GetLabel().GetName() {
+			return nil, nil
 		}
+		message, err = renderTemplate("pullRequestLabelled", event)
+	case "closed":
+		message, err = renderTemplate("closedPR", event)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		if action == "labeled" {
-			if label != "" && label == eventLabel {
-				pullRequestLabelledMessage, err := renderTemplate("pullRequestLabelled", event)
-				if err != nil {
-					p.API.LogWarn("Failed to render template", "error", err.Error())
-					return
-				}
+	// Listing the changed files is left for last as it may need a request to GitHub
+	if !r.p.pullRequestMatchesPaths(sub, event) {
+		return nil, nil
+	}
 
-				post.Message = pullRequestLabelledMessage
-			} else {
-				continue
-			}
-		}
+	if event.GetAction() == "opened" && r.p.isSelfEvent(selfEventPullRequestOpened, pr.GetHTMLURL(), event.GetSender().GetLogin(), sub.ChannelID) {
+		return nil, nil
+	}
 
-		if action == "opened" {
-			post.Message = newPRMessage
+	message = r.p.applyNotificationTemplate(sub, event, message)
+	if event.GetAction() == "opened" && sub.Flags.SuggestReviewers {
+		if suggestion := r.p.getSuggestedReviewers(sub, event); suggestion != "" {
+			message = strings.TrimRight(message, "\n") + "\n\n" + suggestion
 		}
+	}
 
-		if action == "closed" {
-			post.Message = closedPRMessage
-		}
+	return &model.Post{
+		Type:    "custom_git_pr",
+		Message: message,
+		Props:   eventPostProps(event.GetRepo().GetFullName(), objectTypePullRequest, strconv.Itoa(pr.GetNumber()), "pull_request."+event.GetAction()),
+	}, nil
+}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
-	}
+func (p *Plugin) postPullRequestEvent(event *github.PullRequestEvent, delivery *webhookDelivery) {
+	p.postRenderedEvent(&pullRequestRenderer{p}, event.GetRepo(), event, delivery)
 }
 
 func (p *Plugin) handlePRDescriptionMentionNotification(event *github.PullRequestEvent) {
@@ -300,12 +328,6 @@ func (p *Plugin) handlePRDescriptionMentionNotification(event *github.PullReques
 		return
 	}
 
-	post := &model.Post{
-		UserId:  p.BotUserID,
-		Message: message,
-		Type:    "custom_git_mention",
-	}
-
 	for _, username := range mentionedUsernames {
 		// Don't notify user of their own comment
 		if username == event.GetSender().GetLogin() {
@@ -319,6 +341,10 @@ func (p *Plugin) handlePRDescriptionMentionNotification(event *github.PullReques
 
 		userID := p.getGitHubToUserIDMapping(username)
 		if userID == "" {
+			if !event.GetRepo().GetPrivate() {
+				p.inviteUnconnectedUser(username, fmt.Sprintf("@%s mentioned you in the description of [%s#%d](%s)",
+					event.GetSender().GetLogin(), event.GetRepo().GetFullName(), event.GetPullRequest().GetNumber(), event.GetPullRequest().GetHTMLURL()), time.Now())
+			}
 			continue
 		}
 
@@ -326,319 +352,364 @@ func (p *Plugin) handlePRDescriptionMentionNotification(event *github.PullReques
 			continue
 		}
 
-		channel, err := p.API.GetDirectChannel(userID, p.BotUserID)
-		if err != nil {
-			continue
-		}
+		p.CreateNotificationDMPost(userID, p.withConnectCallToAction(userID, message), "custom_git_mention", true)
+		p.sendRefreshEvent(userID)
+	}
+}
 
-		post.ChannelId = channel.Id
+// issueRenderer renders the issues opened, closed, reopened and labeled.
+type issueRenderer struct {
+	p *Plugin
+}
 
-		if _, err = p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
+func (r *issueRenderer) Feature() string {
+	return featureIssues
+}
 
-		p.sendRefreshEvent(userID)
+func (r *issueRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*github.IssuesEvent)
+	if !ok || (!sub.Issues() && !sub.IssueCreations()) {
+		return nil, nil
 	}
-}
 
-func (p *Plugin) postIssueEvent(event *github.IssuesEvent) {
-	repo := event.GetRepo()
-	issue := event.GetIssue()
 	action := event.GetAction()
-
-	// This condition is made to check if the message doesn't get automatically labeled to prevent duplicated issue messages
-	timeDiff := time.Until(issue.GetCreatedAt()) * -1
-	if action == "labeled" && timeDiff.Seconds() < 4.00 {
-		return
+	if sub.IssueCreations() && action != "opened" {
+		return nil, nil
 	}
 
-	subscribedChannels := p.GetSubscribedChannelsForRepository(repo)
-	if len(subscribedChannels) == 0 {
-		return
+	issue := event.GetIssue()
+	if r.p.excludeSender(event.GetSender(), sub) || !subscribedToLabels(sub, issue.Labels) {
+		return nil, nil
 	}
 
-	issueTemplate := ""
+	var message string
+	var err error
 	switch action {
 	case "opened":
-		issueTemplate = "newIssue"
-
+		message, err = r.p.renderNewIssueMessage(sub, event)
 	case "closed":
-		issueTemplate = "closedIssue"
-
+		message, err = renderTemplate("closedIssue", event)
 	case "reopened":
-		issueTemplate = "reopenedIssue"
-
+		message, err = renderTemplate("reopenedIssue", event)
 	case "labeled":
-		issueTemplate = "issueLabelled"
-
+		if sub.Label() == "" || sub.Label() != event.GetLabel().GetName() {
+			return nil, nil
+		}
+		message, err = renderTemplate("issueLabelled", event)
 	default:
-		return
+		return nil, nil
 	}
-
-	renderedMessage, err := renderTemplate(issueTemplate, event)
 	if err != nil {
-		p.API.LogWarn("Failed to render template", "error", err.Error())
-		return
-	}
-	post := &model.Post{
-		UserId:  p.BotUserID,
-		Type:    "custom_git_issue",
-		Message: renderedMessage,
+		return nil, err
 	}
 
-	eventLabel := event.GetLabel().GetName()
-	labels := make([]string, len(issue.Labels))
-	for i, v := range issue.Labels {
-		labels[i] = v.GetName()
+	if action == "opened" && r.p.isSelfEvent(selfEventIssueOpened, issue.GetHTMLURL(), event.GetSender().GetLogin(), sub.ChannelID) {
+		return nil, nil
 	}
 
-	for _, sub := range subscribedChannels {
-		if !sub.Issues() && !sub.IssueCreations() {
-			continue
-		}
+	return &model.Post{
+		Type:    "custom_git_issue",
+		Message: r.p.applyNotificationTemplate(sub, event, message),
+		Props:   eventPostProps(event.GetRepo().GetFullName(), objectTypeIssue, strconv.Itoa(issue.GetNumber()), "issues."+action),
+	}, nil
+}
 
-		if sub.IssueCreations() && action != "opened" {
-			continue
-		}
+func (p *Plugin) postIssueEvent(event *github.IssuesEvent, delivery *webhookDelivery) {
+	issue := event.GetIssue()
+	action := event.GetAction()
 
-		if p.excludeConfigOrgMember(event.GetSender(), sub) {
-			continue
-		}
+	// This condition is made to check if the message doesn't get automatically labeled to prevent duplicated issue messages
+	timeDiff := time.Until(issue.GetCreatedAt()) * -1
+	if action == "labeled" && timeDiff.Seconds() < 4.00 {
+		return
+	}
 
-		label := sub.Label()
+	subscribedChannels := delivery.matchSubscriptions(p.GetSubscribedChannelsForRepository(event.GetRepo()))
+	if len(subscribedChannels) == 0 {
+		return
+	}
 
-		contained := false
-		for _, v := range labels {
-			if v == label {
-				contained = true
-			}
-		}
+	// Replayed events may be older than the current state of the issue
+	liveUpdated := map[*Subscription]bool{}
+	if isLiveUpdateAction(action) && !delivery.Replayed {
+		liveUpdated = p.liveUpdateIssuePosts(subscribedChannels, event)
+	}
 
-		if !contained && label != "" {
+	renderer := &issueRenderer{p}
+	for _, sub := range subscribedChannels {
+		// The updated post counts as the post of the event
+		if liveUpdated[sub] {
+			delivery.recordPosted()
 			continue
 		}
 
-		if action == "labeled" {
-			if label == "" || label != eventLabel {
-				continue
-			}
+		post := p.renderSubscriptionPost(renderer, event, sub, delivery)
+		if post == nil {
+			continue
 		}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
+		created := p.postSubscriptionEvent(post, sub, renderer.Feature(), delivery)
+		if created != nil && action == "opened" && sub.Flags.LiveUpdate {
+			p.storeIssuePost(sub, issue.GetHTMLURL(), created)
+		}
+		// The search for related pull requests doesn't hold the other notifications of the event
+		if created != nil && action == "opened" && sub.Flags.RelatedPRs && !delivery.Replayed {
+			go p.postRelatedPullRequests(context.Background(), sub, event, created)
 		}
 	}
 }
 
-func (p *Plugin) postPushEvent(event *github.PushEvent) {
-	repo := event.GetRepo()
+// pushRenderer renders the commits pushed to a branch.
+type pushRenderer struct {
+	p *Plugin
+	// commits are all the commits of the push, of which only the first ones are rendered.
+	commits []*github.HeadCommit
+}
 
-	subs := p.GetSubscribedChannelsForRepository(ConvertPushEventRepositoryToRepository(repo))
+func (r *pushRenderer) Feature() string {
+	return featurePushes
+}
 
-	if len(subs) == 0 {
-		return
+func (r *pushRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*github.PushEvent)
+	if !ok || !sub.Pushes() || r.p.excludeSender(event.GetSender(), sub) {
+		return nil, nil
 	}
 
-	commits := event.Commits
-	if len(commits) == 0 {
-		return
+	if !r.p.pushMatchesPaths(sub, r.commits) {
+		return nil, nil
 	}
 
-	pushedCommitsMessage, err := renderTemplate("pushedCommits", event)
+	message, err := renderTemplate("pushedCommits", event)
 	if err != nil {
-		p.API.LogWarn("Failed to render template", "error", err.Error())
-		return
+		return nil, err
 	}
 
-	post := &model.Post{
-		UserId:  p.BotUserID,
-		Type:    "custom_git_push",
-		Message: pushedCommitsMessage,
+	if sub.Flags.MentionUsers {
+		withMentions, err := renderTemplate("pushedCommitsWithMentions", event)
+		if err != nil {
+			r.p.API.LogWarn("Failed to render template", "error", err.Error())
+		} else {
+			message = withMentions
+		}
 	}
 
-	for _, sub := range subs {
-		if !sub.Pushes() {
-			continue
-		}
+	return &model.Post{
+		Type:    "custom_git_push",
+		Message: r.p.applyNotificationTemplate(sub, event, message),
+		Props:   eventPostProps(event.GetRepo().GetFullName(), objectTypeRef, event.GetRef(), "push"),
+	}, nil
+}
 
-		if p.excludeConfigOrgMember(event.GetSender(), sub) {
-			continue
-		}
+func (p *Plugin) postPushEvent(event *github.PushEvent, delivery *webhookDelivery) {
+	commits := event.Commits
+	if len(commits) == 0 {
+		return
+	}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
+	// Only render the first commits of big pushes, keeping track of the total for the summary.
+	if len(commits) > maxPushCommitsRendered {
+		truncated := *event
+		truncated.Commits = commits[:maxPushCommitsRendered]
+		if truncated.GetSize() < len(commits) {
+			truncated.Size = github.Int(len(commits))
 		}
+		event = &truncated
 	}
+
+	p.postRenderedEvent(&pushRenderer{p: p, commits: commits}, ConvertPushEventRepositoryToRepository(event.GetRepo()), event, delivery)
 }
 
-func (p *Plugin) postCreateEvent(event *github.CreateEvent) {
-	repo := event.GetRepo()
+// createRenderer renders the branches and tags created.
+type createRenderer struct {
+	p *Plugin
+}
 
-	subs := p.GetSubscribedChannelsForRepository(repo)
-	if len(subs) == 0 {
-		return
+func (r *createRenderer) Feature() string {
+	return featureCreates
+}
+
+func (r *createRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*github.CreateEvent)
+	if !ok || !sub.Creates() || r.p.excludeSender(event.GetSender(), sub) {
+		return nil, nil
 	}
 
-	typ := event.GetRefType()
-	if typ != "tag" && typ != "branch" {
-		return
+	if typ := event.GetRefType(); typ != "tag" && typ != "branch" {
+		return nil, nil
 	}
 
-	newCreateMessage, err := renderTemplate("newCreateMessage", event)
+	message, err := renderTemplate("newCreateMessage", event)
 	if err != nil {
-		p.API.LogWarn("Failed to render template", "error", err.Error())
-		return
+		return nil, err
 	}
 
-	post := &model.Post{
-		UserId:  p.BotUserID,
+	return &model.Post{
 		Type:    "custom_git_create",
-		Message: newCreateMessage,
-	}
-
-	for _, sub := range subs {
-		if !sub.Creates() {
-			continue
-		}
-
-		if p.excludeConfigOrgMember(event.GetSender(), sub) {
-			continue
-		}
+		Message: r.p.applyNotificationTemplate(sub, event, message),
+		Props:   eventPostProps(event.GetRepo().GetFullName(), objectTypeRef, event.GetRef(), "create"),
+	}, nil
+}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
-	}
+func (p *Plugin) postCreateEvent(event *github.CreateEvent, delivery *webhookDelivery) {
+	p.postRenderedEvent(&createRenderer{p}, event.GetRepo(), event, delivery)
 }
 
-func (p *Plugin) postDeleteEvent(event *github.DeleteEvent) {
-	repo := event.GetRepo()
+// deleteRenderer renders the branches and tags deleted.
+type deleteRenderer struct {
+	p *Plugin
+}
 
-	subs := p.GetSubscribedChannelsForRepository(repo)
+func (r *deleteRenderer) Feature() string {
+	return featureDeletes
+}
 
-	if len(subs) == 0 {
-		return
+func (r *deleteRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*github.DeleteEvent)
+	if !ok || !sub.Deletes() || r.p.excludeSender(event.GetSender(), sub) {
+		return nil, nil
 	}
 
-	typ := event.GetRefType()
-
-	if typ != "tag" && typ != "branch" {
-		return
+	if typ := event.GetRefType(); typ != "tag" && typ != "branch" {
+		return nil, nil
 	}
 
-	newDeleteMessage, err := renderTemplate("newDeleteMessage", event)
+	message, err := renderTemplate("newDeleteMessage", event)
 	if err != nil {
-		p.API.LogWarn("Failed to render template", "error", err.Error())
-		return
+		return nil, err
 	}
 
-	post := &model.Post{
-		UserId:  p.BotUserID,
+	return &model.Post{
 		Type:    "custom_git_delete",
-		Message: newDeleteMessage,
-	}
+		Message: r.p.applyNotificationTemplate(sub, event, message),
+		Props:   eventPostProps(event.GetRepo().GetFullName(), objectTypeRef, event.GetRef(), "delete"),
+	}, nil
+}
 
-	for _, sub := range subs {
-		if !sub.Deletes() {
-			continue
-		}
+func (p *Plugin) postDeleteEvent(event *github.DeleteEvent, delivery *webhookDelivery) {
+	p.postRenderedEvent(&deleteRenderer{p}, event.GetRepo(), event, delivery)
+}
 
-		if p.excludeConfigOrgMember(event.GetSender(), sub) {
-			continue
-		}
+// forkRenderer renders the forks of a repository.
+type forkRenderer struct {
+	p *Plugin
+}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
+func (r *forkRenderer) Feature() string {
+	return featureForks
+}
+
+func (r *forkRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*github.ForkEvent)
+	if !ok || !sub.Forks() || r.p.excludeSender(event.GetSender(), sub) {
+		return nil, nil
+	}
+
+	message, err := renderTemplate("newFork", event)
+	if err != nil {
+		return nil, err
 	}
+
+	return &model.Post{
+		Type:    "custom_git_fork",
+		Message: r.p.applyNotificationTemplate(sub, event, message),
+		Props:   eventPostProps(event.GetRepo().GetFullName(), objectTypeRepository, event.GetForkee().GetFullName(), "fork"),
+	}, nil
 }
 
-func (p *Plugin) postIssueCommentEvent(event *github.IssueCommentEvent) {
-	repo := event.GetRepo()
+func (p *Plugin) postForkEvent(event *github.ForkEvent, delivery *webhookDelivery) {
+	p.postRenderedEvent(&forkRenderer{p}, event.GetRepo(), event, delivery)
+}
 
-	subs := p.GetSubscribedChannelsForRepository(repo)
+// issueCommentRenderer renders the comments created on issues and pull requests.
+type issueCommentRenderer struct {
+	p *Plugin
+}
 
-	if len(subs) == 0 {
-		return
-	}
+func (r *issueCommentRenderer) Feature() string {
+	return featureIssueComments
+}
 
-	if event.GetAction() != "created" {
-		return
+func (r *issueCommentRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*github.IssueCommentEvent)
+	if !ok || event.GetAction() != "created" || !sub.IssueComments() || r.p.excludeSender(event.GetSender(), sub) {
+		return nil, nil
 	}
 
-	message, err := renderTemplate("issueComment", event)
-	if err != nil {
-		p.API.LogWarn("Failed to render template", "error", err.Error())
-		return
+	if !subscribedToLabels(sub, event.GetIssue().Labels) {
+		return nil, nil
 	}
 
-	post := &model.Post{
-		UserId: p.BotUserID,
-		Type:   "custom_git_comment",
+	if r.p.isSelfEvent(selfEventIssueComment, event.GetComment().GetHTMLURL(), event.GetSender().GetLogin(), sub.ChannelID) {
+		return nil, nil
 	}
 
-	labels := make([]string, len(event.GetIssue().Labels))
-	for i, v := range event.GetIssue().Labels {
-		labels[i] = v.GetName()
+	message, err := renderTemplate("issueComment", event)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, sub := range subs {
-		if !sub.IssueComments() {
-			continue
-		}
+	return &model.Post{
+		Type:    "custom_git_comment",
+		Message: r.p.applyNotificationTemplate(sub, event, message),
+		Props:   eventPostProps(event.GetRepo().GetFullName(), issueObjectType(event.GetIssue()), strconv.Itoa(event.GetIssue().GetNumber()), "issue_comment."+event.GetAction()),
+	}, nil
+}
 
-		if p.excludeConfigOrgMember(event.GetSender(), sub) {
+func (p *Plugin) postIssueCommentEvent(event *github.IssueCommentEvent, delivery *webhookDelivery) {
+	renderer := &issueCommentRenderer{p}
+	for _, sub := range delivery.matchSubscriptions(p.GetSubscribedChannelsForRepository(event.GetRepo())) {
+		post := p.renderSubscriptionPost(renderer, event, sub, delivery)
+		if post == nil {
 			continue
 		}
 
-		label := sub.Label()
-
-		contained := false
-		for _, v := range labels {
-			if v == label {
-				contained = true
-			}
-		}
-
-		if !contained && label != "" {
+		if sub.Flags.CoalesceComments != "" {
+			p.postCoalescedComment(post, sub, event, delivery)
 			continue
 		}
 
-		if event.GetAction() == "created" {
-			post.Message = message
-		}
-
-		post.ChannelId = sub.ChannelID
-
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
+		p.postSubscriptionEvent(post, sub, renderer.Feature(), delivery)
 	}
 }
 
 func (p *Plugin) senderMutedByReceiver(userID string, sender string) bool {
-	mutedUsernameBytes, _ := p.API.KVGet(userID + "-muted-users")
-	mutedUsernames := string(mutedUsernameBytes)
-	return strings.Contains(mutedUsernames, sender)
+	// Every scope mutes the notifications
+	return p.getMutedUser(userID, sender) != nil
 }
 
-func (p *Plugin) postPullRequestReviewEvent(event *github.PullRequestReviewEvent) {
-	repo := event.GetRepo()
+// pullRequestReviewRenderer renders the reviews submitted on pull requests.
+type pullRequestReviewRenderer struct {
+	p *Plugin
+}
 
-	subs := p.GetSubscribedChannelsForRepository(repo)
-	if len(subs) == 0 {
-		return
+func (r *pullRequestReviewRenderer) Feature() string {
+	return featurePullReviews
+}
+
+func (r *pullRequestReviewRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*github.PullRequestReviewEvent)
+	if !ok || event.GetAction() != "submitted" || !sub.PullReviews() || r.p.excludeSender(event.GetSender(), sub) {
+		return nil, nil
 	}
 
-	action := event.GetAction()
-	if action != "submitted" {
-		return
+	if !subscribedToLabels(sub, event.GetPullRequest().Labels) {
+		return nil, nil
+	}
+
+	message, err := renderTemplate("pullRequestReviewEvent", event)
+	if err != nil {
+		return nil, err
 	}
 
+	return &model.Post{
+		Type:    "custom_git_pull_review",
+		Message: r.p.applyNotificationTemplate(sub, event, message),
+		Props:   eventPostProps(event.GetRepo().GetFullName(), objectTypePullRequest, strconv.Itoa(event.GetPullRequest().GetNumber()), "pull_request_review."+event.GetAction()),
+	}, nil
+}
+
+func (p *Plugin) postPullRequestReviewEvent(event *github.PullRequestReviewEvent, delivery *webhookDelivery) {
 	switch event.GetReview().GetState() {
 	case "APPROVED":
 	case "COMMENTED":
@@ -648,104 +719,42 @@ func (p *Plugin) postPullRequestReviewEvent(event *github.PullRequestReviewEvent
 		return
 	}
 
-	newReviewMessage, err := renderTemplate("pullRequestReviewEvent", event)
-	if err != nil {
-		p.API.LogWarn("Failed to render template", "error", err.Error())
-		return
-	}
-
-	post := &model.Post{
-		UserId:  p.BotUserID,
-		Type:    "custom_git_pull_review",
-		Message: newReviewMessage,
-	}
-
-	labels := make([]string, len(event.GetPullRequest().Labels))
-	for i, v := range event.GetPullRequest().Labels {
-		labels[i] = v.GetName()
-	}
-
-	for _, sub := range subs {
-		if !sub.PullReviews() {
-			continue
-		}
-
-		if p.excludeConfigOrgMember(event.GetSender(), sub) {
-			continue
-		}
-
-		label := sub.Label()
-
-		contained := false
-		for _, v := range labels {
-			if v == label {
-				contained = true
-			}
-		}
+	p.postRenderedEvent(&pullRequestReviewRenderer{p}, event.GetRepo(), event, delivery)
+}
 
-		if !contained && label != "" {
-			continue
-		}
+// pullRequestReviewCommentRenderer renders the comments of pull request reviews.
+type pullRequestReviewCommentRenderer struct {
+	p *Plugin
+}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
-	}
+func (r *pullRequestReviewCommentRenderer) Feature() string {
+	return featurePullReviews
 }
 
-func (p *Plugin) postPullRequestReviewCommentEvent(event *github.PullRequestReviewCommentEvent) {
-	repo := event.GetRepo()
+func (r *pullRequestReviewCommentRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*github.PullRequestReviewCommentEvent)
+	if !ok || !sub.PullReviews() || r.p.excludeSender(event.GetSender(), sub) {
+		return nil, nil
+	}
 
-	subs := p.GetSubscribedChannelsForRepository(repo)
-	if len(subs) == 0 {
-		return
+	if !subscribedToLabels(sub, event.GetPullRequest().Labels) {
+		return nil, nil
 	}
 
-	newReviewMessage, err := renderTemplate("newReviewComment", event)
+	message, err := renderTemplate("newReviewComment", event)
 	if err != nil {
-		p.API.LogWarn("Failed to render template", "error", err.Error())
-		return
+		return nil, err
 	}
 
-	post := &model.Post{
-		UserId:  p.BotUserID,
+	return &model.Post{
 		Type:    "custom_git_pull_review_comment",
-		Message: newReviewMessage,
-	}
-
-	labels := make([]string, len(event.GetPullRequest().Labels))
-	for i, v := range event.GetPullRequest().Labels {
-		labels[i] = v.GetName()
-	}
-
-	for _, sub := range subs {
-		if !sub.PullReviews() {
-			continue
-		}
-
-		if p.excludeConfigOrgMember(event.GetSender(), sub) {
-			continue
-		}
-
-		label := sub.Label()
-
-		contained := false
-		for _, v := range labels {
-			if v == label {
-				contained = true
-			}
-		}
-
-		if !contained && label != "" {
-			continue
-		}
+		Message: r.p.applyNotificationTemplate(sub, event, message),
+		Props:   eventPostProps(event.GetRepo().GetFullName(), objectTypePullRequest, strconv.Itoa(event.GetPullRequest().GetNumber()), "pull_request_review_comment."+event.GetAction()),
+	}, nil
+}
 
-		post.ChannelId = sub.ChannelID
-		if _, err := p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error webhook post", "post", post, "error", err.Error())
-		}
-	}
+func (p *Plugin) postPullRequestReviewCommentEvent(event *github.PullRequestReviewCommentEvent, delivery *webhookDelivery) {
+	p.postRenderedEvent(&pullRequestReviewCommentRenderer{p}, event.GetRepo(), event, delivery)
 }
 
 func (p *Plugin) handleCommentMentionNotification(event *github.IssueCommentEvent) {
@@ -769,12 +778,6 @@ func (p *Plugin) handleCommentMentionNotification(event *github.IssueCommentEven
 		return
 	}
 
-	post := &model.Post{
-		UserId:  p.BotUserID,
-		Message: message,
-		Type:    "custom_git_mention",
-	}
-
 	for _, username := range mentionedUsernames {
 		// Don't notify user of their own comment
 		if username == event.GetSender().GetLogin() {
@@ -788,6 +791,10 @@ func (p *Plugin) handleCommentMentionNotification(event *github.IssueCommentEven
 
 		userID := p.getGitHubToUserIDMapping(username)
 		if userID == "" {
+			if !event.GetRepo().GetPrivate() {
+				p.inviteUnconnectedUser(username, fmt.Sprintf("@%s mentioned you in a comment on [%s#%d](%s)",
+					event.GetSender().GetLogin(), event.GetRepo().GetFullName(), event.GetIssue().GetNumber(), event.GetComment().GetHTMLURL()), time.Now())
+			}
 			continue
 		}
 
@@ -795,16 +802,7 @@ func (p *Plugin) handleCommentMentionNotification(event *github.IssueCommentEven
 			continue
 		}
 
-		channel, err := p.API.GetDirectChannel(userID, p.BotUserID)
-		if err != nil {
-			continue
-		}
-
-		post.ChannelId = channel.Id
-		if _, err = p.API.CreatePost(post); err != nil {
-			p.API.LogWarn("Error creating mention post", "error", err.Error())
-		}
-
+		p.CreateNotificationDMPost(userID, p.withConnectCallToAction(userID, message), "custom_git_mention", true)
 		p.sendRefreshEvent(userID)
 	}
 }
@@ -856,7 +854,7 @@ func (p *Plugin) handleCommentAuthorNotification(event *github.IssueCommentEvent
 		return
 	}
 
-	p.CreateBotDMPost(authorUserID, message, "custom_git_author")
+	p.CreateNotificationDMPost(authorUserID, message, "custom_git_author", false)
 	p.sendRefreshEvent(authorUserID)
 }
 
@@ -873,11 +871,20 @@ func (p *Plugin) handlePullRequestNotification(event *github.PullRequestEvent) {
 
 	switch event.GetAction() {
 	case "review_requested":
+		if event.RequestedTeam != nil {
+			p.handleTeamReviewRequestNotification(event)
+			return
+		}
+
 		requestedReviewer = event.GetRequestedReviewer().GetLogin()
 		if requestedReviewer == sender {
 			return
 		}
 		requestedUserID = p.getGitHubToUserIDMapping(requestedReviewer)
+		if requestedUserID == "" && !isPrivate {
+			p.inviteUnconnectedUser(requestedReviewer, fmt.Sprintf("@%s requested your review on [%s#%d](%s)",
+				sender, repoName, event.GetPullRequest().GetNumber(), event.GetPullRequest().GetHTMLURL()), time.Now())
+		}
 		if isPrivate && !p.permissionToRepo(requestedUserID, repoName) {
 			requestedUserID = ""
 		}
@@ -918,7 +925,7 @@ func (p *Plugin) handlePullRequestNotification(event *github.PullRequestEvent) {
 	}
 
 	if len(requestedUserID) > 0 {
-		p.CreateBotDMPost(requestedUserID, message, "custom_git_review_request")
+		p.CreateNotificationDMPost(requestedUserID, p.withConnectCallToAction(requestedUserID, message), "custom_git_review_request", true)
 		p.sendRefreshEvent(requestedUserID)
 	}
 
@@ -974,12 +981,12 @@ func (p *Plugin) handleIssueNotification(event *github.IssuesEvent) {
 
 func (p *Plugin) postIssueNotification(message, authorUserID, assigneeUserID string) {
 	if len(authorUserID) > 0 {
-		p.CreateBotDMPost(authorUserID, message, "custom_git_author")
+		p.CreateNotificationDMPost(authorUserID, p.withConnectCallToAction(authorUserID, message), "custom_git_author", false)
 		p.sendRefreshEvent(authorUserID)
 	}
 
 	if len(assigneeUserID) > 0 {
-		p.CreateBotDMPost(assigneeUserID, message, "custom_git_assigned")
+		p.CreateNotificationDMPost(assigneeUserID, p.withConnectCallToAction(assigneeUserID, message), "custom_git_assigned", false)
 		p.sendRefreshEvent(assigneeUserID)
 	}
 }
@@ -1009,6 +1016,6 @@ func (p *Plugin) handlePullRequestReviewNotification(event *github.PullRequestRe
 		return
 	}
 
-	p.CreateBotDMPost(authorUserID, message, "custom_git_review")
+	p.CreateNotificationDMPost(authorUserID, message, "custom_git_review", false)
 	p.sendRefreshEvent(authorUserID)
 }
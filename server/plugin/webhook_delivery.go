@@ -0,0 +1,204 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// Reasons the post of an event for a subscription wasn't created.
+const (
+	filterReasonSubscription = "subscription filters"
+	filterReasonRender       = "render failed"
+	filterReasonSampled      = "sampled"
+	filterReasonCoalesced    = "coalesced"
+	filterReasonPostFailed   = "post failed"
+	filterReasonRateCapped   = "rate capped"
+)
+
+// webhookDelivery is the processing of a webhook delivery. It holds the context logged with the
+// messages about the delivery, and counts what happened to the subscriptions it matched so that
+// a single summary line tells why a channel didn't get a post.
+type webhookDelivery struct {
+	ID        string
+	EventType string
+	Action    string
+	Repo      string
+	// Replayed deliveries only recreate the posts of the subscriptions.
+	Replayed bool
+
+	log *contextLogger
+
+	lock     sync.Mutex
+	matched  int
+	posted   int
+	filtered map[string]int
+}
+
+func newWebhookDelivery(log *contextLogger, id, eventType string, replayed bool) *webhookDelivery {
+	return &webhookDelivery{
+		ID:        id,
+		EventType: eventType,
+		Replayed:  replayed,
+		log:       log,
+		filtered:  map[string]int{},
+	}
+}
+
+func (p *Plugin) newWebhookDelivery(id, eventType string, replayed bool) *webhookDelivery {
+	return newWebhookDelivery(&contextLogger{api: p.API}, id, eventType, replayed)
+}
+
+// logContext is the structured context of log messages.
+type logContext map[string]interface{}
+
+// contextLogger logs messages with their structured context.
+type contextLogger struct {
+	api     plugin.API
+	context logContext
+}
+
+// With returns a logger adding the extra context to the context of this one.
+func (l *contextLogger) With(extra logContext) *contextLogger {
+	merged := logContext{}
+	for key, value := range l.context {
+		merged[key] = value
+	}
+	for key, value := range extra {
+		merged[key] = value
+	}
+
+	return &contextLogger{api: l.api, context: merged}
+}
+
+// keyValuePairs returns the context sorted by key, as expected by the plugin API.
+func (l *contextLogger) keyValuePairs() []interface{} {
+	keys := make([]string, 0, len(l.context))
+	for key := range l.context {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]interface{}, 0, 2*len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key, l.context[key])
+	}
+	return pairs
+}
+
+func (l *contextLogger) Debugf(format string, args ...interface{}) {
+	l.api.LogDebug(fmt.Sprintf(format, args...), l.keyValuePairs()...)
+}
+
+func (l *contextLogger) Warnf(format string, args ...interface{}) {
+	l.api.LogWarn(fmt.Sprintf(format, args...), l.keyValuePairs()...)
+}
+
+func (d *webhookDelivery) logContext() logContext {
+	return logContext{
+		"delivery": d.ID,
+		"event":    d.EventType,
+		"action":   d.Action,
+		"repo":     d.Repo,
+		"replayed": d.Replayed,
+	}
+}
+
+// logger returns a logger with the context of the delivery and the extra context.
+func (d *webhookDelivery) logger(extra logContext) *contextLogger {
+	return d.log.With(d.logContext()).With(extra)
+}
+
+// subscriptionLogger returns a logger for the processing of the delivery for a subscription.
+func (d *webhookDelivery) subscriptionLogger(sub *Subscription) *contextLogger {
+	return d.logger(logContext{
+		"channelID":         sub.ChannelID,
+		"subscription_repo": sub.Repository,
+	})
+}
+
+// matchSubscriptions counts the subscriptions the delivery is processed for, and returns them.
+func (d *webhookDelivery) matchSubscriptions(subs []*Subscription) []*Subscription {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.matched += len(subs)
+	return subs
+}
+
+func (d *webhookDelivery) recordPosted() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.posted++
+}
+
+func (d *webhookDelivery) recordFiltered(reason string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.filtered[reason]++
+}
+
+// webhookDeliverySummary is what happened to the subscriptions matched by a delivery.
+type webhookDeliverySummary struct {
+	Matched  int
+	Posted   int
+	Filtered map[string]int
+}
+
+// summary counts the matched subscriptions which got neither a post nor a recorded reason as
+// filtered by the features, labels and other filters of the subscriptions.
+func (d *webhookDelivery) summary() webhookDeliverySummary {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	filtered := map[string]int{}
+	accounted := d.posted
+	for reason, count := range d.filtered {
+		filtered[reason] = count
+		accounted += count
+	}
+	if d.matched > accounted {
+		filtered[filterReasonSubscription] += d.matched - accounted
+	}
+
+	return webhookDeliverySummary{Matched: d.matched, Posted: d.posted, Filtered: filtered}
+}
+
+func (s webhookDeliverySummary) filteredCount() int {
+	count := 0
+	for _, c := range s.Filtered {
+		count += c
+	}
+	return count
+}
+
+// formatFilterReasons lists the reasons posts were filtered, e.g. " (sampled: 2, subscription filters: 1)".
+func (s webhookDeliverySummary) formatFilterReasons() string {
+	if len(s.Filtered) == 0 {
+		return ""
+	}
+
+	reasons := make([]string, 0, len(s.Filtered))
+	for reason, count := range s.Filtered {
+		reasons = append(reasons, fmt.Sprintf("%s: %d", reason, count))
+	}
+	sort.Strings(reasons)
+
+	return " (" + strings.Join(reasons, ", ") + ")"
+}
+
+// logSummary logs a single line telling what happened to the delivery.
+func (d *webhookDelivery) logSummary() {
+	summary := d.summary()
+	d.logger(logContext{
+		"subscriptions": summary.Matched,
+		"posted":        summary.Posted,
+		"filtered":      summary.filteredCount(),
+	}).Debugf("Processed webhook delivery: received, matched %d subscriptions, posted %d messages, filtered %d%s",
+		summary.Matched, summary.Posted, summary.filteredCount(), summary.formatFilterReasons())
+}
@@ -0,0 +1,153 @@
+package plugin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// testWebhookDelivery returns the processing of a delivery received for the first time, for the
+// tests calling the handlers of the events directly.
+func testWebhookDelivery(p *Plugin) *webhookDelivery {
+	return p.newWebhookDelivery("", "", false)
+}
+
+// loggedDelivery is a message logged with the context of a delivery.
+type loggedDelivery struct {
+	Level   string
+	Message string
+	Context map[string]interface{}
+}
+
+// maxDeliveryLogPairs is the most keys logged with the context of a delivery.
+const maxDeliveryLogPairs = 8
+
+// mockDeliveryLogs mocks the debug and warning messages logged with the context of a delivery, and
+// returns them as they are logged.
+func mockDeliveryLogs(api *plugintest.API) func() []loggedDelivery {
+	var lock sync.Mutex
+	logged := []loggedDelivery{}
+
+	for _, level := range []string{"Debug", "Warn"} {
+		level := level
+		// The context is sorted by key, and always starts with the action of the delivery. Missing
+		// arguments match mock.Anything, so calls with fewer keys match too.
+		args := []interface{}{mock.AnythingOfType("string"), "action"}
+		for i := 0; i < 2*maxDeliveryLogPairs-1; i++ {
+			args = append(args, mock.Anything)
+		}
+
+		api.On("Log"+level, args...).Run(func(args mock.Arguments) {
+			entry := loggedDelivery{Level: level, Message: args.String(0), Context: map[string]interface{}{}}
+			for i := 1; i+1 < len(args); i += 2 {
+				entry.Context[args.String(i)] = args[i+1]
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			logged = append(logged, entry)
+		}).Maybe()
+	}
+
+	return func() []loggedDelivery {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]loggedDelivery{}, logged...)
+	}
+}
+
+func TestWebhookDeliverySummary(t *testing.T) {
+	delivery := newWebhookDelivery(nil, "deliveryID", "push", false)
+	delivery.matchSubscriptions([]*Subscription{{}, {}, {}, {}})
+	delivery.matchSubscriptions([]*Subscription{{}})
+	delivery.recordPosted()
+	delivery.recordFiltered(filterReasonSampled)
+	delivery.recordFiltered(filterReasonSampled)
+
+	summary := delivery.summary()
+	assert.Equal(t, 5, summary.Matched)
+	assert.Equal(t, 1, summary.Posted)
+	assert.Equal(t, map[string]int{filterReasonSampled: 2, filterReasonSubscription: 2}, summary.Filtered)
+	assert.Equal(t, 4, summary.filteredCount())
+	assert.Equal(t, " (sampled: 2, subscription filters: 2)", summary.formatFilterReasons())
+
+	assert.Equal(t, "", newWebhookDelivery(nil, "", "push", false).summary().formatFilterReasons())
+}
+
+func TestContextLogger(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("LogWarn", "Failed", "a", 1, "b", 2, "c", 3).Once()
+
+	log := &contextLogger{api: api}
+	parent := log.With(logContext{"b": 2, "a": 1})
+	parent.With(logContext{"c": 3}).Warnf("Failed")
+	// The context of the parent isn't changed
+	assert.Equal(t, logContext{"a": 1, "b": 2}, parent.context)
+	api.AssertExpectations(t)
+}
+
+func TestProcessWebhookEventLogsSummary(t *testing.T) {
+	subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "allChannelID", Repository: "owner/repo", Features: "pulls,issues,pushes,creates,deletes"},
+			{ChannelID: "sampledChannelID", Repository: "owner/repo", Features: "pushes", Flags: SubscriptionFlags{Sample: "1/2"}},
+			{ChannelID: "issuesChannelID", Repository: "owner/repo", Features: "issues"},
+			{ChannelID: "failingChannelID", Repository: "owner/repo", Features: "pushes"},
+		},
+	}})
+	require.NoError(t, err)
+
+	body, err := ioutil.ReadFile(filepath.Join("testdata", "webhooks", "push.branch.json"))
+	require.NoError(t, err)
+
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	p.setConfiguration(&Configuration{})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store[SubscriptionsKey] = subscriptions
+	api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil).Maybe()
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(true, nil).Maybe()
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		return post.ChannelId == "failingChannelID"
+	})).Return(nil, &model.AppError{Message: "failed"})
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		return post
+	}, nil)
+	api.On("GetChannel", "failingChannelID").Return(&model.Channel{Id: "failingChannelID"}, nil)
+	api.On("LogWarn", "Error webhook post", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	logged := mockDeliveryLogs(api)
+	p.SetAPI(api)
+
+	// The first push of a sampled subscription is posted, the second one is skipped
+	require.NoError(t, p.processWebhookEvent("push", "firstID", body, false))
+	require.NoError(t, p.processWebhookEvent("push", "secondID", body, false))
+
+	summaries := logged()
+	require.Len(t, summaries, 2)
+	first, second := summaries[0], summaries[1]
+	assert.Equal(t, "Debug", first.Level)
+	assert.Equal(t, map[string]interface{}{
+		"delivery":      "firstID",
+		"event":         "push",
+		"action":        "",
+		"repo":          "owner/repo",
+		"replayed":      false,
+		"subscriptions": 4,
+		"posted":        2,
+		"filtered":      2,
+	}, first.Context)
+	assert.Equal(t, "Processed webhook delivery: received, matched 4 subscriptions, posted 2 messages, filtered 2 (post failed: 1, subscription filters: 1)", first.Message)
+
+	assert.Equal(t, "secondID", second.Context["delivery"])
+	assert.Equal(t, 1, second.Context["posted"])
+	assert.Equal(t, "Processed webhook delivery: received, matched 4 subscriptions, posted 1 messages, filtered 3 (post failed: 1, sampled: 1, subscription filters: 1)", second.Message)
+}
@@ -0,0 +1,217 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	webhookInfoKey     = "_webhookinfo"
+	webhookDeliveryKey = "_webhookdelivery"
+	pendingWebhookKey  = "_pendingwebhook"
+
+	// pendingWebhookTTL is how long after subscribing a channel is told about the webhook becoming active.
+	pendingWebhookTTL = 60 * 60
+
+	// webhookDeliveryWriteInterval throttles how often the last webhook delivery of a repository is stored by a server.
+	webhookDeliveryWriteInterval = 5 * time.Minute
+)
+
+// WebhookInfo is the configuration of a GitHub webhook, as received with its ping event.
+type WebhookInfo struct {
+	HookID      int64
+	Events      []string
+	ContentType string
+	InsecureSSL bool
+	PingedAt    time.Time
+}
+
+// pingPayload holds the parts of a ping event go-github doesn't parse.
+type pingPayload struct {
+	Hook         *github.Hook         `json:"hook"`
+	Repository   *github.Repository   `json:"repository"`
+	Organization *github.Organization `json:"organization"`
+}
+
+// webhookTarget normalizes the repository or organization a webhook is configured on.
+func webhookTarget(repoOrOrg string) string {
+	return strings.TrimSuffix(strings.ToLower(repoOrOrg), "/")
+}
+
+func parseWebhookInfo(hook *github.Hook, now time.Time) *WebhookInfo {
+	info := &WebhookInfo{
+		HookID:   hook.GetID(),
+		Events:   hook.Events,
+		PingedAt: now,
+	}
+
+	if contentType, ok := hook.Config["content_type"].(string); ok {
+		info.ContentType = contentType
+	}
+
+	// GitHub sends insecure_ssl as "0" or "1", but older versions of GitHub Enterprise send a number.
+	switch insecureSSL := hook.Config["insecure_ssl"].(type) {
+	case string:
+		info.InsecureSSL = insecureSSL == "1"
+	case float64:
+		info.InsecureSSL = insecureSSL == 1
+	}
+
+	return info
+}
+
+func (p *Plugin) storeWebhookInfo(target string, info *WebhookInfo) error {
+	value, err := json.Marshal(info)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal webhook info")
+	}
+
+	if appErr := p.API.KVSet(hashKey(webhookInfoKey, webhookTarget(target)), value); appErr != nil {
+		return errors.Wrap(appErr, "could not store webhook info in KV store")
+	}
+
+	return nil
+}
+
+// getWebhookInfo returns the webhook info of a repository or organization, or nil if no ping was received for it.
+func (p *Plugin) getWebhookInfo(target string) (*WebhookInfo, error) {
+	value, appErr := p.API.KVGet(hashKey(webhookInfoKey, webhookTarget(target)))
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not get webhook info from KV store")
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	var info *WebhookInfo
+	if err := json.Unmarshal(value, &info); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal webhook info")
+	}
+
+	return info, nil
+}
+
+// recordWebhookDelivery stores the time of the last webhook event received for a repository. It's
+// stored at most once every webhookDeliveryWriteInterval, which is precise enough to debug webhooks.
+func (p *Plugin) recordWebhookDelivery(target string, now time.Time) {
+	target = webhookTarget(target)
+	if last, ok := p.webhookDeliveryWrites.Load(target); ok && now.Sub(last.(time.Time)) < webhookDeliveryWriteInterval {
+		return
+	}
+	p.webhookDeliveryWrites.Store(target, now)
+
+	value := []byte(now.UTC().Format(time.RFC3339))
+	if appErr := p.API.KVSet(hashKey(webhookDeliveryKey, target), value); appErr != nil {
+		p.API.LogWarn("Failed to record webhook delivery", "target", target, "error", appErr.Error())
+	}
+}
+
+// getLastWebhookDelivery returns the time of the last webhook event received for a repository, or
+// the zero time if none was.
+func (p *Plugin) getLastWebhookDelivery(target string) (time.Time, error) {
+	value, appErr := p.API.KVGet(hashKey(webhookDeliveryKey, webhookTarget(target)))
+	if appErr != nil {
+		return time.Time{}, errors.Wrap(appErr, "could not get last webhook delivery from KV store")
+	}
+
+	if value == nil {
+		return time.Time{}, nil
+	}
+
+	lastDelivery, err := time.Parse(time.RFC3339, string(value))
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "could not parse last webhook delivery")
+	}
+
+	return lastDelivery, nil
+}
+
+// markPendingWebhook remembers that a channel just subscribed to a repository or organization, to
+// confirm in it when the webhook is pinged.
+func (p *Plugin) markPendingWebhook(target, channelID string) error {
+	if appErr := p.API.KVSetWithExpiry(hashKey(pendingWebhookKey, webhookTarget(target)), []byte(channelID), pendingWebhookTTL); appErr != nil {
+		return errors.Wrap(appErr, "could not store pending webhook marker in KV store")
+	}
+
+	return nil
+}
+
+// handlePingEvent records the configuration of a webhook and confirms it's active in the channel
+// that recently subscribed to its repository or organization, if any.
+func (p *Plugin) handlePingEvent(payload *pingPayload) {
+	if payload == nil {
+		return
+	}
+
+	target := payload.Repository.GetFullName()
+	if target == "" {
+		target = payload.Organization.GetLogin()
+	}
+	if target == "" || payload.Hook == nil {
+		return
+	}
+
+	info := parseWebhookInfo(payload.Hook, time.Now())
+	if err := p.storeWebhookInfo(target, info); err != nil {
+		p.API.LogWarn("Failed to store webhook info", "target", target, "error", err.Error())
+	}
+
+	key := hashKey(pendingWebhookKey, webhookTarget(target))
+	channelID, appErr := p.API.KVGet(key)
+	if appErr != nil {
+		p.API.LogWarn("Failed to get pending webhook marker", "target", target, "error", appErr.Error())
+		return
+	}
+	if channelID == nil {
+		return
+	}
+
+	if appErr := p.API.KVDelete(key); appErr != nil {
+		p.API.LogWarn("Failed to delete pending webhook marker", "target", target, "error", appErr.Error())
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: string(channelID),
+		Message:   fmt.Sprintf("Webhook for %s is now active, delivering: %s.", target, strings.Join(info.Events, ", ")),
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to post webhook confirmation", "channelID", post.ChannelId, "error", appErr.Error())
+	}
+}
+
+// formatWebhookInfo describes the webhook of a repository for debugging purposes.
+func formatWebhookInfo(target string, info *WebhookInfo, lastDelivery time.Time) string {
+	var message strings.Builder
+	fmt.Fprintf(&message, "#### Webhook for %s\n", target)
+
+	if info == nil {
+		message.WriteString("No ping was received from a webhook for this repository or its organization. Check that a webhook is configured on GitHub with the URL and secret of this plugin.\n")
+	} else {
+		insecureSSL := "disabled"
+		if info.InsecureSSL {
+			insecureSSL = "enabled"
+		}
+
+		fmt.Fprintf(&message, "* Hook ID: %d\n", info.HookID)
+		fmt.Fprintf(&message, "* Events: %s\n", strings.Join(info.Events, ", "))
+		fmt.Fprintf(&message, "* Content type: %s\n", info.ContentType)
+		fmt.Fprintf(&message, "* Insecure SSL: %s\n", insecureSSL)
+		fmt.Fprintf(&message, "* Last ping: %s\n", info.PingedAt.UTC().Format(time.RFC1123))
+	}
+
+	if lastDelivery.IsZero() {
+		message.WriteString("* Last delivery: never")
+	} else {
+		fmt.Fprintf(&message, "* Last delivery: %s", lastDelivery.UTC().Format(time.RFC1123))
+	}
+
+	return message.String()
+}
@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestParseWebhookInfo(t *testing.T) {
+	now := time.Now()
+
+	info := parseWebhookInfo(&github.Hook{
+		ID:     github.Int64(12),
+		Events: []string{"pull_request", "issues"},
+		Config: map[string]interface{}{"content_type": "json", "insecure_ssl": "1"},
+	}, now)
+	assert.Equal(t, &WebhookInfo{HookID: 12, Events: []string{"pull_request", "issues"}, ContentType: "json", InsecureSSL: true, PingedAt: now}, info)
+
+	info = parseWebhookInfo(&github.Hook{Config: map[string]interface{}{"insecure_ssl": float64(0)}}, now)
+	assert.False(t, info.InsecureSSL)
+}
+
+func parsePingPayload(t *testing.T, body []byte) *pingPayload {
+	payload, err := parsePingEvent(body)
+	require.NoError(t, err)
+	return payload
+}
+
+func TestHandlePingEvent(t *testing.T) {
+	body := []byte(`{
+		"zen": "Keep it logically awesome.",
+		"hook_id": 12,
+		"hook": {"id": 12, "events": ["pull_request", "issues"], "config": {"content_type": "json", "insecure_ssl": "0"}},
+		"repository": {"full_name": "Owner/Repo"}
+	}`)
+	infoKey := hashKey(webhookInfoKey, "owner/repo")
+	pendingKey := hashKey(pendingWebhookKey, "owner/repo")
+
+	isInfo := mock.MatchedBy(func(value []byte) bool {
+		var info WebhookInfo
+		require.NoError(t, json.Unmarshal(value, &info))
+		return info.HookID == 12 && info.ContentType == "json" && !info.InsecureSSL
+	})
+
+	t.Run("no pending subscription", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		api.On("KVSet", infoKey, isInfo).Return(nil).Once()
+		api.On("KVGet", pendingKey).Return(nil, nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.handlePingEvent(parsePingPayload(t, body))
+	})
+
+	t.Run("pending subscription", func(t *testing.T) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		api := &plugintest.API{}
+		api.On("KVSet", infoKey, isInfo).Return(nil).Once()
+		api.On("KVGet", pendingKey).Return([]byte("channelID"), nil).Once()
+		api.On("KVDelete", pendingKey).Return(nil).Once()
+		api.On("CreatePost", &model.Post{
+			UserId:    "botID",
+			ChannelId: "channelID",
+			Message:   "Webhook for Owner/Repo is now active, delivering: pull_request, issues.",
+		}).Return(&model.Post{}, nil).Once()
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.handlePingEvent(parsePingPayload(t, body))
+	})
+
+	t.Run("no repository or organization", func(t *testing.T) {
+		p := NewPlugin()
+		api := &plugintest.API{}
+		p.SetAPI(api)
+		defer api.AssertExpectations(t)
+
+		p.handlePingEvent(parsePingPayload(t, []byte(`{"zen": "Keep it logically awesome."}`)))
+	})
+}
+
+func TestFormatWebhookInfo(t *testing.T) {
+	pingedAt := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	lastDelivery := time.Date(2020, 9, 2, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "#### Webhook for owner/repo\n"+
+		"* Hook ID: 12\n"+
+		"* Events: pull_request, issues\n"+
+		"* Content type: json\n"+
+		"* Insecure SSL: disabled\n"+
+		"* Last ping: Tue, 01 Sep 2020 10:00:00 UTC\n"+
+		"* Last delivery: Wed, 02 Sep 2020 10:00:00 UTC",
+		formatWebhookInfo("owner/repo", &WebhookInfo{HookID: 12, Events: []string{"pull_request", "issues"}, ContentType: "json", PingedAt: pingedAt}, lastDelivery))
+
+	assert.Contains(t, formatWebhookInfo("owner/repo", nil, time.Time{}), "* Last delivery: never")
+}
+
+func TestRecordWebhookDelivery(t *testing.T) {
+	now := time.Date(2020, 9, 1, 10, 0, 0, 0, time.UTC)
+	key := hashKey(webhookDeliveryKey, "owner/repo")
+
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("KVSet", key, []byte("2020-09-01T10:00:00Z")).Return(nil).Once()
+	api.On("KVSet", key, []byte("2020-09-01T10:05:00Z")).Return(nil).Once()
+	p.SetAPI(api)
+	defer api.AssertExpectations(t)
+
+	p.recordWebhookDelivery("Owner/Repo", now)
+	p.recordWebhookDelivery("owner/repo", now.Add(time.Minute))
+	p.recordWebhookDelivery("owner/repo", now.Add(webhookDeliveryWriteInterval))
+}
+
+func TestHandleWebhookCommand(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v3/repos/owner/repo" {
+			fmt.Fprint(w, `{"full_name": "owner/repo"}`)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+	}))
+	defer ts.Close()
+
+	webhookInfo := func(api *plugintest.API, repo string) string {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+
+		userInfo := &GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: "token"}}
+		return p.handleWebhookCommand(nil, &model.CommandArgs{UserId: "userID"}, []string{"info", repo}, userInfo)
+	}
+
+	t.Run("accessible repository", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("KVGet", hashKey(webhookInfoKey, "owner/repo")).Return(nil, nil)
+		api.On("KVGet", hashKey(webhookInfoKey, "owner")).Return(nil, nil)
+		api.On("KVGet", hashKey(webhookDeliveryKey, "owner/repo")).Return(nil, nil)
+		defer api.AssertExpectations(t)
+
+		assert.Contains(t, webhookInfo(api, "owner/repo"), "#### Webhook for owner/repo")
+	})
+
+	t.Run("inaccessible repository", func(t *testing.T) {
+		api := &plugintest.API{}
+		defer api.AssertExpectations(t)
+
+		assert.Equal(t, "Unable to get the webhook info of owner/private. Make sure the repository exists and you have access to it.", webhookInfo(api, "owner/private"))
+	})
+}
@@ -0,0 +1,384 @@
+package plugin
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const pingEventType = "ping"
+
+var errInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// WebhookParser validates the signature of webhook deliveries and parses them into typed events.
+type WebhookParser struct {
+	// Secret is the secret the deliveries are signed with.
+	Secret []byte
+}
+
+// Verify checks that a delivery was signed with the secret. It returns errInvalidWebhookSignature
+// if it wasn't.
+func (wp *WebhookParser) Verify(signature string, body []byte) error {
+	valid, err := verifyWebhookSignature(wp.Secret, signature, body)
+	if err != nil {
+		return errors.Wrap(err, "failed to verify webhook signature")
+	}
+	if !valid {
+		return errInvalidWebhookSignature
+	}
+
+	return nil
+}
+
+// Parse returns the typed event of a delivery. The events go-github can't parse are parsed into
+// the types of this package.
+func (wp *WebhookParser) Parse(eventType string, body []byte) (interface{}, error) {
+	switch eventType {
+	case pingEventType:
+		return parsePingEvent(body)
+	case reviewThreadEventType:
+		return parseReviewThreadEvent(body)
+	case starEventType:
+		return parseStarEvent(body)
+	case workflowRunEventType:
+		return parseWorkflowRunEvent(body)
+	case branchProtectionRuleEventType:
+		return parseBranchProtectionRuleEvent(body)
+	case issuesEventType, pullRequestEventType, repositoryEventType:
+		// go-github doesn't parse where a transferred issue went, the milestone of milestone changes,
+		// nor the changes of a repository edit, so only those actions are parsed into the types of
+		// this package.
+		var delivery struct {
+			Action string `json:"action"`
+		}
+		if err := json.Unmarshal(body, &delivery); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case eventType == repositoryEventType:
+			if delivery.Action == "edited" {
+				return parseRepositoryEditEvent(body)
+			}
+		case eventType == issuesEventType && delivery.Action == "transferred":
+			return parseIssueTransferEvent(body)
+		case delivery.Action == milestoneActionAdded || delivery.Action == milestoneActionRemoved:
+			return parseMilestoneChangeEvent(body)
+		}
+	}
+
+	return github.ParseWebHook(eventType, body)
+}
+
+func (p *Plugin) webhookParser() *WebhookParser {
+	return &WebhookParser{Secret: []byte(p.getConfiguration().WebhookSecret)}
+}
+
+// WebhookEvent is a parsed webhook delivery.
+type WebhookEvent struct {
+	Type   string
+	Action string
+	// Payload is the typed event returned by WebhookParser.
+	Payload interface{}
+	// Replayed events only recreate the posts of the subscriptions, as the personal notifications
+	// were sent when the event was first received.
+	Replayed bool
+	// Delivery is the processing of the delivery of the event, which handlers log with.
+	Delivery *webhookDelivery
+}
+
+func newWebhookEvent(eventType string, payload interface{}, replayed bool) *WebhookEvent {
+	event := &WebhookEvent{Type: eventType, Payload: payload, Replayed: replayed}
+	if withAction, ok := payload.(interface{ GetAction() string }); ok {
+		event.Action = withAction.GetAction()
+	}
+
+	return event
+}
+
+// EventHandler handles the webhook events routed to it.
+type EventHandler func(event *WebhookEvent)
+
+// skipReplayed wraps a handler of events only handled when first received, like the personal notifications.
+func skipReplayed(handler EventHandler) EventHandler {
+	return func(event *WebhookEvent) {
+		if !event.Replayed {
+			handler(event)
+		}
+	}
+}
+
+type eventRoute struct {
+	eventType string
+	actions   map[string]bool
+	handler   EventHandler
+}
+
+func (r *eventRoute) matches(event *WebhookEvent) bool {
+	return r.eventType == event.Type && (len(r.actions) == 0 || r.actions[event.Action])
+}
+
+// EventRouter maps the type and action of the webhook events to their handlers.
+type EventRouter struct {
+	routes []*eventRoute
+}
+
+func NewEventRouter() *EventRouter {
+	return &EventRouter{}
+}
+
+// Handle registers a handler for the events of a type having one of the actions, or any action if
+// none is given. The handlers of an event are called in the order they were registered.
+func (er *EventRouter) Handle(eventType string, handler EventHandler, actions ...string) {
+	route := &eventRoute{eventType: eventType, handler: handler}
+	if len(actions) > 0 {
+		route.actions = map[string]bool{}
+		for _, action := range actions {
+			route.actions[action] = true
+		}
+	}
+
+	er.routes = append(er.routes, route)
+}
+
+// Handles checks if any handler is registered for an event.
+func (er *EventRouter) Handles(event *WebhookEvent) bool {
+	for _, route := range er.routes {
+		if route.matches(event) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Route calls the handlers registered for an event.
+func (er *EventRouter) Route(event *WebhookEvent) {
+	for _, route := range er.routes {
+		if route.matches(event) {
+			route.handler(event)
+		}
+	}
+}
+
+// Renderer renders the posts of a subscription feature.
+type Renderer interface {
+	// Feature returns the feature of the subscriptions the posts are rendered for.
+	Feature() string
+	// Render returns the post of an event for a subscription, or nil if the subscription doesn't get one.
+	Render(event interface{}, sub *Subscription) (*model.Post, error)
+}
+
+// renderSubscriptionPost renders the post of an event for a subscription, ready to be posted in its channel.
+func (p *Plugin) renderSubscriptionPost(renderer Renderer, event interface{}, sub *Subscription, delivery *webhookDelivery) *model.Post {
+	post, err := renderer.Render(event, sub)
+	if err != nil {
+		delivery.recordFiltered(filterReasonRender)
+		delivery.subscriptionLogger(sub).Warnf("Failed to render template: %s", err.Error())
+		return nil
+	}
+	if post == nil {
+		return nil
+	}
+
+	post.UserId = p.BotUserID
+	post.ChannelId = sub.ChannelID
+	return post
+}
+
+// postRenderedEvent posts an event in the channels subscribed to its repository, as rendered for
+// each of their subscriptions.
+func (p *Plugin) postRenderedEvent(renderer Renderer, repo *github.Repository, event interface{}, delivery *webhookDelivery) {
+	for _, sub := range delivery.matchSubscriptions(p.GetSubscribedChannelsForRepository(repo)) {
+		if post := p.renderSubscriptionPost(renderer, event, sub, delivery); post != nil {
+			p.postSubscriptionEvent(post, sub, renderer.Feature(), delivery)
+		}
+	}
+}
+
+// webhookEventRepo returns the repository of an event, and false for the events not about a repository.
+func webhookEventRepo(payload interface{}) (*github.Repository, bool) {
+	switch event := payload.(type) {
+	case *github.PushEvent:
+		return ConvertPushEventRepositoryToRepository(event.GetRepo()), true
+	case interface{ GetRepo() *github.Repository }:
+		return event.GetRepo(), true
+	default:
+		return nil, false
+	}
+}
+
+// newEventRouter registers the handlers of the webhook events supported by the plugin.
+func (p *Plugin) newEventRouter() *EventRouter {
+	router := NewEventRouter()
+
+	router.Handle(pingEventType, skipReplayed(func(e *WebhookEvent) {
+		p.handlePingEvent(e.Payload.(*pingPayload))
+	}))
+	router.Handle("membership", func(e *WebhookEvent) {
+		p.handleMembershipEvent(e.Payload.(*github.MembershipEvent))
+	})
+	router.Handle("organization", func(e *WebhookEvent) {
+		p.handleOrganizationEvent(e.Payload.(*github.OrganizationEvent))
+	})
+	router.Handle(repositoryEventType, func(e *WebhookEvent) {
+		p.handleRepositoryEditEvent(e.Payload.(*RepositoryEditEvent))
+	}, "edited")
+
+	router.Handle("pull_request", func(e *WebhookEvent) {
+		event := e.Payload.(*github.PullRequestEvent)
+		p.postPullRequestEvent(event, e.Delivery)
+	}, "opened", "labeled", "closed")
+	router.Handle("pull_request", skipReplayed(func(e *WebhookEvent) {
+		event, ok := e.Payload.(*github.PullRequestEvent)
+		if !ok {
+			return
+		}
+		p.handlePullRequestNotification(event)
+		p.handlePRDescriptionMentionNotification(event)
+		p.trackReviewRequests(event)
+		p.handleKeywordMentions(event)
+	}))
+	router.Handle(pullRequestEventType, func(e *WebhookEvent) {
+		p.postMilestoneChangeEvent(e.Payload.(*MilestoneChangeEvent), e.Delivery)
+	}, milestoneActionAdded, milestoneActionRemoved)
+
+	router.Handle(issuesEventType, func(e *WebhookEvent) {
+		p.handleIssueTransferEvent(e.Payload.(*IssueTransferEvent), e.Delivery)
+	}, "transferred")
+	router.Handle(issuesEventType, func(e *WebhookEvent) {
+		p.postMilestoneChangeEvent(e.Payload.(*MilestoneChangeEvent), e.Delivery)
+	}, milestoneActionAdded, milestoneActionRemoved)
+	router.Handle(issuesEventType, func(e *WebhookEvent) {
+		if event, ok := e.Payload.(*github.IssuesEvent); ok {
+			p.postIssueEvent(event, e.Delivery)
+			if !e.Replayed {
+				p.handleIssueNotification(event)
+				p.handleKeywordMentions(event)
+			}
+		}
+	})
+
+	router.Handle("issue_comment", func(e *WebhookEvent) {
+		event := e.Payload.(*github.IssueCommentEvent)
+		p.postIssueCommentEvent(event, e.Delivery)
+	}, "created")
+	router.Handle("issue_comment", skipReplayed(func(e *WebhookEvent) {
+		event := e.Payload.(*github.IssueCommentEvent)
+		p.handleCommentMentionNotification(event)
+		p.handleCommentAuthorNotification(event)
+		p.handleKeywordMentions(event)
+	}))
+
+	router.Handle("pull_request_review", func(e *WebhookEvent) {
+		event := e.Payload.(*github.PullRequestReviewEvent)
+		p.postPullRequestReviewEvent(event, e.Delivery)
+	}, "submitted")
+	router.Handle("pull_request_review", skipReplayed(func(e *WebhookEvent) {
+		event := e.Payload.(*github.PullRequestReviewEvent)
+		p.handlePullRequestReviewNotification(event)
+		p.completeReviewRequest(event)
+	}))
+
+	router.Handle("pull_request_review_comment", func(e *WebhookEvent) {
+		event := e.Payload.(*github.PullRequestReviewCommentEvent)
+		p.postPullRequestReviewCommentEvent(event, e.Delivery)
+		if !e.Replayed {
+			p.handleKeywordMentions(event)
+		}
+	})
+
+	router.Handle(reviewThreadEventType, func(e *WebhookEvent) {
+		event := e.Payload.(*PullRequestReviewThreadEvent)
+		p.postReviewThreadEvent(event, e.Delivery)
+		if !e.Replayed {
+			p.handleReviewThreadNotification(event)
+		}
+	})
+
+	router.Handle("push", func(e *WebhookEvent) {
+		p.postPushEvent(e.Payload.(*github.PushEvent), e.Delivery)
+	})
+	router.Handle("create", func(e *WebhookEvent) {
+		p.postCreateEvent(e.Payload.(*github.CreateEvent), e.Delivery)
+	})
+	router.Handle("delete", func(e *WebhookEvent) {
+		p.postDeleteEvent(e.Payload.(*github.DeleteEvent), e.Delivery)
+	})
+	router.Handle("fork", func(e *WebhookEvent) {
+		p.postForkEvent(e.Payload.(*github.ForkEvent), e.Delivery)
+	})
+	router.Handle("gollum", func(e *WebhookEvent) {
+		p.postGollumEvent(e.Payload.(*github.GollumEvent), e.Delivery)
+	})
+	router.Handle(starEventType, func(e *WebhookEvent) {
+		p.postStarEvent(e.Payload.(*StarEvent), e.Delivery)
+	})
+	router.Handle(branchProtectionRuleEventType, func(e *WebhookEvent) {
+		p.postBranchProtectionRuleEvent(e.Payload.(*BranchProtectionRuleEvent), e.Delivery)
+	})
+	router.Handle(workflowRunEventType, func(e *WebhookEvent) {
+		p.postWorkflowFailureEvent(e.Payload.(*WorkflowRunEvent), e.Delivery)
+	}, "completed")
+	router.Handle(workflowRunEventType, skipReplayed(func(e *WebhookEvent) {
+		p.handleWorkflowRunEvent(e.Payload.(*WorkflowRunEvent))
+	}), "completed")
+
+	return router
+}
+
+// processWebhookEvent parses the payload of a webhook event and routes it to its handlers. Events
+// of private repositories are dropped unless they are enabled. A summary of the processing of the
+// delivery is logged at the debug level.
+func (p *Plugin) processWebhookEvent(eventType, deliveryID string, body []byte, replayed bool) error {
+	payload, err := p.webhookParser().Parse(eventType, body)
+	if err != nil {
+		return err
+	}
+
+	p.routeWebhookEvent(eventType, deliveryID, payload, replayed)
+	return nil
+}
+
+// routeWebhookEvent routes the parsed payload of a webhook event to its handlers.
+func (p *Plugin) routeWebhookEvent(eventType, deliveryID string, payload interface{}, replayed bool) {
+	event := newWebhookEvent(eventType, payload, replayed)
+	if !p.eventRouter.Handles(event) {
+		return
+	}
+
+	delivery := p.newWebhookDelivery(deliveryID, eventType, replayed)
+	delivery.Action = event.Action
+	event.Delivery = delivery
+
+	if repo, ok := webhookEventRepo(payload); ok {
+		if repo == nil {
+			return
+		}
+
+		delivery.Repo = repo.GetFullName()
+		if repo.GetPrivate() && !p.getConfiguration().EnablePrivateRepo {
+			delivery.logger(nil).Debugf("Dropped webhook delivery of a private repository")
+			return
+		}
+
+		if !replayed {
+			p.recordWebhookDelivery(repo.GetFullName(), time.Now())
+		}
+	}
+
+	p.eventRouter.Route(event)
+	delivery.logSummary()
+}
+
+// parsePingEvent parses a ping event, whose repository or organization go-github doesn't parse.
+func parsePingEvent(body []byte) (*pingPayload, error) {
+	var payload *pingPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,230 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookParser(t *testing.T) {
+	parser := &WebhookParser{Secret: []byte(testWebhookSecret)}
+	body := []byte(`{"action": "created", "repository": {"full_name": "owner/repo"}}`)
+
+	t.Run("verify", func(t *testing.T) {
+		assert.NoError(t, parser.Verify(signWebhookBody(body), body))
+		assert.Equal(t, errInvalidWebhookSignature, parser.Verify(signWebhookBody([]byte("{}")), body))
+		assert.Equal(t, errInvalidWebhookSignature, parser.Verify("", body))
+		assert.Error(t, parser.Verify("sha1="+strings.Repeat("z", 40), body))
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		for eventType, expected := range map[string]interface{}{
+			"issue_comment":               &github.IssueCommentEvent{},
+			pingEventType:                 &pingPayload{},
+			starEventType:                 &StarEvent{},
+			reviewThreadEventType:         &PullRequestReviewThreadEvent{},
+			workflowRunEventType:          &WorkflowRunEvent{},
+			branchProtectionRuleEventType: &BranchProtectionRuleEvent{},
+			issuesEventType:               &github.IssuesEvent{},
+		} {
+			payload, err := parser.Parse(eventType, body)
+			require.NoError(t, err)
+			assert.IsType(t, expected, payload, eventType)
+		}
+
+		payload, err := parser.Parse(issuesEventType, []byte(`{"action": "transferred", "changes": {"new_issue": {"number": 3}}}`))
+		require.NoError(t, err)
+		assert.IsType(t, &IssueTransferEvent{}, payload)
+
+		for _, eventType := range []string{issuesEventType, pullRequestEventType} {
+			payload, err = parser.Parse(eventType, []byte(`{"action": "milestoned", "milestone": {"title": "v2.0"}}`))
+			require.NoError(t, err)
+			assert.IsType(t, &MilestoneChangeEvent{}, payload, eventType)
+		}
+
+		payload, err = parser.Parse(pullRequestEventType, []byte(`{"action": "transferred"}`))
+		require.NoError(t, err)
+		assert.IsType(t, &github.PullRequestEvent{}, payload)
+
+		payload, err = parser.Parse(repositoryEventType, []byte(`{"action": "edited", "changes": {"description": {"from": "Old"}}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "Old", *payload.(*RepositoryEditEvent).Changes.Description.From)
+
+		_, err = parser.Parse(issuesEventType, []byte(`{`))
+		assert.Error(t, err)
+
+		_, err = parser.Parse("unknown", body)
+		assert.Error(t, err)
+		_, err = parser.Parse(starEventType, []byte(`{"action": 1}`))
+		assert.Error(t, err)
+	})
+}
+
+func TestEventRouter(t *testing.T) {
+	var calls []string
+	handler := func(name string) EventHandler {
+		return func(event *WebhookEvent) {
+			calls = append(calls, name)
+		}
+	}
+
+	router := NewEventRouter()
+	router.Handle("pull_request", handler("posts"), "opened", "closed")
+	router.Handle("pull_request", skipReplayed(handler("notifications")))
+	router.Handle("push", handler("push"))
+
+	route := func(event *WebhookEvent) []string {
+		calls = nil
+		router.Route(event)
+		return calls
+	}
+
+	opened := newWebhookEvent("pull_request", &github.PullRequestEvent{Action: github.String("opened")}, false)
+	assert.Equal(t, "opened", opened.Action)
+	assert.True(t, router.Handles(opened))
+	assert.Equal(t, []string{"posts", "notifications"}, route(opened))
+
+	synchronized := newWebhookEvent("pull_request", &github.PullRequestEvent{Action: github.String("synchronize")}, false)
+	assert.Equal(t, []string{"notifications"}, route(synchronized))
+
+	replayed := newWebhookEvent("pull_request", &github.PullRequestEvent{Action: github.String("closed")}, true)
+	assert.Equal(t, []string{"posts"}, route(replayed))
+
+	assert.Equal(t, []string{"push"}, route(newWebhookEvent("push", &github.PushEvent{}, false)))
+
+	release := newWebhookEvent("release", &github.ReleaseEvent{Action: github.String("published")}, false)
+	assert.False(t, router.Handles(release))
+	assert.Empty(t, route(release))
+}
+
+func TestPullRequestRenderer(t *testing.T) {
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{})
+	api := &plugintest.API{}
+	mockKVStore(api)
+	p.SetAPI(api)
+	renderer := &pullRequestRenderer{p}
+
+	event := &github.PullRequestEvent{
+		Action:      github.String("labeled"),
+		Label:       &github.Label{Name: github.String("bug")},
+		PullRequest: &github.PullRequest{Number: github.Int(42), Title: github.String("Fix"), Labels: []*github.Label{{Name: github.String("bug")}}},
+		Repo:        &github.Repository{FullName: github.String("owner/repo")},
+		Sender:      &github.User{Login: github.String("panda")},
+	}
+
+	for name, test := range map[string]struct {
+		features string
+		posted   bool
+	}{
+		"label":       {`pulls,label:"bug"`, true},
+		"other label": {`pulls,label:"crash"`, false},
+		"no label":    {"pulls", false},
+		"no pulls":    {`issues,label:"bug"`, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			post, err := renderer.Render(event, &Subscription{ChannelID: "channelID", Repository: "owner/repo", Features: test.features})
+			require.NoError(t, err)
+			if !test.posted {
+				assert.Nil(t, post)
+				return
+			}
+
+			require.NotNil(t, post)
+			assert.Equal(t, "custom_git_pr", post.Type)
+			assert.Equal(t, "pull_request.labeled", post.GetProp(postPropEventType))
+		})
+	}
+
+	// Events of other types aren't rendered
+	post, err := renderer.Render(&github.IssuesEvent{}, &Subscription{Features: "pulls"})
+	require.NoError(t, err)
+	assert.Nil(t, post)
+}
+
+var updateGolden = flag.Bool("update", false, "update the golden files of the webhook events")
+
+// goldenPost holds the parts of a post compared by the golden files.
+type goldenPost struct {
+	ChannelID string                 `json:"channel_id"`
+	Type      string                 `json:"type"`
+	Message   string                 `json:"message"`
+	Props     map[string]interface{} `json:"props,omitempty"`
+}
+
+// TestWebhookEventsGolden posts the fixture payloads of testdata/webhooks, named after their event
+// type, to a channel subscribed to every feature, one subscribed to the bug label and one subscribed
+// to the changes of the v2.0 milestone. The posts are compared with the golden files next to the
+// fixtures, which -update rewrites.
+func TestWebhookEventsGolden(t *testing.T) {
+	subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "allChannelID", Repository: "owner/repo", Features: "pulls,issues,pushes,creates,deletes,issue_comments,pull_reviews,branch_protection,forks,stars,wiki,milestone_changes,workflow_failure"},
+			{ChannelID: "bugChannelID", Repository: "owner/repo", Features: `pulls,issues,issue_comments,pull_reviews,label:"bug"`},
+			{ChannelID: "v2ChannelID", Repository: "owner/repo", Features: `milestone_changes:"v2.0"`},
+		},
+	}})
+	require.NoError(t, err)
+
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "webhooks", "*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, fixtures)
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".json")
+		t.Run(name, func(t *testing.T) {
+			body, err := ioutil.ReadFile(fixture)
+			require.NoError(t, err)
+
+			p := NewPlugin()
+			p.BotUserID = "botID"
+			p.setConfiguration(&Configuration{})
+			api := &plugintest.API{}
+			store := mockKVStore(api)
+			store[SubscriptionsKey] = subscriptions
+			api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil).Maybe()
+			api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(true, nil).Maybe()
+			api.On("KVDelete", mock.AnythingOfType("string")).Return(nil).Maybe()
+			api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Maybe()
+			mockDeliveryLogs(api)
+			posts := []*goldenPost{}
+			api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+				posts = append(posts, &goldenPost{ChannelID: post.ChannelId, Type: post.Type, Message: post.Message, Props: post.GetProps()})
+				created := post.Clone()
+				created.Id = model.NewId()
+				return created
+			}, nil)
+			p.SetAPI(api)
+
+			eventType := strings.SplitN(name, ".", 2)[0]
+			require.NoError(t, p.processWebhookEvent(eventType, "", body, false))
+
+			var buffer bytes.Buffer
+			encoder := json.NewEncoder(&buffer)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			require.NoError(t, encoder.Encode(posts))
+			actual := buffer.Bytes()
+
+			golden := strings.TrimSuffix(fixture, ".json") + ".golden"
+			if *updateGolden {
+				require.NoError(t, ioutil.WriteFile(golden, actual, 0600))
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(actual))
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// webhookWorkers is the number of webhook events processed concurrently.
+	webhookWorkers = 8
+	// webhookQueueSize is the number of webhook events waiting to be processed after which new
+	// deliveries are rejected, for GitHub to redeliver them.
+	webhookQueueSize = 1000
+	// webhookRetryAfter is how long GitHub is asked to wait when the queue is full, in seconds.
+	webhookRetryAfter = 30
+	// webhookQueueDrainTimeout is how long the queued events are processed for when the plugin stops.
+	webhookQueueDrainTimeout = 30 * time.Second
+)
+
+var errWebhookQueueFull = errors.New("webhook queue is full")
+
+// webhookJob is a webhook delivery waiting to be processed.
+type webhookJob struct {
+	EventType  string
+	DeliveryID string
+	// Payload is the event parsed when it was received.
+	Payload interface{}
+	// Target is the repository or organization of the event, whose events are processed in order.
+	Target     string
+	ReceivedAt time.Time
+	// Recent is the delivery kept for replaying it, or nil if event replay is disabled.
+	Recent *recentDelivery
+}
+
+// webhookTargetPayload holds the parts of a webhook payload telling what the event is about.
+type webhookTargetPayload struct {
+	Repository *struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	Organization *struct {
+		Login string `json:"login"`
+	} `json:"organization"`
+}
+
+// parseWebhookTarget returns the repository or organization of a webhook payload, or an empty
+// string if it has none. It fails if the payload isn't JSON.
+func parseWebhookTarget(body []byte) (string, error) {
+	var payload webhookTargetPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", err
+	}
+
+	switch {
+	case payload.Repository != nil && payload.Repository.FullName != "":
+		return strings.ToLower(payload.Repository.FullName), nil
+	case payload.Organization != nil:
+		return strings.ToLower(payload.Organization.Login), nil
+	default:
+		return "", nil
+	}
+}
+
+// webhookQueueStats describes the activity of the webhook queue since the plugin started.
+type webhookQueueStats struct {
+	Depth     int
+	Capacity  int
+	Workers   int
+	Processed int64
+	Rejected  int64
+	// AverageWait is the average time events waited in the queue before being processed.
+	AverageWait time.Duration
+	// AverageProcessing and MaxProcessing are the times taken to process events.
+	AverageProcessing time.Duration
+	MaxProcessing     time.Duration
+}
+
+// webhookQueue processes webhook deliveries in a bounded pool of workers. The events of a
+// repository or organization are always processed by the same worker, in the order they were
+// received.
+type webhookQueue struct {
+	workers  []chan *webhookJob
+	capacity int
+	process  func(job *webhookJob)
+	// recovered is called when processing an event panics.
+	recovered func(job *webhookJob, x interface{}, stack []byte)
+
+	// lock guards closed, and the workers channels from being closed while events are enqueued.
+	lock   sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+
+	statsLock sync.Mutex
+	stats     webhookQueueStats
+	totalWait time.Duration
+	totalTime time.Duration
+}
+
+func newWebhookQueue(workers, capacity int, process func(job *webhookJob), recovered func(job *webhookJob, x interface{}, stack []byte)) *webhookQueue {
+	q := &webhookQueue{
+		workers:   make([]chan *webhookJob, workers),
+		capacity:  capacity,
+		process:   process,
+		recovered: recovered,
+		stats:     webhookQueueStats{Capacity: capacity, Workers: workers},
+	}
+
+	for i := range q.workers {
+		// The depth is checked against the capacity before sending, so sends never block
+		q.workers[i] = make(chan *webhookJob, capacity)
+		q.wg.Add(1)
+		go q.work(q.workers[i])
+	}
+
+	return q
+}
+
+// worker returns the worker processing the events of a target. Events without a target are spread
+// over the workers by their delivery.
+func (q *webhookQueue) worker(job *webhookJob) chan *webhookJob {
+	key := job.Target
+	if key == "" {
+		key = job.DeliveryID
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return q.workers[h.Sum32()%uint32(len(q.workers))]
+}
+
+// enqueue queues a delivery to be processed. It fails if the queue is full or closed.
+func (q *webhookQueue) enqueue(job *webhookJob) error {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+	if q.closed {
+		return errors.New("webhook queue is closed")
+	}
+
+	q.statsLock.Lock()
+	if q.stats.Depth >= q.capacity {
+		q.stats.Rejected++
+		q.statsLock.Unlock()
+		return errWebhookQueueFull
+	}
+	q.stats.Depth++
+	q.statsLock.Unlock()
+
+	q.worker(job) <- job
+	return nil
+}
+
+func (q *webhookQueue) work(jobs chan *webhookJob) {
+	defer q.wg.Done()
+	for job := range jobs {
+		start := time.Now()
+		q.run(job)
+		q.record(start.Sub(job.ReceivedAt), time.Since(start))
+	}
+}
+
+// run processes an event, recovering from panics so the worker keeps processing the next ones.
+func (q *webhookQueue) run(job *webhookJob) {
+	defer func() {
+		if x := recover(); x != nil {
+			q.recovered(job, x, debug.Stack())
+		}
+	}()
+
+	q.process(job)
+}
+
+func (q *webhookQueue) record(wait, processing time.Duration) {
+	q.statsLock.Lock()
+	defer q.statsLock.Unlock()
+
+	q.stats.Depth--
+	q.stats.Processed++
+	q.totalWait += wait
+	q.totalTime += processing
+	if processing > q.stats.MaxProcessing {
+		q.stats.MaxProcessing = processing
+	}
+}
+
+// getStats returns the activity of the queue.
+func (q *webhookQueue) getStats() webhookQueueStats {
+	q.statsLock.Lock()
+	defer q.statsLock.Unlock()
+
+	stats := q.stats
+	if stats.Processed > 0 {
+		stats.AverageWait = q.totalWait / time.Duration(stats.Processed)
+		stats.AverageProcessing = q.totalTime / time.Duration(stats.Processed)
+	}
+
+	return stats
+}
+
+// close stops accepting deliveries and waits for the queued ones to be processed, at most for the
+// given timeout. It returns false if some weren't processed in time.
+func (q *webhookQueue) close(timeout time.Duration) bool {
+	q.lock.Lock()
+	if !q.closed {
+		q.closed = true
+		for _, jobs := range q.workers {
+			close(jobs)
+		}
+	}
+	q.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// startWebhookQueue starts the workers processing the webhook deliveries.
+func (p *Plugin) startWebhookQueue() {
+	p.webhookQueue = newWebhookQueue(webhookWorkers, webhookQueueSize, func(job *webhookJob) {
+		p.recordRecentDelivery(job.Recent)
+		p.routeWebhookEvent(job.EventType, job.DeliveryID, job.Payload, false)
+	}, func(job *webhookJob, x interface{}, stack []byte) {
+		p.API.LogError("Recovered from a panic processing a webhook event",
+			"event", job.EventType,
+			"delivery", job.DeliveryID,
+			"error", x,
+			"stack", string(stack))
+	})
+}
+
+// stopWebhookQueue processes the queued webhook deliveries and stops the workers.
+func (p *Plugin) stopWebhookQueue() {
+	if p.webhookQueue == nil {
+		return
+	}
+
+	if !p.webhookQueue.close(webhookQueueDrainTimeout) {
+		p.API.LogWarn("Timed out processing the queued webhook events", "left", p.webhookQueue.getStats().Depth)
+	}
+}
+
+func formatWebhookQueueStats(stats webhookQueueStats) string {
+	return fmt.Sprintf("#### Webhook queue\n"+
+		"* Queued events: %d of at most %d\n"+
+		"* Workers: %d\n"+
+		"* Processed events: %d\n"+
+		"* Rejected events: %d\n"+
+		"* Average wait in the queue: %s\n"+
+		"* Average processing time: %s\n"+
+		"* Longest processing time: %s",
+		stats.Depth, stats.Capacity, stats.Workers, stats.Processed, stats.Rejected,
+		stats.AverageWait.Round(time.Millisecond), stats.AverageProcessing.Round(time.Millisecond), stats.MaxProcessing.Round(time.Millisecond))
+}
+
+func (p *Plugin) handleAdminWebhookQueue() string {
+	if p.webhookQueue == nil {
+		return "The webhook queue isn't running."
+	}
+
+	return formatWebhookQueueStats(p.webhookQueue.getStats())
+}
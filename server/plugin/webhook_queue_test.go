@@ -0,0 +1,224 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWebhookTarget(t *testing.T) {
+	for name, test := range map[string]struct {
+		body     string
+		expected string
+	}{
+		"repository":          {`{"repository": {"full_name": "Owner/Repo"}, "organization": {"login": "owner"}}`, "owner/repo"},
+		"organization":        {`{"organization": {"login": "Owner"}}`, "owner"},
+		"neither":             {`{"zen": "Keep it logically awesome."}`, ""},
+		"repository unparsed": {`{"repository": {}, "organization": {"login": "owner"}}`, "owner"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			target, err := parseWebhookTarget([]byte(test.body))
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, target)
+		})
+	}
+
+	_, err := parseWebhookTarget([]byte("payload=%7B%7D"))
+	assert.Error(t, err)
+}
+
+func TestWebhookQueue(t *testing.T) {
+	noPanic := func(t *testing.T) func(*webhookJob, interface{}, []byte) {
+		return func(job *webhookJob, x interface{}, _ []byte) {
+			t.Errorf("unexpected panic processing %s: %v", job.DeliveryID, x)
+		}
+	}
+
+	t.Run("full queue", func(t *testing.T) {
+		release := make(chan struct{})
+		q := newWebhookQueue(2, 3, func(job *webhookJob) { <-release }, noPanic(t))
+
+		for i := 0; i < 3; i++ {
+			require.NoError(t, q.enqueue(&webhookJob{DeliveryID: fmt.Sprintf("delivery-%d", i), ReceivedAt: time.Now()}))
+		}
+		assert.Equal(t, errWebhookQueueFull, q.enqueue(&webhookJob{DeliveryID: "delivery-3", ReceivedAt: time.Now()}))
+
+		stats := q.getStats()
+		assert.Equal(t, 3, stats.Depth)
+		assert.Equal(t, int64(1), stats.Rejected)
+
+		close(release)
+		require.True(t, q.close(time.Second))
+
+		stats = q.getStats()
+		assert.Equal(t, 0, stats.Depth)
+		assert.Equal(t, int64(3), stats.Processed)
+		assert.Error(t, q.enqueue(&webhookJob{DeliveryID: "delivery-4"}))
+	})
+
+	t.Run("events of a repository are processed in order", func(t *testing.T) {
+		var lock sync.Mutex
+		processed := map[string][]int{}
+		q := newWebhookQueue(4, 1000, func(job *webhookJob) {
+			// Slow events must not be overtaken by the next ones of their repository
+			var n int
+			_, _ = fmt.Sscanf(job.DeliveryID, "%d", &n)
+			if n%7 == 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			processed[job.Target] = append(processed[job.Target], n)
+		}, noPanic(t))
+
+		repos := []string{"owner/a", "owner/b", "owner/c", "owner/d", "owner/e"}
+		for i := 0; i < 200; i++ {
+			require.NoError(t, q.enqueue(&webhookJob{DeliveryID: fmt.Sprintf("%d", i), Target: repos[i%len(repos)], ReceivedAt: time.Now()}))
+		}
+		require.True(t, q.close(5*time.Second))
+
+		for i, repo := range repos {
+			require.Len(t, processed[repo], 40)
+			for j, n := range processed[repo] {
+				assert.Equal(t, i+j*len(repos), n, "events of %s out of order", repo)
+			}
+		}
+	})
+
+	t.Run("panics are recovered", func(t *testing.T) {
+		var recovered []string
+		q := newWebhookQueue(1, 10, func(job *webhookJob) {
+			if job.DeliveryID == "bad" {
+				panic("nil pointer")
+			}
+		}, func(job *webhookJob, x interface{}, stack []byte) {
+			recovered = append(recovered, fmt.Sprintf("%s: %v", job.DeliveryID, x))
+			assert.NotEmpty(t, stack)
+		})
+
+		require.NoError(t, q.enqueue(&webhookJob{DeliveryID: "bad", Target: "owner/repo", ReceivedAt: time.Now()}))
+		require.NoError(t, q.enqueue(&webhookJob{DeliveryID: "good", Target: "owner/repo", ReceivedAt: time.Now()}))
+		require.True(t, q.close(time.Second))
+
+		assert.Equal(t, []string{"bad: nil pointer"}, recovered)
+		assert.Equal(t, int64(2), q.getStats().Processed)
+	})
+
+	t.Run("drain timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		q := newWebhookQueue(1, 10, func(job *webhookJob) { <-release }, noPanic(t))
+
+		require.NoError(t, q.enqueue(&webhookJob{DeliveryID: "slow", ReceivedAt: time.Now()}))
+		assert.False(t, q.close(10*time.Millisecond))
+	})
+}
+
+func TestHandleWebhookQueue(t *testing.T) {
+	body := []byte(`{"zen": "Keep it logically awesome.", "repository": {"full_name": "owner/repo"}}`)
+
+	setupPlugin := func(q *webhookQueue) (*Plugin, *plugintest.API) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{WebhookSecret: testWebhookSecret})
+		api := &plugintest.API{}
+		api.On("KVGet", hashKey(webhookSignatureFailuresKey, "owner/repo")).Return(nil, nil).Maybe()
+		p.SetAPI(api)
+		p.webhookQueue = q
+		return p, api
+	}
+
+	t.Run("queued", func(t *testing.T) {
+		var jobs []*webhookJob
+		q := newWebhookQueue(1, 10, func(job *webhookJob) { jobs = append(jobs, job) }, nil)
+		p, api := setupPlugin(q)
+		defer api.AssertExpectations(t)
+
+		w := httptest.NewRecorder()
+		p.handleWebhook(w, newWebhookRequest(bytes.NewReader(body), signWebhookBody(body)))
+		require.True(t, q.close(time.Second))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "ping", jobs[0].EventType)
+		assert.Equal(t, "delivery-id", jobs[0].DeliveryID)
+		assert.Equal(t, "owner/repo", jobs[0].Target)
+		assert.IsType(t, &pingPayload{}, jobs[0].Payload)
+	})
+
+	t.Run("queue full", func(t *testing.T) {
+		release := make(chan struct{})
+		q := newWebhookQueue(1, 1, func(job *webhookJob) { <-release }, nil)
+		defer q.close(time.Second)
+		defer close(release)
+		p, api := setupPlugin(q)
+		api.On("LogWarn", "Rejected webhook event", "event", "ping", "delivery", "delivery-id", "error", errWebhookQueueFull.Error()).Once()
+		defer api.AssertExpectations(t)
+
+		w := httptest.NewRecorder()
+		p.handleWebhook(w, newWebhookRequest(bytes.NewReader(body), signWebhookBody(body)))
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		p.handleWebhook(w, newWebhookRequest(bytes.NewReader(body), signWebhookBody(body)))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		q := newWebhookQueue(1, 10, func(job *webhookJob) { t.Error("invalid payload queued") }, nil)
+		p, api := setupPlugin(q)
+		api.On("LogDebug", "GitHub webhook content type should be set to \"application/json\"", "error", "invalid character 'p' looking for beginning of value").Once()
+		defer api.AssertExpectations(t)
+
+		form := []byte("payload=%7B%7D")
+		w := httptest.NewRecorder()
+		p.handleWebhook(w, newWebhookRequest(bytes.NewReader(form), signWebhookBody(form)))
+		require.True(t, q.close(time.Second))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("unparseable event", func(t *testing.T) {
+		q := newWebhookQueue(1, 10, func(job *webhookJob) { t.Error("unparseable event queued") }, nil)
+		p, api := setupPlugin(q)
+		api.On("LogDebug", "GitHub webhook content type should be set to \"application/json\"", "error", mock.AnythingOfType("string")).Once()
+		defer api.AssertExpectations(t)
+
+		star := []byte(`{"action": 1, "repository": {"full_name": "owner/repo"}}`)
+		req := newWebhookRequest(bytes.NewReader(star), signWebhookBody(star))
+		req.Header.Set("X-GitHub-Event", starEventType)
+		w := httptest.NewRecorder()
+		p.handleWebhook(w, req)
+		require.True(t, q.close(time.Second))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestFormatWebhookQueueStats(t *testing.T) {
+	message := formatWebhookQueueStats(webhookQueueStats{
+		Depth:             3,
+		Capacity:          1000,
+		Workers:           8,
+		Processed:         120,
+		Rejected:          2,
+		AverageWait:       1500 * time.Microsecond,
+		AverageProcessing: 42 * time.Millisecond,
+		MaxProcessing:     2 * time.Second,
+	})
+
+	assert.Contains(t, message, "* Queued events: 3 of at most 1000\n")
+	assert.Contains(t, message, "* Rejected events: 2\n")
+	assert.Contains(t, message, "* Average wait in the queue: 2ms\n")
+	assert.Contains(t, message, "* Longest processing time: 2s")
+}
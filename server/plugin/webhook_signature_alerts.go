@@ -0,0 +1,221 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	webhookSignatureFailuresKey = "_sigfailures"
+	webhookSignatureAlertKey    = "webhook_signature_alert"
+
+	// webhookSignatureFailureThreshold is how many deliveries of a repository or organization are
+	// rejected in a row, within webhookSignatureFailureWindow, before the admins are alerted.
+	webhookSignatureFailureThreshold = 5
+	webhookSignatureFailureWindow    = time.Hour
+	// webhookSignatureAlertTTL is how long the admins aren't alerted again, whatever the repository or
+	// organization, in seconds.
+	webhookSignatureAlertTTL = 24 * 60 * 60
+
+	webhookSignatureFailureUpdateAttempts = 3
+	adminsPerPage                         = 100
+)
+
+// webhookSignatureFailures counts the deliveries of a repository or organization rejected in a row
+// because of their signature.
+type webhookSignatureFailures struct {
+	Count int       `json:"count"`
+	Since time.Time `json:"since"`
+}
+
+// add counts a rejected delivery. The count starts over once the window since the first counted
+// delivery is over.
+func (f *webhookSignatureFailures) add(now time.Time) {
+	if f.Since.IsZero() || now.Sub(f.Since) > webhookSignatureFailureWindow {
+		f.Count = 0
+		f.Since = now
+	}
+	f.Count++
+}
+
+// getWebhookTargetSubscriptions returns the subscriptions to a repository, or to its organization,
+// or to an organization.
+func (p *Plugin) getWebhookTargetSubscriptions(target string) ([]*Subscription, error) {
+	if strings.Contains(target, "/") {
+		owner := strings.Split(target, "/")[0]
+		return p.getIndexedSubscriptions(target, fullNameFromOwnerAndRepo(owner, ""))
+	}
+
+	return p.getIndexedSubscriptions(fullNameFromOwnerAndRepo(target, ""))
+}
+
+// recordWebhookSignatureFailure counts a delivery rejected because of its signature, which usually
+// means the secret of the webhook on GitHub doesn't match the one of the plugin. Past the threshold,
+// the admins and the creators of the subscriptions are alerted at most once a day.
+//
+// The payload isn't trusted: its repository only keys the count, and only if a channel is
+// subscribed to it and deliveries of it were verified before. Forged deliveries can't make the
+// plugin alert about repositories whose webhook never reached it.
+func (p *Plugin) recordWebhookSignatureFailure(body []byte, now time.Time) {
+	target, err := parseWebhookTarget(body)
+	if err != nil || target == "" {
+		return
+	}
+
+	subs, err := p.getWebhookTargetSubscriptions(target)
+	if err != nil {
+		p.API.LogWarn("Failed to get subscriptions", "target", target, "error", err.Error())
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	lastDelivery, err := p.getLastWebhookDelivery(target)
+	if err != nil {
+		p.API.LogWarn("Failed to get last webhook delivery", "target", target, "error", err.Error())
+		return
+	}
+	if lastDelivery.IsZero() {
+		return
+	}
+
+	count, err := p.incrementWebhookSignatureFailures(target, now)
+	if err != nil {
+		p.API.LogWarn("Failed to count rejected webhook delivery", "target", target, "error", err.Error())
+		return
+	}
+	if count < webhookSignatureFailureThreshold {
+		return
+	}
+
+	claimed, appErr := p.API.KVSetWithOptions(webhookSignatureAlertKey, []byte(now.UTC().Format(time.RFC3339)), model.PluginKVSetOptions{
+		Atomic:          true,
+		OldValue:        nil,
+		ExpireInSeconds: webhookSignatureAlertTTL,
+	})
+	if appErr != nil {
+		p.API.LogWarn("Failed to store webhook signature alert", "target", target, "error", appErr.Error())
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	p.API.LogWarn("Webhook deliveries rejected because of their signature", "target", target, "count", count)
+	p.sendWebhookSignatureAlert(target, count, subs)
+}
+
+func (p *Plugin) incrementWebhookSignatureFailures(target string, now time.Time) (int, error) {
+	key := hashKey(webhookSignatureFailuresKey, target)
+
+	for attempt := 0; attempt < webhookSignatureFailureUpdateAttempts; attempt++ {
+		oldValue, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return 0, errors.Wrap(appErr, "could not get signature failures from KV store")
+		}
+
+		failures := &webhookSignatureFailures{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, failures); err != nil {
+				return 0, errors.Wrap(err, "could not unmarshal signature failures")
+			}
+		}
+
+		failures.add(now)
+
+		newValue, err := json.Marshal(failures)
+		if err != nil {
+			return 0, errors.Wrap(err, "could not marshal signature failures")
+		}
+
+		stored, appErr := p.API.KVCompareAndSet(key, oldValue, newValue)
+		if appErr != nil {
+			return 0, errors.Wrap(appErr, "could not store signature failures in KV store")
+		}
+		if stored {
+			return failures.Count, nil
+		}
+	}
+
+	return 0, errors.New("too many concurrent updates of the signature failures")
+}
+
+// resetWebhookSignatureFailures starts the count of rejected deliveries over once a delivery of a
+// repository or organization is accepted.
+func (p *Plugin) resetWebhookSignatureFailures(target string) {
+	if target == "" {
+		return
+	}
+
+	key := hashKey(webhookSignatureFailuresKey, target)
+	value, appErr := p.API.KVGet(key)
+	if appErr != nil || value == nil {
+		return
+	}
+
+	if appErr := p.API.KVDelete(key); appErr != nil {
+		p.API.LogWarn("Failed to reset signature failures", "target", target, "error", appErr.Error())
+	}
+}
+
+// getSystemAdminIDs returns the IDs of the active system admins.
+func (p *Plugin) getSystemAdminIDs() ([]string, error) {
+	userIDs := []string{}
+	for page := 0; ; page++ {
+		users, appErr := p.API.GetUsers(&model.UserGetOptions{
+			Role:    model.SYSTEM_ADMIN_ROLE_ID,
+			Active:  true,
+			Page:    page,
+			PerPage: adminsPerPage,
+		})
+		if appErr != nil {
+			return nil, errors.Wrap(appErr, "could not get system admins")
+		}
+
+		for _, user := range users {
+			userIDs = append(userIDs, user.Id)
+		}
+		if len(users) < adminsPerPage {
+			return userIDs, nil
+		}
+	}
+}
+
+func (p *Plugin) sendWebhookSignatureAlert(target string, count int, subs []*Subscription) {
+	userIDs, err := p.getSystemAdminIDs()
+	if err != nil {
+		p.API.LogWarn("Failed to get system admins", "error", err.Error())
+	}
+	for _, sub := range subs {
+		if sub.CreatorID != "" && !containsValue(userIDs, sub.CreatorID) {
+			userIDs = append(userIDs, sub.CreatorID)
+		}
+	}
+
+	message := p.formatWebhookSignatureAlert(target, count)
+	for _, userID := range userIDs {
+		p.CreateBotDMPost(userID, message, "")
+	}
+}
+
+func (p *Plugin) formatWebhookSignatureAlert(target string, count int) string {
+	settingsURL := p.getBaseURL() + target + "/settings/hooks"
+	repository := target
+	if !strings.Contains(target, "/") {
+		settingsURL = p.getBaseURL() + "organizations/" + target + "/settings/hooks"
+		repository = "owner/repo"
+	}
+
+	return fmt.Sprintf("#### GitHub webhook deliveries of %s are rejected\n"+
+		"The last %d deliveries of the webhook of `%s` were rejected because they weren't signed with the **Webhook Secret** of the plugin settings. "+
+		"The secret of the [webhook on GitHub](%s) likely doesn't match it anymore, for example after it was regenerated. "+
+		"Until it does, the events of `%s` aren't posted in the subscribed channels.\n\n"+
+		"Set the secret of the webhook to the **Webhook Secret** of the plugin settings, then check the webhook with `/github setup test %s`.",
+		target, count, target, settingsURL, target, repository)
+}
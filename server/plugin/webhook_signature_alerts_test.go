@@ -0,0 +1,177 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSignatureFailuresAdd(t *testing.T) {
+	now := time.Date(2021, 3, 5, 12, 0, 0, 0, time.UTC)
+	failures := &webhookSignatureFailures{}
+
+	failures.add(now)
+	failures.add(now.Add(30 * time.Minute))
+	assert.Equal(t, &webhookSignatureFailures{Count: 2, Since: now}, failures)
+
+	// The count starts over after the window
+	failures.add(now.Add(61 * time.Minute))
+	assert.Equal(t, &webhookSignatureFailures{Count: 1, Since: now.Add(61 * time.Minute)}, failures)
+}
+
+func TestRecordWebhookSignatureFailure(t *testing.T) {
+	body := []byte(`{"action": "opened", "repository": {"full_name": "Owner/Repo"}}`)
+	now := time.Date(2021, 3, 5, 12, 0, 0, 0, time.UTC)
+
+	setup := func(t *testing.T) (*Plugin, *plugintest.API, map[string][]byte, map[string][]string) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{WebhookSecret: testWebhookSecret})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+			"owner/repo": {
+				{ChannelID: "channelID", CreatorID: "creatorID", Repository: "owner/repo"},
+				{ChannelID: "otherChannelID", CreatorID: "adminID", Repository: "owner/repo"},
+			},
+			"owner/other":       {{ChannelID: "channelID", CreatorID: "creatorID", Repository: "owner/other"}},
+			"owner/unconfirmed": {{ChannelID: "channelID", CreatorID: "creatorID", Repository: "owner/unconfirmed"}},
+		}})
+		require.NoError(t, err)
+		store[SubscriptionsKey] = subscriptions
+		store[hashKey(webhookDeliveryKey, "owner/repo")] = []byte(now.Add(-time.Hour).Format(time.RFC3339))
+		store[hashKey(webhookDeliveryKey, "owner/other")] = []byte(now.Add(-time.Hour).Format(time.RFC3339))
+
+		api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(func(key string, value []byte, options model.PluginKVSetOptions) bool {
+			if options.Atomic && options.OldValue == nil && store[key] != nil {
+				return false
+			}
+			store[key] = value
+			return true
+		}, nil)
+		api.On("KVDelete", mock.AnythingOfType("string")).Return(func(key string) *model.AppError {
+			delete(store, key)
+			return nil
+		})
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		api.On("GetUsers", &model.UserGetOptions{Role: model.SYSTEM_ADMIN_ROLE_ID, Active: true, Page: 0, PerPage: adminsPerPage}).Return([]*model.User{{Id: "adminID"}}, nil)
+
+		dms := map[string][]string{}
+		api.On("GetDirectChannel", mock.AnythingOfType("string"), "botID").Return(func(userID, botID string) *model.Channel {
+			return &model.Channel{Id: userID + "DM"}
+		}, nil)
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			dms[post.ChannelId] = append(dms[post.ChannelId], post.Message)
+			return post
+		}, nil)
+		p.SetAPI(api)
+
+		return p, api, store, dms
+	}
+
+	t.Run("threshold", func(t *testing.T) {
+		p, _, _, dms := setup(t)
+
+		for i := 0; i < webhookSignatureFailureThreshold-1; i++ {
+			p.recordWebhookSignatureFailure(body, now.Add(time.Duration(i)*time.Minute))
+		}
+		assert.Empty(t, dms)
+
+		p.recordWebhookSignatureFailure(body, now.Add(10*time.Minute))
+		require.Len(t, dms, 2)
+		require.Len(t, dms["adminIDDM"], 1)
+		require.Len(t, dms["creatorIDDM"], 1)
+		assert.Contains(t, dms["adminIDDM"][0], "#### GitHub webhook deliveries of owner/repo are rejected")
+		assert.Contains(t, dms["adminIDDM"][0], "The last 5 deliveries of the webhook of `owner/repo`")
+		assert.Contains(t, dms["adminIDDM"][0], "[webhook on GitHub](https://github.com/owner/repo/settings/hooks)")
+		assert.Contains(t, dms["adminIDDM"][0], "`/github setup test owner/repo`")
+	})
+
+	t.Run("failures spread over more than the window", func(t *testing.T) {
+		p, _, _, dms := setup(t)
+
+		for i := 0; i < webhookSignatureFailureThreshold; i++ {
+			p.recordWebhookSignatureFailure(body, now.Add(time.Duration(i)*20*time.Minute))
+		}
+		assert.Empty(t, dms)
+	})
+
+	t.Run("alerted once a day", func(t *testing.T) {
+		p, _, store, dms := setup(t)
+
+		for i := 0; i < 2*webhookSignatureFailureThreshold; i++ {
+			p.recordWebhookSignatureFailure(body, now.Add(time.Duration(i)*time.Minute))
+		}
+		assert.Len(t, dms["adminIDDM"], 1)
+
+		// Whatever the repository
+		for i := 0; i < webhookSignatureFailureThreshold; i++ {
+			p.recordWebhookSignatureFailure([]byte(`{"repository": {"full_name": "owner/other"}}`), now.Add(time.Duration(i)*time.Minute))
+		}
+		assert.Len(t, dms["adminIDDM"], 1)
+
+		// The alert marker expires after a day
+		delete(store, webhookSignatureAlertKey)
+		p.recordWebhookSignatureFailure(body, now.Add(24*time.Hour))
+		assert.Empty(t, store[webhookSignatureAlertKey])
+		for i := 0; i < webhookSignatureFailureThreshold; i++ {
+			p.recordWebhookSignatureFailure(body, now.Add(24*time.Hour+time.Duration(i)*time.Minute))
+		}
+		assert.Len(t, dms["adminIDDM"], 2)
+	})
+
+	t.Run("reset by an accepted delivery", func(t *testing.T) {
+		p, _, store, dms := setup(t)
+		api := p.API.(*plugintest.API)
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.webhookQueue = newWebhookQueue(1, 10, func(job *webhookJob) {}, nil)
+
+		deliver := func(signature string) int {
+			w := httptest.NewRecorder()
+			p.handleWebhook(w, newWebhookRequest(bytes.NewReader(body), signature))
+			return w.Code
+		}
+
+		for i := 0; i < webhookSignatureFailureThreshold-1; i++ {
+			assert.Equal(t, http.StatusUnauthorized, deliver(signWebhookBody([]byte("{}"))))
+		}
+		require.NotNil(t, store[hashKey(webhookSignatureFailuresKey, "owner/repo")])
+
+		assert.Equal(t, http.StatusOK, deliver(signWebhookBody(body)))
+		require.True(t, p.webhookQueue.close(time.Second))
+		assert.Nil(t, store[hashKey(webhookSignatureFailuresKey, "owner/repo")])
+
+		assert.Equal(t, http.StatusUnauthorized, deliver(signWebhookBody([]byte("{}"))))
+		assert.Empty(t, dms)
+	})
+
+	t.Run("unsubscribed repository", func(t *testing.T) {
+		p, _, store, dms := setup(t)
+
+		for i := 0; i < webhookSignatureFailureThreshold; i++ {
+			p.recordWebhookSignatureFailure([]byte(`{"repository": {"full_name": "someone/else"}}`), now)
+			p.recordWebhookSignatureFailure([]byte(`not json`), now)
+		}
+		assert.Nil(t, store[hashKey(webhookSignatureFailuresKey, "someone/else")])
+		assert.Empty(t, dms)
+	})
+
+	t.Run("repository without verified deliveries", func(t *testing.T) {
+		p, _, store, dms := setup(t)
+
+		for i := 0; i < webhookSignatureFailureThreshold; i++ {
+			p.recordWebhookSignatureFailure([]byte(`{"repository": {"full_name": "owner/unconfirmed"}}`), now)
+		}
+		assert.Nil(t, store[hashKey(webhookSignatureFailuresKey, "owner/unconfirmed")])
+		assert.Empty(t, dms)
+	})
+}
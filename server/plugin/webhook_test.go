@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // GitHub webhooks are signed using sha1 https://developer.github.com/webhooks/.
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+const testWebhookSecret = "secret"
+
+// slowReader returns its data only after a delay.
+type slowReader struct {
+	delay time.Duration
+	data  io.Reader
+}
+
+func (r *slowReader) Read(b []byte) (int, error) {
+	time.Sleep(r.delay)
+	return r.data.Read(b)
+}
+
+func signWebhookBody(body []byte) string {
+	mac := hmac.New(sha1.New, []byte(testWebhookSecret))
+	_, _ = mac.Write(body)
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookRequest(body io.Reader, signature string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "ping")
+	req.Header.Set("X-GitHub-Delivery", "delivery-id")
+	req.Header.Set("X-Hub-Signature", signature)
+	return req
+}
+
+func TestHandleWebhookPayloadLimits(t *testing.T) {
+	limit := 1024 * 1024
+
+	// pingBody returns a ping payload of exactly the given size.
+	pingBody := func(size int) []byte {
+		prefix, suffix := `{"zen":"`, `"}`
+		return []byte(prefix + strings.Repeat("a", size-len(prefix)-len(suffix)) + suffix)
+	}
+
+	setupPlugin := func() (*Plugin, *plugintest.API) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{
+			WebhookSecret:           testWebhookSecret,
+			WebhookPayloadSizeLimit: 1,
+		})
+		api := &plugintest.API{}
+		mockDeliveryLogs(api)
+		p.SetAPI(api)
+		return p, api
+	}
+
+	t.Run("payload near the limit", func(t *testing.T) {
+		p, api := setupPlugin()
+		defer api.AssertExpectations(t)
+
+		body := pingBody(limit)
+		w := httptest.NewRecorder()
+		p.handleWebhook(w, newWebhookRequest(bytes.NewReader(body), signWebhookBody(body)))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("oversized payload", func(t *testing.T) {
+		p, api := setupPlugin()
+		api.On("LogWarn", "Webhook payload too large", "event", "ping", "delivery", "delivery-id", "limit", int64(limit)).Once()
+		defer api.AssertExpectations(t)
+
+		body := pingBody(limit + 1)
+		w := httptest.NewRecorder()
+		p.handleWebhook(w, newWebhookRequest(bytes.NewReader(body), signWebhookBody(body)))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+
+	t.Run("slow payload", func(t *testing.T) {
+		defer func(timeout time.Duration) { webhookReadTimeout = timeout }(webhookReadTimeout)
+		webhookReadTimeout = 50 * time.Millisecond
+
+		p, api := setupPlugin()
+		api.On("LogWarn", "Timed out reading webhook payload", "event", "ping", "delivery", "delivery-id").Once()
+		defer api.AssertExpectations(t)
+
+		body := pingBody(100)
+		w := httptest.NewRecorder()
+		p.handleWebhook(w, newWebhookRequest(&slowReader{delay: 500 * time.Millisecond, data: bytes.NewReader(body)}, signWebhookBody(body)))
+
+		assert.Equal(t, http.StatusRequestTimeout, w.Code)
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		p, api := setupPlugin()
+		defer api.AssertExpectations(t)
+
+		body := pingBody(100)
+		w := httptest.NewRecorder()
+		p.handleWebhook(w, newWebhookRequest(bytes.NewReader(body), "sha1=0000000000000000000000000000000000000000"))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
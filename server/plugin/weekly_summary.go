@@ -0,0 +1,334 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/pkg/errors"
+)
+
+const (
+	weeklySummaryJobKey = "weekly_summary"
+	weeklySummaryKey    = "_weeklysummary"
+	// weeklySummaryUsersKey holds the IDs of the users asking for a weekly summary, so that the job
+	// doesn't list the whole KV store to find them.
+	weeklySummaryUsersKey = "weekly_summary_users"
+	// weeklySummaryUsersUpdateAttempts is the number of times a concurrent update of the users is retried.
+	weeklySummaryUsersUpdateAttempts = 5
+	// defaultWeeklySummarySchedule is when the weekly summary is sent if the user doesn't pick a time.
+	defaultWeeklySummarySchedule = "sunday 18:00"
+
+	// weeklySummaryTopItems is the number of items listed in each section of a summary.
+	weeklySummaryTopItems = 3
+)
+
+// weeklySummarySection is a count of items of a summary, along with the top ones.
+type weeklySummarySection struct {
+	digestCount
+	Items []*github.Issue
+}
+
+// weeklySummary is the personal summary of the past week of a user.
+type weeklySummary struct {
+	From            time.Time
+	To              time.Time
+	MergedPRs       weeklySummarySection
+	Reviews         weeklySummarySection
+	ClosedIssues    weeklySummarySection
+	AwaitingReviews weeklySummarySection
+}
+
+// isEmpty tells if there is nothing to report in the summary.
+func (s *weeklySummary) isEmpty() bool {
+	for _, section := range []weeklySummarySection{s.MergedPRs, s.Reviews, s.ClosedIssues, s.AwaitingReviews} {
+		if section.Count > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// getWeeklySummarySchedule returns when the weekly summary of a user is due, or nil if they don't get one.
+func getWeeklySummarySchedule(settings *UserSettings) *weeklySchedule {
+	if settings == nil || !settings.WeeklySummary {
+		return nil
+	}
+
+	value := settings.WeeklySummarySchedule
+	if value == "" {
+		value = defaultWeeklySummarySchedule
+	}
+
+	schedule, err := parseWeeklySchedule(value)
+	if err != nil {
+		return nil
+	}
+	return schedule
+}
+
+// getWeeklySummaryUsers returns the IDs of the users asking for a weekly summary, along with the
+// stored value they were read from. The value is nil if the users were never listed.
+func (p *Plugin) getWeeklySummaryUsers() ([]string, []byte, error) {
+	value, appErr := p.API.KVGet(weeklySummaryUsersKey)
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "could not get weekly summary users from KV store")
+	}
+
+	userIDs := []string{}
+	if value != nil {
+		if err := json.Unmarshal(value, &userIDs); err != nil {
+			return nil, nil, errors.Wrap(err, "could not unmarshal weekly summary users")
+		}
+	}
+
+	return userIDs, value, nil
+}
+
+// updateWeeklySummaryUsers applies update to the IDs of the users asking for a weekly summary,
+// retrying if they are changed concurrently. Nothing is stored until the users were listed once by
+// listWeeklySummaryUsers, as the users asking for a summary until then are found by it.
+func (p *Plugin) updateWeeklySummaryUsers(update func(userIDs []string) []string) error {
+	for i := 0; i < weeklySummaryUsersUpdateAttempts; i++ {
+		userIDs, oldValue, err := p.getWeeklySummaryUsers()
+		if err != nil {
+			return err
+		}
+		if oldValue == nil {
+			return nil
+		}
+
+		newValue, err := json.Marshal(update(userIDs))
+		if err != nil {
+			return errors.Wrap(err, "could not marshal weekly summary users")
+		}
+		if bytes.Equal(oldValue, newValue) {
+			return nil
+		}
+
+		ok, appErr := p.API.KVCompareAndSet(weeklySummaryUsersKey, oldValue, newValue)
+		if appErr != nil {
+			return errors.Wrap(appErr, "could not store weekly summary users")
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	return errors.New("weekly summary users were changed concurrently too many times")
+}
+
+// addWeeklySummaryUser adds a user to the users getting a weekly summary if they ask for one.
+func (p *Plugin) addWeeklySummaryUser(info *GitHubUserInfo) {
+	if getWeeklySummarySchedule(info.Settings) == nil {
+		return
+	}
+
+	err := p.updateWeeklySummaryUsers(func(userIDs []string) []string {
+		if SliceContainsString(userIDs, info.UserID) {
+			return userIDs
+		}
+		return append(userIDs, info.UserID)
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to add weekly summary user", "userID", info.UserID, "error", err.Error())
+	}
+}
+
+// listWeeklySummaryUsers returns the IDs of the users asking for a weekly summary. The first time,
+// they are found by listing the connected users, and stored for the next times.
+func (p *Plugin) listWeeklySummaryUsers() ([]string, error) {
+	userIDs, value, err := p.getWeeklySummaryUsers()
+	if err != nil || value != nil {
+		return userIDs, err
+	}
+
+	connected, err := p.listConnectedUserIDs()
+	if err != nil {
+		return nil, err
+	}
+	for _, userID := range connected {
+		if info, apiErr := p.getGitHubUserInfo(userID); apiErr == nil && getWeeklySummarySchedule(info.Settings) != nil {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	newValue, err := json.Marshal(userIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal weekly summary users")
+	}
+	ok, appErr := p.API.KVCompareAndSet(weeklySummaryUsersKey, nil, newValue)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "could not store weekly summary users")
+	}
+	if !ok {
+		// Listed concurrently by another server
+		userIDs, _, err = p.getWeeklySummaryUsers()
+		return userIDs, err
+	}
+
+	return userIDs, nil
+}
+
+// postWeeklySummaries sends the weekly summaries that are due. It's run periodically by the scheduler.
+// The users who no longer ask for a summary are forgotten.
+func (p *Plugin) postWeeklySummaries() {
+	now := time.Now().UTC()
+
+	userIDs, err := p.listWeeklySummaryUsers()
+	if err != nil {
+		p.API.LogWarn("Failed to list weekly summary users", "error", err.Error())
+		return
+	}
+
+	stopped := map[string]bool{}
+	for _, userID := range userIDs {
+		subscribed, err := p.postWeeklySummaryIfDue(userID, now)
+		if err != nil {
+			p.API.LogWarn("Failed to post weekly summary", "userID", userID, "error", err.Error())
+		}
+		if !subscribed {
+			stopped[userID] = true
+		}
+	}
+	if len(stopped) == 0 {
+		return
+	}
+
+	err = p.updateWeeklySummaryUsers(func(userIDs []string) []string {
+		kept := []string{}
+		for _, userID := range userIDs {
+			if !stopped[userID] {
+				kept = append(kept, userID)
+			}
+		}
+		return kept
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to remove weekly summary users", "error", err.Error())
+	}
+}
+
+// postWeeklySummaryIfDue sends the weekly summary of a user if it's due. It returns whether the user
+// still asks for weekly summaries.
+func (p *Plugin) postWeeklySummaryIfDue(userID string, now time.Time) (bool, error) {
+	info, apiErr := p.getGitHubUserInfo(userID)
+	if apiErr != nil {
+		if apiErr.ID == apiErrorIDNotConnected {
+			return false, nil
+		}
+		return true, errors.Wrap(apiErr, "failed to get user info")
+	}
+
+	schedule := getWeeklySummarySchedule(info.Settings)
+	if schedule == nil {
+		return false, nil
+	}
+
+	due := schedule.lastOccurrence(now)
+	if now.Sub(due) > weeklyDigestGracePeriod || p.getLastWeeklySummary(userID) >= due.Unix() {
+		return true, nil
+	}
+
+	// The summary is marked as sent first, so that a restart doesn't send it twice
+	if appErr := p.API.KVSet(userID+weeklySummaryKey, []byte(strconv.FormatInt(due.Unix(), 10))); appErr != nil {
+		return true, errors.Wrap(appErr, "failed to store weekly summary time")
+	}
+
+	summary := p.getWeeklySummary(context.Background(), p.githubConnect(*info.Token), info.GitHubUsername, due.AddDate(0, 0, -7), due)
+	if summary.isEmpty() {
+		return true, nil
+	}
+
+	message, err := renderTemplate("weeklySummary", summary)
+	if err != nil {
+		return true, errors.Wrap(err, "failed to render template")
+	}
+
+	p.CreateBotDMPost(userID, message, "custom_git_weekly_summary")
+	return true, nil
+}
+
+// getLastWeeklySummary returns the unix time of the period end of the last summary sent to a user.
+func (p *Plugin) getLastWeeklySummary(userID string) int64 {
+	value, appErr := p.API.KVGet(userID + weeklySummaryKey)
+	if appErr != nil || value == nil {
+		return 0
+	}
+
+	last, _ := strconv.ParseInt(string(value), 10, 64)
+	return last
+}
+
+// getWeeklySummary computes the summary of a user for the given period, scoped to the organization
+// the plugin is locked to if any. Sections that fail to be computed are marked as unavailable.
+func (p *Plugin) getWeeklySummary(ctx context.Context, githubClient *github.Client, username string, from, to time.Time) *weeklySummary {
+	scope := ""
+	if org := strings.TrimSpace(p.getConfiguration().GitHubOrg); org != "" {
+		scope = " org:" + org
+	}
+	period := fmt.Sprintf("%s..%s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+
+	return &weeklySummary{
+		From:            from,
+		To:              to,
+		MergedPRs:       p.searchWeeklySummarySection(ctx, githubClient, fmt.Sprintf("is:pr is:merged author:%s merged:%s%s", username, period, scope)),
+		Reviews:         p.searchWeeklySummarySection(ctx, githubClient, fmt.Sprintf("is:pr reviewed-by:%s -author:%s updated:%s%s", username, username, period, scope)),
+		ClosedIssues:    p.searchWeeklySummarySection(ctx, githubClient, fmt.Sprintf("is:issue is:closed assignee:%s closed:%s%s", username, period, scope)),
+		AwaitingReviews: p.searchWeeklySummarySection(ctx, githubClient, fmt.Sprintf("is:pr is:open review-requested:%s archived:false%s", username, scope)),
+	}
+}
+
+func (p *Plugin) searchWeeklySummarySection(ctx context.Context, githubClient *github.Client, query string) weeklySummarySection {
+	result, _, err := githubClient.Search.Issues(ctx, query, &github.SearchOptions{
+		Sort:        "updated",
+		Order:       "desc",
+		ListOptions: github.ListOptions{PerPage: weeklySummaryTopItems},
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to search for weekly summary", "query", query, "error", err.Error())
+		return weeklySummarySection{}
+	}
+
+	items := result.Issues
+	if len(items) > weeklySummaryTopItems {
+		items = items[:weeklySummaryTopItems]
+	}
+
+	return weeklySummarySection{
+		digestCount: digestCount{Count: result.GetTotal(), Available: true},
+		Items:       items,
+	}
+}
+
+// handleWeeklySummarySetting updates the weekly summary settings of a user from the parameters of
+// `/github settings weekly-summary on|off [day hh:mm]`, and returns the message to show.
+func handleWeeklySummarySetting(settings *UserSettings, parameters []string) (string, error) {
+	switch parameters[0] {
+	case settingOff:
+		if len(parameters) != 1 {
+			return "", errors.New("Invalid value. Use `/github settings weekly-summary off`.")
+		}
+		settings.WeeklySummary = false
+		return "You will no longer get a weekly summary.", nil
+	case settingOn:
+		schedule := defaultWeeklySummarySchedule
+		if len(parameters) > 1 {
+			schedule = strings.Join(parameters[1:], " ")
+		}
+		if _, err := parseWeeklySchedule(schedule); err != nil {
+			return "", errors.Errorf("Invalid weekly summary schedule %q. Use a day and a time in UTC, e.g. `sunday 18:00`.", schedule)
+		}
+
+		settings.WeeklySummary = true
+		settings.WeeklySummarySchedule = strings.ToLower(schedule)
+		return fmt.Sprintf("You will get a weekly summary every %s (UTC).", settings.WeeklySummarySchedule), nil
+	default:
+		return "", errors.New("Invalid value. Accepted values are: \"on [day hh:mm]\" or \"off\".")
+	}
+}
@@ -0,0 +1,272 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestHandleWeeklySummarySetting(t *testing.T) {
+	settings := &UserSettings{}
+
+	message, err := handleWeeklySummarySetting(settings, []string{"on"})
+	require.NoError(t, err)
+	assert.Equal(t, "You will get a weekly summary every sunday 18:00 (UTC).", message)
+	assert.Equal(t, &UserSettings{WeeklySummary: true, WeeklySummarySchedule: "sunday 18:00"}, settings)
+
+	message, err = handleWeeklySummarySetting(settings, []string{"on", "Friday", "16:30"})
+	require.NoError(t, err)
+	assert.Equal(t, "You will get a weekly summary every friday 16:30 (UTC).", message)
+	assert.Equal(t, "friday 16:30", settings.WeeklySummarySchedule)
+
+	_, err = handleWeeklySummarySetting(settings, []string{"on", "someday", "16:30"})
+	assert.EqualError(t, err, "Invalid weekly summary schedule \"someday 16:30\". Use a day and a time in UTC, e.g. `sunday 18:00`.")
+	assert.Equal(t, "friday 16:30", settings.WeeklySummarySchedule)
+
+	message, err = handleWeeklySummarySetting(settings, []string{"off"})
+	require.NoError(t, err)
+	assert.Equal(t, "You will no longer get a weekly summary.", message)
+	assert.False(t, settings.WeeklySummary)
+
+	_, err = handleWeeklySummarySetting(settings, []string{"maybe"})
+	assert.Error(t, err)
+}
+
+func TestGetWeeklySummarySchedule(t *testing.T) {
+	assert.Nil(t, getWeeklySummarySchedule(nil))
+	assert.Nil(t, getWeeklySummarySchedule(&UserSettings{WeeklySummarySchedule: "monday 09:00"}))
+	assert.Equal(t, &weeklySchedule{Weekday: time.Sunday, Hour: 18}, getWeeklySummarySchedule(&UserSettings{WeeklySummary: true}))
+	assert.Equal(t, &weeklySchedule{Weekday: time.Monday, Hour: 9}, getWeeklySummarySchedule(&UserSettings{WeeklySummary: true, WeeklySummarySchedule: "monday 09:00"}))
+}
+
+func TestGetWeeklySummary(t *testing.T) {
+	from := time.Date(2020, 9, 6, 18, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("q") {
+		case "is:pr is:merged author:alice merged:2020-09-06T18:00:00Z..2020-09-13T18:00:00Z org:mattermost":
+			fmt.Fprint(w, `{"total_count": 4, "items": [
+				{"number": 1, "title": "First"},
+				{"number": 2, "title": "Second"},
+				{"number": 3, "title": "Third"},
+				{"number": 4, "title": "Fourth"}
+			]}`)
+		case "is:pr reviewed-by:alice -author:alice updated:2020-09-06T18:00:00Z..2020-09-13T18:00:00Z org:mattermost":
+			fmt.Fprint(w, `{"total_count": 0, "items": []}`)
+		case "is:pr is:open review-requested:alice archived:false org:mattermost":
+			fmt.Fprint(w, `{"total_count": 1, "items": [{"number": 5, "title": "Fifth"}]}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	client := newTestGitHubClient(t, mux)
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{GitHubOrg: "mattermost"})
+	api := &plugintest.API{}
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	p.SetAPI(api)
+
+	summary := p.getWeeklySummary(context.Background(), client, "alice", from, to)
+
+	assert.Equal(t, digestCount{Count: 4, Available: true}, summary.MergedPRs.digestCount)
+	assert.Len(t, summary.MergedPRs.Items, weeklySummaryTopItems)
+	assert.Equal(t, digestCount{Count: 0, Available: true}, summary.Reviews.digestCount)
+	assert.Equal(t, digestCount{Available: false}, summary.ClosedIssues.digestCount)
+	assert.Equal(t, digestCount{Count: 1, Available: true}, summary.AwaitingReviews.digestCount)
+	assert.False(t, summary.isEmpty())
+
+	assert.True(t, (&weeklySummary{Reviews: weeklySummarySection{digestCount: digestCount{Available: true}}}).isEmpty())
+}
+
+func TestWeeklySummaryTemplate(t *testing.T) {
+	summary := &weeklySummary{
+		From: time.Date(2020, 9, 6, 18, 0, 0, 0, time.UTC),
+		To:   time.Date(2020, 9, 13, 18, 0, 0, 0, time.UTC),
+		MergedPRs: weeklySummarySection{
+			digestCount: digestCount{Count: 1, Available: true},
+			Items: []*github.Issue{{
+				Number:  github.Int(1),
+				Title:   github.String("First"),
+				HTMLURL: github.String("https://github.com/owner/repo/pull/1"),
+			}},
+		},
+		Reviews:         weeklySummarySection{digestCount: digestCount{Count: 0, Available: true}},
+		AwaitingReviews: weeklySummarySection{digestCount: digestCount{Count: 2, Available: true}},
+	}
+
+	expected := `
+#### Your GitHub week
+Sep 6 - Sep 13, 2020
+##### Merged pull requests: 1
+* [#1 First](https://github.com/owner/repo/pull/1)
+##### Reviewed pull requests: 0
+##### Closed issues: unavailable
+##### Awaiting your review: 2
+`
+
+	actual, err := renderTemplate("weeklySummary", summary)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+func TestPostWeeklySummaryIfDue(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+
+	now := time.Date(2020, 9, 13, 18, 10, 0, 0, time.UTC)
+	due := time.Date(2020, 9, 13, 18, 0, 0, 0, time.UTC)
+
+	setup := func(t *testing.T, settings *UserSettings, last []byte, totalCount int) (*Plugin, *plugintest.API) {
+		info, err := json.Marshal(&GitHubUserInfo{UserID: "userID", Token: &oauth2.Token{AccessToken: token}, GitHubUsername: "alice", Settings: settings})
+		require.NoError(t, err)
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.True(t, strings.HasPrefix(r.URL.Path, "/api/v3/search/issues"))
+			fmt.Fprintf(w, `{"total_count": %d, "items": []}`, totalCount)
+		}))
+		t.Cleanup(ts.Close)
+
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL, EnterpriseUploadURL: ts.URL})
+		api := &plugintest.API{}
+		api.On("KVGet", "userID"+githubTokenKey).Return(info, nil)
+		api.On("KVGet", "userID"+weeklySummaryKey).Return(last, nil)
+		p.SetAPI(api)
+
+		return p, api
+	}
+
+	t.Run("summary sent", func(t *testing.T) {
+		p, api := setup(t, &UserSettings{WeeklySummary: true}, nil, 2)
+		api.On("KVSet", "userID"+weeklySummaryKey, []byte(strconv.FormatInt(due.Unix(), 10))).Return(nil).Once()
+		api.On("GetDirectChannel", "userID", "botID").Return(&model.Channel{Id: "dmID"}, nil)
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "dmID" && post.Type == "custom_git_weekly_summary" && strings.Contains(post.Message, "##### Merged pull requests: 2")
+		})).Return(&model.Post{}, nil).Once()
+		defer api.AssertExpectations(t)
+
+		subscribed, err := p.postWeeklySummaryIfDue("userID", now)
+		require.NoError(t, err)
+		assert.True(t, subscribed)
+	})
+
+	t.Run("nothing to report", func(t *testing.T) {
+		p, api := setup(t, &UserSettings{WeeklySummary: true}, nil, 0)
+		api.On("KVSet", "userID"+weeklySummaryKey, []byte(strconv.FormatInt(due.Unix(), 10))).Return(nil).Once()
+		defer api.AssertExpectations(t)
+
+		subscribed, err := p.postWeeklySummaryIfDue("userID", now)
+		require.NoError(t, err)
+		assert.True(t, subscribed)
+	})
+
+	t.Run("already sent", func(t *testing.T) {
+		p, api := setup(t, &UserSettings{WeeklySummary: true}, []byte(strconv.FormatInt(due.Unix(), 10)), 2)
+		defer api.AssertExpectations(t)
+
+		subscribed, err := p.postWeeklySummaryIfDue("userID", now)
+		require.NoError(t, err)
+		assert.True(t, subscribed)
+	})
+
+	t.Run("past the grace period", func(t *testing.T) {
+		p, api := setup(t, &UserSettings{WeeklySummary: true}, nil, 2)
+
+		subscribed, err := p.postWeeklySummaryIfDue("userID", now.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.True(t, subscribed)
+		api.AssertNotCalled(t, "KVSet", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not opted in", func(t *testing.T) {
+		p, api := setup(t, &UserSettings{}, nil, 2)
+
+		subscribed, err := p.postWeeklySummaryIfDue("userID", now)
+		require.NoError(t, err)
+		assert.False(t, subscribed)
+		api.AssertNotCalled(t, "KVGet", "userID"+weeklySummaryKey)
+	})
+}
+
+func TestWeeklySummaryUsers(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+
+	setup := func(t *testing.T) (*Plugin, *plugintest.API, map[string][]byte) {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		p.SetAPI(api)
+
+		return p, api, store
+	}
+	storeUser := func(t *testing.T, store map[string][]byte, userID string, settings *UserSettings) {
+		info, err := json.Marshal(&GitHubUserInfo{UserID: userID, Token: &oauth2.Token{AccessToken: token}, GitHubUsername: userID, Settings: settings})
+		require.NoError(t, err)
+		store[userID+githubTokenKey] = info
+	}
+
+	t.Run("listed once", func(t *testing.T) {
+		p, api, store := setup(t)
+		storeUser(t, store, "alice", &UserSettings{WeeklySummary: true})
+		storeUser(t, store, "bob", &UserSettings{})
+		api.On("KVList", 0, refreshAllPageSize).Return([]string{"alice" + githubTokenKey, "bob" + githubTokenKey, "other"}, nil).Once()
+
+		userIDs, err := p.listWeeklySummaryUsers()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice"}, userIDs)
+
+		userIDs, err = p.listWeeklySummaryUsers()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice"}, userIDs)
+		api.AssertNumberOfCalls(t, "KVList", 1)
+	})
+
+	t.Run("added", func(t *testing.T) {
+		p, _, store := setup(t)
+		store[weeklySummaryUsersKey] = []byte(`["alice"]`)
+
+		p.addWeeklySummaryUser(&GitHubUserInfo{UserID: "bob", Settings: &UserSettings{WeeklySummary: true}})
+		p.addWeeklySummaryUser(&GitHubUserInfo{UserID: "bob", Settings: &UserSettings{WeeklySummary: true}})
+		p.addWeeklySummaryUser(&GitHubUserInfo{UserID: "carol", Settings: &UserSettings{}})
+		assert.Equal(t, `["alice","bob"]`, string(store[weeklySummaryUsersKey]))
+	})
+
+	t.Run("not added before being listed", func(t *testing.T) {
+		p, _, store := setup(t)
+
+		p.addWeeklySummaryUser(&GitHubUserInfo{UserID: "bob", Settings: &UserSettings{WeeklySummary: true}})
+		assert.Nil(t, store[weeklySummaryUsersKey])
+	})
+
+	t.Run("removed when they stop", func(t *testing.T) {
+		p, _, store := setup(t)
+		storeUser(t, store, "bob", &UserSettings{})
+		store[weeklySummaryUsersKey] = []byte(`["gone","bob"]`)
+
+		p.postWeeklySummaries()
+		assert.Equal(t, `[]`, string(store[weeklySummaryUsersKey]))
+	})
+}
@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// welcomeMessageData is what a custom welcome message is rendered with.
+type welcomeMessageData struct {
+	GitHubUsername string
+	GitHubURL      string
+	SiteURL        string
+}
+
+// parseWelcomeMessage parses the CustomWelcomeMessage setting. It returns nil if no custom welcome message is set.
+func parseWelcomeMessage(raw string) (*template.Template, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	tmpl, err := masterTemplate.Clone()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to clone templates")
+	}
+
+	tmpl, err = tmpl.New("customWelcomeMessage").Parse(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse custom welcome message")
+	}
+
+	return tmpl, nil
+}
+
+// defaultWelcomeMessage is the welcome message sent when no custom one is configured.
+func defaultWelcomeMessage(data *welcomeMessageData, restoredNotice, commandHelp string) string {
+	return fmt.Sprintf("#### Welcome to the Mattermost GitHub Plugin!\n"+
+		"You've connected your Mattermost account to [%s](%s) on GitHub. Read about the features of this plugin below:\n\n"+
+		"%s"+
+		"##### Daily Reminders\n"+
+		"The first time you log in each day, you will get a post right here letting you know what messages you need to read and what pull requests are awaiting your review.\n"+
+		"Turn off reminders with `/github settings reminders off`.\n\n"+
+		"##### Notifications\n"+
+		"When someone mentions you, requests your review, comments on or modifies one of your pull requests/issues, or assigns you, you'll get a post here about it.\n"+
+		"Turn off notifications with `/github settings notifications off`.\n\n"+
+		"##### Sidebar Buttons\n"+
+		"Check out the buttons in the left-hand sidebar of Mattermost.\n"+
+		"* The first button tells you how many pull requests you have submitted.\n"+
+		"* The second shows the number of PR that are awaiting your review.\n"+
+		"* The third shows the number of PR and issues your are assiged to.\n"+
+		"* The fourth tracks the number of unread messages you have.\n"+
+		"* The fifth will refresh the numbers.\n\n"+
+		"Click on them!\n\n"+
+		"##### Slash Commands\n"+
+		commandHelp, data.GitHubUsername, data.GitHubURL, restoredNotice)
+}
+
+// getWelcomeMessage returns the message sent to a user who just connected their GitHub account. The
+// custom welcome message replaces the built-in one if set, followed by the slash command help unless
+// it's suppressed. The built-in message is used if the custom one fails to render.
+func (p *Plugin) getWelcomeMessage(githubUsername, githubURL, restoredNotice string) string {
+	config := p.getConfiguration()
+
+	commandHelp, err := renderTemplate("helpText", config)
+	if err != nil {
+		p.API.LogWarn("Failed to render help template", "error", err.Error())
+	}
+
+	data := &welcomeMessageData{
+		GitHubUsername: githubUsername,
+		GitHubURL:      githubURL,
+	}
+	if config.welcomeMessage == nil {
+		return defaultWelcomeMessage(data, restoredNotice, commandHelp)
+	}
+
+	if siteURL := p.API.GetConfig().ServiceSettings.SiteURL; siteURL != nil {
+		data.SiteURL = *siteURL
+	}
+
+	var buf bytes.Buffer
+	if err := config.welcomeMessage.ExecuteTemplate(&buf, "customWelcomeMessage", data); err != nil {
+		p.API.LogWarn("Failed to render the custom welcome message", "error", err.Error())
+		return defaultWelcomeMessage(data, restoredNotice, commandHelp)
+	}
+
+	message := strings.TrimSpace(buf.String()) + "\n\n" + restoredNotice
+	if !config.SuppressHelpInWelcome {
+		message += "##### Slash Commands\n" + commandHelp
+	}
+
+	return strings.TrimSpace(message)
+}
@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWelcomeMessage(t *testing.T) {
+	tmpl, err := parseWelcomeMessage("  \n")
+	require.NoError(t, err)
+	assert.Nil(t, tmpl)
+
+	tmpl, err = parseWelcomeMessage("Welcome {{.GitHubUsername}}!")
+	require.NoError(t, err)
+	assert.NotNil(t, tmpl)
+
+	_, err = parseWelcomeMessage("Welcome {{.GitHubUsername")
+	assert.Error(t, err)
+}
+
+func TestGetWelcomeMessage(t *testing.T) {
+	setup := func(config *Configuration) (*Plugin, *plugintest.API) {
+		welcomeMessage, err := parseWelcomeMessage(config.CustomWelcomeMessage)
+		require.NoError(t, err)
+		config.welcomeMessage = welcomeMessage
+
+		p := NewPlugin()
+		p.setConfiguration(config)
+		api := &plugintest.API{}
+		siteURL := "https://mattermost.example.com"
+		api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		p.SetAPI(api)
+		return p, api
+	}
+
+	t.Run("default", func(t *testing.T) {
+		p, _ := setup(&Configuration{})
+		message := p.getWelcomeMessage("alice", "https://github.com/alice", "")
+
+		assert.Contains(t, message, "#### Welcome to the Mattermost GitHub Plugin!\nYou've connected your Mattermost account to [alice](https://github.com/alice) on GitHub.")
+		assert.Contains(t, message, "##### Slash Commands\n* `/github connect`")
+	})
+
+	t.Run("custom with placeholders", func(t *testing.T) {
+		p, _ := setup(&Configuration{CustomWelcomeMessage: "Hi [{{.GitHubUsername}}]({{.GitHubURL}}), see {{.SiteURL}}/docs for the rules.\n"})
+		message := p.getWelcomeMessage("alice", "https://github.com/alice", "Settings restored.\n\n")
+
+		assert.True(t, strings.HasPrefix(message, "Hi [alice](https://github.com/alice), see https://mattermost.example.com/docs for the rules.\n\nSettings restored.\n\n##### Slash Commands\n* `/github connect`"), message)
+		assert.NotContains(t, message, "Welcome to the Mattermost GitHub Plugin")
+	})
+
+	t.Run("custom without help", func(t *testing.T) {
+		p, _ := setup(&Configuration{CustomWelcomeMessage: "Hi {{.GitHubUsername}}", SuppressHelpInWelcome: true})
+
+		assert.Equal(t, "Hi alice", p.getWelcomeMessage("alice", "https://github.com/alice", ""))
+	})
+
+	t.Run("custom failing to render", func(t *testing.T) {
+		p, api := setup(&Configuration{CustomWelcomeMessage: "Hi {{.Unknown}}"})
+		api.On("LogWarn", "Failed to render the custom welcome message", "error", mock.AnythingOfType("string")).Once()
+
+		message := p.getWelcomeMessage("alice", "https://github.com/alice", "")
+		assert.Contains(t, message, "#### Welcome to the Mattermost GitHub Plugin!")
+		api.AssertExpectations(t)
+	})
+}
+
+func TestInvalidWelcomeMessageConfiguration(t *testing.T) {
+	p := NewPlugin()
+	api := &plugintest.API{}
+	api.On("LoadPluginConfiguration", mock.AnythingOfType("*plugin.Configuration")).Return(func(dest interface{}) error {
+		dest.(*Configuration).CustomWelcomeMessage = "Hi {{.GitHubUsername"
+		return nil
+	})
+	p.SetAPI(api)
+
+	err := p.OnConfigurationChange()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid custom welcome message")
+}
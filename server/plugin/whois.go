@@ -0,0 +1,104 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// getConnectedUserInfo returns the stored info of a connected user, with their token left encrypted,
+// or nil if the user isn't connected.
+func (p *Plugin) getConnectedUserInfo(userID string) *GitHubUserInfo {
+	infoBytes, appErr := p.API.KVGet(userID + githubTokenKey)
+	if appErr != nil || infoBytes == nil {
+		return nil
+	}
+
+	var info GitHubUserInfo
+	if err := json.Unmarshal(infoBytes, &info); err != nil {
+		p.API.LogWarn("Failed to unmarshal GitHub user info", "userID", userID, "error", err.Error())
+		return nil
+	}
+
+	return &info
+}
+
+// handleWhois tells if a Mattermost user, given as an @mention, is connected to GitHub, or which
+// Mattermost user a GitHub login is connected to. Only discoverable users are shown; the others are
+// reported as not connected, so that whois doesn't tell that their connection exists.
+func (p *Plugin) handleWhois(_ *plugin.Context, _ *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) != 1 {
+		return "Please use `/github whois @mattermost-user` or `/github whois github-login`."
+	}
+
+	name := parameters[0]
+	if strings.HasPrefix(name, "@") {
+		return p.whoisMattermostUser(strings.TrimPrefix(name, "@"), userInfo)
+	}
+
+	return p.whoisGitHubLogin(name, userInfo)
+}
+
+func (p *Plugin) whoisMattermostUser(username string, userInfo *GitHubUserInfo) string {
+	user, appErr := p.API.GetUserByUsername(username)
+	if appErr != nil {
+		return fmt.Sprintf("There is no Mattermost user @%s.", username)
+	}
+
+	info := p.getConnectedUserInfo(user.Id)
+	if info == nil || info.Settings == nil || !info.Settings.isDiscoverable() {
+		return fmt.Sprintf("@%s hasn't connected their GitHub account.", user.Username)
+	}
+
+	return p.formatWhois(user, info, userInfo)
+}
+
+func (p *Plugin) whoisGitHubLogin(login string, userInfo *GitHubUserInfo) string {
+	userID := p.getGitHubToUserIDMapping(login)
+	if userID == "" {
+		return fmt.Sprintf("`%s` isn't connected to a Mattermost account.", login)
+	}
+
+	// The mapping is left behind when a user connects another account
+	info := p.getConnectedUserInfo(userID)
+	if info == nil || !strings.EqualFold(info.GitHubUsername, login) || info.Settings == nil || !info.Settings.isDiscoverable() {
+		return fmt.Sprintf("`%s` isn't connected to a Mattermost account.", login)
+	}
+
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		p.API.LogWarn("Failed to get user", "userID", userID, "error", appErr.Error())
+		return fmt.Sprintf("Encountered an error getting the Mattermost user of `%s`.", login)
+	}
+
+	return p.formatWhois(user, info, userInfo)
+}
+
+// formatWhois describes the GitHub connection of a user. Their membership of the organization the
+// plugin is locked to is checked with the client of the asking user, so that private memberships
+// are only shown to those who can see them on GitHub.
+func (p *Plugin) formatWhois(user *model.User, info, askingUserInfo *GitHubUserInfo) string {
+	message := fmt.Sprintf("@%s is connected to GitHub as [%s](%s).", user.Username, info.GitHubUsername, p.getBaseURL()+info.GitHubUsername)
+
+	org := strings.TrimSpace(p.getConfiguration().GitHubOrg)
+	if org == "" {
+		return message
+	}
+
+	isMember, _, err := p.getGithubClient(askingUserInfo).Organizations.IsMember(context.Background(), org, info.GitHubUsername)
+	switch {
+	case err != nil:
+		p.API.LogWarn("Failed to check if user is org member", "GitHub username", info.GitHubUsername, "org", org, "error", err.Error())
+		message += fmt.Sprintf("\nCouldn't check if they are a member of the %s organization.", org)
+	case isMember:
+		message += fmt.Sprintf("\nThey are a member of the %s organization.", org)
+	default:
+		message += fmt.Sprintf("\nThey aren't a member of the %s organization, or their membership isn't visible to you.", org)
+	}
+
+	return message
+}
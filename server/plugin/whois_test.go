@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestHandleWhois(t *testing.T) {
+	var memberChecks []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		memberChecks = append(memberChecks, r.Header.Get("Authorization")+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/api/v3/orgs/org/members/bob-gh":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+		}
+	}))
+	defer ts.Close()
+
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	askerInfo := &GitHubUserInfo{UserID: "askerID", Token: &oauth2.Token{AccessToken: "asker-token"}, GitHubUsername: "asker"}
+	discoverable := false
+
+	setup := func(t *testing.T, org string) *Plugin {
+		p := NewPlugin()
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, GitHubOrg: org, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		for userID, info := range map[string]*GitHubUserInfo{
+			"bobID":   {UserID: "bobID", GitHubUsername: "bob-gh", Token: &oauth2.Token{AccessToken: "encrypted"}, Settings: &UserSettings{}},
+			"carolID": {UserID: "carolID", GitHubUsername: "carol-gh", Token: &oauth2.Token{AccessToken: "encrypted"}, Settings: &UserSettings{Discoverable: &discoverable}},
+			"daveID":  {UserID: "daveID", GitHubUsername: "dave-gh", Token: &oauth2.Token{AccessToken: "encrypted"}, Settings: &UserSettings{}},
+		} {
+			value, err := json.Marshal(info)
+			require.NoError(t, err)
+			store[userID+githubTokenKey] = value
+			store[info.GitHubUsername+githubUsernameKey] = []byte(userID)
+		}
+		// An account dave connected before
+		store["old-dave-gh"+githubUsernameKey] = []byte("daveID")
+
+		for userID, username := range map[string]string{"bobID": "bob", "carolID": "carol", "daveID": "dave", "erinID": "erin"} {
+			user := &model.User{Id: userID, Username: username}
+			api.On("GetUserByUsername", username).Return(user, nil)
+			api.On("GetUser", userID).Return(user, nil)
+		}
+		api.On("GetUserByUsername", "nobody").Return(nil, model.NewAppError("GetUserByUsername", "app.user.get_by_username.app_error", nil, "", http.StatusNotFound))
+		api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		p.SetAPI(api)
+		memberChecks = nil
+
+		return p
+	}
+
+	whois := func(p *Plugin, name string) string {
+		return p.handleWhois(nil, &model.CommandArgs{UserId: "askerID"}, []string{name}, askerInfo)
+	}
+
+	t.Run("connected", func(t *testing.T) {
+		p := setup(t, "")
+
+		expected := fmt.Sprintf("@bob is connected to GitHub as [bob-gh](%s/bob-gh).", ts.URL)
+		assert.Equal(t, expected, whois(p, "@bob"))
+		assert.Equal(t, expected, whois(p, "bob-gh"))
+		assert.Empty(t, memberChecks)
+	})
+
+	t.Run("organization membership", func(t *testing.T) {
+		p := setup(t, "org")
+
+		assert.Equal(t, fmt.Sprintf("@bob is connected to GitHub as [bob-gh](%s/bob-gh).\nThey are a member of the org organization.", ts.URL), whois(p, "@bob"))
+		assert.Equal(t, fmt.Sprintf("@dave is connected to GitHub as [dave-gh](%s/dave-gh).\nThey aren't a member of the org organization, or their membership isn't visible to you.", ts.URL), whois(p, "dave-gh"))
+
+		// Memberships are checked as the asking user
+		assert.Equal(t, []string{"Bearer asker-token /api/v3/orgs/org/members/bob-gh", "Bearer asker-token /api/v3/orgs/org/members/dave-gh"}, memberChecks)
+	})
+
+	t.Run("not connected", func(t *testing.T) {
+		p := setup(t, "org")
+
+		assert.Equal(t, "@erin hasn't connected their GitHub account.", whois(p, "@erin"))
+		assert.Equal(t, "`erin-gh` isn't connected to a Mattermost account.", whois(p, "erin-gh"))
+		assert.Equal(t, "`old-dave-gh` isn't connected to a Mattermost account.", whois(p, "old-dave-gh"))
+		assert.Equal(t, "There is no Mattermost user @nobody.", whois(p, "@nobody"))
+		assert.Empty(t, memberChecks)
+	})
+
+	t.Run("not discoverable", func(t *testing.T) {
+		p := setup(t, "org")
+
+		// The same as not connected, so that it doesn't tell the connection exists
+		assert.Equal(t, "@carol hasn't connected their GitHub account.", whois(p, "@carol"))
+		assert.Equal(t, "`carol-gh` isn't connected to a Mattermost account.", whois(p, "carol-gh"))
+		assert.Empty(t, memberChecks)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		p := setup(t, "")
+
+		assert.Equal(t, "Please use `/github whois @mattermost-user` or `/github whois github-login`.", p.handleWhois(nil, &model.CommandArgs{}, nil, askerInfo))
+	})
+}
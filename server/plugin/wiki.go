@@ -0,0 +1,45 @@
+package plugin
+
+import (
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// postGollumEvent posts the wiki pages created or edited in a delivery of a gollum event.
+func (p *Plugin) postGollumEvent(event *github.GollumEvent, delivery *webhookDelivery) {
+	if len(event.Pages) == 0 {
+		return
+	}
+
+	repo := event.GetRepo()
+
+	subs := delivery.matchSubscriptions(p.GetSubscribedChannelsForRepository(repo))
+	if len(subs) == 0 {
+		return
+	}
+
+	for _, sub := range subs {
+		if !sub.Wiki() {
+			continue
+		}
+
+		if p.excludeSender(event.GetSender(), sub) {
+			continue
+		}
+
+		message, err := renderStyledTemplate("wikiPages", p.getRenderStyle(sub), event)
+		if err != nil {
+			p.API.LogWarn("Failed to render template", "error", err.Error())
+			continue
+		}
+
+		post := &model.Post{
+			UserId:    p.BotUserID,
+			ChannelId: sub.ChannelID,
+			Type:      "custom_git_wiki",
+			Message:   message,
+			Props:     eventPostProps(repo.GetFullName(), objectTypeRepository, repo.GetFullName(), "gollum"),
+		}
+		p.postSubscriptionEvent(post, sub, featureWiki, delivery)
+	}
+}
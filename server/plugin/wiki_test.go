@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// gollumPayload is a gollum webhook payload touching the given number of pages.
+func gollumPayload(pages int) []byte {
+	entries := []string{}
+	for i := 1; i <= pages; i++ {
+		action := "edited"
+		if i == 1 {
+			action = "created"
+		}
+		entries = append(entries, fmt.Sprintf(`{
+			"page_name": "Page-%[1]d",
+			"title": "Page %[1]d",
+			"summary": null,
+			"action": %[2]q,
+			"sha": "91ea1bd42aa2ba166b86e8aefe049e9837214e67",
+			"html_url": "https://github.com/owner/repo/wiki/Page-%[1]d"
+		}`, i, action))
+	}
+
+	return []byte(`{
+		"pages": [` + strings.Join(entries, ",") + `],
+		"repository": {
+			"name": "repo",
+			"full_name": "owner/repo",
+			"html_url": "https://github.com/owner/repo",
+			"owner": {"login": "owner"}
+		},
+		"sender": {"login": "alice", "html_url": "https://github.com/alice"}
+	}`)
+}
+
+func parseGollumPayload(t *testing.T, payload []byte) *github.GollumEvent {
+	event, err := github.ParseWebHook("gollum", payload)
+	require.NoError(t, err)
+	require.IsType(t, &github.GollumEvent{}, event)
+	return event.(*github.GollumEvent)
+}
+
+func TestWikiPagesTemplate(t *testing.T) {
+	t.Run("single page", func(t *testing.T) {
+		event := parseGollumPayload(t, gollumPayload(1))
+
+		message, err := renderTemplate("wikiPages", event)
+		require.NoError(t, err)
+		assert.Equal(t, `
+[\[owner/repo\]](https://github.com/owner/repo) Wiki updated by [alice](https://github.com/alice):
+* [Page 1](https://github.com/owner/repo/wiki/Page-1) created
+`, message)
+
+		message, err = renderStyledTemplate("wikiPages", renderStyleCollapsed, event)
+		require.NoError(t, err)
+		assert.Equal(t, "\n[\\[owner/repo\\]](https://github.com/owner/repo) Wiki page [Page 1](https://github.com/owner/repo/wiki/Page-1) was created by [alice](https://github.com/alice).\n", message)
+	})
+
+	t.Run("several pages", func(t *testing.T) {
+		message, err := renderTemplate("wikiPages", parseGollumPayload(t, gollumPayload(3)))
+		require.NoError(t, err)
+		assert.Equal(t, `
+[\[owner/repo\]](https://github.com/owner/repo) Wiki updated by [alice](https://github.com/alice):
+* [Page 1](https://github.com/owner/repo/wiki/Page-1) created
+* [Page 2](https://github.com/owner/repo/wiki/Page-2) edited
+* [Page 3](https://github.com/owner/repo/wiki/Page-3) edited
+`, message)
+	})
+
+	t.Run("many pages", func(t *testing.T) {
+		event := parseGollumPayload(t, gollumPayload(8))
+
+		message, err := renderTemplate("wikiPages", event)
+		require.NoError(t, err)
+		assert.Equal(t, "\n[\\[owner/repo\\]](https://github.com/owner/repo) Wiki updated by [alice](https://github.com/alice): 8 pages were created or edited, see the [wiki history](https://github.com/owner/repo/wiki/_history).", message)
+
+		message, err = renderStyledTemplate("wikiPages", renderStyleCollapsed, event)
+		require.NoError(t, err)
+		assert.Equal(t, "\n[\\[owner/repo\\]](https://github.com/owner/repo) [8 wiki pages](https://github.com/owner/repo/wiki/_history) were created or edited by [alice](https://github.com/alice).\n", message)
+	})
+}
+
+func TestPostGollumEvent(t *testing.T) {
+	subscriptions, err := json.Marshal(&Subscriptions{Repositories: map[string][]*Subscription{
+		"owner/repo": {
+			{ChannelID: "wikiChannelID", Features: "pulls,wiki", Repository: "owner/repo"},
+			{ChannelID: "collapsedChannelID", Features: featureWiki, Repository: "owner/repo", Flags: SubscriptionFlags{RenderStyle: renderStyleCollapsed}},
+			{ChannelID: "pullsChannelID", Features: featurePulls, Repository: "owner/repo"},
+		},
+	}})
+	require.NoError(t, err)
+
+	p := NewPlugin()
+	p.BotUserID = "botID"
+	p.setConfiguration(&Configuration{})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store[SubscriptionsKey] = subscriptions
+	posts := []*model.Post{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+		posts = append(posts, post)
+		return post
+	}, nil)
+	p.SetAPI(api)
+
+	p.postGollumEvent(parseGollumPayload(t, gollumPayload(2)), testWebhookDelivery(p))
+
+	require.Len(t, posts, 2)
+	assert.Equal(t, "wikiChannelID", posts[0].ChannelId)
+	assert.Equal(t, "custom_git_wiki", posts[0].Type)
+	assert.Contains(t, posts[0].Message, "* [Page 2](https://github.com/owner/repo/wiki/Page-2) edited\n")
+	assert.Equal(t, "gollum", posts[0].GetProp(postPropEventType))
+	assert.Equal(t, "collapsedChannelID", posts[1].ChannelId)
+	assert.Equal(t, "[\\[owner/repo\\]](https://github.com/owner/repo) [2 wiki pages](https://github.com/owner/repo/wiki/_history) were created or edited by [alice](https://github.com/alice).", strings.TrimSpace(posts[1].Message))
+}
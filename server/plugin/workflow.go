@@ -0,0 +1,583 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// workflowRunEventType is the type of the webhook events sent when a workflow run is requested or completed.
+	workflowRunEventType = "workflow_run"
+	// workflowDispatchEvent is the event of the workflow runs triggered with a workflow_dispatch.
+	workflowDispatchEvent = "workflow_dispatch"
+
+	workflowDispatchesKey = "_workflowdispatches"
+	// workflowDispatchTTL is how long a dispatched workflow is waited for to complete.
+	workflowDispatchTTL            = 24 * time.Hour
+	workflowDispatchUpdateAttempts = 5
+	workflowListPageSize           = 100
+)
+
+// WorkflowRunEvent is triggered when a workflow run is requested or completed. go-github doesn't
+// support it yet.
+type WorkflowRunEvent struct {
+	Action      *string             `json:"action,omitempty"`
+	WorkflowRun *github.WorkflowRun `json:"workflow_run,omitempty"`
+	Workflow    *github.Workflow    `json:"workflow,omitempty"`
+	Repo        *github.Repository  `json:"repository,omitempty"`
+	Sender      *github.User        `json:"sender,omitempty"`
+}
+
+func (e *WorkflowRunEvent) GetAction() string {
+	if e == nil || e.Action == nil {
+		return ""
+	}
+	return *e.Action
+}
+
+func (e *WorkflowRunEvent) GetWorkflowRun() *github.WorkflowRun {
+	if e == nil {
+		return nil
+	}
+	return e.WorkflowRun
+}
+
+func (e *WorkflowRunEvent) GetWorkflow() *github.Workflow {
+	if e == nil {
+		return nil
+	}
+	return e.Workflow
+}
+
+func (e *WorkflowRunEvent) GetRepo() *github.Repository {
+	if e == nil {
+		return nil
+	}
+	return e.Repo
+}
+
+func (e *WorkflowRunEvent) GetSender() *github.User {
+	if e == nil {
+		return nil
+	}
+	return e.Sender
+}
+
+func parseWorkflowRunEvent(body []byte) (*WorkflowRunEvent, error) {
+	var event *WorkflowRunEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// workflowInput is an input declared by the workflow_dispatch trigger of a workflow.
+type workflowInput struct {
+	Description string   `yaml:"description"`
+	Required    bool     `yaml:"required"`
+	Default     string   `yaml:"default"`
+	Type        string   `yaml:"type"`
+	Options     []string `yaml:"options"`
+}
+
+// parseWorkflowDispatchInputs reads the inputs of the workflow_dispatch trigger of a workflow file.
+// It reports false if the workflow can't be dispatched.
+func parseWorkflowDispatchInputs(content []byte) (map[string]*workflowInput, bool, error) {
+	var workflow struct {
+		// `on` is a boolean in YAML 1.1, hence the use of yaml.v3
+		On yaml.Node `yaml:"on"`
+	}
+	if err := yaml.Unmarshal(content, &workflow); err != nil {
+		return nil, false, errors.Wrap(err, "could not parse workflow file")
+	}
+
+	inputs := map[string]*workflowInput{}
+
+	on := workflow.On
+	switch on.Kind {
+	case yaml.ScalarNode:
+		return inputs, on.Value == workflowDispatchEvent, nil
+	case yaml.SequenceNode:
+		for _, event := range on.Content {
+			if event.Value == workflowDispatchEvent {
+				return inputs, true, nil
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(on.Content); i += 2 {
+			if on.Content[i].Value != workflowDispatchEvent {
+				continue
+			}
+
+			var trigger struct {
+				Inputs map[string]*workflowInput `yaml:"inputs"`
+			}
+			if err := on.Content[i+1].Decode(&trigger); err != nil {
+				return nil, false, errors.Wrap(err, "could not parse workflow_dispatch inputs")
+			}
+			for name, input := range trigger.Inputs {
+				if input == nil {
+					input = &workflowInput{}
+				}
+				inputs[name] = input
+			}
+			return inputs, true, nil
+		}
+	}
+
+	return inputs, false, nil
+}
+
+// parseWorkflowInputArgs parses the key=value inputs given to `/github workflow run`.
+func parseWorkflowInputArgs(args []string) (map[string]string, error) {
+	inputs := map[string]string{}
+	for _, arg := range args {
+		split := strings.SplitN(arg, "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			return nil, errors.Errorf("Invalid input `%s`. Inputs must be given as `key=value`.", arg)
+		}
+		if _, ok := inputs[split[0]]; ok {
+			return nil, errors.Errorf("The input `%s` is given more than once.", split[0])
+		}
+		inputs[split[0]] = split[1]
+	}
+
+	return inputs, nil
+}
+
+// sortedWorkflowInputNames returns the names of the inputs of a workflow in alphabetical order.
+func sortedWorkflowInputNames(declared map[string]*workflowInput) []string {
+	names := make([]string, 0, len(declared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateWorkflowInputs checks the given inputs against the inputs declared by a workflow.
+func validateWorkflowInputs(declared map[string]*workflowInput, inputs map[string]string) error {
+	for name := range inputs {
+		if _, ok := declared[name]; !ok {
+			if len(declared) == 0 {
+				return errors.Errorf("The workflow doesn't accept any input, but `%s` was given.", name)
+			}
+			return errors.Errorf("The workflow has no input `%s`. Its inputs are: %s.", name, strings.Join(sortedWorkflowInputNames(declared), ", "))
+		}
+	}
+
+	for _, name := range sortedWorkflowInputNames(declared) {
+		input := declared[name]
+		value, ok := inputs[name]
+		if !ok {
+			if input.Required && input.Default == "" {
+				return errors.Errorf("The input `%s` is required.", name)
+			}
+			continue
+		}
+
+		switch input.Type {
+		case "boolean":
+			if value != "true" && value != "false" {
+				return errors.Errorf("The input `%s` must be `true` or `false`.", name)
+			}
+		case "number":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return errors.Errorf("The input `%s` must be a number.", name)
+			}
+		case "choice":
+			if !SliceContainsString(input.Options, value) {
+				return errors.Errorf("The input `%s` must be one of: %s.", name, strings.Join(input.Options, ", "))
+			}
+		}
+	}
+
+	return nil
+}
+
+// getWorkflowDispatchInputs fetches a workflow file at the given ref and reads the inputs of its
+// workflow_dispatch trigger.
+func getWorkflowDispatchInputs(ctx context.Context, githubClient *github.Client, owner, repo string, workflow *github.Workflow, ref string) (map[string]*workflowInput, bool, error) {
+	file, _, _, err := githubClient.Repositories.GetContents(ctx, owner, repo, workflow.GetPath(), &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return nil, false, err
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "could not decode workflow file")
+	}
+
+	return parseWorkflowDispatchInputs([]byte(content))
+}
+
+// dispatchWorkflow triggers a workflow_dispatch event. go-github doesn't support it yet.
+func dispatchWorkflow(ctx context.Context, githubClient *github.Client, owner, repo, workflowFileName, ref string, inputs map[string]string) error {
+	u := fmt.Sprintf("repos/%v/%v/actions/workflows/%v/dispatches", owner, repo, url.PathEscape(workflowFileName))
+	body := struct {
+		Ref    string            `json:"ref"`
+		Inputs map[string]string `json:"inputs,omitempty"`
+	}{Ref: ref, Inputs: inputs}
+
+	req, err := githubClient.NewRequest(http.MethodPost, u, body)
+	if err != nil {
+		return errors.Wrap(err, "could not create workflow dispatch request")
+	}
+
+	_, err = githubClient.Do(ctx, req, nil)
+	return err
+}
+
+// missingOAuthScopes returns the OAuth scopes accepted by an API endpoint if the token has none of them.
+func missingOAuthScopes(resp *http.Response) []string {
+	accepted := splitOAuthScopes(resp.Header.Get("X-Accepted-OAuth-Scopes"))
+	granted := splitOAuthScopes(resp.Header.Get("X-OAuth-Scopes"))
+	for _, scope := range accepted {
+		if SliceContainsString(granted, scope) {
+			return nil
+		}
+	}
+
+	return accepted
+}
+
+func splitOAuthScopes(header string) []string {
+	scopes := []string{}
+	for _, scope := range strings.Split(header, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// describeWorkflowError explains why a workflow couldn't be found or dispatched.
+func describeWorkflowError(err error, fullName, workflowFileName string) string {
+	var gerr *github.ErrorResponse
+	if !errors.As(err, &gerr) || gerr.Response == nil {
+		return fmt.Sprintf("Encountered an error running the workflow `%s` of %s.", workflowFileName, fullName)
+	}
+
+	switch gerr.Response.StatusCode {
+	case http.StatusForbidden, http.StatusNotFound:
+		if isOAuthAppRestrictionError(err) {
+			return fmt.Sprintf("The organization of %s restricts access to OAuth apps. Please ask an organization owner to approve the Mattermost GitHub app.", fullName)
+		}
+		if scopes := missingOAuthScopes(gerr.Response); len(scopes) > 0 {
+			return fmt.Sprintf("Your GitHub authorization is missing the `%s` scope needed to run workflows of %s. Please reconnect your account with `/github connect`.",
+				strings.Join(scopes, "` or `"), fullName)
+		}
+		if gerr.Response.StatusCode == http.StatusNotFound {
+			if workflowFileName == "" {
+				return fmt.Sprintf("Couldn't find the repository %s. Please check its name and that you have access to it.", fullName)
+			}
+			return fmt.Sprintf("Couldn't find the workflow `%s` in %s. Please check the file name and that you have access to the repository.", workflowFileName, fullName)
+		}
+		return fmt.Sprintf("You don't have permission to run workflows of %s. Running a workflow needs write access to the repository.", fullName)
+	case http.StatusUnprocessableEntity:
+		return fmt.Sprintf("GitHub refused to run the workflow `%s` of %s: %s", workflowFileName, fullName, gerr.Message)
+	}
+
+	return fmt.Sprintf("Encountered an error running the workflow `%s` of %s.", workflowFileName, fullName)
+}
+
+// workflowRunsURL links to the runs of a workflow triggered on the given ref.
+func (p *Plugin) workflowRunsURL(owner, repo, workflowFileName, ref string) string {
+	return fmt.Sprintf("%s%s/%s/actions/workflows/%s?query=%s",
+		p.getBaseURL(), owner, repo, url.PathEscape(workflowFileName), url.QueryEscape("branch:"+ref))
+}
+
+// shortRef returns the branch or tag name of a ref, as found in the head branch of workflow runs.
+func shortRef(ref string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(ref, "refs/heads/"), "refs/tags/")
+}
+
+// workflowDispatch is a workflow dispatched from Mattermost, waiting for its run to complete to
+// follow up in the thread of its confirmation post.
+type workflowDispatch struct {
+	Ref            string
+	GitHubUsername string
+	PostID         string
+	DispatchedAt   time.Time
+}
+
+func workflowDispatchesKeyFor(fullName string, workflowID int64) string {
+	return hashKey(workflowDispatchesKey, strings.ToLower(fullName), strconv.FormatInt(workflowID, 10))
+}
+
+// updateWorkflowDispatches applies update to the dispatches of a workflow waiting for their run,
+// dropping the expired ones and retrying if they are changed concurrently.
+func (p *Plugin) updateWorkflowDispatches(key string, update func(dispatches []*workflowDispatch) []*workflowDispatch) error {
+	for i := 0; i < workflowDispatchUpdateAttempts; i++ {
+		oldValue, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return errors.Wrap(appErr, "could not get workflow dispatches from KV store")
+		}
+
+		dispatches := []*workflowDispatch{}
+		if oldValue != nil {
+			if err := json.Unmarshal(oldValue, &dispatches); err != nil {
+				return errors.Wrap(err, "could not unmarshal workflow dispatches")
+			}
+		}
+
+		live := []*workflowDispatch{}
+		for _, dispatch := range dispatches {
+			if time.Since(dispatch.DispatchedAt) < workflowDispatchTTL {
+				live = append(live, dispatch)
+			}
+		}
+
+		var newValue []byte
+		if dispatches = update(live); len(dispatches) > 0 {
+			var err error
+			newValue, err = json.Marshal(dispatches)
+			if err != nil {
+				return errors.Wrap(err, "could not marshal workflow dispatches")
+			}
+		}
+
+		ok, appErr := p.API.KVCompareAndSet(key, oldValue, newValue)
+		if appErr != nil {
+			return errors.Wrap(appErr, "could not store workflow dispatches")
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	return errors.New("workflow dispatches were changed concurrently too many times")
+}
+
+// claimWorkflowDispatch removes and returns the oldest dispatch of a workflow by the given user on the
+// given ref that happened before its run was created, if any.
+func (p *Plugin) claimWorkflowDispatch(fullName string, workflowID int64, ref, githubUsername string, runCreatedAt time.Time) (*workflowDispatch, error) {
+	var claimed *workflowDispatch
+	err := p.updateWorkflowDispatches(workflowDispatchesKeyFor(fullName, workflowID), func(dispatches []*workflowDispatch) []*workflowDispatch {
+		claimed = nil
+		kept := []*workflowDispatch{}
+		for _, dispatch := range dispatches {
+			// The run is created by GitHub once the dispatch request was answered, with some leeway for clock skew
+			if claimed == nil && dispatch.Ref == ref && strings.EqualFold(dispatch.GitHubUsername, githubUsername) &&
+				!dispatch.DispatchedAt.After(runCreatedAt.Add(time.Minute)) {
+				claimed = dispatch
+				continue
+			}
+			kept = append(kept, dispatch)
+		}
+		return kept
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return claimed, nil
+}
+
+func (p *Plugin) handleWorkflow(_ *plugin.Context, args *model.CommandArgs, parameters []string, userInfo *GitHubUserInfo) string {
+	if len(parameters) == 2 && parameters[0] == "list" {
+		return p.handleWorkflowList(parameters[1], userInfo)
+	}
+	if len(parameters) >= 4 && parameters[0] == "run" {
+		return p.handleWorkflowRun(args, parameters[1], parameters[2], parameters[3], parameters[4:], userInfo)
+	}
+
+	return "Please use `/github workflow list owner/repo` or `/github workflow run owner/repo workflow-file.yml ref [key=value ...]`."
+}
+
+func (p *Plugin) handleWorkflowList(repository string, userInfo *GitHubUserInfo) string {
+	owner, repo, err := parseRepo(repository)
+	if err != nil {
+		return "Please specify a repository as `owner/repo`."
+	}
+	fullName := fullNameFromOwnerAndRepo(owner, repo)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	githubClient := p.getGithubClient(userInfo)
+	workflows, _, err := githubClient.Actions.ListWorkflows(ctx, owner, repo, &github.ListOptions{PerPage: workflowListPageSize})
+	if err != nil {
+		p.API.LogWarn("Failed to list workflows", "repo", fullName, "error", err.Error())
+		return describeWorkflowError(err, fullName, "")
+	}
+
+	var lines []string
+	for _, workflow := range workflows.Workflows {
+		if workflow.GetState() != "active" {
+			continue
+		}
+
+		inputs, dispatchable, err := getWorkflowDispatchInputs(ctx, githubClient, owner, repo, workflow, "")
+		if err != nil {
+			p.API.LogDebug("Failed to read workflow file", "repo", fullName, "path", workflow.GetPath(), "error", err.Error())
+			continue
+		}
+		if !dispatchable {
+			continue
+		}
+
+		line := fmt.Sprintf("* `%s` - [%s](%s)", workflow.GetPath()[strings.LastIndex(workflow.GetPath(), "/")+1:], workflow.GetName(), workflow.GetHTMLURL())
+		if len(inputs) > 0 {
+			names := sortedWorkflowInputNames(inputs)
+			for i, name := range names {
+				if inputs[name].Required && inputs[name].Default == "" {
+					names[i] += " (required)"
+				}
+			}
+			line += ", inputs: " + strings.Join(names, ", ")
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) == 0 {
+		return fmt.Sprintf("%s has no workflow that can be run with `workflow_dispatch`.", fullName)
+	}
+
+	return fmt.Sprintf("Workflows of %s that can be run with `/github workflow run %s <file> <ref>`:\n%s", fullName, fullName, strings.Join(lines, "\n"))
+}
+
+func (p *Plugin) handleWorkflowRun(args *model.CommandArgs, repository, workflowFileName, ref string, inputArgs []string, userInfo *GitHubUserInfo) string {
+	owner, repo, err := parseRepo(repository)
+	if err != nil {
+		return "Please specify a repository as `owner/repo`."
+	}
+	fullName := fullNameFromOwnerAndRepo(owner, repo)
+
+	inputs, err := parseWorkflowInputArgs(inputArgs)
+	if err != nil {
+		return err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	githubClient, err := p.getGithubClientFor(userInfo, githubWrite)
+	if err != nil {
+		return readOnlyModeMessage
+	}
+
+	workflow, _, err := githubClient.Actions.GetWorkflowByFileName(ctx, owner, repo, workflowFileName)
+	if err != nil {
+		p.API.LogDebug("Failed to get workflow", "repo", fullName, "workflow", workflowFileName, "error", err.Error())
+		return describeWorkflowError(err, fullName, workflowFileName)
+	}
+
+	declared, dispatchable, err := getWorkflowDispatchInputs(ctx, githubClient, owner, repo, workflow, ref)
+	if err != nil {
+		var gerr *github.ErrorResponse
+		if errors.As(err, &gerr) && gerr.Response != nil && gerr.Response.StatusCode == http.StatusNotFound {
+			return fmt.Sprintf("The workflow `%s` doesn't exist at `%s` in %s.", workflowFileName, ref, fullName)
+		}
+		p.API.LogWarn("Failed to read workflow file", "repo", fullName, "path", workflow.GetPath(), "error", err.Error())
+		return fmt.Sprintf("Encountered an error reading the workflow `%s` of %s.", workflowFileName, fullName)
+	}
+	if !dispatchable {
+		return fmt.Sprintf("The workflow `%s` of %s can't be run from Mattermost as it has no `workflow_dispatch` trigger at `%s`.", workflowFileName, fullName, ref)
+	}
+	if err = validateWorkflowInputs(declared, inputs); err != nil {
+		return err.Error()
+	}
+
+	if err = dispatchWorkflow(ctx, githubClient, owner, repo, workflowFileName, ref, inputs); err != nil {
+		p.API.LogDebug("Failed to dispatch workflow", "repo", fullName, "workflow", workflowFileName, "error", err.Error())
+		return describeWorkflowError(err, fullName, workflowFileName)
+	}
+
+	message := fmt.Sprintf("@%s ran the workflow [%s](%s) of [%s](%s%s) on `%s`. [See its runs](%s).",
+		userInfo.GitHubUsername, workflow.GetName(), workflow.GetHTMLURL(), fullName, p.getBaseURL(), fullName, ref,
+		p.workflowRunsURL(owner, repo, workflowFileName, shortRef(ref)))
+	if len(inputs) > 0 {
+		var lines []string
+		for _, name := range sortedWorkflowInputNames(declared) {
+			if value, ok := inputs[name]; ok {
+				lines = append(lines, fmt.Sprintf("* %s: `%s`", name, value))
+			}
+		}
+		message += "\n" + strings.Join(lines, "\n")
+	}
+
+	post, appErr := p.API.CreatePost(&model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: args.ChannelId,
+		RootId:    args.RootId,
+		Message:   message,
+	})
+	if appErr != nil {
+		p.API.LogWarn("Failed to post workflow dispatch", "error", appErr.Error())
+		return fmt.Sprintf("The workflow `%s` of %s is running, but the confirmation couldn't be posted.", workflowFileName, fullName)
+	}
+
+	threadID := post.Id
+	if post.RootId != "" {
+		threadID = post.RootId
+	}
+	dispatch := &workflowDispatch{
+		Ref:            shortRef(ref),
+		GitHubUsername: userInfo.GitHubUsername,
+		PostID:         threadID,
+		DispatchedAt:   time.Now().UTC(),
+	}
+	err = p.updateWorkflowDispatches(workflowDispatchesKeyFor(fullName, workflow.GetID()), func(dispatches []*workflowDispatch) []*workflowDispatch {
+		return append(dispatches, dispatch)
+	})
+	if err != nil {
+		p.API.LogWarn("Failed to store workflow dispatch", "repo", fullName, "error", err.Error())
+	}
+
+	return ""
+}
+
+// handleWorkflowRunEvent handles a workflow_run event, which go-github can't parse, to follow up
+// on the workflows dispatched from Mattermost.
+func (p *Plugin) handleWorkflowRunEvent(event *WorkflowRunEvent) {
+	run := event.GetWorkflowRun()
+	repo := event.GetRepo()
+	if event.GetAction() != "completed" || run.GetEvent() != workflowDispatchEvent {
+		return
+	}
+
+	workflowID := event.GetWorkflow().GetID()
+	dispatch, err := p.claimWorkflowDispatch(repo.GetFullName(), workflowID, run.GetHeadBranch(), event.GetSender().GetLogin(), run.GetCreatedAt().Time)
+	if err != nil {
+		p.API.LogWarn("Failed to claim workflow dispatch", "repo", repo.GetFullName(), "error", err.Error())
+		return
+	}
+	if dispatch == nil {
+		return
+	}
+
+	root, appErr := p.API.GetPost(dispatch.PostID)
+	if appErr != nil || root.DeleteAt != 0 {
+		return
+	}
+
+	message, err := renderTemplate("workflowRunCompleted", event)
+	if err != nil {
+		p.API.LogWarn("Failed to render template", "error", err.Error())
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: root.ChannelId,
+		RootId:    root.Id,
+		Message:   message,
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogWarn("Failed to post workflow run completion", "error", appErr.Error())
+	}
+}
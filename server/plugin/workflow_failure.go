@@ -0,0 +1,227 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	logExcerptFlag = "include-log-excerpt"
+
+	// logExcerptLines is the number of lines of a job log shown before its failure.
+	logExcerptLines = 30
+	// maxLogExcerptSize is the largest excerpt added to a post, in bytes, well below the size of a post.
+	maxLogExcerptSize = 4000
+	// maxWorkflowLogSize is the largest job log downloaded for an excerpt, in bytes.
+	maxWorkflowLogSize = 5 * 1024 * 1024
+	// workflowLogDownloadTimeout bounds the download of a job log.
+	workflowLogDownloadTimeout = 10 * time.Second
+)
+
+var errWorkflowLogTooLarge = errors.New("workflow job log is too large")
+
+// workflowFailureConclusions are the conclusions of the workflow runs posted by the workflow_failure feature.
+var workflowFailureConclusions = map[string]bool{
+	"failure":   true,
+	"timed_out": true,
+}
+
+var (
+	ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+	// logTimestampPattern matches the timestamp GitHub Actions prefixes the lines of job logs with.
+	logTimestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z ?`)
+	// logFailurePattern matches the lines marking the failure of a job: the first error, or the exit
+	// of the failed process.
+	logFailurePattern = regexp.MustCompile(`##\[error\]|(?i)process completed with exit code [1-9]`)
+)
+
+// cleanLogLine removes the timestamp and the ANSI escape codes of a line of a job log.
+func cleanLogLine(line string) string {
+	line = strings.TrimSuffix(line, "\r")
+	line = ansiEscapePattern.ReplaceAllString(line, "")
+	return logTimestampPattern.ReplaceAllString(line, "")
+}
+
+// extractLogExcerpt returns the last logExcerptLines lines of a job log up to its first failure
+// marker, or the end of the log if there is none. The excerpt is capped to maxLogExcerptSize bytes
+// by dropping its first lines, keeping the ones closest to the failure.
+func extractLogExcerpt(log string) string {
+	lines := strings.Split(log, "\n")
+	end := len(lines)
+	for i, line := range lines {
+		lines[i] = cleanLogLine(line)
+		if end == len(lines) && logFailurePattern.MatchString(lines[i]) {
+			end = i + 1
+		}
+	}
+
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	start := end - logExcerptLines
+	if start < 0 {
+		start = 0
+	}
+
+	excerpt := lines[start:end]
+	for len(excerpt) > 1 && len(strings.Join(excerpt, "\n")) > maxLogExcerptSize {
+		excerpt = excerpt[1:]
+	}
+
+	text := strings.Join(excerpt, "\n")
+	if len(text) > maxLogExcerptSize {
+		// A single line too long is cut from its start, keeping the end closest to the failure
+		text = text[len(text)-maxLogExcerptSize:]
+	}
+
+	return strings.TrimLeft(text, "\n")
+}
+
+// formatLogExcerpt renders a log excerpt as a code block, with a fence the log can't close.
+func formatLogExcerpt(jobName, excerpt string) string {
+	fence := "```"
+	for strings.Contains(excerpt, fence) {
+		fence += "`"
+	}
+
+	return fmt.Sprintf("**Log of `%s`:**\n%s\n%s\n%s", jobName, fence, excerpt, fence)
+}
+
+// downloadWorkflowLog downloads a job log from the URL GitHub redirected to. The URL is signed, so
+// the log is downloaded without the credentials of the GitHub client.
+func downloadWorkflowLog(ctx context.Context, logURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, workflowLogDownloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, logURL, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "could not create workflow job log request")
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", errors.Wrap(err, "could not download workflow job log")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("unexpected status code %d downloading workflow job log", resp.StatusCode)
+	}
+	if resp.ContentLength > maxWorkflowLogSize {
+		return "", errWorkflowLogTooLarge
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxWorkflowLogSize+1))
+	if err != nil {
+		return "", errors.Wrap(err, "could not read workflow job log")
+	}
+	if len(content) > maxWorkflowLogSize {
+		return "", errWorkflowLogTooLarge
+	}
+
+	return string(content), nil
+}
+
+// getWorkflowLogExcerpt returns the excerpt of the log of the first failed job of a workflow run,
+// fetched with the GitHub account of the subscription creator. An empty string is returned when
+// there's no excerpt, including when the log can't be downloaded or is too large, as the excerpt
+// is only a bonus.
+func (p *Plugin) getWorkflowLogExcerpt(sub *Subscription, event *WorkflowRunEvent) string {
+	repo := event.GetRepo()
+	logFailure := func(message string, err error) string {
+		p.API.LogDebug(message, "repo", repo.GetFullName(), "runID", event.GetWorkflowRun().GetID(), "error", err.Error())
+		return ""
+	}
+
+	info, apiErr := p.getGitHubUserInfo(sub.CreatorID)
+	if apiErr != nil {
+		return logFailure("Not adding a log excerpt, the subscription creator isn't connected", apiErr)
+	}
+	githubClient := p.githubConnect(*info.Token)
+
+	ctx := context.Background()
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+	jobs, _, err := githubClient.Actions.ListWorkflowJobs(ctx, owner, name, event.GetWorkflowRun().GetID(), &github.ListWorkflowJobsOptions{Filter: "latest"})
+	if err != nil {
+		return logFailure("Failed to list workflow jobs for a log excerpt", err)
+	}
+
+	var failed *github.WorkflowJob
+	for _, job := range jobs.Jobs {
+		if workflowFailureConclusions[job.GetConclusion()] {
+			failed = job
+			break
+		}
+	}
+	if failed == nil {
+		return ""
+	}
+
+	logURL, _, err := githubClient.Actions.GetWorkflowJobLogs(ctx, owner, name, failed.GetID(), true)
+	if err != nil {
+		return logFailure("Failed to get workflow job log URL for a log excerpt", err)
+	}
+
+	log, err := downloadWorkflowLog(ctx, logURL.String())
+	if err != nil {
+		return logFailure("Failed to download workflow job log for a log excerpt", err)
+	}
+
+	excerpt := extractLogExcerpt(log)
+	if excerpt == "" {
+		return ""
+	}
+
+	return formatLogExcerpt(failed.GetName(), excerpt)
+}
+
+// workflowFailureRenderer renders the failed workflow runs of a repository.
+type workflowFailureRenderer struct {
+	p *Plugin
+}
+
+func (r *workflowFailureRenderer) Feature() string {
+	return featureWorkflowFailure
+}
+
+func (r *workflowFailureRenderer) Render(e interface{}, sub *Subscription) (*model.Post, error) {
+	event, ok := e.(*WorkflowRunEvent)
+	if !ok || event.GetAction() != "completed" || !workflowFailureConclusions[event.GetWorkflowRun().GetConclusion()] {
+		return nil, nil
+	}
+	if !sub.WorkflowFailures() || r.p.excludeSender(event.GetSender(), sub) {
+		return nil, nil
+	}
+
+	message, err := renderTemplate("workflowRunFailed", event)
+	if err != nil {
+		return nil, err
+	}
+	message = r.p.applyNotificationTemplate(sub, event, message)
+
+	if sub.Flags.IncludeLogExcerpt {
+		if excerpt := r.p.getWorkflowLogExcerpt(sub, event); excerpt != "" {
+			message += "\n\n" + excerpt
+		}
+	}
+
+	return &model.Post{
+		Type:    "custom_git_workflow_failure",
+		Message: message,
+		Props:   eventPostProps(event.GetRepo().GetFullName(), objectTypeRepository, event.GetWorkflowRun().GetHTMLURL(), "workflow_run."+event.GetWorkflowRun().GetConclusion()),
+	}, nil
+}
+
+func (p *Plugin) postWorkflowFailureEvent(event *WorkflowRunEvent, delivery *webhookDelivery) {
+	p.postRenderedEvent(&workflowFailureRenderer{p}, event.GetRepo(), event, delivery)
+}
@@ -0,0 +1,216 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func readWorkflowLog(t *testing.T, name string) string {
+	content, err := ioutil.ReadFile(filepath.Join("testdata", "workflow_logs", name))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestExtractLogExcerpt(t *testing.T) {
+	t.Run("error marker", func(t *testing.T) {
+		excerpt := extractLogExcerpt(readWorkflowLog(t, "go_test_failure.log"))
+		assert.Equal(t, strings.Join([]string{
+			"##[group]Run go test ./...",
+			"go test ./...",
+			"shell: /usr/bin/bash -e {0}",
+			"##[endgroup]",
+			"ok  \texample.com/app/config\t0.012s",
+			"--- FAIL: TestCharge (0.01s)",
+			"    charge_test.go:42: ",
+			"        \tError Trace:\tcharge_test.go:42",
+			"        \tError:      \tNot equal: ",
+			"        \t            \texpected: 1000",
+			"        \t            \tactual  : 999",
+			"FAIL",
+			"FAIL\texample.com/app/payments\t0.021s",
+			"##[error]Process completed with exit code 1.",
+		}, "\n"), excerpt)
+		assert.NotContains(t, excerpt, "\x1b")
+	})
+
+	t.Run("process exit", func(t *testing.T) {
+		lines := strings.Split(extractLogExcerpt(readWorkflowLog(t, "npm_build_failure.log")), "\n")
+		require.Len(t, lines, logExcerptLines)
+		assert.Equal(t, "npm WARN deprecated package-14@1.0.0", lines[0])
+		assert.Equal(t, "Error: Cannot find module './routes'", lines[len(lines)-2])
+		assert.Equal(t, "Error: Process completed with exit code 2.", lines[len(lines)-1])
+	})
+
+	t.Run("no marker", func(t *testing.T) {
+		log := ""
+		for i := 1; i <= 50; i++ {
+			log += fmt.Sprintf("2024-03-02T10:00:00.0000000Z step %d\r\n", i)
+		}
+
+		lines := strings.Split(extractLogExcerpt(log+"\n\n"), "\n")
+		require.Len(t, lines, logExcerptLines)
+		assert.Equal(t, "step 21", lines[0])
+		assert.Equal(t, "step 50", lines[len(lines)-1])
+	})
+
+	t.Run("size cap", func(t *testing.T) {
+		log := ""
+		for i := 0; i < 40; i++ {
+			log += fmt.Sprintf("line %02d %s\n", i, strings.Repeat("x", 500))
+		}
+		log += "##[error]boom\n"
+
+		excerpt := extractLogExcerpt(log)
+		assert.True(t, len(excerpt) <= maxLogExcerptSize)
+		assert.True(t, strings.HasPrefix(excerpt, "line 33 "), excerpt[:20])
+		assert.True(t, strings.HasSuffix(excerpt, "\n##[error]boom"))
+
+		excerpt = extractLogExcerpt("##[error]" + strings.Repeat("y", 2*maxLogExcerptSize) + "z")
+		assert.Len(t, excerpt, maxLogExcerptSize)
+		assert.True(t, strings.HasSuffix(excerpt, "yz"))
+	})
+
+	assert.Empty(t, extractLogExcerpt(""))
+}
+
+func TestFormatLogExcerpt(t *testing.T) {
+	assert.Equal(t, "**Log of `test`:**\n```\nFAIL\n```", formatLogExcerpt("test", "FAIL"))
+	assert.Equal(t, "**Log of `docs`:**\n````\n```go\n````", formatLogExcerpt("docs", "```go"))
+}
+
+func TestWorkflowFailureLogExcerpt(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	info, err := json.Marshal(&GitHubUserInfo{UserID: "creatorID", GitHubUsername: "creator", Token: &oauth2.Token{AccessToken: token}})
+	require.NoError(t, err)
+
+	sample := readWorkflowLog(t, "go_test_failure.log")
+	logs := sample
+	logsStatus := http.StatusFound
+	requests := 0
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo/actions/runs/4242/jobs":
+			assert.Equal(t, "latest", r.URL.Query().Get("filter"))
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"total_count": 3, "jobs": [{"id": 1, "name": "lint", "conclusion": "success"}, {"id": 2, "name": "test", "conclusion": "failure"}, {"id": 3, "name": "build", "conclusion": "failure"}]}`)
+		case "/api/v3/repos/owner/repo/actions/jobs/2/logs":
+			if logsStatus != http.StatusFound {
+				w.WriteHeader(logsStatus)
+				return
+			}
+			http.Redirect(w, r, ts.URL+"/signed/logs/2?sig=abc", http.StatusFound)
+		case "/signed/logs/2":
+			// The signed URL is downloaded without the credentials of the GitHub client
+			assert.Empty(t, r.Header.Get("Authorization"))
+			fmt.Fprint(w, logs)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+	api := &plugintest.API{}
+	store := mockKVStore(api)
+	store["creatorID"+githubTokenKey] = info
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	p.SetAPI(api)
+
+	var event *WorkflowRunEvent
+	require.NoError(t, json.Unmarshal([]byte(readWorkflowFixture(t)), &event))
+	renderer := &workflowFailureRenderer{p}
+	sub := &Subscription{ChannelID: "channelID", CreatorID: "creatorID", Repository: "owner/repo", Features: featureWorkflowFailure, Flags: SubscriptionFlags{IncludeLogExcerpt: true}}
+
+	render := func(t *testing.T, sub *Subscription) string {
+		post, err := renderer.Render(event, sub)
+		require.NoError(t, err)
+		require.NotNil(t, post)
+		assert.Contains(t, post.Message, "workflow [CI #128](https://github.com/owner/repo/actions/runs/4242) failed on `main`, triggered by [panda](https://github.com/panda)")
+		return post.Message
+	}
+
+	t.Run("excerpt", func(t *testing.T) {
+		message := render(t, sub)
+		assert.Contains(t, message, "\n\n**Log of `test`:**\n```\n##[group]Run go test ./...\n")
+		assert.True(t, strings.HasSuffix(message, "FAIL\texample.com/app/payments\t0.021s\n##[error]Process completed with exit code 1.\n```"))
+		assert.NotContains(t, message, "Post job cleanup.")
+	})
+
+	t.Run("permission error", func(t *testing.T) {
+		logsStatus = http.StatusForbidden
+		defer func() { logsStatus = http.StatusFound }()
+
+		assert.NotContains(t, render(t, sub), "**Log of")
+	})
+
+	t.Run("oversized log", func(t *testing.T) {
+		logs = strings.Repeat("x", maxWorkflowLogSize) + "\n" + sample
+		defer func() { logs = sample }()
+
+		assert.NotContains(t, render(t, sub), "**Log of")
+	})
+
+	t.Run("creator not connected", func(t *testing.T) {
+		requests = 0
+		stranger := *sub
+		stranger.CreatorID = "strangerID"
+
+		assert.NotContains(t, render(t, &stranger), "**Log of")
+		assert.Zero(t, requests)
+	})
+
+	t.Run("flag not set", func(t *testing.T) {
+		requests = 0
+		plain := *sub
+		plain.Flags = SubscriptionFlags{}
+
+		assert.NotContains(t, render(t, &plain), "**Log of")
+		assert.Zero(t, requests)
+	})
+
+	t.Run("not a failure", func(t *testing.T) {
+		var success *WorkflowRunEvent
+		require.NoError(t, json.Unmarshal([]byte(strings.Replace(readWorkflowFixture(t), `"conclusion": "failure"`, `"conclusion": "success"`, 1)), &success))
+
+		post, err := renderer.Render(success, sub)
+		require.NoError(t, err)
+		assert.Nil(t, post)
+	})
+}
+
+func readWorkflowFixture(t *testing.T) string {
+	content, err := ioutil.ReadFile(filepath.Join("testdata", "webhooks", "workflow_run.failure.json"))
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestSubscribeIncludeLogExcerpt(t *testing.T) {
+	flags := SubscriptionFlags{}
+	require.NoError(t, flags.SetFlag(logExcerptFlag, "true"))
+	assert.True(t, flags.IncludeLogExcerpt)
+	assert.Equal(t, "--include-log-excerpt true", flags.String())
+	assert.EqualError(t, flags.SetFlag(logExcerptFlag, "yes please"), "Invalid value \"yes please\" for --include-log-excerpt. Use `true` or `false`.")
+
+	p := NewPlugin()
+	p.setConfiguration(&Configuration{})
+	err := p.Subscribe(nil, nil, "userID", "owner", "repo", "channelID", "pulls", flags)
+	assert.EqualError(t, err, "Unable to set --include-log-excerpt flag. It requires the workflow_failure feature.")
+}
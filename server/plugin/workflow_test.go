@@ -0,0 +1,248 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+const deployWorkflow = `
+name: Deploy
+on:
+  push:
+    branches: [main]
+  workflow_dispatch:
+    inputs:
+      environment:
+        description: Where to deploy
+        required: true
+        type: choice
+        options: [staging, production]
+      dry-run:
+        type: boolean
+        default: false
+      version:
+        required: true
+        default: latest
+`
+
+func TestParseWorkflowDispatchInputs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		content      string
+		dispatchable bool
+		inputs       []string
+	}{
+		"single event":              {content: "on: workflow_dispatch", dispatchable: true, inputs: []string{}},
+		"list of events":            {content: "on: [push, workflow_dispatch]", dispatchable: true, inputs: []string{}},
+		"trigger without inputs":    {content: "on:\n  workflow_dispatch:\n  push:", dispatchable: true, inputs: []string{}},
+		"trigger with inputs":       {content: deployWorkflow, dispatchable: true, inputs: []string{"dry-run", "environment", "version"}},
+		"not dispatchable":          {content: "on:\n  push:\n  pull_request:", inputs: []string{}},
+		"not dispatchable, as list": {content: "on: [push]", inputs: []string{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			inputs, dispatchable, err := parseWorkflowDispatchInputs([]byte(tc.content))
+			require.NoError(t, err)
+			assert.Equal(t, tc.dispatchable, dispatchable)
+			assert.Equal(t, tc.inputs, sortedWorkflowInputNames(inputs))
+		})
+	}
+
+	t.Run("input declarations", func(t *testing.T) {
+		inputs, _, err := parseWorkflowDispatchInputs([]byte(deployWorkflow))
+		require.NoError(t, err)
+		assert.Equal(t, &workflowInput{Description: "Where to deploy", Required: true, Type: "choice", Options: []string{"staging", "production"}}, inputs["environment"])
+		assert.Equal(t, &workflowInput{Type: "boolean", Default: "false"}, inputs["dry-run"])
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		_, _, err := parseWorkflowDispatchInputs([]byte("on: [push"))
+		assert.Error(t, err)
+	})
+}
+
+func TestValidateWorkflowInputs(t *testing.T) {
+	declared, _, err := parseWorkflowDispatchInputs([]byte(deployWorkflow))
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		args []string
+		err  string
+	}{
+		"valid":             {args: []string{"environment=staging", "dry-run=true"}},
+		"unknown input":     {args: []string{"environment=staging", "region=eu"}, err: "The workflow has no input `region`. Its inputs are: dry-run, environment, version."},
+		"missing required":  {args: []string{"dry-run=true"}, err: "The input `environment` is required."},
+		"invalid choice":    {args: []string{"environment=dev"}, err: "The input `environment` must be one of: staging, production."},
+		"invalid boolean":   {args: []string{"environment=staging", "dry-run=yes"}, err: "The input `dry-run` must be `true` or `false`."},
+		"invalid argument":  {args: []string{"environment"}, err: "Invalid input `environment`. Inputs must be given as `key=value`."},
+		"repeated argument": {args: []string{"environment=staging", "environment=production"}, err: "The input `environment` is given more than once."},
+	} {
+		t.Run(name, func(t *testing.T) {
+			inputs, err := parseWorkflowInputArgs(tc.args)
+			if err == nil {
+				err = validateWorkflowInputs(declared, inputs)
+			}
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tc.err, err.Error())
+		})
+	}
+
+	t.Run("workflow without inputs", func(t *testing.T) {
+		err := validateWorkflowInputs(map[string]*workflowInput{}, map[string]string{"environment": "staging"})
+		require.Error(t, err)
+		assert.Equal(t, "The workflow doesn't accept any input, but `environment` was given.", err.Error())
+	})
+}
+
+func TestWorkflowCommand(t *testing.T) {
+	encryptionKey := "abcdefghijklmnopqrstuvwxyz123456"
+	token, err := encrypt([]byte(encryptionKey), "token")
+	require.NoError(t, err)
+	userInfo := &GitHubUserInfo{UserID: "userID", GitHubUsername: "alice", Token: &oauth2.Token{AccessToken: token}}
+
+	var dispatched map[string]interface{}
+	dispatchStatus := http.StatusNoContent
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/owner/repo/actions/workflows/deploy.yml":
+			fmt.Fprint(w, `{"id": 42, "name": "Deploy", "path": ".github/workflows/deploy.yml", "state": "active", "html_url": "https://github.com/owner/repo/actions/workflows/deploy.yml"}`)
+		case "/api/v3/repos/owner/repo/actions/workflows":
+			fmt.Fprint(w, `{"total_count": 2, "workflows": [
+				{"id": 42, "name": "Deploy", "path": ".github/workflows/deploy.yml", "state": "active", "html_url": "https://github.com/owner/repo/actions/workflows/deploy.yml"},
+				{"id": 43, "name": "CI", "path": ".github/workflows/ci.yml", "state": "active"}
+			]}`)
+		case "/api/v3/repos/owner/repo/contents/.github/workflows/deploy.yml":
+			fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q}`, base64.StdEncoding.EncodeToString([]byte(deployWorkflow)))
+		case "/api/v3/repos/owner/repo/contents/.github/workflows/ci.yml":
+			fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "content": %q}`, base64.StdEncoding.EncodeToString([]byte("on: [push]")))
+		case "/api/v3/repos/owner/repo/actions/workflows/deploy.yml/dispatches":
+			body, _ := ioutil.ReadAll(r.Body)
+			require.NoError(t, json.Unmarshal(body, &dispatched))
+			if dispatchStatus != http.StatusNoContent {
+				w.Header().Set("X-Accepted-OAuth-Scopes", "repo")
+				w.Header().Set("X-OAuth-Scopes", "public_repo, read:org")
+				w.WriteHeader(dispatchStatus)
+				fmt.Fprint(w, `{"message": "Not Found"}`)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	setup := func() (*Plugin, *plugintest.API, map[string][]byte) {
+		p := NewPlugin()
+		p.BotUserID = "botID"
+		p.setConfiguration(&Configuration{EncryptionKey: encryptionKey, EnterpriseBaseURL: ts.URL + "/", EnterpriseUploadURL: ts.URL + "/"})
+		api := &plugintest.API{}
+		store := mockKVStore(api)
+		api.On("LogDebug", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+		mockDeliveryLogs(api)
+		p.SetAPI(api)
+		dispatched = nil
+		dispatchStatus = http.StatusNoContent
+		return p, api, store
+	}
+	args := &model.CommandArgs{UserId: "userID", ChannelId: "channelID"}
+
+	t.Run("list", func(t *testing.T) {
+		p, _, _ := setup()
+
+		message := p.handleWorkflow(nil, args, []string{"list", "owner/repo"}, userInfo)
+		assert.Contains(t, message, "* `deploy.yml` - [Deploy](https://github.com/owner/repo/actions/workflows/deploy.yml), inputs: dry-run, environment (required), version")
+		assert.NotContains(t, message, "ci.yml")
+	})
+
+	t.Run("run and follow up", func(t *testing.T) {
+		p, api, store := setup()
+		var posts []*model.Post
+		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(func(post *model.Post) *model.Post {
+			post.Id = fmt.Sprintf("post%d", len(posts)+1)
+			posts = append(posts, post)
+			return post
+		}, nil)
+		api.On("GetPost", "post1").Return(func(string) *model.Post { return posts[0] }, nil)
+		api.On("KVSet", mock.AnythingOfType("string"), mock.Anything).Return(nil)
+
+		message := p.handleWorkflow(nil, args, []string{"run", "owner/repo", "deploy.yml", "main", "environment=staging"}, userInfo)
+		assert.Empty(t, message)
+		assert.Equal(t, map[string]interface{}{"ref": "main", "inputs": map[string]interface{}{"environment": "staging"}}, dispatched)
+		require.Len(t, posts, 1)
+		assert.Equal(t, "channelID", posts[0].ChannelId)
+		assert.Contains(t, posts[0].Message, "@alice ran the workflow [Deploy](https://github.com/owner/repo/actions/workflows/deploy.yml)")
+		assert.Contains(t, posts[0].Message, "/owner/repo/actions/workflows/deploy.yml?query=branch%3Amain")
+		assert.Contains(t, posts[0].Message, "* environment: `staging`")
+		assert.Len(t, store, 1)
+
+		event := func(sender string) []byte {
+			return []byte(fmt.Sprintf(`{
+				"action": "completed",
+				"workflow_run": {"id": 7, "run_number": 12, "event": "workflow_dispatch", "conclusion": "success", "head_branch": "main", "html_url": "https://github.com/owner/repo/actions/runs/7", "created_at": %q},
+				"workflow": {"id": 42, "name": "Deploy"},
+				"repository": {"full_name": "owner/repo"},
+				"sender": {"login": %q}
+			}`, time.Now().UTC().Format(time.RFC3339), sender))
+		}
+
+		// Someone else's run isn't a follow-up
+		require.NoError(t, p.processWebhookEvent(workflowRunEventType, "", event("bob"), false))
+		assert.Len(t, posts, 1)
+
+		require.NoError(t, p.processWebhookEvent(workflowRunEventType, "", event("alice"), false))
+		require.Len(t, posts, 2)
+		assert.Equal(t, "post1", posts[1].RootId)
+		assert.Equal(t, "[Deploy #12](https://github.com/owner/repo/actions/runs/7) on `main` completed: **success**", posts[1].Message)
+		assert.Nil(t, store[workflowDispatchesKeyFor("owner/repo", 42)])
+
+		// The dispatch is only followed up once
+		require.NoError(t, p.processWebhookEvent(workflowRunEventType, "", event("alice"), false))
+		assert.Len(t, posts, 2)
+	})
+
+	t.Run("invalid inputs aren't dispatched", func(t *testing.T) {
+		p, _, _ := setup()
+
+		message := p.handleWorkflow(nil, args, []string{"run", "owner/repo", "deploy.yml", "main", "environment=dev"}, userInfo)
+		assert.Equal(t, "The input `environment` must be one of: staging, production.", message)
+		assert.Nil(t, dispatched)
+	})
+
+	t.Run("missing scope", func(t *testing.T) {
+		p, _, _ := setup()
+		dispatchStatus = http.StatusNotFound
+
+		message := p.handleWorkflow(nil, args, []string{"run", "owner/repo", "deploy.yml", "main", "environment=staging"}, userInfo)
+		assert.Equal(t, "Your GitHub authorization is missing the `repo` scope needed to run workflows of owner/repo. Please reconnect your account with `/github connect`.", message)
+	})
+
+	t.Run("unknown workflow", func(t *testing.T) {
+		p, _, _ := setup()
+
+		message := p.handleWorkflow(nil, args, []string{"run", "owner/repo", "release.yml", "main"}, userInfo)
+		assert.Equal(t, "Couldn't find the workflow `release.yml` in owner/repo. Please check the file name and that you have access to the repository.", message)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		p, _, _ := setup()
+
+		assert.Equal(t, "Please use `/github workflow list owner/repo` or `/github workflow run owner/repo workflow-file.yml ref [key=value ...]`.",
+			p.handleWorkflow(nil, args, []string{"run", "owner/repo"}, userInfo))
+	})
+}
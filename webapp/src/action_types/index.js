@@ -10,9 +10,12 @@ export default {
     RECEIVED_YOUR_PRS: pluginId + '_received_your_prs',
     RECEIVED_YOUR_PRS_DETAILS: pluginId + '_received_your_prs_details',
     RECEIVED_YOUR_ASSIGNMENTS: pluginId + '_received_your_assignments',
+    RECEIVED_SAVED_SEARCHES: pluginId + '_received_saved_searches',
     RECEIVED_MENTIONS: pluginId + '_received_mentions',
     RECEIVED_UNREADS: pluginId + '_received_unreads',
     RECEIVED_CONNECTED: pluginId + '_received_connected',
+    RECEIVED_CONFIG_UPDATE: pluginId + '_received_config_update',
+    RECEIVED_SIDEBAR_BUTTONS: pluginId + '_received_sidebar_buttons',
     RECEIVED_GITHUB_USER: pluginId + '_received_github_user',
     RECEIVED_SHOW_RHS_ACTION: pluginId + '_received_rhs_action',
     UPDATE_RHS_STATE: pluginId + '_update_rhs_state',
@@ -211,6 +211,42 @@ export function getMilestoneOptions(repo) {
     };
 }
 
+export function getIssueTypeOptions(repo) {
+    return async (dispatch, getState) => {
+        let data;
+        try {
+            data = await Client.getIssueTypes(repo);
+        } catch (error) {
+            return {error};
+        }
+
+        const connected = await checkAndHandleNotConnected(data)(dispatch, getState);
+        if (!connected) {
+            return {error: data};
+        }
+
+        return {data};
+    };
+}
+
+export function getDiscussionCategoryOptions(repo) {
+    return async (dispatch, getState) => {
+        let data;
+        try {
+            data = await Client.getDiscussionCategories(repo);
+        } catch (error) {
+            return {error};
+        }
+
+        const connected = await checkAndHandleNotConnected(data)(dispatch, getState);
+        if (!connected) {
+            return {error: data};
+        }
+
+        return {data};
+    };
+}
+
 export function getYourAssignments() {
     return async (dispatch, getState) => {
         let data;
@@ -234,6 +270,29 @@ export function getYourAssignments() {
     };
 }
 
+export function getSavedSearches() {
+    return async (dispatch, getState) => {
+        let data;
+        try {
+            data = await Client.getSavedSearches();
+        } catch (error) {
+            return {error};
+        }
+
+        const connected = await checkAndHandleNotConnected(data)(dispatch, getState);
+        if (!connected) {
+            return {error: data};
+        }
+
+        dispatch({
+            type: ActionTypes.RECEIVED_SAVED_SEARCHES,
+            data,
+        });
+
+        return {data};
+    };
+}
+
 export function getMentions() {
     return async (dispatch, getState) => {
         let data;
@@ -280,6 +339,50 @@ export function getUnreads() {
     };
 }
 
+// The actions receiving the items of the sidebar buttons returned by getLHSContent.
+const sidebarButtonActionTypes = {
+    prs: ActionTypes.RECEIVED_YOUR_PRS,
+    reviews: ActionTypes.RECEIVED_REVIEWS,
+    assignments: ActionTypes.RECEIVED_YOUR_ASSIGNMENTS,
+    unreads: ActionTypes.RECEIVED_UNREADS,
+};
+
+// getSidebarContent fetches the items of the sidebar buttons. Explicit refreshes skip the items
+// prefetched by the server.
+export function getSidebarContent(buttons, refresh = false) {
+    return async (dispatch, getState) => {
+        const sections = buttons.filter((button) => sidebarButtonActionTypes[button]);
+        if (!sections.length) {
+            return {data: {}};
+        }
+
+        let data;
+        try {
+            data = await Client.getLHSContent(sections, refresh);
+        } catch (error) {
+            return {error};
+        }
+
+        const connected = await checkAndHandleNotConnected(data)(dispatch, getState);
+        if (!connected) {
+            return {error: data};
+        }
+
+        // Sections that failed keep their last items
+        const content = data.sections || {};
+        for (const section of sections) {
+            if (content[section]) {
+                dispatch({
+                    type: sidebarButtonActionTypes[section],
+                    data: content[section],
+                });
+            }
+        }
+
+        return {data};
+    };
+}
+
 const GITHUB_USER_GET_TIMEOUT_MILLISECONDS = 1000 * 60 * 60; // 1 hour
 
 export function getGitHubUser(userID) {
@@ -382,6 +485,24 @@ export function createIssue(payload) {
     };
 }
 
+export function createDiscussion(payload) {
+    return async (dispatch) => {
+        let data;
+        try {
+            data = await Client.createDiscussion(payload);
+        } catch (error) {
+            return {error};
+        }
+
+        const connected = await dispatch(checkAndHandleNotConnected(data));
+        if (!connected) {
+            return {error: data};
+        }
+
+        return {data};
+    };
+}
+
 export function openAttachCommentToIssueModal(postId) {
     return {
         type: ActionTypes.OPEN_ATTACH_COMMENT_TO_ISSUE_MODAL,
@@ -31,6 +31,10 @@ export default class Client {
         return this.doGet(`${this.url}/yourassignments`);
     }
 
+    getSavedSearches = async () => {
+        return this.doGet(`${this.url}/saved_searches`);
+    }
+
     getMentions = async () => {
         return this.doGet(`${this.url}/mentions`);
     }
@@ -39,10 +43,18 @@ export default class Client {
         return this.doGet(`${this.url}/unreads`);
     }
 
+    getLHSContent = async (sections, refresh = false) => {
+        return this.doGet(`${this.url}/lhs-content?sections=${sections.join(',')}&refresh=${refresh}`);
+    }
+
     getGitHubUser = async (userID) => {
         return this.doPost(`${this.url}/user`, {user_id: userID});
     }
 
+    resolveUsers = async (logins) => {
+        return this.doPost(`${this.url}/resolve_users`, {logins});
+    }
+
     getRepositories = async () => {
         return this.doGet(`${this.url}/repositories`);
     }
@@ -63,8 +75,24 @@ export default class Client {
         return this.doPost(`${this.url}/createissue`, payload);
     }
 
-    searchIssues = async (searchTerm) => {
-        return this.doGet(`${this.url}/searchissues?term=${searchTerm}`);
+    getIssueTypes = async (repo) => {
+        return this.doGet(`${this.url}/issue_types?repo=${repo}`);
+    }
+
+    getDiscussionCategories = async (repo) => {
+        return this.doGet(`${this.url}/discussion_categories?repo=${repo}`);
+    }
+
+    createDiscussion = async (payload) => {
+        return this.doPost(`${this.url}/creatediscussion`, payload);
+    }
+
+    createPullRequest = async (payload) => {
+        return this.doPost(`${this.url}/createpr`, payload);
+    }
+
+    searchIssues = async (searchTerm, includePullRequests = false) => {
+        return this.doGet(`${this.url}/searchissues?term=${searchTerm}&include_prs=${includePullRequests}`);
     }
 
     attachCommentToIssue = async (payload) => {
@@ -97,13 +125,7 @@ export default class Client {
             return response.json();
         }
 
-        const text = await response.text();
-
-        throw new ClientError(Client4.url, {
-            message: text || '',
-            status_code: response.status,
-            url,
-        });
+        throw await toClientError(response, url);
     }
 
     doPost = async (url, body, headers = {}) => {
@@ -121,13 +143,7 @@ export default class Client {
             return response.json();
         }
 
-        const text = await response.text();
-
-        throw new ClientError(Client4.url, {
-            message: text || '',
-            status_code: response.status,
-            url,
-        });
+        throw await toClientError(response, url);
     }
 
     doDelete = async (url, body, headers = {}) => {
@@ -144,13 +160,7 @@ export default class Client {
             return response.json();
         }
 
-        const text = await response.text();
-
-        throw new ClientError(Client4.url, {
-            message: text || '',
-            status_code: response.status,
-            url,
-        });
+        throw await toClientError(response, url);
     }
 
     doPut = async (url, body, headers = {}) => {
@@ -168,12 +178,26 @@ export default class Client {
             return response.json();
         }
 
-        const text = await response.text();
+        throw await toClientError(response, url);
+    }
+}
 
-        throw new ClientError(Client4.url, {
-            message: text || '',
-            status_code: response.status,
-            url,
-        });
+// toClientError builds the error of a failed request. The id of the errors of the plugin API, e.g.
+// not_connected or rate_limited, is kept as the server_error_id.
+async function toClientError(response, url) {
+    const text = await response.text();
+
+    let data = {};
+    try {
+        data = JSON.parse(text) || {};
+    } catch (e) {
+        // Not an error of the plugin API
     }
+
+    return new ClientError(Client4.url, {
+        message: data.message || text || '',
+        server_error_id: data.id,
+        status_code: response.status,
+        url,
+    });
 }
@@ -0,0 +1,57 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, {PureComponent} from 'react';
+import PropTypes from 'prop-types';
+
+import IssueAttributeSelector from 'components/issue_attribute_selector';
+
+export default class GithubDiscussionCategorySelector extends PureComponent {
+    static propTypes = {
+        repoName: PropTypes.string.isRequired,
+        theme: PropTypes.object.isRequired,
+        selectedCategory: PropTypes.object,
+        onChange: PropTypes.func.isRequired,
+        actions: PropTypes.shape({
+            getDiscussionCategoryOptions: PropTypes.func.isRequired,
+        }).isRequired,
+    };
+
+    loadCategories = async () => {
+        if (this.props.repoName === '') {
+            return [];
+        }
+
+        const options = await this.props.actions.getDiscussionCategoryOptions(this.props.repoName);
+
+        if (options.error) {
+            // e.g. the repository has discussions disabled
+            throw new Error(options.error.message || 'Failed to load discussion categories');
+        }
+
+        if (!options || !options.data) {
+            return [];
+        }
+
+        return options.data.map((option) => ({
+            value: option.id,
+            label: option.name,
+        }));
+    };
+
+    render() {
+        return (
+            <div className='form-group margin-bottom x3'>
+                <label className='control-label margin-bottom x2'>
+                    {'Discussion Category'}
+                </label>
+                <IssueAttributeSelector
+                    {...this.props}
+                    isMulti={false}
+                    selection={this.props.selectedCategory}
+                    loadOptions={this.loadCategories}
+                />
+            </div>
+        );
+    }
+}
@@ -0,0 +1,18 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import {connect} from 'react-redux';
+import {bindActionCreators} from 'redux';
+
+import {getDiscussionCategoryOptions} from '../../actions';
+
+import GithubDiscussionCategorySelector from './github_discussion_category_selector.jsx';
+
+const mapDispatchToProps = (dispatch) => ({
+    actions: bindActionCreators({getDiscussionCategoryOptions}, dispatch),
+});
+
+export default connect(
+    null,
+    mapDispatchToProps,
+)(GithubDiscussionCategorySelector);
@@ -19,6 +19,7 @@ export default class GithubIssueSelector extends PureComponent {
         onChange: PropTypes.func.isRequired,
         error: PropTypes.string,
         value: PropTypes.object,
+        includePullRequests: PropTypes.bool,
     };
 
     constructor(props) {
@@ -40,7 +41,7 @@ export default class GithubIssueSelector extends PureComponent {
     searchIssues = (text) => {
         const textEncoded = encodeURIComponent(text.trim().replace(/"/g, '\\"'));
 
-        return Client.searchIssues(textEncoded).then((data) => {
+        return Client.searchIssues(textEncoded, this.props.includePullRequests).then((data) => {
             if (!Array.isArray(data)) {
                 return [];
             }
@@ -50,6 +51,9 @@ export default class GithubIssueSelector extends PureComponent {
                 if (repoParts.length >= 2) {
                     prefix = repoParts[repoParts.length - 2] + '/' + repoParts[repoParts.length - 1] + ', ';
                 }
+                if (item.pull_request) {
+                    prefix += 'PR ';
+                }
                 return ({value: item, label: prefix + '#' + item.number + ': ' + item.title, isDisabled: item.locked});
             });
         }).catch((e) => {
@@ -125,12 +129,12 @@ export default class GithubIssueSelector extends PureComponent {
                     className={'control-label'}
                     htmlFor={'issue'}
                 >
-                    {'GitHub Issue'}
+                    {this.props.includePullRequests ? 'GitHub Issue or Pull Request' : 'GitHub Issue'}
                 </label>
                 {this.props.required && requiredStar}
                 <AsyncSelect
                     name={'issue'}
-                    placeholder={'Search for issues containing text...'}
+                    placeholder={this.props.includePullRequests ? 'Search for issues and pull requests containing text...' : 'Search for issues containing text...'}
                     onChange={this.onChange}
                     required={true}
                     disabled={false}
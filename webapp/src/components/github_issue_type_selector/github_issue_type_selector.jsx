@@ -0,0 +1,83 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import React, {PureComponent} from 'react';
+import PropTypes from 'prop-types';
+
+import IssueAttributeSelector from 'components/issue_attribute_selector';
+
+// GithubIssueTypeSelector is hidden for repositories without issue types, e.g. on GitHub
+// Enterprise versions that don't support them.
+export default class GithubIssueTypeSelector extends PureComponent {
+    static propTypes = {
+        repoName: PropTypes.string.isRequired,
+        theme: PropTypes.object.isRequired,
+        selectedIssueType: PropTypes.object,
+        onChange: PropTypes.func.isRequired,
+        actions: PropTypes.shape({
+            getIssueTypeOptions: PropTypes.func.isRequired,
+        }).isRequired,
+    };
+
+    constructor(props) {
+        super(props);
+
+        this.state = {
+            options: [],
+        };
+    }
+
+    componentDidMount() {
+        this.fetchIssueTypes();
+    }
+
+    componentDidUpdate(prevProps) {
+        if (prevProps.repoName !== this.props.repoName) {
+            this.fetchIssueTypes();
+        }
+    }
+
+    fetchIssueTypes = async () => {
+        const repoName = this.props.repoName;
+        this.setState({options: []});
+        this.props.onChange(null);
+
+        if (repoName === '') {
+            return;
+        }
+
+        const types = await this.props.actions.getIssueTypeOptions(repoName);
+        if (types.error || !types.data || repoName !== this.props.repoName) {
+            return;
+        }
+
+        this.setState({
+            options: types.data.map((option) => ({
+                value: option.name,
+                label: option.name,
+            })),
+        });
+    };
+
+    loadIssueTypes = async () => this.state.options;
+
+    render() {
+        if (this.state.options.length === 0) {
+            return null;
+        }
+
+        return (
+            <div className='form-group margin-bottom x3'>
+                <label className='control-label margin-bottom x2'>
+                    {'Issue Type'}
+                </label>
+                <IssueAttributeSelector
+                    {...this.props}
+                    isMulti={false}
+                    selection={this.props.selectedIssueType}
+                    loadOptions={this.loadIssueTypes}
+                />
+            </div>
+        );
+    }
+}
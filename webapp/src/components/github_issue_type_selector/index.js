@@ -0,0 +1,18 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import {connect} from 'react-redux';
+import {bindActionCreators} from 'redux';
+
+import {getIssueTypeOptions} from '../../actions';
+
+import GithubIssueTypeSelector from './github_issue_type_selector.jsx';
+
+const mapDispatchToProps = (dispatch) => ({
+    actions: bindActionCreators({getIssueTypeOptions}, dispatch),
+});
+
+export default connect(
+    null,
+    mapDispatchToProps,
+)(GithubIssueTypeSelector);
@@ -52,6 +52,7 @@ export default class AttachIssueModal extends PureComponent {
             number,
             comment: this.props.post.message,
             post_id: this.props.post.id,
+            expected_state: this.state.issueValue.state,
         };
 
         this.setState({submitting: true});
@@ -104,6 +105,7 @@ export default class AttachIssueModal extends PureComponent {
                     theme={theme}
                     error={error}
                     value={this.state.issueValue}
+                    includePullRequests={true}
                 />
                 <Input
                     label='Message Attached to GitHub Issue'
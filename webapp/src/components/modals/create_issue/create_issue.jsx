@@ -8,6 +8,8 @@ import {Modal} from 'react-bootstrap';
 import GithubLabelSelector from 'components/github_label_selector';
 import GithubAssigneeSelector from 'components/github_assignee_selector';
 import GithubMilestoneSelector from 'components/github_milestone_selector';
+import GithubIssueTypeSelector from 'components/github_issue_type_selector';
+import GithubDiscussionCategorySelector from 'components/github_discussion_category_selector';
 import GithubRepoSelector from 'components/github_repo_selector';
 import Validator from 'components/validator';
 import FormButton from 'components/form_button';
@@ -22,14 +24,19 @@ const initialState = {
     labels: [],
     assignees: [],
     milestone: null,
+    issueType: null,
+    isDiscussion: false,
+    category: null,
     showErrors: false,
     issueTitleValid: true,
+    categoryValid: true,
 };
 
 export default class CreateIssueModal extends PureComponent {
     static propTypes = {
         close: PropTypes.func.isRequired,
         create: PropTypes.func.isRequired,
+        createDiscussion: PropTypes.func.isRequired,
         post: PropTypes.object,
         title: PropTypes.string,
         channelId: PropTypes.string,
@@ -57,9 +64,11 @@ export default class CreateIssueModal extends PureComponent {
             e.preventDefault();
         }
 
-        if (!this.validator.validate() || !this.state.issueTitle) {
+        const categoryValid = !this.state.isDiscussion || Boolean(this.state.category);
+        if (!this.validator.validate() || !this.state.issueTitle || !categoryValid) {
             this.setState({
                 issueTitleValid: Boolean(this.state.issueTitle),
+                categoryValid,
                 showErrors: true,
             });
             return;
@@ -68,6 +77,20 @@ export default class CreateIssueModal extends PureComponent {
         const {post} = this.props;
         const postId = (post) ? post.id : '';
 
+        if (this.state.isDiscussion) {
+            const discussion = {
+                title: this.state.issueTitle,
+                body: this.state.issueDescription,
+                repo: this.state.repo && this.state.repo.name,
+                category_id: this.state.category.value,
+                post_id: postId,
+                channel_id: this.props.channelId,
+            };
+
+            await this.submit(this.props.createDiscussion, discussion, e);
+            return;
+        }
+
         const issue = {
             title: this.state.issueTitle,
             body: this.state.issueDescription,
@@ -75,13 +98,18 @@ export default class CreateIssueModal extends PureComponent {
             labels: this.state.labels,
             assignees: this.state.assignees,
             milestone: this.state.milestone && this.state.milestone.value,
+            issue_type: this.state.issueType && this.state.issueType.value,
             post_id: postId,
             channel_id: this.props.channelId,
         };
 
+        await this.submit(this.props.create, issue, e);
+    };
+
+    submit = async (create, payload, e) => {
         this.setState({submitting: true});
 
-        const created = await this.props.create(issue);
+        const created = await create(payload);
 
         if (created.error) {
             this.setState({
@@ -109,17 +137,57 @@ export default class CreateIssueModal extends PureComponent {
 
     handleMilestoneChange = (milestone) => this.setState({milestone});
 
+    handleIssueTypeChange = (issueType) => this.setState({issueType});
+
+    handleIsDiscussionChange = (e) => this.setState({isDiscussion: e.target.checked});
+
+    handleCategoryChange = (category) => this.setState({category, categoryValid: true});
+
     handleIssueTitleChange = (issueTitle) => this.setState({issueTitle});
 
     handleIssueDescriptionChange = (issueDescription) => this.setState({issueDescription});
 
     renderIssueAttributeSelectors = () => {
-        if (!this.state.repo || (this.state.repo.permissions && !this.state.repo.permissions.push)) {
+        if (!this.state.repo) {
+            return null;
+        }
+
+        if (this.state.isDiscussion) {
+            let categoryValidationError = null;
+            if (this.state.showErrors && !this.state.categoryValid) {
+                categoryValidationError = (
+                    <p className='help-text error-text'>
+                        <span>{'This field is required.'}</span>
+                    </p>
+                );
+            }
+
+            return (
+                <>
+                    <GithubDiscussionCategorySelector
+                        repoName={this.state.repo.name}
+                        theme={this.props.theme}
+                        selectedCategory={this.state.category}
+                        onChange={this.handleCategoryChange}
+                    />
+                    {categoryValidationError}
+                </>
+            );
+        }
+
+        if (this.state.repo.permissions && !this.state.repo.permissions.push) {
             return null;
         }
 
         return (
             <>
+                <GithubIssueTypeSelector
+                    repoName={this.state.repo.name}
+                    theme={this.props.theme}
+                    selectedIssueType={this.state.issueType}
+                    onChange={this.handleIssueTypeChange}
+                />
+
                 <GithubLabelSelector
                     repoName={this.state.repo.name}
                     theme={this.props.theme}
@@ -153,6 +221,8 @@ export default class CreateIssueModal extends PureComponent {
         const {error, submitting} = this.state;
         const style = getStyle(theme);
 
+        const kind = this.state.isDiscussion ? 'Discussion' : 'Issue';
+
         const requiredMsg = 'This field is required.';
         let issueTitleValidationError = null;
         if (this.state.showErrors && !this.state.issueTitleValid) {
@@ -183,9 +253,20 @@ export default class CreateIssueModal extends PureComponent {
                     removeValidate={this.validator.removeComponent}
                 />
 
+                <div className='checkbox margin-bottom x3'>
+                    <label>
+                        <input
+                            type='checkbox'
+                            checked={this.state.isDiscussion}
+                            onChange={this.handleIsDiscussionChange}
+                        />
+                        {'Create a discussion instead of an issue'}
+                    </label>
+                </div>
+
                 <Input
                     id={'title'}
-                    label='Title for the GitHub Issue'
+                    label={`Title for the GitHub ${kind}`}
                     type='input'
                     required={true}
                     disabled={false}
@@ -198,7 +279,7 @@ export default class CreateIssueModal extends PureComponent {
                 {this.renderIssueAttributeSelectors()}
 
                 <Input
-                    label='Description for the GitHub Issue'
+                    label={`Description for the GitHub ${kind}`}
                     type='textarea'
                     value={this.state.issueDescription}
                     onChange={this.handleIssueDescriptionChange}
@@ -217,7 +298,7 @@ export default class CreateIssueModal extends PureComponent {
             >
                 <Modal.Header closeButton={true}>
                     <Modal.Title>
-                        {'Create GitHub Issue'}
+                        {`Create GitHub ${kind}`}
                     </Modal.Title>
                 </Modal.Header>
                 <form
@@ -6,7 +6,7 @@ import {bindActionCreators} from 'redux';
 import {getPost} from 'mattermost-redux/selectors/entities/posts';
 
 import {id as pluginId} from 'manifest';
-import {closeCreateIssueModal, createIssue} from 'actions';
+import {closeCreateIssueModal, createIssue, createDiscussion} from 'actions';
 
 import CreateIssueModal from './create_issue';
 
@@ -25,6 +25,7 @@ const mapStateToProps = (state) => {
 const mapDispatchToProps = (dispatch) => bindActionCreators({
     close: closeCreateIssueModal,
     create: createIssue,
+    createDiscussion,
 }, dispatch);
 
 export default connect(mapStateToProps, mapDispatchToProps)(CreateIssueModal);
@@ -4,7 +4,7 @@
 import {connect} from 'react-redux';
 import {bindActionCreators} from 'redux';
 
-import {getConnected, getReviews, getUnreads, getYourAssignments, getYourPrs, updateRhsState} from '../../actions';
+import {getConnected, getSavedSearches, getSidebarContent, updateRhsState} from '../../actions';
 
 import {id as pluginId} from '../../manifest';
 
@@ -14,10 +14,12 @@ function mapStateToProps(state) {
     return {
         connected: state[`plugins-${pluginId}`].connected,
         clientId: state[`plugins-${pluginId}`].clientId,
+        sidebarButtons: state[`plugins-${pluginId}`].sidebarButtons,
         reviews: state[`plugins-${pluginId}`].reviews,
         yourPrs: state[`plugins-${pluginId}`].yourPrs,
         yourAssignments: state[`plugins-${pluginId}`].yourAssignments,
         unreads: state[`plugins-${pluginId}`].unreads,
+        savedSearches: state[`plugins-${pluginId}`].savedSearches,
         enterpriseURL: state[`plugins-${pluginId}`].enterpriseURL,
         showRHSPlugin: state[`plugins-${pluginId}`].rhsPluginAction,
     };
@@ -27,10 +29,8 @@ function mapDispatchToProps(dispatch) {
     return {
         actions: bindActionCreators({
             getConnected,
-            getReviews,
-            getUnreads,
-            getYourPrs,
-            getYourAssignments,
+            getSidebarContent,
+            getSavedSearches,
             updateRhsState,
         }, dispatch),
     };
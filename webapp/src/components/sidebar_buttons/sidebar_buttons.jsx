@@ -18,14 +18,14 @@ export default class SidebarButtons extends React.PureComponent {
         unreads: PropTypes.arrayOf(PropTypes.object),
         yourPrs: PropTypes.arrayOf(PropTypes.object),
         yourAssignments: PropTypes.arrayOf(PropTypes.object),
+        savedSearches: PropTypes.arrayOf(PropTypes.object),
+        sidebarButtons: PropTypes.arrayOf(PropTypes.string).isRequired,
         isTeamSidebar: PropTypes.bool,
         showRHSPlugin: PropTypes.func.isRequired,
         actions: PropTypes.shape({
             getConnected: PropTypes.func.isRequired,
-            getReviews: PropTypes.func.isRequired,
-            getUnreads: PropTypes.func.isRequired,
-            getYourPrs: PropTypes.func.isRequired,
-            getYourAssignments: PropTypes.func.isRequired,
+            getSidebarContent: PropTypes.func.isRequired,
+            getSavedSearches: PropTypes.func.isRequired,
             updateRhsState: PropTypes.func.isRequired,
         }).isRequired,
     };
@@ -64,10 +64,8 @@ export default class SidebarButtons extends React.PureComponent {
 
         this.setState({refreshing: true});
         await Promise.all([
-            this.props.actions.getReviews(),
-            this.props.actions.getUnreads(),
-            this.props.actions.getYourPrs(),
-            this.props.actions.getYourAssignments(),
+            this.props.actions.getSidebarContent(this.props.sidebarButtons, Boolean(e)),
+            this.props.actions.getSavedSearches(),
         ]);
         this.setState({refreshing: false});
     }
@@ -120,6 +118,7 @@ export default class SidebarButtons extends React.PureComponent {
         const yourPrs = this.props.yourPrs || [];
         const unreads = this.props.unreads || [];
         const yourAssignments = this.props.yourAssignments || [];
+        const savedSearches = this.props.savedSearches || [];
         const refreshClass = this.state.refreshing ? ' fa-spin' : '';
 
         let baseURL = 'https://github.com';
@@ -127,17 +126,8 @@ export default class SidebarButtons extends React.PureComponent {
             baseURL = this.props.enterpriseURL;
         }
 
-        return (
-            <div style={container}>
-                <a
-                    key='githubHeader'
-                    href={baseURL + '/settings/connections/applications/' + this.props.clientId}
-                    target='_blank'
-                    rel='noopener noreferrer'
-                    style={button}
-                >
-                    <i className='fa fa-github fa-lg'/>
-                </a>
+        const buttons = {
+            prs: (
                 <OverlayTrigger
                     key='githubYourPrsLink'
                     placement={placement}
@@ -151,6 +141,8 @@ export default class SidebarButtons extends React.PureComponent {
                         {' ' + yourPrs.length}
                     </a>
                 </OverlayTrigger>
+            ),
+            reviews: (
                 <OverlayTrigger
                     key='githubReviewsLink'
                     placement={placement}
@@ -164,6 +156,8 @@ export default class SidebarButtons extends React.PureComponent {
                         {' ' + reviews.length}
                     </a>
                 </OverlayTrigger>
+            ),
+            assignments: (
                 <OverlayTrigger
                     key='githubAssignmentsLink'
                     placement={placement}
@@ -177,6 +171,8 @@ export default class SidebarButtons extends React.PureComponent {
                         {' ' + yourAssignments.length}
                     </a>
                 </OverlayTrigger>
+            ),
+            unreads: (
                 <OverlayTrigger
                     key='githubUnreadsLink'
                     placement={placement}
@@ -190,6 +186,8 @@ export default class SidebarButtons extends React.PureComponent {
                         {' ' + unreads.length}
                     </a>
                 </OverlayTrigger>
+            ),
+            refresh: (
                 <OverlayTrigger
                     key='githubRefreshButton'
                     placement={placement}
@@ -203,6 +201,40 @@ export default class SidebarButtons extends React.PureComponent {
                         <i className={'fa fa-refresh' + refreshClass}/>
                     </a>
                 </OverlayTrigger>
+            ),
+        };
+
+        // The saved searches are shown before the refresh button, or last if it's hidden
+        const savedSearchButtons = savedSearches.map((search) => (
+            <OverlayTrigger
+                key={'githubSavedSearchLink-' + search.name}
+                placement={placement}
+                overlay={<Tooltip id='savedSearchTooltip'>{search.error || search.name}</Tooltip>}
+            >
+                <a
+                    onClick={() => this.openRHS(RHSStates.SAVED_SEARCH + search.name)}
+                    style={button}
+                >
+                    <i className='fa fa-search'/>
+                    {' ' + (search.error ? '!' : search.total)}
+                </a>
+            </OverlayTrigger>
+        ));
+        const shown = this.props.sidebarButtons.filter((name) => buttons[name]);
+
+        return (
+            <div style={container}>
+                <a
+                    key='githubHeader'
+                    href={baseURL + '/settings/connections/applications/' + this.props.clientId}
+                    target='_blank'
+                    rel='noopener noreferrer'
+                    style={button}
+                >
+                    <i className='fa fa-github fa-lg'/>
+                </a>
+                {shown.map((name) => (name === 'refresh' ? [...savedSearchButtons, buttons[name]] : buttons[name]))}
+                {!shown.includes('refresh') && savedSearchButtons}
             </div>
         );
     }
@@ -41,7 +41,7 @@ function GithubItems(props) {
             };
 
             let icon;
-            if (item.pull_request) {
+            if (item.pull_request || item.type === 'pull_request') {
                 // item is a pull request
                 icon = <GitPullRequestIcon {...iconProps}/>;
             } else {
@@ -42,6 +42,7 @@ function mapStateToProps(state) {
         yourPrs: mapPrsToDetails(state[`plugins-${pluginId}`].yourPrs, state[`plugins-${pluginId}`].yourPrsDetails),
         yourAssignments: state[`plugins-${pluginId}`].yourAssignments,
         unreads: state[`plugins-${pluginId}`].unreads,
+        savedSearches: state[`plugins-${pluginId}`].savedSearches,
         enterpriseURL: state[`plugins-${pluginId}`].enterpriseURL,
         org: state[`plugins-${pluginId}`].organization,
         rhsState: state[`plugins-${pluginId}`].rhsState,
@@ -72,6 +72,7 @@ export default class SidebarRight extends React.PureComponent {
         unreads: PropTypes.arrayOf(PropTypes.object),
         yourPrs: PropTypes.arrayOf(PropTypes.object),
         yourAssignments: PropTypes.arrayOf(PropTypes.object),
+        savedSearches: PropTypes.arrayOf(PropTypes.object),
         rhsState: PropTypes.string,
         theme: PropTypes.object.isRequired,
         actions: PropTypes.shape({
@@ -135,9 +136,23 @@ export default class SidebarRight extends React.PureComponent {
             title = 'Your Assignments';
             listUrl = baseURL + '/pulls?q=is%3Aopen+archived%3Afalse+assignee%3A' + this.props.username + orgQuery;
             break;
-        default:
+        default: {
+            if (!this.props.rhsState || !this.props.rhsState.startsWith(RHSStates.SAVED_SEARCH)) {
+                break;
+            }
+
+            const name = this.props.rhsState.substring(RHSStates.SAVED_SEARCH.length);
+            const search = (this.props.savedSearches || []).find((s) => s.name === name);
+            if (!search) {
+                break;
+            }
+
+            githubItems = search.items;
+            title = search.error ? `${search.name}: ${search.error}` : `${search.name} (${search.total})`;
+            listUrl = baseURL + '/issues?q=' + encodeURIComponent(search.query) + orgQuery;
             break;
         }
+        }
 
         return (
             <React.Fragment>
@@ -5,9 +5,13 @@ export default {
     SETTING_BUTTONS_TEAM: 'team',
 };
 
+// The sidebar buttons shown to users who didn't choose theirs, in order.
+export const DefaultSidebarButtons = ['prs', 'reviews', 'assignments', 'unreads', 'refresh'];
+
 export const RHSStates = {
     PRS: 'pullRequests',
     REVIEWS: 'reviews',
     UNREADS: 'unreads',
     ASSIGNMENTS: 'assignments',
+    SAVED_SEARCH: 'savedSearch:',
 };
@@ -12,7 +12,7 @@ import SidebarRight from './components/sidebar_right';
 import LinkTooltip from './components/link_tooltip';
 import Reducer from './reducers';
 import {getConnected, setShowRHSAction, getSettings} from './actions';
-import {handleConnect, handleDisconnect, handleOpenCreateIssueModal, handleReconnect, handleRefresh} from './websocket';
+import {handleConfigUpdate, handleConnect, handleDisconnect, handleOpenCreateIssueModal, handleReconnect, handleRefresh, handleSidebarButtons} from './websocket';
 
 import {id as pluginId} from './manifest';
 
@@ -45,6 +45,8 @@ class PluginClass {
         registry.registerWebSocketEventHandler(`custom_${pluginId}_disconnect`, handleDisconnect(store));
         registry.registerWebSocketEventHandler(`custom_${pluginId}_refresh`, handleRefresh(store));
         registry.registerWebSocketEventHandler(`custom_${pluginId}_createIssue`, handleOpenCreateIssueModal(store));
+        registry.registerWebSocketEventHandler(`custom_${pluginId}_sidebar_buttons`, handleSidebarButtons(store));
+        registry.registerWebSocketEventHandler(`custom_${pluginId}_config_update`, handleConfigUpdate(store));
         registry.registerReconnectHandler(handleReconnect(store));
 
         activityFunc = () => {
@@ -4,7 +4,7 @@
 import {combineReducers} from 'redux';
 
 import ActionTypes from '../action_types';
-import Constants from '../constants';
+import Constants, {DefaultSidebarButtons} from '../constants';
 
 function connected(state = false, action) {
     switch (action.type) {
@@ -18,6 +18,7 @@ function connected(state = false, action) {
 function enterpriseURL(state = '', action) {
     switch (action.type) {
     case ActionTypes.RECEIVED_CONNECTED:
+    case ActionTypes.RECEIVED_CONFIG_UPDATE:
         if (action.data && action.data.enterprise_base_url) {
             return action.data.enterprise_base_url;
         }
@@ -30,6 +31,7 @@ function enterpriseURL(state = '', action) {
 function organization(state = '', action) {
     switch (action.type) {
     case ActionTypes.RECEIVED_CONNECTED:
+    case ActionTypes.RECEIVED_CONFIG_UPDATE:
         if (action.data && action.data.organization) {
             return action.data.organization;
         }
@@ -57,9 +59,20 @@ function settings(state = {sidebar_buttons: Constants.SETTING_BUTTONS_TEAM, dail
     }
 }
 
+function sidebarButtons(state = DefaultSidebarButtons, action) {
+    switch (action.type) {
+    case ActionTypes.RECEIVED_CONNECTED:
+    case ActionTypes.RECEIVED_SIDEBAR_BUTTONS:
+        return (action.data && action.data.sidebar_button_list) || DefaultSidebarButtons;
+    default:
+        return state;
+    }
+}
+
 function clientId(state = '', action) {
     switch (action.type) {
     case ActionTypes.RECEIVED_CONNECTED:
+    case ActionTypes.RECEIVED_CONFIG_UPDATE:
         return action.data.github_client_id;
     default:
         return state;
@@ -120,6 +133,15 @@ function yourAssignments(state = [], action) {
     }
 }
 
+function savedSearches(state = [], action) {
+    switch (action.type) {
+    case ActionTypes.RECEIVED_SAVED_SEARCHES:
+        return action.data;
+    default:
+        return state;
+    }
+}
+
 function mentions(state = [], action) {
     switch (action.type) {
     case ActionTypes.RECEIVED_MENTIONS:
@@ -225,6 +247,7 @@ export default combineReducers({
     organization,
     username,
     settings,
+    sidebarButtons,
     clientId,
     reviews,
     reviewsDetails,
@@ -232,6 +255,7 @@ export default combineReducers({
     yourRepos,
     yourPrsDetails,
     yourAssignments,
+    savedSearches,
     mentions,
     unreads,
     githubUsers,
@@ -5,10 +5,8 @@ import ActionTypes from '../action_types';
 import Constants from '../constants';
 import {
     getConnected,
-    getReviews,
-    getUnreads,
-    getYourAssignments,
-    getYourPrs,
+    getSavedSearches,
+    getSidebarContent,
     openCreateIssueModalWithoutPost,
 } from '../actions';
 
@@ -41,14 +39,27 @@ export function handleDisconnect(store) {
     };
 }
 
+// handleConfigUpdate keeps the configuration received when connecting current, e.g. when an
+// administrator changes the locked organization.
+export function handleConfigUpdate(store) {
+    return (msg) => {
+        if (!msg.data) {
+            return;
+        }
+
+        store.dispatch({
+            type: ActionTypes.RECEIVED_CONFIG_UPDATE,
+            data: msg.data,
+        });
+    };
+}
+
 export function handleReconnect(store, reminder = false) {
     return async () => {
         const {data} = await getConnected(reminder)(store.dispatch, store.getState);
         if (data && data.connected) {
-            getReviews()(store.dispatch, store.getState);
-            getUnreads()(store.dispatch, store.getState);
-            getYourPrs()(store.dispatch, store.getState);
-            getYourAssignments()(store.dispatch, store.getState);
+            getSidebarContent(store.getState()[`plugins-${pluginId}`].sidebarButtons)(store.dispatch, store.getState);
+            getSavedSearches()(store.dispatch, store.getState);
         }
     };
 }
@@ -56,14 +67,27 @@ export function handleReconnect(store, reminder = false) {
 export function handleRefresh(store) {
     return () => {
         if (store.getState()[`plugins-${pluginId}`].connected) {
-            getReviews()(store.dispatch, store.getState);
-            getUnreads()(store.dispatch, store.getState);
-            getYourPrs()(store.dispatch, store.getState);
-            getYourAssignments()(store.dispatch, store.getState);
+            getSidebarContent(store.getState()[`plugins-${pluginId}`].sidebarButtons, true)(store.dispatch, store.getState);
+            getSavedSearches()(store.dispatch, store.getState);
         }
     };
 }
 
+export function handleSidebarButtons(store) {
+    return (msg) => {
+        if (!msg.data) {
+            return;
+        }
+
+        store.dispatch({
+            type: ActionTypes.RECEIVED_SIDEBAR_BUTTONS,
+            data: msg.data,
+        });
+
+        getSidebarContent(msg.data.sidebar_button_list || [])(store.dispatch, store.getState);
+    };
+}
+
 export function handleOpenCreateIssueModal(store) {
     return (msg) => {
         if (!msg.data) {